@@ -74,9 +74,229 @@ func (db *DB) runMigrations() error {
 		}
 	}
 
+	var hasBeta bool
+	err = db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('symbols') WHERE name = 'beta'").Scan(&hasBeta)
+	if err != nil {
+		return fmt.Errorf("failed to check for beta column: %w", err)
+	}
+
+	if !hasBeta {
+		_, err := db.Exec("ALTER TABLE symbols ADD COLUMN beta REAL")
+		if err != nil {
+			return fmt.Errorf("failed to add beta column: %w", err)
+		}
+	}
+
+	var hasManualPrice bool
+	err = db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('symbols') WHERE name = 'manual_price'").Scan(&hasManualPrice)
+	if err != nil {
+		return fmt.Errorf("failed to check for manual_price column: %w", err)
+	}
+
+	if !hasManualPrice {
+		_, err := db.Exec("ALTER TABLE symbols ADD COLUMN manual_price INTEGER NOT NULL DEFAULT 0")
+		if err != nil {
+			return fmt.Errorf("failed to add manual_price column: %w", err)
+		}
+	}
+
+	var hasBasisFlag bool
+	err = db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('long_positions') WHERE name = 'basis_includes_put_premium'").Scan(&hasBasisFlag)
+	if err != nil {
+		return fmt.Errorf("failed to check for basis_includes_put_premium column: %w", err)
+	}
+
+	if !hasBasisFlag {
+		_, err := db.Exec("ALTER TABLE long_positions ADD COLUMN basis_includes_put_premium INTEGER NOT NULL DEFAULT 0")
+		if err != nil {
+			return fmt.Errorf("failed to add basis_includes_put_premium column: %w", err)
+		}
+	}
+
+	for _, table := range []string{"options", "long_positions"} {
+		var hasAccount bool
+		err = db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('" + table + "') WHERE name = 'account'").Scan(&hasAccount)
+		if err != nil {
+			return fmt.Errorf("failed to check for %s account column: %w", table, err)
+		}
+
+		if !hasAccount {
+			_, err := db.Exec("ALTER TABLE " + table + " ADD COLUMN account TEXT NOT NULL DEFAULT 'Default'")
+			if err != nil {
+				return fmt.Errorf("failed to add %s account column: %w", table, err)
+			}
+		}
+	}
+
+	// The same trade may be held in several accounts, so the options key includes account
+	var uniqueHasAccount bool
+	err = db.QueryRow("SELECT COUNT(*) FROM pragma_index_info('idx_options_unique') WHERE name = 'account'").Scan(&uniqueHasAccount)
+	if err != nil {
+		return fmt.Errorf("failed to check options unique index: %w", err)
+	}
+
+	if !uniqueHasAccount {
+		if err := db.rebuildOptionsUniqueIndex(); err != nil {
+			return err
+		}
+	}
+
+	for column, definition := range map[string]string{
+		"coupon_rate":      "REAL NOT NULL DEFAULT 0",
+		"coupon_frequency": "INTEGER NOT NULL DEFAULT 0",
+	} {
+		var hasColumn bool
+		err = db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('treasuries') WHERE name = ?", column).Scan(&hasColumn)
+		if err != nil {
+			return fmt.Errorf("failed to check for treasuries %s column: %w", column, err)
+		}
+
+		if !hasColumn {
+			_, err := db.Exec("ALTER TABLE treasuries ADD COLUMN " + column + " " + definition)
+			if err != nil {
+				return fmt.Errorf("failed to add treasuries %s column: %w", column, err)
+			}
+		}
+	}
+
+	for column, definition := range map[string]string{
+		"assigned":             "INTEGER NOT NULL DEFAULT 0",
+		"assigned_position_id": "INTEGER",
+		"strategy":             "TEXT",
+		"rolled_from_id":       "INTEGER",
+		"realized_profit":      "REAL",
+		"currency":             "TEXT NOT NULL DEFAULT 'USD'",
+		"fx_rate":              "REAL NOT NULL DEFAULT 1.0",
+		"underlying_at_open":   "REAL",
+		"underlying_at_close":  "REAL",
+		"status":               "TEXT NOT NULL DEFAULT 'active'",
+		"fees":                 "REAL NOT NULL DEFAULT 0.0",
+	} {
+		var hasColumn bool
+		err = db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('options') WHERE name = ?", column).Scan(&hasColumn)
+		if err != nil {
+			return fmt.Errorf("failed to check for options %s column: %w", column, err)
+		}
+
+		if !hasColumn {
+			_, err := db.Exec("ALTER TABLE options ADD COLUMN " + column + " " + definition)
+			if err != nil {
+				return fmt.Errorf("failed to add options %s column: %w", column, err)
+			}
+		}
+	}
+
+	for column, definition := range map[string]string{
+		"reinvested_amount":      "REAL NOT NULL DEFAULT 0.0",
+		"reinvested_position_id": "INTEGER",
+	} {
+		var hasColumn bool
+		err = db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('dividends') WHERE name = ?", column).Scan(&hasColumn)
+		if err != nil {
+			return fmt.Errorf("failed to check for dividends %s column: %w", column, err)
+		}
+
+		if !hasColumn {
+			_, err := db.Exec("ALTER TABLE dividends ADD COLUMN " + column + " " + definition)
+			if err != nil {
+				return fmt.Errorf("failed to add dividends %s column: %w", column, err)
+			}
+		}
+	}
+
+	// BASIS_INCLUDE_OPEN_CALL_PREMIUM was renamed to BASIS_DEFER_OPEN_CALL_PREMIUM; carry over a stored value
+	_, err = db.Exec(`UPDATE settings SET value = (SELECT value FROM settings WHERE name = 'BASIS_INCLUDE_OPEN_CALL_PREMIUM')
+		WHERE name = 'BASIS_DEFER_OPEN_CALL_PREMIUM' AND EXISTS (SELECT 1 FROM settings WHERE name = 'BASIS_INCLUDE_OPEN_CALL_PREMIUM')`)
+	if err != nil {
+		return fmt.Errorf("failed to carry over BASIS_INCLUDE_OPEN_CALL_PREMIUM: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM settings WHERE name = 'BASIS_INCLUDE_OPEN_CALL_PREMIUM'"); err != nil {
+		return fmt.Errorf("failed to remove BASIS_INCLUDE_OPEN_CALL_PREMIUM: %w", err)
+	}
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	if version < treasuryPercentYieldsVersion {
+		if err := db.rescaleTreasuryYieldsToPercent(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// treasuryPercentYieldsVersion is the user_version recorded once stored treasury yields have been
+// brought to percent. Data migrations that must run only once are keyed on PRAGMA user_version.
+const treasuryPercentYieldsVersion = 1
+
+// rescaleTreasuryYieldsToPercent converts stored yields and coupon rates to percent, the convention
+// imports now enforce, and records the schema version in the same transaction so it never runs
+// twice. Rows are rescaled together (0.045 becomes 4.5) when TREASURY_YIELD_INPUT_SCALE is
+// "decimal", or, under the "auto" default seeded on upgrade, when every positive yield is below 1.
+// The whole table is judged rather than each row, since a genuine 0.05% bill yield looks like a
+// decimal fraction next to percent-style rows; "percent" leaves rows alone.
+func (db *DB) rescaleTreasuryYieldsToPercent() error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin treasury yield rescale: %w", err)
+	}
+	defer tx.Rollback()
+
+	var scale string
+	err = tx.QueryRow("SELECT value FROM settings WHERE name = 'TREASURY_YIELD_INPUT_SCALE'").Scan(&scale)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read treasury yield scale: %w", err)
+	}
+
+	var rescale bool
+	switch strings.ToLower(strings.TrimSpace(scale)) {
+	case "decimal":
+		rescale = true
+	case "percent":
+		rescale = false
+	default:
+		var decimalRows, percentRows int
+		err := tx.QueryRow("SELECT COUNT(CASE WHEN yield > 0 AND yield < 1 THEN 1 END), COUNT(CASE WHEN yield >= 1 THEN 1 END) FROM treasuries").
+			Scan(&decimalRows, &percentRows)
+		if err != nil {
+			return fmt.Errorf("failed to inspect treasury yields: %w", err)
+		}
+		rescale = decimalRows > 0 && percentRows == 0
+	}
+
+	if rescale {
+		for _, column := range []string{"yield", "coupon_rate"} {
+			if _, err := tx.Exec("UPDATE treasuries SET " + column + " = " + column + " * 100"); err != nil {
+				return fmt.Errorf("failed to rescale treasury %s: %w", column, err)
+			}
+		}
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", treasuryPercentYieldsVersion)); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	return tx.Commit()
+}
+
+// rebuildOptionsUniqueIndex replaces idx_options_unique with the account-scoped key in one transaction
+func (db *DB) rebuildOptionsUniqueIndex() error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin options unique index rebuild: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DROP INDEX IF EXISTS idx_options_unique"); err != nil {
+		return fmt.Errorf("failed to drop options unique index: %w", err)
+	}
+	if _, err := tx.Exec("CREATE UNIQUE INDEX idx_options_unique ON options(symbol, type, opened, strike, expiration, premium, contracts, account)"); err != nil {
+		return fmt.Errorf("failed to rebuild options unique index: %w", err)
+	}
+	return tx.Commit()
+}
+
 func (db *DB) Close() error {
 	return db.DB.Close()
 }
@@ -166,6 +386,88 @@ func CreateNewDatabase(name string) error {
 	return nil
 }
 
+// CloneDatabase copies an existing database in the data directory to a new name using VACUUM INTO,
+// producing a consistent snapshot even while the source is in use. It refuses to overwrite.
+func CloneDatabase(source, target string) error {
+	if !strings.HasSuffix(source, ".db") {
+		source = source + ".db"
+	}
+	if !strings.HasSuffix(target, ".db") {
+		target = target + ".db"
+	}
+
+	sourcePath := filepath.Join("./data", source)
+	targetPath := filepath.Join("./data", target)
+
+	if _, err := os.Stat(sourcePath); err != nil {
+		return fmt.Errorf("source database %s not found", source)
+	}
+	if _, err := os.Stat(targetPath); err == nil {
+		return fmt.Errorf("database %s already exists", target)
+	}
+
+	db, err := sql.Open("sqlite3", sourcePath+"?_busy_timeout=10000")
+	if err != nil {
+		return fmt.Errorf("failed to open source database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("VACUUM INTO ?", targetPath); err != nil {
+		return fmt.Errorf("failed to clone database: %w", err)
+	}
+
+	return nil
+}
+
+// ResetTables lists the trading data tables cleared by ResetTradingData, children before parents
+// so foreign keys are satisfied. The settings table is never cleared.
+var ResetTables = []string{
+	"long_position_exits",
+	"stock_splits",
+	"greeks_snapshots",
+	"option_contract_details",
+	"call_coverage",
+	"treasury_coupons",
+	"long_positions",
+	"options",
+	"dividends",
+	"treasuries",
+	"metrics",
+}
+
+// ResetTradingData deletes every row from ResetTables, and from symbols when includeSymbols is set,
+// in one transaction. It returns the number of rows deleted per table.
+func ResetTradingData(db *sql.DB, includeSymbols bool) (map[string]int64, error) {
+	tables := ResetTables
+	if includeSymbols {
+		tables = append(append([]string{}, ResetTables...), "symbols")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin reset: %w", err)
+	}
+	defer tx.Rollback()
+
+	deleted := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		result, err := tx.Exec("DELETE FROM " + table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to count rows cleared from %s: %w", table, err)
+		}
+		deleted[table] = rows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit reset: %w", err)
+	}
+	return deleted, nil
+}
+
 // ListDatabases returns a list of all .db files in the data directory
 func ListDatabases() ([]string, error) {
 	dataDir := "./data"
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// openPreUpgradeDB creates a database file holding only the original treasuries table with the
+// given yields, as written before yields were stored in percent
+func openPreUpgradeDB(t *testing.T, yields map[string]float64) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "wheeler.db")
+	legacy, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open legacy database: %v", err)
+	}
+	defer legacy.Close()
+
+	if _, err := legacy.Exec(`CREATE TABLE treasuries (
+		cuspid TEXT PRIMARY KEY,
+		purchased DATE NOT NULL,
+		maturity DATE NOT NULL,
+		amount REAL NOT NULL,
+		yield REAL NOT NULL,
+		buy_price REAL NOT NULL,
+		current_value REAL,
+		exit_price REAL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		t.Fatalf("failed to create legacy treasuries table: %v", err)
+	}
+	for cuspid, yield := range yields {
+		if _, err := legacy.Exec(`INSERT INTO treasuries (cuspid, purchased, maturity, amount, yield, buy_price)
+			VALUES (?, '2024-01-02', '2024-07-02', 10000, ?, 9800)`, cuspid, yield); err != nil {
+			t.Fatalf("failed to insert legacy treasury: %v", err)
+		}
+	}
+	return path
+}
+
+func TestTreasuryYieldsRescaledToPercentOnUpgrade(t *testing.T) {
+	tests := []struct {
+		name   string
+		yields map[string]float64
+		want   map[string]float64
+	}{
+		{"decimal yields", map[string]float64{"912797AA1": 0.045, "912797AA2": 0.0525}, map[string]float64{"912797AA1": 4.5, "912797AA2": 5.25}},
+		{"percent yields with a sub-1% bill", map[string]float64{"912797AA1": 4.5, "912797AA2": 0.05}, map[string]float64{"912797AA1": 4.5, "912797AA2": 0.05}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := NewDB(openPreUpgradeDB(t, tt.yields))
+			if err != nil {
+				t.Fatalf("failed to upgrade database: %v", err)
+			}
+			defer db.Close()
+
+			checkYields := func(when string) {
+				for cuspid, want := range tt.want {
+					var yield float64
+					if err := db.QueryRow("SELECT yield FROM treasuries WHERE cuspid = ?", cuspid).Scan(&yield); err != nil {
+						t.Fatalf("failed to read %s: %v", cuspid, err)
+					}
+					if yield < want-1e-9 || yield > want+1e-9 {
+						t.Errorf("expected %s yield %.4f %s, got %.4f", cuspid, want, when, yield)
+					}
+				}
+			}
+			checkYields("after the upgrade")
+
+			// The rescale is recorded and never applied twice
+			if err := db.InitSchema(); err != nil {
+				t.Fatalf("failed to rerun migrations: %v", err)
+			}
+			checkYields("after a second start")
+		})
+	}
+}
+
+func TestOpenCallPremiumSettingCarriedOverOnRename(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "wheeler.db"))
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("INSERT INTO settings (name, value) VALUES ('BASIS_INCLUDE_OPEN_CALL_PREMIUM', 'true')"); err != nil {
+		t.Fatalf("failed to insert legacy setting: %v", err)
+	}
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("failed to rerun migrations: %v", err)
+	}
+
+	var value string
+	if err := db.QueryRow("SELECT value FROM settings WHERE name = 'BASIS_DEFER_OPEN_CALL_PREMIUM'").Scan(&value); err != nil {
+		t.Fatalf("failed to read renamed setting: %v", err)
+	}
+	if value != "true" {
+		t.Errorf("expected BASIS_DEFER_OPEN_CALL_PREMIUM to carry over 'true', got %q", value)
+	}
+	var legacy int
+	if err := db.QueryRow("SELECT COUNT(*) FROM settings WHERE name = 'BASIS_INCLUDE_OPEN_CALL_PREMIUM'").Scan(&legacy); err != nil {
+		t.Fatalf("failed to count legacy settings: %v", err)
+	}
+	if legacy != 0 {
+		t.Errorf("expected the legacy setting to be removed, found %d rows", legacy)
+	}
+}
@@ -4,6 +4,8 @@ CREATE TABLE IF NOT EXISTS symbols (
     dividend REAL DEFAULT 0.0,
     ex_dividend_date DATE,
     pe_ratio REAL,
+    beta REAL,
+    manual_price INTEGER NOT NULL DEFAULT 0,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
@@ -18,11 +20,42 @@ CREATE TABLE IF NOT EXISTS long_positions (
     adjusted_cost_basis_per_share REAL NOT NULL DEFAULT 0.0,
     adjusted_cost_basis_total REAL NOT NULL DEFAULT 0.0,
     exit_price REAL,
+    account TEXT NOT NULL DEFAULT 'Default',
+    basis_includes_put_premium INTEGER NOT NULL DEFAULT 0,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (symbol) REFERENCES symbols(symbol)
 );
 
+CREATE TABLE IF NOT EXISTS long_position_exits (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    position_id INTEGER NOT NULL,
+    exited DATE NOT NULL,
+    shares INTEGER NOT NULL CHECK (shares > 0),
+    price REAL NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (position_id) REFERENCES long_positions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS stock_splits (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    position_id INTEGER NOT NULL,
+    symbol TEXT NOT NULL,
+    effective DATE NOT NULL,
+    split_to INTEGER NOT NULL CHECK (split_to > 0),
+    split_from INTEGER NOT NULL CHECK (split_from > 0),
+    rounding TEXT NOT NULL,
+    shares_before INTEGER NOT NULL,
+    shares_after INTEGER NOT NULL,
+    remainder_shares REAL NOT NULL DEFAULT 0.0,
+    remainder_pre_split_shares REAL NOT NULL DEFAULT 0.0,
+    remainder_basis REAL NOT NULL DEFAULT 0.0,
+    cash_in_lieu REAL NOT NULL DEFAULT 0.0,
+    realized REAL NOT NULL DEFAULT 0.0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (position_id) REFERENCES long_positions(id) ON DELETE CASCADE
+);
+
 CREATE TABLE IF NOT EXISTS options (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     symbol TEXT NOT NULL,
@@ -35,7 +68,19 @@ CREATE TABLE IF NOT EXISTS options (
     contracts INTEGER NOT NULL,
     exit_price REAL,
     commission REAL DEFAULT 0.0,
+    fees REAL NOT NULL DEFAULT 0.0,
     current_price REAL,
+    account TEXT NOT NULL DEFAULT 'Default',
+    assigned INTEGER NOT NULL DEFAULT 0,
+    assigned_position_id INTEGER,
+    strategy TEXT,
+    rolled_from_id INTEGER,
+    realized_profit REAL,
+    currency TEXT NOT NULL DEFAULT 'USD',
+    fx_rate REAL NOT NULL DEFAULT 1.0,
+    underlying_at_open REAL,
+    underlying_at_close REAL,
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'canceled')),
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (symbol) REFERENCES symbols(symbol)
@@ -46,6 +91,8 @@ CREATE TABLE IF NOT EXISTS dividends (
     symbol TEXT NOT NULL,
     received DATE NOT NULL,
     amount REAL NOT NULL,
+    reinvested_amount REAL NOT NULL DEFAULT 0.0,
+    reinvested_position_id INTEGER,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (symbol) REFERENCES symbols(symbol)
 );
@@ -59,10 +106,73 @@ CREATE TABLE IF NOT EXISTS treasuries (
     buy_price REAL NOT NULL,
     current_value REAL,
     exit_price REAL,
+    coupon_rate REAL NOT NULL DEFAULT 0,
+    coupon_frequency INTEGER NOT NULL DEFAULT 0,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE TABLE IF NOT EXISTS treasury_coupons (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    cuspid TEXT NOT NULL,
+    received DATE NOT NULL,
+    amount REAL NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cuspid) REFERENCES treasuries(cuspid) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS greeks_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    option_id INTEGER NOT NULL,
+    captured DATE NOT NULL,
+    contract TEXT NOT NULL,
+    delta REAL,
+    gamma REAL,
+    theta REAL,
+    vega REAL,
+    implied_volatility REAL,
+    source TEXT NOT NULL DEFAULT 'capture',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (option_id) REFERENCES options(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS option_contract_details (
+    option_id INTEGER PRIMARY KEY,
+    contract TEXT NOT NULL,
+    exercise_style TEXT NOT NULL DEFAULT 'american',
+    shares_per_contract REAL NOT NULL DEFAULT 100,
+    source TEXT NOT NULL DEFAULT 'polygon',
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (option_id) REFERENCES options(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS symbol_defaults (
+    symbol TEXT PRIMARY KEY,
+    contracts INTEGER,
+    strategy TEXT,
+    commission_per_contract REAL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (symbol) REFERENCES symbols(symbol) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS call_coverage (
+    option_id INTEGER NOT NULL,
+    position_id INTEGER NOT NULL,
+    shares INTEGER NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (option_id, position_id),
+    FOREIGN KEY (option_id) REFERENCES options(id) ON DELETE CASCADE,
+    FOREIGN KEY (position_id) REFERENCES long_positions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS symbol_price_history (
+    symbol TEXT NOT NULL,
+    date DATE NOT NULL,
+    close REAL NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (symbol, date),
+    FOREIGN KEY (symbol) REFERENCES symbols(symbol) ON DELETE CASCADE
+);
 
 CREATE TABLE IF NOT EXISTS settings (
     name TEXT PRIMARY KEY,
@@ -83,12 +193,166 @@ CREATE TABLE IF NOT EXISTS metrics (
 INSERT OR IGNORE INTO settings (name, value, description) 
 VALUES ('POLYGON_API_KEY', '', 'API key for Polygon.io stock market data integration');
 
+-- Insert default API_TOKEN setting (empty disables token checks)
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('API_TOKEN', '', 'Optional token required on protected API endpoints via Authorization: Bearer or X-API-Token header');
+
+-- Insert default AUTO_ENRICH_SYMBOLS setting
+INSERT OR IGNORE INTO settings (name, value, description) 
+VALUES ('AUTO_ENRICH_SYMBOLS', 'false', 'Fetch price and dividend data from Polygon.io in the background when new symbols are created');
+
+-- Insert default OPTION_RETURNS_BASIS setting
+INSERT OR IGNORE INTO settings (name, value, description) 
+VALUES ('OPTION_RETURNS_BASIS', 'net', 'Compute option profit, ROI and AROI net of commission (net) or before commission (gross)');
+
+-- Insert default IMPORT_DEFAULT_COMMISSION_PER_CONTRACT setting
+INSERT OR IGNORE INTO settings (name, value, description) 
+VALUES ('IMPORT_DEFAULT_COMMISSION_PER_CONTRACT', '0.65', 'Commission per contract per side applied to options imported from CSV files without a commission column');
+
+-- Insert default MARKET_TIMEZONE setting
+INSERT OR IGNORE INTO settings (name, value, description) 
+VALUES ('MARKET_TIMEZONE', 'America/New_York', 'IANA timezone used to decide the current market date for metric snapshots');
+
+-- Insert default EXPIRING_MONEYNESS_BAND_PERCENT setting
+INSERT OR IGNORE INTO settings (name, value, description) 
+VALUES ('EXPIRING_MONEYNESS_BAND_PERCENT', '2', 'Options expiring within this percent of the underlying price are flagged to roll in the expiring-week view');
+
+-- Insert default ASSIGNMENT_BUY_PRICE_MODE setting
+INSERT OR IGNORE INTO settings (name, value, description) 
+VALUES ('ASSIGNMENT_BUY_PRICE_MODE', 'strike', 'Buy price for lots created by put assignment: strike (premium applied via cost basis adjustment) or net_premium (strike minus premium)');
+
+-- Insert default Greeks fetch limits for the owned-options view
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('GREEKS_FETCH_CONCURRENCY', '4', 'Maximum concurrent Polygon Greeks requests when loading owned options');
+
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('GREEKS_FETCH_TIMEOUT_SECONDS', '20', 'Overall timeout in seconds for fetching Polygon Greeks for owned options');
+
+-- Insert default navigation symbol list settings
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('NAV_ACTIVE_SYMBOLS_ONLY', 'false', 'Limit the navigation symbol list to symbols with open positions or recent activity (false shows all symbols; add ?all=1 to a page URL to show all symbols on that view)');
+
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('NAV_SYMBOL_LOOKBACK_DAYS', '90', 'Days of trade or dividend activity that keep a closed-out symbol in the navigation list');
+
+-- Insert default IMPORT_NUMBER_LOCALE setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('IMPORT_NUMBER_LOCALE', 'en', 'Number format of imported CSV files: en (1,234.56) or eu (1.234,56)');
+
+-- Insert default WAL_CHECKPOINT_INTERVAL_MINUTES setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('WAL_CHECKPOINT_INTERVAL_MINUTES', '30', 'Minutes between automatic passive WAL checkpoints (0 disables)');
+
+-- Insert default INCLUDE_TREASURIES_IN_TOTAL setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('INCLUDE_TREASURIES_IN_TOTAL', 'true', 'Include treasuries in the total value metric and dashboard portfolio total (false reports equity and options only)');
+
+-- Insert default PERCENT_DECIMALS setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('PERCENT_DECIMALS', '2', 'Decimal places percentages (ROI, AROI, percent of profit/time, percent OTM) are rounded to in API responses');
+
+-- Insert default MAX_UPLOAD_MB setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('MAX_UPLOAD_MB', '10', 'Maximum size in megabytes of an uploaded import CSV file');
+
+-- Insert default PRESERVE_PRICE_ON_ZERO setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('PRESERVE_PRICE_ON_ZERO', 'true', 'Keep the last known price when Polygon returns a zero or empty price for a symbol (false stores the zero)');
+
+-- Insert default ALLOW_PENDING_TRADES setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('ALLOW_PENDING_TRADES', 'false', 'Accept options and long positions with a future opened date as pending planned trades, excluded from open positions and metrics until that date');
+
+-- Insert default OPTIONS_SUMMARY_SORT setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('OPTIONS_SUMMARY_SORT', 'symbol', 'Default order of the options summary by symbol: symbol, profit, exposure or count (overridable with ?sort=)');
+
+-- Insert default SNAP_NON_TRADING_EXPIRATIONS setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('SNAP_NON_TRADING_EXPIRATIONS', 'false', 'Move option expirations entered on a weekend or market holiday back to the prior trading day; when off they are kept and flagged in the data-quality report');
+
+-- Insert default DATABASE_SCOPE setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('DATABASE_SCOPE', 'global', 'Whether switching databases applies to every browser (global) or only the current browser session via a cookie (session); read from the globally selected database');
+
+-- Insert default BROKER_PROFILE setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('BROKER_PROFILE', '', 'Broker commission and fee schedule (tasty, schwab or ibkr) applied to options entered or imported without a commission; blank uses $0.65 per contract');
+
+-- Insert default AUTO_UPDATE_PRICES_ON_DASHBOARD setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('AUTO_UPDATE_PRICES_ON_DASHBOARD', 'false', 'Refresh prices of active-position symbols in the background when the dashboard loads and they are older than PRICE_STALE_MINUTES (uses Polygon API calls)');
+
+-- Insert default PRICE_STALE_MINUTES setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('PRICE_STALE_MINUTES', '60', 'Age in minutes after which a stored symbol price is considered stale for automatic updates');
+
+-- Insert default MIN_PRICE_UPDATE_INTERVAL_MINUTES setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('MIN_PRICE_UPDATE_INTERVAL_MINUTES', '1', 'Minimum minutes between Polygon price updates of a symbol, judged by its last update; more frequent updates are skipped unless forced (0 disables the check)');
+
+-- Insert default SPLIT_ROUNDING setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('SPLIT_ROUNDING', 'cash_in_lieu', 'How a stock split that leaves a fractional share is handled: cash_in_lieu (round down and realize the remainder at the cash-in-lieu price, which is then required). Lots hold whole shares, so keeping fractional shares is not supported yet');
+
+-- Insert default SNAPSHOT_SCHEDULE_TIME setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('SNAPSHOT_SCHEDULE_TIME', '', 'Daily time (HH:MM, market timezone) to snapshot metrics automatically; blank disables the scheduled snapshot');
+
+-- Insert default SNAPSHOT_RETRY_ATTEMPTS setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('SNAPSHOT_RETRY_ATTEMPTS', '3', 'Attempts a scheduled snapshot makes when the database is locked (e.g. by a backup) before it is recorded as failed');
+
+-- Insert default SNAPSHOT_RETRY_BACKOFF_SECONDS setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('SNAPSHOT_RETRY_BACKOFF_SECONDS', '5', 'Wait before the first scheduled snapshot retry; doubles after each locked attempt');
+
+-- Insert default RISK_FREE_RATE_PERCENT setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('RISK_FREE_RATE_PERCENT', '5', 'Annual risk-free rate in percent used for risk-adjusted return (Sharpe-like) calculations');
+
+-- Insert default treasury yield settings
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('TREASURY_YIELD_INPUT_SCALE', 'auto', 'How imported treasury yields and coupon rates are read before being stored in percent: auto (a % sign or a value of 1 or more is percent, a bare value below 1 is a decimal such as 0.045), percent or decimal');
+
+-- Insert default ANNUALIZATION_BASIS setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('ANNUALIZATION_BASIS', 'calendar', 'Day count for annualized returns (AROI, premium yield, capital efficiency, income yield): calendar (days held over 365.25) or trading (trading days held over 252)');
+
+-- Insert default BASIS_COMMISSION_MODE setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('BASIS_COMMISSION_MODE', 'exclude', 'Commission in adjusted cost basis: exclude (premium before commission reduces basis; commission is a separate expense) or net (premium net of commission reduces basis)');
+
+-- Insert default BASIS_DEFER_OPEN_CALL_PREMIUM setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('BASIS_DEFER_OPEN_CALL_PREMIUM', 'false', 'Keep open covered call premium out of the persisted adjusted basis and show it as a provisional cost basis instead (display only). Off: open calls adjust the persisted basis like closed ones');
+
+-- Insert default PUT_CONCENTRATION_ALERT_PERCENT setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('PUT_CONCENTRATION_ALERT_PERCENT', '25', 'Alert when a single symbol holds more than this percent of total open put exposure');
+
+-- Insert default automatic backup settings
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('BACKUP_INTERVAL_HOURS', '0', 'Hours between automatic backups of the active database (0 disables automatic backups)');
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('BACKUP_RETENTION_COUNT', '7', 'Number of automatic backups kept per database; older ones are removed (0 keeps all, manual backups are never removed)');
+
+-- Insert default IBKR_UNKNOWN_RIGHT_HANDLING setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('IBKR_UNKNOWN_RIGHT_HANDLING', 'skip', 'IBKR Greeks entries whose right/type is not C, P, CALL or PUT: skip (report them in the response) or match (use the one open option with the same symbol, strike and expiration)');
+
+-- Insert default CALL_COVERAGE_TRACKING setting
+INSERT OR IGNORE INTO settings (name, value, description)
+VALUES ('CALL_COVERAGE_TRACKING', 'false', 'Store which long lots each covered call is written against (FIFO, as in adjusted cost basis) whenever cost basis is recalculated');
+
 -- Indexes for performance
 -- Note: Primary key columns automatically have indexes, so we don't need explicit indexes for:
 -- symbols(symbol), treasuries(cuspid), settings(name) - they have PRIMARY KEY
 -- Foreign key and commonly queried columns:
 CREATE INDEX IF NOT EXISTS idx_long_positions_symbol ON long_positions(symbol);
 CREATE INDEX IF NOT EXISTS idx_long_positions_opened ON long_positions(opened);
+CREATE INDEX IF NOT EXISTS idx_long_position_exits_position ON long_position_exits(position_id);
+CREATE INDEX IF NOT EXISTS idx_stock_splits_symbol ON stock_splits(symbol);
 CREATE INDEX IF NOT EXISTS idx_options_symbol ON options(symbol);
 CREATE INDEX IF NOT EXISTS idx_options_expiration ON options(expiration);
 CREATE INDEX IF NOT EXISTS idx_options_type ON options(type);
@@ -96,10 +360,14 @@ CREATE INDEX IF NOT EXISTS idx_dividends_symbol ON dividends(symbol);
 CREATE INDEX IF NOT EXISTS idx_dividends_received ON dividends(received);
 CREATE INDEX IF NOT EXISTS idx_treasuries_maturity ON treasuries(maturity);
 CREATE INDEX IF NOT EXISTS idx_treasuries_purchased ON treasuries(purchased);
+CREATE INDEX IF NOT EXISTS idx_treasury_coupons_cuspid ON treasury_coupons(cuspid);
+CREATE INDEX IF NOT EXISTS idx_greeks_snapshots_option ON greeks_snapshots(option_id);
+CREATE INDEX IF NOT EXISTS idx_call_coverage_position ON call_coverage(position_id);
 CREATE INDEX IF NOT EXISTS idx_metrics_created ON metrics(created);
 CREATE INDEX IF NOT EXISTS idx_metrics_type ON metrics(type);
 
 -- Unique constraints to prevent duplicate business records
 -- (These replace the compound primary keys while allowing easier HTTP CRUD with integer IDs)
-CREATE UNIQUE INDEX IF NOT EXISTS idx_options_unique ON options(symbol, type, opened, strike, expiration, premium, contracts);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_options_unique ON options(symbol, type, opened, strike, expiration, premium, contracts, account);
 CREATE UNIQUE INDEX IF NOT EXISTS idx_dividends_unique ON dividends(symbol, received, amount);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_greeks_snapshots_unique ON greeks_snapshots(option_id, captured);
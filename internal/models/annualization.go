@@ -0,0 +1,59 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Day-count bases for annualizing returns (ANNUALIZATION_BASIS setting)
+const (
+	// AnnualizationCalendar scales a return by calendar days held over 365.25
+	AnnualizationCalendar = "calendar"
+	// AnnualizationTrading scales a return by trading sessions held over TradingDaysPerYear
+	AnnualizationTrading = "trading"
+)
+
+// CalendarDaysPerYear annualizes returns measured in calendar days
+const CalendarDaysPerYear = 365.25
+
+// NormalizeAnnualizationBasis returns the basis named by value, defaulting to calendar
+func NormalizeAnnualizationBasis(value string) string {
+	if strings.EqualFold(strings.TrimSpace(value), AnnualizationTrading) {
+		return AnnualizationTrading
+	}
+	return AnnualizationCalendar
+}
+
+// DaysPerYear returns the length of a year on an annualization basis
+func DaysPerYear(basis string) float64 {
+	if basis == AnnualizationTrading {
+		return TradingDaysPerYear
+	}
+	return CalendarDaysPerYear
+}
+
+// AnnualizationFactor returns the multiplier that annualizes a return earned from one date to
+// another. calendarDays is the period as the caller measures it and is used on the calendar basis;
+// the trading basis instead counts the trading sessions after from through to, at least one.
+func AnnualizationFactor(basis string, from, to time.Time, calendarDays float64) float64 {
+	if basis == AnnualizationTrading {
+		return TradingDaysPerYear / float64(max(TradingDaysBetween(from, to), 1))
+	}
+	return CalendarDaysPerYear / calendarDays
+}
+
+// TradingDaysBetween counts the trading days after from up to and including to, by calendar date
+func TradingDaysBetween(from, to time.Time) int {
+	fy, fm, fd := from.Date()
+	ty, tm, td := to.Date()
+	day := time.Date(fy, fm, fd, 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	last := time.Date(ty, tm, td, 0, 0, 0, 0, time.UTC)
+
+	count := 0
+	for ; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if IsTradingDay(day) {
+			count++
+		}
+	}
+	return count
+}
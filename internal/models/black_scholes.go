@@ -0,0 +1,81 @@
+package models
+
+import (
+	"math"
+	"strings"
+)
+
+// TheoreticalValue is a Black-Scholes price per share and its Greeks. Theta is per calendar day,
+// vega per one volatility point and rho per one percentage point of the risk-free rate, matching
+// the units Polygon reports.
+type TheoreticalValue struct {
+	Price     float64 `json:"price"`
+	Intrinsic float64 `json:"intrinsic"`
+	Extrinsic float64 `json:"extrinsic"`
+	Delta     float64 `json:"delta"`
+	Gamma     float64 `json:"gamma"`
+	Theta     float64 `json:"theta"`
+	Vega      float64 `json:"vega"`
+	Rho       float64 `json:"rho"`
+}
+
+// IntrinsicValue returns an option's exercise value per share at the given underlying price
+func IntrinsicValue(optionType string, underlying, strike float64) float64 {
+	if strings.EqualFold(optionType, "Call") {
+		return math.Max(underlying-strike, 0)
+	}
+	return math.Max(strike-underlying, 0)
+}
+
+// BlackScholes prices a European option per share. years is the time to expiration and vol and
+// rate are annual decimals. With no time or no volatility left the option is worth its intrinsic
+// value (discounted forward intrinsic when only volatility is zero) and delta is 0 or +/-1.
+func BlackScholes(optionType string, underlying, strike, years, vol, rate float64) TheoreticalValue {
+	isCall := strings.EqualFold(optionType, "Call")
+	value := TheoreticalValue{Intrinsic: IntrinsicValue(optionType, underlying, strike)}
+
+	if years <= 0 || vol <= 0 {
+		discount := 1.0
+		if years > 0 {
+			discount = math.Exp(-rate * years)
+		}
+		forward := IntrinsicValue(optionType, underlying, strike*discount)
+		value.Price = forward
+		switch {
+		case forward > 0 && isCall:
+			value.Delta = 1
+		case forward > 0:
+			value.Delta = -1
+		}
+		value.Extrinsic = value.Price - value.Intrinsic
+		return value
+	}
+
+	sqrtT := math.Sqrt(years)
+	d1 := (math.Log(underlying/strike) + (rate+0.5*vol*vol)*years) / (vol * sqrtT)
+	d2 := d1 - vol*sqrtT
+	discount := math.Exp(-rate * years)
+	pdf := math.Exp(-0.5*d1*d1) / math.Sqrt(2*math.Pi)
+
+	value.Gamma = pdf / (underlying * vol * sqrtT)
+	value.Vega = underlying * pdf * sqrtT / 100
+	decay := -underlying * pdf * vol / (2 * sqrtT)
+	if isCall {
+		value.Price = underlying*standardNormalCDF(d1) - strike*discount*standardNormalCDF(d2)
+		value.Delta = standardNormalCDF(d1)
+		value.Theta = (decay - rate*strike*discount*standardNormalCDF(d2)) / 365
+		value.Rho = strike * years * discount * standardNormalCDF(d2) / 100
+	} else {
+		value.Price = strike*discount*standardNormalCDF(-d2) - underlying*standardNormalCDF(-d1)
+		value.Delta = standardNormalCDF(d1) - 1
+		value.Theta = (decay + rate*strike*discount*standardNormalCDF(-d2)) / 365
+		value.Rho = -strike * years * discount * standardNormalCDF(-d2) / 100
+	}
+	value.Extrinsic = value.Price - value.Intrinsic
+	return value
+}
+
+// standardNormalCDF is the cumulative distribution function of the standard normal distribution
+func standardNormalCDF(x float64) float64 {
+	return 0.5 * (1 + math.Erf(x/math.Sqrt2))
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// BrokerProfile is a broker's option commission and fee schedule, per contract. Fees cover
+// exchange, clearing and regulatory charges and apply on both sides of the trade.
+type BrokerProfile struct {
+	Name                         string  `json:"name"`
+	OpeningCommissionPerContract float64 `json:"opening_commission_per_contract"`
+	OpeningCommissionCap         float64 `json:"opening_commission_cap,omitempty"` // most commission charged per opening leg; 0 means uncapped
+	ClosingCommissionPerContract float64 `json:"closing_commission_per_contract"`
+	FeesPerContract              float64 `json:"fees_per_contract"`
+}
+
+// BrokerProfiles are the built-in schedules selectable with BROKER_PROFILE. Rates are the
+// brokers' published retail pricing and approximate; regulatory fees vary by trade.
+var BrokerProfiles = map[string]BrokerProfile{
+	"tasty": {
+		Name:                         "tasty",
+		OpeningCommissionPerContract: 1.00, // closing trades are free
+		OpeningCommissionCap:         10.00,
+		ClosingCommissionPerContract: 0,
+		FeesPerContract:              0.14,
+	},
+	"schwab": {
+		Name:                         "schwab",
+		OpeningCommissionPerContract: 0.65,
+		ClosingCommissionPerContract: 0.65,
+		FeesPerContract:              0.02,
+	},
+	"ibkr": {
+		Name:                         "ibkr",
+		OpeningCommissionPerContract: 0.65,
+		ClosingCommissionPerContract: 0.65,
+		FeesPerContract:              0.05,
+	},
+}
+
+// LookupBrokerProfile returns the built-in profile for a name, matched case-insensitively
+func LookupBrokerProfile(name string) (BrokerProfile, bool) {
+	profile, ok := BrokerProfiles[strings.ToLower(strings.TrimSpace(name))]
+	return profile, ok
+}
+
+// BrokerProfileNames lists the built-in profile names in alphabetical order
+func BrokerProfileNames() []string {
+	names := make([]string, 0, len(BrokerProfiles))
+	for name := range BrokerProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpeningCommission returns the commission and fees for opening the given number of contracts.
+// The commission stops at OpeningCommissionCap when one is set; fees are never capped.
+func (p BrokerProfile) OpeningCommission(contracts int) float64 {
+	commission := p.OpeningCommissionPerContract * float64(contracts)
+	if p.OpeningCommissionCap > 0 && commission > p.OpeningCommissionCap {
+		commission = p.OpeningCommissionCap
+	}
+	return commission + p.FeesPerContract*float64(contracts)
+}
+
+// ClosingCommission returns the commission and fees for closing the given number of contracts
+func (p BrokerProfile) ClosingCommission(contracts int) float64 {
+	return (p.ClosingCommissionPerContract + p.FeesPerContract) * float64(contracts)
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBrokerProfileOpeningCommissionCap(t *testing.T) {
+	tasty, ok := LookupBrokerProfile("tasty")
+	if !ok {
+		t.Fatalf("expected the tasty profile")
+	}
+	schwab, _ := LookupBrokerProfile("schwab")
+
+	for _, tc := range []struct {
+		profile   BrokerProfile
+		contracts int
+		want      float64
+	}{
+		{tasty, 1, 1.14},   // $1.00 commission + $0.14 fees
+		{tasty, 10, 11.40}, // exactly at the $10 cap
+		{tasty, 50, 17.00}, // commission capped at $10, fees 50 * $0.14 = $7
+		{schwab, 50, 33.50},
+	} {
+		if got := tc.profile.OpeningCommission(tc.contracts); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s opening %d contracts: expected %.2f, got %.2f", tc.profile.Name, tc.contracts, tc.want, got)
+		}
+	}
+
+	// Closing is never capped; tasty closes commission-free and only pays fees
+	if got := tasty.ClosingCommission(50); math.Abs(got-7.00) > 1e-9 {
+		t.Errorf("expected tasty closing fees 7.00, got %.2f", got)
+	}
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"time"
+	_ "time/tzdata" // embedded so market timezones resolve in minimal containers
+)
+
+// DefaultMarketTimezone is the exchange timezone used when MARKET_TIMEZONE is not configured
+const DefaultMarketTimezone = "America/New_York"
+
+// marketNow returns the current time in the timezone location reports, or server-local time when
+// location is unset or returns nil
+func marketNow(location func() *time.Location) time.Time {
+	if location != nil {
+		if loc := location(); loc != nil {
+			return time.Now().In(loc)
+		}
+	}
+	return time.Now()
+}
+
+// Expiration classes for listed equity options
+const (
+	ExpirationWeekly    = "weekly"
+	ExpirationMonthly   = "monthly"
+	ExpirationQuarterly = "quarterly"
+	ExpirationLEAPS     = "leaps"
+)
+
+// ClassifyExpiration labels an expiration as LEAPS (more than a year out when opened), the standard
+// monthly (third Friday, or the prior trading day when that Friday is a holiday), an end-of-quarter
+// expiration, or otherwise a weekly.
+func ClassifyExpiration(opened, expiration time.Time) string {
+	if !opened.IsZero() && expiration.After(opened.AddDate(1, 0, 0)) {
+		return ExpirationLEAPS
+	}
+
+	year, month, _ := expiration.Date()
+	if sameCalendarDate(expiration, StandardMonthlyExpiration(year, month)) {
+		return ExpirationMonthly
+	}
+	if month%3 == 0 && sameCalendarDate(expiration, PreviousTradingDay(calendarDate(year, month+1, 1).AddDate(0, 0, -1))) {
+		return ExpirationQuarterly
+	}
+	return ExpirationWeekly
+}
+
+// StandardMonthlyExpiration returns the standard monthly expiration for a month: the third
+// Friday, shifted back to the previous trading day when it falls on a market holiday
+func StandardMonthlyExpiration(year int, month time.Month) time.Time {
+	return PreviousTradingDay(nthWeekday(year, month, time.Friday, 3))
+}
+
+func sameCalendarDate(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// IsTradingDay reports whether US equity/option markets are open on the given date.
+// Weekends and NYSE full-day holidays (with weekend observance) are excluded.
+func IsTradingDay(date time.Time) bool {
+	switch date.Weekday() {
+	case time.Saturday, time.Sunday:
+		return false
+	}
+	return !IsMarketHoliday(date)
+}
+
+// IsMarketHoliday reports whether the given date is an observed NYSE full-day holiday
+func IsMarketHoliday(date time.Time) bool {
+	year, month, day := date.Date()
+	for _, holiday := range marketHolidays(year) {
+		if holiday.Month() == month && holiday.Day() == day {
+			return true
+		}
+	}
+	return false
+}
+
+// PreviousTradingDay returns the closest trading day on or before the given date
+func PreviousTradingDay(date time.Time) time.Time {
+	for !IsTradingDay(date) {
+		date = date.AddDate(0, 0, -1)
+	}
+	return date
+}
+
+// marketHolidays returns the observed NYSE full-day holidays for a year
+func marketHolidays(year int) []time.Time {
+	holidays := []time.Time{
+		nthWeekday(year, time.January, time.Monday, 3),    // Martin Luther King Jr. Day
+		nthWeekday(year, time.February, time.Monday, 3),   // Presidents' Day
+		easterSunday(year).AddDate(0, 0, -2),              // Good Friday
+		lastWeekday(year, time.May, time.Monday),          // Memorial Day
+		observed(calendarDate(year, time.July, 4)),        // Independence Day
+		nthWeekday(year, time.September, time.Monday, 1),  // Labor Day
+		nthWeekday(year, time.November, time.Thursday, 4), // Thanksgiving
+		observed(calendarDate(year, time.December, 25)),   // Christmas
+	}
+
+	// New Year's Day falling on a Saturday is not observed on the prior Friday
+	newYears := calendarDate(year, time.January, 1)
+	if newYears.Weekday() != time.Saturday {
+		holidays = append(holidays, observed(newYears))
+	}
+
+	// Juneteenth became a market holiday in 2022
+	if year >= 2022 {
+		holidays = append(holidays, observed(calendarDate(year, time.June, 19)))
+	}
+
+	return holidays
+}
+
+func calendarDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// observed shifts Saturday holidays to Friday and Sunday holidays to Monday
+func observed(t time.Time) time.Time {
+	switch t.Weekday() {
+	case time.Saturday:
+		return t.AddDate(0, 0, -1)
+	case time.Sunday:
+		return t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	t := calendarDate(year, month, 1)
+	for t.Weekday() != weekday {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t.AddDate(0, 0, 7*(n-1))
+}
+
+func lastWeekday(year int, month time.Month, weekday time.Weekday) time.Time {
+	t := calendarDate(year, month+1, 1).AddDate(0, 0, -1)
+	for t.Weekday() != weekday {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t
+}
+
+// easterSunday computes Easter using the anonymous Gregorian algorithm
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := ((h + l - 7*m + 114) % 31) + 1
+	return calendarDate(year, time.Month(month), day)
+}
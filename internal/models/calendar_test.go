@@ -0,0 +1,107 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsTradingDayKnownNYSEHolidays(t *testing.T) {
+	tests := []struct {
+		name    string
+		date    string
+		trading bool
+	}{
+		{"New Year's Day", "2025-01-01", false},
+		{"New Year's Day on Sunday observed Monday", "2023-01-02", false},
+		{"New Year's Day on Saturday not observed Friday", "2021-12-31", true},
+		{"Martin Luther King Jr. Day", "2025-01-20", false},
+		{"Presidents' Day", "2025-02-17", false},
+		{"Good Friday", "2024-03-29", false},
+		{"Good Friday in April", "2025-04-18", false},
+		{"Thursday before Good Friday", "2025-04-17", true},
+		{"Memorial Day", "2025-05-26", false},
+		{"Juneteenth on Sunday observed Monday", "2022-06-20", false},
+		{"Juneteenth before it was a market holiday", "2021-06-18", true},
+		{"Independence Day", "2025-07-04", false},
+		{"Independence Day on Saturday observed Friday", "2026-07-03", false},
+		{"Independence Day on Sunday observed Monday", "2021-07-05", false},
+		{"Day before Independence Day", "2025-07-03", true},
+		{"Labor Day", "2025-09-01", false},
+		{"Thanksgiving", "2025-11-27", false},
+		{"Christmas on Sunday observed Monday", "2022-12-26", false},
+		{"Christmas", "2025-12-25", false},
+		{"Ordinary Monday", "2026-07-06", true},
+		{"Saturday", "2026-07-04", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, err := time.Parse("2006-01-02", tt.date)
+			if err != nil {
+				t.Fatalf("invalid test date %q: %v", tt.date, err)
+			}
+			if got := IsTradingDay(date); got != tt.trading {
+				t.Fatalf("IsTradingDay(%s) = %v, want %v", tt.date, got, tt.trading)
+			}
+		})
+	}
+}
+
+func TestStandardMonthlyExpiration(t *testing.T) {
+	tests := []struct {
+		name  string
+		year  int
+		month time.Month
+		want  string
+	}{
+		{"ordinary third Friday", 2025, time.May, "2025-05-16"},
+		{"Good Friday shifts to Thursday", 2025, time.April, "2025-04-17"},
+		{"Juneteenth shifts to Thursday", 2026, time.June, "2026-06-18"},
+		{"month wraps into the next year", 2025, time.December + 1, "2026-01-16"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StandardMonthlyExpiration(tt.year, tt.month).Format("2006-01-02"); got != tt.want {
+				t.Fatalf("StandardMonthlyExpiration(%d, %d) = %s, want %s", tt.year, tt.month, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyExpiration(t *testing.T) {
+	tests := []struct {
+		name       string
+		opened     string
+		expiration string
+		want       string
+	}{
+		{"third Friday monthly", "2025-04-01", "2025-05-16", ExpirationMonthly},
+		{"Good Friday-shifted monthly on Thursday", "2025-03-03", "2025-04-17", ExpirationMonthly},
+		{"Friday weekly", "2025-04-01", "2025-04-25", ExpirationWeekly},
+		{"Thursday weekly before a holiday that is not the monthly", "2025-06-02", "2025-07-03", ExpirationWeekly},
+		{"quarter end on a Monday", "2025-03-03", "2025-03-31", ExpirationQuarterly},
+		{"quarter end shifted off a weekend and Good Friday", "2024-03-01", "2024-03-28", ExpirationQuarterly},
+		{"last day of a month that does not end a quarter", "2025-04-01", "2025-04-30", ExpirationWeekly},
+		{"LEAPS more than a year out", "2025-01-02", "2026-01-16", ExpirationLEAPS},
+		{"monthly exactly a year out is not LEAPS", "2025-01-17", "2026-01-16", ExpirationMonthly},
+		{"unknown open date", "", "2027-01-15", ExpirationMonthly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opened time.Time
+			if tt.opened != "" {
+				parsed, err := time.Parse("2006-01-02", tt.opened)
+				if err != nil {
+					t.Fatalf("invalid test date %q: %v", tt.opened, err)
+				}
+				opened = parsed
+			}
+			expiration, err := time.Parse("2006-01-02", tt.expiration)
+			if err != nil {
+				t.Fatalf("invalid test date %q: %v", tt.expiration, err)
+			}
+			if got := ClassifyExpiration(opened, expiration); got != tt.want {
+				t.Fatalf("ClassifyExpiration(%s, %s) = %s, want %s", tt.opened, tt.expiration, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CallCoverage records the shares of one long lot a covered call was written against. Rows are
+// written by RecalculateAdjustedCostBasisForSymbol with the same FIFO allocation that spreads call
+// premium across lots, and only while CALL_COVERAGE_TRACKING is enabled.
+type CallCoverage struct {
+	OptionID   int        `json:"option_id"`
+	PositionID int        `json:"position_id"`
+	Shares     int        `json:"shares"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// SetCallCoverageTracking registers a callback reporting whether CALL_COVERAGE_TRACKING is on.
+// It is read on every recalculation; without one, coverage is not persisted.
+func (s *LongPositionService) SetCallCoverageTracking(enabled func() bool) {
+	s.coverageTracking = enabled
+}
+
+// CallCoverageTracked reports whether call-to-lot coverage is being persisted
+func (s *LongPositionService) CallCoverageTracked() bool {
+	return s.coverageTracking != nil && s.coverageTracking()
+}
+
+// replaceCallCoverage swaps the stored coverage of a symbol's calls for a fresh allocation
+func replaceCallCoverage(tx *sql.Tx, symbol string, coverage []CallCoverage) error {
+	if _, err := tx.Exec(`DELETE FROM call_coverage WHERE option_id IN (SELECT id FROM options WHERE symbol = ?)`, symbol); err != nil {
+		return fmt.Errorf("failed to clear call coverage: %w", err)
+	}
+	for _, c := range coverage {
+		if _, err := tx.Exec(`INSERT INTO call_coverage (option_id, position_id, shares) VALUES (?, ?, ?)`, c.OptionID, c.PositionID, c.Shares); err != nil {
+			return fmt.Errorf("failed to save call coverage: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetCallCoverage returns the lots a call was written against, oldest lot first
+func (s *LongPositionService) GetCallCoverage(optionID int) ([]*CallCoverage, error) {
+	return s.queryCallCoverage(`SELECT c.option_id, c.position_id, c.shares, c.updated_at
+		FROM call_coverage c JOIN long_positions p ON p.id = c.position_id
+		WHERE c.option_id = ? ORDER BY p.opened ASC, p.id ASC`, optionID)
+}
+
+// GetOpenCallCoverage returns the stored coverage of every open call
+func (s *LongPositionService) GetOpenCallCoverage() ([]*CallCoverage, error) {
+	return s.queryCallCoverage(`SELECT c.option_id, c.position_id, c.shares, c.updated_at
+		FROM call_coverage c JOIN options o ON o.id = c.option_id
+		WHERE o.closed IS NULL AND o.status != 'canceled' ORDER BY c.option_id, c.position_id`)
+}
+
+func (s *LongPositionService) queryCallCoverage(query string, args ...interface{}) ([]*CallCoverage, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query call coverage: %w", err)
+	}
+	defer rows.Close()
+
+	var coverage []*CallCoverage
+	for rows.Next() {
+		var (
+			c         CallCoverage
+			updatedAt time.Time
+		)
+		if err := rows.Scan(&c.OptionID, &c.PositionID, &c.Shares, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan call coverage: %w", err)
+		}
+		c.UpdatedAt = &updatedAt
+		coverage = append(coverage, &c)
+	}
+	return coverage, rows.Err()
+}
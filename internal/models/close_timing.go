@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// CloseTimingBucket aggregates closed options whose share of time held falls in one range
+type CloseTimingBucket struct {
+	Label                string  `json:"label"` // e.g. "25-50%"
+	MinPercentOfTime     float64 `json:"min_percent_of_time"`
+	MaxPercentOfTime     float64 `json:"max_percent_of_time"`
+	Trades               int     `json:"trades"`
+	AvgPercentOfTime     float64 `json:"avg_percent_of_time"`
+	AvgPercentOfProfit   float64 `json:"avg_percent_of_profit"`
+	ProfitCapturePerTime float64 `json:"profit_capture_per_time"` // avg percent of profit / avg percent of time
+	TotalProfit          float64 `json:"total_profit"`
+}
+
+// SummarizeCloseTiming buckets closed options of one type by CalculatePercentOfTime and averages
+// CalculatePercentOfProfit in each bucket. Assigned options are skipped since their captured
+// premium does not reflect a closing decision. bucketSize is in percentage points of time held;
+// TotalProfit is before commission and fees when gross.
+func SummarizeCloseTiming(options []*Option, optionType string, bucketSize float64, gross bool) []CloseTimingBucket {
+	count := int(math.Ceil(100 / bucketSize))
+	buckets := make([]CloseTimingBucket, count)
+	for i := range buckets {
+		low := float64(i) * bucketSize
+		high := math.Min(low+bucketSize, 100)
+		buckets[i] = CloseTimingBucket{
+			Label:            fmt.Sprintf("%g-%g%%", low, high),
+			MinPercentOfTime: low,
+			MaxPercentOfTime: high,
+		}
+	}
+
+	for _, option := range options {
+		if option.Closed == nil || option.Assigned || option.Type != optionType {
+			continue
+		}
+		percentOfTime := option.CalculatePercentOfTime()
+		index := int(percentOfTime / bucketSize)
+		if index >= count {
+			index = count - 1 // held to expiration lands in the last bucket
+		}
+		bucket := &buckets[index]
+		bucket.Trades++
+		bucket.AvgPercentOfTime += percentOfTime
+		bucket.AvgPercentOfProfit += option.CalculatePercentOfProfit()
+		bucket.TotalProfit += option.RealizedProfitOnBasis(gross)
+	}
+
+	for i := range buckets {
+		bucket := &buckets[i]
+		if bucket.Trades == 0 {
+			continue
+		}
+		bucket.AvgPercentOfTime /= float64(bucket.Trades)
+		bucket.AvgPercentOfProfit /= float64(bucket.Trades)
+		if bucket.AvgPercentOfTime > 0 {
+			bucket.ProfitCapturePerTime = bucket.AvgPercentOfProfit / bucket.AvgPercentOfTime
+		}
+	}
+	return buckets
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Standard listed equity option terms, assumed when vendor contract details are unavailable
+const (
+	DefaultSharesPerContract = 100
+	DefaultExerciseStyle     = "american"
+)
+
+// Contract details sources
+const (
+	ContractDetailsSourcePolygon = "polygon"
+	ContractDetailsSourceDefault = "default"
+)
+
+// OptionContractDetails holds the vendor terms of an option contract. Adjusted contracts (for
+// example after a split) can deliver a non-100 number of shares.
+type OptionContractDetails struct {
+	OptionID          int        `json:"option_id"`
+	Contract          string     `json:"contract"`
+	ExerciseStyle     string     `json:"exercise_style"`
+	SharesPerContract float64    `json:"shares_per_contract"`
+	Source            string     `json:"source"`
+	UpdatedAt         *time.Time `json:"updated_at,omitempty"`
+}
+
+// DefaultContractDetails returns standard 100-share American-style terms for an option
+func DefaultContractDetails(optionID int) *OptionContractDetails {
+	return &OptionContractDetails{
+		OptionID:          optionID,
+		ExerciseStyle:     DefaultExerciseStyle,
+		SharesPerContract: DefaultSharesPerContract,
+		Source:            ContractDetailsSourceDefault,
+	}
+}
+
+// IsStandard reports whether the contract delivers 100 shares with American-style exercise
+func (d *OptionContractDetails) IsStandard() bool {
+	return math.Abs(d.SharesPerContract-DefaultSharesPerContract) < 1e-9 && strings.EqualFold(d.ExerciseStyle, DefaultExerciseStyle)
+}
+
+// SaveContractDetails stores or replaces the vendor contract details for an option
+func (s *OptionService) SaveContractDetails(details *OptionContractDetails) error {
+	_, err := s.db.Exec(`INSERT INTO option_contract_details (option_id, contract, exercise_style, shares_per_contract, source)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(option_id) DO UPDATE SET contract = excluded.contract, exercise_style = excluded.exercise_style,
+			shares_per_contract = excluded.shares_per_contract, source = excluded.source, updated_at = CURRENT_TIMESTAMP`,
+		details.OptionID, details.Contract, strings.ToLower(details.ExerciseStyle), details.SharesPerContract, details.Source)
+	if err != nil {
+		return fmt.Errorf("failed to save contract details: %w", err)
+	}
+	return nil
+}
+
+// GetContractDetails returns the stored contract details for an option, or nil when none were fetched
+func (s *OptionService) GetContractDetails(optionID int) (*OptionContractDetails, error) {
+	var details OptionContractDetails
+	var updatedAt time.Time
+	err := s.db.QueryRow(`SELECT option_id, contract, exercise_style, shares_per_contract, source, updated_at
+		FROM option_contract_details WHERE option_id = ?`, optionID).Scan(
+		&details.OptionID, &details.Contract, &details.ExerciseStyle, &details.SharesPerContract, &details.Source, &updatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contract details: %w", err)
+	}
+	details.UpdatedAt = &updatedAt
+	return &details, nil
+}
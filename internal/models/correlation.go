@@ -0,0 +1,70 @@
+package models
+
+import (
+	"math"
+	"sort"
+)
+
+// ReturnCorrelation is the Pearson correlation of two symbols' daily returns over the sessions
+// both have a return for
+type ReturnCorrelation struct {
+	SymbolA      string  `json:"symbol_a"`
+	SymbolB      string  `json:"symbol_b"`
+	Correlation  float64 `json:"correlation"`
+	Observations int     `json:"observations"`
+}
+
+// DailyReturns turns a date-ordered close series into simple returns keyed by the interval they
+// cover ("prev|date"). Keying by both ends means two symbols only line up on a return when they
+// were priced on the same pair of sessions, so a day missing from one series never pairs a
+// one-day move with a multi-day one.
+func DailyReturns(closes []PriceClose) map[string]float64 {
+	returns := make(map[string]float64)
+	for i := 1; i < len(closes); i++ {
+		prev, cur := closes[i-1], closes[i]
+		if prev.Close <= 0 || cur.Close <= 0 {
+			continue
+		}
+		key := prev.Date.Format("2006-01-02") + "|" + cur.Date.Format("2006-01-02")
+		returns[key] = cur.Close/prev.Close - 1
+	}
+	return returns
+}
+
+// CorrelateReturns computes the pairwise correlation of the given return series. ok is false when
+// the pair shares fewer than minObservations returns or either side has no variance over them.
+func CorrelateReturns(a, b map[string]float64, minObservations int) (ReturnCorrelation, bool) {
+	keys := make([]string, 0, len(a))
+	for key := range a {
+		if _, shared := b[key]; shared {
+			keys = append(keys, key)
+		}
+	}
+	result := ReturnCorrelation{Observations: len(keys)}
+	if len(keys) < minObservations || len(keys) < 2 {
+		return result, false
+	}
+	sort.Strings(keys)
+
+	var meanA, meanB float64
+	for _, key := range keys {
+		meanA += a[key]
+		meanB += b[key]
+	}
+	n := float64(len(keys))
+	meanA /= n
+	meanB /= n
+
+	var cov, varA, varB float64
+	for _, key := range keys {
+		da, db := a[key]-meanA, b[key]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return result, false
+	}
+	result.Correlation = cov / math.Sqrt(varA*varB)
+	return result, true
+}
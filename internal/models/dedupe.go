@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DuplicateOptionGroup is a set of options with identical symbol, type, opened, strike,
+// expiration, premium, contracts and account, and the one row kept when they are collapsed
+type DuplicateOptionGroup struct {
+	Keep    *Option   `json:"keep"`
+	Removed []*Option `json:"removed"`
+}
+
+type optionDuplicateKey struct {
+	Symbol     string
+	Type       string
+	Opened     string
+	Strike     float64
+	Expiration string
+	Premium    float64
+	Contracts  int
+	Account    string
+}
+
+// FindDuplicateOptions groups exact-duplicate options. The row kept in each group is a closed
+// one when any duplicate is closed, so close and exit data survive, then the lowest ID.
+func FindDuplicateOptions(options []*Option) []DuplicateOptionGroup {
+	byKey := make(map[optionDuplicateKey][]*Option)
+	var keys []optionDuplicateKey
+	for _, option := range options {
+		key := optionDuplicateKey{
+			Symbol:     option.Symbol,
+			Type:       option.Type,
+			Opened:     option.Opened.Format("2006-01-02"),
+			Strike:     option.Strike,
+			Expiration: option.Expiration.Format("2006-01-02"),
+			Premium:    option.Premium,
+			Contracts:  option.Contracts,
+			Account:    option.Account,
+		}
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], option)
+	}
+
+	var groups []DuplicateOptionGroup
+	for _, key := range keys {
+		rows := byKey[key]
+		if len(rows) < 2 {
+			continue
+		}
+		sort.Slice(rows, func(i, j int) bool {
+			if (rows[i].Closed != nil) != (rows[j].Closed != nil) {
+				return rows[i].Closed != nil
+			}
+			return rows[i].ID < rows[j].ID
+		})
+		groups = append(groups, DuplicateOptionGroup{Keep: rows[0], Removed: rows[1:]})
+	}
+	return groups
+}
+
+// CollapseDuplicate deletes the removed options of a group in one transaction, first pointing
+// Greeks snapshots and roll links at the kept option so no history is lost
+func (s *OptionService) CollapseDuplicate(group DuplicateOptionGroup) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	keepID := group.Keep.ID
+	for _, removed := range group.Removed {
+		// Snapshots already captured for the kept option at the same time are dropped with the duplicate
+		if _, err := tx.Exec(`UPDATE OR IGNORE greeks_snapshots SET option_id = ? WHERE option_id = ?`, keepID, removed.ID); err != nil {
+			return fmt.Errorf("failed to move greeks snapshots: %w", err)
+		}
+		if _, err := tx.Exec(`UPDATE options SET rolled_from_id = ? WHERE rolled_from_id = ?`, keepID, removed.ID); err != nil {
+			return fmt.Errorf("failed to move roll links: %w", err)
+		}
+		if group.Keep.RolledFromID == nil && removed.RolledFromID != nil {
+			if _, err := tx.Exec(`UPDATE options SET rolled_from_id = ? WHERE id = ?`, *removed.RolledFromID, keepID); err != nil {
+				return fmt.Errorf("failed to keep roll link: %w", err)
+			}
+		}
+		if _, err := tx.Exec(`DELETE FROM options WHERE id = ?`, removed.ID); err != nil {
+			return fmt.Errorf("failed to delete duplicate option %d: %w", removed.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"math"
 	"time"
 )
 
@@ -22,11 +23,11 @@ func (s *DividendService) Create(symbol string, received time.Time, amount float
 
 	query := `INSERT INTO dividends (symbol, received, amount) 
 			  VALUES (?, ?, ?) 
-			  RETURNING id, symbol, received, amount, created_at`
+			  RETURNING id, symbol, received, amount, reinvested_amount, reinvested_position_id, created_at`
 
 	var dividend Dividend
 	err := s.db.QueryRow(query, symbol, received, amount).Scan(
-		&dividend.ID, &dividend.Symbol, &dividend.Received, &dividend.Amount, &dividend.CreatedAt,
+		&dividend.ID, &dividend.Symbol, &dividend.Received, &dividend.Amount, &dividend.ReinvestedAmount, &dividend.ReinvestedPositionID, &dividend.CreatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create dividend: %w", err)
@@ -35,8 +36,62 @@ func (s *DividendService) Create(symbol string, received time.Time, amount float
 	return &dividend, nil
 }
 
+// CreateWithReinvestment records a dividend of which reinvested is used to buy shares at
+// reinvestPrice (creating a long position lot) and cash is paid out. reinvested + cash must equal
+// amount, and the reinvested portion must buy whole shares since lots hold whole shares.
+func (s *DividendService) CreateWithReinvestment(symbol string, received time.Time, amount, reinvested, cash, reinvestPrice float64) (*Dividend, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("dividend amount must be positive")
+	}
+	if reinvested < 0 || cash < 0 {
+		return nil, fmt.Errorf("reinvested and cash portions must not be negative")
+	}
+	if math.Abs(reinvested+cash-amount) > 0.005 {
+		return nil, fmt.Errorf("reinvested ($%.2f) plus cash ($%.2f) must equal the dividend amount ($%.2f)", reinvested, cash, amount)
+	}
+	if reinvested == 0 {
+		return s.Create(symbol, received, amount)
+	}
+	if reinvestPrice <= 0 {
+		return nil, fmt.Errorf("reinvest price is required when part of the dividend is reinvested")
+	}
+	shares := int(math.Round(reinvested / reinvestPrice))
+	if shares == 0 || math.Abs(float64(shares)*reinvestPrice-reinvested) > 0.005 {
+		return nil, fmt.Errorf("reinvested $%.2f at $%.2f is not a whole number of shares; pay the fraction as cash", reinvested, reinvestPrice)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var positionID int
+	err = tx.QueryRow(`INSERT INTO long_positions (symbol, opened, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total)
+			  VALUES (?, ?, ?, ?, ?, ?) RETURNING id`, symbol, received, shares, reinvestPrice, reinvestPrice, reinvestPrice*float64(shares)).Scan(&positionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reinvested long position: %w", err)
+	}
+
+	var dividend Dividend
+	err = tx.QueryRow(`INSERT INTO dividends (symbol, received, amount, reinvested_amount, reinvested_position_id)
+			  VALUES (?, ?, ?, ?, ?)
+			  RETURNING id, symbol, received, amount, reinvested_amount, reinvested_position_id, created_at`,
+		symbol, received, amount, reinvested, positionID).Scan(
+		&dividend.ID, &dividend.Symbol, &dividend.Received, &dividend.Amount, &dividend.ReinvestedAmount, &dividend.ReinvestedPositionID, &dividend.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dividend: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit dividend: %w", err)
+	}
+	return &dividend, nil
+}
+
 func (s *DividendService) GetBySymbol(symbol string) ([]*Dividend, error) {
-	query := `SELECT id, symbol, received, amount, created_at 
+	query := `SELECT id, symbol, received, amount, reinvested_amount, reinvested_position_id, created_at 
 			  FROM dividends WHERE symbol = ? ORDER BY received DESC`
 
 	rows, err := s.db.Query(query, symbol)
@@ -48,7 +103,7 @@ func (s *DividendService) GetBySymbol(symbol string) ([]*Dividend, error) {
 	var dividends []*Dividend
 	for rows.Next() {
 		var dividend Dividend
-		if err := rows.Scan(&dividend.ID, &dividend.Symbol, &dividend.Received, &dividend.Amount, &dividend.CreatedAt); err != nil {
+		if err := rows.Scan(&dividend.ID, &dividend.Symbol, &dividend.Received, &dividend.Amount, &dividend.ReinvestedAmount, &dividend.ReinvestedPositionID, &dividend.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan dividend: %w", err)
 		}
 		dividends = append(dividends, &dividend)
@@ -62,7 +117,7 @@ func (s *DividendService) GetBySymbol(symbol string) ([]*Dividend, error) {
 }
 
 func (s *DividendService) GetAll() ([]*Dividend, error) {
-	query := `SELECT id, symbol, received, amount, created_at 
+	query := `SELECT id, symbol, received, amount, reinvested_amount, reinvested_position_id, created_at 
 			  FROM dividends ORDER BY received DESC`
 
 	rows, err := s.db.Query(query)
@@ -74,7 +129,7 @@ func (s *DividendService) GetAll() ([]*Dividend, error) {
 	var dividends []*Dividend
 	for rows.Next() {
 		var dividend Dividend
-		if err := rows.Scan(&dividend.ID, &dividend.Symbol, &dividend.Received, &dividend.Amount, &dividend.CreatedAt); err != nil {
+		if err := rows.Scan(&dividend.ID, &dividend.Symbol, &dividend.Received, &dividend.Amount, &dividend.ReinvestedAmount, &dividend.ReinvestedPositionID, &dividend.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan dividend: %w", err)
 		}
 		dividends = append(dividends, &dividend)
@@ -88,7 +143,7 @@ func (s *DividendService) GetAll() ([]*Dividend, error) {
 }
 
 func (s *DividendService) GetByDateRange(symbol string, startDate, endDate time.Time) ([]*Dividend, error) {
-	query := `SELECT id, symbol, received, amount, created_at 
+	query := `SELECT id, symbol, received, amount, reinvested_amount, reinvested_position_id, created_at 
 			  FROM dividends 
 			  WHERE symbol = ? AND received BETWEEN ? AND ? 
 			  ORDER BY received DESC`
@@ -102,7 +157,7 @@ func (s *DividendService) GetByDateRange(symbol string, startDate, endDate time.
 	var dividends []*Dividend
 	for rows.Next() {
 		var dividend Dividend
-		if err := rows.Scan(&dividend.ID, &dividend.Symbol, &dividend.Received, &dividend.Amount, &dividend.CreatedAt); err != nil {
+		if err := rows.Scan(&dividend.ID, &dividend.Symbol, &dividend.Received, &dividend.Amount, &dividend.ReinvestedAmount, &dividend.ReinvestedPositionID, &dividend.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan dividend: %w", err)
 		}
 		dividends = append(dividends, &dividend)
@@ -115,6 +170,8 @@ func (s *DividendService) GetByDateRange(symbol string, startDate, endDate time.
 	return dividends, nil
 }
 
+// GetTotalForSymbol returns the dividends received for a symbol. Reinvested portions count as
+// income too; the lot they bought carries them as its cost.
 func (s *DividendService) GetTotalForSymbol(symbol string) (float64, error) {
 	query := `SELECT COALESCE(SUM(amount), 0) FROM dividends WHERE symbol = ?`
 
@@ -130,15 +187,9 @@ func (s *DividendService) GetTotalForSymbol(symbol string) (float64, error) {
 func (s *DividendService) Delete(symbol string, received time.Time, amount float64) error {
 	// Use ABS() function to handle floating-point precision issues
 	// Allow for a small epsilon (0.001) in the comparison
-	query := `DELETE FROM dividends WHERE symbol = ? AND received = ? AND ABS(amount - ?) < 0.001`
-	result, err := s.db.Exec(query, symbol, received, amount)
-	if err != nil {
-		return fmt.Errorf("failed to delete dividend: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
+	rowsAffected, err := s.deleteWhere(`symbol = ? AND received = ? AND ABS(amount - ?) < 0.001`, symbol, received, amount)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return err
 	}
 
 	if rowsAffected == 0 {
@@ -149,15 +200,9 @@ func (s *DividendService) Delete(symbol string, received time.Time, amount float
 }
 
 func (s *DividendService) DeleteByID(id int) error {
-	query := `DELETE FROM dividends WHERE id = ?`
-	result, err := s.db.Exec(query, id)
-	if err != nil {
-		return fmt.Errorf("failed to delete dividend: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
+	rowsAffected, err := s.deleteWhere(`id = ?`, id)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return err
 	}
 
 	if rowsAffected == 0 {
@@ -168,17 +213,41 @@ func (s *DividendService) DeleteByID(id int) error {
 }
 
 func (s *DividendService) DeleteBySymbol(symbol string) error {
-	query := `DELETE FROM dividends WHERE symbol = ?`
-	result, err := s.db.Exec(query, symbol)
+	rowsAffected, err := s.deleteWhere(`symbol = ?`, symbol)
 	if err != nil {
 		return fmt.Errorf("failed to delete dividends for symbol %s: %w", symbol, err)
 	}
 
+	log.Printf("Deleted %d dividends for symbol: %s", rowsAffected, symbol)
+	return nil
+}
+
+// deleteWhere deletes the dividends matching where together with the long position lots their
+// reinvested portions bought, in one transaction, and returns how many dividends were removed
+func (s *DividendService) deleteWhere(where string, args ...interface{}) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM long_positions WHERE id IN
+		(SELECT reinvested_position_id FROM dividends WHERE reinvested_position_id IS NOT NULL AND `+where+`)`, args...); err != nil {
+		return 0, fmt.Errorf("failed to delete reinvested long positions: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM dividends WHERE `+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete dividend: %w", err)
+	}
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
-	log.Printf("Deleted %d dividends for symbol: %s", rowsAffected, symbol)
-	return nil
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit dividend deletion: %w", err)
+	}
+	return rowsAffected, nil
 }
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"math"
+	"stonks/internal/database"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupDividendTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	testDB, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("failed to setup test database: %v", err)
+	}
+	testDB.SetMaxOpenConns(1) // one connection, so transactions see the in-memory schema
+
+	if _, err := NewSymbolService(testDB.DB).Create("KO"); err != nil {
+		t.Fatalf("failed to create symbol: %v", err)
+	}
+	return testDB
+}
+
+func countLongPositions(t *testing.T, testDB *database.DB) int {
+	t.Helper()
+	var n int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM long_positions`).Scan(&n); err != nil {
+		t.Fatalf("failed to count long positions: %v", err)
+	}
+	return n
+}
+
+func TestCreateWithReinvestmentCountsFullAmount(t *testing.T) {
+	testDB := setupDividendTestDB(t)
+	defer testDB.Close()
+
+	dividendService := NewDividendService(testDB.DB)
+	lpService := NewLongPositionService(testDB.DB)
+	received := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	// $60 of a $100 dividend buys 3 shares at $20; $40 is paid as cash
+	dividend, err := dividendService.CreateWithReinvestment("KO", received, 100.0, 60.0, 40.0, 20.0)
+	if err != nil {
+		t.Fatalf("failed to create reinvested dividend: %v", err)
+	}
+	if dividend.ReinvestedPositionID == nil || dividend.ReinvestedAmount != 60.0 {
+		t.Fatalf("expected the reinvested lot and amount recorded, got %+v", dividend)
+	}
+
+	lot, err := lpService.GetByID(*dividend.ReinvestedPositionID)
+	if err != nil {
+		t.Fatalf("failed to fetch reinvested lot: %v", err)
+	}
+	if lot.Shares != 3 || lot.BuyPrice != 20.0 || !lot.Opened.Equal(received) {
+		t.Fatalf("expected 3 shares at $20 opened %s, got %+v", received.Format("2006-01-02"), lot)
+	}
+
+	// The reinvested portion is still income; the lot carries it only as cost
+	total, err := dividendService.GetTotalForSymbol("KO")
+	if err != nil {
+		t.Fatalf("failed to total dividends: %v", err)
+	}
+	if math.Abs(total-100.0) > 0.001 {
+		t.Fatalf("expected total dividends 100.00, got %.2f", total)
+	}
+}
+
+func TestCreateWithReinvestmentValidation(t *testing.T) {
+	testDB := setupDividendTestDB(t)
+	defer testDB.Close()
+
+	dividendService := NewDividendService(testDB.DB)
+	received := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name                               string
+		amount, reinvested, cash, reinvest float64
+	}{
+		{"portions do not sum to amount", 100.0, 60.0, 30.0, 20.0},
+		{"fractional shares", 100.0, 50.0, 50.0, 20.0},
+		{"missing reinvest price", 100.0, 60.0, 40.0, 0},
+		{"negative cash", 100.0, 120.0, -20.0, 20.0},
+	} {
+		if _, err := dividendService.CreateWithReinvestment("KO", received, tc.amount, tc.reinvested, tc.cash, tc.reinvest); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+	if n := countLongPositions(t, testDB); n != 0 {
+		t.Fatalf("expected rejected dividends to leave no lots, got %d", n)
+	}
+}
+
+func TestDeleteDividendRemovesReinvestedLot(t *testing.T) {
+	testDB := setupDividendTestDB(t)
+	defer testDB.Close()
+
+	dividendService := NewDividendService(testDB.DB)
+	lpService := NewLongPositionService(testDB.DB)
+
+	var dividends []*Dividend
+	for month := time.January; month <= time.March; month++ {
+		dividend, err := dividendService.CreateWithReinvestment("KO", time.Date(2025, month, 15, 0, 0, 0, 0, time.UTC), 100.0, 60.0, 40.0, 20.0)
+		if err != nil {
+			t.Fatalf("failed to create reinvested dividend: %v", err)
+		}
+		dividends = append(dividends, dividend)
+	}
+	// A lot with a partial exit goes together with its exit rows
+	if _, err := lpService.AddExit(*dividends[0].ReinvestedPositionID, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), 1, 22.0); err != nil {
+		t.Fatalf("failed to add exit: %v", err)
+	}
+	if _, err := lpService.Create("KO", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), 100, 60.0); err != nil {
+		t.Fatalf("failed to create unrelated lot: %v", err)
+	}
+
+	if err := dividendService.DeleteByID(dividends[0].ID); err != nil {
+		t.Fatalf("failed to delete dividend by id: %v", err)
+	}
+	if _, err := lpService.GetByID(*dividends[0].ReinvestedPositionID); err == nil {
+		t.Fatalf("expected the reinvested lot deleted with its dividend")
+	}
+	var exits int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM long_position_exits`).Scan(&exits); err != nil || exits != 0 {
+		t.Fatalf("expected the lot's exits deleted, got %d (%v)", exits, err)
+	}
+
+	if err := dividendService.Delete("KO", dividends[1].Received, 100.0); err != nil {
+		t.Fatalf("failed to delete dividend by key: %v", err)
+	}
+	if n := countLongPositions(t, testDB); n != 2 {
+		t.Fatalf("expected 2 lots left after deleting by key, got %d", n)
+	}
+
+	if err := dividendService.DeleteBySymbol("KO"); err != nil {
+		t.Fatalf("failed to delete dividends by symbol: %v", err)
+	}
+	// Only the lot bought outright remains
+	if n := countLongPositions(t, testDB); n != 1 {
+		t.Fatalf("expected only the unrelated lot left, got %d", n)
+	}
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// DrawdownStats summarizes peak-to-trough declines in a value series. Amounts are in the series'
+// units and percentages are relative to the peak. Dates are empty when the series has no values.
+type DrawdownStats struct {
+	From         string `json:"from,omitempty"`
+	To           string `json:"to,omitempty"`
+	Observations int    `json:"observations"` // days with a value
+
+	MaxDrawdown        float64 `json:"max_drawdown"`
+	MaxDrawdownPercent float64 `json:"max_drawdown_percent"`
+	MaxPeakDate        string  `json:"max_peak_date,omitempty"`
+	MaxPeakValue       float64 `json:"max_peak_value"`
+	MaxTroughDate      string  `json:"max_trough_date,omitempty"`
+	MaxTroughValue     float64 `json:"max_trough_value"`
+	RecoveryDate       string  `json:"recovery_date,omitempty"` // first day back at the max drawdown's peak; empty while unrecovered
+
+	CurrentValue           float64 `json:"current_value"`
+	CurrentDrawdown        float64 `json:"current_drawdown"`
+	CurrentDrawdownPercent float64 `json:"current_drawdown_percent"`
+	PeakDate               string  `json:"peak_date,omitempty"`
+	PeakValue              float64 `json:"peak_value"`
+}
+
+// ComputeDrawdown derives the maximum and current drawdown from a value series. The latest value
+// on each calendar day is used, so sparse or irregular snapshots are handled as they are; gaps are
+// not filled. Non-positive values are ignored since a decline from them has no percentage.
+func ComputeDrawdown(metrics []*Metric) DrawdownStats {
+	var stats DrawdownStats
+
+	type observation struct {
+		day     time.Time
+		created time.Time
+		value   float64
+	}
+	byDay := make(map[string]observation)
+	for _, metric := range metrics {
+		if metric.Value <= 0 {
+			continue
+		}
+		year, month, date := metric.Created.Date()
+		key := time.Date(year, month, date, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+		if existing, ok := byDay[key]; ok && existing.created.After(metric.Created) {
+			continue
+		}
+		byDay[key] = observation{day: time.Date(year, month, date, 0, 0, 0, 0, time.UTC), created: metric.Created, value: metric.Value}
+	}
+
+	series := make([]observation, 0, len(byDay))
+	for _, obs := range byDay {
+		series = append(series, obs)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].day.Before(series[j].day) })
+
+	stats.Observations = len(series)
+	if len(series) == 0 {
+		return stats
+	}
+	stats.From = series[0].day.Format("2006-01-02")
+	stats.To = series[len(series)-1].day.Format("2006-01-02")
+
+	peak := series[0]
+	maxPeak := series[0]
+	recovered := true
+	for _, obs := range series {
+		if obs.value >= peak.value {
+			peak = obs
+		}
+		if !recovered && obs.value >= maxPeak.value && obs.day.After(maxPeak.day) {
+			stats.RecoveryDate = obs.day.Format("2006-01-02")
+			recovered = true
+		}
+
+		drawdown := peak.value - obs.value
+		if drawdown > stats.MaxDrawdown {
+			stats.MaxDrawdown = drawdown
+			stats.MaxDrawdownPercent = drawdown / peak.value * 100
+			stats.MaxPeakDate = peak.day.Format("2006-01-02")
+			stats.MaxPeakValue = peak.value
+			stats.MaxTroughDate = obs.day.Format("2006-01-02")
+			stats.MaxTroughValue = obs.value
+			stats.RecoveryDate = ""
+			maxPeak = peak
+			recovered = false
+		}
+	}
+
+	last := series[len(series)-1]
+	stats.CurrentValue = last.value
+	stats.PeakDate = peak.day.Format("2006-01-02")
+	stats.PeakValue = peak.value
+	stats.CurrentDrawdown = peak.value - last.value
+	stats.CurrentDrawdownPercent = stats.CurrentDrawdown / peak.value * 100
+	return stats
+}
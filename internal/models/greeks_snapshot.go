@@ -0,0 +1,85 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Greeks snapshot sources
+const (
+	GreeksSourceCapture = "capture"
+	GreeksSourceImport  = "import"
+)
+
+// GreeksSnapshot is the Greeks and implied volatility of an option contract on one date
+type GreeksSnapshot struct {
+	ID                int       `json:"id"`
+	OptionID          int       `json:"option_id"`
+	Captured          time.Time `json:"captured"`
+	Contract          string    `json:"contract"`
+	Delta             *float64  `json:"delta"`
+	Gamma             *float64  `json:"gamma"`
+	Theta             *float64  `json:"theta"`
+	Vega              *float64  `json:"vega"`
+	ImpliedVolatility *float64  `json:"implied_volatility"`
+	Source            string    `json:"source"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type GreeksSnapshotService struct {
+	db *sql.DB
+}
+
+func NewGreeksSnapshotService(db *sql.DB) *GreeksSnapshotService {
+	return &GreeksSnapshotService{db: db}
+}
+
+// CreateIfNotExists stores a snapshot unless one already exists for the option on that date.
+// It reports whether a new row was written.
+func (s *GreeksSnapshotService) CreateIfNotExists(snapshot *GreeksSnapshot) (bool, error) {
+	source := snapshot.Source
+	if source == "" {
+		source = GreeksSourceCapture
+	}
+
+	result, err := s.db.Exec(`INSERT OR IGNORE INTO greeks_snapshots (option_id, captured, contract, delta, gamma, theta, vega, implied_volatility, source)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		snapshot.OptionID, snapshot.Captured, snapshot.Contract, snapshot.Delta, snapshot.Gamma, snapshot.Theta, snapshot.Vega, snapshot.ImpliedVolatility, source)
+	if err != nil {
+		return false, fmt.Errorf("failed to create greeks snapshot: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetByOptionID returns an option's snapshots in date order
+func (s *GreeksSnapshotService) GetByOptionID(optionID int) ([]*GreeksSnapshot, error) {
+	rows, err := s.db.Query(`SELECT id, option_id, captured, contract, delta, gamma, theta, vega, implied_volatility, source, created_at
+			  FROM greeks_snapshots WHERE option_id = ? ORDER BY captured ASC`, optionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get greeks snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []*GreeksSnapshot
+	for rows.Next() {
+		var snapshot GreeksSnapshot
+		if err := rows.Scan(&snapshot.ID, &snapshot.OptionID, &snapshot.Captured, &snapshot.Contract, &snapshot.Delta, &snapshot.Gamma,
+			&snapshot.Theta, &snapshot.Vega, &snapshot.ImpliedVolatility, &snapshot.Source, &snapshot.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan greeks snapshot: %w", err)
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating greeks snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
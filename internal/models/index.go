@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -277,13 +278,15 @@ func compareOptions(opt1, opt2 *Option) bool {
 
 // FilterOptions represents filtering criteria for combined queries
 type FilterOptions struct {
-	Symbols     []string    `json:"symbols,omitempty"`     // Filter by specific symbols
-	Types       []string    `json:"types,omitempty"`       // Filter by option types (Put/Call)
-	Status      string      `json:"status,omitempty"`      // "open", "closed", or "all"
-	DateRange   *DateRange  `json:"date_range,omitempty"`  // Filter by expiration date range
-	OpenedRange *DateRange  `json:"opened_range,omitempty"` // Filter by opened date range
-	ClosedRange *DateRange  `json:"closed_range,omitempty"` // Filter by closed date range
-	StrikeRange *StrikeRange `json:"strike_range,omitempty"` // Filter by strike price range
+	Symbols           []string     `json:"symbols,omitempty"`            // Filter by specific symbols
+	Types             []string     `json:"types,omitempty"`              // Filter by option types (Put/Call)
+	Status            string       `json:"status,omitempty"`             // "open", "closed", or "all"
+	DateRange         *DateRange   `json:"date_range,omitempty"`         // Filter by expiration date range
+	OpenedRange       *DateRange   `json:"opened_range,omitempty"`       // Filter by opened date range
+	ClosedRange       *DateRange   `json:"closed_range,omitempty"`       // Filter by closed date range
+	StrikeRange       *StrikeRange `json:"strike_range,omitempty"`       // Filter by strike price range
+	Account           string       `json:"account,omitempty"`            // Filter by broker account
+	ExpirationClasses []string     `json:"expiration_classes,omitempty"` // Filter by weekly/monthly/quarterly/leaps
 }
 
 type DateRange struct {
@@ -373,6 +376,26 @@ func matchesFilters(option *Option, filters FilterOptions) bool {
 		}
 	}
 	
+	// Account filter
+	if filters.Account != "" && option.Account != filters.Account {
+		return false
+	}
+	
+	// Expiration class filter
+	if len(filters.ExpirationClasses) > 0 {
+		class := option.ExpirationClass()
+		classMatch := false
+		for _, expirationClass := range filters.ExpirationClasses {
+			if strings.EqualFold(expirationClass, class) {
+				classMatch = true
+				break
+			}
+		}
+		if !classMatch {
+			return false
+		}
+	}
+	
 	// Status filter (open/closed)
 	if filters.Status != "" && filters.Status != "all" {
 		if filters.Status == "open" && option.Closed != nil {
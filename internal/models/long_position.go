@@ -8,7 +8,49 @@ import (
 )
 
 type LongPositionService struct {
-	db *sql.DB
+	db               *sql.DB
+	commissionMode   func() string
+	coverageTracking func() bool
+	openCallSplit    func() bool
+	marketLocation   func() *time.Location
+}
+
+// Commission treatment in adjusted cost basis (BASIS_COMMISSION_MODE setting)
+const (
+	// BasisCommissionExclude adjusts basis by premium before commission; commission stays a
+	// separate expense, as in CalculateNetPremiumNoFees
+	BasisCommissionExclude = "exclude"
+	// BasisCommissionNet adjusts basis by premium net of commission, so fees raise the basis
+	BasisCommissionNet = "net"
+)
+
+// SetBasisCommissionMode registers a callback returning the BASIS_COMMISSION_MODE in effect.
+// It is read on every recalculation; without one, commission is excluded from basis.
+func (s *LongPositionService) SetBasisCommissionMode(mode func() string) {
+	s.commissionMode = mode
+}
+
+// basisNetOfCommission reports whether basis adjustments subtract option commission
+func (s *LongPositionService) basisNetOfCommission() bool {
+	return s.commissionMode != nil && s.commissionMode() == BasisCommissionNet
+}
+
+// SetOpenCallPremiumSplit registers a callback reporting whether BASIS_DEFER_OPEN_CALL_PREMIUM
+// is on. With it on, open call premium is kept out of the persisted adjusted basis and only
+// reduces the provisional basis; otherwise open calls adjust the persisted basis like closed ones.
+func (s *LongPositionService) SetOpenCallPremiumSplit(enabled func() bool) {
+	s.openCallSplit = enabled
+}
+
+// splitOpenCallPremium reports whether open call premium is kept apart as a provisional adjustment
+func (s *LongPositionService) splitOpenCallPremium() bool {
+	return s.openCallSplit != nil && s.openCallSplit()
+}
+
+// SetMarketLocation sets how the service looks up the MARKET_TIMEZONE that decides which calendar
+// day "today" is when separating pending lots; without one, the server's local time is used.
+func (s *LongPositionService) SetMarketLocation(location func() *time.Location) {
+	s.marketLocation = location
 }
 
 func NewLongPositionService(db *sql.DB) *LongPositionService {
@@ -18,12 +60,12 @@ func NewLongPositionService(db *sql.DB) *LongPositionService {
 func (s *LongPositionService) Create(symbol string, opened time.Time, shares int, buyPrice float64) (*LongPosition, error) {
 	query := `INSERT INTO long_positions (symbol, opened, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total) 
 			  VALUES (?, ?, ?, ?, ?, ?) 
-			  RETURNING id, symbol, opened, closed, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, exit_price, created_at, updated_at`
+			  RETURNING id, symbol, opened, closed, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, exit_price, account, created_at, updated_at`
 
 	var position LongPosition
 	err := s.db.QueryRow(query, symbol, opened, shares, buyPrice, buyPrice, buyPrice*float64(shares)).Scan(
 		&position.ID, &position.Symbol, &position.Opened, &position.Closed, &position.Shares,
-		&position.BuyPrice, &position.AdjustedCostBasisPerShare, &position.AdjustedCostBasisTotal, &position.ExitPrice, &position.CreatedAt, &position.UpdatedAt,
+		&position.BuyPrice, &position.AdjustedCostBasisPerShare, &position.AdjustedCostBasisTotal, &position.ExitPrice, &position.Account, &position.CreatedAt, &position.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create long position: %w", err)
@@ -45,7 +87,7 @@ func (s *LongPositionService) Create(symbol string, opened time.Time, shares int
 }
 
 func (s *LongPositionService) GetBySymbol(symbol string) ([]*LongPosition, error) {
-	query := `SELECT id, symbol, opened, closed, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, exit_price, created_at, updated_at 
+	query := `SELECT id, symbol, opened, closed, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, exit_price, account, created_at, updated_at 
 			  FROM long_positions WHERE symbol = ? ORDER BY opened DESC`
 
 	rows, err := s.db.Query(query, symbol)
@@ -58,7 +100,7 @@ func (s *LongPositionService) GetBySymbol(symbol string) ([]*LongPosition, error
 	for rows.Next() {
 		var position LongPosition
 		if err := rows.Scan(&position.ID, &position.Symbol, &position.Opened, &position.Closed, &position.Shares,
-			&position.BuyPrice, &position.AdjustedCostBasisPerShare, &position.AdjustedCostBasisTotal, &position.ExitPrice, &position.CreatedAt, &position.UpdatedAt); err != nil {
+			&position.BuyPrice, &position.AdjustedCostBasisPerShare, &position.AdjustedCostBasisTotal, &position.ExitPrice, &position.Account, &position.CreatedAt, &position.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan long position: %w", err)
 		}
 		positions = append(positions, &position)
@@ -72,7 +114,7 @@ func (s *LongPositionService) GetBySymbol(symbol string) ([]*LongPosition, error
 }
 
 func (s *LongPositionService) GetAll() ([]*LongPosition, error) {
-	query := `SELECT id, symbol, opened, closed, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, exit_price, created_at, updated_at 
+	query := `SELECT id, symbol, opened, closed, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, exit_price, account, created_at, updated_at 
 			  FROM long_positions ORDER BY opened DESC`
 
 	rows, err := s.db.Query(query)
@@ -85,7 +127,7 @@ func (s *LongPositionService) GetAll() ([]*LongPosition, error) {
 	for rows.Next() {
 		var position LongPosition
 		if err := rows.Scan(&position.ID, &position.Symbol, &position.Opened, &position.Closed, &position.Shares,
-			&position.BuyPrice, &position.AdjustedCostBasisPerShare, &position.AdjustedCostBasisTotal, &position.ExitPrice, &position.CreatedAt, &position.UpdatedAt); err != nil {
+			&position.BuyPrice, &position.AdjustedCostBasisPerShare, &position.AdjustedCostBasisTotal, &position.ExitPrice, &position.Account, &position.CreatedAt, &position.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan long position: %w", err)
 		}
 		positions = append(positions, &position)
@@ -141,13 +183,13 @@ func (s *LongPositionService) Delete(symbol string, opened time.Time, shares int
 
 // GetByID retrieves a long position by its ID
 func (s *LongPositionService) GetByID(id int) (*LongPosition, error) {
-	query := `SELECT id, symbol, opened, closed, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, exit_price, created_at, updated_at 
+	query := `SELECT id, symbol, opened, closed, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, exit_price, account, created_at, updated_at 
 			  FROM long_positions WHERE id = ?`
 
 	var position LongPosition
 	err := s.db.QueryRow(query, id).Scan(
 		&position.ID, &position.Symbol, &position.Opened, &position.Closed, &position.Shares,
-		&position.BuyPrice, &position.AdjustedCostBasisPerShare, &position.AdjustedCostBasisTotal, &position.ExitPrice, &position.CreatedAt, &position.UpdatedAt,
+		&position.BuyPrice, &position.AdjustedCostBasisPerShare, &position.AdjustedCostBasisTotal, &position.ExitPrice, &position.Account, &position.CreatedAt, &position.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -164,12 +206,12 @@ func (s *LongPositionService) UpdateByID(id int, symbol string, opened time.Time
 	query := `UPDATE long_positions 
 			  SET symbol = ?, opened = ?, shares = ?, buy_price = ?, closed = ?, exit_price = ?, updated_at = CURRENT_TIMESTAMP 
 			  WHERE id = ? 
-			  RETURNING id, symbol, opened, closed, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, exit_price, created_at, updated_at`
+			  RETURNING id, symbol, opened, closed, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, exit_price, account, created_at, updated_at`
 
 	var position LongPosition
 	err := s.db.QueryRow(query, symbol, opened, shares, buyPrice, closed, exitPrice, id).Scan(
 		&position.ID, &position.Symbol, &position.Opened, &position.Closed, &position.Shares,
-		&position.BuyPrice, &position.AdjustedCostBasisPerShare, &position.AdjustedCostBasisTotal, &position.ExitPrice, &position.CreatedAt, &position.UpdatedAt,
+		&position.BuyPrice, &position.AdjustedCostBasisPerShare, &position.AdjustedCostBasisTotal, &position.ExitPrice, &position.Account, &position.CreatedAt, &position.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -213,8 +255,30 @@ func (s *LongPositionService) DeleteByID(id int) error {
 	return nil
 }
 
-// CloseByID closes a long position by its ID
+// CloseByID closes a long position by its ID. Lots that already have partial exits
+// record the remaining shares as a final exit event instead.
 func (s *LongPositionService) CloseByID(id int, closed time.Time, exitPrice float64) error {
+	var exitCount int
+	if err := s.db.QueryRow(`SELECT COUNT(1) FROM long_position_exits WHERE position_id = ?`, id).Scan(&exitCount); err != nil {
+		return fmt.Errorf("failed to check long position exits: %w", err)
+	}
+	if exitCount > 0 {
+		var shares, exitedShares int
+		err := s.db.QueryRow(`SELECT lp.shares, COALESCE(SUM(e.shares), 0) FROM long_positions lp
+			LEFT JOIN long_position_exits e ON e.position_id = lp.id WHERE lp.id = ? GROUP BY lp.id`, id).Scan(&shares, &exitedShares)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("long position not found")
+			}
+			return fmt.Errorf("failed to get remaining shares: %w", err)
+		}
+		if shares-exitedShares <= 0 {
+			return fmt.Errorf("long position already closed")
+		}
+		_, err = s.AddExit(id, closed, shares-exitedShares, exitPrice)
+		return err
+	}
+
 	query := `UPDATE long_positions 
 			  SET closed = ?, exit_price = ?, updated_at = CURRENT_TIMESTAMP 
 			  WHERE id = ?`
@@ -236,6 +300,25 @@ func (s *LongPositionService) CloseByID(id int, closed time.Time, exitPrice floa
 	return nil
 }
 
+// SetAccount tags a long position with a broker account; a blank account resets it to DefaultAccount
+func (s *LongPositionService) SetAccount(id int, account string) error {
+	result, err := s.db.Exec(`UPDATE long_positions SET account = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, NormalizeAccount(account), id)
+	if err != nil {
+		return fmt.Errorf("failed to set long position account: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("long position not found")
+	}
+
+	return nil
+}
+
 func (s *LongPositionService) DeleteBySymbol(symbol string) error {
 	query := `DELETE FROM long_positions WHERE symbol = ?`
 	result, err := s.db.Exec(query, symbol)
@@ -251,12 +334,157 @@ func (s *LongPositionService) DeleteBySymbol(symbol string) error {
 	return nil
 }
 
-// GetOpenPositions retrieves all open long positions (where closed is NULL)
+// AddExit records a sale of some or all shares from a lot. Once every share has been
+// exited the lot is closed at the last exit date with the share-weighted average price.
+func (s *LongPositionService) AddExit(positionID int, exited time.Time, shares int, price float64) (*LongPositionExit, error) {
+	if shares <= 0 {
+		return nil, fmt.Errorf("exit shares must be positive")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		positionShares int
+		closed         sql.NullTime
+		exitedShares   int
+		exitCount      int
+	)
+	err = tx.QueryRow(`SELECT lp.shares, lp.closed, COALESCE(SUM(e.shares), 0), COUNT(e.id) FROM long_positions lp
+		LEFT JOIN long_position_exits e ON e.position_id = lp.id WHERE lp.id = ? GROUP BY lp.id`, positionID).Scan(&positionShares, &closed, &exitedShares, &exitCount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("long position not found")
+		}
+		return nil, fmt.Errorf("failed to get long position: %w", err)
+	}
+
+	if closed.Valid && exitCount == 0 {
+		return nil, fmt.Errorf("long position already closed")
+	}
+	if exitedShares+shares > positionShares {
+		return nil, fmt.Errorf("exit of %d shares exceeds %d remaining shares", shares, positionShares-exitedShares)
+	}
+
+	var exit LongPositionExit
+	err = tx.QueryRow(`INSERT INTO long_position_exits (position_id, exited, shares, price) VALUES (?, ?, ?, ?)
+		RETURNING id, position_id, exited, shares, price, created_at`, positionID, exited, shares, price).Scan(
+		&exit.ID, &exit.PositionID, &exit.Exited, &exit.Shares, &exit.Price, &exit.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create long position exit: %w", err)
+	}
+
+	if err := syncClosureFromExits(tx, positionID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit long position exit: %w", err)
+	}
+
+	return &exit, nil
+}
+
+// GetExits retrieves the exit events recorded for a lot in chronological order
+func (s *LongPositionService) GetExits(positionID int) ([]*LongPositionExit, error) {
+	rows, err := s.db.Query(`SELECT id, position_id, exited, shares, price, created_at
+		FROM long_position_exits WHERE position_id = ? ORDER BY exited ASC, id ASC`, positionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get long position exits: %w", err)
+	}
+	defer rows.Close()
+
+	var exits []*LongPositionExit
+	for rows.Next() {
+		var exit LongPositionExit
+		if err := rows.Scan(&exit.ID, &exit.PositionID, &exit.Exited, &exit.Shares, &exit.Price, &exit.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan long position exit: %w", err)
+		}
+		exits = append(exits, &exit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating long position exits: %w", err)
+	}
+
+	return exits, nil
+}
+
+// AttachExits loads exit events onto the given positions
+func (s *LongPositionService) AttachExits(positions []*LongPosition) error {
+	for _, position := range positions {
+		exits, err := s.GetExits(position.ID)
+		if err != nil {
+			return err
+		}
+		position.Exits = exits
+	}
+	return nil
+}
+
+// DeleteExit removes an exit event and reopens the lot if shares remain
+func (s *LongPositionService) DeleteExit(id int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var positionID int
+	err = tx.QueryRow(`DELETE FROM long_position_exits WHERE id = ? RETURNING position_id`, id).Scan(&positionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("long position exit not found")
+		}
+		return fmt.Errorf("failed to delete long position exit: %w", err)
+	}
+
+	if err := syncClosureFromExits(tx, positionID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// syncClosureFromExits sets closed/exit_price from exit rows when they cover the whole lot, and clears them otherwise
+func syncClosureFromExits(tx *sql.Tx, positionID int) error {
+	var (
+		positionShares int
+		exitedShares   int
+		lastExit       sql.NullString
+		avgPrice       sql.NullFloat64
+	)
+	err := tx.QueryRow(`SELECT lp.shares, COALESCE(SUM(e.shares), 0), MAX(e.exited), SUM(e.shares * e.price) / SUM(e.shares)
+		FROM long_positions lp LEFT JOIN long_position_exits e ON e.position_id = lp.id
+		WHERE lp.id = ? GROUP BY lp.id`, positionID).Scan(&positionShares, &exitedShares, &lastExit, &avgPrice)
+	if err != nil {
+		return fmt.Errorf("failed to summarize long position exits: %w", err)
+	}
+
+	if exitedShares >= positionShares && lastExit.Valid {
+		_, err = tx.Exec(`UPDATE long_positions SET closed = (SELECT MAX(exited) FROM long_position_exits WHERE position_id = ?),
+			exit_price = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, positionID, avgPrice.Float64, positionID)
+	} else {
+		_, err = tx.Exec(`UPDATE long_positions SET closed = NULL, exit_price = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, positionID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update long position closure: %w", err)
+	}
+
+	return nil
+}
+
+// GetOpenPositions retrieves all open long positions (where closed is NULL), excluding pending
+// positions whose opened date is still in the future
 func (s *LongPositionService) GetOpenPositions() ([]*LongPosition, error) {
-	query := `SELECT id, symbol, opened, closed, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, exit_price, created_at, updated_at 
-			  FROM long_positions WHERE closed IS NULL ORDER BY opened DESC`
+	query := `SELECT id, symbol, opened, closed, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, exit_price, account, created_at, updated_at 
+			  FROM long_positions WHERE closed IS NULL AND opened < ? ORDER BY opened DESC`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(query, pendingCutoff(marketNow(s.marketLocation)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get open long positions: %w", err)
 	}
@@ -266,7 +494,7 @@ func (s *LongPositionService) GetOpenPositions() ([]*LongPosition, error) {
 	for rows.Next() {
 		var position LongPosition
 		if err := rows.Scan(&position.ID, &position.Symbol, &position.Opened, &position.Closed, &position.Shares,
-			&position.BuyPrice, &position.AdjustedCostBasisPerShare, &position.AdjustedCostBasisTotal, &position.ExitPrice, &position.CreatedAt, &position.UpdatedAt); err != nil {
+			&position.BuyPrice, &position.AdjustedCostBasisPerShare, &position.AdjustedCostBasisTotal, &position.ExitPrice, &position.Account, &position.CreatedAt, &position.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan open long position: %w", err)
 		}
 		positions = append(positions, &position)
@@ -279,66 +507,330 @@ func (s *LongPositionService) GetOpenPositions() ([]*LongPosition, error) {
 	return positions, nil
 }
 
-// RecalculateAdjustedCostBasisForSymbol recomputes adjusted cost basis values for all lots of a symbol
-// based on assigned put premiums and covered call premiums collected while shares are held.
-func (s *LongPositionService) RecalculateAdjustedCostBasisForSymbol(symbol string) error {
+// GetPendingPositions retrieves open long positions whose opened date is still in the future
+func (s *LongPositionService) GetPendingPositions() ([]*LongPosition, error) {
+	query := `SELECT id, symbol, opened, closed, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, exit_price, account, created_at, updated_at 
+			  FROM long_positions WHERE closed IS NULL AND opened >= ? ORDER BY opened ASC`
+
+	rows, err := s.db.Query(query, pendingCutoff(marketNow(s.marketLocation)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending long positions: %w", err)
+	}
+	defer rows.Close()
+
+	var positions []*LongPosition
+	for rows.Next() {
+		var position LongPosition
+		if err := rows.Scan(&position.ID, &position.Symbol, &position.Opened, &position.Closed, &position.Shares,
+			&position.BuyPrice, &position.AdjustedCostBasisPerShare, &position.AdjustedCostBasisTotal, &position.ExitPrice, &position.Account, &position.CreatedAt, &position.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pending long position: %w", err)
+		}
+		positions = append(positions, &position)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pending long positions: %w", err)
+	}
+
+	return positions, nil
+}
+
+// Assignment buy price modes (ASSIGNMENT_BUY_PRICE_MODE setting)
+const (
+	// AssignmentBuyPriceStrike records the lot at the strike; the put premium reduces the
+	// adjusted cost basis through RecalculateAdjustedCostBasisForSymbol.
+	AssignmentBuyPriceStrike = "strike"
+	// AssignmentBuyPriceNetPremium records the lot at strike minus premium and flags it so the
+	// recalculation does not apply the put premium a second time.
+	AssignmentBuyPriceNetPremium = "net_premium"
+)
+
+// AssignPut records assignment of an open put: the put is closed on the assignment date with a
+// zero exit price, flagged as assigned and linked to a lot of contracts*100 shares opened the same
+// day in the put's account.
+// Both modes end with the same adjusted cost basis; they differ only in the recorded buy price.
+func (s *LongPositionService) AssignPut(optionID int, assigned time.Time, mode string) (*LongPosition, error) {
+	if mode != AssignmentBuyPriceStrike && mode != AssignmentBuyPriceNetPremium {
+		return nil, fmt.Errorf("invalid assignment mode %q", mode)
+	}
+
 	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Load positions in chronological order to allocate coverage FIFO
-	posRows, err := tx.Query(`SELECT id, opened, closed, shares, buy_price FROM long_positions WHERE symbol = ? ORDER BY opened ASC`, symbol)
+	var (
+		symbol, optionType, account string
+		strike, premium, commission float64
+		contracts                   int
+		closed                      sql.NullTime
+		status                      string
+	)
+	err = tx.QueryRow(`SELECT symbol, type, strike, premium, commission + fees, contracts, closed, account, status FROM options WHERE id = ?`, optionID).Scan(
+		&symbol, &optionType, &strike, &premium, &commission, &contracts, &closed, &account, &status)
 	if err != nil {
-		return fmt.Errorf("failed to load positions: %w", err)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("option not found")
+		}
+		return nil, fmt.Errorf("failed to get option: %w", err)
+	}
+	if optionType != "Put" {
+		return nil, fmt.Errorf("only puts can be assigned into a long position")
+	}
+	if closed.Valid {
+		return nil, fmt.Errorf("option is already closed")
+	}
+	if status == OptionStatusCanceled {
+		return nil, fmt.Errorf("option was canceled")
+	}
+
+	shares := contracts * 100
+	buyPrice := strike
+	netOfPut := mode == AssignmentBuyPriceNetPremium
+	if netOfPut {
+		buyPrice = strike - premium
+		// Match the basis adjustment the strike mode would make under BASIS_COMMISSION_MODE
+		if s.basisNetOfCommission() {
+			buyPrice += commission / float64(shares)
+		}
+	}
+
+	var positionID int
+	err = tx.QueryRow(`INSERT INTO long_positions (symbol, opened, shares, buy_price, adjusted_cost_basis_per_share, adjusted_cost_basis_total, account, basis_includes_put_premium)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		symbol, assigned, shares, buyPrice, buyPrice, buyPrice*float64(shares), account, netOfPut).Scan(&positionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create assigned long position: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE options SET closed = ?, exit_price = 0, assigned = 1, assigned_position_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		assigned, positionID, optionID); err != nil {
+		return nil, fmt.Errorf("failed to close assigned option: %w", err)
+	}
+	if err := recordRealizedProfit(tx, optionID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if err := s.RecalculateAdjustedCostBasisForSymbol(symbol); err != nil {
+		return nil, fmt.Errorf("failed to recalculate cost basis after assignment: %w", err)
+	}
+
+	return s.GetByID(positionID)
+}
+
+// CallAway records assignment of an open covered call: the call is closed on the assignment date
+// with a zero exit price and flagged as assigned, and contracts*100 shares are exited at the strike
+// FIFO from the open lots in the call's account. The first lot drawn from is linked to the call.
+func (s *LongPositionService) CallAway(optionID int, assigned time.Time) ([]*LongPositionExit, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		symbol, optionType, account string
+		strike                      float64
+		contracts                   int
+		closed                      sql.NullTime
+		status                      string
+	)
+	err = tx.QueryRow(`SELECT symbol, type, strike, contracts, closed, account, status FROM options WHERE id = ?`, optionID).Scan(
+		&symbol, &optionType, &strike, &contracts, &closed, &account, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("option not found")
+		}
+		return nil, fmt.Errorf("failed to get option: %w", err)
+	}
+	if optionType != "Call" {
+		return nil, fmt.Errorf("only calls can be called away from a long position")
+	}
+	if closed.Valid {
+		return nil, fmt.Errorf("option is already closed")
+	}
+	if status == OptionStatusCanceled {
+		return nil, fmt.Errorf("option was canceled")
+	}
+
+	rows, err := tx.Query(`SELECT lp.id, lp.shares - COALESCE(SUM(e.shares), 0) FROM long_positions lp
+		LEFT JOIN long_position_exits e ON e.position_id = lp.id
+		WHERE lp.symbol = ? AND lp.account = ? AND lp.closed IS NULL AND lp.opened <= ?
+		GROUP BY lp.id ORDER BY lp.opened, lp.id`, symbol, account, assigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open lots: %w", err)
+	}
+	type lot struct {
+		id        int
+		remaining int
+	}
+	var lots []lot
+	available := 0
+	for rows.Next() {
+		var l lot
+		if err := rows.Scan(&l.id, &l.remaining); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan lot: %w", err)
+		}
+		if l.remaining > 0 {
+			lots = append(lots, l)
+			available += l.remaining
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate lots: %w", err)
+	}
+
+	needed := contracts * 100
+	if available < needed {
+		return nil, fmt.Errorf("call covers %d shares but only %d %s shares are open", needed, available, symbol)
+	}
+
+	var exits []*LongPositionExit
+	for _, l := range lots {
+		if needed == 0 {
+			break
+		}
+		shares := l.remaining
+		if shares > needed {
+			shares = needed
+		}
+		var exit LongPositionExit
+		err := tx.QueryRow(`INSERT INTO long_position_exits (position_id, exited, shares, price) VALUES (?, ?, ?, ?)
+			RETURNING id, position_id, exited, shares, price, created_at`, l.id, assigned, shares, strike).Scan(
+			&exit.ID, &exit.PositionID, &exit.Exited, &exit.Shares, &exit.Price, &exit.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create long position exit: %w", err)
+		}
+		if err := syncClosureFromExits(tx, l.id); err != nil {
+			return nil, err
+		}
+		exits = append(exits, &exit)
+		needed -= shares
+	}
+
+	if _, err := tx.Exec(`UPDATE options SET closed = ?, exit_price = 0, assigned = 1, assigned_position_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		assigned, exits[0].PositionID, optionID); err != nil {
+		return nil, fmt.Errorf("failed to close assigned option: %w", err)
+	}
+	if err := recordRealizedProfit(tx, optionID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if err := s.RecalculateAdjustedCostBasisForSymbol(symbol); err != nil {
+		return nil, fmt.Errorf("failed to recalculate cost basis after call assignment: %w", err)
 	}
-	defer posRows.Close()
 
-	type positionCalc struct {
-		id       int
-		opened   time.Time
-		closed   *time.Time
-		shares   int
-		buyPrice float64
-		adjust   float64
+	return exits, nil
+}
+
+// BasisAttribution is a single option premium credited against a lot's cost basis. Unrealized
+// marks premium from a call that is still open, which only counts toward the provisional basis.
+type BasisAttribution struct {
+	OptionID        int       `json:"option_id"`
+	Type            string    `json:"type"`
+	Opened          time.Time `json:"opened"`
+	Strike          float64   `json:"strike"`
+	Expiration      time.Time `json:"expiration"`
+	Contracts       int       `json:"contracts"`
+	NetPremium      float64   `json:"net_premium"`
+	AllocatedShares int       `json:"allocated_shares"`
+	Amount          float64   `json:"amount"`
+	Unrealized      bool      `json:"unrealized,omitempty"`
+}
+
+// LotBasisDetail breaks a lot's adjusted cost basis down into its original cost and the
+// put/call premiums allocated to it by RecalculateAdjustedCostBasisForSymbol. When open call
+// premium is split out, the adjusted basis is realized (closed options only) and the provisional
+// basis additionally subtracts the premium of open calls written against the lot; it is never
+// persisted. Otherwise both figures are the same.
+type LotBasisDetail struct {
+	PositionID                   int                `json:"position_id"`
+	Symbol                       string             `json:"symbol"`
+	Opened                       time.Time          `json:"opened"`
+	Closed                       *time.Time         `json:"closed"`
+	Shares                       int                `json:"shares"`
+	BuyPrice                     float64            `json:"buy_price"`
+	BasisIncludesPutPremium      bool               `json:"basis_includes_put_premium"`
+	OriginalCost                 float64            `json:"original_cost"`
+	Attributions                 []BasisAttribution `json:"attributions"`
+	TotalAdjustment              float64            `json:"total_adjustment"`
+	AdjustedCostBasisTotal       float64            `json:"adjusted_cost_basis_total"`
+	AdjustedCostBasisPerShare    float64            `json:"adjusted_cost_basis_per_share"`
+	UnrealizedAdjustment         float64            `json:"unrealized_adjustment"`
+	ProvisionalCostBasisTotal    float64            `json:"provisional_cost_basis_total"`
+	ProvisionalCostBasisPerShare float64            `json:"provisional_cost_basis_per_share"`
+}
+
+// basisQuerier is satisfied by both *sql.DB and *sql.Tx
+type basisQuerier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// computeBasisDetails loads a symbol's lots and options and allocates premiums to lots:
+// assigned put premiums to lots opened on the put's close date, and covered call premiums
+// FIFO across lots that were active when each call was opened, skipping shares already written
+// against by calls still open then. Premiums are net of commission
+// only when netOfCommission is set. With splitOpenCalls, open calls are allocated the same way but
+// kept out of the realized adjustment. The call allocation is also returned as call-to-lot coverage,
+// including calls whose premium nets to zero.
+func computeBasisDetails(q basisQuerier, symbol string, netOfCommission, splitOpenCalls bool) ([]*LotBasisDetail, []CallCoverage, error) {
+	// Load positions in chronological order to allocate coverage FIFO
+	posRows, err := q.Query(`SELECT id, opened, closed, shares, buy_price, basis_includes_put_premium FROM long_positions WHERE symbol = ? ORDER BY opened ASC`, symbol)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load positions: %w", err)
 	}
+	defer posRows.Close()
 
-	var positions []positionCalc
+	var lots []*LotBasisDetail
 	for posRows.Next() {
 		var (
-			p  positionCalc
-			cl sql.NullTime
+			lot LotBasisDetail
+			cl  sql.NullTime
 		)
-		if err := posRows.Scan(&p.id, &p.opened, &cl, &p.shares, &p.buyPrice); err != nil {
-			return fmt.Errorf("failed to scan position: %w", err)
+		if err := posRows.Scan(&lot.PositionID, &lot.Opened, &cl, &lot.Shares, &lot.BuyPrice, &lot.BasisIncludesPutPremium); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan position: %w", err)
 		}
 		if cl.Valid {
-			p.closed = &cl.Time
+			lot.Closed = &cl.Time
 		}
-		positions = append(positions, p)
+		lot.Symbol = symbol
+		lot.Attributions = []BasisAttribution{}
+		lots = append(lots, &lot)
 	}
 	if err := posRows.Err(); err != nil {
-		return fmt.Errorf("error iterating positions: %w", err)
+		return nil, nil, fmt.Errorf("error iterating positions: %w", err)
 	}
 
 	// Load options for symbol
-	optRows, err := tx.Query(`SELECT id, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission FROM options WHERE symbol = ? ORDER BY opened ASC`, symbol)
+	optRows, err := q.Query(`SELECT id, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, fees FROM options WHERE symbol = ? AND status != 'canceled' ORDER BY opened ASC`, symbol)
 	if err != nil {
-		return fmt.Errorf("failed to load options: %w", err)
+		return nil, nil, fmt.Errorf("failed to load options: %w", err)
 	}
 	defer optRows.Close()
 
 	var callOptions []*Option
 	var putOptions []*Option
+	optionsByID := make(map[int]*Option)
 	for optRows.Next() {
 		var (
 			opt  Option
 			cl   sql.NullTime
 			exit sql.NullFloat64
 		)
-		if err := optRows.Scan(&opt.ID, &opt.Type, &opt.Opened, &cl, &opt.Strike, &opt.Expiration, &opt.Premium, &opt.Contracts, &exit, &opt.Commission); err != nil {
-			return fmt.Errorf("failed to scan option: %w", err)
+		if err := optRows.Scan(&opt.ID, &opt.Type, &opt.Opened, &cl, &opt.Strike, &opt.Expiration, &opt.Premium, &opt.Contracts, &exit, &opt.Commission, &opt.Fees); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan option: %w", err)
 		}
 		if cl.Valid {
 			opt.Closed = &cl.Time
@@ -347,6 +839,7 @@ func (s *LongPositionService) RecalculateAdjustedCostBasisForSymbol(symbol strin
 			val := exit.Float64
 			opt.ExitPrice = &val
 		}
+		optionsByID[opt.ID] = &opt
 		if opt.Type == "Call" {
 			callOptions = append(callOptions, &opt)
 		} else if opt.Type == "Put" {
@@ -354,65 +847,188 @@ func (s *LongPositionService) RecalculateAdjustedCostBasisForSymbol(symbol strin
 		}
 	}
 	if err := optRows.Err(); err != nil {
-		return fmt.Errorf("error iterating options: %w", err)
+		return nil, nil, fmt.Errorf("error iterating options: %w", err)
+	}
+
+	attribute := func(lot *LotBasisDetail, opt *Option, netPremium float64, shares int, amount float64) {
+		unrealized := splitOpenCalls && opt.Closed == nil
+		lot.Attributions = append(lot.Attributions, BasisAttribution{
+			OptionID:        opt.ID,
+			Type:            opt.Type,
+			Opened:          opt.Opened,
+			Strike:          opt.Strike,
+			Expiration:      opt.Expiration,
+			Contracts:       opt.Contracts,
+			NetPremium:      netPremium,
+			AllocatedShares: shares,
+			Amount:          amount,
+			Unrealized:      unrealized,
+		})
+		if unrealized {
+			lot.UnrealizedAdjustment += amount
+			return
+		}
+		lot.TotalAdjustment += amount
 	}
 
-	// Apply cash-secured put assignment premiums: match puts closed on the lot's open date
-	for idx := range positions {
-		p := &positions[idx]
+	// Apply cash-secured put assignment premiums: match puts closed on the lot's open date.
+	// Lots bought net of premium already carry it in buy_price, so adding it again would double-count.
+	for _, lot := range lots {
+		if lot.BasisIncludesPutPremium {
+			continue
+		}
 		for _, opt := range putOptions {
 			if opt.Closed == nil {
 				continue
 			}
-			if sameDay(opt.Closed, &p.opened) {
-				netPremium := netOptionPremium(opt)
-				p.adjust += netPremium
+			if sameDay(opt.Closed, &lot.Opened) {
+				netPremium := netOptionPremium(opt, netOfCommission)
+				attribute(lot, opt, netPremium, opt.Contracts*100, netPremium)
 			}
 		}
 	}
 
-	// Apply covered call premiums to lots that were active when the calls were opened
+	// Apply covered call premiums to lots that were active when the calls were opened. Shares an
+	// earlier call still holds on that date are taken, so overlapping calls fill the next lot.
+	var coverage []CallCoverage
 	for _, opt := range callOptions {
 		remainingCoverage := opt.Contracts * 100
-		netPremium := netOptionPremium(opt)
-		if netPremium == 0 || remainingCoverage == 0 {
+		netPremium := netOptionPremium(opt, netOfCommission)
+		if remainingCoverage == 0 {
 			continue
 		}
-		for idx := range positions {
+		taken := make(map[int]int)
+		for _, c := range coverage {
+			if callHeldOn(optionsByID[c.OptionID], opt.Opened) {
+				taken[c.PositionID] += c.Shares
+			}
+		}
+		for _, lot := range lots {
 			if remainingCoverage == 0 {
 				break
 			}
-			p := &positions[idx]
-			if !positionActiveOn(p.opened, p.closed, opt.Opened) {
+			if !positionActiveOn(lot.Opened, lot.Closed, opt.Opened) {
+				continue
+			}
+			allocShares := minInt(remainingCoverage, lot.Shares-taken[lot.PositionID])
+			if allocShares <= 0 {
 				continue
 			}
-			allocShares := minInt(remainingCoverage, p.shares)
-			allocationRatio := float64(allocShares) / float64(opt.Contracts*100)
-			p.adjust += netPremium * allocationRatio
+			coverage = append(coverage, CallCoverage{OptionID: opt.ID, PositionID: lot.PositionID, Shares: allocShares})
+			if netPremium != 0 {
+				allocationRatio := float64(allocShares) / float64(opt.Contracts*100)
+				attribute(lot, opt, netPremium, allocShares, netPremium*allocationRatio)
+			}
 			remainingCoverage -= allocShares
 		}
 	}
 
+	for _, lot := range lots {
+		lot.OriginalCost = lot.BuyPrice * float64(lot.Shares)
+		lot.AdjustedCostBasisTotal = lot.OriginalCost - lot.TotalAdjustment
+		lot.ProvisionalCostBasisTotal = lot.AdjustedCostBasisTotal - lot.UnrealizedAdjustment
+		if lot.Shares > 0 {
+			lot.AdjustedCostBasisPerShare = lot.AdjustedCostBasisTotal / float64(lot.Shares)
+			lot.ProvisionalCostBasisPerShare = lot.ProvisionalCostBasisTotal / float64(lot.Shares)
+		}
+	}
+
+	return lots, coverage, nil
+}
+
+// RecalculateAdjustedCostBasisForSymbol recomputes adjusted cost basis values for all lots of a symbol
+// based on assigned put premiums and the premiums of covered calls written while shares are held.
+// With BASIS_DEFER_OPEN_CALL_PREMIUM on, only closed calls count and open calls reduce the
+// provisional basis instead (see AttachProvisionalBasis). With call coverage tracking on, it also
+// replaces the symbol's stored call-to-lot coverage.
+func (s *LongPositionService) RecalculateAdjustedCostBasisForSymbol(symbol string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	lots, coverage, err := computeBasisDetails(tx, symbol, s.basisNetOfCommission(), s.splitOpenCallPremium())
+	if err != nil {
+		return err
+	}
+
 	// Persist recalculated values
-	for _, p := range positions {
-		baseTotal := p.buyPrice * float64(p.shares)
-		adjustedTotal := baseTotal - p.adjust
-		if adjustedTotal < 0 {
-			log.Printf("[COST BASIS] Adjusted cost basis below zero for symbol %s (position %d). Base=%.2f, adjustments=%.2f", symbol, p.id, baseTotal, p.adjust)
-			return fmt.Errorf("adjusted cost basis below zero for symbol %s (position %d)", symbol, p.id)
-		}
-		var adjustedPerShare float64
-		if p.shares > 0 {
-			adjustedPerShare = adjustedTotal / float64(p.shares)
-		}
-		if _, err := tx.Exec(`UPDATE long_positions SET adjusted_cost_basis_per_share = ?, adjusted_cost_basis_total = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, adjustedPerShare, adjustedTotal, p.id); err != nil {
+	for _, lot := range lots {
+		if lot.AdjustedCostBasisTotal < 0 {
+			log.Printf("[COST BASIS] Adjusted cost basis below zero for symbol %s (position %d). Base=%.2f, adjustments=%.2f", symbol, lot.PositionID, lot.OriginalCost, lot.TotalAdjustment)
+			return fmt.Errorf("adjusted cost basis below zero for symbol %s (position %d)", symbol, lot.PositionID)
+		}
+		if _, err := tx.Exec(`UPDATE long_positions SET adjusted_cost_basis_per_share = ?, adjusted_cost_basis_total = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, lot.AdjustedCostBasisPerShare, lot.AdjustedCostBasisTotal, lot.PositionID); err != nil {
 			return fmt.Errorf("failed to update adjusted cost basis: %w", err)
 		}
 	}
 
+	if s.CallCoverageTracked() {
+		if err := replaceCallCoverage(tx, symbol, coverage); err != nil {
+			return err
+		}
+	}
+
 	return tx.Commit()
 }
 
+// GetSymbolBasisDetails returns the premium attribution behind the adjusted cost basis of every
+// lot of a symbol, oldest lot first
+func (s *LongPositionService) GetSymbolBasisDetails(symbol string) ([]*LotBasisDetail, error) {
+	lots, _, err := computeBasisDetails(s.db, symbol, s.basisNetOfCommission(), s.splitOpenCallPremium())
+	return lots, err
+}
+
+// GetBasisDetail returns the per-lot premium attribution behind a position's adjusted cost basis,
+// computed the same way RecalculateAdjustedCostBasisForSymbol allocates it
+func (s *LongPositionService) GetBasisDetail(positionID int) (*LotBasisDetail, error) {
+	position, err := s.GetByID(positionID)
+	if err != nil {
+		return nil, err
+	}
+
+	lots, _, err := computeBasisDetails(s.db, position.Symbol, s.basisNetOfCommission(), s.splitOpenCallPremium())
+	if err != nil {
+		return nil, err
+	}
+	for _, lot := range lots {
+		if lot.PositionID == positionID {
+			return lot, nil
+		}
+	}
+	return nil, fmt.Errorf("long position not found")
+}
+
+// AttachProvisionalBasis sets the provisional basis of positions that have open calls written
+// against them, for display only; the persisted adjusted basis is left untouched
+func (s *LongPositionService) AttachProvisionalBasis(positions []*LongPosition) error {
+	details := make(map[string]map[int]*LotBasisDetail)
+	for _, position := range positions {
+		lots, ok := details[position.Symbol]
+		if !ok {
+			computed, _, err := computeBasisDetails(s.db, position.Symbol, s.basisNetOfCommission(), s.splitOpenCallPremium())
+			if err != nil {
+				return err
+			}
+			lots = make(map[int]*LotBasisDetail, len(computed))
+			for _, lot := range computed {
+				lots[lot.PositionID] = lot
+			}
+			details[position.Symbol] = lots
+		}
+		lot := lots[position.ID]
+		if lot == nil || lot.UnrealizedAdjustment == 0 {
+			continue
+		}
+		perShare, total := lot.ProvisionalCostBasisPerShare, lot.ProvisionalCostBasisTotal
+		position.UnrealizedBasisAdjustment = lot.UnrealizedAdjustment
+		position.ProvisionalCostBasisPerShare = &perShare
+		position.ProvisionalCostBasisTotal = &total
+	}
+	return nil
+}
+
 func sameDay(a *time.Time, b *time.Time) bool {
 	if a == nil || b == nil {
 		return false
@@ -437,6 +1053,17 @@ func positionActiveOn(opened time.Time, closed *time.Time, t time.Time) bool {
 	return closed.After(t)
 }
 
+// callHeldOn reports whether a call still ties up the shares written against it on t, i.e. it has
+// not been closed or expired by then. A call closed the day another opens, as in a roll, frees its
+// shares for the new call.
+func callHeldOn(opt *Option, t time.Time) bool {
+	end := opt.Expiration
+	if opt.Closed != nil {
+		end = *opt.Closed
+	}
+	return end.After(t) && !sameDay(&end, &t)
+}
+
 func minInt(a, b int) int {
 	if a < b {
 		return a
@@ -444,7 +1071,12 @@ func minInt(a, b int) int {
 	return b
 }
 
-func netOptionPremium(opt *Option) float64 {
+// netOptionPremium is the premium an option contributes to basis adjustments. Realized profit is
+// always net of commission and fees; excluding them here keeps the costs out of the share basis.
+func netOptionPremium(opt *Option, netOfCommission bool) float64 {
+	if netOfCommission {
+		return opt.CalculateNetPremiumNoFees() - opt.TransactionCosts()
+	}
 	return opt.CalculateNetPremiumNoFees()
 }
 
@@ -28,7 +28,22 @@ func setupLongPositionTestDB(t *testing.T) *sql.DB {
 		premium REAL NOT NULL,
 		contracts INTEGER NOT NULL,
 		exit_price REAL,
-		commission REAL DEFAULT 0.0
+		commission REAL DEFAULT 0.0,
+		fees REAL NOT NULL DEFAULT 0.0,
+		current_price REAL,
+		account TEXT NOT NULL DEFAULT 'Default',
+		assigned INTEGER NOT NULL DEFAULT 0,
+		assigned_position_id INTEGER,
+		strategy TEXT,
+		rolled_from_id INTEGER,
+		realized_profit REAL,
+		currency TEXT NOT NULL DEFAULT 'USD',
+		fx_rate REAL NOT NULL DEFAULT 1.0,
+		underlying_at_open REAL,
+		underlying_at_close REAL,
+		status TEXT NOT NULL DEFAULT 'active',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
 	CREATE TABLE long_positions (
@@ -41,9 +56,38 @@ func setupLongPositionTestDB(t *testing.T) *sql.DB {
 		adjusted_cost_basis_per_share REAL NOT NULL DEFAULT 0.0,
 		adjusted_cost_basis_total REAL NOT NULL DEFAULT 0.0,
 		exit_price REAL,
+		account TEXT NOT NULL DEFAULT 'Default',
+		basis_includes_put_premium INTEGER NOT NULL DEFAULT 0,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
+
+	CREATE TABLE long_position_exits (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		position_id INTEGER NOT NULL,
+		exited DATE NOT NULL,
+		shares INTEGER NOT NULL,
+		price REAL NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE stock_splits (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		position_id INTEGER NOT NULL,
+		symbol TEXT NOT NULL,
+		effective DATE NOT NULL,
+		split_to INTEGER NOT NULL,
+		split_from INTEGER NOT NULL,
+		rounding TEXT NOT NULL,
+		shares_before INTEGER NOT NULL,
+		shares_after INTEGER NOT NULL,
+		remainder_shares REAL NOT NULL DEFAULT 0.0,
+		remainder_pre_split_shares REAL NOT NULL DEFAULT 0.0,
+		remainder_basis REAL NOT NULL DEFAULT 0.0,
+		cash_in_lieu REAL NOT NULL DEFAULT 0.0,
+		realized REAL NOT NULL DEFAULT 0.0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
 	`
 	if _, err := db.Exec(schema); err != nil {
 		t.Fatalf("failed to create schema: %v", err)
@@ -120,3 +164,409 @@ func TestRecalculateAdjustedCostBasisForSymbol(t *testing.T) {
 		t.Fatalf("second position basis should remain unchanged, got %.2f", got)
 	}
 }
+
+func TestOpenCallPremiumSplitSetting(t *testing.T) {
+	for _, tc := range []struct {
+		split       bool
+		adjusted    float64
+		provisional float64 // 0 means no provisional basis is attached
+	}{
+		// Off: the open call's $100 adjusts the persisted basis with the closed call's $40
+		{false, 4860.0, 0},
+		// On: only the closed call is persisted; the open call shows in the provisional basis
+		{true, 4960.0, 4860.0},
+	} {
+		db := setupLongPositionTestDB(t)
+		lpService := NewLongPositionService(db)
+		split := tc.split
+		lpService.SetOpenCallPremiumSplit(func() bool { return split })
+
+		symbol := "EEE"
+		position, err := lpService.Create(symbol, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), 100, 50.0)
+		if err != nil {
+			t.Fatalf("failed to create long position: %v", err)
+		}
+		closedOn := time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)
+		if _, err := db.Exec(`
+			INSERT INTO options (symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price)
+			VALUES (?, 'Call', ?, ?, 55.0, ?, 0.50, 1, 0.10), (?, 'Call', ?, NULL, 55.0, ?, 1.00, 1, NULL)
+		`, symbol, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), closedOn, closedOn,
+			symbol, time.Date(2025, 1, 21, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 21, 0, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatalf("failed to insert call options: %v", err)
+		}
+
+		if err := lpService.RecalculateAdjustedCostBasisForSymbol(symbol); err != nil {
+			t.Fatalf("split=%v: recalculate failed: %v", tc.split, err)
+		}
+		updated, err := lpService.GetByID(position.ID)
+		if err != nil {
+			t.Fatalf("failed to fetch position: %v", err)
+		}
+		if math.Abs(updated.AdjustedCostBasisTotal-tc.adjusted) > 0.001 {
+			t.Fatalf("split=%v: expected adjusted total %.2f, got %.2f", tc.split, tc.adjusted, updated.AdjustedCostBasisTotal)
+		}
+
+		if err := lpService.AttachProvisionalBasis([]*LongPosition{updated}); err != nil {
+			t.Fatalf("split=%v: failed to attach provisional basis: %v", tc.split, err)
+		}
+		switch {
+		case tc.provisional == 0 && updated.ProvisionalCostBasisTotal != nil:
+			t.Fatalf("split=%v: expected no provisional basis, got %.2f", tc.split, *updated.ProvisionalCostBasisTotal)
+		case tc.provisional != 0 && (updated.ProvisionalCostBasisTotal == nil || math.Abs(*updated.ProvisionalCostBasisTotal-tc.provisional) > 0.001):
+			t.Fatalf("split=%v: expected provisional total %.2f, got %v", tc.split, tc.provisional, updated.ProvisionalCostBasisTotal)
+		}
+		db.Close()
+	}
+}
+
+func TestOverlappingCallsFillSeparateLots(t *testing.T) {
+	db := setupLongPositionTestDB(t)
+	defer db.Close()
+
+	lpService := NewLongPositionService(db)
+
+	symbol := "DDD"
+	for _, opened := range []time.Time{
+		time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC),
+	} {
+		if _, err := lpService.Create(symbol, opened, 100, 30.0); err != nil {
+			t.Fatalf("failed to create long position: %v", err)
+		}
+	}
+
+	insertCall := func(opened, expiration time.Time, closed *time.Time) {
+		t.Helper()
+		if _, err := db.Exec(`
+			INSERT INTO options (symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price)
+			VALUES (?, 'Call', ?, ?, 35.0, ?, 0.50, 1, 0.0)
+		`, symbol, opened, closed, expiration); err != nil {
+			t.Fatalf("failed to insert call option: %v", err)
+		}
+	}
+	// Two open calls overlap; a third opens the day the first is rolled and takes its shares
+	rolled := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	insertCall(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), &rolled)
+	insertCall(time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 21, 0, 0, 0, 0, time.UTC), nil)
+	insertCall(rolled, time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC), nil)
+
+	lots, coverage, err := computeBasisDetails(db, symbol, false, false)
+	if err != nil {
+		t.Fatalf("computeBasisDetails failed: %v", err)
+	}
+	if len(coverage) != 3 {
+		t.Fatalf("expected 3 coverage rows, got %+v", coverage)
+	}
+	// Calls 1 and 3 on the first lot, call 2 on the second
+	for i, want := range []CallCoverage{
+		{OptionID: 1, PositionID: lots[0].PositionID, Shares: 100},
+		{OptionID: 2, PositionID: lots[1].PositionID, Shares: 100},
+		{OptionID: 3, PositionID: lots[0].PositionID, Shares: 100},
+	} {
+		if coverage[i] != want {
+			t.Fatalf("coverage %d: expected %+v, got %+v", i, want, coverage[i])
+		}
+	}
+}
+
+func TestLongPositionPartialExits(t *testing.T) {
+	db := setupLongPositionTestDB(t)
+	defer db.Close()
+
+	lpService := NewLongPositionService(db)
+
+	opened := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	position, err := lpService.Create("BBB", opened, 100, 40.0)
+	if err != nil {
+		t.Fatalf("failed to create long position: %v", err)
+	}
+
+	if _, err := lpService.AddExit(position.ID, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), 50, 44.0); err != nil {
+		t.Fatalf("failed to add first exit: %v", err)
+	}
+	if _, err := lpService.AddExit(position.ID, time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC), 60, 45.0); err == nil {
+		t.Fatalf("expected error when exiting more shares than remain")
+	}
+
+	partial, err := lpService.GetByID(position.ID)
+	if err != nil {
+		t.Fatalf("failed to fetch position: %v", err)
+	}
+	if partial.Closed != nil {
+		t.Fatalf("position should stay open after partial exit")
+	}
+
+	// Closing the remainder records a final exit at the closing price
+	finalDate := time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)
+	if err := lpService.CloseByID(position.ID, finalDate, 38.0); err != nil {
+		t.Fatalf("failed to close remaining shares: %v", err)
+	}
+
+	closed, err := lpService.GetByID(position.ID)
+	if err != nil {
+		t.Fatalf("failed to fetch closed position: %v", err)
+	}
+	if err := lpService.AttachExits([]*LongPosition{closed}); err != nil {
+		t.Fatalf("failed to attach exits: %v", err)
+	}
+	if len(closed.Exits) != 2 {
+		t.Fatalf("expected 2 exits, got %d", len(closed.Exits))
+	}
+	if closed.Closed == nil || !sameDay(closed.Closed, &finalDate) {
+		t.Fatalf("expected position closed on %s, got %v", finalDate.Format("2006-01-02"), closed.Closed)
+	}
+	// Weighted exit price: (50*44 + 50*38) / 100 = 41.00
+	if closed.ExitPrice == nil || math.Abs(*closed.ExitPrice-41.0) > 0.001 {
+		t.Fatalf("expected weighted exit price 41.00, got %v", closed.ExitPrice)
+	}
+	// Realized: 50*(44-40) + 50*(38-40) = 100
+	if got := closed.CalculateRealizedProfitLoss(); math.Abs(got-100.0) > 0.001 {
+		t.Fatalf("expected realized P/L 100.00, got %.2f", got)
+	}
+	if events := closed.ExitEvents(); len(events) != 2 || !sameDay(&events[1].Exited, &finalDate) {
+		t.Fatalf("expected 2 exit events ending %s, got %+v", finalDate.Format("2006-01-02"), events)
+	}
+
+	// Removing an exit reopens the lot
+	if err := lpService.DeleteExit(closed.Exits[1].ID); err != nil {
+		t.Fatalf("failed to delete exit: %v", err)
+	}
+	reopened, err := lpService.GetByID(position.ID)
+	if err != nil {
+		t.Fatalf("failed to fetch reopened position: %v", err)
+	}
+	if reopened.Closed != nil || reopened.ExitPrice != nil {
+		t.Fatalf("expected position to reopen after deleting exit")
+	}
+}
+
+func TestExitEventsFallBackToClosedLot(t *testing.T) {
+	closed := time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)
+	exitPrice := 31.0
+	legacy := &LongPosition{ID: 7, Shares: 100, BuyPrice: 30.0, Closed: &closed, ExitPrice: &exitPrice}
+
+	events := legacy.ExitEvents()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 exit event for a lot closed without exit rows, got %d", len(events))
+	}
+	if events[0].Shares != 100 || events[0].Price != 31.0 || !sameDay(&events[0].Exited, &closed) {
+		t.Fatalf("expected 100 shares at 31.00 on %s, got %+v", closed.Format("2006-01-02"), events[0])
+	}
+
+	open := &LongPosition{ID: 8, Shares: 100, BuyPrice: 30.0}
+	if events := open.ExitEvents(); len(events) != 0 {
+		t.Fatalf("expected no exit events for an open lot, got %d", len(events))
+	}
+}
+
+func TestLongPositionAmountsUseRemainingShares(t *testing.T) {
+	db := setupLongPositionTestDB(t)
+	defer db.Close()
+
+	lpService := NewLongPositionService(db)
+
+	position, err := lpService.Create("CCC", time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), 200, 20.0)
+	if err != nil {
+		t.Fatalf("failed to create long position: %v", err)
+	}
+	if _, err := lpService.AddExit(position.ID, time.Date(2025, 5, 15, 0, 0, 0, 0, time.UTC), 150, 25.0); err != nil {
+		t.Fatalf("failed to add exit: %v", err)
+	}
+
+	partial, err := lpService.GetByID(position.ID)
+	if err != nil {
+		t.Fatalf("failed to fetch position: %v", err)
+	}
+	if err := lpService.AttachExits([]*LongPosition{partial}); err != nil {
+		t.Fatalf("failed to attach exits: %v", err)
+	}
+
+	if got := partial.RemainingShares(); got != 50 {
+		t.Fatalf("expected 50 remaining shares, got %d", got)
+	}
+	// Only the 50 shares still held count: 50 * 20 = 1000
+	if got := partial.CalculateAmount(); math.Abs(got-1000.0) > 0.001 {
+		t.Fatalf("expected amount 1000.00, got %.2f", got)
+	}
+	if got := partial.CalculateTotalInvested(); math.Abs(got-1000.0) > 0.001 {
+		t.Fatalf("expected total invested 1000.00, got %.2f", got)
+	}
+	// Realized 150 * (25 - 20) = 750 plus unrealized 50 * (22 - 20) = 100
+	if got := partial.CalculateProfitLoss(22.0); math.Abs(got-850.0) > 0.001 {
+		t.Fatalf("expected profit/loss 850.00, got %.2f", got)
+	}
+}
+
+func TestAssignPutModesProduceSameAdjustedBasis(t *testing.T) {
+	opened := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	expiration := time.Date(2025, 4, 17, 0, 0, 0, 0, time.UTC)
+	callOpened := time.Date(2025, 4, 22, 0, 0, 0, 0, time.UTC)
+
+	type result struct {
+		buyPrice, perShare, total float64
+	}
+	assign := func(mode string) result {
+		db := setupLongPositionTestDB(t)
+		defer db.Close()
+
+		lpService := NewLongPositionService(db)
+		putResult, err := db.Exec(`
+			INSERT INTO options (symbol, type, opened, strike, expiration, premium, contracts, commission)
+			VALUES ('CCC', 'Put', ?, 30.0, ?, 1.20, 2, 1.30)
+		`, opened, expiration)
+		if err != nil {
+			t.Fatalf("failed to insert put option: %v", err)
+		}
+		putID, _ := putResult.LastInsertId()
+
+		position, err := lpService.AssignPut(int(putID), expiration, mode)
+		if err != nil {
+			t.Fatalf("assign put (%s) failed: %v", mode, err)
+		}
+		if position.Shares != 200 {
+			t.Fatalf("expected 200 shares, got %d", position.Shares)
+		}
+
+		// A covered call written afterwards must adjust both modes identically
+		if _, err := db.Exec(`
+			INSERT INTO options (symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission)
+			VALUES ('CCC', 'Call', ?, ?, 32.0, ?, 0.40, 2, 0.0, 1.30)
+		`, callOpened, callOpened, callOpened); err != nil {
+			t.Fatalf("failed to insert call option: %v", err)
+		}
+		if err := lpService.RecalculateAdjustedCostBasisForSymbol("CCC"); err != nil {
+			t.Fatalf("recalculate failed: %v", err)
+		}
+
+		updated, err := lpService.GetByID(position.ID)
+		if err != nil {
+			t.Fatalf("failed to fetch position: %v", err)
+		}
+		return result{updated.BuyPrice, updated.AdjustedCostBasisPerShare, updated.AdjustedCostBasisTotal}
+	}
+
+	strike := assign(AssignmentBuyPriceStrike)
+	net := assign(AssignmentBuyPriceNetPremium)
+
+	if math.Abs(strike.buyPrice-30.0) > 0.001 {
+		t.Fatalf("strike mode should record buy price at strike, got %.2f", strike.buyPrice)
+	}
+	if math.Abs(net.buyPrice-28.80) > 0.001 {
+		t.Fatalf("net mode should record buy price net of premium, got %.2f", net.buyPrice)
+	}
+
+	// Put premium 1.20 and call premium 0.40 => adjusted basis 30.00 - 1.60 = 28.40 in both modes
+	for name, got := range map[string]result{"strike": strike, "net_premium": net} {
+		if math.Abs(got.perShare-28.40) > 0.001 {
+			t.Fatalf("%s mode: expected adjusted basis per share 28.40, got %.4f", name, got.perShare)
+		}
+		if math.Abs(got.total-5680.0) > 0.01 {
+			t.Fatalf("%s mode: expected adjusted total 5680.00, got %.2f", name, got.total)
+		}
+	}
+}
+
+func TestApplyReverseSplitRounding(t *testing.T) {
+	opened := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	effective := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		rounding     string
+		wantBuyPrice float64
+		wantBasis    float64
+		wantCash     float64
+		wantRealized float64
+	}{
+		// 155 shares at $2 = $310; 1-for-10 leaves 15.5 shares at $20, the 0.5 share is paid out at $24
+		{name: "cash in lieu", rounding: SplitRoundingCashInLieu, wantBuyPrice: 20.0, wantBasis: 10.0, wantCash: 12.0, wantRealized: 2.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupLongPositionTestDB(t)
+			defer db.Close()
+			lpService := NewLongPositionService(db)
+
+			position, err := lpService.Create("RVS", opened, 155, 2.0)
+			if err != nil {
+				t.Fatalf("failed to create long position: %v", err)
+			}
+
+			splits, err := lpService.ApplySplit("RVS", effective, 1, 10, tt.rounding, 24.0)
+			if err != nil {
+				t.Fatalf("apply split failed: %v", err)
+			}
+			if len(splits) != 1 {
+				t.Fatalf("expected 1 split record, got %d", len(splits))
+			}
+			split := splits[0]
+			if split.SharesAfter != 15 {
+				t.Fatalf("expected 15 shares after split, got %d", split.SharesAfter)
+			}
+			if math.Abs(split.RemainderPreSplitShares-5) > 1e-9 {
+				t.Fatalf("expected 5-share pre-split remainder, got %.4f", split.RemainderPreSplitShares)
+			}
+			if math.Abs(split.RemainderShares-0.5) > 1e-9 {
+				t.Fatalf("expected 0.5 post-split remainder, got %.4f", split.RemainderShares)
+			}
+			if math.Abs(split.RemainderBasis-tt.wantBasis) > 0.001 || math.Abs(split.CashInLieu-tt.wantCash) > 0.001 || math.Abs(split.Realized-tt.wantRealized) > 0.001 {
+				t.Fatalf("expected basis %.2f, cash %.2f, realized %.2f; got %.2f, %.2f, %.2f",
+					tt.wantBasis, tt.wantCash, tt.wantRealized, split.RemainderBasis, split.CashInLieu, split.Realized)
+			}
+
+			updated, err := lpService.GetByID(position.ID)
+			if err != nil {
+				t.Fatalf("failed to fetch position: %v", err)
+			}
+			if updated.Shares != 15 {
+				t.Fatalf("expected lot to hold 15 shares, got %d", updated.Shares)
+			}
+			if math.Abs(updated.BuyPrice-tt.wantBuyPrice) > 0.001 || math.Abs(updated.AdjustedCostBasisPerShare-tt.wantBuyPrice) > 0.001 {
+				t.Fatalf("expected buy price and basis %.4f, got %.4f and %.4f", tt.wantBuyPrice, updated.BuyPrice, updated.AdjustedCostBasisPerShare)
+			}
+
+			recorded, err := lpService.GetSplits("RVS")
+			if err != nil {
+				t.Fatalf("failed to get splits: %v", err)
+			}
+			if len(recorded) != 1 || recorded[0].Rounding != tt.rounding {
+				t.Fatalf("expected one recorded %s split, got %+v", tt.rounding, recorded)
+			}
+		})
+	}
+}
+
+func TestApplySplitRequiresCashInLieuPrice(t *testing.T) {
+	db := setupLongPositionTestDB(t)
+	defer db.Close()
+	lpService := NewLongPositionService(db)
+
+	opened := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	effective := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	position, err := lpService.Create("RVS", opened, 155, 2.0)
+	if err != nil {
+		t.Fatalf("failed to create long position: %v", err)
+	}
+
+	// Without a price the half share left by 1-for-10 would be booked as sold for nothing
+	if _, err := lpService.ApplySplit("RVS", effective, 1, 10, SplitRoundingCashInLieu, 0); err == nil {
+		t.Fatalf("expected a missing cash-in-lieu price to be rejected")
+	}
+	unchanged, err := lpService.GetByID(position.ID)
+	if err != nil {
+		t.Fatalf("failed to fetch position: %v", err)
+	}
+	if unchanged.Shares != 155 || unchanged.BuyPrice != 2.0 {
+		t.Fatalf("expected the lot untouched at 155 shares at $2, got %d at $%.2f", unchanged.Shares, unchanged.BuyPrice)
+	}
+
+	// A split without a remainder needs no price
+	splits, err := lpService.ApplySplit("RVS", effective, 2, 1, SplitRoundingCashInLieu, 0)
+	if err != nil {
+		t.Fatalf("apply split failed: %v", err)
+	}
+	if len(splits) != 1 || splits[0].SharesAfter != 310 || splits[0].Realized != 0 {
+		t.Fatalf("expected 310 shares and nothing realized, got %+v", splits)
+	}
+}
@@ -14,7 +14,7 @@ const (
 	// TreasuryValue is the total value of open treasury transactions
 	TreasuryValue MetricType = "treasury_value"
 
-	// TotalValue is the total value of Treasuries and Longs
+	// TotalValue is the total value of Treasuries and Longs (Longs only when treasuries are excluded)
 	TotalValue MetricType = "total_value"
 
 	// LongValue is the total value of all open long_positions
@@ -47,11 +47,48 @@ type Metric struct {
 }
 
 type MetricService struct {
-	db *sql.DB
+	db                *sql.DB
+	location          func() *time.Location
+	now               func() time.Time
+	includeTreasuries func() bool
 }
 
 func NewMetricService(db *sql.DB) *MetricService {
-	return &MetricService{db: db}
+	return &MetricService{db: db, now: time.Now}
+}
+
+// SetMarketLocation sets how the service looks up the timezone that decides which calendar day
+// "today" is for snapshots. It is read on every snapshot, so set it once when the service is built;
+// without one, or when it returns nil, the server's local time is used.
+func (ms *MetricService) SetMarketLocation(location func() *time.Location) {
+	ms.location = location
+}
+
+// SetIncludeTreasuries sets how the service decides whether treasuries count toward the TotalValue
+// metric; TreasuryValue is still recorded either way. Like SetMarketLocation it is read on every
+// snapshot; without one, treasuries are included.
+func (ms *MetricService) SetIncludeTreasuries(include func() bool) {
+	ms.includeTreasuries = include
+}
+
+// marketLocation returns the market timezone, defaulting to the server's local time
+func (ms *MetricService) marketLocation() *time.Location {
+	if ms.location != nil {
+		if location := ms.location(); location != nil {
+			return location
+		}
+	}
+	return time.Local
+}
+
+// treasuriesIncluded reports whether treasuries count toward TotalValue
+func (ms *MetricService) treasuriesIncluded() bool {
+	return ms.includeTreasuries == nil || ms.includeTreasuries()
+}
+
+// marketNow returns the current time in the market timezone
+func (ms *MetricService) marketNow() time.Time {
+	return ms.now().In(ms.marketLocation())
 }
 
 func (ms *MetricService) Create(metricType MetricType, value float64) (*Metric, error) {
@@ -185,97 +222,127 @@ func (ms *MetricService) ComprehensiveSnapshot(days int) error {
 		return fmt.Errorf("days must be positive")
 	}
 
-	// Get today's date and calculate the start date
-	today := time.Now()
+	// Get today's date in the market timezone and calculate the start date
+	today := ms.marketNow()
 
 	// For each day in the range, calculate and upsert all metrics
 	for i := 0; i < days; i++ {
-		targetDate := today.AddDate(0, 0, -i)
-
-		// Calculate and upsert treasury value
-		treasuryValue, err := ms.calculateTreasuryValueForDate(targetDate)
-		if err != nil {
-			return fmt.Errorf("failed to calculate treasury value for %s: %w", targetDate.Format("2006-01-02"), err)
-		}
-		if err = ms.upsertMetricForDate(TreasuryValue, treasuryValue, targetDate); err != nil {
-			return fmt.Errorf("failed to upsert treasury metric for %s: %w", targetDate.Format("2006-01-02"), err)
+		if _, err := ms.RecomputeSnapshotForDate(today.AddDate(0, 0, -i)); err != nil {
+			return err
 		}
+	}
 
-		// Calculate and upsert long value
-		longValue, err := ms.calculateLongValueForDate(targetDate)
-		if err != nil {
-			return fmt.Errorf("failed to calculate long value for %s: %w", targetDate.Format("2006-01-02"), err)
-		}
-		if err = ms.upsertMetricForDate(LongValue, longValue, targetDate); err != nil {
-			return fmt.Errorf("failed to upsert long value metric for %s: %w", targetDate.Format("2006-01-02"), err)
-		}
+	return nil
+}
 
-		// Calculate and upsert long count
-		longCount, err := ms.calculateLongCountForDate(targetDate)
-		if err != nil {
-			return fmt.Errorf("failed to calculate long count for %s: %w", targetDate.Format("2006-01-02"), err)
-		}
-		if err = ms.upsertMetricForDate(LongCount, longCount, targetDate); err != nil {
-			return fmt.Errorf("failed to upsert long count metric for %s: %w", targetDate.Format("2006-01-02"), err)
-		}
+// RecomputeSnapshotForDate recalculates every snapshot metric as of one date and upserts it over
+// whatever was recorded that day, returning the values written
+func (ms *MetricService) RecomputeSnapshotForDate(targetDate time.Time) (map[MetricType]float64, error) {
+	values, err := ms.calculateMetricsForDate(targetDate, "")
+	if err != nil {
+		return nil, err
+	}
 
-		// Calculate and upsert put exposure
-		putExposure, err := ms.calculatePutExposureForDate(targetDate)
-		if err != nil {
-			return fmt.Errorf("failed to calculate put exposure for %s: %w", targetDate.Format("2006-01-02"), err)
-		}
-		if err = ms.upsertMetricForDate(PutExposure, putExposure, targetDate); err != nil {
-			return fmt.Errorf("failed to upsert put exposure metric for %s: %w", targetDate.Format("2006-01-02"), err)
+	for _, metricType := range snapshotMetricTypes {
+		if err := ms.upsertMetricForDate(metricType, values[metricType], targetDate); err != nil {
+			return nil, fmt.Errorf("failed to upsert %s metric for %s: %w", metricType, targetDate.Format("2006-01-02"), err)
 		}
+	}
+	return values, nil
+}
 
-		// Calculate and upsert open put premium
-		openPutPremium, err := ms.calculateOpenPutPremiumForDate(targetDate)
-		if err != nil {
-			return fmt.Errorf("failed to calculate open put premium for %s: %w", targetDate.Format("2006-01-02"), err)
-		}
-		if err = ms.upsertMetricForDate(OpenPutPremium, openPutPremium, targetDate); err != nil {
-			return fmt.Errorf("failed to upsert open put premium metric for %s: %w", targetDate.Format("2006-01-02"), err)
-		}
+// snapshotMetricTypes lists the metric types written by a comprehensive snapshot, in write order
+var snapshotMetricTypes = []MetricType{
+	TreasuryValue,
+	LongValue,
+	LongCount,
+	PutExposure,
+	OpenPutPremium,
+	OpenPutCount,
+	OpenCallPremium,
+	OpenCallCount,
+	TotalValue,
+}
 
-		// Calculate and upsert open put count
-		openPutCount, err := ms.calculateOpenPutCountForDate(targetDate)
-		if err != nil {
-			return fmt.Errorf("failed to calculate open put count for %s: %w", targetDate.Format("2006-01-02"), err)
-		}
-		if err = ms.upsertMetricForDate(OpenPutCount, openPutCount, targetDate); err != nil {
-			return fmt.Errorf("failed to upsert open put count metric for %s: %w", targetDate.Format("2006-01-02"), err)
+// MetricSnapshotPoint holds every snapshot metric value computed for a single date
+type MetricSnapshotPoint struct {
+	Date   string                 `json:"date"`
+	Values map[MetricType]float64 `json:"values"`
+}
+
+// PreviewSnapshotSeries computes the snapshot series for the last N days without persisting anything.
+// When tradingDaysOnly is set, weekends and market holidays are skipped.
+func (ms *MetricService) PreviewSnapshotSeries(days int, tradingDaysOnly bool) ([]MetricSnapshotPoint, error) {
+	if days <= 0 {
+		return nil, fmt.Errorf("days must be positive")
+	}
+
+	today := ms.marketNow()
+	var series []MetricSnapshotPoint
+	for i := days - 1; i >= 0; i-- {
+		targetDate := today.AddDate(0, 0, -i)
+		if tradingDaysOnly && !IsTradingDay(targetDate) {
+			continue
 		}
 
-		// Calculate and upsert open call premium
-		openCallPremium, err := ms.calculateOpenCallPremiumForDate(targetDate)
+		values, err := ms.calculateMetricsForDate(targetDate, "")
 		if err != nil {
-			return fmt.Errorf("failed to calculate open call premium for %s: %w", targetDate.Format("2006-01-02"), err)
-		}
-		if err = ms.upsertMetricForDate(OpenCallPremium, openCallPremium, targetDate); err != nil {
-			return fmt.Errorf("failed to upsert open call premium metric for %s: %w", targetDate.Format("2006-01-02"), err)
+			return nil, err
 		}
+		series = append(series, MetricSnapshotPoint{
+			Date:   targetDate.Format("2006-01-02"),
+			Values: values,
+		})
+	}
+
+	return series, nil
+}
 
-		// Calculate and upsert open call count
-		openCallCount, err := ms.calculateOpenCallCountForDate(targetDate)
+// calculateMetricsForDate computes every snapshot metric as of the given date, over the whole
+// portfolio or, when symbol is set, only that symbol's positions
+func (ms *MetricService) calculateMetricsForDate(targetDate time.Time, symbol string) (map[MetricType]float64, error) {
+	dateStr := targetDate.Format("2006-01-02")
+	values := make(map[MetricType]float64, len(snapshotMetricTypes))
+
+	calculations := []struct {
+		metricType MetricType
+		label      string
+		calculate  func(time.Time, string) (float64, error)
+	}{
+		{TreasuryValue, "treasury value", ms.calculateTreasuryValueForDate},
+		{LongValue, "long value", ms.calculateLongValueForDate},
+		{LongCount, "long count", ms.calculateLongCountForDate},
+		{PutExposure, "put exposure", ms.calculatePutExposureForDate},
+		{OpenPutPremium, "open put premium", ms.calculateOpenPutPremiumForDate},
+		{OpenPutCount, "open put count", ms.calculateOpenPutCountForDate},
+		{OpenCallPremium, "open call premium", ms.calculateOpenCallPremiumForDate},
+		{OpenCallCount, "open call count", ms.calculateOpenCallCountForDate},
+	}
+
+	for _, calc := range calculations {
+		value, err := calc.calculate(targetDate, symbol)
 		if err != nil {
-			return fmt.Errorf("failed to calculate open call count for %s: %w", targetDate.Format("2006-01-02"), err)
-		}
-		if err = ms.upsertMetricForDate(OpenCallCount, openCallCount, targetDate); err != nil {
-			return fmt.Errorf("failed to upsert open call count metric for %s: %w", targetDate.Format("2006-01-02"), err)
+			return nil, fmt.Errorf("failed to calculate %s for %s: %w", calc.label, dateStr, err)
 		}
+		values[calc.metricType] = value
+	}
 
-		// Calculate and upsert total value (treasuries + longs)
-		totalValue := treasuryValue + longValue
-		if err = ms.upsertMetricForDate(TotalValue, totalValue, targetDate); err != nil {
-			return fmt.Errorf("failed to upsert total value metric for %s: %w", targetDate.Format("2006-01-02"), err)
-		}
+	// Total value is treasuries + longs, or longs alone when treasuries are excluded
+	values[TotalValue] = values[LongValue]
+	if ms.treasuriesIncluded() {
+		values[TotalValue] += values[TreasuryValue]
 	}
 
-	return nil
+	return values, nil
 }
 
 // calculateTreasuryValueForDate calculates total treasury value as of a specific date
-func (ms *MetricService) calculateTreasuryValueForDate(date time.Time) (float64, error) {
+func (ms *MetricService) calculateTreasuryValueForDate(date time.Time, symbol string) (float64, error) {
+	// Treasuries carry no symbol, so they contribute nothing to a single symbol's metrics
+	if symbol != "" {
+		return 0, nil
+	}
+
 	// Query for treasuries that were active on the given date
 	// Since treasuries don't have a sold_date field, we need to handle this differently:
 	// - Include treasuries that were purchased on or before the target date
@@ -284,7 +351,7 @@ func (ms *MetricService) calculateTreasuryValueForDate(date time.Time) (float64,
 	//   but included in historical dates before they were sold (assuming sold at maturity for historical data)
 
 	// For current date calculations, only include unsold treasuries
-	if date.Format("2006-01-02") == time.Now().Format("2006-01-02") {
+	if date.Format("2006-01-02") == ms.marketNow().Format("2006-01-02") {
 		query := `
 			SELECT COALESCE(SUM(amount), 0) as total_value
 			FROM treasuries 
@@ -318,24 +385,30 @@ func (ms *MetricService) calculateTreasuryValueForDate(date time.Time) (float64,
 	return totalValue, nil
 }
 
+// remainingSharesAsOf is a lot's shares less its partial exits on or before the bound date, for
+// queries over long_positions
+const remainingSharesAsOf = `(shares - COALESCE((SELECT SUM(e.shares) FROM long_position_exits e
+			WHERE e.position_id = long_positions.id AND date(e.exited) <= date(?)), 0))`
+
 // calculateLongValueForDate calculates total long position value as of a specific date
-func (ms *MetricService) calculateLongValueForDate(date time.Time) (float64, error) {
+func (ms *MetricService) calculateLongValueForDate(date time.Time, symbol string) (float64, error) {
 	// Query for long positions that were active on the given date
 	// Active means: opened <= date AND (closed IS NULL OR closed > date)
-	// Value = shares * cost basis (prefer adjusted if present)
+	// Value = shares still held after partial exits * cost basis (prefer adjusted if present)
 	query := `
-		SELECT COALESCE(SUM(shares * CASE 
+		SELECT COALESCE(SUM(` + remainingSharesAsOf + ` * CASE 
 			WHEN adjusted_cost_basis_per_share > 0 THEN adjusted_cost_basis_per_share 
 			ELSE buy_price 
 		END), 0) as total_value
 		FROM long_positions 
 		WHERE date(opened) <= date(?) 
 		AND (closed IS NULL OR date(closed) > date(?))
+		AND (? = '' OR symbol = ?)
 	`
 
 	dateStr := date.Format("2006-01-02")
 	var totalValue float64
-	err := ms.db.QueryRow(query, dateStr, dateStr).Scan(&totalValue)
+	err := ms.db.QueryRow(query, dateStr, dateStr, dateStr, symbol, symbol).Scan(&totalValue)
 	if err != nil {
 		return 0, fmt.Errorf("failed to calculate long value: %w", err)
 	}
@@ -344,19 +417,21 @@ func (ms *MetricService) calculateLongValueForDate(date time.Time) (float64, err
 }
 
 // calculateLongCountForDate calculates total count of long positions as of a specific date
-func (ms *MetricService) calculateLongCountForDate(date time.Time) (float64, error) {
+func (ms *MetricService) calculateLongCountForDate(date time.Time, symbol string) (float64, error) {
 	// Query for long positions that were active on the given date
-	// Active means: opened <= date AND (closed IS NULL OR closed > date)
+	// Active means: opened <= date AND (closed IS NULL OR closed > date), with shares left after exits
 	query := `
 		SELECT COALESCE(COUNT(*), 0) as total_count
 		FROM long_positions 
 		WHERE date(opened) <= date(?) 
 		AND (closed IS NULL OR date(closed) > date(?))
+		AND ` + remainingSharesAsOf + ` > 0
+		AND (? = '' OR symbol = ?)
 	`
 
 	dateStr := date.Format("2006-01-02")
 	var totalCount int64
-	err := ms.db.QueryRow(query, dateStr, dateStr).Scan(&totalCount)
+	err := ms.db.QueryRow(query, dateStr, dateStr, dateStr, symbol, symbol).Scan(&totalCount)
 	if err != nil {
 		return 0, fmt.Errorf("failed to calculate long count: %w", err)
 	}
@@ -365,7 +440,7 @@ func (ms *MetricService) calculateLongCountForDate(date time.Time) (float64, err
 }
 
 // calculatePutExposureForDate calculates total put option exposure as of a specific date
-func (ms *MetricService) calculatePutExposureForDate(date time.Time) (float64, error) {
+func (ms *MetricService) calculatePutExposureForDate(date time.Time, symbol string) (float64, error) {
 	// Query for put options that were active on the given date
 	// Active means: opened <= date AND (closed IS NULL OR closed > date) AND type = 'Put'
 	// Exposure = strike * contracts * 100 (standard option contract multiplier)
@@ -374,12 +449,14 @@ func (ms *MetricService) calculatePutExposureForDate(date time.Time) (float64, e
 		FROM options 
 		WHERE date(opened) <= date(?) 
 		AND (closed IS NULL OR date(closed) > date(?))
+		AND status != 'canceled'
 		AND type = 'Put'
+		AND (? = '' OR symbol = ?)
 	`
 
 	dateStr := date.Format("2006-01-02")
 	var totalExposure float64
-	err := ms.db.QueryRow(query, dateStr, dateStr).Scan(&totalExposure)
+	err := ms.db.QueryRow(query, dateStr, dateStr, symbol, symbol).Scan(&totalExposure)
 	if err != nil {
 		return 0, fmt.Errorf("failed to calculate put exposure: %w", err)
 	}
@@ -388,7 +465,7 @@ func (ms *MetricService) calculatePutExposureForDate(date time.Time) (float64, e
 }
 
 // calculateOpenPutPremiumForDate calculates total premium value of open put options as of a specific date
-func (ms *MetricService) calculateOpenPutPremiumForDate(date time.Time) (float64, error) {
+func (ms *MetricService) calculateOpenPutPremiumForDate(date time.Time, symbol string) (float64, error) {
 	// Query for put options that were active on the given date
 	// Active means: opened <= date AND (closed IS NULL OR closed > date) AND type = 'Put'
 	// Premium value = premium * contracts * 100 (standard option contract multiplier)
@@ -397,12 +474,14 @@ func (ms *MetricService) calculateOpenPutPremiumForDate(date time.Time) (float64
 		FROM options 
 		WHERE date(opened) <= date(?) 
 		AND (closed IS NULL OR date(closed) > date(?))
+		AND status != 'canceled'
 		AND type = 'Put'
+		AND (? = '' OR symbol = ?)
 	`
 
 	dateStr := date.Format("2006-01-02")
 	var totalPremium float64
-	err := ms.db.QueryRow(query, dateStr, dateStr).Scan(&totalPremium)
+	err := ms.db.QueryRow(query, dateStr, dateStr, symbol, symbol).Scan(&totalPremium)
 	if err != nil {
 		return 0, fmt.Errorf("failed to calculate open put premium: %w", err)
 	}
@@ -411,7 +490,7 @@ func (ms *MetricService) calculateOpenPutPremiumForDate(date time.Time) (float64
 }
 
 // calculateOpenPutCountForDate calculates total count of open put options as of a specific date
-func (ms *MetricService) calculateOpenPutCountForDate(date time.Time) (float64, error) {
+func (ms *MetricService) calculateOpenPutCountForDate(date time.Time, symbol string) (float64, error) {
 	// Query for put options that were active on the given date
 	// Active means: opened <= date AND (closed IS NULL OR closed > date) AND type = 'Put'
 	query := `
@@ -419,12 +498,14 @@ func (ms *MetricService) calculateOpenPutCountForDate(date time.Time) (float64,
 		FROM options 
 		WHERE date(opened) <= date(?) 
 		AND (closed IS NULL OR date(closed) > date(?))
+		AND status != 'canceled'
 		AND type = 'Put'
+		AND (? = '' OR symbol = ?)
 	`
 
 	dateStr := date.Format("2006-01-02")
 	var totalCount int64
-	err := ms.db.QueryRow(query, dateStr, dateStr).Scan(&totalCount)
+	err := ms.db.QueryRow(query, dateStr, dateStr, symbol, symbol).Scan(&totalCount)
 	if err != nil {
 		return 0, fmt.Errorf("failed to calculate open put count: %w", err)
 	}
@@ -433,7 +514,7 @@ func (ms *MetricService) calculateOpenPutCountForDate(date time.Time) (float64,
 }
 
 // calculateOpenCallPremiumForDate calculates total premium value of open call options as of a specific date
-func (ms *MetricService) calculateOpenCallPremiumForDate(date time.Time) (float64, error) {
+func (ms *MetricService) calculateOpenCallPremiumForDate(date time.Time, symbol string) (float64, error) {
 	// Query for call options that were active on the given date
 	// Active means: opened <= date AND (closed IS NULL OR closed > date) AND type = 'Call'
 	// Premium value = premium * contracts * 100 (standard option contract multiplier)
@@ -442,12 +523,14 @@ func (ms *MetricService) calculateOpenCallPremiumForDate(date time.Time) (float6
 		FROM options 
 		WHERE date(opened) <= date(?) 
 		AND (closed IS NULL OR date(closed) > date(?))
+		AND status != 'canceled'
 		AND type = 'Call'
+		AND (? = '' OR symbol = ?)
 	`
 
 	dateStr := date.Format("2006-01-02")
 	var totalPremium float64
-	err := ms.db.QueryRow(query, dateStr, dateStr).Scan(&totalPremium)
+	err := ms.db.QueryRow(query, dateStr, dateStr, symbol, symbol).Scan(&totalPremium)
 	if err != nil {
 		return 0, fmt.Errorf("failed to calculate open call premium: %w", err)
 	}
@@ -456,7 +539,7 @@ func (ms *MetricService) calculateOpenCallPremiumForDate(date time.Time) (float6
 }
 
 // calculateOpenCallCountForDate calculates total count of open call options as of a specific date
-func (ms *MetricService) calculateOpenCallCountForDate(date time.Time) (float64, error) {
+func (ms *MetricService) calculateOpenCallCountForDate(date time.Time, symbol string) (float64, error) {
 	// Query for call options that were active on the given date
 	// Active means: opened <= date AND (closed IS NULL OR closed > date) AND type = 'Call'
 	query := `
@@ -464,12 +547,14 @@ func (ms *MetricService) calculateOpenCallCountForDate(date time.Time) (float64,
 		FROM options 
 		WHERE date(opened) <= date(?) 
 		AND (closed IS NULL OR date(closed) > date(?))
+		AND status != 'canceled'
 		AND type = 'Call'
+		AND (? = '' OR symbol = ?)
 	`
 
 	dateStr := date.Format("2006-01-02")
 	var totalCount int64
-	err := ms.db.QueryRow(query, dateStr, dateStr).Scan(&totalCount)
+	err := ms.db.QueryRow(query, dateStr, dateStr, symbol, symbol).Scan(&totalCount)
 	if err != nil {
 		return 0, fmt.Errorf("failed to calculate open call count: %w", err)
 	}
@@ -477,6 +562,144 @@ func (ms *MetricService) calculateOpenCallCountForDate(date time.Time) (float64,
 	return float64(totalCount), nil
 }
 
+// CalculateSymbolContribution returns how much one symbol contributes to each snapshot metric on a
+// date, using the same activity rules as the portfolio-wide calculations scoped to the symbol.
+// Treasuries carry no symbol, so the symbol's total value contribution is its long value.
+func (ms *MetricService) CalculateSymbolContribution(symbol string, date time.Time) (map[MetricType]float64, error) {
+	values, err := ms.calculateMetricsForDate(date, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate contribution for %s: %w", symbol, err)
+	}
+	return values, nil
+}
+
+// CalculateMetricsForDate computes every snapshot metric as of the given date without persisting it
+func (ms *MetricService) CalculateMetricsForDate(date time.Time) (map[MetricType]float64, error) {
+	return ms.calculateMetricsForDate(date, "")
+}
+
+// AverageCapitalDeployed averages the daily snapshot sum of put exposure, long cost basis and
+// treasury value over metric history between from and to (inclusive). It returns the average and
+// the number of snapshot dates it was taken over; zero dates means no history in the range.
+func (ms *MetricService) AverageCapitalDeployed(from, to time.Time) (float64, int, error) {
+	query := `
+		SELECT date(created) AS day, SUM(value)
+		FROM metrics
+		WHERE type IN (?, ?, ?)
+		AND date(created) BETWEEN date(?) AND date(?)
+		GROUP BY day
+	`
+
+	rows, err := ms.db.Query(query, string(PutExposure), string(LongValue), string(TreasuryValue),
+		from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get capital deployed history: %w", err)
+	}
+	defer rows.Close()
+
+	var total float64
+	var days int
+	for rows.Next() {
+		var day string
+		var capital float64
+		if err := rows.Scan(&day, &capital); err != nil {
+			return 0, 0, fmt.Errorf("failed to scan capital deployed: %w", err)
+		}
+		total += capital
+		days++
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("error iterating capital deployed history: %w", err)
+	}
+
+	if days == 0 {
+		return 0, 0, nil
+	}
+	return total / float64(days), days, nil
+}
+
+// GetLatestSnapshot returns the most recent value of each metric type, ordered by type
+func (ms *MetricService) GetLatestSnapshot() ([]*Metric, error) {
+	query := `SELECT m.id, m.created, m.type, m.value FROM metrics m
+		JOIN (SELECT type, MAX(created) AS created FROM metrics GROUP BY type) latest
+			ON latest.type = m.type AND latest.created = m.created
+		ORDER BY m.type, m.id DESC`
+	rows, err := ms.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest metrics: %w", err)
+	}
+	defer rows.Close()
+
+	var metrics []*Metric
+	seen := make(map[MetricType]bool)
+	for rows.Next() {
+		var metric Metric
+		if err := rows.Scan(&metric.ID, &metric.Created, &metric.Type, &metric.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan metric: %w", err)
+		}
+		// Two rows of a type sharing the latest timestamp keep the newest insert
+		if seen[metric.Type] {
+			continue
+		}
+		seen[metric.Type] = true
+		metrics = append(metrics, &metric)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating metrics: %w", err)
+	}
+
+	return metrics, nil
+}
+
+// RecordedDates returns the calendar dates (YYYY-MM-DD) between from and to (inclusive) that have a
+// value of the given metric type
+func (ms *MetricService) RecordedDates(metricType MetricType, from, to time.Time) (map[string]bool, error) {
+	rows, err := ms.db.Query(`SELECT DISTINCT date(created) FROM metrics WHERE type = ? AND date(created) BETWEEN date(?) AND date(?)`,
+		string(metricType), from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recorded metric dates: %w", err)
+	}
+	defer rows.Close()
+
+	dates := make(map[string]bool)
+	for rows.Next() {
+		var day string
+		if err := rows.Scan(&day); err != nil {
+			return nil, fmt.Errorf("failed to scan metric date: %w", err)
+		}
+		dates[day] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating metric dates: %w", err)
+	}
+
+	return dates, nil
+}
+
+// FirstRecordedDate returns the earliest date a metric type was recorded, or false when it has no history
+func (ms *MetricService) FirstRecordedDate(metricType MetricType) (time.Time, bool, error) {
+	var day sql.NullString
+	if err := ms.db.QueryRow(`SELECT MIN(date(created)) FROM metrics WHERE type = ?`, string(metricType)).Scan(&day); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get first metric date: %w", err)
+	}
+	if !day.Valid {
+		return time.Time{}, false, nil
+	}
+	first, err := time.Parse("2006-01-02", day.String)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to parse first metric date: %w", err)
+	}
+	return first, true, nil
+}
+
+// SnapshotMetricTypes returns the metric types written by a comprehensive snapshot, in write order
+func SnapshotMetricTypes() []MetricType {
+	return append([]MetricType(nil), snapshotMetricTypes...)
+}
+
 // upsertMetricForDate inserts or updates a metric for a specific date
 func (ms *MetricService) upsertMetricForDate(metricType MetricType, value float64, date time.Time) error {
 	// First, try to find an existing metric for this date and type
@@ -488,8 +711,8 @@ func (ms *MetricService) upsertMetricForDate(metricType MetricType, value float6
 
 	if err == sql.ErrNoRows {
 		// No existing metric, insert new one with the target date
-		// Set time to noon for consistent historical snapshots
-		dateWithTime := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, date.Location())
+		// Set time to noon in the market timezone for consistent historical snapshots
+		dateWithTime := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, ms.marketLocation())
 		insertQuery := `INSERT INTO metrics (created, type, value) VALUES (?, ?, ?)`
 		_, err = ms.db.Exec(insertQuery, dateWithTime, string(metricType), value)
 		if err != nil {
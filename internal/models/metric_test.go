@@ -545,4 +545,97 @@ func TestMetricService_ComprehensiveSnapshot(t *testing.T) {
 	} else {
 		t.Errorf("Missing open call count metric for date %s", testDate3Key)
 	}
-}
\ No newline at end of file
+}
+
+func TestMetricService_TodaySnapshotUsesMarketDate(t *testing.T) {
+	testDB, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer testDB.Close()
+
+	market, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("Failed to load market timezone: %v", err)
+	}
+
+	// 02:30 UTC on March 11 is still the evening of March 10 in New York
+	metricService := NewMetricService(testDB.DB)
+	metricService.SetMarketLocation(func() *time.Location { return market })
+	metricService.now = func() time.Time {
+		return time.Date(2025, 3, 11, 2, 30, 0, 0, time.UTC)
+	}
+
+	if err := metricService.ComprehensiveSnapshot(1); err != nil {
+		t.Fatalf("ComprehensiveSnapshot failed: %v", err)
+	}
+
+	var snapshotDate string
+	var count int
+	err = testDB.DB.QueryRow(`SELECT date(created), COUNT(*) FROM metrics GROUP BY date(created)`).Scan(&snapshotDate, &count)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot date: %v", err)
+	}
+
+	if snapshotDate != "2025-03-10" {
+		t.Errorf("Expected today snapshot on market date 2025-03-10, got %s", snapshotDate)
+	}
+	if count != len(snapshotMetricTypes) {
+		t.Errorf("Expected %d metrics for the snapshot date, got %d", len(snapshotMetricTypes), count)
+	}
+}
+
+func TestMetricService_SnapshotSubtractsPartialExits(t *testing.T) {
+	testDB, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	defer testDB.Close()
+
+	metricService := NewMetricService(testDB.DB)
+	symbolService := NewSymbolService(testDB.DB)
+	positionService := NewLongPositionService(testDB.DB)
+
+	if _, err := symbolService.Create("AAPL"); err != nil {
+		t.Fatalf("Failed to create symbol: %v", err)
+	}
+	position, err := positionService.Create("AAPL", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), 100, 50.0)
+	if err != nil {
+		t.Fatalf("Failed to create long position: %v", err)
+	}
+	if _, err := positionService.AddExit(position.ID, time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC), 40, 55.0); err != nil {
+		t.Fatalf("Failed to add partial exit: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		date      time.Time
+		wantValue float64
+	}{
+		{"before the exit", time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), 5000},
+		{"on the exit date", time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC), 3000},
+		{"after the exit", time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC), 3000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := metricService.RecomputeSnapshotForDate(tt.date)
+			if err != nil {
+				t.Fatalf("RecomputeSnapshotForDate failed: %v", err)
+			}
+			if values[LongValue] != tt.wantValue || values[TotalValue] != tt.wantValue {
+				t.Errorf("Expected long and total value %.2f, got %.2f and %.2f", tt.wantValue, values[LongValue], values[TotalValue])
+			}
+			if values[LongCount] != 1 {
+				t.Errorf("Expected 1 long position, got %.0f", values[LongCount])
+			}
+
+			contribution, err := metricService.CalculateSymbolContribution("AAPL", tt.date)
+			if err != nil {
+				t.Fatalf("CalculateSymbolContribution failed: %v", err)
+			}
+			if contribution[LongValue] != tt.wantValue || contribution[LongCount] != 1 {
+				t.Errorf("Expected AAPL contribution %.2f over 1 lot, got %.2f over %.0f", tt.wantValue, contribution[LongValue], contribution[LongCount])
+			}
+		})
+	}
+}
@@ -2,8 +2,11 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"math"
+	"strings"
 	"time"
 )
 
@@ -11,13 +14,20 @@ import (
 const OptionCommissionPerContract = 0.65
 
 type OptionService struct {
-	db *sql.DB
+	db             *sql.DB
+	marketLocation func() *time.Location
 }
 
 func NewOptionService(db *sql.DB) *OptionService {
 	return &OptionService{db: db}
 }
 
+// SetMarketLocation sets how the service looks up the MARKET_TIMEZONE that decides which calendar
+// day "today" is when separating pending trades; without one, the server's local time is used.
+func (s *OptionService) SetMarketLocation(location func() *time.Location) {
+	s.marketLocation = location
+}
+
 func (s *OptionService) Create(symbol, optionType string, opened time.Time, strike float64, expiration time.Time, premium float64, contracts int) (*Option, error) {
 	// Automatically calculate opening commission: $0.65 per contract
 	openingCommission := OptionCommissionPerContract * float64(contracts)
@@ -25,19 +35,29 @@ func (s *OptionService) Create(symbol, optionType string, opened time.Time, stri
 }
 
 func (s *OptionService) CreateWithCommission(symbol, optionType string, opened time.Time, strike float64, expiration time.Time, premium float64, contracts int, commission float64) (*Option, error) {
+	return s.CreateInAccount(symbol, optionType, opened, strike, expiration, premium, contracts, commission, DefaultAccount)
+}
+
+// CreateInAccount creates an option in a broker account, written with the row so the trade is
+// never left in the wrong account; a blank account uses DefaultAccount. The same trade may be held
+// in several accounts, so account is part of the option's unique key.
+func (s *OptionService) CreateInAccount(symbol, optionType string, opened time.Time, strike float64, expiration time.Time, premium float64, contracts int, commission float64, account string) (*Option, error) {
 	if optionType != "Put" && optionType != "Call" {
 		return nil, fmt.Errorf("option type must be 'Put' or 'Call'")
 	}
+	if contracts <= 0 {
+		return nil, fmt.Errorf("contracts must be positive")
+	}
 
-	query := `INSERT INTO options (symbol, type, opened, strike, expiration, premium, contracts, commission) 
-			  VALUES (?, ?, ?, ?, ?, ?, ?, ?) 
-			  RETURNING id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, current_price, created_at, updated_at`
+	query := `INSERT INTO options (symbol, type, opened, strike, expiration, premium, contracts, commission, account) 
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) 
+			  RETURNING id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, fees, current_price, account, assigned, assigned_position_id, strategy, rolled_from_id, realized_profit, currency, fx_rate, underlying_at_open, underlying_at_close, status, created_at, updated_at`
 
 	var option Option
-	err := s.db.QueryRow(query, symbol, optionType, opened, strike, expiration, premium, contracts, commission).Scan(
+	err := s.db.QueryRow(query, symbol, optionType, opened, strike, expiration, premium, contracts, commission, NormalizeAccount(account)).Scan(
 		&option.ID, &option.Symbol, &option.Type, &option.Opened, &option.Closed, &option.Strike,
 		&option.Expiration, &option.Premium, &option.Contracts, &option.ExitPrice, &option.Commission,
-		&option.CurrentPrice, &option.CreatedAt, &option.UpdatedAt,
+		&option.Fees, &option.CurrentPrice, &option.Account, &option.Assigned, &option.AssignedPositionID, &option.Strategy, &option.RolledFromID, &option.RealizedProfit, &option.Currency, &option.FXRate, &option.UnderlyingAtOpen, &option.UnderlyingAtClose, &option.Status, &option.CreatedAt, &option.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create option: %w", err)
@@ -47,8 +67,8 @@ func (s *OptionService) CreateWithCommission(symbol, optionType string, opened t
 }
 
 func (s *OptionService) GetBySymbol(symbol string) ([]*Option, error) {
-	query := `SELECT id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, current_price, created_at, updated_at 
-			  FROM options WHERE symbol = ? ORDER BY expiration DESC, opened DESC`
+	query := `SELECT id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, fees, current_price, account, assigned, assigned_position_id, strategy, rolled_from_id, realized_profit, currency, fx_rate, underlying_at_open, underlying_at_close, status, created_at, updated_at 
+			  FROM options WHERE symbol = ? AND status != 'canceled' ORDER BY expiration DESC, opened DESC`
 
 	rows, err := s.db.Query(query, symbol)
 	if err != nil {
@@ -61,7 +81,7 @@ func (s *OptionService) GetBySymbol(symbol string) ([]*Option, error) {
 		var option Option
 		if err := rows.Scan(&option.ID, &option.Symbol, &option.Type, &option.Opened, &option.Closed,
 			&option.Strike, &option.Expiration, &option.Premium, &option.Contracts,
-			&option.ExitPrice, &option.Commission, &option.CurrentPrice, &option.CreatedAt, &option.UpdatedAt); err != nil {
+			&option.ExitPrice, &option.Commission, &option.Fees, &option.CurrentPrice, &option.Account, &option.Assigned, &option.AssignedPositionID, &option.Strategy, &option.RolledFromID, &option.RealizedProfit, &option.Currency, &option.FXRate, &option.UnderlyingAtOpen, &option.UnderlyingAtClose, &option.Status, &option.CreatedAt, &option.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan option: %w", err)
 		}
 		options = append(options, &option)
@@ -75,8 +95,8 @@ func (s *OptionService) GetBySymbol(symbol string) ([]*Option, error) {
 }
 
 func (s *OptionService) GetAll() ([]*Option, error) {
-	query := `SELECT id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, current_price, created_at, updated_at 
-			  FROM options ORDER BY expiration DESC, opened DESC`
+	query := `SELECT id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, fees, current_price, account, assigned, assigned_position_id, strategy, rolled_from_id, realized_profit, currency, fx_rate, underlying_at_open, underlying_at_close, status, created_at, updated_at 
+			  FROM options WHERE status != 'canceled' ORDER BY expiration DESC, opened DESC`
 
 	rows, err := s.db.Query(query)
 	if err != nil {
@@ -89,7 +109,7 @@ func (s *OptionService) GetAll() ([]*Option, error) {
 		var option Option
 		if err := rows.Scan(&option.ID, &option.Symbol, &option.Type, &option.Opened, &option.Closed,
 			&option.Strike, &option.Expiration, &option.Premium, &option.Contracts,
-			&option.ExitPrice, &option.Commission, &option.CurrentPrice, &option.CreatedAt, &option.UpdatedAt); err != nil {
+			&option.ExitPrice, &option.Commission, &option.Fees, &option.CurrentPrice, &option.Account, &option.Assigned, &option.AssignedPositionID, &option.Strategy, &option.RolledFromID, &option.RealizedProfit, &option.Currency, &option.FXRate, &option.UnderlyingAtOpen, &option.UnderlyingAtClose, &option.Status, &option.CreatedAt, &option.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan option: %w", err)
 		}
 		options = append(options, &option)
@@ -103,10 +123,10 @@ func (s *OptionService) GetAll() ([]*Option, error) {
 }
 
 func (s *OptionService) GetOpen() ([]*Option, error) {
-	query := `SELECT id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, current_price, created_at, updated_at 
-			  FROM options WHERE closed IS NULL ORDER BY expiration ASC`
+	query := `SELECT id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, fees, current_price, account, assigned, assigned_position_id, strategy, rolled_from_id, realized_profit, currency, fx_rate, underlying_at_open, underlying_at_close, status, created_at, updated_at 
+			  FROM options WHERE closed IS NULL AND opened < ? AND status != 'canceled' ORDER BY expiration ASC`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(query, pendingCutoff(marketNow(s.marketLocation)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get open options: %w", err)
 	}
@@ -117,7 +137,43 @@ func (s *OptionService) GetOpen() ([]*Option, error) {
 		var option Option
 		if err := rows.Scan(&option.ID, &option.Symbol, &option.Type, &option.Opened, &option.Closed,
 			&option.Strike, &option.Expiration, &option.Premium, &option.Contracts,
-			&option.ExitPrice, &option.Commission, &option.CurrentPrice, &option.CreatedAt, &option.UpdatedAt); err != nil {
+			&option.ExitPrice, &option.Commission, &option.Fees, &option.CurrentPrice, &option.Account, &option.Assigned, &option.AssignedPositionID, &option.Strategy, &option.RolledFromID, &option.RealizedProfit, &option.Currency, &option.FXRate, &option.UnderlyingAtOpen, &option.UnderlyingAtClose, &option.Status, &option.CreatedAt, &option.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan option: %w", err)
+		}
+		options = append(options, &option)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating options: %w", err)
+	}
+
+	return options, nil
+}
+
+// pendingCutoff returns the start of the day after now, which callers take in the market timezone;
+// trades opened on or after it are pending planned trades, kept out of open positions and summaries until their opened date arrives
+func pendingCutoff(now time.Time) time.Time {
+	year, month, day := now.Date()
+	return calendarDate(year, month, day).AddDate(0, 0, 1)
+}
+
+// GetPending retrieves open options whose opened date is still in the future
+func (s *OptionService) GetPending() ([]*Option, error) {
+	query := `SELECT id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, fees, current_price, account, assigned, assigned_position_id, strategy, rolled_from_id, realized_profit, currency, fx_rate, underlying_at_open, underlying_at_close, status, created_at, updated_at 
+			  FROM options WHERE closed IS NULL AND opened >= ? AND status != 'canceled' ORDER BY opened ASC, expiration ASC`
+
+	rows, err := s.db.Query(query, pendingCutoff(marketNow(s.marketLocation)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending options: %w", err)
+	}
+	defer rows.Close()
+
+	var options []*Option
+	for rows.Next() {
+		var option Option
+		if err := rows.Scan(&option.ID, &option.Symbol, &option.Type, &option.Opened, &option.Closed,
+			&option.Strike, &option.Expiration, &option.Premium, &option.Contracts,
+			&option.ExitPrice, &option.Commission, &option.Fees, &option.CurrentPrice, &option.Account, &option.Assigned, &option.AssignedPositionID, &option.Strategy, &option.RolledFromID, &option.RealizedProfit, &option.Currency, &option.FXRate, &option.UnderlyingAtOpen, &option.UnderlyingAtClose, &option.Status, &option.CreatedAt, &option.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan option: %w", err)
 		}
 		options = append(options, &option)
@@ -130,60 +186,180 @@ func (s *OptionService) GetOpen() ([]*Option, error) {
 	return options, nil
 }
 
-func (s *OptionService) Close(symbol, optionType string, opened time.Time, strike float64, expiration time.Time, premium float64, contracts int, closed time.Time, exitPrice float64) error {
+// GetOpenByType retrieves open options of the given type ("Put" or "Call"), optionally limited to one symbol
+func (s *OptionService) GetOpenByType(symbol, optionType string) ([]*Option, error) {
+	query := `SELECT id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, fees, current_price, account, assigned, assigned_position_id, strategy, rolled_from_id, realized_profit, currency, fx_rate, underlying_at_open, underlying_at_close, status, created_at, updated_at 
+			  FROM options WHERE closed IS NULL AND opened < ? AND type = ? AND (? = '' OR symbol = ?) AND status != 'canceled' ORDER BY strike ASC, expiration ASC`
+
+	rows, err := s.db.Query(query, pendingCutoff(marketNow(s.marketLocation)), optionType, symbol, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open options by type: %w", err)
+	}
+	defer rows.Close()
+
+	var options []*Option
+	for rows.Next() {
+		var option Option
+		if err := rows.Scan(&option.ID, &option.Symbol, &option.Type, &option.Opened, &option.Closed,
+			&option.Strike, &option.Expiration, &option.Premium, &option.Contracts,
+			&option.ExitPrice, &option.Commission, &option.Fees, &option.CurrentPrice, &option.Account, &option.Assigned, &option.AssignedPositionID, &option.Strategy, &option.RolledFromID, &option.RealizedProfit, &option.Currency, &option.FXRate, &option.UnderlyingAtOpen, &option.UnderlyingAtClose, &option.Status, &option.CreatedAt, &option.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan option: %w", err)
+		}
+		options = append(options, &option)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating options: %w", err)
+	}
+
+	return options, nil
+}
+
+// ErrAmbiguousOptionKey is returned when a compound-key operation matches more than one option
+// and was not asked to apply to every match
+var ErrAmbiguousOptionKey = errors.New("compound key matches more than one option")
+
+// optionKeyWhere matches an option on its compound key (symbol, type, opened, strike, expiration,
+// premium, contracts, account), the columns of idx_options_unique. The key is still not guaranteed
+// unique: rows written before the index existed, or with differently formatted dates, can share it.
+const optionKeyWhere = `symbol = ? AND type = ? AND opened = ? AND strike = ? AND expiration = ? AND premium = ? AND contracts = ? AND account = ?`
+
+// FindByKey returns every option matching the compound key, lowest ID first. A blank account
+// matches DefaultAccount.
+func (s *OptionService) FindByKey(symbol, optionType string, opened time.Time, strike float64, expiration time.Time, premium float64, contracts int, account string) ([]*Option, error) {
+	query := `SELECT id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, fees, current_price, account, assigned, assigned_position_id, strategy, rolled_from_id, realized_profit, currency, fx_rate, underlying_at_open, underlying_at_close, status, created_at, updated_at 
+			  FROM options WHERE ` + optionKeyWhere + ` ORDER BY id`
+
+	rows, err := s.db.Query(query, symbol, optionType, opened, strike, expiration, premium, contracts, NormalizeAccount(account))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find options by key: %w", err)
+	}
+	defer rows.Close()
+
+	var options []*Option
+	for rows.Next() {
+		var option Option
+		if err := rows.Scan(&option.ID, &option.Symbol, &option.Type, &option.Opened, &option.Closed,
+			&option.Strike, &option.Expiration, &option.Premium, &option.Contracts,
+			&option.ExitPrice, &option.Commission, &option.Fees, &option.CurrentPrice, &option.Account, &option.Assigned, &option.AssignedPositionID, &option.Strategy, &option.RolledFromID, &option.RealizedProfit, &option.Currency, &option.FXRate, &option.UnderlyingAtOpen, &option.UnderlyingAtClose, &option.Status, &option.CreatedAt, &option.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan option: %w", err)
+		}
+		options = append(options, &option)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating options: %w", err)
+	}
+
+	return options, nil
+}
+
+// keyMatchIDs returns the IDs matching a compound key. More than one match is an
+// ErrAmbiguousOptionKey unless all is set, in which case every match is returned with a warning.
+func (s *OptionService) keyMatchIDs(operation string, all bool, symbol, optionType string, opened time.Time, strike float64, expiration time.Time, premium float64, contracts int, account string) ([]int, error) {
+	matches, err := s.FindByKey(symbol, optionType, opened, strike, expiration, premium, contracts, account)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("option not found")
+	}
+	ids := make([]int, len(matches))
+	for i, match := range matches {
+		ids[i] = match.ID
+	}
+	if len(ids) > 1 {
+		if !all {
+			return nil, fmt.Errorf("%w: %d options (ids %v) match %s %s %s in %s; %s by id instead", ErrAmbiguousOptionKey, len(ids), ids, symbol, optionType, opened.Format("2006-01-02"), NormalizeAccount(account), operation)
+		}
+		log.Printf("[OPTIONS] WARNING: %s by compound key affects %d options (ids %v) for %s %s %s in %s", operation, len(ids), ids, symbol, optionType, opened.Format("2006-01-02"), NormalizeAccount(account))
+	}
+	return ids, nil
+}
+
+// Close closes the option matching the composite key with the default per-contract closing commission.
+// See CloseWithCommission for how multiple matches are handled.
+func (s *OptionService) Close(symbol, optionType string, opened time.Time, strike float64, expiration time.Time, premium float64, contracts int, account string, closed time.Time, exitPrice float64, all bool) error {
 	// Calculate closing commission: $0.65 per contract
 	closingCommission := OptionCommissionPerContract * float64(contracts)
+	return s.CloseWithCommission(symbol, optionType, opened, strike, expiration, premium, contracts, account, closed, exitPrice, closingCommission, all)
+}
 
-	query := `UPDATE options 
-			  SET closed = ?, exit_price = ?, commission = commission + ?, updated_at = CURRENT_TIMESTAMP 
-			  WHERE symbol = ? AND type = ? AND opened = ? AND strike = ? AND expiration = ? AND premium = ? AND contracts = ?`
-
-	result, err := s.db.Exec(query, closed, exitPrice, closingCommission, symbol, optionType, opened, strike, expiration, premium, contracts)
+// CloseWithCommission closes the option matching the composite key, adding closingCommission to its commission.
+// When several options share the key it fails with ErrAmbiguousOptionKey unless all is set, which closes every match
+// in one transaction: either every match is closed or none is.
+func (s *OptionService) CloseWithCommission(symbol, optionType string, opened time.Time, strike float64, expiration time.Time, premium float64, contracts int, account string, closed time.Time, exitPrice float64, closingCommission float64, all bool) error {
+	ids, err := s.keyMatchIDs("close", all, symbol, optionType, opened, strike, expiration, premium, contracts, account)
 	if err != nil {
-		return fmt.Errorf("failed to close option: %w", err)
+		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("option not found")
+	for _, id := range ids {
+		if err := closeOptionByID(tx, id, closed, exitPrice, closingCommission); err != nil {
+			return err
+		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
-func (s *OptionService) Delete(symbol, optionType string, opened time.Time, strike float64, expiration time.Time, premium float64, contracts int) error {
-	query := `DELETE FROM options WHERE symbol = ? AND type = ? AND opened = ? AND strike = ? AND expiration = ? AND premium = ? AND contracts = ?`
-	result, err := s.db.Exec(query, symbol, optionType, opened, strike, expiration, premium, contracts)
+// Delete deletes the option matching the composite key and returns how many rows were removed.
+// When several options share the key it fails with ErrAmbiguousOptionKey unless all is set, which deletes every match
+// in one transaction: either every match is deleted or none is.
+func (s *OptionService) Delete(symbol, optionType string, opened time.Time, strike float64, expiration time.Time, premium float64, contracts int, account string, all bool) (int, error) {
+	ids, err := s.keyMatchIDs("delete", all, symbol, optionType, opened, strike, expiration, premium, contracts, account)
 	if err != nil {
-		return fmt.Errorf("failed to delete option: %w", err)
+		return 0, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("option not found")
+	for _, id := range ids {
+		if err := deleteOptionByID(tx, id); err != nil {
+			return 0, err
+		}
 	}
 
-	return nil
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(ids), nil
+}
+
+// optionQuerier is satisfied by both *sql.DB and *sql.Tx
+type optionQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
 // GetByID retrieves an option by its ID
 func (s *OptionService) GetByID(id int) (*Option, error) {
-	query := `SELECT id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, current_price, created_at, updated_at 
+	return getOptionByID(s.db, id)
+}
+
+// getOptionByID loads an option through q, so it sees writes made earlier in a transaction
+func getOptionByID(q optionQuerier, id int) (*Option, error) {
+	query := `SELECT id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, fees, current_price, account, assigned, assigned_position_id, strategy, rolled_from_id, realized_profit, currency, fx_rate, underlying_at_open, underlying_at_close, status, created_at, updated_at 
 			  FROM options WHERE id = ?`
 
 	var option Option
-	err := s.db.QueryRow(query, id).Scan(
+	err := q.QueryRow(query, id).Scan(
 		&option.ID, &option.Symbol, &option.Type, &option.Opened, &option.Closed,
 		&option.Strike, &option.Expiration, &option.Premium, &option.Contracts,
-		&option.ExitPrice, &option.Commission, &option.CurrentPrice, &option.CreatedAt, &option.UpdatedAt,
+		&option.ExitPrice, &option.Commission, &option.Fees, &option.CurrentPrice, &option.Account, &option.Assigned, &option.AssignedPositionID, &option.Strategy, &option.RolledFromID, &option.RealizedProfit, &option.Currency, &option.FXRate, &option.UnderlyingAtOpen, &option.UnderlyingAtClose, &option.Status, &option.CreatedAt, &option.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -195,22 +371,48 @@ func (s *OptionService) GetByID(id int) (*Option, error) {
 	return &option, nil
 }
 
-// UpdateByID updates an option by its ID
+// UpdateByID updates an option by its ID, keeping its account
 func (s *OptionService) UpdateByID(id int, symbol, optionType string, opened time.Time, strike float64, expiration time.Time, premium float64, contracts int, commission float64, closed *time.Time, exitPrice *float64) (*Option, error) {
+	return s.UpdateByIDInAccount(id, symbol, optionType, opened, strike, expiration, premium, contracts, commission, closed, exitPrice, nil)
+}
+
+// UpdateByIDInAccount updates an option by its ID and, when account is not nil, moves it to that
+// account in the same statement, since the account is part of the option's unique key
+func (s *OptionService) UpdateByIDInAccount(id int, symbol, optionType string, opened time.Time, strike float64, expiration time.Time, premium float64, contracts int, commission float64, closed *time.Time, exitPrice *float64, account *string) (*Option, error) {
 	if optionType != "Put" && optionType != "Call" {
 		return nil, fmt.Errorf("option type must be 'Put' or 'Call'")
 	}
+	if contracts <= 0 {
+		return nil, fmt.Errorf("contracts must be positive")
+	}
 
 	query := `UPDATE options 
-			  SET symbol = ?, type = ?, opened = ?, strike = ?, expiration = ?, premium = ?, contracts = ?, commission = ?, closed = ?, exit_price = ?, updated_at = CURRENT_TIMESTAMP 
+			  SET symbol = ?, type = ?, opened = ?, strike = ?, expiration = ?, premium = ?, contracts = ?, commission = ?, closed = ?, exit_price = ?,
+			      account = COALESCE(?, account),
+			      assigned = CASE WHEN ? IS NULL THEN 0 ELSE assigned END,
+			      assigned_position_id = CASE WHEN ? IS NULL THEN NULL ELSE assigned_position_id END,
+			      underlying_at_open = CASE WHEN symbol = ? AND opened = ? THEN underlying_at_open ELSE NULL END,
+			      underlying_at_close = CASE WHEN symbol = ? AND closed IS ? THEN underlying_at_close ELSE NULL END,
+			      updated_at = CURRENT_TIMESTAMP 
 			  WHERE id = ? 
-			  RETURNING id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, current_price, created_at, updated_at`
+			  RETURNING id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, fees, current_price, account, assigned, assigned_position_id, strategy, rolled_from_id, realized_profit, currency, fx_rate, underlying_at_open, underlying_at_close, status, created_at, updated_at`
+
+	var normalizedAccount *string
+	if account != nil {
+		normalized := NormalizeAccount(*account)
+		normalizedAccount = &normalized
+	}
+
+	before, err := getOptionByID(s.db, id)
+	if err != nil {
+		return nil, err
+	}
 
 	var option Option
-	err := s.db.QueryRow(query, symbol, optionType, opened, strike, expiration, premium, contracts, commission, closed, exitPrice, id).Scan(
+	err = s.db.QueryRow(query, symbol, optionType, opened, strike, expiration, premium, contracts, commission, closed, exitPrice, normalizedAccount, closed, closed, symbol, opened, symbol, closed, id).Scan(
 		&option.ID, &option.Symbol, &option.Type, &option.Opened, &option.Closed,
 		&option.Strike, &option.Expiration, &option.Premium, &option.Contracts,
-		&option.ExitPrice, &option.Commission, &option.CurrentPrice, &option.CreatedAt, &option.UpdatedAt,
+		&option.ExitPrice, &option.Commission, &option.Fees, &option.CurrentPrice, &option.Account, &option.Assigned, &option.AssignedPositionID, &option.Strategy, &option.RolledFromID, &option.RealizedProfit, &option.Currency, &option.FXRate, &option.UnderlyingAtOpen, &option.UnderlyingAtClose, &option.Status, &option.CreatedAt, &option.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -219,13 +421,31 @@ func (s *OptionService) UpdateByID(id int, symbol, optionType string, opened tim
 		return nil, fmt.Errorf("failed to update option: %w", err)
 	}
 
+	// Edits that leave the profit inputs alone keep the snapshot taken when the option closed
+	if profitInputsChanged(before, &option) {
+		if err := recordRealizedProfit(s.db, option.ID); err != nil {
+			return nil, err
+		}
+		if option.Closed != nil {
+			realized := option.CalculateTotalProfit()
+			option.RealizedProfit = &realized
+		} else {
+			option.RealizedProfit = nil
+		}
+	}
+
 	return &option, nil
 }
 
 // DeleteByID deletes an option by its ID
 func (s *OptionService) DeleteByID(id int) error {
+	return deleteOptionByID(s.db, id)
+}
+
+// deleteOptionByID deletes an option through q
+func deleteOptionByID(q optionQuerier, id int) error {
 	query := `DELETE FROM options WHERE id = ?`
-	result, err := s.db.Exec(query, id)
+	result, err := q.Exec(query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete option: %w", err)
 	}
@@ -252,12 +472,21 @@ func (s *OptionService) CloseByID(id int, closed time.Time, exitPrice float64) e
 
 	// Calculate closing commission: $0.65 per contract
 	closingCommission := OptionCommissionPerContract * float64(option.Contracts)
+	return s.CloseByIDWithCommission(id, closed, exitPrice, closingCommission)
+}
+
+// CloseByIDWithCommission closes an option by its ID, adding closingCommission to its commission
+func (s *OptionService) CloseByIDWithCommission(id int, closed time.Time, exitPrice float64, closingCommission float64) error {
+	return closeOptionByID(s.db, id, closed, exitPrice, closingCommission)
+}
 
+// closeOptionByID closes an option through q and records its realized profit in the same place
+func closeOptionByID(q optionQuerier, id int, closed time.Time, exitPrice float64, closingCommission float64) error {
 	query := `UPDATE options 
 			  SET closed = ?, exit_price = ?, commission = commission + ?, updated_at = CURRENT_TIMESTAMP 
 			  WHERE id = ?`
 
-	result, err := s.db.Exec(query, closed, exitPrice, closingCommission, id)
+	result, err := q.Exec(query, closed, exitPrice, closingCommission, id)
 	if err != nil {
 		return fmt.Errorf("failed to close option: %w", err)
 	}
@@ -271,9 +500,383 @@ func (s *OptionService) CloseByID(id int, closed time.Time, exitPrice float64) e
 		return fmt.Errorf("option not found")
 	}
 
+	return recordRealizedProfit(q, id)
+}
+
+// SetAccount tags an option with a broker account; a blank account resets it to DefaultAccount
+func (s *OptionService) SetAccount(id int, account string) error {
+	result, err := s.db.Exec(`UPDATE options SET account = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, NormalizeAccount(account), id)
+	if err != nil {
+		return fmt.Errorf("failed to set option account: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("option not found")
+	}
+
+	return nil
+}
+
+// SetStrategy labels an option with a strategy (e.g. CSP, CC, wheel, spread); a blank label
+// clears it so the strategy is inferred again
+func (s *OptionService) SetStrategy(id int, strategy string) error {
+	result, err := s.db.Exec(`UPDATE options SET strategy = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, nullableString(NormalizeStrategy(strategy)), id)
+	if err != nil {
+		return fmt.Errorf("failed to set option strategy: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("option not found")
+	}
+
+	return nil
+}
+
+// SetFees records an option's total exchange and regulatory fees, kept apart from the broker
+// commission, and refreshes its realized profit snapshot since net profit subtracts both. Setting
+// the fees already stored leaves the option and its snapshot untouched.
+func (s *OptionService) SetFees(id int, fees float64) error {
+	if fees < 0 {
+		return fmt.Errorf("fees cannot be negative")
+	}
+
+	before, err := getOptionByID(s.db, id)
+	if err != nil {
+		return err
+	}
+	if before.Fees == fees {
+		return nil
+	}
+
+	if _, err := s.db.Exec(`UPDATE options SET fees = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, fees, id); err != nil {
+		return fmt.Errorf("failed to set option fees: %w", err)
+	}
+
+	return recordRealizedProfit(s.db, id)
+}
+
+// BaseCurrency is the currency profit and exposure are reported in; options default to it at rate 1.0
+const BaseCurrency = "USD"
+
+// NormalizeCurrency upper-cases a currency code, returning BaseCurrency when it is blank
+func NormalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return BaseCurrency
+	}
+	return currency
+}
+
+// ToBase converts an amount in the option's currency to the base currency at the trade-time FX rate
+func (o *Option) ToBase(amount float64) float64 {
+	if o.FXRate <= 0 {
+		return amount
+	}
+	return amount * o.FXRate
+}
+
+// SetCurrency records the trading currency of an option and the FX rate (base currency per unit)
+// captured at trade time. The base currency always uses a rate of 1.0.
+func (s *OptionService) SetCurrency(id int, currency string, fxRate float64) error {
+	currency = NormalizeCurrency(currency)
+	if currency == BaseCurrency {
+		fxRate = 1.0
+	}
+	if fxRate <= 0 {
+		return fmt.Errorf("fx rate must be positive")
+	}
+
+	result, err := s.db.Exec(`UPDATE options SET currency = ?, fx_rate = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, currency, fxRate, id)
+	if err != nil {
+		return fmt.Errorf("failed to set option currency: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("option not found")
+	}
+
+	return nil
+}
+
+// Option statuses. A canceled option is an order that never filled: it is kept for the record but
+// excluded from metrics, profit and exposure.
+const (
+	OptionStatusActive   = "active"
+	OptionStatusCanceled = "canceled"
+)
+
+// NormalizeOptionStatus lower-cases a status, returning OptionStatusActive when it is blank
+func NormalizeOptionStatus(status string) (string, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+	switch status {
+	case "":
+		return OptionStatusActive, nil
+	case OptionStatusActive, OptionStatusCanceled:
+		return status, nil
+	}
+	return "", fmt.Errorf("status must be %q or %q", OptionStatusActive, OptionStatusCanceled)
+}
+
+// IsCanceled reports whether the option is a canceled order kept only for the record
+func (o *Option) IsCanceled() bool {
+	return o.Status == OptionStatusCanceled
+}
+
+// SetStatus marks an option active or canceled
+func (s *OptionService) SetStatus(id int, status string) error {
+	status, err := NormalizeOptionStatus(status)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(`UPDATE options SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, status, id)
+	if err != nil {
+		return fmt.Errorf("failed to set option status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("option not found")
+	}
+
+	return nil
+}
+
+// GetAllWithCanceled retrieves every option including canceled orders, for exports and maintenance
+// that must see the full record
+func (s *OptionService) GetAllWithCanceled() ([]*Option, error) {
+	return s.queryOptions(`SELECT id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, fees, current_price, account, assigned, assigned_position_id, strategy, rolled_from_id, realized_profit, currency, fx_rate, underlying_at_open, underlying_at_close, status, created_at, updated_at 
+			  FROM options ORDER BY expiration DESC, opened DESC`)
+}
+
+// GetBySymbolWithCanceled retrieves a symbol's options including canceled orders
+func (s *OptionService) GetBySymbolWithCanceled(symbol string) ([]*Option, error) {
+	return s.queryOptions(`SELECT id, symbol, type, opened, closed, strike, expiration, premium, contracts, exit_price, commission, fees, current_price, account, assigned, assigned_position_id, strategy, rolled_from_id, realized_profit, currency, fx_rate, underlying_at_open, underlying_at_close, status, created_at, updated_at 
+			  FROM options WHERE symbol = ? ORDER BY expiration DESC, opened DESC`, symbol)
+}
+
+func (s *OptionService) queryOptions(query string, args ...interface{}) ([]*Option, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get options: %w", err)
+	}
+	defer rows.Close()
+
+	var options []*Option
+	for rows.Next() {
+		var option Option
+		if err := rows.Scan(&option.ID, &option.Symbol, &option.Type, &option.Opened, &option.Closed,
+			&option.Strike, &option.Expiration, &option.Premium, &option.Contracts,
+			&option.ExitPrice, &option.Commission, &option.Fees, &option.CurrentPrice, &option.Account, &option.Assigned, &option.AssignedPositionID, &option.Strategy, &option.RolledFromID, &option.RealizedProfit, &option.Currency, &option.FXRate, &option.UnderlyingAtOpen, &option.UnderlyingAtClose, &option.Status, &option.CreatedAt, &option.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan option: %w", err)
+		}
+		options = append(options, &option)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating options: %w", err)
+	}
+
+	return options, nil
+}
+
+// SetUnderlyingPrices stores the underlying price on the option's opened and closed dates; a nil
+// price leaves that side unchanged
+func (s *OptionService) SetUnderlyingPrices(id int, atOpen, atClose *float64) error {
+	result, err := s.db.Exec(`UPDATE options SET underlying_at_open = COALESCE(?, underlying_at_open), underlying_at_close = COALESCE(?, underlying_at_close) WHERE id = ?`, atOpen, atClose, id)
+	if err != nil {
+		return fmt.Errorf("failed to set option underlying prices: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("option not found")
+	}
+
+	return nil
+}
+
+// NeedsUnderlyingPrices reports whether the option is missing its underlying price at open, or at
+// close once it has been closed
+func (o *Option) NeedsUnderlyingPrices() bool {
+	return o.UnderlyingAtOpen == nil || (o.Closed != nil && o.UnderlyingAtClose == nil)
+}
+
+// SetRolledFrom links an option to the option it was rolled from; a nil fromID clears the link
+func (s *OptionService) SetRolledFrom(id int, fromID *int) error {
+	if fromID != nil && *fromID == id {
+		return fmt.Errorf("an option cannot be rolled from itself")
+	}
+
+	result, err := s.db.Exec(`UPDATE options SET rolled_from_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, fromID, id)
+	if err != nil {
+		return fmt.Errorf("failed to set option roll link: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("option not found")
+	}
+
 	return nil
 }
 
+// SetRolledFromLinks records every link in one transaction, so a missing option or a
+// self-roll anywhere in the list leaves all roll links unchanged
+func (s *OptionService) SetRolledFromLinks(links []RollLink) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, link := range links {
+		if link.FromID == link.ToID {
+			return fmt.Errorf("option %d cannot be rolled from itself", link.ToID)
+		}
+		if _, err := getOptionByID(tx, link.FromID); err != nil {
+			return fmt.Errorf("option %d not found", link.FromID)
+		}
+		result, err := tx.Exec(`UPDATE options SET rolled_from_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, link.FromID, link.ToID)
+		if err != nil {
+			return fmt.Errorf("failed to set option roll link: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("option %d not found", link.ToID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// SetAssignment marks a closed option as assigned (or clears the flag), optionally linking the
+// long position the assignment opened or called away. Assigned options keep their full premium.
+func (s *OptionService) SetAssignment(id int, assigned bool, positionID *int) error {
+	if !assigned {
+		positionID = nil
+	}
+
+	before, err := getOptionByID(s.db, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(`UPDATE options SET assigned = ?, assigned_position_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND (closed IS NOT NULL OR ? = 0)`,
+		assigned, positionID, id, assigned)
+	if err != nil {
+		return fmt.Errorf("failed to set option assignment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("option not found or not closed")
+	}
+
+	// Assignment zeroes the exit price in the profit, so only a change of flag re-snapshots it
+	if before.Assigned == assigned {
+		return nil
+	}
+	return recordRealizedProfit(s.db, id)
+}
+
+// profitInputsChanged reports whether an edit touched any field net profit is computed from:
+// premium, exit price, contracts, commission, fees, the close date or the assignment flag
+func profitInputsChanged(before, after *Option) bool {
+	if before.Premium != after.Premium || before.Contracts != after.Contracts || before.Commission != after.Commission ||
+		before.Fees != after.Fees || before.Assigned != after.Assigned {
+		return true
+	}
+	if (before.ExitPrice == nil) != (after.ExitPrice == nil) || before.ExitPrice != nil && *before.ExitPrice != *after.ExitPrice {
+		return true
+	}
+	return (before.Closed == nil) != (after.Closed == nil) || before.Closed != nil && !before.Closed.Equal(*after.Closed)
+}
+
+// recordRealizedProfit snapshots a closed option's net profit into realized_profit, or clears it
+// for an open option, so historical reports do not shift when the profit formula changes
+func recordRealizedProfit(q optionQuerier, id int) error {
+	option, err := getOptionByID(q, id)
+	if err != nil {
+		return fmt.Errorf("failed to load option for realized profit: %w", err)
+	}
+
+	var realized interface{}
+	if option.Closed != nil {
+		realized = option.CalculateTotalProfit()
+	}
+	if _, err := q.Exec(`UPDATE options SET realized_profit = ? WHERE id = ?`, realized, id); err != nil {
+		return fmt.Errorf("failed to store realized profit: %w", err)
+	}
+	return nil
+}
+
+// BackfillRealizedProfit stores realized_profit for closed options that predate the snapshot,
+// returning how many were filled. Options that already have a snapshot are left untouched.
+func (s *OptionService) BackfillRealizedProfit() (int, error) {
+	rows, err := s.db.Query(`SELECT id FROM options WHERE closed IS NOT NULL AND realized_profit IS NULL`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find options to backfill: %w", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan option to backfill: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating options to backfill: %w", err)
+	}
+
+	for i, id := range ids {
+		if err := recordRealizedProfit(s.db, id); err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
 func (s *OptionService) DeleteBySymbol(symbol string) error {
 	query := `DELETE FROM options WHERE symbol = ?`
 	result, err := s.db.Exec(query, symbol)
@@ -299,6 +902,39 @@ type OptionSummary struct {
 	PutPremium     float64 `json:"put_premium"`
 	CallPremium    float64 `json:"call_premium"`
 	NetPremium     float64 `json:"net_premium"`
+	TotalProfit    float64 `json:"total_profit"`  // realized profit of the symbol's closed options, net unless gross was requested
+	OpenExposure   float64 `json:"open_exposure"` // strike * contracts * 100 across open positions
+
+	// Book-wide fee breakdown, set on the totals only: OptionAttribution summed over every open and
+	// closed option. Open options count their premium with no buyback yet.
+	GrossPremium     float64 `json:"gross_premium,omitempty"`
+	BuybackCost      float64 `json:"buyback_cost,omitempty"` // cost to close, positive
+	TotalCommissions float64 `json:"total_commissions,omitempty"`
+	TotalFees        float64 `json:"total_fees,omitempty"`   // exchange and regulatory fees
+	GrossProfit      float64 `json:"gross_profit,omitempty"` // before commissions and fees
+	NetProfit        float64 `json:"net_profit,omitempty"`   // after commissions and fees, rounded as CalculateTotalProfit
+}
+
+// Sort keys accepted by GetOptionsSummaryBySymbolSorted
+const (
+	OptionsSummarySortSymbol   = "symbol"
+	OptionsSummarySortProfit   = "profit"
+	OptionsSummarySortExposure = "exposure"
+	OptionsSummarySortCount    = "count"
+)
+
+// optionsSummaryOrderBy whitelists the ORDER BY clause for each summary sort key
+var optionsSummaryOrderBy = map[string]string{
+	OptionsSummarySortSymbol:   "symbol",
+	OptionsSummarySortProfit:   "total_profit DESC, symbol",
+	OptionsSummarySortExposure: "open_exposure DESC, symbol",
+	OptionsSummarySortCount:    "total_positions DESC, symbol",
+}
+
+// IsValidOptionsSummarySort reports whether key is a supported options summary sort key
+func IsValidOptionsSummarySort(key string) bool {
+	_, ok := optionsSummaryOrderBy[key]
+	return ok
 }
 
 // OpenPositionData represents an open option position with additional calculated fields
@@ -311,6 +947,24 @@ type OpenPositionData struct {
 
 // GetOptionsSummaryBySymbol returns options summary data grouped by symbol
 func (s *OptionService) GetOptionsSummaryBySymbol() ([]*OptionSummary, error) {
+	return s.GetOptionsSummaryBySymbolForAccount("")
+}
+
+// GetOptionsSummaryBySymbolForAccount returns options summary data grouped by symbol for one account.
+// An empty account includes every account.
+func (s *OptionService) GetOptionsSummaryBySymbolForAccount(account string) ([]*OptionSummary, error) {
+	return s.GetOptionsSummaryBySymbolSorted(account, OptionsSummarySortSymbol, false)
+}
+
+// GetOptionsSummaryBySymbolSorted returns the per-symbol summary for one account (empty means all)
+// ordered by sortKey: symbol ascending, or profit, exposure or count descending. TotalProfit adds
+// back commission and fees when gross.
+func (s *OptionService) GetOptionsSummaryBySymbolSorted(account, sortKey string, gross bool) ([]*OptionSummary, error) {
+	orderBy, ok := optionsSummaryOrderBy[sortKey]
+	if !ok {
+		return nil, fmt.Errorf("invalid options summary sort: %s", sortKey)
+	}
+
 	query := `
 		SELECT 
 			symbol,
@@ -320,13 +974,16 @@ func (s *OptionService) GetOptionsSummaryBySymbol() ([]*OptionSummary, error) {
 			SUM(premium) as total_premium,
 			SUM(CASE WHEN type = 'Put' THEN premium ELSE 0 END) as put_premium,
 			SUM(CASE WHEN type = 'Call' THEN premium ELSE 0 END) as call_premium,
-			SUM(premium) as net_premium
+			SUM(premium) as net_premium,
+			(SELECT COALESCE(SUM(c.realized_profit + CASE WHEN ? THEN c.commission + c.fees ELSE 0 END), 0) FROM options c
+			 WHERE c.symbol = options.symbol AND c.closed IS NOT NULL AND c.status != 'canceled' AND (? = '' OR c.account = ?)) as total_profit,
+			SUM(strike * contracts * 100) as open_exposure
 		FROM options 
-		WHERE closed IS NULL
+		WHERE closed IS NULL AND contracts > 0 AND opened < ? AND status != 'canceled' AND (? = '' OR account = ?)
 		GROUP BY symbol 
-		ORDER BY symbol`
+		ORDER BY ` + orderBy
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(query, gross, account, account, pendingCutoff(marketNow(s.marketLocation)), account, account)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get options summary: %w", err)
 	}
@@ -338,6 +995,7 @@ func (s *OptionService) GetOptionsSummaryBySymbol() ([]*OptionSummary, error) {
 		if err := rows.Scan(
 			&summary.Symbol, &summary.TotalPositions, &summary.PutPositions, &summary.CallPositions,
 			&summary.TotalPremium, &summary.PutPremium, &summary.CallPremium, &summary.NetPremium,
+			&summary.TotalProfit, &summary.OpenExposure,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan options summary: %w", err)
 		}
@@ -390,22 +1048,27 @@ func (s *OptionService) GetOpenPositionsWithDetails() ([]*OpenPositionData, erro
 
 // GetOptionsSummaryTotals returns aggregate totals for all options
 func (s *OptionService) GetOptionsSummaryTotals() (*OptionSummary, error) {
+	return s.GetOptionsSummaryTotalsForAccount("")
+}
+
+// GetOptionsSummaryTotalsForAccount returns aggregate totals for one account; empty means all accounts
+func (s *OptionService) GetOptionsSummaryTotalsForAccount(account string) (*OptionSummary, error) {
 	query := `
 		SELECT 
 			COUNT(*) as total_positions,
-			SUM(CASE WHEN type = 'Put' THEN 1 ELSE 0 END) as put_positions,
-			SUM(CASE WHEN type = 'Call' THEN 1 ELSE 0 END) as call_positions,
-			SUM(premium) as total_premium,
-			SUM(CASE WHEN type = 'Put' THEN premium ELSE 0 END) as put_premium,
-			SUM(CASE WHEN type = 'Call' THEN premium ELSE 0 END) as call_premium,
-			SUM(premium) as net_premium
+			COALESCE(SUM(CASE WHEN type = 'Put' THEN 1 ELSE 0 END), 0) as put_positions,
+			COALESCE(SUM(CASE WHEN type = 'Call' THEN 1 ELSE 0 END), 0) as call_positions,
+			COALESCE(SUM(premium), 0) as total_premium,
+			COALESCE(SUM(CASE WHEN type = 'Put' THEN premium ELSE 0 END), 0) as put_premium,
+			COALESCE(SUM(CASE WHEN type = 'Call' THEN premium ELSE 0 END), 0) as call_premium,
+			COALESCE(SUM(premium), 0) as net_premium
 		FROM options 
-		WHERE closed IS NULL`
+		WHERE closed IS NULL AND contracts > 0 AND opened < ? AND status != 'canceled' AND (? = '' OR account = ?)`
 
 	var totals OptionSummary
 	totals.Symbol = "Total"
 
-	err := s.db.QueryRow(query).Scan(
+	err := s.db.QueryRow(query, pendingCutoff(marketNow(s.marketLocation)), account, account).Scan(
 		&totals.TotalPositions, &totals.PutPositions, &totals.CallPositions,
 		&totals.TotalPremium, &totals.PutPremium, &totals.CallPremium, &totals.NetPremium,
 	)
@@ -413,9 +1076,39 @@ func (s *OptionService) GetOptionsSummaryTotals() (*OptionSummary, error) {
 		return nil, fmt.Errorf("failed to get options summary totals: %w", err)
 	}
 
+	if err := s.addFeeBreakdown(&totals, account); err != nil {
+		return nil, err
+	}
 	return &totals, nil
 }
 
+// addFeeBreakdown fills the summary's gross premium, buyback cost, commissions, fees and gross and net
+// profit from the per-option attribution of every filled option in the account (empty means all)
+func (s *OptionService) addFeeBreakdown(totals *OptionSummary, account string) error {
+	rows, err := s.db.Query(`SELECT premium, contracts, exit_price, commission, fees FROM options
+		WHERE contracts > 0 AND opened < ? AND status != 'canceled' AND (? = '' OR account = ?)`,
+		pendingCutoff(marketNow(s.marketLocation)), account, account)
+	if err != nil {
+		return fmt.Errorf("failed to get options fee breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var option Option
+		if err := rows.Scan(&option.Premium, &option.Contracts, &option.ExitPrice, &option.Commission, &option.Fees); err != nil {
+			return fmt.Errorf("failed to scan options fee breakdown: %w", err)
+		}
+		attribution := option.CalculateAttribution()
+		totals.GrossPremium += attribution.Gross
+		totals.BuybackCost -= attribution.Buyback
+		totals.TotalCommissions -= attribution.Commission
+		totals.TotalFees -= attribution.Fees
+		totals.NetProfit += attribution.Net
+	}
+	totals.GrossProfit = totals.NetProfit + totals.TotalCommissions + totals.TotalFees
+	return rows.Err()
+}
+
 // Index creates a nested index structure for all options
 func (s *OptionService) Index() (map[string]interface{}, error) {
 	options, err := s.GetAll()
@@ -0,0 +1,622 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"stonks/internal/database"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupOptionTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open in-memory db: %v", err)
+	}
+
+	schema := `
+	CREATE TABLE options (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		symbol TEXT NOT NULL,
+		type TEXT NOT NULL,
+		opened DATE NOT NULL,
+		closed DATE,
+		strike REAL NOT NULL,
+		expiration DATE NOT NULL,
+		premium REAL NOT NULL,
+		contracts INTEGER NOT NULL,
+		exit_price REAL,
+		commission REAL DEFAULT 0.0,
+		fees REAL NOT NULL DEFAULT 0.0,
+		current_price REAL,
+		account TEXT NOT NULL DEFAULT 'Default',
+		assigned INTEGER NOT NULL DEFAULT 0,
+		assigned_position_id INTEGER,
+		strategy TEXT,
+		rolled_from_id INTEGER,
+		realized_profit REAL,
+		currency TEXT NOT NULL DEFAULT 'USD',
+		fx_rate REAL NOT NULL DEFAULT 1.0,
+		underlying_at_open REAL,
+		underlying_at_close REAL,
+		status TEXT NOT NULL DEFAULT 'active',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	return db
+}
+
+func TestOptionsSummaryExcludesZeroContractRows(t *testing.T) {
+	db := setupOptionTestDB(t)
+	defer db.Close()
+
+	optionService := NewOptionService(db)
+	opened := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	expiration := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	if _, err := optionService.Create("AAA", "Put", opened, 50.0, expiration, 1.25, 2); err != nil {
+		t.Fatalf("failed to create option: %v", err)
+	}
+
+	if _, err := optionService.Create("AAA", "Put", opened, 45.0, expiration, 0.80, 0); err == nil {
+		t.Fatalf("expected zero-contract option to be rejected")
+	}
+
+	// Simulate a leftover zero-contract row written outside the service
+	if _, err := db.Exec(`
+		INSERT INTO options (symbol, type, opened, strike, expiration, premium, contracts, commission)
+		VALUES ('AAA', 'Call', ?, 55.0, ?, 0.90, 0, 0.0)
+	`, opened, expiration); err != nil {
+		t.Fatalf("failed to insert zero-contract option: %v", err)
+	}
+
+	totals, err := optionService.GetOptionsSummaryTotals()
+	if err != nil {
+		t.Fatalf("failed to get summary totals: %v", err)
+	}
+	if totals.TotalPositions != 1 || totals.CallPositions != 0 {
+		t.Fatalf("expected 1 position and 0 calls, got %d positions and %d calls", totals.TotalPositions, totals.CallPositions)
+	}
+	if totals.TotalPremium != 1.25 {
+		t.Fatalf("expected total premium 1.25, got %.2f", totals.TotalPremium)
+	}
+
+	summaries, err := optionService.GetOptionsSummaryBySymbol()
+	if err != nil {
+		t.Fatalf("failed to get summary by symbol: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].TotalPositions != 1 || summaries[0].CallPositions != 0 {
+		t.Fatalf("expected zero-contract row excluded from symbol summary, got %+v", summaries)
+	}
+}
+
+func TestCloseByIDStoresRealizedProfit(t *testing.T) {
+	db := setupOptionTestDB(t)
+	defer db.Close()
+
+	optionService := NewOptionService(db)
+	opened := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	expiration := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	option, err := optionService.Create("AAA", "Put", opened, 50.0, expiration, 1.25, 2)
+	if err != nil {
+		t.Fatalf("failed to create option: %v", err)
+	}
+	if option.RealizedProfit != nil {
+		t.Fatalf("expected no realized profit on an open option, got %.2f", *option.RealizedProfit)
+	}
+
+	if err := optionService.CloseByID(option.ID, time.Date(2025, 5, 20, 0, 0, 0, 0, time.UTC), 0.50); err != nil {
+		t.Fatalf("failed to close option: %v", err)
+	}
+
+	closed, err := optionService.GetByID(option.ID)
+	if err != nil {
+		t.Fatalf("failed to reload option: %v", err)
+	}
+	// floor((1.25 - 0.50) * 2 * 100) = 150, less 2.60 opening and closing commission
+	if closed.RealizedProfit == nil || *closed.RealizedProfit != 147.40 {
+		t.Fatalf("expected stored realized profit 147.40, got %v", closed.RealizedProfit)
+	}
+
+	// A closed option written before the snapshot existed is filled by the backfill
+	if _, err := db.Exec(`UPDATE options SET realized_profit = NULL WHERE id = ?`, option.ID); err != nil {
+		t.Fatalf("failed to clear realized profit: %v", err)
+	}
+	count, err := optionService.BackfillRealizedProfit()
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 backfilled option, got %d (%v)", count, err)
+	}
+	backfilled, err := optionService.GetByID(option.ID)
+	if err != nil {
+		t.Fatalf("failed to reload option: %v", err)
+	}
+	if backfilled.RealizedProfitValue() != 147.40 {
+		t.Fatalf("expected backfilled realized profit 147.40, got %.2f", backfilled.RealizedProfitValue())
+	}
+}
+
+func TestEditKeepsRealizedProfitUnlessProfitInputsChange(t *testing.T) {
+	db := setupOptionTestDB(t)
+	defer db.Close()
+
+	optionService := NewOptionService(db)
+	opened := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	expiration := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+	closed := time.Date(2025, 5, 20, 0, 0, 0, 0, time.UTC)
+
+	option, err := optionService.Create("AAA", "Put", opened, 50.0, expiration, 1.25, 2)
+	if err != nil {
+		t.Fatalf("failed to create option: %v", err)
+	}
+	if err := optionService.CloseByID(option.ID, closed, 0.50); err != nil {
+		t.Fatalf("failed to close option: %v", err)
+	}
+	stored, err := optionService.GetByID(option.ID)
+	if err != nil {
+		t.Fatalf("failed to reload option: %v", err)
+	}
+
+	// A snapshot taken under an older profit formula
+	if _, err := db.Exec(`UPDATE options SET realized_profit = 150 WHERE id = ?`, option.ID); err != nil {
+		t.Fatalf("failed to set historical realized profit: %v", err)
+	}
+	expectRealized := func(want float64, when string) {
+		t.Helper()
+		reloaded, err := optionService.GetByID(option.ID)
+		if err != nil {
+			t.Fatalf("failed to reload option: %v", err)
+		}
+		if reloaded.RealizedProfit == nil || *reloaded.RealizedProfit != want {
+			t.Fatalf("expected realized profit %.2f %s, got %v", want, when, reloaded.RealizedProfit)
+		}
+	}
+
+	exitPrice := 0.50
+	updated, err := optionService.UpdateByIDInAccount(option.ID, "AAA", "Put", opened, 50.0, expiration, 1.25, 2, stored.Commission, &closed, &exitPrice, nil)
+	if err != nil {
+		t.Fatalf("failed to update option: %v", err)
+	}
+	if updated.RealizedProfit == nil || *updated.RealizedProfit != 150 {
+		t.Fatalf("expected the returned option to keep realized profit 150, got %v", updated.RealizedProfit)
+	}
+	if err := optionService.SetStrategy(option.ID, "CSP"); err != nil {
+		t.Fatalf("failed to set strategy: %v", err)
+	}
+	if err := optionService.SetFees(option.ID, stored.Fees); err != nil {
+		t.Fatalf("failed to set fees: %v", err)
+	}
+	expectRealized(150, "after edits that leave the profit inputs alone")
+
+	// Changing the exit price re-snapshots with the current formula
+	exitPrice = 0.25
+	if _, err := optionService.UpdateByIDInAccount(option.ID, "AAA", "Put", opened, 50.0, expiration, 1.25, 2, stored.Commission, &closed, &exitPrice, nil); err != nil {
+		t.Fatalf("failed to update option: %v", err)
+	}
+	// floor((1.25 - 0.25) * 2 * 100) = 200, less 2.60 commission
+	expectRealized(197.40, "after the exit price changed")
+}
+
+func TestOptionsSummaryTotalsFeeBreakdown(t *testing.T) {
+	db := setupOptionTestDB(t)
+	defer db.Close()
+
+	optionService := NewOptionService(db)
+	opened := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	expiration := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	closedOption, err := optionService.Create("AAA", "Put", opened, 50.0, expiration, 1.25, 2)
+	if err != nil {
+		t.Fatalf("failed to create option: %v", err)
+	}
+	if err := optionService.CloseByID(closedOption.ID, time.Date(2025, 5, 20, 0, 0, 0, 0, time.UTC), 0.50); err != nil {
+		t.Fatalf("failed to close option: %v", err)
+	}
+	if _, err := optionService.Create("BBB", "Call", opened, 30.0, expiration, 0.80, 1); err != nil {
+		t.Fatalf("failed to create option: %v", err)
+	}
+
+	totals, err := optionService.GetOptionsSummaryTotals()
+	if err != nil {
+		t.Fatalf("failed to get summary totals: %v", err)
+	}
+	// Premium 250 + 80, buyback 100, commission 2.60 + 0.65
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"gross premium", totals.GrossPremium, 330},
+		{"buyback cost", totals.BuybackCost, 100},
+		{"commissions", totals.TotalCommissions, 3.25},
+		{"gross profit", totals.GrossProfit, 230},
+		{"net profit", totals.NetProfit, 226.75},
+	}
+	for _, check := range checks {
+		if math.Abs(check.got-check.want) > 1e-9 {
+			t.Errorf("expected %s %.2f, got %.2f", check.name, check.want, check.got)
+		}
+	}
+	if totals.TotalPositions != 1 || totals.TotalPremium != 0.80 {
+		t.Fatalf("expected the open-position totals unchanged, got %d positions and %.2f premium", totals.TotalPositions, totals.TotalPremium)
+	}
+}
+
+func TestOptionFeesSubtractFromProfit(t *testing.T) {
+	db := setupOptionTestDB(t)
+	defer db.Close()
+
+	optionService := NewOptionService(db)
+	opened := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	expiration := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	option, err := optionService.Create("AAA", "Put", opened, 50.0, expiration, 1.25, 2)
+	if err != nil {
+		t.Fatalf("failed to create option: %v", err)
+	}
+	if err := optionService.CloseByID(option.ID, time.Date(2025, 5, 20, 0, 0, 0, 0, time.UTC), 0.50); err != nil {
+		t.Fatalf("failed to close option: %v", err)
+	}
+	if err := optionService.SetFees(option.ID, 0.12); err != nil {
+		t.Fatalf("failed to set fees: %v", err)
+	}
+	if err := optionService.SetFees(option.ID, -1); err == nil {
+		t.Fatalf("expected negative fees to be rejected")
+	}
+
+	stored, err := optionService.GetByID(option.ID)
+	if err != nil {
+		t.Fatalf("failed to get option: %v", err)
+	}
+	// Premium 250 less buyback 100, commission 2.60 and fees 0.12
+	if math.Abs(stored.CalculateTotalProfit()-147.28) > 1e-9 {
+		t.Fatalf("expected net profit 147.28, got %.2f", stored.CalculateTotalProfit())
+	}
+	if stored.RealizedProfit == nil || math.Abs(*stored.RealizedProfit-147.28) > 1e-9 {
+		t.Fatalf("expected realized profit snapshot 147.28, got %v", stored.RealizedProfit)
+	}
+	if gross := stored.CalculateGrossProfit(); math.Abs(gross-150) > 1e-9 {
+		t.Fatalf("expected gross profit 150, got %.2f", gross)
+	}
+	attribution := stored.CalculateAttribution()
+	if math.Abs(attribution.Commission+2.60) > 1e-9 || math.Abs(attribution.Fees+0.12) > 1e-9 {
+		t.Fatalf("expected commission -2.60 and fees -0.12, got %.2f and %.2f", attribution.Commission, attribution.Fees)
+	}
+
+	totals, err := optionService.GetOptionsSummaryTotals()
+	if err != nil {
+		t.Fatalf("failed to get summary totals: %v", err)
+	}
+	if math.Abs(totals.TotalFees-0.12) > 1e-9 || math.Abs(totals.GrossProfit-150) > 1e-9 {
+		t.Fatalf("expected summary fees 0.12 and gross profit 150, got %.2f and %.2f", totals.TotalFees, totals.GrossProfit)
+	}
+}
+
+func TestOptionAttributionReconcilesToNet(t *testing.T) {
+	exitPrice := 0.333
+	for _, option := range []Option{
+		{Premium: 1.005, Contracts: 3, Commission: 1.95, Fees: 0.07},
+		{Premium: 2.137, Contracts: 1, ExitPrice: &exitPrice, Commission: 0.65},
+		{Premium: 0.50, Contracts: 2, ExitPrice: &exitPrice, Assigned: true},
+	} {
+		attribution := option.CalculateAttribution()
+		if math.Abs(attribution.Gross-option.Premium*float64(option.Contracts)*100) > 1e-9 {
+			t.Errorf("expected unrounded gross premium for %+v, got %.4f", option, attribution.Gross)
+		}
+		if attribution.Rounding > 0 || attribution.Rounding <= -1 {
+			t.Errorf("expected rounding in (-1, 0], got %.4f", attribution.Rounding)
+		}
+		sum := attribution.Gross + attribution.Buyback + attribution.Rounding + attribution.Commission + attribution.Fees
+		if math.Abs(sum-attribution.Net) > 1e-9 {
+			t.Errorf("expected components to sum to net %.4f, got %.4f (%+v)", attribution.Net, sum, attribution)
+		}
+	}
+}
+
+func TestOptionAttributionReconcilesAtCentPrices(t *testing.T) {
+	// Cent prices are not exact in floating point, so the floored premium leg can land a dollar
+	// below gross plus buyback; e.g. 0.03 - 0.01 per share nets $1, not $2
+	for premiumCents := 1; premiumCents <= 100; premiumCents++ {
+		for exitCents := 0; exitCents <= premiumCents; exitCents++ {
+			for contracts := 1; contracts <= 3; contracts++ {
+				exitPrice := float64(exitCents) / 100
+				option := Option{Premium: float64(premiumCents) / 100, Contracts: contracts, ExitPrice: &exitPrice, Commission: 0.65 * float64(contracts), Fees: 0.02}
+				attribution := option.CalculateAttribution()
+				if attribution.Net != option.CalculateTotalProfit() {
+					t.Fatalf("expected net %.4f to match total profit %.4f", attribution.Net, option.CalculateTotalProfit())
+				}
+				sum := attribution.Gross + attribution.Buyback + attribution.Rounding + attribution.Commission + attribution.Fees
+				if math.Abs(sum-attribution.Net) > 1e-9 {
+					t.Fatalf("premium %.2f exit %.2f x%d: expected components to sum to net %.4f, got %.4f (%+v)",
+						option.Premium, exitPrice, contracts, attribution.Net, sum, attribution)
+				}
+			}
+		}
+	}
+}
+
+func TestCreateInAccountKeysTradesByAccount(t *testing.T) {
+	testDB, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("failed to setup test database: %v", err)
+	}
+	defer testDB.Close()
+
+	// Databases created before the key included account get the index rebuilt on startup
+	if _, err := testDB.Exec(`DROP INDEX idx_options_unique`); err != nil {
+		t.Fatalf("failed to drop index: %v", err)
+	}
+	if _, err := testDB.Exec(`CREATE UNIQUE INDEX idx_options_unique ON options(symbol, type, opened, strike, expiration, premium, contracts)`); err != nil {
+		t.Fatalf("failed to create legacy index: %v", err)
+	}
+	if err := testDB.InitSchema(); err != nil {
+		t.Fatalf("failed to rerun migrations: %v", err)
+	}
+
+	symbolService := NewSymbolService(testDB.DB)
+	for _, symbol := range []string{"AAA", "BBB"} {
+		if _, err := symbolService.Create(symbol); err != nil {
+			t.Fatalf("failed to create symbol: %v", err)
+		}
+	}
+
+	optionService := NewOptionService(testDB.DB)
+	opened := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	expiration := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	roth, err := optionService.CreateInAccount("AAA", "Put", opened, 50.0, expiration, 1.25, 1, 0.65, "Roth")
+	if err != nil {
+		t.Fatalf("failed to create Roth option: %v", err)
+	}
+	if roth.Account != "Roth" {
+		t.Fatalf("expected the account written with the row, got %q", roth.Account)
+	}
+	if _, err := optionService.CreateInAccount("AAA", "Put", opened, 50.0, expiration, 1.25, 1, 0.65, "Taxable"); err != nil {
+		t.Fatalf("expected the same trade in another account to be allowed: %v", err)
+	}
+	if _, err := optionService.CreateInAccount("AAA", "Put", opened, 50.0, expiration, 1.25, 1, 0.65, "Roth"); err == nil {
+		t.Fatalf("expected a duplicate within one account to be rejected")
+	}
+	if blank, err := optionService.CreateInAccount("BBB", "Put", opened, 50.0, expiration, 1.25, 1, 0.65, " "); err != nil || blank.Account != DefaultAccount {
+		t.Fatalf("expected a blank account to use %q, got %v (%v)", DefaultAccount, blank, err)
+	}
+
+	// Updates keep the account unless one is given, and move it in the same statement
+	kept, err := optionService.UpdateByID(roth.ID, "AAA", "Put", opened, 50.0, expiration, 1.25, 1, 0.65, nil, nil)
+	if err != nil || kept.Account != "Roth" {
+		t.Fatalf("expected the update to keep the Roth account, got %v (%v)", kept, err)
+	}
+	ira := "IRA"
+	moved, err := optionService.UpdateByIDInAccount(roth.ID, "AAA", "Put", opened, 50.0, expiration, 1.25, 1, 0.65, nil, nil, &ira)
+	if err != nil || moved.Account != "IRA" {
+		t.Fatalf("expected the update to move the option to IRA, got %v (%v)", moved, err)
+	}
+	taxable := "Taxable"
+	if _, err := optionService.UpdateByIDInAccount(roth.ID, "AAA", "Put", opened, 50.0, expiration, 1.25, 1, 0.65, nil, nil, &taxable); err == nil {
+		t.Fatalf("expected moving onto the same trade in another account to be rejected")
+	}
+}
+
+func TestOptionsSummaryGrossAddsBackCommission(t *testing.T) {
+	db := setupOptionTestDB(t)
+	defer db.Close()
+
+	optionService := NewOptionService(db)
+	opened := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	expiration := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	// The summary lists symbols with an open option; profit comes from the closed ones
+	if _, err := optionService.Create("AAA", "Put", opened, 45.0, expiration, 0.80, 1); err != nil {
+		t.Fatalf("failed to create open option: %v", err)
+	}
+	closed, err := optionService.Create("AAA", "Put", opened, 50.0, expiration, 1.25, 2)
+	if err != nil {
+		t.Fatalf("failed to create option: %v", err)
+	}
+	if err := optionService.CloseByID(closed.ID, time.Date(2025, 5, 20, 0, 0, 0, 0, time.UTC), 0.50); err != nil {
+		t.Fatalf("failed to close option: %v", err)
+	}
+
+	// floor((1.25 - 0.50) * 2 * 100) = 150, less 2.60 opening and closing commission
+	for _, tc := range []struct {
+		gross  bool
+		profit float64
+	}{
+		{false, 147.40},
+		{true, 150.00},
+	} {
+		summaries, err := optionService.GetOptionsSummaryBySymbolSorted("", OptionsSummarySortSymbol, tc.gross)
+		if err != nil {
+			t.Fatalf("failed to get summary (gross=%v): %v", tc.gross, err)
+		}
+		if len(summaries) != 1 || math.Abs(summaries[0].TotalProfit-tc.profit) > 1e-9 {
+			t.Fatalf("gross=%v: expected total profit %.2f, got %+v", tc.gross, tc.profit, summaries)
+		}
+	}
+}
+
+func TestCompoundKeyBulkOperationsAreAtomic(t *testing.T) {
+	db := setupOptionTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1) // one connection, so the transaction sees the in-memory schema
+
+	optionService := NewOptionService(db)
+	opened := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	expiration := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	// Legacy rows written before the unique index can share a compound key within one account
+	var ids []int
+	for i := 0; i < 2; i++ {
+		option, err := optionService.CreateInAccount("AAA", "Put", opened, 50.0, expiration, 1.25, 1, 0.65, "A")
+		if err != nil {
+			t.Fatalf("failed to create option %d: %v", i, err)
+		}
+		ids = append(ids, option.ID)
+	}
+	count := func(where string) int {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM options WHERE ` + where).Scan(&n); err != nil {
+			t.Fatalf("failed to count options: %v", err)
+		}
+		return n
+	}
+
+	if _, err := optionService.Delete("AAA", "Put", opened, 50.0, expiration, 1.25, 1, "A", false); !errors.Is(err, ErrAmbiguousOptionKey) {
+		t.Fatalf("expected ErrAmbiguousOptionKey, got %v", err)
+	}
+
+	// A failure on the second match must leave the first one untouched
+	if _, err := db.Exec(fmt.Sprintf(`CREATE TRIGGER block_second BEFORE UPDATE ON options WHEN OLD.id = %d
+		BEGIN SELECT RAISE(ABORT, 'blocked'); END`, ids[1])); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+	if err := optionService.CloseWithCommission("AAA", "Put", opened, 50.0, expiration, 1.25, 1, "A", opened.AddDate(0, 0, 10), 0.25, 0.65, true); err == nil {
+		t.Fatalf("expected the blocked close to fail")
+	}
+	if closed := count(`closed IS NOT NULL`); closed != 0 {
+		t.Fatalf("expected the failed close to roll back, got %d closed options", closed)
+	}
+	if _, err := db.Exec(`DROP TRIGGER block_second`); err != nil {
+		t.Fatalf("failed to drop trigger: %v", err)
+	}
+
+	if err := optionService.CloseWithCommission("AAA", "Put", opened, 50.0, expiration, 1.25, 1, "A", opened.AddDate(0, 0, 10), 0.25, 0.65, true); err != nil {
+		t.Fatalf("failed to close both matches: %v", err)
+	}
+	if closed := count(`closed IS NOT NULL AND realized_profit IS NOT NULL`); closed != 2 {
+		t.Fatalf("expected 2 closed options with realized profit, got %d", closed)
+	}
+
+	deleted, err := optionService.Delete("AAA", "Put", opened, 50.0, expiration, 1.25, 1, "A", true)
+	if err != nil || deleted != 2 {
+		t.Fatalf("expected 2 options deleted, got %d (%v)", deleted, err)
+	}
+	if remaining := count(`1 = 1`); remaining != 0 {
+		t.Fatalf("expected no options left, got %d", remaining)
+	}
+}
+
+func TestCompoundKeyOperationsStayInAccount(t *testing.T) {
+	db := setupOptionTestDB(t)
+	defer db.Close()
+
+	optionService := NewOptionService(db)
+	opened := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	expiration := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	// The same trade held in two accounts
+	for _, account := range []string{DefaultAccount, "Roth"} {
+		if _, err := optionService.CreateInAccount("AAA", "Put", opened, 50.0, expiration, 1.25, 1, 0.65, account); err != nil {
+			t.Fatalf("failed to create option in %s: %v", account, err)
+		}
+	}
+
+	// A blank account closes the Default row only
+	if err := optionService.CloseWithCommission("AAA", "Put", opened, 50.0, expiration, 1.25, 1, "", opened.AddDate(0, 0, 10), 0.25, 0.65, false); err != nil {
+		t.Fatalf("expected the Default row to close without match_all: %v", err)
+	}
+	roth, err := optionService.FindByKey("AAA", "Put", opened, 50.0, expiration, 1.25, 1, "Roth")
+	if err != nil || len(roth) != 1 {
+		t.Fatalf("expected one Roth match, got %d (%v)", len(roth), err)
+	}
+	if roth[0].Closed != nil {
+		t.Fatalf("closing the Default row must not close the Roth row")
+	}
+
+	deleted, err := optionService.Delete("AAA", "Put", opened, 50.0, expiration, 1.25, 1, "Roth", false)
+	if err != nil || deleted != 1 {
+		t.Fatalf("expected the Roth row deleted, got %d (%v)", deleted, err)
+	}
+	remaining, err := optionService.FindByKey("AAA", "Put", opened, 50.0, expiration, 1.25, 1, DefaultAccount)
+	if err != nil || len(remaining) != 1 || remaining[0].Closed == nil {
+		t.Fatalf("expected the closed Default row to remain, got %v (%v)", remaining, err)
+	}
+}
+
+func TestSetRolledFromLinksIsAtomic(t *testing.T) {
+	db := setupOptionTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1) // one connection, so the transaction sees the in-memory schema
+
+	optionService := NewOptionService(db)
+	var ids []int
+	for month := time.January; month <= time.March; month++ {
+		option, err := optionService.Create("AAA", "Put", time.Date(2025, month, 2, 0, 0, 0, 0, time.UTC), 50.0, time.Date(2025, month, 24, 0, 0, 0, 0, time.UTC), 1.25, 1)
+		if err != nil {
+			t.Fatalf("failed to create option: %v", err)
+		}
+		ids = append(ids, option.ID)
+	}
+
+	// The second link names a missing option, so the first must not be written either
+	err := optionService.SetRolledFromLinks([]RollLink{{FromID: ids[0], ToID: ids[1]}, {FromID: 9999, ToID: ids[2]}})
+	if err == nil {
+		t.Fatalf("expected an error for a missing option")
+	}
+	rolled, err := optionService.GetByID(ids[1])
+	if err != nil {
+		t.Fatalf("failed to get option: %v", err)
+	}
+	if rolled.RolledFromID != nil {
+		t.Fatalf("expected no roll link after a failed batch, got %d", *rolled.RolledFromID)
+	}
+
+	if err := optionService.SetRolledFromLinks([]RollLink{{FromID: ids[0], ToID: ids[1]}, {FromID: ids[1], ToID: ids[2]}}); err != nil {
+		t.Fatalf("failed to link rolls: %v", err)
+	}
+	for i := 1; i < len(ids); i++ {
+		option, err := optionService.GetByID(ids[i])
+		if err != nil {
+			t.Fatalf("failed to get option: %v", err)
+		}
+		if option.RolledFromID == nil || *option.RolledFromID != ids[i-1] {
+			t.Fatalf("expected option %d rolled from %d, got %v", ids[i], ids[i-1], option.RolledFromID)
+		}
+	}
+}
+
+func TestPendingOptionsUseMarketDate(t *testing.T) {
+	db := setupOptionTestDB(t)
+	defer db.Close()
+
+	ahead, err := time.LoadLocation("Pacific/Kiritimati") // UTC+14
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	behind, err := time.LoadLocation("Etc/GMT+12") // UTC-12, always a day or two behind
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+
+	optionService := NewOptionService(db)
+	year, month, day := time.Now().In(ahead).Date()
+	opened := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if _, err := optionService.Create("AAA", "Put", opened, 50.0, opened.AddDate(0, 0, 30), 1.25, 1); err != nil {
+		t.Fatalf("failed to create option: %v", err)
+	}
+
+	for _, tc := range []struct {
+		location *time.Location
+		pending  int
+	}{
+		{ahead, 0},  // opened today in the market timezone
+		{behind, 1}, // opened on a date the market has not reached
+	} {
+		optionService.SetMarketLocation(func() *time.Location { return tc.location })
+		pending, err := optionService.GetPending()
+		if err != nil {
+			t.Fatalf("failed to get pending options: %v", err)
+		}
+		if len(pending) != tc.pending {
+			t.Errorf("%s: expected %d pending options, got %d", tc.location, tc.pending, len(pending))
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PriceClose is a symbol's closing price for one trading session. Closes are recorded as prices
+// are refreshed from Polygon, so history only starts from the first update after this table was
+// added and has gaps for days no update ran.
+type PriceClose struct {
+	Symbol string    `json:"symbol"`
+	Date   time.Time `json:"date"`
+	Close  float64   `json:"close"`
+}
+
+// RecordClose stores or replaces a symbol's close for a session date. Non-positive closes are
+// ignored since they are missing data rather than a price.
+func (s *SymbolService) RecordClose(symbol string, date time.Time, price float64) error {
+	if price <= 0 {
+		return nil
+	}
+	year, month, day := date.Date()
+	session := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	_, err := s.db.Exec(`INSERT INTO symbol_price_history (symbol, date, close) VALUES (?, ?, ?)
+		ON CONFLICT(symbol, date) DO UPDATE SET close = excluded.close, updated_at = CURRENT_TIMESTAMP`,
+		symbol, session, price)
+	if err != nil {
+		return fmt.Errorf("failed to record close for %s: %w", symbol, err)
+	}
+	return nil
+}
+
+// GetCloseHistory returns the recorded closes of the given symbols on or after since, keyed by
+// symbol in date order. Symbols without history are absent from the map.
+func (s *SymbolService) GetCloseHistory(symbols []string, since time.Time) (map[string][]PriceClose, error) {
+	history := make(map[string][]PriceClose)
+	if len(symbols) == 0 {
+		return history, nil
+	}
+
+	args := make([]interface{}, 0, len(symbols)+1)
+	for _, symbol := range symbols {
+		args = append(args, symbol)
+	}
+	args = append(args, since)
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(symbols)), ",")
+
+	rows, err := s.db.Query(`SELECT symbol, date, close FROM symbol_price_history
+		WHERE symbol IN (`+placeholders+`) AND date >= ? ORDER BY symbol, date`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var point PriceClose
+		if err := rows.Scan(&point.Symbol, &point.Date, &point.Close); err != nil {
+			return nil, fmt.Errorf("failed to scan price history: %w", err)
+		}
+		history[point.Symbol] = append(history[point.Symbol], point)
+	}
+	return history, rows.Err()
+}
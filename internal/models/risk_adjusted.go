@@ -0,0 +1,120 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// TradingDaysPerYear annualizes daily return statistics
+const TradingDaysPerYear = 252
+
+// RiskAdjustedStats is a Sharpe-like summary of a daily value series. Sharpe is nil when fewer
+// than two returns are available or the returns have no variance.
+type RiskAdjustedStats struct {
+	From                 string   `json:"from,omitempty"`
+	To                   string   `json:"to,omitempty"`
+	Observations         int      `json:"observations"`          // trading days with a value
+	Returns              int      `json:"returns"`               // daily returns computed
+	GapDays              int      `json:"gap_days"`              // trading days without a snapshot, spanned by returns
+	MeanDailyReturn      float64  `json:"mean_daily_return"`     // percent
+	StdDevDailyReturn    float64  `json:"std_dev_daily_return"`  // percent, sample standard deviation
+	AnnualizedReturn     float64  `json:"annualized_return"`     // percent, mean daily return x 252
+	AnnualizedVolatility float64  `json:"annualized_volatility"` // percent, daily std dev x sqrt(252)
+	RiskFreeRate         float64  `json:"risk_free_rate"`        // annual percent
+	Sharpe               *float64 `json:"sharpe"`
+}
+
+// ComputeRiskAdjustedReturn derives daily returns from a value series and their annualized
+// Sharpe-like ratio against riskFreePercent (annual). Snapshots are aligned to trading days: a
+// snapshot on a weekend or holiday counts for the trading day before it, and the latest snapshot
+// wins when several land on the same trading day. A return spanning missing trading days is
+// converted to its equivalent daily rate, so sparse history does not inflate volatility.
+func ComputeRiskAdjustedReturn(metrics []*Metric, riskFreePercent float64) RiskAdjustedStats {
+	stats := RiskAdjustedStats{RiskFreeRate: riskFreePercent}
+
+	type observation struct {
+		day     time.Time
+		created time.Time
+		value   float64
+	}
+	byDay := make(map[string]observation)
+	for _, metric := range metrics {
+		year, month, date := metric.Created.Date()
+		day := PreviousTradingDay(time.Date(year, month, date, 0, 0, 0, 0, time.UTC))
+		key := day.Format("2006-01-02")
+		if existing, ok := byDay[key]; ok && existing.created.After(metric.Created) {
+			continue
+		}
+		byDay[key] = observation{day: day, created: metric.Created, value: metric.Value}
+	}
+
+	series := make([]observation, 0, len(byDay))
+	for _, obs := range byDay {
+		series = append(series, obs)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].day.Before(series[j].day) })
+
+	stats.Observations = len(series)
+	if len(series) > 0 {
+		stats.From = series[0].day.Format("2006-01-02")
+		stats.To = series[len(series)-1].day.Format("2006-01-02")
+	}
+
+	var returns []float64
+	for i := 1; i < len(series); i++ {
+		previous, current := series[i-1], series[i]
+		if previous.value <= 0 {
+			continue
+		}
+		span := tradingDaysBetween(previous.day, current.day)
+		if span < 1 {
+			continue
+		}
+		stats.GapDays += span - 1
+		periodReturn := current.value / previous.value
+		returns = append(returns, math.Pow(periodReturn, 1/float64(span))-1)
+	}
+	stats.Returns = len(returns)
+	if len(returns) == 0 {
+		return stats
+	}
+
+	var sum float64
+	for _, r := range returns {
+		sum += r
+	}
+	mean := sum / float64(len(returns))
+	stats.MeanDailyReturn = mean * 100
+	stats.AnnualizedReturn = mean * TradingDaysPerYear * 100
+	if len(returns) < 2 {
+		return stats
+	}
+
+	var squares float64
+	for _, r := range returns {
+		squares += (r - mean) * (r - mean)
+	}
+	stdDev := math.Sqrt(squares / float64(len(returns)-1))
+	stats.StdDevDailyReturn = stdDev * 100
+	stats.AnnualizedVolatility = stdDev * math.Sqrt(TradingDaysPerYear) * 100
+	if stdDev == 0 {
+		return stats
+	}
+
+	dailyRiskFree := math.Pow(1+riskFreePercent/100, 1.0/TradingDaysPerYear) - 1
+	sharpe := (mean - dailyRiskFree) / stdDev * math.Sqrt(TradingDaysPerYear)
+	stats.Sharpe = &sharpe
+	return stats
+}
+
+// tradingDaysBetween counts the trading days after from, up to and including to
+func tradingDaysBetween(from, to time.Time) int {
+	days := 0
+	for d := from.AddDate(0, 0, 1); !d.After(to); d = d.AddDate(0, 0, 1) {
+		if IsTradingDay(d) {
+			days++
+		}
+	}
+	return days
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"sort"
+)
+
+// RollLink confirms that ToID was opened to roll FromID
+type RollLink struct {
+	FromID int `json:"from_id"`
+	ToID   int `json:"to_id"`
+}
+
+// RollPair is a closed option matched with the option opened in its place
+type RollPair struct {
+	From          *Option `json:"from"`
+	To            *Option `json:"to"`
+	Date          string  `json:"date"`
+	AlreadyLinked bool    `json:"already_linked"`
+}
+
+// DetectRolls pairs each closed (not assigned) option with an option of the same symbol, type and
+// account opened on its close date at a different strike or expiration. Each option is used at
+// most once on either side; existing rolled_from_id links are reported as already linked and take
+// precedence. Candidates with the same contract count and the nearest later expiration win.
+func DetectRolls(options []*Option) []RollPair {
+	byID := make(map[int]*Option, len(options))
+	for _, option := range options {
+		byID[option.ID] = option
+	}
+
+	var pairs []RollPair
+	usedFrom := make(map[int]bool)
+	usedTo := make(map[int]bool)
+	for _, option := range options {
+		if option.RolledFromID == nil {
+			continue
+		}
+		from, ok := byID[*option.RolledFromID]
+		if !ok {
+			continue
+		}
+		pairs = append(pairs, RollPair{From: from, To: option, Date: option.Opened.Format("2006-01-02"), AlreadyLinked: true})
+		usedFrom[from.ID] = true
+		usedTo[option.ID] = true
+	}
+
+	closed := make([]*Option, 0, len(options))
+	for _, option := range options {
+		if option.Closed != nil && !option.Assigned && !usedFrom[option.ID] {
+			closed = append(closed, option)
+		}
+	}
+	sort.Slice(closed, func(i, j int) bool {
+		if !closed[i].Closed.Equal(*closed[j].Closed) {
+			return closed[i].Closed.Before(*closed[j].Closed)
+		}
+		return closed[i].ID < closed[j].ID
+	})
+
+	for _, from := range closed {
+		var best *Option
+		for _, candidate := range options {
+			if candidate.ID == from.ID || usedTo[candidate.ID] || !isRollCandidate(from, candidate) {
+				continue
+			}
+			if best == nil || betterRollCandidate(from, candidate, best) {
+				best = candidate
+			}
+		}
+		if best == nil {
+			continue
+		}
+		pairs = append(pairs, RollPair{From: from, To: best, Date: from.Closed.Format("2006-01-02")})
+		usedFrom[from.ID] = true
+		usedTo[best.ID] = true
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Date != pairs[j].Date {
+			return pairs[i].Date < pairs[j].Date
+		}
+		return pairs[i].From.ID < pairs[j].From.ID
+	})
+	return pairs
+}
+
+func isRollCandidate(from, to *Option) bool {
+	if to.RolledFromID != nil || to.Symbol != from.Symbol || to.Type != from.Type || to.Account != from.Account {
+		return false
+	}
+	if !sameCalendarDate(to.Opened, *from.Closed) {
+		return false
+	}
+	return to.Strike != from.Strike || !sameCalendarDate(to.Expiration, from.Expiration)
+}
+
+// betterRollCandidate prefers a matching contract count, then a later expiration closest to the
+// one being rolled, then the lowest ID
+func betterRollCandidate(from, candidate, best *Option) bool {
+	candidateSame := candidate.Contracts == from.Contracts
+	bestSame := best.Contracts == from.Contracts
+	if candidateSame != bestSame {
+		return candidateSame
+	}
+	candidateLater := candidate.Expiration.After(from.Expiration)
+	bestLater := best.Expiration.After(from.Expiration)
+	if candidateLater != bestLater {
+		return candidateLater
+	}
+	candidateGap := candidate.Expiration.Sub(from.Expiration).Abs()
+	bestGap := best.Expiration.Sub(from.Expiration).Abs()
+	if candidateGap != bestGap {
+		return candidateGap < bestGap
+	}
+	return candidate.ID < best.ID
+}
+
+// RollChains groups roll pairs into chains of option IDs, from the first option in each chain
+// to the option currently carrying the position
+func RollChains(pairs []RollPair) [][]int {
+	next := make(map[int]int, len(pairs))
+	hasPrevious := make(map[int]bool, len(pairs))
+	for _, pair := range pairs {
+		next[pair.From.ID] = pair.To.ID
+		hasPrevious[pair.To.ID] = true
+	}
+
+	var roots []int
+	for from := range next {
+		if !hasPrevious[from] {
+			roots = append(roots, from)
+		}
+	}
+	sort.Ints(roots)
+
+	chains := make([][]int, 0, len(roots))
+	for _, root := range roots {
+		chain := []int{root}
+		seen := map[int]bool{root: true}
+		for id, ok := next[root]; ok && !seen[id]; id, ok = next[id] {
+			chain = append(chain, id)
+			seen[id] = true
+		}
+		chains = append(chains, chain)
+	}
+	return chains
+}
+
+// LinkedRollChain returns the options joined to the given one through stored rolled_from_id links,
+// from the first option rolled to the last, or nil when id is not among options. Past the given
+// option, an option rolled into more than one option continues with the earliest opened of them.
+func LinkedRollChain(options []*Option, id int) []*Option {
+	byID := make(map[int]*Option, len(options))
+	next := make(map[int]*Option)
+	for _, option := range options {
+		byID[option.ID] = option
+	}
+	for _, option := range options {
+		if option.RolledFromID == nil {
+			continue
+		}
+		if current, ok := next[*option.RolledFromID]; !ok || option.Opened.Before(current.Opened) ||
+			(option.Opened.Equal(current.Opened) && option.ID < current.ID) {
+			next[*option.RolledFromID] = option
+		}
+	}
+
+	option, ok := byID[id]
+	if !ok {
+		return nil
+	}
+	chain := []*Option{option}
+	seen := map[int]bool{option.ID: true}
+	for option.RolledFromID != nil {
+		previous, ok := byID[*option.RolledFromID]
+		if !ok || seen[previous.ID] {
+			break
+		}
+		chain = append([]*Option{previous}, chain...)
+		seen[previous.ID] = true
+		option = previous
+	}
+	for following, ok := next[id]; ok && !seen[following.ID]; following, ok = next[following.ID] {
+		chain = append(chain, following)
+		seen[following.ID] = true
+	}
+	return chain
+}
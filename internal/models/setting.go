@@ -3,7 +3,9 @@ package models
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,6 +19,11 @@ type Setting struct {
 
 type SettingService struct {
 	db *sql.DB
+
+	// cache holds every setting value, loaded on the first read and dropped on any write made
+	// through the service. A new service (e.g. after a database switch) starts with an empty cache.
+	cacheMu sync.RWMutex
+	cache   map[string]string
 }
 
 func NewSettingService(db *sql.DB) *SettingService {
@@ -36,6 +43,7 @@ func (s *SettingService) Create(name, value, description string) (*Setting, erro
 	if err != nil {
 		return nil, fmt.Errorf("failed to create setting: %w", err)
 	}
+	s.invalidateCache()
 
 	return &setting, nil
 }
@@ -94,6 +102,7 @@ func (s *SettingService) Update(name, value, description string) (*Setting, erro
 		}
 		return nil, fmt.Errorf("failed to update setting: %w", err)
 	}
+	s.invalidateCache()
 
 	return &setting, nil
 }
@@ -119,6 +128,7 @@ func (s *SettingService) Delete(name string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete setting: %w", err)
 	}
+	s.invalidateCache()
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
@@ -132,8 +142,12 @@ func (s *SettingService) Delete(name string) error {
 	return nil
 }
 
-// GetValue returns the setting value as a string, or empty string if not found
+// GetValue returns the setting value as a string, or empty string if not found.
+// Values come from the in-memory cache, falling back to a direct query if it cannot be loaded.
 func (s *SettingService) GetValue(name string) string {
+	if values, err := s.cachedValues(); err == nil {
+		return values[name]
+	}
 	setting, err := s.GetByName(name)
 	if err != nil || setting.Value == nil {
 		return ""
@@ -147,6 +161,51 @@ func (s *SettingService) SetValue(name, value, description string) error {
 	return err
 }
 
+// cachedValues returns every setting value, loading them all in one query when the cache is empty
+func (s *SettingService) cachedValues() (map[string]string, error) {
+	s.cacheMu.RLock()
+	values := s.cache
+	s.cacheMu.RUnlock()
+	if values != nil {
+		return values, nil
+	}
+
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	if s.cache != nil {
+		return s.cache, nil
+	}
+
+	rows, err := s.db.Query(`SELECT name, value FROM settings`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+	defer rows.Close()
+
+	values = make(map[string]string)
+	for rows.Next() {
+		var name string
+		var value sql.NullString
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan setting: %w", err)
+		}
+		values[name] = value.String
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating settings: %w", err)
+	}
+
+	s.cache = values
+	return values, nil
+}
+
+// invalidateCache drops the cached values so the next read reloads them from the database
+func (s *SettingService) invalidateCache() {
+	s.cacheMu.Lock()
+	s.cache = nil
+	s.cacheMu.Unlock()
+}
+
 // GetValueWithDefault returns the setting value or a default if not found
 func (s *SettingService) GetValueWithDefault(name, defaultValue string) string {
 	value := s.GetValue(name)
@@ -156,6 +215,27 @@ func (s *SettingService) GetValueWithDefault(name, defaultValue string) string {
 	return value
 }
 
+// GetBoolWithDefault returns the setting parsed as a boolean (true/1/yes/on), or a default if unset
+func (s *SettingService) GetBoolWithDefault(name string, defaultValue bool) bool {
+	switch strings.ToLower(strings.TrimSpace(s.GetValue(name))) {
+	case "true", "1", "yes", "on":
+		return true
+	case "false", "0", "no", "off":
+		return false
+	default:
+		return defaultValue
+	}
+}
+
+// GetFloatWithDefault returns the setting parsed as a float, or a default if unset or invalid
+func (s *SettingService) GetFloatWithDefault(name string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(s.GetValue(name)), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 // Helper function to convert empty strings to nil for database storage
 func nullableString(s string) interface{} {
 	if s == "" {
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Split rounding modes (SPLIT_ROUNDING setting). Lots hold whole shares, so keeping a fractional
+// share is not supported yet and a split that leaves one always rounds the lot down.
+const (
+	// SplitRoundingCashInLieu rounds down and records the fractional remainder as sold at the
+	// cash-in-lieu price, realizing its gain or loss against the lot's buy price
+	SplitRoundingCashInLieu = "cash_in_lieu"
+)
+
+// IsValidSplitRounding reports whether mode is a supported split rounding mode
+func IsValidSplitRounding(mode string) bool {
+	return mode == SplitRoundingCashInLieu
+}
+
+// StockSplit records a split applied to one lot, including any fractional remainder
+type StockSplit struct {
+	ID                      int       `json:"id"`
+	PositionID              int       `json:"position_id"`
+	Symbol                  string    `json:"symbol"`
+	Effective               time.Time `json:"effective"`
+	SplitTo                 int       `json:"split_to"`   // new shares per SplitFrom old shares
+	SplitFrom               int       `json:"split_from"` // e.g. 1-for-10 reverse split is to=1, from=10
+	Rounding                string    `json:"rounding"`
+	SharesBefore            int       `json:"shares_before"`
+	SharesAfter             int       `json:"shares_after"`
+	RemainderShares         float64   `json:"remainder_shares"`           // fractional post-split shares dropped by rounding
+	RemainderPreSplitShares float64   `json:"remainder_pre_split_shares"` // the same remainder in pre-split shares
+	RemainderBasis          float64   `json:"remainder_basis"`
+	CashInLieu              float64   `json:"cash_in_lieu"`
+	Realized                float64   `json:"realized"`
+	CreatedAt               time.Time `json:"created_at"`
+}
+
+// ApplySplit applies a splitTo-for-splitFrom split to every open lot of a symbol opened on or before
+// the effective date. Share counts and buy prices are rescaled and the remainder of a fractional
+// result is handled per rounding; cashInLieuPrice is the post-split price paid per whole share and
+// must be positive whenever a lot leaves a remainder, or the remainder would be booked as sold at $0.
+// Lots with partial exits are rejected since their exit history is recorded in pre-split shares.
+func (s *LongPositionService) ApplySplit(symbol string, effective time.Time, splitTo, splitFrom int, rounding string, cashInLieuPrice float64) ([]*StockSplit, error) {
+	if splitTo <= 0 || splitFrom <= 0 {
+		return nil, fmt.Errorf("split ratio must be positive")
+	}
+	if splitTo == splitFrom {
+		return nil, fmt.Errorf("split ratio must change the share count")
+	}
+	if !IsValidSplitRounding(rounding) {
+		return nil, fmt.Errorf("invalid split rounding %q (use %s)", rounding, SplitRoundingCashInLieu)
+	}
+	if cashInLieuPrice < 0 {
+		return nil, fmt.Errorf("cash-in-lieu price must not be negative")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT lp.id, lp.shares, lp.buy_price, COUNT(e.id) FROM long_positions lp
+		LEFT JOIN long_position_exits e ON e.position_id = lp.id
+		WHERE lp.symbol = ? AND lp.closed IS NULL AND lp.opened <= ?
+		GROUP BY lp.id ORDER BY lp.opened, lp.id`, symbol, effective)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open lots: %w", err)
+	}
+	type lot struct {
+		id       int
+		shares   int
+		buyPrice float64
+		exits    int
+	}
+	var lots []lot
+	for rows.Next() {
+		var l lot
+		if err := rows.Scan(&l.id, &l.shares, &l.buyPrice, &l.exits); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan lot: %w", err)
+		}
+		lots = append(lots, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate lots: %w", err)
+	}
+	if len(lots) == 0 {
+		return nil, fmt.Errorf("no open lots of %s opened on or before %s", symbol, effective.Format("2006-01-02"))
+	}
+
+	ratio := float64(splitTo) / float64(splitFrom)
+	var splits []*StockSplit
+	for _, l := range lots {
+		if l.exits > 0 {
+			return nil, fmt.Errorf("long position %d has partial exits; record the split manually", l.id)
+		}
+
+		exact := float64(l.shares) * ratio
+		sharesAfter := int(math.Floor(exact + 1e-9))
+		if sharesAfter == 0 {
+			return nil, fmt.Errorf("split would leave long position %d with no whole shares", l.id)
+		}
+		remainder := exact - float64(sharesAfter)
+		if remainder < 1e-9 {
+			remainder = 0
+		}
+		if remainder > 0 && cashInLieuPrice <= 0 {
+			return nil, fmt.Errorf("split leaves long position %d with %.4f fractional shares; a positive cash-in-lieu price is required", l.id, remainder)
+		}
+
+		split := &StockSplit{
+			PositionID:              l.id,
+			Symbol:                  symbol,
+			Effective:               effective,
+			SplitTo:                 splitTo,
+			SplitFrom:               splitFrom,
+			Rounding:                rounding,
+			SharesBefore:            l.shares,
+			SharesAfter:             sharesAfter,
+			RemainderShares:         remainder,
+			RemainderPreSplitShares: remainder / ratio,
+		}
+
+		// Cash in lieu moves the remainder's share of the lot's cost out of the lot and realizes it
+		// against the proceeds
+		buyPrice := l.buyPrice / ratio
+		if remainder > 0 {
+			split.RemainderBasis = buyPrice * remainder
+			split.CashInLieu = cashInLieuPrice * remainder
+			split.Realized = split.CashInLieu - split.RemainderBasis
+		}
+
+		if _, err := tx.Exec(`UPDATE long_positions SET shares = ?, buy_price = ?, adjusted_cost_basis_per_share = ?, adjusted_cost_basis_total = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+			sharesAfter, buyPrice, buyPrice, buyPrice*float64(sharesAfter), l.id); err != nil {
+			return nil, fmt.Errorf("failed to update long position %d: %w", l.id, err)
+		}
+
+		err := tx.QueryRow(`INSERT INTO stock_splits (position_id, symbol, effective, split_to, split_from, rounding, shares_before, shares_after,
+			remainder_shares, remainder_pre_split_shares, remainder_basis, cash_in_lieu, realized)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, created_at`,
+			split.PositionID, split.Symbol, split.Effective, split.SplitTo, split.SplitFrom, split.Rounding, split.SharesBefore, split.SharesAfter,
+			split.RemainderShares, split.RemainderPreSplitShares, split.RemainderBasis, split.CashInLieu, split.Realized).Scan(&split.ID, &split.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record split for long position %d: %w", l.id, err)
+		}
+		splits = append(splits, split)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit split: %w", err)
+	}
+
+	if err := s.RecalculateAdjustedCostBasisForSymbol(symbol); err != nil {
+		return nil, fmt.Errorf("failed to recalculate cost basis after split: %w", err)
+	}
+	return splits, nil
+}
+
+// GetSplits returns the splits recorded for a symbol, or for every symbol when symbol is empty
+func (s *LongPositionService) GetSplits(symbol string) ([]*StockSplit, error) {
+	query := `SELECT id, position_id, symbol, effective, split_to, split_from, rounding, shares_before, shares_after,
+		remainder_shares, remainder_pre_split_shares, remainder_basis, cash_in_lieu, realized, created_at
+		FROM stock_splits WHERE ? = '' OR symbol = ? ORDER BY effective, id`
+	rows, err := s.db.Query(query, symbol, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock splits: %w", err)
+	}
+	defer rows.Close()
+
+	var splits []*StockSplit
+	for rows.Next() {
+		var split StockSplit
+		if err := rows.Scan(&split.ID, &split.PositionID, &split.Symbol, &split.Effective, &split.SplitTo, &split.SplitFrom, &split.Rounding,
+			&split.SharesBefore, &split.SharesAfter, &split.RemainderShares, &split.RemainderPreSplitShares, &split.RemainderBasis,
+			&split.CashInLieu, &split.Realized, &split.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stock split: %w", err)
+		}
+		splits = append(splits, &split)
+	}
+	return splits, rows.Err()
+}
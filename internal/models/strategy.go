@@ -0,0 +1,103 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// Common strategy labels; any other label is stored as entered
+const (
+	StrategyCSP    = "CSP"
+	StrategyCC     = "CC"
+	StrategyWheel  = "wheel"
+	StrategySpread = "spread"
+)
+
+var knownStrategies = []string{StrategyCSP, StrategyCC, StrategyWheel, StrategySpread}
+
+// NormalizeStrategy trims a strategy label and matches the known labels case-insensitively
+func NormalizeStrategy(strategy string) string {
+	strategy = strings.TrimSpace(strategy)
+	for _, known := range knownStrategies {
+		if strings.EqualFold(strategy, known) {
+			return known
+		}
+	}
+	return strategy
+}
+
+// EffectiveStrategy returns the option's strategy label, inferring CSP for puts and CC for
+// calls when none was set
+func (o *Option) EffectiveStrategy() string {
+	if o.Strategy != nil && strings.TrimSpace(*o.Strategy) != "" {
+		return *o.Strategy
+	}
+	if o.Type == "Call" {
+		return StrategyCC
+	}
+	return StrategyCSP
+}
+
+// StrategyPerformance aggregates realized results for the options tagged with one strategy
+type StrategyPerformance struct {
+	Strategy       string  `json:"strategy"`
+	Trades         int     `json:"trades"`
+	OpenTrades     int     `json:"open_trades"`
+	ClosedTrades   int     `json:"closed_trades"`
+	Wins           int     `json:"wins"`
+	WinRate        float64 `json:"win_rate"` // percent of closed trades with a profit
+	RealizedProfit float64 `json:"realized_profit"`
+	AROI           float64 `json:"aroi"` // capital-weighted average AROI of closed trades
+}
+
+// SummarizeStrategyPerformance groups options by effective strategy and aggregates realized
+// profit, win rate and AROI over the closed trades, on the net or gross commission basis and
+// annualized on the calendar or trading basis
+func SummarizeStrategyPerformance(options []*Option, gross bool, annualization string) []StrategyPerformance {
+	byStrategy := make(map[string]*StrategyPerformance)
+	weightedAROI := make(map[string]float64)
+	capital := make(map[string]float64)
+
+	for _, option := range options {
+		strategy := option.EffectiveStrategy()
+		summary, ok := byStrategy[strategy]
+		if !ok {
+			summary = &StrategyPerformance{Strategy: strategy}
+			byStrategy[strategy] = summary
+		}
+		summary.Trades++
+		if option.Closed == nil {
+			summary.OpenTrades++
+			continue
+		}
+
+		// Profit comes from the snapshot stored at close; AROI is still derived live
+		performance := option.CalculatePerformance(gross, annualization)
+		profit := option.RealizedProfitOnBasis(gross)
+		summary.ClosedTrades++
+		summary.RealizedProfit += profit
+		if profit > 0 {
+			summary.Wins++
+		}
+
+		base := option.Strike * float64(option.Contracts) * 100
+		weightedAROI[strategy] += performance.AROI * base
+		capital[strategy] += base
+	}
+
+	result := make([]StrategyPerformance, 0, len(byStrategy))
+	for strategy, summary := range byStrategy {
+		if summary.ClosedTrades > 0 {
+			summary.WinRate = float64(summary.Wins) / float64(summary.ClosedTrades) * 100
+		}
+		if capital[strategy] > 0 {
+			summary.AROI = weightedAROI[strategy] / capital[strategy]
+		}
+		result = append(result, *summary)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Strategy < result[j].Strategy
+	})
+	return result
+}
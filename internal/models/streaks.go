@@ -0,0 +1,93 @@
+package models
+
+import "sort"
+
+// Streak kinds
+const (
+	StreakWin  = "win"
+	StreakLoss = "loss"
+)
+
+// Streak is a run of consecutive winning or losing closed options. From and To are the close
+// dates of the first and last option in the run.
+type Streak struct {
+	Kind   string `json:"kind"`
+	Length int    `json:"length"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// OptionStreaks summarizes win/loss runs over closed options. A streak is nil when no option of
+// that outcome exists; Current is nil when the most recent close broke even.
+type OptionStreaks struct {
+	Closed      int     `json:"closed"`
+	Wins        int     `json:"wins"`
+	Losses      int     `json:"losses"`
+	Breakeven   int     `json:"breakeven"`
+	Current     *Streak `json:"current"`
+	LongestWin  *Streak `json:"longest_win"`
+	LongestLoss *Streak `json:"longest_loss"`
+}
+
+// ComputeOptionStreaks orders closed options by close date (ties by ID) and measures runs of
+// winning and losing outcomes, judged net of commission and fees or before them when gross.
+// A breakeven close ends the current run without starting a new one. Open options are ignored;
+// on equal length the earlier streak is kept as the longest.
+func ComputeOptionStreaks(options []*Option, gross bool) OptionStreaks {
+	var closed []*Option
+	for _, option := range options {
+		if option.Closed != nil {
+			closed = append(closed, option)
+		}
+	}
+	sort.Slice(closed, func(i, j int) bool {
+		if !closed[i].Closed.Equal(*closed[j].Closed) {
+			return closed[i].Closed.Before(*closed[j].Closed)
+		}
+		return closed[i].ID < closed[j].ID
+	})
+
+	stats := OptionStreaks{Closed: len(closed)}
+	var run *Streak
+	for _, option := range closed {
+		profit := option.CalculateTotalProfit()
+		if gross {
+			profit = option.CalculateGrossProfit()
+		}
+
+		var kind string
+		switch {
+		case profit > 0:
+			kind = StreakWin
+			stats.Wins++
+		case profit < 0:
+			kind = StreakLoss
+			stats.Losses++
+		default:
+			stats.Breakeven++
+			run = nil
+			continue
+		}
+
+		day := option.Closed.Format("2006-01-02")
+		if run == nil || run.Kind != kind {
+			run = &Streak{Kind: kind, From: day}
+		}
+		run.Length++
+		run.To = day
+
+		longest := &stats.LongestWin
+		if kind == StreakLoss {
+			longest = &stats.LongestLoss
+		}
+		if *longest == nil || run.Length > (*longest).Length {
+			copied := *run
+			*longest = &copied
+		}
+	}
+	if run != nil {
+		current := *run
+		stats.Current = &current
+	}
+	return stats
+}
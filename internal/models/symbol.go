@@ -8,14 +8,29 @@ import (
 	"time"
 )
 
+// DefaultAccount is the account assigned to trades that are not tagged with one
+const DefaultAccount = "Default"
+
+// NormalizeAccount trims an account name, returning DefaultAccount when it is blank
+func NormalizeAccount(account string) string {
+	account = strings.TrimSpace(account)
+	if account == "" {
+		return DefaultAccount
+	}
+	return account
+}
+
 type Symbol struct {
 	Symbol         string     `json:"symbol"`
 	Price          float64    `json:"price"`
 	Dividend       float64    `json:"dividend"`
 	ExDividendDate *time.Time `json:"ex_dividend_date"`
 	PERatio        *float64   `json:"pe_ratio"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
+	Beta           *float64   `json:"beta"`
+	// ManualPrice marks a price maintained by hand (illiquid or untracked tickers); price updates skip it
+	ManualPrice bool      `json:"manual_price"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 // CalculateYield calculates the annualized dividend yield percentage
@@ -27,18 +42,35 @@ func (s *Symbol) CalculateYield() float64 {
 }
 
 type LongPosition struct {
-	ID                        int        `json:"id"`
-	Symbol                    string     `json:"symbol"`
-	Opened                    time.Time  `json:"opened"`
-	Closed                    *time.Time `json:"closed"`
-	Shares                    int        `json:"shares"`
-	BuyPrice                  float64    `json:"buy_price"`
-	AdjustedCostBasisPerShare float64    `json:"adjusted_cost_basis_per_share"`
-	AdjustedCostBasisTotal    float64    `json:"adjusted_cost_basis_total"`
-	ExitPrice                 *float64   `json:"exit_price"`
-	CostBasisOptions          []*Option  `json:"cost_basis_options,omitempty"`
-	CreatedAt                 time.Time  `json:"created_at"`
-	UpdatedAt                 time.Time  `json:"updated_at"`
+	ID                        int                 `json:"id"`
+	Symbol                    string              `json:"symbol"`
+	Opened                    time.Time           `json:"opened"`
+	Closed                    *time.Time          `json:"closed"`
+	Shares                    int                 `json:"shares"`
+	BuyPrice                  float64             `json:"buy_price"`
+	AdjustedCostBasisPerShare float64             `json:"adjusted_cost_basis_per_share"`
+	AdjustedCostBasisTotal    float64             `json:"adjusted_cost_basis_total"`
+	ExitPrice                 *float64            `json:"exit_price"`
+	CostBasisOptions          []*Option           `json:"cost_basis_options,omitempty"`
+	Exits                     []*LongPositionExit `json:"exits,omitempty"`
+	Account                   string              `json:"account"`
+	CreatedAt                 time.Time           `json:"created_at"`
+	UpdatedAt                 time.Time           `json:"updated_at"`
+	// Display-only basis that also nets open call premium; set by AttachProvisionalBasis when
+	// BASIS_DEFER_OPEN_CALL_PREMIUM is on and never persisted
+	UnrealizedBasisAdjustment    float64  `json:"unrealized_basis_adjustment,omitempty"`
+	ProvisionalCostBasisPerShare *float64 `json:"provisional_cost_basis_per_share,omitempty"`
+	ProvisionalCostBasisTotal    *float64 `json:"provisional_cost_basis_total,omitempty"`
+}
+
+// LongPositionExit records a (possibly partial) sale of shares from a long position lot
+type LongPositionExit struct {
+	ID         int       `json:"id"`
+	PositionID int       `json:"position_id"`
+	Exited     time.Time `json:"exited"`
+	Shares     int       `json:"shares"`
+	Price      float64   `json:"price"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 func (lp *LongPosition) CalculateYield(quarterlyDividend float64) float64 {
@@ -49,12 +81,12 @@ func (lp *LongPosition) CalculateYield(quarterlyDividend float64) float64 {
 	return (quarterlyDividend * 4) / costBasis * 100
 }
 
+// CalculateProfitLoss returns the realized gain on exited shares plus the unrealized gain on the
+// shares still held at currentPrice. Partially exited lots need their exits loaded through
+// LongPositionService.AttachExits first; without them every share counts as still held.
 func (lp *LongPosition) CalculateProfitLoss(currentPrice float64) float64 {
-	exitPrice := currentPrice
-	if lp.ExitPrice != nil {
-		exitPrice = *lp.ExitPrice
-	}
-	return (exitPrice - lp.costBasisPerShare()) * float64(lp.Shares)
+	unrealized := (currentPrice - lp.costBasisPerShare()) * float64(lp.RemainingShares())
+	return lp.CalculateRealizedProfitLoss() + unrealized
 }
 
 func (lp *LongPosition) CalculateROI(currentPrice float64) float64 {
@@ -69,15 +101,22 @@ func (lp *LongPosition) CalculateROI(currentPrice float64) float64 {
 	return ((exitPrice - costBasis) / costBasis) * 100
 }
 
+// CalculateAmount returns the cost basis of the shares still held in the lot. Like
+// RemainingShares it needs exits loaded through LongPositionService.AttachExits first, or a
+// partially exited lot reports its full original amount.
 func (lp *LongPosition) CalculateAmount() float64 {
-	return lp.costBasisPerShare() * float64(lp.Shares)
+	return lp.costBasisPerShare() * float64(lp.RemainingShares())
 }
 
 func (lp *LongPosition) CalculateTotalInvested() float64 {
-	// For now, same as amount (but could include fees, etc. in future)
 	return lp.CalculateAmount()
 }
 
+// CostBasisPerShare returns the adjusted cost basis per share, or the buy price when unadjusted
+func (lp *LongPosition) CostBasisPerShare() float64 {
+	return lp.costBasisPerShare()
+}
+
 func (lp *LongPosition) costBasisPerShare() float64 {
 	if lp.AdjustedCostBasisPerShare > 0 {
 		return lp.AdjustedCostBasisPerShare
@@ -92,21 +131,171 @@ func (lp *LongPosition) GetExitPriceValue() float64 {
 	return *lp.ExitPrice
 }
 
+// GetProvisionalBasisValue returns the provisional basis per share, or the adjusted basis when
+// no provisional figure was attached
+func (lp *LongPosition) GetProvisionalBasisValue() float64 {
+	if lp.ProvisionalCostBasisPerShare == nil {
+		return lp.AdjustedCostBasisPerShare
+	}
+	return *lp.ProvisionalCostBasisPerShare
+}
+
+// ExitedShares returns the number of shares sold through recorded exit events
+func (lp *LongPosition) ExitedShares() int {
+	var shares int
+	for _, exit := range lp.Exits {
+		shares += exit.Shares
+	}
+	return shares
+}
+
+// RemainingShares returns the shares still held in the lot. Exits must be loaded through
+// LongPositionService.AttachExits; a lot without them counts only its closed flag.
+func (lp *LongPosition) RemainingShares() int {
+	if lp.Closed != nil && len(lp.Exits) == 0 {
+		return 0
+	}
+	return lp.Shares - lp.ExitedShares()
+}
+
+// ExitEvents returns the lot's exit events, or a single event built from closed/exit_price for
+// lots closed without exit rows. Exits must be loaded through LongPositionService.AttachExits.
+func (lp *LongPosition) ExitEvents() []*LongPositionExit {
+	if len(lp.Exits) > 0 {
+		return lp.Exits
+	}
+	if lp.Closed != nil && lp.ExitPrice != nil {
+		return []*LongPositionExit{{PositionID: lp.ID, Exited: *lp.Closed, Shares: lp.Shares, Price: *lp.ExitPrice}}
+	}
+	return nil
+}
+
+// CalculateRealizedProfitLoss returns the realized gain across all exit events.
+// Lots without exit rows fall back to the single closed/exit_price pair.
+func (lp *LongPosition) CalculateRealizedProfitLoss() float64 {
+	if len(lp.Exits) == 0 {
+		if lp.Closed != nil && lp.ExitPrice != nil {
+			return (*lp.ExitPrice - lp.costBasisPerShare()) * float64(lp.Shares)
+		}
+		return 0
+	}
+
+	costBasis := lp.costBasisPerShare()
+	var realized float64
+	for _, exit := range lp.Exits {
+		realized += (exit.Price - costBasis) * float64(exit.Shares)
+	}
+	return realized
+}
+
 type Option struct {
-	ID           int        `json:"id"`
-	Symbol       string     `json:"symbol"`
-	Type         string     `json:"type"`
-	Opened       time.Time  `json:"opened"`
-	Closed       *time.Time `json:"closed"`
-	Strike       float64    `json:"strike"`
-	Expiration   time.Time  `json:"expiration"`
-	Premium      float64    `json:"premium"`
-	Contracts    int        `json:"contracts"`
-	ExitPrice    *float64   `json:"exit_price"`
-	Commission   float64    `json:"commission"`
-	CurrentPrice *float64   `json:"current_price"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID                 int        `json:"id"`
+	Symbol             string     `json:"symbol"`
+	Type               string     `json:"type"`
+	Opened             time.Time  `json:"opened"`
+	Closed             *time.Time `json:"closed"`
+	Strike             float64    `json:"strike"`
+	Expiration         time.Time  `json:"expiration"`
+	Premium            float64    `json:"premium"`
+	Contracts          int        `json:"contracts"`
+	ExitPrice          *float64   `json:"exit_price"`
+	Commission         float64    `json:"commission"`
+	Fees               float64    `json:"fees"` // exchange and regulatory fees, kept apart from commission
+	CurrentPrice       *float64   `json:"current_price"`
+	Account            string     `json:"account"`
+	Assigned           bool       `json:"assigned"` // closed by assignment; the premium is fully kept
+	AssignedPositionID *int       `json:"assigned_position_id,omitempty"`
+	Strategy           *string    `json:"strategy,omitempty"`            // journaling label; nil means inferred from type
+	RolledFromID       *int       `json:"rolled_from_id,omitempty"`      // option closed to open this one
+	RealizedProfit     *float64   `json:"realized_profit,omitempty"`     // net profit stored at close time
+	Currency           string     `json:"currency"`                      // trading currency; amounts are in this currency
+	FXRate             float64    `json:"fx_rate"`                       // base currency per unit of Currency at trade time
+	UnderlyingAtOpen   *float64   `json:"underlying_at_open,omitempty"`  // underlying price on the opened date
+	UnderlyingAtClose  *float64   `json:"underlying_at_close,omitempty"` // underlying price on the closed date
+	Status             string     `json:"status"`                        // active, or canceled for orders that never filled
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// Option outcomes, distinguishing assignment from expiry and buyback
+const (
+	OptionOutcomeOpen     = "open"
+	OptionOutcomeAssigned = "assigned"
+	OptionOutcomeExpired  = "expired"
+	OptionOutcomeBuyback  = "buyback"
+)
+
+// Outcome reports how the option ended: assigned, expired worthless (closed at expiration with no
+// exit price or a zero exit price), bought back, or still open
+func (o *Option) Outcome() string {
+	switch {
+	case o.Closed == nil:
+		return OptionOutcomeOpen
+	case o.Assigned:
+		return OptionOutcomeAssigned
+	case o.GetExitPriceValue() == 0 && !o.Closed.Before(calendarDate(o.Expiration.Date())):
+		return OptionOutcomeExpired
+	default:
+		return OptionOutcomeBuyback
+	}
+}
+
+// ExpirationClass classifies the option's expiration as weekly, monthly, quarterly or LEAPS
+func (o *Option) ExpirationClass() string {
+	return ClassifyExpiration(o.Opened, o.Expiration)
+}
+
+// Option data-quality issue categories, in report order
+const (
+	OptionIssueExpirationBeforeOpened = "expiration_before_opened"
+	OptionIssueClosedBeforeOpened     = "closed_before_opened"
+	OptionIssueZeroContracts          = "zero_contracts"
+	OptionIssueInvalidStrike          = "invalid_strike"
+	OptionIssueClosedWithoutExitPrice = "closed_without_exit_price"
+	OptionIssueOpenPastExpiration     = "open_past_expiration"
+	OptionIssueNonTradingExpiration   = "non_trading_expiration"
+)
+
+// OptionIssueCategories lists every data-quality category with the rule it checks
+var OptionIssueCategories = []struct {
+	Category    string
+	Description string
+}{
+	{OptionIssueExpirationBeforeOpened, "Expiration date is before the opened date"},
+	{OptionIssueClosedBeforeOpened, "Closed date is before the opened date"},
+	{OptionIssueZeroContracts, "Contracts must be positive"},
+	{OptionIssueInvalidStrike, "Strike price must be positive"},
+	{OptionIssueClosedWithoutExitPrice, "Closed option has no exit price"},
+	{OptionIssueOpenPastExpiration, "Option is still open well after its expiration"},
+	{OptionIssueNonTradingExpiration, "Expiration falls on a weekend or market holiday"},
+}
+
+// DataQualityIssues returns the categories of inconsistent data found on the option. An open
+// option counts as stale once its expiration is more than staleAfterDays before now.
+func (o *Option) DataQualityIssues(now time.Time, staleAfterDays int) []string {
+	var issues []string
+	if o.Expiration.Before(o.Opened) {
+		issues = append(issues, OptionIssueExpirationBeforeOpened)
+	}
+	if o.Closed != nil && o.Closed.Before(o.Opened) {
+		issues = append(issues, OptionIssueClosedBeforeOpened)
+	}
+	if o.Contracts <= 0 {
+		issues = append(issues, OptionIssueZeroContracts)
+	}
+	if o.Strike <= 0 {
+		issues = append(issues, OptionIssueInvalidStrike)
+	}
+	if o.Closed != nil && o.ExitPrice == nil {
+		issues = append(issues, OptionIssueClosedWithoutExitPrice)
+	}
+	if o.Closed == nil && o.Expiration.AddDate(0, 0, staleAfterDays).Before(now) {
+		issues = append(issues, OptionIssueOpenPastExpiration)
+	}
+	if !IsTradingDay(o.Expiration) {
+		issues = append(issues, OptionIssueNonTradingExpiration)
+	}
+	return issues
 }
 
 func (o *Option) CalculatePercentOTM(currentPrice float64) float64 {
@@ -146,12 +335,32 @@ func (o *Option) CalculateDaysRemaining() int {
 }
 
 func (o *Option) CalculateTotalProfit() float64 {
-	exitPrice := 0.0
-	if o.ExitPrice != nil {
-		exitPrice = *o.ExitPrice
-	}
+	exitPrice := o.GetExitPriceValue()
 	profit := math.Floor((o.Premium - exitPrice) * float64(o.Contracts) * 100)
-	return profit - o.Commission // Subtract commission for accurate net profit
+	return profit - o.TransactionCosts() // Subtract commission and fees for accurate net profit
+}
+
+// TransactionCosts returns the option's broker commission plus exchange and regulatory fees
+func (o *Option) TransactionCosts() float64 {
+	return o.Commission + o.Fees
+}
+
+// RealizedProfitValue returns the net profit stored when the option was closed, falling back to
+// CalculateTotalProfit for open options and closes recorded before the snapshot existed
+func (o *Option) RealizedProfitValue() float64 {
+	if o.RealizedProfit != nil {
+		return *o.RealizedProfit
+	}
+	return o.CalculateTotalProfit()
+}
+
+// RealizedProfitOnBasis is RealizedProfitValue net of commission and fees, or before them when gross
+func (o *Option) RealizedProfitOnBasis(gross bool) float64 {
+	profit := o.RealizedProfitValue()
+	if gross {
+		profit += o.TransactionCosts()
+	}
+	return profit
 }
 
 func (o *Option) CalculatePercentOfProfit() float64 {
@@ -204,35 +413,98 @@ func (o *Option) CalculateMultiplier() float64 {
 	return o.CalculatePercentOfProfit() / percentTime
 }
 
+// CalculateExposure returns the capital the option commits: strike x contracts x 100
+func (o *Option) CalculateExposure() float64 {
+	return o.Strike * float64(o.Contracts) * 100
+}
+
+// CalculateBreakEven returns the underlying price at expiration where the option stops being
+// profitable: strike minus premium per share for puts, strike plus premium per share for calls
+func (o *Option) CalculateBreakEven() float64 {
+	if o.Type == "Call" {
+		return o.Strike + o.Premium
+	}
+	return o.Strike - o.Premium
+}
+
 // CalculateNetPremiumNoFees returns the premium collected for the option excluding fees/commission.
 // This is used for cost basis adjustments where fees are ignored.
 func (o *Option) CalculateNetPremiumNoFees() float64 {
-	exit := 0.0
-	if o.ExitPrice != nil {
-		exit = *o.ExitPrice
-	}
-	return (o.Premium - exit) * float64(o.Contracts) * 100
+	return (o.Premium - o.GetExitPriceValue()) * float64(o.Contracts) * 100
 }
 
 // CalculateAROI calculates the Annualized Return on Investment (AROI) for the option
-// This extrapolates the profit to an annual basis based on time in trade
-func (o *Option) CalculateAROI() float64 {
-	// Calculate days the trade has been active
-	var endDate time.Time
-	if o.Closed == nil {
-		endDate = time.Now()
-	} else {
-		endDate = *o.Closed
-	}
+// This extrapolates the net profit to an annual basis based on time in trade, counted on the
+// given ANNUALIZATION_BASIS (calendar or trading days)
+func (o *Option) CalculateAROI(annualization string) float64 {
+	return o.annualizeReturn(o.CalculateROI(), NormalizeAnnualizationBasis(annualization))
+}
 
-	daysInTrade := endDate.Sub(o.Opened).Hours() / 24
-	if daysInTrade <= 0 {
-		daysInTrade = 1 // Minimum 1 day to avoid division by zero
+// CalculateGrossProfit returns the option profit before commission and fees
+func (o *Option) CalculateGrossProfit() float64 {
+	return o.CalculateTotalProfit() + o.TransactionCosts()
+}
+
+// CalculateROI returns the period return on the capital base, net of commission
+func (o *Option) CalculateROI() float64 {
+	return o.returnOnCapital(o.CalculateTotalProfit())
+}
+
+// CalculateGrossROI returns the period return on the capital base before commission
+func (o *Option) CalculateGrossROI() float64 {
+	return o.returnOnCapital(o.CalculateGrossProfit())
+}
+
+// OptionPerformance summarizes profit and return figures on a single commission basis
+type OptionPerformance struct {
+	Basis         string  `json:"basis"` // "net" or "gross"
+	Profit        float64 `json:"profit"`
+	ROI           float64 `json:"roi"`
+	AROI          float64 `json:"aroi"`
+	Annualization string  `json:"annualization"` // "calendar" or "trading" day count behind AROI
+}
+
+// CalculatePerformance returns profit, ROI and AROI either gross (pre-commission) or net, with
+// AROI annualized on the given basis (calendar or trading days)
+func (o *Option) CalculatePerformance(gross bool, annualization string) OptionPerformance {
+	annualization = NormalizeAnnualizationBasis(annualization)
+	if gross {
+		roi := o.CalculateGrossROI()
+		return OptionPerformance{Basis: "gross", Profit: o.CalculateGrossProfit(), ROI: roi, AROI: o.annualizeReturn(roi, annualization), Annualization: annualization}
 	}
+	roi := o.CalculateROI()
+	return OptionPerformance{Basis: "net", Profit: o.CalculateTotalProfit(), ROI: roi, AROI: o.annualizeReturn(roi, annualization), Annualization: annualization}
+}
 
-	// Calculate total profit
-	profit := o.CalculateTotalProfit()
+// OptionAttribution decomposes an option's net profit into where the money came from and went.
+// The components are unrounded, so Gross + Buyback + Rounding + Commission + Fees equals Net.
+type OptionAttribution struct {
+	Gross      float64 `json:"gross"`      // premium collected
+	Buyback    float64 `json:"buyback"`    // cost to close (negative)
+	Rounding   float64 `json:"rounding"`   // premium leg rounded down to whole dollars (zero or negative)
+	Commission float64 `json:"commission"` // broker commission (negative)
+	Fees       float64 `json:"fees"`       // exchange and regulatory fees (negative)
+	Net        float64 `json:"net"`
+}
+
+// CalculateAttribution splits profit into gross premium, buyback cost, commission and fees.
+// Net matches CalculateTotalProfit, which rounds the premium leg down to whole dollars; whatever
+// that floor dropped is reported as Rounding, taken from Net so the parts always reconcile.
+func (o *Option) CalculateAttribution() OptionAttribution {
+	contractsMultiplier := float64(o.Contracts) * 100
+	attribution := OptionAttribution{
+		Gross:      o.Premium * contractsMultiplier,
+		Buyback:    -o.GetExitPriceValue() * contractsMultiplier,
+		Commission: -o.Commission,
+		Fees:       -o.Fees,
+		Net:        o.CalculateTotalProfit(),
+	}
+	attribution.Rounding = attribution.Net - (attribution.Gross + attribution.Buyback + attribution.Commission + attribution.Fees)
+	return attribution
+}
 
+// returnOnCapital converts a profit into a percentage of the option's capital base
+func (o *Option) returnOnCapital(profit float64) float64 {
 	// Calculate the capital base (exposure for puts, long value for calls)
 	var capitalBase float64
 	if o.Type == "Put" {
@@ -248,16 +520,34 @@ func (o *Option) CalculateAROI() float64 {
 		return 0
 	}
 
-	// Calculate return percentage for the period
-	periodReturn := (profit / capitalBase) * 100
+	return (profit / capitalBase) * 100
+}
 
-	// Annualize the return: (period return) * (365.25 days per year / days in trade)
-	aroi := periodReturn * (365.25 / daysInTrade)
+// annualizeReturn extrapolates a period return to an annual basis based on time in trade
+func (o *Option) annualizeReturn(periodReturn float64, basis string) float64 {
+	// Calculate days the trade has been active
+	var endDate time.Time
+	if o.Closed == nil {
+		endDate = time.Now()
+	} else {
+		endDate = *o.Closed
+	}
+
+	daysInTrade := endDate.Sub(o.Opened).Hours() / 24
+	if daysInTrade <= 0 {
+		daysInTrade = 1 // Minimum 1 day to avoid division by zero
+	}
 
-	return aroi
+	// Annualize the return: (period return) * (days per year / days in trade), where the trading
+	// basis counts sessions in trade against 252
+	return periodReturn * AnnualizationFactor(basis, o.Opened, endDate, daysInTrade)
 }
 
+// GetExitPriceValue returns the exit price, treating assigned options as exited at zero
 func (o *Option) GetExitPriceValue() float64 {
+	if o.Assigned {
+		return 0.0
+	}
 	if o.ExitPrice != nil {
 		return *o.ExitPrice
 	}
@@ -294,34 +584,19 @@ func (o *Option) GetProfitLossClass() string {
 	return ""
 }
 
-// GetFormattedTotalProfit returns formatted total profit with appropriate styling class
-func (o *Option) GetFormattedTotalProfit() string {
-	profit := o.CalculateTotalProfit()
-	if profit < 0 {
-		return fmt.Sprintf("<span class=\"negative\">$%.2f</span>", profit)
-	}
-	return fmt.Sprintf("$%.2f", profit)
-}
-
-// GetFormattedPercentOfProfit returns formatted percent of profit with appropriate styling
-func (o *Option) GetFormattedPercentOfProfit() string {
-	percent := o.CalculatePercentOfProfit()
-	if percent < 0 {
-		return fmt.Sprintf("<span class=\"negative\">%.2f%%</span>", percent)
-	}
-	return fmt.Sprintf("%.2f%%", percent)
-}
-
 type Dividend struct {
-	ID        int       `json:"id"`
-	Symbol    string    `json:"symbol"`
-	Received  time.Time `json:"received"`
-	Amount    float64   `json:"amount"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                   int       `json:"id"`
+	Symbol               string    `json:"symbol"`
+	Received             time.Time `json:"received"`
+	Amount               float64   `json:"amount"`
+	ReinvestedAmount     float64   `json:"reinvested_amount"`      // portion used to buy shares (DRIP)
+	ReinvestedPositionID *int      `json:"reinvested_position_id"` // lot created by the reinvestment
+	CreatedAt            time.Time `json:"created_at"`
 }
 
 type SymbolService struct {
-	db *sql.DB
+	db       *sql.DB
+	onCreate func(symbol string)
 }
 
 func NewSymbolService(db *sql.DB) *SymbolService {
@@ -334,20 +609,30 @@ func (s *SymbolService) Create(symbol string) (*Symbol, error) {
 		return nil, fmt.Errorf("symbol cannot be empty")
 	}
 
-	query := `INSERT INTO symbols (symbol) VALUES (?) RETURNING symbol, price, dividend, ex_dividend_date, pe_ratio, created_at, updated_at`
+	query := `INSERT INTO symbols (symbol) VALUES (?) RETURNING symbol, price, dividend, ex_dividend_date, pe_ratio, beta, manual_price, created_at, updated_at`
 	var sym Symbol
-	err := s.db.QueryRow(query, symbol).Scan(&sym.Symbol, &sym.Price, &sym.Dividend, &sym.ExDividendDate, &sym.PERatio, &sym.CreatedAt, &sym.UpdatedAt)
+	err := s.db.QueryRow(query, symbol).Scan(&sym.Symbol, &sym.Price, &sym.Dividend, &sym.ExDividendDate, &sym.PERatio, &sym.Beta, &sym.ManualPrice, &sym.CreatedAt, &sym.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create symbol: %w", err)
 	}
 
+	if s.onCreate != nil {
+		s.onCreate(sym.Symbol)
+	}
+
 	return &sym, nil
 }
 
+// SetCreateHook registers a callback invoked after a symbol is created.
+// The callback runs on the caller's goroutine and must not block.
+func (s *SymbolService) SetCreateHook(hook func(symbol string)) {
+	s.onCreate = hook
+}
+
 func (s *SymbolService) GetBySymbol(symbol string) (*Symbol, error) {
-	query := `SELECT symbol, price, dividend, ex_dividend_date, pe_ratio, created_at, updated_at FROM symbols WHERE symbol = ?`
+	query := `SELECT symbol, price, dividend, ex_dividend_date, pe_ratio, beta, manual_price, created_at, updated_at FROM symbols WHERE symbol = ?`
 	var sym Symbol
-	err := s.db.QueryRow(query, symbol).Scan(&sym.Symbol, &sym.Price, &sym.Dividend, &sym.ExDividendDate, &sym.PERatio, &sym.CreatedAt, &sym.UpdatedAt)
+	err := s.db.QueryRow(query, symbol).Scan(&sym.Symbol, &sym.Price, &sym.Dividend, &sym.ExDividendDate, &sym.PERatio, &sym.Beta, &sym.ManualPrice, &sym.CreatedAt, &sym.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("symbol not found")
@@ -359,7 +644,7 @@ func (s *SymbolService) GetBySymbol(symbol string) (*Symbol, error) {
 }
 
 func (s *SymbolService) GetAll() ([]*Symbol, error) {
-	query := `SELECT symbol, price, dividend, ex_dividend_date, pe_ratio, created_at, updated_at FROM symbols ORDER BY symbol`
+	query := `SELECT symbol, price, dividend, ex_dividend_date, pe_ratio, beta, manual_price, created_at, updated_at FROM symbols ORDER BY symbol`
 	rows, err := s.db.Query(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get symbols: %w", err)
@@ -369,7 +654,7 @@ func (s *SymbolService) GetAll() ([]*Symbol, error) {
 	var symbols []*Symbol
 	for rows.Next() {
 		var symbol Symbol
-		if err := rows.Scan(&symbol.Symbol, &symbol.Price, &symbol.Dividend, &symbol.ExDividendDate, &symbol.PERatio, &symbol.CreatedAt, &symbol.UpdatedAt); err != nil {
+		if err := rows.Scan(&symbol.Symbol, &symbol.Price, &symbol.Dividend, &symbol.ExDividendDate, &symbol.PERatio, &symbol.Beta, &symbol.ManualPrice, &symbol.CreatedAt, &symbol.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan symbol: %w", err)
 		}
 		symbols = append(symbols, &symbol)
@@ -391,7 +676,7 @@ func (s *SymbolService) GetActivePositionSymbols() ([]string, error) {
 			SELECT symbol FROM long_positions WHERE closed IS NULL
 			UNION
 			-- Symbols with open options positions
-			SELECT symbol FROM options WHERE closed IS NULL
+			SELECT symbol FROM options WHERE closed IS NULL AND status != 'canceled'
 		) ORDER BY symbol
 	`
 
@@ -417,15 +702,56 @@ func (s *SymbolService) GetActivePositionSymbols() ([]string, error) {
 	return symbols, nil
 }
 
+// GetRecentlyActiveSymbols returns symbols with open positions plus those with any trade, close or
+// dividend on or after the cutoff date, for a less cluttered navigation list
+func (s *SymbolService) GetRecentlyActiveSymbols(cutoff time.Time) ([]string, error) {
+	query := `
+		SELECT DISTINCT symbol FROM (
+			-- Symbols with open positions
+			SELECT symbol FROM long_positions WHERE closed IS NULL
+			UNION
+			SELECT symbol FROM options WHERE closed IS NULL AND status != 'canceled'
+			UNION
+			-- Symbols with recent activity
+			SELECT symbol FROM long_positions WHERE date(opened) >= date(?1) OR date(closed) >= date(?1)
+			UNION
+			SELECT symbol FROM options WHERE status != 'canceled' AND (date(opened) >= date(?1) OR date(closed) >= date(?1))
+			UNION
+			SELECT symbol FROM dividends WHERE date(received) >= date(?1)
+		) ORDER BY symbol
+	`
+
+	rows, err := s.db.Query(query, cutoff.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recently active symbols: %w", err)
+	}
+	defer rows.Close()
+
+	var symbols []string
+	for rows.Next() {
+		var symbol string
+		if err := rows.Scan(&symbol); err != nil {
+			return nil, fmt.Errorf("failed to scan symbol: %w", err)
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating symbols: %w", err)
+	}
+
+	return symbols, nil
+}
+
 func (s *SymbolService) Update(symbol string, price float64, dividend float64, exDividendDate *time.Time, peRatio *float64) (*Symbol, error) {
 	symbol = strings.TrimSpace(strings.ToUpper(symbol))
 	if symbol == "" {
 		return nil, fmt.Errorf("symbol cannot be empty")
 	}
 
-	query := `UPDATE symbols SET price = ?, dividend = ?, ex_dividend_date = ?, pe_ratio = ?, updated_at = CURRENT_TIMESTAMP WHERE symbol = ? RETURNING symbol, price, dividend, ex_dividend_date, pe_ratio, created_at, updated_at`
+	query := `UPDATE symbols SET price = ?, dividend = ?, ex_dividend_date = ?, pe_ratio = ?, updated_at = CURRENT_TIMESTAMP WHERE symbol = ? RETURNING symbol, price, dividend, ex_dividend_date, pe_ratio, beta, manual_price, created_at, updated_at`
 	var sym Symbol
-	err := s.db.QueryRow(query, price, dividend, exDividendDate, peRatio, symbol).Scan(&sym.Symbol, &sym.Price, &sym.Dividend, &sym.ExDividendDate, &sym.PERatio, &sym.CreatedAt, &sym.UpdatedAt)
+	err := s.db.QueryRow(query, price, dividend, exDividendDate, peRatio, symbol).Scan(&sym.Symbol, &sym.Price, &sym.Dividend, &sym.ExDividendDate, &sym.PERatio, &sym.Beta, &sym.ManualPrice, &sym.CreatedAt, &sym.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("symbol not found")
@@ -436,6 +762,46 @@ func (s *SymbolService) Update(symbol string, price float64, dividend float64, e
 	return &sym, nil
 }
 
+// UpdateBeta sets (or clears, when nil) the manually maintained beta for a symbol
+func (s *SymbolService) UpdateBeta(symbol string, beta *float64) (*Symbol, error) {
+	symbol = strings.TrimSpace(strings.ToUpper(symbol))
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol cannot be empty")
+	}
+
+	query := `UPDATE symbols SET beta = ?, updated_at = CURRENT_TIMESTAMP WHERE symbol = ? RETURNING symbol, price, dividend, ex_dividend_date, pe_ratio, beta, manual_price, created_at, updated_at`
+	var sym Symbol
+	err := s.db.QueryRow(query, beta, symbol).Scan(&sym.Symbol, &sym.Price, &sym.Dividend, &sym.ExDividendDate, &sym.PERatio, &sym.Beta, &sym.ManualPrice, &sym.CreatedAt, &sym.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("symbol not found")
+		}
+		return nil, fmt.Errorf("failed to update symbol beta: %w", err)
+	}
+
+	return &sym, nil
+}
+
+// SetManualPrice flags a symbol's price as manually maintained, or returns it to automatic updates
+func (s *SymbolService) SetManualPrice(symbol string, manual bool) (*Symbol, error) {
+	symbol = strings.TrimSpace(strings.ToUpper(symbol))
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol cannot be empty")
+	}
+
+	query := `UPDATE symbols SET manual_price = ?, updated_at = CURRENT_TIMESTAMP WHERE symbol = ? RETURNING symbol, price, dividend, ex_dividend_date, pe_ratio, beta, manual_price, created_at, updated_at`
+	var sym Symbol
+	err := s.db.QueryRow(query, manual, symbol).Scan(&sym.Symbol, &sym.Price, &sym.Dividend, &sym.ExDividendDate, &sym.PERatio, &sym.Beta, &sym.ManualPrice, &sym.CreatedAt, &sym.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("symbol not found")
+		}
+		return nil, fmt.Errorf("failed to update symbol manual price flag: %w", err)
+	}
+
+	return &sym, nil
+}
+
 func (s *SymbolService) Delete(symbol string) error {
 	query := `DELETE FROM symbols WHERE symbol = ?`
 	result, err := s.db.Exec(query, symbol)
@@ -455,6 +821,188 @@ func (s *SymbolService) Delete(symbol string) error {
 	return nil
 }
 
+// SymbolRenameResult summarizes the records moved by a ticker change
+type SymbolRenameResult struct {
+	OldSymbol         string `json:"old_symbol"`
+	NewSymbol         string `json:"new_symbol"`
+	Merged            bool   `json:"merged"`
+	Options           int64  `json:"options"`
+	LongPositions     int64  `json:"long_positions"`
+	Dividends         int64  `json:"dividends"`
+	DuplicatesRemoved int64  `json:"duplicates_removed"`
+}
+
+// NormalizeTicker upper-cases and trims a ticker and checks it only contains
+// letters, digits, '.' or '-' (e.g. BRK.B), up to 10 characters.
+func NormalizeTicker(symbol string) (string, error) {
+	symbol = strings.TrimSpace(strings.ToUpper(symbol))
+	if symbol == "" {
+		return "", fmt.Errorf("symbol cannot be empty")
+	}
+	if len(symbol) > 10 {
+		return "", fmt.Errorf("symbol %q is too long", symbol)
+	}
+	for _, r := range symbol {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '.' && r != '-' {
+			return "", fmt.Errorf("symbol %q contains invalid character %q", symbol, r)
+		}
+	}
+	return symbol, nil
+}
+
+// Rename moves every option, long position and dividend from oldSymbol to newSymbol in a
+// single transaction. If newSymbol already exists the records are merged into it and its
+// market data is kept; otherwise it is created with oldSymbol's data. Rows that become exact
+// duplicates of records already under newSymbol are dropped, with links to a dropped option
+// moved onto the option it duplicates. Callers should recalculate
+// adjusted cost basis for newSymbol afterwards.
+func (s *SymbolService) Rename(oldSymbol, newSymbol string) (*SymbolRenameResult, error) {
+	oldSymbol, err := NormalizeTicker(oldSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("invalid old symbol: %w", err)
+	}
+	newSymbol, err = NormalizeTicker(newSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("invalid new symbol: %w", err)
+	}
+	if oldSymbol == newSymbol {
+		return nil, fmt.Errorf("old and new symbol are the same")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oldExists, newExists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM symbols WHERE symbol = ?`, oldSymbol).Scan(&oldExists); err != nil {
+		return nil, fmt.Errorf("failed to check old symbol: %w", err)
+	}
+	if oldExists == 0 {
+		return nil, fmt.Errorf("symbol not found")
+	}
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM symbols WHERE symbol = ?`, newSymbol).Scan(&newExists); err != nil {
+		return nil, fmt.Errorf("failed to check new symbol: %w", err)
+	}
+
+	result := &SymbolRenameResult{OldSymbol: oldSymbol, NewSymbol: newSymbol, Merged: newExists > 0}
+
+	// The new symbol must exist before child rows can reference it
+	if newExists == 0 {
+		_, err := tx.Exec(`INSERT INTO symbols (symbol, price, dividend, ex_dividend_date, pe_ratio, beta, manual_price)
+			SELECT ?, price, dividend, ex_dividend_date, pe_ratio, beta, manual_price FROM symbols WHERE symbol = ?`, newSymbol, oldSymbol)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new symbol: %w", err)
+		}
+	}
+
+	// OR IGNORE skips rows that would collide with the unique indexes on options and dividends;
+	// whatever is left under the old symbol afterwards is a duplicate and is removed below.
+	moves := []struct {
+		table string
+		count *int64
+	}{
+		{"options", &result.Options},
+		{"long_positions", &result.LongPositions},
+		{"dividends", &result.Dividends},
+	}
+	for _, move := range moves {
+		res, err := tx.Exec(`UPDATE OR IGNORE `+move.table+` SET symbol = ? WHERE symbol = ?`, newSymbol, oldSymbol)
+		if err != nil {
+			return nil, fmt.Errorf("failed to move %s: %w", move.table, err)
+		}
+		if *move.count, err = res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if move.table == "options" {
+			if err := repointDuplicateOptions(tx, oldSymbol, newSymbol); err != nil {
+				return nil, err
+			}
+		}
+
+		res, err = tx.Exec(`DELETE FROM `+move.table+` WHERE symbol = ?`, oldSymbol)
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove duplicate %s: %w", move.table, err)
+		}
+		duplicates, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		result.DuplicatesRemoved += duplicates
+	}
+
+	// Option-entry defaults follow the ticker unless the new symbol already has its own
+	if _, err := tx.Exec(`UPDATE OR IGNORE symbol_defaults SET symbol = ? WHERE symbol = ?`, newSymbol, oldSymbol); err != nil {
+		return nil, fmt.Errorf("failed to move symbol defaults: %w", err)
+	}
+
+	// Recorded closes follow too; days the new symbol already has keep its own close
+	if _, err := tx.Exec(`UPDATE OR IGNORE symbol_price_history SET symbol = ? WHERE symbol = ?`, newSymbol, oldSymbol); err != nil {
+		return nil, fmt.Errorf("failed to move price history: %w", err)
+	}
+
+	// Recorded splits follow their lots; splits of duplicate lots were removed with them
+	if _, err := tx.Exec(`UPDATE stock_splits SET symbol = ? WHERE symbol = ?`, newSymbol, oldSymbol); err != nil {
+		return nil, fmt.Errorf("failed to move stock splits: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM symbols WHERE symbol = ?`, oldSymbol); err != nil {
+		return nil, fmt.Errorf("failed to delete old symbol: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// repointDuplicateOptions moves every link to an option left under oldSymbol onto the option with
+// the same key under newSymbol that survives the rename: other options' rolled_from_id, greeks
+// snapshots and contract details, and the duplicate's own assignment and roll links when the
+// survivor has none. It runs before the duplicates are deleted so nothing cascades or dangles.
+func repointDuplicateOptions(tx *sql.Tx, oldSymbol, newSymbol string) error {
+	rows, err := tx.Query(`SELECT d.id, s.id FROM options d
+		JOIN options s ON s.symbol = ? AND s.type = d.type AND s.opened = d.opened AND s.strike = d.strike
+			AND s.expiration = d.expiration AND s.premium = d.premium AND s.contracts = d.contracts AND s.account = d.account
+		WHERE d.symbol = ?`, newSymbol, oldSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to match duplicate options: %w", err)
+	}
+	type duplicate struct{ id, survivor int }
+	var duplicates []duplicate
+	for rows.Next() {
+		var d duplicate
+		if err := rows.Scan(&d.id, &d.survivor); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan duplicate option: %w", err)
+		}
+		duplicates = append(duplicates, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate duplicate options: %w", err)
+	}
+
+	for _, d := range duplicates {
+		if _, err := tx.Exec(`UPDATE options SET assigned_position_id = COALESCE(assigned_position_id, (SELECT assigned_position_id FROM options WHERE id = ?)),
+			rolled_from_id = COALESCE(rolled_from_id, (SELECT rolled_from_id FROM options WHERE id = ?)) WHERE id = ?`, d.id, d.id, d.survivor); err != nil {
+			return fmt.Errorf("failed to carry links to option %d: %w", d.survivor, err)
+		}
+		// A roll from the duplicate is a roll from the survivor, unless that would make it its own roll
+		if _, err := tx.Exec(`UPDATE options SET rolled_from_id = CASE WHEN id = ? THEN NULL ELSE ? END WHERE rolled_from_id = ?`, d.survivor, d.survivor, d.id); err != nil {
+			return fmt.Errorf("failed to repoint rolls from option %d: %w", d.id, err)
+		}
+		for _, table := range []string{"greeks_snapshots", "option_contract_details"} {
+			if _, err := tx.Exec(`UPDATE OR IGNORE `+table+` SET option_id = ? WHERE option_id = ?`, d.survivor, d.id); err != nil {
+				return fmt.Errorf("failed to move %s of option %d: %w", table, d.id, err)
+			}
+		}
+	}
+	return nil
+}
+
 func (s *SymbolService) GetDistinctSymbols() ([]string, error) {
 	query := `
 		SELECT DISTINCT symbol FROM (
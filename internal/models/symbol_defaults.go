@@ -0,0 +1,81 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SymbolDefaults are per-symbol prefills for entering a new option. A nil field falls back to the
+// global default.
+type SymbolDefaults struct {
+	Symbol                string     `json:"symbol"`
+	Contracts             *int       `json:"contracts,omitempty"`
+	Strategy              *string    `json:"strategy,omitempty"`
+	CommissionPerContract *float64   `json:"commission_per_contract,omitempty"`
+	UpdatedAt             *time.Time `json:"updated_at,omitempty"`
+}
+
+// GetDefaults returns the option-entry defaults stored for a symbol, or nil when none are set
+func (s *SymbolService) GetDefaults(symbol string) (*SymbolDefaults, error) {
+	var defaults SymbolDefaults
+	var contracts sql.NullInt64
+	var strategy sql.NullString
+	var commission sql.NullFloat64
+	var updatedAt time.Time
+	err := s.db.QueryRow(`SELECT symbol, contracts, strategy, commission_per_contract, updated_at FROM symbol_defaults WHERE symbol = ?`, symbol).Scan(
+		&defaults.Symbol, &contracts, &strategy, &commission, &updatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get symbol defaults: %w", err)
+	}
+
+	if contracts.Valid {
+		value := int(contracts.Int64)
+		defaults.Contracts = &value
+	}
+	if strategy.Valid {
+		defaults.Strategy = &strategy.String
+	}
+	if commission.Valid {
+		defaults.CommissionPerContract = &commission.Float64
+	}
+	defaults.UpdatedAt = &updatedAt
+	return &defaults, nil
+}
+
+// SaveDefaults stores or replaces a symbol's option-entry defaults. The strategy is normalized and
+// a blank strategy is stored as unset.
+func (s *SymbolService) SaveDefaults(defaults *SymbolDefaults) error {
+	if defaults.Contracts != nil && *defaults.Contracts <= 0 {
+		return fmt.Errorf("default contracts must be positive")
+	}
+	if defaults.CommissionPerContract != nil && *defaults.CommissionPerContract < 0 {
+		return fmt.Errorf("default commission cannot be negative")
+	}
+	var strategy interface{}
+	if defaults.Strategy != nil {
+		strategy = nullableString(NormalizeStrategy(*defaults.Strategy))
+	}
+
+	_, err := s.db.Exec(`INSERT INTO symbol_defaults (symbol, contracts, strategy, commission_per_contract)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(symbol) DO UPDATE SET contracts = excluded.contracts, strategy = excluded.strategy,
+			commission_per_contract = excluded.commission_per_contract, updated_at = CURRENT_TIMESTAMP`,
+		defaults.Symbol, defaults.Contracts, strategy, defaults.CommissionPerContract)
+	if err != nil {
+		return fmt.Errorf("failed to save symbol defaults: %w", err)
+	}
+	return nil
+}
+
+// DeleteDefaults removes a symbol's option-entry defaults so the global defaults apply again
+func (s *SymbolService) DeleteDefaults(symbol string) error {
+	if _, err := s.db.Exec(`DELETE FROM symbol_defaults WHERE symbol = ?`, symbol); err != nil {
+		return fmt.Errorf("failed to delete symbol defaults: %w", err)
+	}
+	return nil
+}
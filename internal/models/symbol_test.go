@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql"
+	"stonks/internal/database"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestRenameRepointsLinksToSurvivingDuplicate(t *testing.T) {
+	testDB, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("failed to setup test database: %v", err)
+	}
+	defer testDB.Close()
+	testDB.SetMaxOpenConns(1) // one connection, so transactions see the in-memory schema
+
+	symbolService := NewSymbolService(testDB.DB)
+	optionService := NewOptionService(testDB.DB)
+	lpService := NewLongPositionService(testDB.DB)
+	for _, symbol := range []string{"FB", "META"} {
+		if _, err := symbolService.Create(symbol); err != nil {
+			t.Fatalf("failed to create symbol %s: %v", symbol, err)
+		}
+	}
+
+	opened := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	expiration := time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)
+	// The same put was imported under both tickers; only the old copy was assigned and rolled from
+	duplicate, err := optionService.Create("FB", "Put", opened, 300, expiration, 2.50, 1)
+	if err != nil {
+		t.Fatalf("failed to create old-ticker option: %v", err)
+	}
+	survivor, err := optionService.Create("META", "Put", opened, 300, expiration, 2.50, 1)
+	if err != nil {
+		t.Fatalf("failed to create new-ticker option: %v", err)
+	}
+	lot, err := lpService.Create("FB", expiration, 100, 300)
+	if err != nil {
+		t.Fatalf("failed to create assigned lot: %v", err)
+	}
+	if _, err := testDB.Exec(`UPDATE options SET assigned_position_id = ? WHERE id = ?`, lot.ID, duplicate.ID); err != nil {
+		t.Fatalf("failed to link assigned lot: %v", err)
+	}
+	roll, err := optionService.Create("FB", "Put", expiration, 295, time.Date(2025, 2, 21, 0, 0, 0, 0, time.UTC), 3.10, 1)
+	if err != nil {
+		t.Fatalf("failed to create rolled option: %v", err)
+	}
+	if _, err := testDB.Exec(`UPDATE options SET rolled_from_id = ? WHERE id = ?`, duplicate.ID, roll.ID); err != nil {
+		t.Fatalf("failed to link roll: %v", err)
+	}
+
+	result, err := symbolService.Rename("FB", "META")
+	if err != nil {
+		t.Fatalf("failed to rename symbol: %v", err)
+	}
+	if result.DuplicatesRemoved != 1 {
+		t.Fatalf("expected 1 duplicate removed, got %d", result.DuplicatesRemoved)
+	}
+
+	var assignedPositionID sql.NullInt64
+	if err := testDB.QueryRow(`SELECT assigned_position_id FROM options WHERE id = ?`, survivor.ID).Scan(&assignedPositionID); err != nil {
+		t.Fatalf("failed to load surviving option: %v", err)
+	}
+	if !assignedPositionID.Valid || int(assignedPositionID.Int64) != lot.ID {
+		t.Fatalf("expected the survivor linked to lot %d, got %v", lot.ID, assignedPositionID)
+	}
+
+	var rolledFromID sql.NullInt64
+	if err := testDB.QueryRow(`SELECT rolled_from_id FROM options WHERE id = ?`, roll.ID).Scan(&rolledFromID); err != nil {
+		t.Fatalf("failed to load rolled option: %v", err)
+	}
+	if !rolledFromID.Valid || int(rolledFromID.Int64) != survivor.ID {
+		t.Fatalf("expected the roll to point at option %d, got %v", survivor.ID, rolledFromID)
+	}
+
+	var dangling int
+	if err := testDB.QueryRow(`SELECT COUNT(*) FROM options WHERE rolled_from_id IS NOT NULL AND rolled_from_id NOT IN (SELECT id FROM options)`).Scan(&dangling); err != nil || dangling != 0 {
+		t.Fatalf("expected no rolls from deleted options, got %d (%v)", dangling, err)
+	}
+}
@@ -16,21 +16,28 @@ type Treasury struct {
 	BuyPrice     float64    `json:"buy_price"`
 	CurrentValue *float64   `json:"current_value"`
 	ExitPrice    *float64   `json:"exit_price"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	// CouponRate is the annual coupon as a percent of face value; 0 for zero-coupon bills
+	CouponRate float64 `json:"coupon_rate"`
+	// CouponFrequency is coupon payments per year (2 for most notes and bonds); 0 for bills
+	CouponFrequency int `json:"coupon_frequency"`
+	// CouponsReceived is the sum of recorded coupon payments
+	CouponsReceived float64   `json:"coupons_received"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 func (t *Treasury) CalculateProfitLoss() float64 {
+	// Coupons received count toward total return alongside the price change
 	// Use exit price if bond was sold
 	if t.ExitPrice != nil {
-		return *t.ExitPrice - t.BuyPrice
+		return *t.ExitPrice - t.BuyPrice + t.CouponsReceived
 	}
 	// Use current market value if available
 	if t.CurrentValue != nil {
-		return *t.CurrentValue - t.BuyPrice
+		return *t.CurrentValue - t.BuyPrice + t.CouponsReceived
 	}
 	// Fall back to face value vs purchase price
-	return t.Amount - t.BuyPrice
+	return t.Amount - t.BuyPrice + t.CouponsReceived
 }
 
 func (t *Treasury) CalculateROI() float64 {
@@ -46,12 +53,84 @@ func (t *Treasury) CalculateTotalInvested() float64 {
 }
 
 func (t *Treasury) CalculateInterest() float64 {
-	// Interest earned is the difference between Exit Price and Buy Price
-	// Only calculate if bond has been sold (Exit Price is set)
+	// Interest earned is the difference between Exit Price and Buy Price plus any coupons received.
+	// The price difference only counts once the bond has been sold (Exit Price is set)
 	if t.ExitPrice == nil {
-		return 0.0 // No interest earned until bond is sold
+		return t.CouponsReceived // Coupons are earned as they are paid
 	}
-	return *t.ExitPrice - t.BuyPrice
+	return *t.ExitPrice - t.BuyPrice + t.CouponsReceived
+}
+
+// HasCoupon reports whether the treasury pays coupons (notes/bonds rather than bills)
+func (t *Treasury) HasCoupon() bool {
+	return t.CouponRate > 0 && t.CouponFrequency > 0
+}
+
+// CalculateCouponPayment returns the amount of each coupon payment on the face value
+func (t *Treasury) CalculateCouponPayment() float64 {
+	if !t.HasCoupon() {
+		return 0
+	}
+	return t.Amount * t.CouponRate / 100 / float64(t.CouponFrequency)
+}
+
+// CouponDates returns the scheduled coupon dates after from and on or before to. Coupons are
+// scheduled backward from maturity at 12/CouponFrequency month intervals.
+func (t *Treasury) CouponDates(from, to time.Time) []time.Time {
+	if !t.HasCoupon() {
+		return nil
+	}
+	monthsBetween := 12 / t.CouponFrequency
+	if monthsBetween <= 0 {
+		monthsBetween = 1
+	}
+
+	var dates []time.Time
+	for i := 0; ; i++ {
+		date := t.Maturity.AddDate(0, -monthsBetween*i, 0)
+		if !date.After(from) || !date.After(t.Purchased) {
+			break
+		}
+		if !date.After(to) {
+			dates = append([]time.Time{date}, dates...)
+		}
+	}
+	return dates
+}
+
+// CalculateAccruedCoupon returns the coupon interest earned since the last coupon date as of asOf
+func (t *Treasury) CalculateAccruedCoupon(asOf time.Time) float64 {
+	if !t.HasCoupon() || t.ExitPrice != nil || asOf.Before(t.Purchased) || !asOf.Before(t.Maturity) {
+		return 0
+	}
+	monthsBetween := 12 / t.CouponFrequency
+	next := t.Maturity
+	for {
+		previous := next.AddDate(0, -monthsBetween, 0)
+		if !previous.After(asOf) {
+			start := previous
+			if start.Before(t.Purchased) {
+				start = t.Purchased
+			}
+			period := next.Sub(previous).Hours()
+			if period <= 0 {
+				return 0
+			}
+			return t.CalculateCouponPayment() * asOf.Sub(start).Hours() / period
+		}
+		next = previous
+	}
+}
+
+// CalculateYieldToMaturity returns the simple annualized return (percent) from holding to maturity:
+// the discount to face value plus all coupons paid after purchase, over the buy price
+func (t *Treasury) CalculateYieldToMaturity() float64 {
+	years := t.Maturity.Sub(t.Purchased).Hours() / (24 * 365)
+	if t.BuyPrice <= 0 || years <= 0 {
+		return 0
+	}
+	coupons := t.CalculateCouponPayment() * float64(len(t.CouponDates(t.Purchased, t.Maturity)))
+	return (t.Amount - t.BuyPrice + coupons) / t.BuyPrice / years * 100
 }
 
 // GetCurrentValue returns the current value as a float64, or 0.0 if nil
@@ -110,7 +189,7 @@ func (s *TreasuryService) Create(cuspid string, purchased, maturity time.Time, a
 
 	query := `INSERT INTO treasuries (cuspid, purchased, maturity, amount, yield, buy_price) 
 			  VALUES (?, ?, ?, ?, ?, ?) 
-			  RETURNING cuspid, purchased, maturity, amount, yield, buy_price, current_value, exit_price, created_at, updated_at`
+			  RETURNING cuspid, purchased, maturity, amount, yield, buy_price, current_value, exit_price, coupon_rate, coupon_frequency, created_at, updated_at`
 	
 	log.Printf("[TREASURY SERVICE] Create: Executing SQL query for CUSPID=%s", cuspid)
 	log.Printf("[TREASURY SERVICE] Create: SQL = %s", query)
@@ -119,7 +198,7 @@ func (s *TreasuryService) Create(cuspid string, purchased, maturity time.Time, a
 	err := s.db.QueryRow(query, cuspid, purchased, maturity, amount, yield, buyPrice).Scan(
 		&treasury.CUSPID, &treasury.Purchased, &treasury.Maturity, &treasury.Amount,
 		&treasury.Yield, &treasury.BuyPrice, &treasury.CurrentValue, &treasury.ExitPrice,
-		&treasury.CreatedAt, &treasury.UpdatedAt,
+		&treasury.CouponRate, &treasury.CouponFrequency, &treasury.CreatedAt, &treasury.UpdatedAt,
 	)
 	if err != nil {
 		log.Printf("[TREASURY SERVICE] Create: ERROR - SQL execution failed for CUSPID=%s: %v", cuspid, err)
@@ -158,7 +237,7 @@ func (s *TreasuryService) CreateFull(cuspid string, purchased, maturity time.Tim
 
 	query := `INSERT INTO treasuries (cuspid, purchased, maturity, amount, yield, buy_price, current_value, exit_price) 
 			  VALUES (?, ?, ?, ?, ?, ?, ?, ?) 
-			  RETURNING cuspid, purchased, maturity, amount, yield, buy_price, current_value, exit_price, created_at, updated_at`
+			  RETURNING cuspid, purchased, maturity, amount, yield, buy_price, current_value, exit_price, coupon_rate, coupon_frequency, created_at, updated_at`
 	
 	log.Printf("[TREASURY SERVICE] CreateFull: Executing SQL query for CUSPID=%s", cuspid)
 	log.Printf("[TREASURY SERVICE] CreateFull: SQL = %s", query)
@@ -167,7 +246,7 @@ func (s *TreasuryService) CreateFull(cuspid string, purchased, maturity time.Tim
 	err := s.db.QueryRow(query, cuspid, purchased, maturity, amount, yield, buyPrice, currentValue, exitPrice).Scan(
 		&treasury.CUSPID, &treasury.Purchased, &treasury.Maturity, &treasury.Amount,
 		&treasury.Yield, &treasury.BuyPrice, &treasury.CurrentValue, &treasury.ExitPrice,
-		&treasury.CreatedAt, &treasury.UpdatedAt,
+		&treasury.CouponRate, &treasury.CouponFrequency, &treasury.CreatedAt, &treasury.UpdatedAt,
 	)
 	if err != nil {
 		log.Printf("[TREASURY SERVICE] CreateFull: ERROR - SQL execution failed for CUSPID=%s: %v", cuspid, err)
@@ -186,7 +265,8 @@ func (s *TreasuryService) CreateFull(cuspid string, purchased, maturity time.Tim
 func (s *TreasuryService) GetAll() ([]*Treasury, error) {
 	log.Printf("[TREASURY SERVICE] GetAll: Starting to retrieve all treasuries")
 	
-	query := `SELECT cuspid, purchased, maturity, amount, yield, buy_price, current_value, exit_price, created_at, updated_at 
+	query := `SELECT cuspid, purchased, maturity, amount, yield, buy_price, current_value, exit_price, coupon_rate, coupon_frequency,
+			  (SELECT COALESCE(SUM(c.amount), 0) FROM treasury_coupons c WHERE c.cuspid = treasuries.cuspid), created_at, updated_at 
 			  FROM treasuries ORDER BY maturity DESC, purchased DESC`
 	
 	log.Printf("[TREASURY SERVICE] GetAll: Executing SQL query")
@@ -205,7 +285,7 @@ func (s *TreasuryService) GetAll() ([]*Treasury, error) {
 		var treasury Treasury
 		if err := rows.Scan(&treasury.CUSPID, &treasury.Purchased, &treasury.Maturity, &treasury.Amount,
 			&treasury.Yield, &treasury.BuyPrice, &treasury.CurrentValue, &treasury.ExitPrice,
-			&treasury.CreatedAt, &treasury.UpdatedAt); err != nil {
+			&treasury.CouponRate, &treasury.CouponFrequency, &treasury.CouponsReceived, &treasury.CreatedAt, &treasury.UpdatedAt); err != nil {
 			log.Printf("[TREASURY SERVICE] GetAll: ERROR - Failed to scan row %d: %v", rowCount, err)
 			return nil, fmt.Errorf("failed to scan treasury: %w", err)
 		}
@@ -227,7 +307,8 @@ func (s *TreasuryService) GetAll() ([]*Treasury, error) {
 func (s *TreasuryService) GetByCUSPID(cuspid string) (*Treasury, error) {
 	log.Printf("[TREASURY SERVICE] GetByCUSPID: Starting to retrieve treasury for CUSPID=%s", cuspid)
 	
-	query := `SELECT cuspid, purchased, maturity, amount, yield, buy_price, current_value, exit_price, created_at, updated_at 
+	query := `SELECT cuspid, purchased, maturity, amount, yield, buy_price, current_value, exit_price, coupon_rate, coupon_frequency,
+			  (SELECT COALESCE(SUM(c.amount), 0) FROM treasury_coupons c WHERE c.cuspid = treasuries.cuspid), created_at, updated_at 
 			  FROM treasuries WHERE cuspid = ?`
 	
 	log.Printf("[TREASURY SERVICE] GetByCUSPID: Executing SQL query for CUSPID=%s", cuspid)
@@ -236,7 +317,7 @@ func (s *TreasuryService) GetByCUSPID(cuspid string) (*Treasury, error) {
 	var treasury Treasury
 	err := s.db.QueryRow(query, cuspid).Scan(&treasury.CUSPID, &treasury.Purchased, &treasury.Maturity,
 		&treasury.Amount, &treasury.Yield, &treasury.BuyPrice, &treasury.CurrentValue, &treasury.ExitPrice,
-		&treasury.CreatedAt, &treasury.UpdatedAt)
+		&treasury.CouponRate, &treasury.CouponFrequency, &treasury.CouponsReceived, &treasury.CreatedAt, &treasury.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("[TREASURY SERVICE] GetByCUSPID: ERROR - Treasury not found for CUSPID=%s", cuspid)
@@ -256,18 +337,21 @@ func (s *TreasuryService) GetByCUSPID(cuspid string) (*Treasury, error) {
 func (s *TreasuryService) Update(cuspid string, currentValue, exitPrice *float64) (*Treasury, error) {
 	query := `UPDATE treasuries SET current_value = ?, exit_price = ?, updated_at = CURRENT_TIMESTAMP 
 			  WHERE cuspid = ? 
-			  RETURNING cuspid, purchased, maturity, amount, yield, buy_price, current_value, exit_price, created_at, updated_at`
+			  RETURNING cuspid, purchased, maturity, amount, yield, buy_price, current_value, exit_price, coupon_rate, coupon_frequency, created_at, updated_at`
 	
 	var treasury Treasury
 	err := s.db.QueryRow(query, currentValue, exitPrice, cuspid).Scan(&treasury.CUSPID, &treasury.Purchased,
 		&treasury.Maturity, &treasury.Amount, &treasury.Yield, &treasury.BuyPrice, &treasury.CurrentValue,
-		&treasury.ExitPrice, &treasury.CreatedAt, &treasury.UpdatedAt)
+		&treasury.ExitPrice, &treasury.CouponRate, &treasury.CouponFrequency, &treasury.CreatedAt, &treasury.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("treasury not found")
 		}
 		return nil, fmt.Errorf("failed to update treasury: %w", err)
 	}
+	if err := s.loadCouponsReceived(&treasury); err != nil {
+		return nil, err
+	}
 
 	return &treasury, nil
 }
@@ -290,7 +374,7 @@ func (s *TreasuryService) UpdateFull(cuspid string, purchased, maturity time.Tim
 	
 	query := `UPDATE treasuries SET purchased = ?, maturity = ?, amount = ?, yield = ?, buy_price = ?, current_value = ?, exit_price = ?, updated_at = CURRENT_TIMESTAMP 
 			  WHERE cuspid = ? 
-			  RETURNING cuspid, purchased, maturity, amount, yield, buy_price, current_value, exit_price, created_at, updated_at`
+			  RETURNING cuspid, purchased, maturity, amount, yield, buy_price, current_value, exit_price, coupon_rate, coupon_frequency, created_at, updated_at`
 	
 	log.Printf("[TREASURY SERVICE] UpdateFull: Executing SQL query for CUSPID=%s", cuspid)
 	log.Printf("[TREASURY SERVICE] UpdateFull: SQL = %s", query)
@@ -299,7 +383,7 @@ func (s *TreasuryService) UpdateFull(cuspid string, purchased, maturity time.Tim
 	err := s.db.QueryRow(query, purchased, maturity, amount, yield, buyPrice, currentValue, exitPrice, cuspid).Scan(
 		&treasury.CUSPID, &treasury.Purchased, &treasury.Maturity, &treasury.Amount,
 		&treasury.Yield, &treasury.BuyPrice, &treasury.CurrentValue, &treasury.ExitPrice,
-		&treasury.CreatedAt, &treasury.UpdatedAt)
+		&treasury.CouponRate, &treasury.CouponFrequency, &treasury.CreatedAt, &treasury.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("[TREASURY SERVICE] UpdateFull: ERROR - Treasury not found for CUSPID=%s", cuspid)
@@ -311,6 +395,10 @@ func (s *TreasuryService) UpdateFull(cuspid string, purchased, maturity time.Tim
 		return nil, fmt.Errorf("failed to update treasury: %w", err)
 	}
 
+	if err := s.loadCouponsReceived(&treasury); err != nil {
+		return nil, err
+	}
+
 	log.Printf("[TREASURY SERVICE] UpdateFull: Successfully updated treasury for CUSPID=%s", cuspid)
 	log.Printf("[TREASURY SERVICE] UpdateFull: Updated treasury data - Amount=%.2f, Yield=%.3f, BuyPrice=%.2f, UpdatedAt=%v", 
 		treasury.Amount, treasury.Yield, treasury.BuyPrice, treasury.UpdatedAt)
@@ -318,6 +406,42 @@ func (s *TreasuryService) UpdateFull(cuspid string, purchased, maturity time.Tim
 	return &treasury, nil
 }
 
+// SetCoupon records the annual coupon rate (percent of face) and payments per year for a note or bond.
+// A zero rate or frequency marks the treasury as a zero-coupon bill.
+func (s *TreasuryService) SetCoupon(cuspid string, couponRate float64, couponFrequency int) error {
+	if couponRate < 0 {
+		return fmt.Errorf("coupon rate cannot be negative")
+	}
+	switch couponFrequency {
+	case 0, 1, 2, 4, 12:
+	default:
+		return fmt.Errorf("coupon frequency must be 0, 1, 2, 4 or 12 payments per year")
+	}
+
+	result, err := s.db.Exec(`UPDATE treasuries SET coupon_rate = ?, coupon_frequency = ?, updated_at = CURRENT_TIMESTAMP WHERE cuspid = ?`,
+		couponRate, couponFrequency, cuspid)
+	if err != nil {
+		return fmt.Errorf("failed to set treasury coupon: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("treasury not found")
+	}
+	return nil
+}
+
+// loadCouponsReceived fills in the total of recorded coupon payments for a treasury
+func (s *TreasuryService) loadCouponsReceived(treasury *Treasury) error {
+	err := s.db.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM treasury_coupons WHERE cuspid = ?`, treasury.CUSPID).Scan(&treasury.CouponsReceived)
+	if err != nil {
+		return fmt.Errorf("failed to load coupons received: %w", err)
+	}
+	return nil
+}
+
 func (s *TreasuryService) Delete(cuspid string) error {
 	log.Printf("[TREASURY SERVICE] Delete: Starting deletion for CUSPID=%s", cuspid)
 	
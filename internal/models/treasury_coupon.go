@@ -0,0 +1,97 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TreasuryCoupon is a coupon payment received on a treasury note or bond
+type TreasuryCoupon struct {
+	ID        int       `json:"id"`
+	CUSPID    string    `json:"cuspid"`
+	Received  time.Time `json:"received"`
+	Amount    float64   `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TreasuryCouponService struct {
+	db *sql.DB
+}
+
+func NewTreasuryCouponService(db *sql.DB) *TreasuryCouponService {
+	return &TreasuryCouponService{db: db}
+}
+
+func (s *TreasuryCouponService) Create(cuspid string, received time.Time, amount float64) (*TreasuryCoupon, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("coupon amount must be positive")
+	}
+
+	query := `INSERT INTO treasury_coupons (cuspid, received, amount) 
+			  VALUES (?, ?, ?) 
+			  RETURNING id, cuspid, received, amount, created_at`
+
+	var coupon TreasuryCoupon
+	err := s.db.QueryRow(query, cuspid, received, amount).Scan(
+		&coupon.ID, &coupon.CUSPID, &coupon.Received, &coupon.Amount, &coupon.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create treasury coupon: %w", err)
+	}
+
+	return &coupon, nil
+}
+
+func (s *TreasuryCouponService) GetByCUSPID(cuspid string) ([]*TreasuryCoupon, error) {
+	query := `SELECT id, cuspid, received, amount, created_at 
+			  FROM treasury_coupons WHERE cuspid = ? ORDER BY received DESC`
+	return s.query(query, cuspid)
+}
+
+func (s *TreasuryCouponService) GetAll() ([]*TreasuryCoupon, error) {
+	query := `SELECT id, cuspid, received, amount, created_at 
+			  FROM treasury_coupons ORDER BY received DESC`
+	return s.query(query)
+}
+
+func (s *TreasuryCouponService) DeleteByID(id int) error {
+	result, err := s.db.Exec(`DELETE FROM treasury_coupons WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete treasury coupon: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("treasury coupon not found")
+	}
+
+	return nil
+}
+
+func (s *TreasuryCouponService) query(query string, args ...interface{}) ([]*TreasuryCoupon, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get treasury coupons: %w", err)
+	}
+	defer rows.Close()
+
+	var coupons []*TreasuryCoupon
+	for rows.Next() {
+		var coupon TreasuryCoupon
+		if err := rows.Scan(&coupon.ID, &coupon.CUSPID, &coupon.Received, &coupon.Amount, &coupon.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan treasury coupon: %w", err)
+		}
+		coupons = append(coupons, &coupon)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating treasury coupons: %w", err)
+	}
+
+	return coupons, nil
+}
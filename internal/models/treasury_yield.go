@@ -0,0 +1,51 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Treasury yields and coupon rates are stored in percent: 4.5 means 4.5%, never 0.045. Coupon
+// payments divide by 100 accordingly, and pages show the stored value with a % sign.
+
+// Input scales for imported treasury yields and coupon rates (TREASURY_YIELD_INPUT_SCALE setting)
+const (
+	// TreasuryYieldScaleAuto reads a value with a % sign as percent, a bare value below 1 as a
+	// decimal fraction (0.045 is 4.5%) and any other bare value as percent
+	TreasuryYieldScaleAuto = "auto"
+	// TreasuryYieldScalePercent reads every value as percent, for files with sub-1% yields
+	TreasuryYieldScalePercent = "percent"
+	// TreasuryYieldScaleDecimal reads bare values as decimal fractions; a % sign still means percent
+	TreasuryYieldScaleDecimal = "decimal"
+)
+
+// MaxTreasuryYieldPercent is the highest yield or coupon rate accepted once normalized to percent.
+// Anything above it is almost certainly a decimal/percent mix-up or a typo.
+const MaxTreasuryYieldPercent = 20.0
+
+// NormalizeTreasuryYield converts a yield or coupon rate read under the given input scale to the
+// stored percent convention. percentSign reports whether the input carried a trailing %. Negative
+// results and results above MaxTreasuryYieldPercent are rejected as implausible.
+func NormalizeTreasuryYield(value float64, percentSign bool, scale string) (float64, error) {
+	if value < 0 {
+		return 0, fmt.Errorf("yield cannot be negative, got %g", value)
+	}
+
+	percent := value
+	if !percentSign {
+		switch strings.ToLower(strings.TrimSpace(scale)) {
+		case TreasuryYieldScalePercent:
+		case TreasuryYieldScaleDecimal:
+			percent = value * 100
+		default:
+			if value < 1 {
+				percent = value * 100
+			}
+		}
+	}
+
+	if percent > MaxTreasuryYieldPercent {
+		return 0, fmt.Errorf("yield %g%% is implausible (above %g%%); check whether the value is a percent or a decimal fraction", percent, MaxTreasuryYieldPercent)
+	}
+	return percent, nil
+}
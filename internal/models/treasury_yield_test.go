@@ -0,0 +1,59 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizeTreasuryYieldInputStyles(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       float64
+		percentSign bool
+		scale       string
+		want        float64
+	}{
+		{"percent style", 4.5, false, TreasuryYieldScaleAuto, 4.5},
+		{"percent style with sign", 4.5, true, TreasuryYieldScaleAuto, 4.5},
+		{"decimal style", 0.045, false, TreasuryYieldScaleAuto, 4.5},
+		{"sub-1% with sign stays percent", 0.45, true, TreasuryYieldScaleAuto, 0.45},
+		{"percent scale keeps sub-1% yields", 0.45, false, TreasuryYieldScalePercent, 0.45},
+		{"decimal scale", 0.045, false, TreasuryYieldScaleDecimal, 4.5},
+		{"decimal scale above 1%", 0.12, false, TreasuryYieldScaleDecimal, 12},
+		{"decimal scale honors sign", 4.5, true, TreasuryYieldScaleDecimal, 4.5},
+		{"unknown scale is auto", 0.0525, false, "", 5.25},
+		{"zero", 0, false, TreasuryYieldScaleAuto, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeTreasuryYield(tt.value, tt.percentSign, tt.scale)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("expected %g%%, got %g%%", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeTreasuryYieldFlagsImplausibleValues(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       float64
+		percentSign bool
+		scale       string
+	}{
+		{"negative", -1, false, TreasuryYieldScaleAuto},
+		{"basis points typed as percent", 450, false, TreasuryYieldScaleAuto},
+		{"percent value under decimal scale", 4.5, false, TreasuryYieldScaleDecimal},
+		{"above the cap", 25, true, TreasuryYieldScaleAuto},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := NormalizeTreasuryYield(tt.value, tt.percentSign, tt.scale); err == nil {
+				t.Fatalf("expected an error, got %g%%", got)
+			}
+		})
+	}
+}
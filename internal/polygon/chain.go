@@ -0,0 +1,125 @@
+package polygon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// chainPageLimit is the largest page size the chain snapshot endpoint accepts
+	chainPageLimit = 250
+	// chainMaxPages bounds pagination so a wide expiration range cannot fan out indefinitely
+	chainMaxPages = 8
+)
+
+// ChainContract is one contract from an option chain snapshot
+type ChainContract struct {
+	BreakEvenPrice    float64         `json:"break_even_price"`
+	Day               DayData         `json:"day"`
+	Details           OptionDetails   `json:"details"`
+	Greeks            Greeks          `json:"greeks"`
+	ImpliedVolatility float64         `json:"implied_volatility"`
+	LastQuote         Quote           `json:"last_quote"`
+	LastTrade         Trade           `json:"last_trade"`
+	OpenInterest      float64         `json:"open_interest"`
+	UnderlyingAsset   UnderlyingAsset `json:"underlying_asset"`
+}
+
+// Expiration parses the contract's expiration date
+func (c ChainContract) Expiration() (time.Time, error) {
+	return time.Parse("2006-01-02", c.Details.ExpirationDate)
+}
+
+// ChainQuery selects the contracts returned by GetOptionChain
+type ChainQuery struct {
+	ContractType   string // "call" or "put"; empty returns both
+	ExpirationFrom time.Time
+	ExpirationTo   time.Time
+}
+
+type chainResponse struct {
+	Status    string          `json:"status"`
+	Results   []ChainContract `json:"results"`
+	NextURL   string          `json:"next_url"`
+	RequestID string          `json:"request_id"`
+}
+
+// GetOptionChain fetches the option chain snapshot for an underlying, following pagination
+func (c *Client) GetOptionChain(ctx context.Context, underlyingAsset string, query ChainQuery) ([]ChainContract, error) {
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("polygon API key not configured")
+	}
+
+	params := url.Values{}
+	params.Set("limit", fmt.Sprintf("%d", chainPageLimit))
+	if query.ContractType != "" {
+		params.Set("contract_type", strings.ToLower(query.ContractType))
+	}
+	if !query.ExpirationFrom.IsZero() {
+		params.Set("expiration_date.gte", query.ExpirationFrom.Format("2006-01-02"))
+	}
+	if !query.ExpirationTo.IsZero() {
+		params.Set("expiration_date.lte", query.ExpirationTo.Format("2006-01-02"))
+	}
+	next := fmt.Sprintf("%s/v3/snapshot/options/%s?%s", c.baseURL, url.PathEscape(underlyingAsset), params.Encode())
+
+	var contracts []ChainContract
+	for page := 0; next != "" && page < chainMaxPages; page++ {
+		// next_url omits the API key, so it is appended to every page
+		chain, err := c.getChainPage(ctx, next+"&apikey="+c.apiKey)
+		if err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, chain.Results...)
+		next = chain.NextURL
+	}
+
+	return contracts, nil
+}
+
+func (c *Client) getChainPage(ctx context.Context, pageURL string) (*chainResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("unauthorized: invalid or missing Polygon API key (status 401)")
+		} else if resp.StatusCode == http.StatusForbidden {
+			return nil, fmt.Errorf("forbidden: API key may not have access to this endpoint (status 403)")
+		}
+		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	var chain chainResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chain); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if chain.Status != "OK" && chain.Status != "DELAYED" {
+		return nil, fmt.Errorf("API returned status: %s", chain.Status)
+	}
+
+	return &chain, nil
+}
+
+// GetOptionChain fetches the option chain for a symbol from Polygon
+func (s *Service) GetOptionChain(ctx context.Context, symbol string, query ChainQuery) ([]ChainContract, error) {
+	client, err := s.getClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetOptionChain(ctx, strings.ToUpper(symbol), query)
+}
@@ -288,6 +288,81 @@ func (c *Client) GetPreviousClose(ctx context.Context, symbol string) (*StockQuo
 	return quote, nil
 }
 
+// GroupedDailyBar is one ticker's daily aggregate from the grouped-daily endpoint
+type GroupedDailyBar struct {
+	Symbol         string  `json:"T"`
+	Volume         float64 `json:"v"`
+	VolumeWeighted float64 `json:"vw"`
+	Open           float64 `json:"o"`
+	Close          float64 `json:"c"`
+	High           float64 `json:"h"`
+	Low            float64 `json:"l"`
+	Timestamp      int64   `json:"t"`
+	Transactions   int     `json:"n"`
+}
+
+// GroupedDaily is the grouped-daily aggregates response covering every US stock for one date
+type GroupedDaily struct {
+	Status       string            `json:"status"`
+	ResultsCount int               `json:"resultsCount"`
+	Adjusted     bool              `json:"adjusted"`
+	Results      []GroupedDailyBar `json:"results"`
+	RequestID    string            `json:"request_id"`
+}
+
+// Closes returns each ticker's close price keyed by symbol
+func (g *GroupedDaily) Closes() map[string]float64 {
+	closes := make(map[string]float64, len(g.Results))
+	for _, bar := range g.Results {
+		if bar.Close > 0 {
+			closes[bar.Symbol] = bar.Close
+		}
+	}
+	return closes
+}
+
+// GetGroupedDaily fetches the daily aggregates for every US stock ticker on the given date in one request
+func (c *Client) GetGroupedDaily(ctx context.Context, date time.Time) (*GroupedDaily, error) {
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("polygon API key not configured")
+	}
+
+	endpoint := fmt.Sprintf("/v2/aggs/grouped/locale/us/market/stocks/%s", date.Format("2006-01-02"))
+	url := fmt.Sprintf("%s%s?adjusted=true&apikey=%s", c.baseURL, endpoint, c.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("unauthorized: invalid or missing Polygon API key (status 401)")
+		} else if resp.StatusCode == http.StatusForbidden {
+			return nil, fmt.Errorf("forbidden: API key may not have access to this endpoint (status 403)")
+		}
+		return nil, fmt.Errorf("API request failed with status %d", resp.StatusCode)
+	}
+
+	var grouped GroupedDaily
+	if err := json.NewDecoder(resp.Body).Decode(&grouped); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// Plans without real-time data report DELAYED for otherwise complete responses
+	if grouped.Status != "OK" && grouped.Status != "DELAYED" {
+		return nil, fmt.Errorf("API returned status: %s", grouped.Status)
+	}
+
+	return &grouped, nil
+}
+
 // GetTickerDetails fetches detailed information about a ticker
 func (c *Client) GetTickerDetails(ctx context.Context, symbol string) (*TickerDetails, error) {
 	if c.apiKey == "" {
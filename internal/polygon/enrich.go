@@ -0,0 +1,178 @@
+package polygon
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// enrichQueueSize bounds how many symbols can wait for enrichment at once
+	enrichQueueSize = 100
+	// enrichRequestInterval spaces out every Polygon request the enrichment worker makes, so it stays
+	// within the free tier limit of 5 requests per minute
+	enrichRequestInterval = 12 * time.Second
+)
+
+// requestPacer spaces out API requests so that no two start less than interval apart
+type requestPacer struct {
+	interval time.Duration
+	last     time.Time
+}
+
+// wait blocks until the next request may start, or until ctx is done
+func (p *requestPacer) wait(ctx context.Context) error {
+	if !p.last.IsZero() {
+		if delay := time.Until(p.last.Add(p.interval)); delay > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+	}
+	p.last = time.Now()
+	return nil
+}
+
+// EnrichmentStatus reports the background enrichment worker's queue and its most recent run
+type EnrichmentStatus struct {
+	Running    bool
+	Queued     int
+	LastRunAt  *time.Time
+	LastSymbol string
+	LastError  string
+}
+
+// EnrichmentStatus returns the worker's current queue depth and the outcome of its last enrichment
+func (s *Service) EnrichmentStatus() EnrichmentStatus {
+	s.enrichMu.Lock()
+	status := s.enrichLast
+	s.enrichMu.Unlock()
+
+	status.Running = s.enrichDone != nil
+	if s.enrichQueue != nil {
+		status.Queued = len(s.enrichQueue)
+	}
+	return status
+}
+
+// StartSymbolEnrichment starts the background worker that enriches newly created symbols
+func (s *Service) StartSymbolEnrichment() {
+	s.enrichQueue = make(chan string, enrichQueueSize)
+	s.enrichDone = make(chan struct{})
+	go s.runSymbolEnrichment(s.enrichQueue, s.enrichDone)
+	log.Printf("[POLYGON] Symbol enrichment worker started (queue size %d)", enrichQueueSize)
+}
+
+// StopSymbolEnrichment stops the background enrichment worker; queued symbols are dropped
+func (s *Service) StopSymbolEnrichment() {
+	if s.enrichDone != nil {
+		close(s.enrichDone)
+		s.enrichDone = nil
+	}
+}
+
+// QueueSymbolEnrichment schedules a symbol for enrichment when AUTO_ENRICH_SYMBOLS is enabled.
+// It never blocks; symbols are dropped with a log line if the queue is full.
+func (s *Service) QueueSymbolEnrichment(symbol string) {
+	if s.enrichQueue == nil || !s.settingService.GetBoolWithDefault("AUTO_ENRICH_SYMBOLS", false) {
+		return
+	}
+
+	select {
+	case s.enrichQueue <- symbol:
+		log.Printf("[POLYGON] Queued %s for enrichment", symbol)
+	default:
+		log.Printf("[POLYGON] Enrichment queue full, skipping %s", symbol)
+	}
+}
+
+func (s *Service) runSymbolEnrichment(queue <-chan string, done <-chan struct{}) {
+	// Stopping the worker also abandons a request waiting on the pacer
+	stopped, stop := context.WithCancel(context.Background())
+	defer stop()
+	go func() {
+		<-done
+		stop()
+	}()
+
+	pacer := &requestPacer{interval: enrichRequestInterval}
+	for {
+		select {
+		case <-done:
+			return
+		case symbol := <-queue:
+			ctx, cancel := context.WithTimeout(stopped, 60*time.Second)
+			started := time.Now()
+			err := s.enrichSymbol(ctx, symbol, pacer)
+			if err != nil {
+				log.Printf("[POLYGON] Failed to enrich %s: %v", symbol, err)
+			}
+			cancel()
+
+			s.enrichMu.Lock()
+			s.enrichLast.LastRunAt = &started
+			s.enrichLast.LastSymbol = symbol
+			s.enrichLast.LastError = ""
+			if err != nil {
+				s.enrichLast.LastError = err.Error()
+			}
+			s.enrichMu.Unlock()
+		}
+	}
+}
+
+// EnrichSymbol populates price, dividend and ex-dividend date for a symbol from Polygon. A manual
+// price is kept.
+func (s *Service) EnrichSymbol(ctx context.Context, symbol string) error {
+	return s.enrichSymbol(ctx, symbol, &requestPacer{interval: enrichRequestInterval})
+}
+
+// enrichSymbol enriches a symbol, waiting on pacer before each of its Polygon requests
+func (s *Service) enrichSymbol(ctx context.Context, symbol string, pacer *requestPacer) error {
+	current, err := s.symbolService.GetBySymbol(symbol)
+	if err != nil {
+		return fmt.Errorf("failed to get current symbol data: %w", err)
+	}
+
+	price := current.Price
+	dividend := current.Dividend
+	exDividendDate := current.ExDividendDate
+
+	client, err := s.getClient()
+	if err != nil {
+		return fmt.Errorf("failed to get Polygon client: %w", err)
+	}
+
+	if err := pacer.wait(ctx); err != nil {
+		return err
+	}
+	quote, err := client.GetPreviousClose(ctx, symbol)
+	if err != nil {
+		log.Printf("[POLYGON] Warning: failed to get current price for %s: %v", symbol, err)
+	} else if quote.Results.Price > 0 && !current.ManualPrice {
+		price = quote.Results.Price
+	}
+
+	if err := pacer.wait(ctx); err != nil {
+		return err
+	}
+	dividends, err := client.GetDividends(ctx, symbol, 1)
+	if err != nil {
+		log.Printf("[POLYGON] Warning: failed to get dividends for %s: %v", symbol, err)
+	} else if len(dividends.Results) > 0 {
+		dividend = dividends.Results[0].CashAmount
+		if parsed, err := time.Parse("2006-01-02", dividends.Results[0].ExDividendDate); err == nil {
+			exDividendDate = &parsed
+		}
+	}
+
+	if _, err := s.symbolService.Update(symbol, price, dividend, exDividendDate, current.PERatio); err != nil {
+		return fmt.Errorf("failed to update symbol: %w", err)
+	}
+
+	log.Printf("[POLYGON] Enriched %s: price=$%.2f dividend=$%.4f", symbol, price, dividend)
+	return nil
+}
@@ -219,7 +219,7 @@ func TestLivePolygonIntegration(t *testing.T) {
 		}
 
 		// Test service-level price update
-		err = service.UpdateSymbolPrice(ctx, "TSLA")
+		err = service.UpdateSymbolPrice(ctx, "TSLA", true)
 		if err != nil {
 			t.Errorf("Service price update failed: %v", err)
 			return
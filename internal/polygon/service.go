@@ -2,11 +2,14 @@ package polygon
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"stonks/internal/models"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,6 +18,15 @@ type Service struct {
 	client         *Client
 	symbolService  *models.SymbolService
 	settingService *models.SettingService
+	enrichQueue    chan string
+	enrichDone     chan struct{}
+
+	// enrichMu guards the outcome of the most recent background enrichment
+	enrichMu   sync.Mutex
+	enrichLast EnrichmentStatus
+
+	// staleUpdateMu keeps overlapping stale-price updates from running at the same time
+	staleUpdateMu sync.Mutex
 }
 
 // NewService creates a new Polygon service
@@ -44,8 +56,25 @@ func (s *Service) getClient() (*Client, error) {
 	return NewClient(apiKey), nil
 }
 
-// UpdateSymbolPrice updates a single symbol's price from Polygon.io
-func (s *Service) UpdateSymbolPrice(ctx context.Context, symbol string) error {
+// ErrRecentlyUpdated is returned by UpdateSymbolPrice when the symbol's price was updated within
+// MIN_PRICE_UPDATE_INTERVAL_MINUTES and the update was not forced
+var ErrRecentlyUpdated = errors.New("price was updated recently")
+
+// UpdateSymbolPrice updates a single symbol's price from Polygon.io. Symbols flagged with a
+// manual price are left untouched and return an error. Unless force is set, a symbol priced
+// within MIN_PRICE_UPDATE_INTERVAL_MINUTES is skipped with an error wrapping ErrRecentlyUpdated.
+func (s *Service) UpdateSymbolPrice(ctx context.Context, symbol string, force bool) error {
+	if current, err := s.symbolService.GetBySymbol(symbol); err == nil {
+		if current.ManualPrice {
+			log.Printf("[POLYGON] Skipping %s: price is maintained manually", symbol)
+			return fmt.Errorf("%s has a manual price and is not updated from Polygon", symbol)
+		}
+		if !force && s.recentlyUpdated(current) {
+			log.Printf("[POLYGON] Skipping %s: price updated at %s, within the minimum update interval", symbol, current.UpdatedAt.Format(time.RFC3339))
+			return fmt.Errorf("%s %w (%s)", symbol, ErrRecentlyUpdated, current.UpdatedAt.Format(time.RFC3339))
+		}
+	}
+
 	client, err := s.getClient()
 	if err != nil {
 		return fmt.Errorf("failed to get Polygon client: %w", err)
@@ -59,16 +88,52 @@ func (s *Service) UpdateSymbolPrice(ctx context.Context, symbol string) error {
 		return fmt.Errorf("failed to get quote for %s: %w", symbol, err)
 	}
 
-	// Get current symbol data
+	if err := s.applySymbolPrice(symbol, quote.Results.Price); err != nil {
+		return err
+	}
+	session := s.previousSessionDate()
+	if quote.Results.Timestamp > 0 {
+		session = time.UnixMilli(quote.Results.Timestamp).In(s.marketLocation())
+	}
+	s.recordClose(symbol, session, quote.Results.Price)
+	return nil
+}
+
+// recentlyUpdated reports whether a symbol was priced within MIN_PRICE_UPDATE_INTERVAL_MINUTES of
+// now, judged by its updated_at so the throttle holds across restarts. Symbols without a price
+// yet are never throttled.
+func (s *Service) recentlyUpdated(current *models.Symbol) bool {
+	minutes := s.settingService.GetFloatWithDefault("MIN_PRICE_UPDATE_INTERVAL_MINUTES", 1)
+	if minutes <= 0 || current.Price <= 0 {
+		return false
+	}
+	return time.Since(current.UpdatedAt) < time.Duration(minutes*float64(time.Minute))
+}
+
+// recordClose keeps a session close in the price history; a failure only costs a history point,
+// so it is logged rather than failing the price update
+func (s *Service) recordClose(symbol string, session time.Time, price float64) {
+	if err := s.symbolService.RecordClose(symbol, session, price); err != nil {
+		log.Printf("[POLYGON] Warning: Failed to record %s close for %s: %v", session.Format("2006-01-02"), symbol, err)
+	}
+}
+
+// applySymbolPrice stores a new price for a symbol, keeping its dividend and valuation fields
+func (s *Service) applySymbolPrice(symbol string, price float64) error {
 	currentSymbol, err := s.symbolService.GetBySymbol(symbol)
 	if err != nil {
 		return fmt.Errorf("failed to get current symbol data: %w", err)
 	}
 
-	// Update symbol with new price
+	// Halted or delisted tickers can come back with no price; keep the last known good one
+	if price <= 0 && currentSymbol.Price > 0 && s.settingService.GetBoolWithDefault("PRESERVE_PRICE_ON_ZERO", true) {
+		log.Printf("[POLYGON] Warning: Polygon returned no price for %s, keeping last price $%.2f", symbol, currentSymbol.Price)
+		return fmt.Errorf("Polygon returned no price for %s, kept last price $%.2f", symbol, currentSymbol.Price)
+	}
+
 	_, err = s.symbolService.Update(
 		symbol,
-		quote.Results.Price,
+		price,
 		currentSymbol.Dividend,
 		currentSymbol.ExDividendDate,
 		currentSymbol.PERatio,
@@ -77,7 +142,7 @@ func (s *Service) UpdateSymbolPrice(ctx context.Context, symbol string) error {
 		return fmt.Errorf("failed to update symbol price: %w", err)
 	}
 
-	log.Printf("[POLYGON] Updated %s price to $%.2f", symbol, quote.Results.Price)
+	log.Printf("[POLYGON] Updated %s price to $%.2f", symbol, price)
 	return nil
 }
 
@@ -91,21 +156,152 @@ func (s *Service) UpdateAllSymbolPrices(ctx context.Context) error {
 
 	log.Printf("[POLYGON] Starting prioritized bulk price update for %d symbols", len(symbols))
 
-	var updated, failed int
+	result := s.UpdateSymbolPricesGrouped(ctx, symbols, false)
+
+	log.Printf("[POLYGON] Prioritized bulk price update complete: %d updated (%d from grouped daily), %d failed",
+		result.Updated, result.Grouped, result.Failed)
+	return nil
+}
+
+// UpdateSymbolPricesGrouped prices the given symbols from a single grouped-daily response for the
+// previous trading session, then falls back to rate-limited per-symbol previous-close requests for
+// tickers missing from it (or for every symbol if the grouped request fails). Symbols flagged with
+// a manual price are skipped, as are symbols priced within MIN_PRICE_UPDATE_INTERVAL_MINUTES
+// unless force is set; those are listed in the result's Recent.
+func (s *Service) UpdateSymbolPricesGrouped(ctx context.Context, symbols []string, force bool) *PriceUpdateResult {
+	result := &PriceUpdateResult{}
+
+	tracked := make([]string, 0, len(symbols))
 	for _, symbol := range symbols {
-		if err := s.UpdateSymbolPrice(ctx, symbol); err != nil {
+		if current, err := s.symbolService.GetBySymbol(symbol); err == nil {
+			if current.ManualPrice {
+				result.Skipped++
+				continue
+			}
+			if !force && s.recentlyUpdated(current) {
+				result.Recent = append(result.Recent, symbol)
+				continue
+			}
+		}
+		tracked = append(tracked, symbol)
+	}
+	if result.Skipped > 0 {
+		log.Printf("[POLYGON] Skipping %d symbols with manual prices", result.Skipped)
+	}
+	if len(result.Recent) > 0 {
+		log.Printf("[POLYGON] Skipping %d symbols updated within the minimum update interval", len(result.Recent))
+	}
+	symbols = tracked
+	if len(symbols) == 0 {
+		return result
+	}
+
+	closes := map[string]float64{}
+	date := s.previousSessionDate()
+	if client, err := s.getClient(); err != nil {
+		log.Printf("[POLYGON] Grouped daily unavailable: %v", err)
+	} else {
+		grouped, err := client.GetGroupedDaily(ctx, date)
+		if err != nil {
+			log.Printf("[POLYGON] Grouped daily request for %s failed, falling back to per-symbol updates: %v", date.Format("2006-01-02"), err)
+		} else {
+			closes = grouped.Closes()
+			log.Printf("[POLYGON] Grouped daily for %s returned %d tickers", date.Format("2006-01-02"), len(closes))
+		}
+	}
+
+	var missing []string
+	for _, symbol := range symbols {
+		price, ok := closes[strings.ToUpper(symbol)]
+		if !ok {
+			missing = append(missing, symbol)
+			continue
+		}
+		if err := s.applySymbolPrice(symbol, price); err != nil {
 			log.Printf("[POLYGON] Failed to update %s: %v", symbol, err)
-			failed++
+			result.Errors = append(result.Errors, symbol+": "+err.Error())
+			result.Failed++
+			continue
+		}
+		s.recordClose(symbol, date, price)
+		result.Updated++
+		result.Grouped++
+	}
+
+	for i, symbol := range missing {
+		// Already checked against the update interval above
+		if err := s.UpdateSymbolPrice(ctx, symbol, true); err != nil {
+			log.Printf("[POLYGON] Failed to update %s: %v", symbol, err)
+			result.Errors = append(result.Errors, symbol+": "+err.Error())
+			result.Failed++
 		} else {
-			updated++
+			result.Updated++
 		}
 
 		// Rate limiting: Free tier allows 5 requests per minute
-		time.Sleep(12 * time.Second)
+		if i < len(missing)-1 {
+			time.Sleep(12 * time.Second)
+		}
 	}
 
-	log.Printf("[POLYGON] Prioritized bulk price update complete: %d updated, %d failed", updated, failed)
-	return nil
+	return result
+}
+
+// UpdateStaleSymbolPrices prices the symbols whose stored price is older than maxAge through
+// UpdateSymbolPricesGrouped. It returns false without doing anything when another stale update
+// is still running; the result is nil when every symbol was fresh.
+func (s *Service) UpdateStaleSymbolPrices(ctx context.Context, symbols []string, maxAge time.Duration) (*PriceUpdateResult, bool) {
+	if !s.staleUpdateMu.TryLock() {
+		return nil, false
+	}
+	defer s.staleUpdateMu.Unlock()
+
+	cutoff := time.Now().Add(-maxAge)
+	var stale []string
+	for _, symbol := range symbols {
+		current, err := s.symbolService.GetBySymbol(symbol)
+		if err != nil || current.Price <= 0 || current.UpdatedAt.Before(cutoff) {
+			stale = append(stale, symbol)
+		}
+	}
+	if len(stale) == 0 {
+		return nil, true
+	}
+
+	log.Printf("[POLYGON] Updating %d of %d symbols with prices older than %s", len(stale), len(symbols), maxAge)
+	return s.UpdateSymbolPricesGrouped(ctx, stale, false), true
+}
+
+// GetClosesOn returns each ticker's close for the trading session on or before date, from a single
+// grouped-daily request, along with the session date used
+func (s *Service) GetClosesOn(ctx context.Context, date time.Time) (map[string]float64, time.Time, error) {
+	client, err := s.getClient()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	session := models.PreviousTradingDay(date)
+	grouped, err := client.GetGroupedDaily(ctx, session)
+	if err != nil {
+		return nil, session, err
+	}
+	return grouped.Closes(), session, nil
+}
+
+// previousSessionDate returns the last trading day before today in the configured market timezone
+func (s *Service) previousSessionDate() time.Time {
+	year, month, day := time.Now().In(s.marketLocation()).Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return models.PreviousTradingDay(today.AddDate(0, 0, -1))
+}
+
+// marketLocation returns the configured market timezone, falling back to local time
+func (s *Service) marketLocation() *time.Location {
+	location, err := time.LoadLocation(s.settingService.GetValueWithDefault("MARKET_TIMEZONE", models.DefaultMarketTimezone))
+	if err != nil {
+		return time.Local
+	}
+	return location
 }
 
 // FetchSymbolDetails gets detailed information about a symbol from Polygon
@@ -234,6 +430,16 @@ type SymbolInfo struct {
 	Volume        float64 `json:"volume"`
 }
 
+// PriceUpdateResult summarizes a bulk price update
+type PriceUpdateResult struct {
+	Updated int      `json:"updated"`
+	Failed  int      `json:"failed"`
+	Grouped int      `json:"grouped"`          // symbols priced from the grouped-daily response
+	Skipped int      `json:"skipped"`          // symbols with a manual price
+	Recent  []string `json:"recent,omitempty"` // symbols skipped as updated within the minimum interval
+	Errors  []string `json:"errors,omitempty"`
+}
+
 // DividendInfo represents dividend information from Polygon
 type DividendInfo struct {
 	Symbol          string  `json:"symbol"`
@@ -267,6 +473,8 @@ type OptionGreeks struct {
 	ContractSymbol       string   `json:"contract_symbol,omitempty"`
 	ExpirationDisplay    string   `json:"expiration_display,omitempty"`
 	ExpirationUnixMillis int64    `json:"expiration_unix_millis,omitempty"`
+	ExerciseStyle        string   `json:"exercise_style,omitempty"`
+	SharesPerContract    float64  `json:"shares_per_contract,omitempty"`
 }
 
 // buildOptionContractSymbol formats a Polygon option contract string (e.g., O:SPY241220C00450000)
@@ -288,6 +496,57 @@ func buildOptionContractSymbol(option *models.Option) string {
 	return fmt.Sprintf("O:%s%s%s%s", strings.ToUpper(option.Symbol), datePart, typeCode, strikePart)
 }
 
+// OptionContractSymbol returns the Polygon contract string for an option (e.g., O:SPY241220C00450000)
+func OptionContractSymbol(option *models.Option) string {
+	return buildOptionContractSymbol(option)
+}
+
+// OptionContractParts are the components encoded in an OCC option contract symbol
+type OptionContractParts struct {
+	Underlying string
+	Type       string // "Put" or "Call"
+	Strike     float64
+	Expiration time.Time
+}
+
+// ParseOptionContractSymbol decodes an OCC contract symbol such as AAPL250117C00150000, the inverse
+// of buildOptionContractSymbol. A Polygon "O:" prefix and the OCC space padding after the root are
+// accepted.
+func ParseOptionContractSymbol(contract string) (*OptionContractParts, error) {
+	value := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(contract), " ", ""))
+	value = strings.TrimPrefix(value, "O:")
+	// Root, then YYMMDD, C or P, and the strike x 1000 as 8 digits
+	if len(value) < 16 {
+		return nil, fmt.Errorf("contract symbol %q is too short", contract)
+	}
+	root, datePart, typeCode, strikePart := value[:len(value)-15], value[len(value)-15:len(value)-9], value[len(value)-9], value[len(value)-8:]
+
+	expiration, err := time.Parse("060102", datePart)
+	if err != nil {
+		return nil, fmt.Errorf("contract symbol %q has an invalid expiration %q", contract, datePart)
+	}
+	var optionType string
+	switch typeCode {
+	case 'C':
+		optionType = "Call"
+	case 'P':
+		optionType = "Put"
+	default:
+		return nil, fmt.Errorf("contract symbol %q has an invalid type %q (want C or P)", contract, string(typeCode))
+	}
+	strikeThousandths, err := strconv.ParseInt(strikePart, 10, 64)
+	if err != nil || strikeThousandths <= 0 {
+		return nil, fmt.Errorf("contract symbol %q has an invalid strike %q", contract, strikePart)
+	}
+
+	return &OptionContractParts{
+		Underlying: root,
+		Type:       optionType,
+		Strike:     float64(strikeThousandths) / 1000,
+		Expiration: expiration,
+	}, nil
+}
+
 // computeRho approximates rho using Black-Scholes, falling back to nil if inputs are insufficient
 func computeRho(option *models.Option, underlyingPrice float64, impliedVol float64, riskFree float64) *float64 {
 	if option == nil || underlyingPrice <= 0 || impliedVol <= 0 {
@@ -319,6 +578,26 @@ func normCDF(x float64) float64 {
 	return 0.5 * (1 + math.Erf(x/math.Sqrt2))
 }
 
+// GetRawOptionSnapshot returns the unprocessed Polygon snapshot for an option along with the
+// contract symbol it was requested under, for comparing vendor Greeks against computed ones
+func (s *Service) GetRawOptionSnapshot(ctx context.Context, option *models.Option) (string, *OptionSnapshot, error) {
+	if option == nil {
+		return "", nil, fmt.Errorf("option is nil")
+	}
+
+	client, err := s.getClient()
+	if err != nil {
+		return "", nil, err
+	}
+
+	contractSymbol := buildOptionContractSymbol(option)
+	snapshot, err := client.GetOptionSnapshot(ctx, option.Symbol, contractSymbol)
+	if err != nil {
+		return contractSymbol, nil, err
+	}
+	return contractSymbol, snapshot, nil
+}
+
 // GetOptionGreeks fetches Greeks for a given option using Polygon snapshots
 func (s *Service) GetOptionGreeks(ctx context.Context, option *models.Option) (*OptionGreeks, error) {
 	if option == nil {
@@ -353,6 +632,8 @@ func (s *Service) GetOptionGreeks(ctx context.Context, option *models.Option) (*
 
 		greeks.UnderlyingPrice = &underlying
 		greeks.ImpliedVolatility = &iv
+		greeks.ExerciseStyle = snapshot.Results.Details.ExerciseStyle
+		greeks.SharesPerContract = snapshot.Results.Details.SharesPerContract
 
 		greeks.Delta = &snapshot.Results.Greeks.Delta
 		greeks.Gamma = &snapshot.Results.Greeks.Gamma
@@ -1,7 +1,11 @@
 package polygon
 
 import (
+	"context"
+	"errors"
+	"stonks/internal/database"
 	"stonks/internal/models"
+	"strings"
 	"testing"
 	"time"
 )
@@ -21,3 +25,194 @@ func TestBuildOptionContractSymbol(t *testing.T) {
 		t.Fatalf("expected %s, got %s", want, got)
 	}
 }
+
+func TestParseOptionContractSymbolRoundTrip(t *testing.T) {
+	options := []*models.Option{
+		{Symbol: "AAPL", Type: "Call", Strike: 150, Expiration: time.Date(2025, time.January, 17, 0, 0, 0, 0, time.UTC)},
+		{Symbol: "SPY", Type: "Put", Strike: 450.5, Expiration: time.Date(2024, time.December, 20, 0, 0, 0, 0, time.UTC)},
+		{Symbol: "F", Type: "Put", Strike: 7.125, Expiration: time.Date(2026, time.March, 6, 0, 0, 0, 0, time.UTC)},
+		{Symbol: "brkb", Type: "call", Strike: 12.5, Expiration: time.Date(2025, time.June, 20, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, opt := range options {
+		contract := buildOptionContractSymbol(opt)
+		parts, err := ParseOptionContractSymbol(contract)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", contract, err)
+		}
+		if parts.Underlying != strings.ToUpper(opt.Symbol) || !strings.EqualFold(parts.Type, opt.Type) ||
+			parts.Strike != opt.Strike || !parts.Expiration.Equal(opt.Expiration) {
+			t.Errorf("%s: expected %s %s %.3f %s, got %+v", contract, opt.Symbol, opt.Type, opt.Strike, opt.Expiration.Format("2006-01-02"), parts)
+		}
+	}
+}
+
+func TestParseOptionContractSymbol(t *testing.T) {
+	exp := time.Date(2025, time.January, 17, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		contract string
+		want     *OptionContractParts
+	}{
+		{"lowercase with Polygon prefix", "o:aapl250117c00150000", &OptionContractParts{Underlying: "AAPL", Type: "Call", Strike: 150, Expiration: exp}},
+		{"OCC space padding", " SPY   250117P00450500 ", &OptionContractParts{Underlying: "SPY", Type: "Put", Strike: 450.5, Expiration: exp}},
+		{"fractional strike", "F250117P00007125", &OptionContractParts{Underlying: "F", Type: "Put", Strike: 7.125, Expiration: exp}},
+		{"too short", "250117C00150000", nil},
+		{"invalid type", "AAPL250117X00150000", nil},
+		{"invalid expiration", "AAPL251317C00150000", nil},
+		{"zero strike", "AAPL250117C00000000", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOptionContractSymbol(tt.contract)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("expected an error for %q, got %+v", tt.contract, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", tt.contract, err)
+			}
+			if got.Underlying != tt.want.Underlying || got.Type != tt.want.Type || got.Strike != tt.want.Strike || !got.Expiration.Equal(tt.want.Expiration) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestApplySymbolPriceKeepsPriceOnZero(t *testing.T) {
+	db, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	symbolService := models.NewSymbolService(db.DB)
+	settingService := models.NewSettingService(db.DB)
+	service := NewService(symbolService, settingService)
+
+	if _, err := symbolService.Create("AAPL"); err != nil {
+		t.Fatalf("failed to create symbol: %v", err)
+	}
+	if _, err := symbolService.Update("AAPL", 187.5, 0, nil, nil); err != nil {
+		t.Fatalf("failed to set price: %v", err)
+	}
+
+	if err := service.applySymbolPrice("AAPL", 0); err == nil {
+		t.Fatalf("expected an error for a zero price")
+	}
+	symbol, err := symbolService.GetBySymbol("AAPL")
+	if err != nil {
+		t.Fatalf("failed to get symbol: %v", err)
+	}
+	if symbol.Price != 187.5 {
+		t.Fatalf("expected price 187.5 to be kept, got %.2f", symbol.Price)
+	}
+
+	// With the guard disabled the zero is stored as returned
+	if err := settingService.SetValue("PRESERVE_PRICE_ON_ZERO", "false", ""); err != nil {
+		t.Fatalf("failed to update setting: %v", err)
+	}
+	if err := service.applySymbolPrice("AAPL", 0); err != nil {
+		t.Fatalf("unexpected error with guard disabled: %v", err)
+	}
+	symbol, _ = symbolService.GetBySymbol("AAPL")
+	if symbol.Price != 0 {
+		t.Fatalf("expected price 0 with guard disabled, got %.2f", symbol.Price)
+	}
+}
+
+func TestBulkPriceUpdateSkipsManualPriceSymbols(t *testing.T) {
+	db, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	symbolService := models.NewSymbolService(db.DB)
+	settingService := models.NewSettingService(db.DB)
+	service := NewService(symbolService, settingService)
+
+	if _, err := symbolService.Create("PRIV"); err != nil {
+		t.Fatalf("failed to create symbol: %v", err)
+	}
+	if _, err := symbolService.Update("PRIV", 42.5, 0, nil, nil); err != nil {
+		t.Fatalf("failed to set price: %v", err)
+	}
+	if _, err := symbolService.SetManualPrice("PRIV", true); err != nil {
+		t.Fatalf("failed to flag manual price: %v", err)
+	}
+
+	// No API key is configured, so any attempt to price the symbol would fail rather than skip
+	result := service.UpdateSymbolPricesGrouped(context.Background(), []string{"PRIV"}, false)
+	if result.Skipped != 1 || result.Failed != 0 || result.Updated != 0 {
+		t.Fatalf("expected the manual symbol to be skipped, got %+v", result)
+	}
+	symbol, err := symbolService.GetBySymbol("PRIV")
+	if err != nil {
+		t.Fatalf("failed to get symbol: %v", err)
+	}
+	if symbol.Price != 42.5 || !symbol.ManualPrice {
+		t.Fatalf("expected manual price 42.50 to be kept, got %.2f (manual=%t)", symbol.Price, symbol.ManualPrice)
+	}
+}
+
+func TestPriceUpdateSkipsRecentlyUpdatedSymbols(t *testing.T) {
+	db, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	symbolService := models.NewSymbolService(db.DB)
+	settingService := models.NewSettingService(db.DB)
+	service := NewService(symbolService, settingService)
+
+	if _, err := symbolService.Create("KO"); err != nil {
+		t.Fatalf("failed to create symbol: %v", err)
+	}
+	if _, err := symbolService.Update("KO", 62.1, 0, nil, nil); err != nil {
+		t.Fatalf("failed to set price: %v", err)
+	}
+
+	// No API key is configured, so only a skipped update returns without a client error
+	if err := service.UpdateSymbolPrice(context.Background(), "KO", false); !errors.Is(err, ErrRecentlyUpdated) {
+		t.Fatalf("expected the fresh symbol to be skipped, got %v", err)
+	}
+	result := service.UpdateSymbolPricesGrouped(context.Background(), []string{"KO"}, false)
+	if len(result.Recent) != 1 || result.Recent[0] != "KO" || result.Failed != 0 || result.Updated != 0 {
+		t.Fatalf("expected KO to be reported as recent, got %+v", result)
+	}
+
+	if err := service.UpdateSymbolPrice(context.Background(), "KO", true); err == nil || errors.Is(err, ErrRecentlyUpdated) {
+		t.Fatalf("expected a forced update to reach Polygon, got %v", err)
+	}
+	if err := settingService.SetValue("MIN_PRICE_UPDATE_INTERVAL_MINUTES", "0", ""); err != nil {
+		t.Fatalf("failed to disable the interval: %v", err)
+	}
+	if err := service.UpdateSymbolPrice(context.Background(), "KO", false); err == nil || errors.Is(err, ErrRecentlyUpdated) {
+		t.Fatalf("expected no throttling with the interval disabled, got %v", err)
+	}
+}
+
+func TestRequestPacerSpacesEveryRequest(t *testing.T) {
+	pacer := &requestPacer{interval: 50 * time.Millisecond}
+	ctx := context.Background()
+
+	started := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := pacer.wait(ctx); err != nil {
+			t.Fatalf("wait %d failed: %v", i, err)
+		}
+	}
+	// The first request goes out at once; the next two each wait a full interval
+	if elapsed := time.Since(started); elapsed < 100*time.Millisecond {
+		t.Fatalf("expected 3 requests to take at least 100ms, took %v", elapsed)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := pacer.wait(canceled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled wait to return context.Canceled, got %v", err)
+	}
+}
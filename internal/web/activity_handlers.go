@@ -0,0 +1,292 @@
+package web
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Activity ledger event types, in the order same-day events are listed
+const (
+	ActivityTreasuryPurchased = "treasury_purchased"
+	ActivityStockBought       = "stock_bought"
+	ActivityOptionOpened      = "option_opened"
+	ActivityOptionClosed      = "option_closed"
+	ActivityStockSold         = "stock_sold"
+	ActivityDividendReceived  = "dividend_received"
+	ActivityTreasuryCoupon    = "treasury_coupon"
+	ActivityTreasuryMatured   = "treasury_matured"
+)
+
+var activityEventOrder = map[string]int{
+	ActivityTreasuryPurchased: 0,
+	ActivityStockBought:       1,
+	ActivityOptionOpened:      2,
+	ActivityOptionClosed:      3,
+	ActivityStockSold:         4,
+	ActivityDividendReceived:  5,
+	ActivityTreasuryCoupon:    6,
+	ActivityTreasuryMatured:   7,
+}
+
+// activityEvent is one row of the flat transaction ledger. Amount is the signed cash flow
+// before commission and fees (credits positive); the exported Net column subtracts both.
+type activityEvent struct {
+	Date        time.Time
+	Event       string
+	Symbol      string
+	Description string
+	Quantity    float64
+	Price       float64
+	Amount      float64
+	Commission  float64
+	Fees        float64
+	Account     string
+}
+
+// buildActivityLedger collects every portfolio event from options, long positions, dividends and
+// treasuries, sorted by date ascending. An option's commission and fees are each stored as one
+// total, so they are reported on the opening row.
+func (s *Server) buildActivityLedger() ([]activityEvent, error) {
+	var events []activityEvent
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get options: %w", err)
+	}
+	for _, option := range options {
+		contracts := float64(option.Contracts)
+		description := fmt.Sprintf("%s %s %.2f exp %s", option.Symbol, option.Type, option.Strike, option.Expiration.Format("2006-01-02"))
+		events = append(events, activityEvent{
+			Date:        option.Opened,
+			Event:       ActivityOptionOpened,
+			Symbol:      option.Symbol,
+			Description: "Sold to open " + description,
+			Quantity:    contracts,
+			Price:       option.Premium,
+			Amount:      option.Premium * contracts * 100,
+			Commission:  option.Commission,
+			Fees:        option.Fees,
+			Account:     option.Account,
+		})
+		if option.Closed != nil {
+			exitPrice := option.GetExitPriceValue()
+			events = append(events, activityEvent{
+				Date:        *option.Closed,
+				Event:       ActivityOptionClosed,
+				Symbol:      option.Symbol,
+				Description: fmt.Sprintf("Closed (%s) %s", option.Outcome(), description),
+				Quantity:    contracts,
+				Price:       exitPrice,
+				Amount:      -exitPrice * contracts * 100,
+				Account:     option.Account,
+			})
+		}
+	}
+
+	positions, err := s.longPositionService.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get long positions: %w", err)
+	}
+	if err := s.longPositionService.AttachExits(positions); err != nil {
+		return nil, fmt.Errorf("failed to get long position exits: %w", err)
+	}
+	for _, position := range positions {
+		events = append(events, activityEvent{
+			Date:        position.Opened,
+			Event:       ActivityStockBought,
+			Symbol:      position.Symbol,
+			Description: fmt.Sprintf("Bought %d %s", position.Shares, position.Symbol),
+			Quantity:    float64(position.Shares),
+			Price:       position.BuyPrice,
+			Amount:      -position.BuyPrice * float64(position.Shares),
+			Account:     position.Account,
+		})
+		for _, exit := range position.Exits {
+			events = append(events, activityEvent{
+				Date:        exit.Exited,
+				Event:       ActivityStockSold,
+				Symbol:      position.Symbol,
+				Description: fmt.Sprintf("Sold %d %s", exit.Shares, position.Symbol),
+				Quantity:    float64(exit.Shares),
+				Price:       exit.Price,
+				Amount:      exit.Price * float64(exit.Shares),
+				Account:     position.Account,
+			})
+		}
+		if len(position.Exits) == 0 && position.Closed != nil {
+			exitPrice := position.GetExitPriceValue()
+			events = append(events, activityEvent{
+				Date:        *position.Closed,
+				Event:       ActivityStockSold,
+				Symbol:      position.Symbol,
+				Description: fmt.Sprintf("Sold %d %s", position.Shares, position.Symbol),
+				Quantity:    float64(position.Shares),
+				Price:       exitPrice,
+				Amount:      exitPrice * float64(position.Shares),
+				Account:     position.Account,
+			})
+		}
+	}
+
+	dividends, err := s.dividendService.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dividends: %w", err)
+	}
+	for _, dividend := range dividends {
+		// A reinvested portion also appears as the stock_bought row of the lot it created
+		description := "Dividend " + dividend.Symbol
+		if dividend.ReinvestedAmount > 0 {
+			description += fmt.Sprintf(" ($%.2f reinvested)", dividend.ReinvestedAmount)
+		}
+		events = append(events, activityEvent{
+			Date:        dividend.Received,
+			Event:       ActivityDividendReceived,
+			Symbol:      dividend.Symbol,
+			Description: description,
+			Amount:      dividend.Amount,
+		})
+	}
+
+	treasuries, err := s.treasuryService.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get treasuries: %w", err)
+	}
+	today := time.Now().In(s.marketLocation())
+	for _, treasury := range treasuries {
+		events = append(events, activityEvent{
+			Date:        treasury.Purchased,
+			Event:       ActivityTreasuryPurchased,
+			Symbol:      treasury.CUSPID,
+			Description: fmt.Sprintf("Bought treasury %s, face $%.2f", treasury.CUSPID, treasury.Amount),
+			Quantity:    treasury.Amount,
+			Price:       treasury.BuyPrice,
+			Amount:      -treasury.BuyPrice,
+		})
+		// Redeemed at the recorded exit price, or at face value once maturity has passed
+		if treasury.ExitPrice != nil || !treasury.Maturity.After(today) {
+			proceeds := treasury.Amount
+			if treasury.ExitPrice != nil {
+				proceeds = *treasury.ExitPrice
+			}
+			events = append(events, activityEvent{
+				Date:        treasury.Maturity,
+				Event:       ActivityTreasuryMatured,
+				Symbol:      treasury.CUSPID,
+				Description: "Treasury matured " + treasury.CUSPID,
+				Quantity:    treasury.Amount,
+				Price:       proceeds,
+				Amount:      proceeds,
+			})
+		}
+	}
+
+	coupons, err := s.treasuryCouponService.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get treasury coupons: %w", err)
+	}
+	for _, coupon := range coupons {
+		events = append(events, activityEvent{
+			Date:        coupon.Received,
+			Event:       ActivityTreasuryCoupon,
+			Symbol:      coupon.CUSPID,
+			Description: "Coupon " + coupon.CUSPID,
+			Amount:      coupon.Amount,
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		di, dj := events[i].Date.Format("2006-01-02"), events[j].Date.Format("2006-01-02")
+		if di != dj {
+			return di < dj
+		}
+		return activityEventOrder[events[i].Event] < activityEventOrder[events[j].Event]
+	})
+	return events, nil
+}
+
+// HandleActivityExport downloads /export/activity.csv?from=&to=, a chronological ledger of every
+// portfolio event for accounting software. from and to are inclusive YYYY-MM-DD dates.
+func (s *Server) HandleActivityExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var from, to time.Time
+	if value := strings.TrimSpace(r.URL.Query().Get("from")); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			http.Error(w, "Invalid from date (use YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	if value := strings.TrimSpace(r.URL.Query().Get("to")); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			http.Error(w, "Invalid to date (use YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		http.Error(w, "to must not be before from", http.StatusBadRequest)
+		return
+	}
+
+	events, err := s.buildActivityLedger()
+	if err != nil {
+		log.Printf("[EXPORT] Error building activity ledger: %v", err)
+		http.Error(w, "Failed to build activity history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=activity.csv")
+
+	formatAmount := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', 2, 64)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Date", "Event", "Symbol", "Description", "Quantity", "Price", "Amount", "Commission", "Fees", "Net", "Account"})
+	exported := 0
+	for _, event := range events {
+		date := event.Date.Format("2006-01-02")
+		if (!from.IsZero() && date < from.Format("2006-01-02")) || (!to.IsZero() && date > to.Format("2006-01-02")) {
+			continue
+		}
+		quantity, price := "", ""
+		if event.Quantity != 0 {
+			quantity = strconv.FormatFloat(event.Quantity, 'f', -1, 64)
+			price = strconv.FormatFloat(event.Price, 'f', -1, 64)
+		}
+		writer.Write([]string{
+			date,
+			event.Event,
+			event.Symbol,
+			event.Description,
+			quantity,
+			price,
+			formatAmount(event.Amount),
+			formatAmount(event.Commission),
+			formatAmount(event.Fees),
+			formatAmount(event.Amount - event.Commission - event.Fees),
+			event.Account,
+		})
+		exported++
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("[EXPORT] Error writing activity CSV: %v", err)
+		return
+	}
+
+	log.Printf("[EXPORT] Exported %d activity events", exported)
+}
@@ -0,0 +1,223 @@
+package web
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"stonks/internal/models"
+	"strings"
+	"time"
+)
+
+// backupScheduleRecheck is how often the automatic backup task checks whether a backup is due
+const backupScheduleRecheck = time.Minute
+
+// backupDir holds manual and automatic backups
+const backupDir = "./data/backups"
+
+// backupTimestampLayout is the timestamp embedded in backup filenames
+const backupTimestampLayout = "2006-01-02-15-04-05"
+
+// autoBackupTag marks automatic backups (<db>.auto.<timestamp>.db) so retention only prunes those
+const autoBackupTag = "auto"
+
+// startBackupSchedule backs up the active database every BACKUP_INTERVAL_HOURS (0 disables it)
+// and keeps the newest BACKUP_RETENTION_COUNT automatic backups. The settings are re-read every
+// minute so changes apply without a restart.
+func (s *Server) startBackupSchedule() {
+	s.backupDone = make(chan struct{})
+	go s.runBackupSchedule(s.backupDone)
+	s.registerScheduledTask("automatic_backup", s.backupTaskStatus)
+	log.Printf("[BACKUP] Automatic backup task started")
+}
+
+// backupTaskStatus reports the automatic backup task for /api/scheduler/status. The next run is
+// due an interval after the newest backup, manual or automatic, and at the next check when overdue.
+func (s *Server) backupTaskStatus() SchedulerTaskStatus {
+	interval, enabled := backupInterval(s.settingService)
+	state := s.backupRun.current()
+
+	status := SchedulerTaskStatus{
+		Description: "Backs up the active database and prunes old automatic backups",
+		Enabled:     enabled,
+		Schedule:    "disabled",
+		Settings:    []string{"BACKUP_INTERVAL_HOURS", "BACKUP_RETENTION_COUNT"},
+		LastRunAt:   state.lastRunAt,
+		LastResult:  state.resultOrNeverRun(),
+		LastError:   state.lastError,
+	}
+	if state.result == TaskResultSuccess {
+		status.Detail = state.detail
+	}
+	if enabled {
+		status.Schedule = fmt.Sprintf("every %g hours", interval.Hours())
+		next := time.Now().Add(backupScheduleRecheck)
+		if latest, err := latestBackupTime(s.getCurrentDatabaseName()); err == nil && !latest.IsZero() && latest.Add(interval).After(next) {
+			next = latest.Add(interval)
+		}
+		status.NextRunAt = &next
+	}
+	return status
+}
+
+// stopBackupSchedule stops the automatic backup task
+func (s *Server) stopBackupSchedule() {
+	if s.backupDone != nil {
+		close(s.backupDone)
+		s.backupDone = nil
+	}
+}
+
+func (s *Server) runBackupSchedule(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(backupScheduleRecheck):
+		}
+
+		db, settings, release := s.activeDB()
+		s.backupIfDue(db, settings)
+		release()
+	}
+}
+
+// backupIfDue runs an automatic backup of db when the newest backup is older than the interval
+func (s *Server) backupIfDue(db *sql.DB, settings *models.SettingService) {
+	interval, ok := backupInterval(settings)
+	if !ok {
+		return
+	}
+	dbFileName := s.getCurrentDatabaseName()
+	latest, err := latestBackupTime(dbFileName)
+	if err != nil {
+		log.Printf("[BACKUP] ERROR: Failed to read backups for %s: %v", dbFileName, err)
+		return
+	}
+	if !latest.IsZero() && time.Since(latest) < interval {
+		return
+	}
+
+	s.runAutomaticBackup(db, settings, dbFileName)
+}
+
+// backupInterval returns the configured automatic backup interval, or false when disabled
+func backupInterval(settings *models.SettingService) (time.Duration, bool) {
+	hours := settings.GetFloatWithDefault("BACKUP_INTERVAL_HOURS", 0)
+	if hours <= 0 {
+		return 0, false
+	}
+	return time.Duration(hours * float64(time.Hour)), true
+}
+
+// runAutomaticBackup writes a consistent online copy of the active database with VACUUM INTO,
+// then prunes automatic backups beyond the retention count
+func (s *Server) runAutomaticBackup(db *sql.DB, settings *models.SettingService, dbFileName string) {
+	started := time.Now()
+	s.backupRun.begin(started, "")
+	baseName := strings.TrimSuffix(dbFileName, ".db")
+	backupFileName := fmt.Sprintf("%s.%s.%s.db", baseName, autoBackupTag, started.Format(backupTimestampLayout))
+
+	err := os.MkdirAll(backupDir, 0755)
+	if err == nil {
+		_, err = db.Exec("VACUUM INTO ?", filepath.Join(backupDir, backupFileName))
+	}
+
+	pruned := 0
+	if err == nil {
+		retain := int(settings.GetFloatWithDefault("BACKUP_RETENTION_COUNT", 7))
+		var pruneErr error
+		if pruned, pruneErr = pruneAutomaticBackups(dbFileName, retain); pruneErr != nil {
+			log.Printf("[BACKUP] Warning: Failed to apply backup retention: %v", pruneErr)
+		}
+	}
+
+	s.backupRun.finish(TaskResultSuccess, err, fmt.Sprintf("%s (%d old backup(s) removed)", backupFileName, pruned))
+	if err != nil {
+		log.Printf("[BACKUP] ERROR: Automatic backup of %s failed: %v", dbFileName, err)
+		return
+	}
+	log.Printf("[BACKUP] Automatic backup created: %s (%d old automatic backup(s) removed)", backupFileName, pruned)
+}
+
+// backupsOf lists the backups of a database in the backup directory, newest first. The boolean
+// reports whether each one is an automatic backup.
+func backupsOf(dbFileName string) ([]string, map[string]bool, error) {
+	files, err := os.ReadDir(backupDir)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	prefix := strings.TrimSuffix(dbFileName, ".db") + "."
+	var names []string
+	automatic := make(map[string]bool)
+	stamps := make(map[string]time.Time)
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".db") {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".db")
+		isAuto := strings.HasPrefix(stamp, autoBackupTag+".")
+		stamp = strings.TrimPrefix(stamp, autoBackupTag+".")
+		// Backups of another database sharing the prefix (e.g. wheeler.test) do not parse
+		created, err := time.Parse(backupTimestampLayout, stamp)
+		if err != nil {
+			continue
+		}
+		names = append(names, name)
+		automatic[name] = isAuto
+		stamps[name] = created
+	}
+	sort.Slice(names, func(i, j int) bool { return stamps[names[i]].After(stamps[names[j]]) })
+	return names, automatic, nil
+}
+
+// latestBackupTime returns when the newest backup of a database, manual or automatic, was written;
+// zero when there is none
+func latestBackupTime(dbFileName string) (time.Time, error) {
+	names, _, err := backupsOf(dbFileName)
+	if err != nil || len(names) == 0 {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(filepath.Join(backupDir, names[0]))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// pruneAutomaticBackups removes automatic backups of a database beyond the newest retain; manual
+// backups are never removed and retain <= 0 keeps everything
+func pruneAutomaticBackups(dbFileName string, retain int) (int, error) {
+	if retain <= 0 {
+		return 0, nil
+	}
+	names, automatic, err := backupsOf(dbFileName)
+	if err != nil {
+		return 0, err
+	}
+
+	kept, removed := 0, 0
+	for _, name := range names {
+		if !automatic[name] {
+			continue
+		}
+		if kept < retain {
+			kept++
+			continue
+		}
+		if err := os.Remove(filepath.Join(backupDir, name)); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+		log.Printf("[BACKUP] Removed old automatic backup: %s", name)
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,177 @@
+package web
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"stonks/internal/models"
+	"strings"
+)
+
+// Sources a configuration value can be resolved from
+const (
+	ConfigSourceDB      = "db"
+	ConfigSourceEnv     = "env"
+	ConfigSourceDefault = "default"
+)
+
+// configKey describes a known configuration value: the settings row it is read from, the
+// environment variable consulted when that row is empty, and the code default used otherwise
+type configKey struct {
+	Name     string
+	Env      string // environment fallback; empty when the value is never read from the environment
+	Default  string
+	Secret   bool
+	DBBacked bool // false for values that are only ever read from the environment
+}
+
+// configRegistry lists every configuration value the application reads. Resolution order is
+// db, then env, then default; keep defaults in sync with the settings seeded in schema.sql.
+var configRegistry = []configKey{
+	{Name: "POLYGON_API_KEY", Secret: true, DBBacked: true},
+	{Name: "API_TOKEN", Secret: true, DBBacked: true},
+	{Name: "AUTO_ENRICH_SYMBOLS", Default: "false", DBBacked: true},
+	{Name: "OPTION_RETURNS_BASIS", Default: "net", DBBacked: true},
+	{Name: "IMPORT_DEFAULT_COMMISSION_PER_CONTRACT", Default: "0.65", DBBacked: true},
+	{Name: "MARKET_TIMEZONE", Default: models.DefaultMarketTimezone, DBBacked: true},
+	{Name: "EXPIRING_MONEYNESS_BAND_PERCENT", Default: "2", DBBacked: true},
+	{Name: "ASSIGNMENT_BUY_PRICE_MODE", Default: models.AssignmentBuyPriceStrike, DBBacked: true},
+	{Name: "GREEKS_FETCH_CONCURRENCY", Default: "4", DBBacked: true},
+	{Name: "GREEKS_FETCH_TIMEOUT_SECONDS", Default: "20", DBBacked: true},
+	{Name: "NAV_ACTIVE_SYMBOLS_ONLY", Default: "false", DBBacked: true},
+	{Name: "NAV_SYMBOL_LOOKBACK_DAYS", Default: "90", DBBacked: true},
+	{Name: "IMPORT_NUMBER_LOCALE", Default: "en", DBBacked: true},
+	{Name: "WAL_CHECKPOINT_INTERVAL_MINUTES", Default: "30", DBBacked: true},
+	{Name: "INCLUDE_TREASURIES_IN_TOTAL", Default: "true", DBBacked: true},
+	{Name: "PERCENT_DECIMALS", Default: "2", DBBacked: true},
+	{Name: "MAX_UPLOAD_MB", Default: "10", DBBacked: true},
+	{Name: "PRESERVE_PRICE_ON_ZERO", Default: "true", DBBacked: true},
+	{Name: "ALLOW_PENDING_TRADES", Default: "false", DBBacked: true},
+	{Name: "OPTIONS_SUMMARY_SORT", Default: models.OptionsSummarySortSymbol, DBBacked: true},
+	{Name: "SNAP_NON_TRADING_EXPIRATIONS", Default: "false", DBBacked: true},
+	{Name: "DATABASE_SCOPE", Env: "DATABASE_SCOPE", Default: DatabaseScopeGlobal, DBBacked: true},
+	{Name: "BROKER_PROFILE", DBBacked: true},
+	{Name: "AUTO_UPDATE_PRICES_ON_DASHBOARD", Default: "false", DBBacked: true},
+	{Name: "PRICE_STALE_MINUTES", Default: "60", DBBacked: true},
+	{Name: "MIN_PRICE_UPDATE_INTERVAL_MINUTES", Default: "1", DBBacked: true},
+	{Name: "SPLIT_ROUNDING", Default: models.SplitRoundingCashInLieu, DBBacked: true},
+	{Name: "SNAPSHOT_SCHEDULE_TIME", DBBacked: true},
+	{Name: "SNAPSHOT_RETRY_ATTEMPTS", Default: "3", DBBacked: true},
+	{Name: "SNAPSHOT_RETRY_BACKOFF_SECONDS", Default: "5", DBBacked: true},
+	{Name: "RISK_FREE_RATE_PERCENT", Default: "5", DBBacked: true},
+	{Name: "ANNUALIZATION_BASIS", Default: models.AnnualizationCalendar, DBBacked: true},
+	{Name: "TREASURY_YIELD_INPUT_SCALE", Default: models.TreasuryYieldScaleAuto, DBBacked: true},
+	{Name: "BASIS_COMMISSION_MODE", Default: models.BasisCommissionExclude, DBBacked: true},
+	{Name: "BASIS_DEFER_OPEN_CALL_PREMIUM", Default: "false", DBBacked: true},
+	{Name: "PUT_CONCENTRATION_ALERT_PERCENT", Default: "25", DBBacked: true},
+	{Name: "BACKUP_INTERVAL_HOURS", Default: "0", DBBacked: true},
+	{Name: "BACKUP_RETENTION_COUNT", Default: "7", DBBacked: true},
+	{Name: "CALL_COVERAGE_TRACKING", Default: "false", DBBacked: true},
+	{Name: "IBKR_UNKNOWN_RIGHT_HANDLING", Default: IBKRUnknownRightSkip, DBBacked: true},
+	{Name: "IBKR_TWS_HOST", Env: "IBKR_TWS_HOST", Default: "127.0.0.1", DBBacked: true},
+	{Name: "IBKR_TWS_PORT", Env: "IBKR_TWS_PORT", Default: "7497", DBBacked: true},
+	{Name: "IBKR_CLIENT_ID", Env: "IBKR_CLIENT_ID", Default: "1", DBBacked: true},
+	{Name: "IBKR_SERVICE_URL", Env: "IBKR_SERVICE_URL", Default: "http://localhost:8081"},
+}
+
+// lookupConfigKey returns the registry entry for a name, or an env-less DB setting when unknown
+func lookupConfigKey(name string) configKey {
+	for _, key := range configRegistry {
+		if key.Name == name {
+			return key
+		}
+	}
+	return configKey{Name: name, DBBacked: true}
+}
+
+// resolveConfig returns the effective value of a configuration key and where it came from.
+// settingService may be nil for values resolved outside a request (env and default only).
+func resolveConfig(settingService *models.SettingService, key configKey) (string, string) {
+	if key.DBBacked && settingService != nil {
+		if value := strings.TrimSpace(settingService.GetValue(key.Name)); value != "" {
+			return value, ConfigSourceDB
+		}
+	}
+	if key.Env != "" {
+		if value := os.Getenv(key.Env); value != "" {
+			return value, ConfigSourceEnv
+		}
+	}
+	return key.Default, ConfigSourceDefault
+}
+
+// configValue returns the effective value of a named configuration key
+func (s *Server) configValue(name string) string {
+	value, _ := resolveConfig(s.settingService, lookupConfigKey(name))
+	return value
+}
+
+// maskSecret hides all but the first and last three characters of a secret value
+func maskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 8 {
+		return "***"
+	}
+	return value[:3] + "..." + value[len(value)-3:]
+}
+
+// effectiveConfigHandler reports every known setting with its resolved value and source
+func (s *Server) effectiveConfigHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAPIToken(w, r) {
+		return
+	}
+
+	entries := make([]EffectiveConfigEntry, 0, len(configRegistry))
+	known := make(map[string]bool, len(configRegistry))
+	for _, key := range configRegistry {
+		known[key.Name] = true
+		entries = append(entries, s.effectiveConfigEntry(key))
+	}
+
+	// Settings stored in the database but not read through the registry are still reported, masked
+	// like secrets since nothing says whether they hold credentials
+	settings, err := s.settingService.GetAll()
+	if err != nil {
+		log.Printf("[CONFIG API] Error loading settings: %v", err)
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+	var extra []EffectiveConfigEntry
+	for _, setting := range settings {
+		if known[setting.Name] {
+			continue
+		}
+		key := lookupConfigKey(setting.Name)
+		key.Secret = true
+		extra = append(extra, s.effectiveConfigEntry(key))
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i].Name < extra[j].Name })
+	entries = append(entries, extra...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EffectiveConfigResponse{Settings: entries})
+}
+
+func (s *Server) effectiveConfigEntry(key configKey) EffectiveConfigEntry {
+	value, source := resolveConfig(s.settingService, key)
+	entry := EffectiveConfigEntry{
+		Name:    key.Name,
+		Value:   value,
+		Source:  source,
+		Default: key.Default,
+		Env:     key.Env,
+		Secret:  key.Secret,
+	}
+	if key.Secret {
+		entry.Value = maskSecret(value)
+	}
+	return entry
+}
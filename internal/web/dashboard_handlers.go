@@ -1,15 +1,22 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 	"sort"
 	"stonks/internal/models"
+	"strconv"
+	"time"
 )
 
 // dashboardHandler serves the TraderVue-style dashboard
 func (s *Server) dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" {
+		s.autoUpdateStalePrices()
+	}
+
 	symbols, err := s.symbolService.GetDistinctSymbols()
 	if err != nil {
 		log.Printf("[DASHBOARD] Error getting symbols: %v", err)
@@ -42,6 +49,9 @@ func (s *Server) buildDashboardData(symbols []string) (DashboardData, error) {
 	// Get all data
 	options, _ := s.optionService.GetAll()
 	longPositions, _ := s.longPositionService.GetAll()
+	if err := s.longPositionService.AttachExits(longPositions); err != nil {
+		log.Printf("[DASHBOARD] Error getting long position exits: %v", err)
+	}
 	dividends, _ := s.dividendService.GetAll()
 	treasuries, _ := s.treasuryService.GetAll()
 
@@ -95,10 +105,8 @@ func (s *Server) buildSymbolSummaries(symbols []string, options []*models.Option
 			if pos.Closed == nil {
 				summary.LongAmount += pos.CalculateAmount()
 			}
-			// Only count realized gains from closed positions
-			if pos.ExitPrice != nil && pos.Closed != nil {
-				summary.CapGains += pos.CalculateProfitLoss(*pos.ExitPrice)
-			}
+			// Count realized gains from closed lots and partial exits
+			summary.CapGains += pos.CalculateRealizedProfitLoss()
 		}
 	}
 
@@ -132,7 +140,7 @@ func (s *Server) buildSymbolSummaries(symbols []string, options []*models.Option
 	for _, pos := range longPositions {
 		if summary, exists := summaryMap[pos.Symbol]; exists {
 			if pos.Closed == nil { // Only open positions
-				if !callCoverage[pos.Symbol] && pos.Shares >= 100 { // No call coverage and enough shares for options
+				if !callCoverage[pos.Symbol] && pos.RemainingShares() >= 100 { // No call coverage and enough shares for options
 					summary.Optionable += pos.CalculateAmount()
 				}
 			}
@@ -270,6 +278,11 @@ func (s *Server) calculateDashboardTotals(symbolSummaries []SymbolSummary, treas
 		totalTreasuries += treasury.Amount
 	}
 
+	grandTotal := totalLong + totalPuts
+	if s.includeTreasuriesInTotal() {
+		grandTotal += totalTreasuries
+	}
+
 	totalNet := totalPutPremiums + totalCallPremiums + totalCapGains + totalDividends
 	overallCashOnCash := 0.0
 	if totalLong > 0 {
@@ -300,7 +313,7 @@ func (s *Server) calculateDashboardTotals(symbolSummaries []SymbolSummary, treas
 		OverallCashOnCash: overallCashOnCash,
 		PutROI:            putROI,
 		LongROI:           longROI,
-		GrandTotal:        totalLong + totalPuts + totalTreasuries,
+		GrandTotal:        grandTotal,
 		TotalOptionable:   totalOptionable,
 	}
 }
@@ -363,6 +376,11 @@ func (s *Server) allocationDataHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to get long positions", http.StatusInternalServerError)
 		return
 	}
+	if err := s.longPositionService.AttachExits(longPositions); err != nil {
+		log.Printf("[ALLOCATION API] Error getting long position exits: %v", err)
+		http.Error(w, "Failed to get long position exits", http.StatusInternalServerError)
+		return
+	}
 
 	var totalLong float64
 	longByTicker := make(map[string]float64)
@@ -407,7 +425,7 @@ func (s *Server) allocationDataHandler(w http.ResponseWriter, r *http.Request) {
 			amount := pos.CalculateAmount()
 			if callCoverage[pos.Symbol] {
 				totalCallCovered += amount
-			} else if pos.Shares >= 100 { // Only count positions with enough shares for options
+			} else if pos.RemainingShares() >= 100 { // Only count positions with enough shares for options
 				totalOptionable += amount
 			}
 		}
@@ -472,6 +490,13 @@ func (s *Server) allocationDataHandler(w http.ResponseWriter, r *http.Request) {
 		longROI = (totalCallPremiums / totalLong) * 100
 	}
 
+	// Headline portfolio value: longs + treasuries, or longs alone when treasuries are excluded
+	includeTreasuries := s.includeTreasuriesInTotal()
+	totalValue := totalLong
+	if includeTreasuries {
+		totalValue += totalTreasuries
+	}
+
 	response := AllocationData{
 		LongByTicker:      longByTickerChart,
 		PutsByTicker:      putsByTickerChart,
@@ -483,6 +508,8 @@ func (s *Server) allocationDataHandler(w http.ResponseWriter, r *http.Request) {
 		TotalCallPremiums: totalCallPremiums,
 		TotalCallCovered:  totalCallCovered,
 		TotalOptionable:   totalOptionable,
+		TotalValue:        totalValue,
+		IncludeTreasuries: includeTreasuries,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -506,6 +533,11 @@ func (s *Server) optionablePositionsHandler(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "Failed to get long positions", http.StatusInternalServerError)
 		return
 	}
+	if err := s.longPositionService.AttachExits(longPositions); err != nil {
+		log.Printf("[OPTIONABLE API] Error getting long position exits: %v", err)
+		http.Error(w, "Failed to get long position exits", http.StatusInternalServerError)
+		return
+	}
 
 	// Get all open call options
 	options, err := s.optionService.GetAll()
@@ -538,7 +570,8 @@ func (s *Server) optionablePositionsHandler(w http.ResponseWriter, r *http.Reque
 
 	for _, pos := range longPositions {
 		if pos.Closed == nil { // Only open positions
-			if !callCoverage[pos.Symbol] && pos.Shares >= 100 { // No call coverage and enough shares for options
+			remaining := pos.RemainingShares()
+			if !callCoverage[pos.Symbol] && remaining >= 100 { // No call coverage and enough shares for options
 				amount := pos.CalculateAmount()
 				
 				// Get current price for value calculation
@@ -549,11 +582,11 @@ func (s *Server) optionablePositionsHandler(w http.ResponseWriter, r *http.Reque
 				
 				optionablePositions = append(optionablePositions, OptionablePosition{
 					Symbol:       pos.Symbol,
-					Shares:       pos.Shares,
+					Shares:       remaining,
 					Amount:       amount,
 					BuyPrice:     pos.BuyPrice,
 					Opened:       pos.Opened.Format("2006-01-02"),
-					CurrentValue: float64(pos.Shares) * currentPrice,
+					CurrentValue: float64(remaining) * currentPrice,
 				})
 				totalOptionableValue += amount
 			}
@@ -573,4 +606,253 @@ func (s *Server) optionablePositionsHandler(w http.ResponseWriter, r *http.Reque
 		log.Printf("[OPTIONABLE API] Error encoding response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
-}
\ No newline at end of file
+}
+
+// autoUpdateStalePrices starts a background price update for active-position symbols when
+// AUTO_UPDATE_PRICES_ON_DASHBOARD is on and their prices are older than PRICE_STALE_MINUTES.
+// It never blocks the page; an update that is already running is left to finish.
+func (s *Server) autoUpdateStalePrices() {
+	if !s.settingService.GetBoolWithDefault("AUTO_UPDATE_PRICES_ON_DASHBOARD", false) {
+		return
+	}
+	if s.settingService.GetValue("POLYGON_API_KEY") == "" {
+		return
+	}
+	minutes := s.settingService.GetFloatWithDefault("PRICE_STALE_MINUTES", 60)
+	if minutes <= 0 {
+		minutes = 60
+	}
+
+	polygonService := s.polygonService
+	symbolService := s.symbolService
+	go func() {
+		symbols, err := symbolService.GetActivePositionSymbols()
+		if err != nil {
+			log.Printf("[DASHBOARD] Auto price update: failed to get active symbols: %v", err)
+			return
+		}
+		if len(symbols) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		result, started := polygonService.UpdateStaleSymbolPrices(ctx, symbols, time.Duration(minutes*float64(time.Minute)))
+		if !started {
+			log.Printf("[DASHBOARD] Auto price update already in progress")
+			return
+		}
+		if result != nil {
+			log.Printf("[DASHBOARD] Auto price update complete: %d updated, %d failed", result.Updated, result.Failed)
+		}
+	}()
+}
+
+// Defaults for GET /api/dashboard
+const (
+	defaultDashboardListLimit    = 5
+	defaultDashboardActivityDays = 7
+	dashboardExDividendDays      = 60
+)
+
+// dashboardAPIHandler handles GET /api/dashboard?limit=&activity_days=, returning what the home
+// dashboard shows in one payload: the latest value of each metric, open-position counts, the
+// nearest open-option expirations, ex-dividend dates in the next 60 days for symbols with open
+// shares, recent activity and the active portfolio alerts. Only open positions, the latest metric
+// rows and rows dated inside the activity window are read. Recent activity is options opened or
+// closed, open lots bought and dividends received in the last activity_days (default 7); limit
+// caps the expiration and activity lists (default 5).
+func (s *Server) dashboardAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := defaultDashboardListLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		value, err := strconv.Atoi(limitStr)
+		if err != nil || value <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = value
+	}
+	activityDays := defaultDashboardActivityDays
+	if daysStr := r.URL.Query().Get("activity_days"); daysStr != "" {
+		value, err := strconv.Atoi(daysStr)
+		if err != nil || value <= 0 {
+			http.Error(w, "Invalid activity_days", http.StatusBadRequest)
+			return
+		}
+		activityDays = value
+	}
+
+	today := s.marketToday()
+	since := today.AddDate(0, 0, -activityDays)
+
+	latest, err := s.metricService.GetLatestSnapshot()
+	if err != nil {
+		log.Printf("[DASHBOARD API] Error getting latest metrics: %v", err)
+		http.Error(w, "Failed to get latest metrics", http.StatusInternalServerError)
+		return
+	}
+	options, err := s.optionService.GetOpen()
+	if err != nil {
+		log.Printf("[DASHBOARD API] Error getting open options: %v", err)
+		http.Error(w, "Failed to get open options", http.StatusInternalServerError)
+		return
+	}
+	positions, err := s.longPositionService.GetOpenPositions()
+	if err != nil {
+		log.Printf("[DASHBOARD API] Error getting open positions: %v", err)
+		http.Error(w, "Failed to get open positions", http.StatusInternalServerError)
+		return
+	}
+	if err := s.longPositionService.AttachExits(positions); err != nil {
+		log.Printf("[DASHBOARD API] Error getting long position exits: %v", err)
+		http.Error(w, "Failed to get long position exits", http.StatusInternalServerError)
+		return
+	}
+	activeSymbols, err := s.symbolService.GetActivePositionSymbols()
+	if err != nil {
+		log.Printf("[DASHBOARD API] Error getting active symbols: %v", err)
+		http.Error(w, "Failed to get active symbols", http.StatusInternalServerError)
+		return
+	}
+
+	response := DashboardAPIResponse{
+		Snapshot:            DashboardSnapshot{Metrics: map[string]float64{}},
+		NearestExpirations:  []DashboardExpiration{},
+		UpcomingExDividends: []UpcomingDividendDate{},
+		RecentActivity:      []DashboardActivity{},
+		ActivityDays:        activityDays,
+	}
+
+	var snapshotAt time.Time
+	for _, metric := range latest {
+		response.Snapshot.Metrics[string(metric.Type)] = metric.Value
+		if metric.Created.After(snapshotAt) {
+			snapshotAt = metric.Created
+		}
+	}
+	if !snapshotAt.IsZero() {
+		response.Snapshot.Date = snapshotAt.Format("2006-01-02")
+	}
+
+	response.Counts.ActiveSymbols = len(activeSymbols)
+	for _, option := range options {
+		if option.Type == "Put" {
+			response.Counts.OpenPuts++
+		} else {
+			response.Counts.OpenCalls++
+		}
+	}
+	// GetOpen returns options by expiration, soonest first
+	for _, option := range options {
+		if len(response.NearestExpirations) == limit {
+			break
+		}
+		response.NearestExpirations = append(response.NearestExpirations, DashboardExpiration{
+			ID:            option.ID,
+			Symbol:        option.Symbol,
+			Type:          option.Type,
+			Strike:        option.Strike,
+			Expiration:    option.Expiration.Format("2006-01-02"),
+			Contracts:     option.Contracts,
+			DaysRemaining: option.CalculateDaysRemaining(),
+		})
+	}
+
+	shares := make(map[string]int)
+	for _, position := range positions {
+		if remaining := position.RemainingShares(); remaining > 0 {
+			response.Counts.OpenLots++
+			response.Counts.SharesHeld += remaining
+			shares[position.Symbol] += remaining
+		}
+	}
+	symbols, err := s.symbolService.GetAll()
+	if err != nil {
+		log.Printf("[DASHBOARD API] Error getting symbols: %v", err)
+		http.Error(w, "Failed to get symbols", http.StatusInternalServerError)
+		return
+	}
+	symbolsByName := make(map[string]*models.Symbol, len(symbols))
+	for _, symbol := range symbols {
+		symbolsByName[symbol.Symbol] = symbol
+	}
+	for _, symbol := range activeSymbols {
+		if shares[symbol] == 0 {
+			continue
+		}
+		symbolData := symbolsByName[symbol]
+		if symbolData == nil || symbolData.ExDividendDate == nil {
+			continue
+		}
+		daysUntil := int(symbolData.ExDividendDate.Sub(today).Hours() / 24)
+		if daysUntil < 0 || daysUntil > dashboardExDividendDays {
+			continue
+		}
+		response.UpcomingExDividends = append(response.UpcomingExDividends, UpcomingDividendDate{
+			Symbol:         symbol,
+			ExDividendDate: *symbolData.ExDividendDate,
+			DaysUntil:      daysUntil,
+			Dividend:       symbolData.Dividend,
+			Shares:         shares[symbol],
+			ExpectedAmount: symbolData.Dividend * float64(shares[symbol]),
+		})
+	}
+	sort.SliceStable(response.UpcomingExDividends, func(i, j int) bool {
+		return response.UpcomingExDividends[i].DaysUntil < response.UpcomingExDividends[j].DaysUntil
+	})
+
+	activity, err := s.recentDashboardActivity(since, today)
+	if err != nil {
+		log.Printf("[DASHBOARD API] Error getting recent activity: %v", err)
+		http.Error(w, "Failed to get recent activity", http.StatusInternalServerError)
+		return
+	}
+	if len(activity) > limit {
+		activity = activity[:limit]
+	}
+	response.RecentActivity = append(response.RecentActivity, activity...)
+
+	response.Alerts = s.portfolioAlerts(options).Alerts
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[DASHBOARD API] Error encoding response: %v", err)
+	}
+}
+
+// recentDashboardActivity lists the activity ledger's events dated from since through today,
+// newest first. Events on the same day follow the activity ledger order.
+func (s *Server) recentDashboardActivity(since, today time.Time) ([]DashboardActivity, error) {
+	events, err := s.buildActivityLedger()
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := since.Format("2006-01-02"), today.Format("2006-01-02")
+	var activity []DashboardActivity
+	for _, event := range events {
+		date := event.Date.Format("2006-01-02")
+		if date < from || date > to {
+			continue
+		}
+		activity = append(activity, DashboardActivity{
+			Date:        date,
+			Event:       event.Event,
+			Symbol:      event.Symbol,
+			Description: event.Description,
+			Amount:      event.Amount,
+		})
+	}
+
+	// The ledger is oldest first; a stable sort keeps its order within each day
+	sort.SliceStable(activity, func(i, j int) bool {
+		return activity[i].Date > activity[j].Date
+	})
+	return activity, nil
+}
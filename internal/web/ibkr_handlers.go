@@ -9,10 +9,12 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"os"
+	"stonks/internal/models"
 	"stonks/internal/polygon"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // IBKRSettingsData holds data for the IBKR settings template
@@ -28,24 +30,14 @@ type IBKRSettingsData struct {
 
 // getIBKRServiceURL returns the URL of the IBKR microservice
 func getIBKRServiceURL() string {
-	url := os.Getenv("IBKR_SERVICE_URL")
-	if url == "" {
-		url = "http://localhost:8081"
-	}
+	url, _ := resolveConfig(nil, lookupConfigKey("IBKR_SERVICE_URL"))
 	return url
 }
 
-func ibkrEnvOrDefault(key, fallback string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
-	}
-	return fallback
-}
-
 func (s *Server) ibkrConnectionConfig() IBKRConnectionConfig {
-	host := s.settingService.GetValueWithDefault("IBKR_TWS_HOST", ibkrEnvOrDefault("IBKR_TWS_HOST", "127.0.0.1"))
-	portStr := s.settingService.GetValueWithDefault("IBKR_TWS_PORT", ibkrEnvOrDefault("IBKR_TWS_PORT", "7497"))
-	clientStr := s.settingService.GetValueWithDefault("IBKR_CLIENT_ID", ibkrEnvOrDefault("IBKR_CLIENT_ID", "1"))
+	host := s.configValue("IBKR_TWS_HOST")
+	portStr := s.configValue("IBKR_TWS_PORT")
+	clientStr := s.configValue("IBKR_CLIENT_ID")
 
 	port, err := strconv.Atoi(portStr)
 	if err != nil {
@@ -74,11 +66,7 @@ func (s *Server) ibkrSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[IBKR SETTINGS] Handling IBKR settings page request")
 
 	// Get all symbols for navigation
-	symbols, err := s.symbolService.GetDistinctSymbols()
-	if err != nil {
-		log.Printf("[IBKR SETTINGS] Error getting symbols: %v", err)
-		symbols = []string{}
-	}
+	symbols := s.getAllSymbolsList(r)
 
 	config := s.ibkrConnectionConfig()
 
@@ -295,20 +283,27 @@ func (s *Server) ibkrOwnedOptionsHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	payload := struct {
-		Options []OwnedOptionView `json:"options"`
-		Surface []VolSurfacePoint `json:"surface"`
-		Warning string            `json:"warning,omitempty"`
+		Options     []OwnedOptionView `json:"options"`
+		Surface     []VolSurfacePoint `json:"surface"`
+		Warning     string            `json:"warning,omitempty"`
+		IBKRSkipped []ibkrGreeksSkip  `json:"ibkr_skipped,omitempty"`
 	}{
 		Options: []OwnedOptionView{},
 		Surface: []VolSurfacePoint{},
 	}
 
-	ibkrGreeks, ibkrWarning := s.fetchIBKRGreeks(r.Context())
+	ibkrGreeks, ibkrSkipped, ibkrWarning := s.fetchIBKRGreeks(r.Context(), openOptions)
 	if ibkrWarning != "" {
 		payload.Warning = ibkrWarning
 	}
+	if len(ibkrSkipped) > 0 {
+		payload.IBKRSkipped = ibkrSkipped
+		payload.Warning = appendWarning(payload.Warning, fmt.Sprintf("IBKR Greeks skipped for %d option(s) with an unrecognized right/type", len(ibkrSkipped)))
+	}
 
-	for _, opt := range openOptions {
+	views := make([]OwnedOptionView, len(openOptions))
+	var needPolygon []int
+	for i, opt := range openOptions {
 		view := OwnedOptionView{
 			ID:         opt.ID,
 			Symbol:     opt.Symbol,
@@ -324,39 +319,51 @@ func (s *Server) ibkrOwnedOptionsHandler(w http.ResponseWriter, r *http.Request)
 			view.Greeks = g.Greeks
 			view.ImpliedVol = g.ImpliedVolatility
 			view.DataSource = "IBKR"
-			if sp := makeSurfacePoint(opt.Symbol, opt.Type, view.Expiration, opt.Expiration.UnixMilli(), opt.Strike, opt.Contracts, g.ImpliedVolatility); sp != nil {
-				view.SurfacePoint = sp
-				payload.Surface = append(payload.Surface, *sp)
-			}
+			view.SurfacePoint = makeSurfacePoint(opt.Symbol, opt.Type, view.Expiration, opt.Expiration.UnixMilli(), opt.Strike, opt.Contracts, g.ImpliedVolatility)
+		}
+		views[i] = view
+
+		// Polygon is only a fallback, so skip the call when IBKR already supplied everything
+		if view.Greeks == nil || view.SurfacePoint == nil {
+			needPolygon = append(needPolygon, i)
 		}
+	}
 
-		if s.polygonService != nil {
-			g, gErr := s.polygonService.GetOptionGreeks(r.Context(), opt)
-			if gErr != nil {
-				payload.Warning = appendWarning(payload.Warning, fmt.Sprintf("Greeks unavailable: %v", gErr))
+	if s.polygonService != nil && len(needPolygon) > 0 {
+		polygonGreeks, warnings := s.fetchPolygonGreeks(r.Context(), openOptions, needPolygon)
+		for _, warning := range warnings {
+			payload.Warning = appendWarning(payload.Warning, warning)
+		}
+
+		for _, i := range needPolygon {
+			g := polygonGreeks[i]
+			if g == nil {
+				continue
 			}
-			if g != nil {
-				// Prefer IBKR if available; otherwise use Polygon as fallback
-				if view.Greeks == nil {
-					view.Greeks = g
-					view.ImpliedVol = g.ImpliedVolatility
-					view.DataSource = "Polygon"
-				}
-				if view.SurfacePoint == nil {
-					if sp := makeSurfacePoint(opt.Symbol, opt.Type, view.Expiration, opt.Expiration.UnixMilli(), opt.Strike, opt.Contracts, g.ImpliedVolatility); sp != nil {
-						view.SurfacePoint = sp
-						payload.Surface = append(payload.Surface, *sp)
-					}
-				}
+			opt := openOptions[i]
+			view := &views[i]
+			// Prefer IBKR if available; otherwise use Polygon as fallback
+			if view.Greeks == nil {
+				view.Greeks = g
+				view.ImpliedVol = g.ImpliedVolatility
+				view.DataSource = "Polygon"
+			}
+			if view.SurfacePoint == nil {
+				view.SurfacePoint = makeSurfacePoint(opt.Symbol, opt.Type, view.Expiration, opt.Expiration.UnixMilli(), opt.Strike, opt.Contracts, g.ImpliedVolatility)
 			}
 		}
+	}
 
+	for _, view := range views {
 		if view.DataSource == "" && view.Greeks != nil {
 			view.DataSource = "Polygon"
 		}
 		if view.DataSource == "" {
 			view.DataSource = "Unavailable"
 		}
+		if view.SurfacePoint != nil {
+			payload.Surface = append(payload.Surface, *view.SurfacePoint)
+		}
 
 		payload.Options = append(payload.Options, view)
 	}
@@ -365,6 +372,73 @@ func (s *Server) ibkrOwnedOptionsHandler(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(payload)
 }
 
+// fetchPolygonGreeks fetches Polygon Greeks for the options at the given indices using a bounded
+// worker pool (GREEKS_FETCH_CONCURRENCY) and an overall deadline (GREEKS_FETCH_TIMEOUT_SECONDS)
+// derived from the request context. Results are keyed by index; failures become per-contract warnings.
+func (s *Server) fetchPolygonGreeks(ctx context.Context, options []*models.Option, indices []int) (map[int]*polygon.OptionGreeks, []string) {
+	workers := int(s.settingService.GetFloatWithDefault("GREEKS_FETCH_CONCURRENCY", 4))
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(indices) {
+		workers = len(indices)
+	}
+	timeout := time.Duration(s.settingService.GetFloatWithDefault("GREEKS_FETCH_TIMEOUT_SECONDS", 20) * float64(time.Second))
+	if timeout <= 0 {
+		timeout = 20 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	results := make(map[int]*polygon.OptionGreeks, len(indices))
+	warnings := make(map[int]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				opt := options[i]
+				g, err := s.polygonService.GetOptionGreeks(ctx, opt)
+				mu.Lock()
+				if err != nil {
+					warnings[i] = fmt.Sprintf("Greeks unavailable for %s %s %.2f %s: %v",
+						opt.Symbol, opt.Type, opt.Strike, opt.Expiration.Format("2006-01-02"), err)
+				}
+				if g != nil {
+					results[i] = g
+				}
+				mu.Unlock()
+				if g != nil {
+					s.saveContractDetails(opt.ID, g.ContractSymbol, g.ExerciseStyle, g.SharesPerContract)
+				}
+			}
+		}()
+	}
+
+	for _, i := range indices {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Report warnings in option order so the message is stable between requests
+	var ordered []string
+	for _, i := range indices {
+		if warning, ok := warnings[i]; ok {
+			ordered = append(ordered, warning)
+		}
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("[IBKR API] Polygon Greeks fetch hit %s timeout", timeout)
+	}
+	return results, ordered
+}
+
 type ibkrGreekOption struct {
 	Symbol            string                `json:"symbol"`
 	Right             string                `json:"right,omitempty"`
@@ -381,6 +455,88 @@ type ibkrGreekResponse struct {
 	Errors  []string          `json:"errors"`
 }
 
+// Handling of IBKR Greeks entries whose right/type cannot be read (IBKR_UNKNOWN_RIGHT_HANDLING)
+const (
+	// IBKRUnknownRightSkip drops the entry and reports it in the response
+	IBKRUnknownRightSkip = "skip"
+	// IBKRUnknownRightMatch assigns the entry to the one open option with the same symbol, strike
+	// and expiration, and skips it when none or both types match
+	IBKRUnknownRightMatch = "match"
+)
+
+// ibkrGreeksSkip is an IBKR Greeks entry that could not be matched to an option type
+type ibkrGreeksSkip struct {
+	Symbol     string  `json:"symbol"`
+	Strike     float64 `json:"strike"`
+	Expiration string  `json:"expiration"`
+	Right      string  `json:"right,omitempty"`
+	Type       string  `json:"type,omitempty"`
+	Reason     string  `json:"reason"`
+	conflict   bool    // type and right disagree, so the entry is never matched
+}
+
+// normalizeIBKRRight maps the right/type encodings the microservice may send (C, CALL, P, PUT in
+// any case) to Call or Put; ok is false for anything else
+func normalizeIBKRRight(value string) (string, bool) {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "C", "CALL":
+		return "Call", true
+	case "P", "PUT":
+		return "Put", true
+	}
+	return "", false
+}
+
+// ibkrOptionType resolves an entry's option type from its type and right fields. An empty reason
+// means the type was resolved; conflict is set when the two fields disagree.
+func ibkrOptionType(opt ibkrGreekOption) (optionType, reason string, conflict bool) {
+	fromType, typeOK := normalizeIBKRRight(opt.Type)
+	fromRight, rightOK := normalizeIBKRRight(opt.Right)
+	switch {
+	case typeOK && rightOK && fromType != fromRight:
+		return "", fmt.Sprintf("type %q conflicts with right %q", opt.Type, opt.Right), true
+	case typeOK:
+		return fromType, "", false
+	case rightOK:
+		return fromRight, "", false
+	case strings.TrimSpace(opt.Type) == "" && strings.TrimSpace(opt.Right) == "":
+		return "", "missing right and type", false
+	}
+	return "", fmt.Sprintf("unrecognized right %q / type %q", opt.Right, opt.Type), false
+}
+
+// matchIBKRSkips assigns skipped entries without a conflicting type to the single open option
+// sharing their symbol, strike and expiration, returning the entries that stay skipped
+func matchIBKRSkips(skipped []ibkrGreeksSkip, entries map[int]ibkrGreekOption, openOptions []*models.Option, result map[string]ibkrGreekOption) []ibkrGreeksSkip {
+	var remaining []ibkrGreeksSkip
+	for i, skip := range skipped {
+		if skip.conflict {
+			remaining = append(remaining, skip)
+			continue
+		}
+		var matchedType string
+		matches := 0
+		for _, optionType := range []string{"Call", "Put"} {
+			key := optionKey(skip.Symbol, optionType, skip.Strike, skip.Expiration)
+			for _, option := range openOptions {
+				if optionKey(option.Symbol, option.Type, option.Strike, option.Expiration.Format("2006-01-02")) == key {
+					matchedType = optionType
+					matches++
+					break
+				}
+			}
+		}
+		if matches != 1 {
+			skip.Reason += "; no unique open option to match"
+			remaining = append(remaining, skip)
+			continue
+		}
+		log.Printf("[IBKR API] Matched IBKR Greeks for %s %.2f %s to the open %s (%s)", skip.Symbol, skip.Strike, skip.Expiration, matchedType, skip.Reason)
+		result[optionKey(skip.Symbol, matchedType, skip.Strike, skip.Expiration)] = entries[i]
+	}
+	return remaining
+}
+
 func optionKey(symbol, optionType string, strike float64, expiration string) string {
 	return fmt.Sprintf("%s|%s|%.4f|%s", strings.ToUpper(symbol), strings.ToUpper(optionType), strike, expiration)
 }
@@ -409,7 +565,7 @@ func makeSurfacePoint(symbol, optionType, expiration string, expiryMs int64, str
 	}
 }
 
-func (s *Server) fetchIBKRGreeks(ctx context.Context) (map[string]ibkrGreekOption, string) {
+func (s *Server) fetchIBKRGreeks(ctx context.Context, openOptions []*models.Option) (map[string]ibkrGreekOption, []ibkrGreeksSkip, string) {
 	result := make(map[string]ibkrGreekOption)
 
 	config := s.ibkrConnectionConfig()
@@ -426,45 +582,57 @@ func (s *Server) fetchIBKRGreeks(ctx context.Context) (map[string]ibkrGreekOptio
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getIBKRServiceURL()+"/api/ibkr/greeks?"+query.Encode(), nil)
 	if err != nil {
-		return result, fmt.Sprintf("Failed to build IBKR Greeks request: %v", err)
+		return result, nil, fmt.Sprintf("Failed to build IBKR Greeks request: %v", err)
 	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return result, fmt.Sprintf("IBKR Greeks unavailable: %v", err)
+		return result, nil, fmt.Sprintf("IBKR Greeks unavailable: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return result, fmt.Sprintf("IBKR Greeks service responded with %d", resp.StatusCode)
+		return result, nil, fmt.Sprintf("IBKR Greeks service responded with %d", resp.StatusCode)
 	}
 
 	var payload ibkrGreekResponse
 	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return result, fmt.Sprintf("Failed to parse IBKR Greeks response: %v", err)
+		return result, nil, fmt.Sprintf("Failed to parse IBKR Greeks response: %v", err)
 	}
 
+	var skipped []ibkrGreeksSkip
+	skippedEntries := make(map[int]ibkrGreekOption)
 	for _, opt := range payload.Options {
-		optionType := opt.Type
-		if optionType == "" && opt.Right != "" {
-			if strings.ToUpper(opt.Right) == "C" {
-				optionType = "Call"
-			} else if strings.ToUpper(opt.Right) == "P" {
-				optionType = "Put"
-			}
-		}
-		if optionType == "" {
+		optionType, reason, conflict := ibkrOptionType(opt)
+		if reason != "" {
+			log.Printf("[IBKR API] Skipping IBKR Greeks for %s %.2f %s: %s", opt.Symbol, opt.Strike, opt.Expiration, reason)
+			skippedEntries[len(skipped)] = opt
+			skipped = append(skipped, ibkrGreeksSkip{
+				Symbol:     opt.Symbol,
+				Strike:     opt.Strike,
+				Expiration: opt.Expiration,
+				Right:      opt.Right,
+				Type:       opt.Type,
+				Reason:     reason,
+				conflict:   conflict,
+			})
 			continue
 		}
 		key := optionKey(opt.Symbol, optionType, opt.Strike, opt.Expiration)
 		result[key] = opt
 	}
+	if len(skipped) > 0 && s.configValue("IBKR_UNKNOWN_RIGHT_HANDLING") == IBKRUnknownRightMatch {
+		skipped = matchIBKRSkips(skipped, skippedEntries, openOptions, result)
+	}
+	if len(skipped) > 0 {
+		log.Printf("[IBKR API] Skipped IBKR Greeks for %d of %d option(s) with an unrecognized right/type", len(skipped), len(payload.Options))
+	}
 
 	if len(payload.Errors) > 0 {
-		return result, strings.Join(payload.Errors, "; ")
+		return result, skipped, strings.Join(payload.Errors, "; ")
 	}
 
-	return result, ""
+	return result, skipped, ""
 }
 
 // ibkrDisconnectHandler proxies disconnect request to IBKR microservice
@@ -3,15 +3,18 @@ package web
 import (
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
 	"stonks/internal/database"
 	"stonks/internal/models"
+	"stonks/internal/polygon"
 	"strconv"
 	"strings"
 	"time"
@@ -48,11 +51,7 @@ func (s *Server) HandleBackup(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[BACKUP] Rendering backup page")
 
 	// Get all symbols for navigation
-	symbols, err := s.symbolService.GetDistinctSymbols()
-	if err != nil {
-		log.Printf("[BACKUP] Error getting symbols: %v", err)
-		symbols = []string{}
-	}
+	symbols := s.getAllSymbolsList(r)
 
 	// Get current working directory and list .db files (active databases)
 	dbFiles, err := s.getAvailableDbFiles()
@@ -93,13 +92,13 @@ func (s *Server) HandleImportUpload(w http.ResponseWriter, r *http.Request) {
 	// Set response header for JSON
 	w.Header().Set("Content-Type", "application/json")
 
-	// Parse multipart form (10MB max)
-	err := r.ParseMultipartForm(10 * 1024 * 1024)
+	// Parse multipart form (MAX_UPLOAD_MB max)
+	err := s.parseUploadForm(w, r)
 	if err != nil {
 		log.Printf("[IMPORT] Error parsing multipart form: %v", err)
 		response := ImportResponse{
 			Success: false,
-			Error:   "Failed to parse upload form",
+			Error:   uploadFormErrorMessage(err, "Failed to parse upload form"),
 		}
 		json.NewEncoder(w).Encode(response)
 		return
@@ -131,8 +130,17 @@ func (s *Server) HandleImportUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// strict=true keeps the original exact 10-column format requirement; otherwise columns are matched by header name
+	strict := r.FormValue("strict") == "true"
+
+	// updateExisting=true applies closes from the file to matching open options instead of skipping them
+	updateExisting := r.FormValue("updateExisting") == "true"
+
+	// matchAll=true applies an update to every stored option sharing the row's key instead of failing
+	matchAll := r.FormValue("matchAll") == "true"
+
 	// Parse CSV and import options
-	importedCount, skippedCount, err := s.importOptionsFromCSV(file)
+	importedCount, updatedCount, skippedCount, err := s.importOptionsFromCSV(file, strict, updateExisting, matchAll)
 	if err != nil {
 		log.Printf("[IMPORT] Error importing options: %v", err)
 		response := ImportResponse{
@@ -144,15 +152,73 @@ func (s *Server) HandleImportUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("[IMPORT] Import completed: %d imported, %d skipped", importedCount, skippedCount)
+	log.Printf("[IMPORT] Import completed: %d imported, %d updated, %d skipped", importedCount, updatedCount, skippedCount)
 	response := ImportResponse{
 		Success:       true,
 		ImportedCount: importedCount,
+		UpdatedCount:  updatedCount,
 		SkippedCount:  skippedCount,
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
+// HandleOptionActivityImportUpload processes a broker activity CSV of options, routing assignment
+// and exercise rows through the assignment flow so the resulting stock lots are opened or closed
+func (s *Server) HandleOptionActivityImportUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Printf("[ACTIVITY_IMPORT] Starting option activity CSV import")
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := s.parseUploadForm(w, r); err != nil {
+		log.Printf("[ACTIVITY_IMPORT] Error parsing multipart form: %v", err)
+		json.NewEncoder(w).Encode(ImportResponse{
+			Success: false,
+			Error:   uploadFormErrorMessage(err, "Failed to parse form data"),
+			Details: err.Error(),
+		})
+		return
+	}
+
+	file, _, err := r.FormFile("csvFile")
+	if err != nil {
+		log.Printf("[ACTIVITY_IMPORT] Error getting form file: %v", err)
+		json.NewEncoder(w).Encode(ImportResponse{
+			Success: false,
+			Error:   "No file provided or error reading file",
+			Details: err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	updateExisting := r.FormValue("updateExisting") == "true"
+	matchAll := r.FormValue("matchAll") == "true"
+
+	importedCount, updatedCount, skippedCount, assignedCount, err := s.importOptionActivityFromCSV(file, updateExisting, matchAll)
+	if err != nil {
+		log.Printf("[ACTIVITY_IMPORT] Import failed: %v", err)
+		json.NewEncoder(w).Encode(ImportResponse{
+			Success: false,
+			Error:   "Failed to import option activity from CSV",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	log.Printf("[ACTIVITY_IMPORT] Import completed: %d imported, %d updated, %d skipped, %d assigned", importedCount, updatedCount, skippedCount, assignedCount)
+	json.NewEncoder(w).Encode(ImportResponse{
+		Success:       true,
+		ImportedCount: importedCount,
+		UpdatedCount:  updatedCount,
+		SkippedCount:  skippedCount,
+		AssignedCount: assignedCount,
+	})
+}
+
 // HandleStocksImportUpload processes the stocks CSV file upload and imports long positions
 func (s *Server) HandleStocksImportUpload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -162,12 +228,12 @@ func (s *Server) HandleStocksImportUpload(w http.ResponseWriter, r *http.Request
 
 	log.Printf("[STOCKS_IMPORT] Starting stocks CSV import")
 
-	// Parse multipart form (10MB max)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	// Parse multipart form (MAX_UPLOAD_MB max)
+	if err := s.parseUploadForm(w, r); err != nil {
 		log.Printf("[STOCKS_IMPORT] Error parsing multipart form: %v", err)
 		response := ImportResponse{
 			Success: false,
-			Error:   "Failed to parse form data",
+			Error:   uploadFormErrorMessage(err, "Failed to parse form data"),
 			Details: err.Error(),
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -223,12 +289,12 @@ func (s *Server) HandleDividendsImportUpload(w http.ResponseWriter, r *http.Requ
 
 	log.Printf("[DIVIDENDS_IMPORT] Starting dividends CSV import")
 
-	// Parse multipart form (10MB max)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	// Parse multipart form (MAX_UPLOAD_MB max)
+	if err := s.parseUploadForm(w, r); err != nil {
 		log.Printf("[DIVIDENDS_IMPORT] Error parsing multipart form: %v", err)
 		response := ImportResponse{
 			Success: false,
-			Error:   "Failed to parse form data",
+			Error:   uploadFormErrorMessage(err, "Failed to parse form data"),
 			Details: err.Error(),
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -284,12 +350,12 @@ func (s *Server) HandleTreasuriesImportUpload(w http.ResponseWriter, r *http.Req
 
 	log.Printf("[TREASURIES_IMPORT] Starting treasuries CSV import")
 
-	// Parse multipart form (10MB max)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	// Parse multipart form (MAX_UPLOAD_MB max)
+	if err := s.parseUploadForm(w, r); err != nil {
 		log.Printf("[TREASURIES_IMPORT] Error parsing multipart form: %v", err)
 		response := ImportResponse{
 			Success: false,
-			Error:   "Failed to parse form data",
+			Error:   uploadFormErrorMessage(err, "Failed to parse form data"),
 			Details: err.Error(),
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -336,33 +402,111 @@ func (s *Server) HandleTreasuriesImportUpload(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(response)
 }
 
-// importOptionsFromCSV parses the CSV file and imports options
-func (s *Server) importOptionsFromCSV(file io.Reader) (importedCount int, skippedCount int, err error) {
+// HandleGreeksImportUpload processes a historical options-activity CSV and backfills greeks_snapshots
+func (s *Server) HandleGreeksImportUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Printf("[GREEKS_IMPORT] Starting Greeks history CSV import")
+
+	// Parse multipart form (MAX_UPLOAD_MB max)
+	if err := s.parseUploadForm(w, r); err != nil {
+		log.Printf("[GREEKS_IMPORT] Error parsing multipart form: %v", err)
+		response := ImportResponse{
+			Success: false,
+			Error:   uploadFormErrorMessage(err, "Failed to parse form data"),
+			Details: err.Error(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	file, _, err := r.FormFile("csvFile")
+	if err != nil {
+		log.Printf("[GREEKS_IMPORT] Error getting form file: %v", err)
+		response := ImportResponse{
+			Success: false,
+			Error:   "No file provided or error reading file",
+			Details: err.Error(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	defer file.Close()
+
+	importedCount, skippedCount, err := s.importGreeksFromCSV(file)
+	if err != nil {
+		log.Printf("[GREEKS_IMPORT] Import failed: %v", err)
+		response := ImportResponse{
+			Success: false,
+			Error:   "Failed to import Greeks history from CSV",
+			Details: err.Error(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	log.Printf("[GREEKS_IMPORT] Import completed: %d imported, %d skipped", importedCount, skippedCount)
+	response := ImportResponse{
+		Success:       true,
+		ImportedCount: importedCount,
+		SkippedCount:  skippedCount,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// importOptionsFromCSV parses the CSV file and imports options.
+// Unless strict is set, columns are located by header name in any order and unrecognized columns
+// are ignored, so broker files with extra or reordered columns import as long as the nine required
+// headers are present. Without a commission (or total_commission) column, commission defaults to the
+// BROKER_PROFILE schedule, or IMPORT_DEFAULT_COMMISSION_PER_CONTRACT for each side traded without a
+// profile; optional 'account' and 'strategy' columns tag each trade and a 'status' column of
+// 'canceled' keeps a row for the record only. An optional 'fees' column records exchange and
+// regulatory fees apart from commission, defaulting to 0. Strict mode requires the exact
+// 10-column order, optionally followed by a fees column. With updateExisting, a row matching an open option
+// that the CSV shows as closed applies the close and exit price instead of being skipped; a row
+// matching several stored options fails unless matchAll applies the close to each of them.
+func (s *Server) importOptionsFromCSV(file io.Reader, strict, updateExisting, matchAll bool) (importedCount int, updatedCount int, skippedCount int, err error) {
 	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = 10 // Expect exactly 10 fields
+	reader.FieldsPerRecord = 0 // Every row must match the header's column count
 
 	// Read header row
 	headers, err := reader.Read()
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to read CSV headers: %w", err)
+		return 0, 0, 0, fmt.Errorf("failed to read CSV headers: %w", err)
 	}
 
-	// Validate headers (accept both 'commission' and 'total_commission' for backward compatibility)
-	expectedHeaders := []string{"symbol", "opened", "closed", "type", "strike", "expiration", "premium", "contracts", "exit_price", "commission"}
-	if len(headers) != len(expectedHeaders) {
-		return 0, 0, fmt.Errorf("CSV must have exactly %d columns, got %d", len(expectedHeaders), len(headers))
-	}
-
-	for i, expected := range expectedHeaders {
-		header := strings.TrimSpace(strings.ToLower(headers[i]))
-		// Accept both 'commission' and 'total_commission' for the last column
-		if i == 9 && (header == "commission" || header == "total_commission") {
-			continue
-		}
-		if header != expected {
-			return 0, 0, fmt.Errorf("column %d should be '%s', got '%s'", i+1, expected, headers[i])
+	var columns map[string]int
+	if strict {
+		expected := optionCSVColumns
+		if len(headers) == len(optionCSVColumnsWithFees) {
+			expected = optionCSVColumnsWithFees
 		}
+		columns, err = strictOptionColumns(headers, expected)
+	} else {
+		columns, err = mapOptionColumns(headers, optionCSVRequiredColumns, "commission", "fees", "account", "strategy", "contract", "status")
 	}
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	_, hasCommission := columns["commission"]
+	if !hasCommission {
+		log.Printf("[IMPORT] CSV has no commission column, using default commission per contract")
+	}
+	accountColumn, hasAccount := columns["account"]
+	strategyColumn, hasStrategy := columns["strategy"]
+	statusColumn, hasStatus := columns["status"]
+	_, hasFees := columns["fees"]
+
+	defaultCommissionPerContract := s.settingService.GetFloatWithDefault("IMPORT_DEFAULT_COMMISSION_PER_CONTRACT", models.OptionCommissionPerContract)
+	profile, hasProfile := s.brokerProfile()
 
 	log.Printf("[IMPORT] CSV headers validated successfully")
 
@@ -374,81 +518,567 @@ func (s *Server) importOptionsFromCSV(file io.Reader) (importedCount int, skippe
 			break
 		}
 		if err != nil {
-			return importedCount, skippedCount, fmt.Errorf("error reading row %d: %w", rowNumber+1, err)
+			return importedCount, updatedCount, skippedCount, fmt.Errorf("error reading row %d: %w", rowNumber+1, err)
 		}
 		rowNumber++
 
 		// Parse and validate the record
-		csvRecord := CSVOptionRecord{
-			Symbol:     strings.TrimSpace(strings.ToUpper(record[0])),
-			Opened:     strings.TrimSpace(record[1]),
-			Closed:     strings.TrimSpace(record[2]),
-			Type:       strings.TrimSpace(record[3]),
-			Strike:     strings.TrimSpace(record[4]),
-			Expiration: strings.TrimSpace(record[5]),
-			Premium:    strings.TrimSpace(record[6]),
-			Contracts:  strings.TrimSpace(record[7]),
-			ExitPrice:  strings.TrimSpace(record[8]),
-			Commission: strings.TrimSpace(record[9]),
-		}
+		csvRecord := optionRecordFromColumns(record, columns)
 
 		// Convert to Option struct
 		option, err := s.convertCSVRecordToOption(csvRecord, rowNumber)
 		if err != nil {
-			return importedCount, skippedCount, fmt.Errorf("error processing row %d: %w", rowNumber, err)
+			return importedCount, updatedCount, skippedCount, fmt.Errorf("error processing row %d: %w", rowNumber, err)
+		}
+		if !hasCommission {
+			// Opening side, plus the closing side for positions that were closed
+			if hasProfile {
+				option.Commission = profile.OpeningCommission(option.Contracts)
+				if option.Closed != nil {
+					option.Commission += profile.ClosingCommission(option.Contracts)
+				}
+			} else {
+				sides := 1.0
+				if option.Closed != nil {
+					sides = 2
+				}
+				option.Commission = defaultCommissionPerContract * float64(option.Contracts) * sides
+			}
 		}
 
 		// Ensure symbol exists (create if it doesn't)
 		err = s.ensureSymbolExists(option.Symbol)
 		if err != nil {
-			return importedCount, skippedCount, fmt.Errorf("error ensuring symbol exists for row %d: %w", rowNumber, err)
+			return importedCount, updatedCount, skippedCount, fmt.Errorf("error ensuring symbol exists for row %d: %w", rowNumber, err)
+		}
+
+		var account, strategy *string
+		var fees *float64
+		if hasAccount {
+			account = &record[accountColumn]
+		}
+		if hasStrategy {
+			strategy = &record[strategyColumn]
+		}
+		if hasFees {
+			fees = &option.Fees
+		}
+		if hasStatus {
+			if option.Status, err = models.NormalizeOptionStatus(record[statusColumn]); err != nil {
+				return importedCount, updatedCount, skippedCount, fmt.Errorf("error processing row %d: %w", rowNumber, err)
+			}
+		}
+		outcome, _, err := s.importOptionRow(option, account, strategy, fees, updateExisting, matchAll, rowNumber)
+		if err != nil {
+			return importedCount, updatedCount, skippedCount, err
+		}
+		switch outcome {
+		case importRowUpdated:
+			updatedCount++
+			continue
+		case importRowSkipped:
+			skippedCount++
+			continue
+		}
+
+		importedCount++
+		if importedCount%10 == 0 {
+			log.Printf("[IMPORT] Progress: %d options imported so far", importedCount)
+		}
+	}
+
+	return importedCount, updatedCount, skippedCount, nil
+}
+
+// optionCSVColumns is the canonical options CSV layout, in the order strict mode requires
+var optionCSVColumns = []string{"symbol", "opened", "closed", "type", "strike", "expiration", "premium", "contracts", "exit_price", "commission"}
+
+// optionCSVColumnsWithFees is the strict layout with the optional trailing fees column
+var optionCSVColumnsWithFees = append(optionCSVColumns[:10:10], "fees")
+
+// optionCSVRequiredColumns are the options CSV columns every file must contain
+var optionCSVRequiredColumns = optionCSVColumns[:9:9]
+
+// optionContractFields are the columns an OCC contract symbol encodes
+var optionContractFields = map[string]bool{"symbol": true, "type": true, "strike": true, "expiration": true}
+
+// canonicalOptionHeader normalizes a CSV header for matching: lower case, spaces as underscores,
+// total_commission as commission, regulatory and exchange fee aliases as fees and OCC symbol
+// aliases as contract
+func canonicalOptionHeader(header string) string {
+	header = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(header)), " ", "_")
+	switch header {
+	case "total_commission":
+		return "commission"
+	case "total_fees", "reg_fees", "regulatory_fees", "exchange_fees":
+		return "fees"
+	case "contract_symbol", "occ_symbol", "option_symbol", "occ":
+		return "contract"
+	}
+	return header
+}
+
+// mapOptionColumns locates each required and optional column by header name, returning its index.
+// Headers outside both lists are ignored; a missing required header or a repeated known header is
+// an error. When contract is an optional column and present, symbol, type, strike and expiration
+// become optional.
+func mapOptionColumns(headers, required []string, optional ...string) (map[string]int, error) {
+	known := make(map[string]bool, len(required)+len(optional))
+	for _, name := range append(append([]string{}, required...), optional...) {
+		known[name] = true
+	}
+
+	columns := make(map[string]int)
+	var ignored []string
+	for i, header := range headers {
+		name := canonicalOptionHeader(header)
+		if !known[name] {
+			ignored = append(ignored, header)
+			continue
+		}
+		if previous, ok := columns[name]; ok {
+			return nil, fmt.Errorf("columns %d and %d are both '%s'", previous+1, i+1, name)
+		}
+		columns[name] = i
+	}
+
+	// A contract column stands in for the fields an OCC symbol encodes
+	_, hasContract := columns["contract"]
+	var missing []string
+	needsContract := false
+	for _, name := range required {
+		if _, ok := columns[name]; ok {
+			continue
+		}
+		if optionContractFields[name] {
+			if hasContract {
+				continue
+			}
+			needsContract = true
+		}
+		missing = append(missing, name)
+	}
+	if len(missing) > 0 {
+		if needsContract && known["contract"] {
+			return nil, fmt.Errorf("CSV is missing required column(s): %s (symbol, type, strike and expiration may be replaced by a contract column)", strings.Join(missing, ", "))
+		}
+		return nil, fmt.Errorf("CSV is missing required column(s): %s", strings.Join(missing, ", "))
+	}
+	if len(ignored) > 0 {
+		log.Printf("[IMPORT] Ignoring unrecognized CSV column(s): %s", strings.Join(ignored, ", "))
+	}
+	return columns, nil
+}
+
+// strictOptionColumns requires headers to be exactly the expected columns in order, accepting
+// total_commission for commission
+func strictOptionColumns(headers, expected []string) (map[string]int, error) {
+	if len(headers) != len(expected) {
+		return nil, fmt.Errorf("CSV must have exactly %d columns, got %d", len(expected), len(headers))
+	}
+	columns := make(map[string]int, len(expected))
+	for i, name := range expected {
+		if canonicalOptionHeader(headers[i]) != name {
+			return nil, fmt.Errorf("column %d should be '%s', got '%s'", i+1, name, headers[i])
+		}
+		columns[name] = i
+	}
+	return columns, nil
+}
+
+// optionRecordFromColumns reads the canonical option fields from a CSV row; commission is "0"
+// when the file has no commission column and fees are blank when it has no fees column
+func optionRecordFromColumns(record []string, columns map[string]int) CSVOptionRecord {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	csvRecord := CSVOptionRecord{
+		Symbol:     strings.ToUpper(field("symbol")),
+		Opened:     field("opened"),
+		Closed:     field("closed"),
+		Type:       field("type"),
+		Strike:     field("strike"),
+		Expiration: field("expiration"),
+		Premium:    field("premium"),
+		Contracts:  field("contracts"),
+		ExitPrice:  field("exit_price"),
+		Commission: field("commission"),
+		Fees:       field("fees"),
+		Contract:   field("contract"),
+	}
+	if _, ok := columns["commission"]; !ok {
+		csvRecord.Commission = "0"
+	}
+	return csvRecord
+}
+
+// Option activity event types recognized in the event column
+const (
+	optionEventTrade      = "trade"
+	optionEventAssignment = "assignment"
+)
+
+// classifyOptionEvent maps a broker event label to an event type. Assignment and exercise labels
+// are assignments; anything else, including blank or unrecognized labels, is an ordinary trade.
+func classifyOptionEvent(raw string) string {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "assigned", "assignment", "assign", "exercised", "exercise", "exercised/assigned", "assigned/exercised":
+		return optionEventAssignment
+	default:
+		return optionEventTrade
+	}
+}
+
+// importOptionActivityFromCSV imports a broker activity export in the options format with a
+// required commission and 'event' column and an optional 'account' column, located by header name
+// like the options importer. Rows whose event is an
+// assignment or exercise and that carry a closed date are assigned on that date: puts open a lot
+// through AssignPut (ASSIGNMENT_BUY_PRICE_MODE applies) and calls close shares through CallAway.
+// Other rows, and assignment rows without a closed date, are imported as ordinary opens and closes.
+func (s *Server) importOptionActivityFromCSV(file io.Reader, updateExisting, matchAll bool) (importedCount, updatedCount, skippedCount, assignedCount int, err error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 0 // Every row must match the header's column count
+
+	headers, err := reader.Read()
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("failed to read CSV headers: %w", err)
+	}
+
+	columns, err := mapOptionColumns(headers, append(append([]string{}, optionCSVRequiredColumns...), "commission", "event"), "fees", "account", "contract")
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	accountColumn, hasAccount := columns["account"]
+	eventColumn := columns["event"]
+	_, hasFees := columns["fees"]
+
+	assignMode := s.settingService.GetValueWithDefault("ASSIGNMENT_BUY_PRICE_MODE", models.AssignmentBuyPriceStrike)
+
+	rowNumber := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return importedCount, updatedCount, skippedCount, assignedCount, fmt.Errorf("error reading row %d: %w", rowNumber+1, err)
+		}
+		rowNumber++
+
+		csvRecord := optionRecordFromColumns(record, columns)
+		option, err := s.convertCSVRecordToOption(csvRecord, rowNumber)
+		if err != nil {
+			return importedCount, updatedCount, skippedCount, assignedCount, fmt.Errorf("error processing row %d: %w", rowNumber, err)
+		}
+		if err := s.ensureSymbolExists(option.Symbol); err != nil {
+			return importedCount, updatedCount, skippedCount, assignedCount, fmt.Errorf("error ensuring symbol exists for row %d: %w", rowNumber, err)
+		}
+
+		var account *string
+		var fees *float64
+		if hasAccount {
+			account = &record[accountColumn]
+		}
+		if hasFees {
+			fees = &option.Fees
+		}
+
+		event := classifyOptionEvent(record[eventColumn])
+		if event == optionEventAssignment && option.Closed == nil {
+			log.Printf("[ACTIVITY_IMPORT] Row %d is marked %q without a closed date, importing as an ordinary trade", rowNumber, record[eventColumn])
+			event = optionEventTrade
+		}
+		if event == optionEventTrade {
+			outcome, _, err := s.importOptionRow(option, account, nil, fees, updateExisting, matchAll, rowNumber)
+			if err != nil {
+				return importedCount, updatedCount, skippedCount, assignedCount, err
+			}
+			switch outcome {
+			case importRowImported:
+				importedCount++
+			case importRowUpdated:
+				updatedCount++
+			case importRowSkipped:
+				skippedCount++
+			}
+			continue
 		}
 
-		// Try to create the option (skip if duplicate) - use CreateWithCommission to set custom commission
-		_, err = s.optionService.CreateWithCommission(option.Symbol, option.Type, option.Opened, option.Strike, option.Expiration, option.Premium, option.Contracts, option.Commission)
+		// Assignments record the option open, then close it through the assignment flow
+		assignedOn := *option.Closed
+		option.Closed, option.ExitPrice = nil, nil
+		outcome, stored, err := s.importOptionRow(option, account, nil, fees, false, false, rowNumber)
 		if err != nil {
-			if strings.Contains(err.Error(), "UNIQUE constraint failed") || strings.Contains(err.Error(), "duplicate") {
-				log.Printf("[IMPORT] Skipping duplicate option at row %d: %s %s %v", rowNumber, option.Symbol, option.Type, option.Opened)
+			return importedCount, updatedCount, skippedCount, assignedCount, err
+		}
+		if outcome == importRowImported {
+			importedCount++
+		} else {
+			// The option was imported before; assign it only if it is the single, still-open match
+			matches := s.findImportedOptions(option)
+			if len(matches) > 1 {
+				return importedCount, updatedCount, skippedCount, assignedCount, fmt.Errorf("assignment at row %d matches %d stored options (ids %v); remove duplicates first", rowNumber, len(matches), optionIDs(matches))
+			}
+			if len(matches) == 0 || matches[0].Closed != nil {
 				skippedCount++
 				continue
 			}
-			return importedCount, skippedCount, fmt.Errorf("error creating option at row %d: %w", rowNumber, err)
+			stored = matches[0]
 		}
 
-		// If the option was closed, update it with exit information
-		if option.Closed != nil {
-			// We need to get the created option to update it
-			options, err := s.optionService.GetBySymbol(option.Symbol)
-			if err == nil {
-				// Find the option we just created (match by key fields)
-				for _, opt := range options {
-					if opt.Symbol == option.Symbol && opt.Type == option.Type &&
-						opt.Opened.Equal(option.Opened) && opt.Strike == option.Strike &&
-						opt.Expiration.Equal(option.Expiration) && opt.Premium == option.Premium &&
-						opt.Contracts == option.Contracts {
-						_, updateErr := s.optionService.UpdateByID(opt.ID, opt.Symbol, opt.Type, opt.Opened, opt.Strike, opt.Expiration, opt.Premium, opt.Contracts, opt.Commission, option.Closed, option.ExitPrice)
-						if updateErr != nil {
-							log.Printf("[IMPORT] Warning: Failed to update option exit info for row %d: %v", rowNumber, updateErr)
+		if stored.Type == "Put" {
+			position, err := s.longPositionService.AssignPut(stored.ID, assignedOn, assignMode)
+			if err != nil {
+				return importedCount, updatedCount, skippedCount, assignedCount, fmt.Errorf("error assigning put at row %d: %w", rowNumber, err)
+			}
+			log.Printf("[ACTIVITY_IMPORT] Row %d: assigned put %d into long position %d", rowNumber, stored.ID, position.ID)
+		} else {
+			exits, err := s.longPositionService.CallAway(stored.ID, assignedOn)
+			if err != nil {
+				return importedCount, updatedCount, skippedCount, assignedCount, fmt.Errorf("error assigning call at row %d: %w", rowNumber, err)
+			}
+			log.Printf("[ACTIVITY_IMPORT] Row %d: call %d called away shares from %d lot(s)", rowNumber, stored.ID, len(exits))
+		}
+		assignedCount++
+	}
+
+	return importedCount, updatedCount, skippedCount, assignedCount, nil
+}
+
+// Outcomes of importing a single option row
+const (
+	importRowImported = iota
+	importRowUpdated
+	importRowSkipped
+)
+
+// importOptionRow creates an imported option, setting its account, strategy and fees when given and
+// applying its close, and returns the created option. Fees are given whenever the file has a fees
+// column, so a 0 there is written too. A duplicate of a stored option is skipped, or
+// with updateExisting has the row's close applied when the stored option is still open. When the
+// row's key matches several stored options the close fails unless matchAll applies it to each.
+func (s *Server) importOptionRow(option *models.Option, account, strategy *string, fees *float64, updateExisting, matchAll bool, rowNumber int) (int, *models.Option, error) {
+	option.Account = models.DefaultAccount
+	if account != nil {
+		option.Account = models.NormalizeAccount(*account)
+	}
+
+	// Try to create the option in its account (skip if duplicate) with the row's commission
+	created, err := s.optionService.CreateInAccount(option.Symbol, option.Type, option.Opened, option.Strike, option.Expiration, option.Premium, option.Contracts, option.Commission, option.Account)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") || strings.Contains(err.Error(), "duplicate") {
+			if updateExisting && option.Closed != nil {
+				matches := s.findImportedOptions(option)
+				if len(matches) > 1 {
+					if !matchAll {
+						return 0, nil, fmt.Errorf("row %d matches %d stored options (ids %v); remove duplicates or set matchAll", rowNumber, len(matches), optionIDs(matches))
+					}
+					log.Printf("[IMPORT] WARNING: Row %d matches %d stored options (ids %v), applying close to each open one", rowNumber, len(matches), optionIDs(matches))
+				}
+				updated := 0
+				for _, existing := range matches {
+					if existing.Closed != nil || existing.IsCanceled() {
+						continue
+					}
+					if _, err := s.optionService.UpdateByID(existing.ID, existing.Symbol, existing.Type, existing.Opened, existing.Strike, existing.Expiration, existing.Premium, existing.Contracts, option.Commission, option.Closed, option.ExitPrice); err != nil {
+						return 0, nil, fmt.Errorf("error applying close to existing option at row %d: %w", rowNumber, err)
+					}
+					if fees != nil {
+						if err := s.optionService.SetFees(existing.ID, *fees); err != nil {
+							return 0, nil, fmt.Errorf("error setting fees on existing option at row %d: %w", rowNumber, err)
 						}
-						break
 					}
+					log.Printf("[IMPORT] Applied close to existing option %d at row %d: %s %s %v", existing.ID, rowNumber, option.Symbol, option.Type, option.Opened)
+					updated++
+				}
+				if updated > 0 {
+					return importRowUpdated, nil, nil
 				}
 			}
+			log.Printf("[IMPORT] Skipping duplicate option at row %d: %s %s %v", rowNumber, option.Symbol, option.Type, option.Opened)
+			return importRowSkipped, nil, nil
 		}
+		return 0, nil, fmt.Errorf("error creating option at row %d: %w", rowNumber, err)
+	}
 
-		importedCount++
-		if importedCount%10 == 0 {
-			log.Printf("[IMPORT] Progress: %d options imported so far", importedCount)
+	if strategy != nil {
+		if err := s.optionService.SetStrategy(created.ID, *strategy); err != nil {
+			return 0, nil, fmt.Errorf("error setting strategy at row %d: %w", rowNumber, err)
+		}
+	}
+	if option.Status == models.OptionStatusCanceled {
+		if err := s.optionService.SetStatus(created.ID, option.Status); err != nil {
+			return 0, nil, fmt.Errorf("error setting status at row %d: %w", rowNumber, err)
+		}
+	}
+	if fees != nil {
+		if err := s.optionService.SetFees(created.ID, *fees); err != nil {
+			return 0, nil, fmt.Errorf("error setting fees at row %d: %w", rowNumber, err)
 		}
 	}
 
-	return importedCount, skippedCount, nil
+	// If the option was closed, update it with exit information
+	if option.Closed != nil {
+		_, updateErr := s.optionService.UpdateByID(created.ID, created.Symbol, created.Type, created.Opened, created.Strike, created.Expiration, created.Premium, created.Contracts, created.Commission, option.Closed, option.ExitPrice)
+		if updateErr != nil {
+			log.Printf("[IMPORT] Warning: Failed to update option exit info for row %d: %v", rowNumber, updateErr)
+		}
+	}
+
+	return importRowImported, created, nil
+}
+
+// findImportedOptions returns every stored option matching an imported row on the compound key
+// fields within the row's account, DefaultAccount when the row has none. The key is meant to be
+// unique but older databases can hold duplicates.
+func (s *Server) findImportedOptions(option *models.Option) []*models.Option {
+	options, err := s.optionService.GetBySymbolWithCanceled(option.Symbol)
+	if err != nil {
+		return nil
+	}
+	account := models.NormalizeAccount(option.Account)
+	var matches []*models.Option
+	for _, opt := range options {
+		if opt.Symbol == option.Symbol && opt.Type == option.Type &&
+			opt.Opened.Equal(option.Opened) && opt.Strike == option.Strike &&
+			opt.Expiration.Equal(option.Expiration) && opt.Premium == option.Premium &&
+			opt.Contracts == option.Contracts && opt.Account == account {
+			matches = append(matches, opt)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].ID < matches[j].ID })
+	return matches
+}
+
+// optionIDs lists the IDs of options for log and error messages
+func optionIDs(options []*models.Option) []int {
+	ids := make([]int, len(options))
+	for i, option := range options {
+		ids[i] = option.ID
+	}
+	return ids
+}
+
+// HandleOptionsExport downloads all options in the import CSV format, including the account column,
+// so the file can be re-imported as-is. ?account= limits the export to one account.
+func (s *Server) HandleOptionsExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	options, err := s.optionService.GetAllWithCanceled()
+	if err != nil {
+		log.Printf("[EXPORT] Error getting options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+	account := strings.TrimSpace(r.URL.Query().Get("account"))
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=options.csv")
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"symbol", "opened", "closed", "type", "strike", "expiration", "premium", "contracts", "exit_price", "commission", "fees", "account", "status"})
+	exported := 0
+	for _, option := range options {
+		if account != "" && option.Account != account {
+			continue
+		}
+		closed, exitPrice := "", ""
+		if option.Closed != nil {
+			closed = option.Closed.Format("2006-01-02")
+		}
+		if option.ExitPrice != nil {
+			exitPrice = strconv.FormatFloat(*option.ExitPrice, 'f', -1, 64)
+		}
+		writer.Write([]string{
+			option.Symbol,
+			option.Opened.Format("2006-01-02"),
+			closed,
+			option.Type,
+			strconv.FormatFloat(option.Strike, 'f', -1, 64),
+			option.Expiration.Format("2006-01-02"),
+			strconv.FormatFloat(option.Premium, 'f', -1, 64),
+			strconv.Itoa(option.Contracts),
+			exitPrice,
+			strconv.FormatFloat(option.Commission, 'f', -1, 64),
+			strconv.FormatFloat(option.Fees, 'f', -1, 64),
+			option.Account,
+			option.Status,
+		})
+		exported++
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("[EXPORT] Error writing options CSV: %v", err)
+		return
+	}
+
+	log.Printf("[EXPORT] Exported %d options", exported)
 }
 
-// importStocksFromCSV parses the CSV file and imports stock positions
+// HandleStocksExport downloads all long positions in the stocks import CSV format plus an account column.
+// ?account= limits the export to one account.
+func (s *Server) HandleStocksExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	positions, err := s.longPositionService.GetAll()
+	if err != nil {
+		log.Printf("[EXPORT] Error getting long positions: %v", err)
+		http.Error(w, "Failed to get long positions", http.StatusInternalServerError)
+		return
+	}
+	account := strings.TrimSpace(r.URL.Query().Get("account"))
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=stocks.csv")
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Symbol", "Purchased", "Closed Date", "Shares (x100)", "Buy Price", "Exit Price", "Account"})
+	exported := 0
+	for _, position := range positions {
+		if account != "" && position.Account != account {
+			continue
+		}
+		closed, exitPrice := "", ""
+		if position.Closed != nil {
+			closed = position.Closed.Format("1/2/2006")
+		}
+		if position.ExitPrice != nil {
+			exitPrice = strconv.FormatFloat(*position.ExitPrice, 'f', -1, 64)
+		}
+		writer.Write([]string{
+			position.Symbol,
+			position.Opened.Format("1/2/2006"),
+			closed,
+			strconv.FormatFloat(float64(position.Shares)/100, 'f', -1, 64),
+			strconv.FormatFloat(position.BuyPrice, 'f', -1, 64),
+			exitPrice,
+			position.Account,
+		})
+		exported++
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("[EXPORT] Error writing stocks CSV: %v", err)
+		return
+	}
+
+	log.Printf("[EXPORT] Exported %d long positions", exported)
+}
+
+// importStocksFromCSV parses the CSV file and imports stock positions.
+// An optional 7th 'account' column tags each position with a broker account.
 func (s *Server) importStocksFromCSV(file io.Reader) (importedCount int, skippedCount int, err error) {
 	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = 6 // Expect exactly 6 fields
+	reader.FieldsPerRecord = 0 // 6 fields, or 7 with account; every row must match the header
 
 	records, err := reader.ReadAll()
 	if err != nil {
@@ -464,12 +1094,18 @@ func (s *Server) importStocksFromCSV(file io.Reader) (importedCount int, skipped
 		return 0, 0, fmt.Errorf("CSV file must contain data rows beyond the header")
 	}
 
+	hasAccount := len(records[0]) == 7 && strings.EqualFold(strings.TrimSpace(records[0][6]), "account")
+	expectedColumns := 6
+	if hasAccount {
+		expectedColumns = 7
+	}
+
 	log.Printf("[STOCKS_IMPORT] Processing %d stock records", len(records)-1)
 
 	for i, record := range records[1:] { // Skip header row
-		if len(record) != 6 {
-			log.Printf("[STOCKS_IMPORT] Row %d: Invalid column count (expected 6, got %d)", i+2, len(record))
-			return importedCount, skippedCount, fmt.Errorf("row %d: expected 6 columns, got %d", i+2, len(record))
+		if len(record) != expectedColumns {
+			log.Printf("[STOCKS_IMPORT] Row %d: Invalid column count (expected %d, got %d)", i+2, expectedColumns, len(record))
+			return importedCount, skippedCount, fmt.Errorf("row %d: expected %d columns, got %d", i+2, expectedColumns, len(record))
 		}
 
 		csvRecord := CSVStockRecord{
@@ -495,7 +1131,7 @@ func (s *Server) importStocksFromCSV(file io.Reader) (importedCount int, skipped
 		}
 
 		// Create long position
-		_, err = s.longPositionService.Create(
+		created, err := s.longPositionService.Create(
 			position.Symbol,
 			position.Opened,
 			position.Shares,
@@ -512,6 +1148,12 @@ func (s *Server) importStocksFromCSV(file io.Reader) (importedCount int, skipped
 			return importedCount, skippedCount, fmt.Errorf("row %d: failed to create position: %w", i+2, err)
 		}
 
+		if hasAccount {
+			if err := s.longPositionService.SetAccount(created.ID, record[6]); err != nil {
+				return importedCount, skippedCount, fmt.Errorf("row %d: failed to set account: %w", i+2, err)
+			}
+		}
+
 		// If position was closed, update with exit data
 		if position.Closed != nil && position.ExitPrice != nil {
 			// We need to find the position we just created and update it
@@ -605,7 +1247,9 @@ func (s *Server) importDividendsFromCSV(file io.Reader) (importedCount int, skip
 // importTreasuriesFromCSV parses the CSV file and imports treasury records
 func (s *Server) importTreasuriesFromCSV(file io.Reader) (importedCount int, skippedCount int, err error) {
 	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = 8 // Expect exactly 8 fields: CUSPID, Purchased, Maturity, Amount, Yield, BuyPrice, CurrentValue, ExitPrice
+	// Expect 8 fields: CUSPID, Purchased, Maturity, Amount, Yield, BuyPrice, CurrentValue, ExitPrice,
+	// optionally followed by CouponRate and CouponFrequency for notes and bonds
+	reader.FieldsPerRecord = -1
 
 	records, err := reader.ReadAll()
 	if err != nil {
@@ -621,12 +1265,17 @@ func (s *Server) importTreasuriesFromCSV(file io.Reader) (importedCount int, ski
 		return 0, 0, fmt.Errorf("CSV file must contain data rows beyond the header")
 	}
 
+	columns := len(records[0])
+	if columns != 8 && columns != 10 {
+		return 0, 0, fmt.Errorf("expected 8 columns (or 10 with coupon_rate, coupon_frequency), got %d", columns)
+	}
+
 	log.Printf("[TREASURIES_IMPORT] Processing %d treasury records", len(records)-1)
 
 	for i, record := range records[1:] { // Skip header row
-		if len(record) != 8 {
-			log.Printf("[TREASURIES_IMPORT] Row %d: Invalid column count (expected 8, got %d)", i+2, len(record))
-			return importedCount, skippedCount, fmt.Errorf("row %d: expected 8 columns, got %d", i+2, len(record))
+		if len(record) != columns {
+			log.Printf("[TREASURIES_IMPORT] Row %d: Invalid column count (expected %d, got %d)", i+2, columns, len(record))
+			return importedCount, skippedCount, fmt.Errorf("row %d: expected %d columns, got %d", i+2, columns, len(record))
 		}
 
 		csvRecord := CSVTreasuryRecord{
@@ -639,6 +1288,10 @@ func (s *Server) importTreasuriesFromCSV(file io.Reader) (importedCount int, ski
 			CurrentValue: strings.TrimSpace(record[6]),
 			ExitPrice:    strings.TrimSpace(record[7]),
 		}
+		if columns == 10 {
+			csvRecord.CouponRate = strings.TrimSpace(record[8])
+			csvRecord.CouponFrequency = strings.TrimSpace(record[9])
+		}
 
 		treasury, created, err := s.processTreasuryRecord(csvRecord, i+2)
 		if err != nil {
@@ -660,8 +1313,128 @@ func (s *Server) importTreasuriesFromCSV(file io.Reader) (importedCount int, ski
 	return importedCount, skippedCount, nil
 }
 
+// importGreeksFromCSV loads date,contract,delta,gamma,theta,vega,iv rows into greeks_snapshots.
+// Contracts are Polygon option symbols (the O: prefix is optional) and must match an owned option
+// that was open on the row's date; other rows, and dates already captured, are skipped.
+func (s *Server) importGreeksFromCSV(file io.Reader) (importedCount int, skippedCount int, err error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 7 // Date, Contract, Delta, Gamma, Theta, Vega, IV
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	if len(records) <= 1 {
+		return 0, 0, fmt.Errorf("CSV file must contain data rows beyond the header")
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load options: %w", err)
+	}
+	byContract := make(map[string][]*models.Option)
+	for _, option := range options {
+		contract := polygon.OptionContractSymbol(option)
+		byContract[contract] = append(byContract[contract], option)
+	}
+
+	locale := s.importNumberLocale()
+	log.Printf("[GREEKS_IMPORT] Processing %d Greeks records against %d owned contracts", len(records)-1, len(byContract))
+
+	for i, record := range records[1:] { // Skip header row
+		rowNum := i + 2
+
+		captured, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+		if err != nil {
+			return importedCount, skippedCount, fmt.Errorf("row %d: invalid date %q, expected YYYY-MM-DD", rowNum, record[0])
+		}
+
+		contract := strings.ToUpper(strings.TrimSpace(record[1]))
+		if !strings.HasPrefix(contract, "O:") {
+			contract = "O:" + contract
+		}
+
+		option := ownedOptionOnDate(byContract[contract], captured)
+		if option == nil {
+			log.Printf("[GREEKS_IMPORT] Row %d: Skipped %s on %s, no owned option open on that date", rowNum, contract, captured.Format("2006-01-02"))
+			skippedCount++
+			continue
+		}
+
+		values := make([]*float64, 5)
+		for j, raw := range record[2:] {
+			if strings.TrimSpace(raw) == "" {
+				continue
+			}
+			value, err := parseMoney(raw, locale)
+			if err != nil {
+				return importedCount, skippedCount, fmt.Errorf("row %d: invalid value in column %d: %w", rowNum, j+3, err)
+			}
+			values[j] = &value
+		}
+
+		created, err := s.greeksSnapshotService.CreateIfNotExists(&models.GreeksSnapshot{
+			OptionID:          option.ID,
+			Captured:          captured,
+			Contract:          contract,
+			Delta:             values[0],
+			Gamma:             values[1],
+			Theta:             values[2],
+			Vega:              values[3],
+			ImpliedVolatility: values[4],
+			Source:            models.GreeksSourceImport,
+		})
+		if err != nil {
+			return importedCount, skippedCount, fmt.Errorf("row %d: %w", rowNum, err)
+		}
+
+		if created {
+			importedCount++
+		} else {
+			log.Printf("[GREEKS_IMPORT] Row %d: Skipped duplicate snapshot for %s on %s", rowNum, contract, captured.Format("2006-01-02"))
+			skippedCount++
+		}
+	}
+
+	return importedCount, skippedCount, nil
+}
+
+// ownedOptionOnDate returns the option on a contract that was open on the given date, if any
+func ownedOptionOnDate(candidates []*models.Option, date time.Time) *models.Option {
+	for _, option := range candidates {
+		end := option.Expiration
+		if option.Closed != nil {
+			end = *option.Closed
+		}
+		if !date.Before(option.Opened) && !date.After(end) {
+			return option
+		}
+	}
+	return nil
+}
+
 // convertCSVRecordToOption converts a CSV record to an Option struct
 func (s *Server) convertCSVRecordToOption(record CSVOptionRecord, rowNumber int) (*models.Option, error) {
+	// An OCC contract symbol supplies whichever contract fields the row leaves blank
+	var contract *polygon.OptionContractParts
+	if record.Contract != "" {
+		parsed, err := polygon.ParseOptionContractSymbol(record.Contract)
+		if err != nil {
+			return nil, err
+		}
+		contract = parsed
+		if record.Symbol == "" {
+			record.Symbol = contract.Underlying
+		}
+		if record.Type == "" {
+			record.Type = contract.Type
+		}
+		if record.Expiration == "" {
+			record.Expiration = contract.Expiration.Format("2006-01-02")
+		}
+	}
+
 	// Validate required fields
 	if record.Symbol == "" {
 		return nil, fmt.Errorf("symbol is required")
@@ -682,10 +1455,11 @@ func (s *Server) convertCSVRecordToOption(record CSVOptionRecord, rowNumber int)
 		return nil, fmt.Errorf("invalid opened date format (must be YYYY-MM-DD): %w", err)
 	}
 
-	expiration, err := time.Parse("2006-01-02", record.Expiration)
+	listedExpiration, err := time.Parse("2006-01-02", record.Expiration)
 	if err != nil {
 		return nil, fmt.Errorf("invalid expiration date format (must be YYYY-MM-DD): %w", err)
 	}
+	expiration := s.normalizeExpiration(listedExpiration)
 
 	var closed *time.Time
 	if record.Closed != "" {
@@ -697,29 +1471,54 @@ func (s *Server) convertCSVRecordToOption(record CSVOptionRecord, rowNumber int)
 	}
 
 	// Parse numeric fields
-	strike, err := strconv.ParseFloat(record.Strike, 64)
-	if err != nil {
+	locale := s.importNumberLocale()
+	var strike float64
+	if record.Strike == "" && contract != nil {
+		strike = contract.Strike
+	} else if strike, err = parseMoney(record.Strike, locale); err != nil {
 		return nil, fmt.Errorf("invalid strike price: %w", err)
 	}
 
-	premium, err := strconv.ParseFloat(record.Premium, 64)
+	if contract != nil {
+		if !strings.EqualFold(record.Symbol, contract.Underlying) {
+			return nil, fmt.Errorf("contract %s is on %s but symbol is %s", record.Contract, contract.Underlying, record.Symbol)
+		}
+		if record.Type != contract.Type {
+			return nil, fmt.Errorf("contract %s is a %s but type is %s", record.Contract, contract.Type, record.Type)
+		}
+		if math.Abs(strike-contract.Strike) > 0.0005 {
+			return nil, fmt.Errorf("contract %s has strike %g but strike is %g", record.Contract, contract.Strike, strike)
+		}
+		if !listedExpiration.Equal(contract.Expiration) {
+			return nil, fmt.Errorf("contract %s expires %s but expiration is %s", record.Contract, contract.Expiration.Format("2006-01-02"), record.Expiration)
+		}
+	}
+
+	premium, err := parseMoney(record.Premium, locale)
 	if err != nil {
 		return nil, fmt.Errorf("invalid premium: %w", err)
 	}
 
-	contracts, err := strconv.Atoi(record.Contracts)
+	contracts, err := parseCount(record.Contracts, locale)
 	if err != nil {
 		return nil, fmt.Errorf("invalid contracts count: %w", err)
 	}
 
-	commission, err := strconv.ParseFloat(record.Commission, 64)
+	commission, err := parseMoney(record.Commission, locale)
 	if err != nil {
 		return nil, fmt.Errorf("invalid commission: %w", err)
 	}
 
+	var fees float64
+	if record.Fees != "" {
+		if fees, err = parseMoney(record.Fees, locale); err != nil {
+			return nil, fmt.Errorf("invalid fees: %w", err)
+		}
+	}
+
 	var exitPrice *float64
 	if record.ExitPrice != "" {
-		price, err := strconv.ParseFloat(record.ExitPrice, 64)
+		price, err := parseMoney(record.ExitPrice, locale)
 		if err != nil {
 			return nil, fmt.Errorf("invalid exit price: %w", err)
 		}
@@ -739,6 +1538,9 @@ func (s *Server) convertCSVRecordToOption(record CSVOptionRecord, rowNumber int)
 	if commission < 0 {
 		return nil, fmt.Errorf("commission cannot be negative")
 	}
+	if fees < 0 {
+		return nil, fmt.Errorf("fees cannot be negative")
+	}
 	if expiration.Before(opened) {
 		return nil, fmt.Errorf("expiration date cannot be before opened date")
 	}
@@ -757,6 +1559,7 @@ func (s *Server) convertCSVRecordToOption(record CSVOptionRecord, rowNumber int)
 		Contracts:  contracts,
 		ExitPrice:  exitPrice,
 		Commission: commission,
+		Fees:       fees,
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
 	}
@@ -791,16 +1594,17 @@ func (s *Server) csvStockRecordToLongPosition(record CSVStockRecord) (*models.Lo
 	}
 
 	// Parse shares (decimal representing hundreds of shares)
-	sharesFloat, err := strconv.ParseFloat(record.Shares, 64)
+	locale := s.importNumberLocale()
+	sharesFloat, err := parseMoney(record.Shares, locale)
 	if err != nil {
-		return nil, fmt.Errorf("invalid shares format: %s", record.Shares)
+		return nil, fmt.Errorf("invalid shares format: %w", err)
 	}
 	shares := int(sharesFloat * 100) // Convert to actual shares count
 
 	// Parse buy price
-	buyPrice, err := strconv.ParseFloat(record.BuyPrice, 64)
+	buyPrice, err := parseMoney(record.BuyPrice, locale)
 	if err != nil {
-		return nil, fmt.Errorf("invalid buy price format: %s", record.BuyPrice)
+		return nil, fmt.Errorf("invalid buy price format: %w", err)
 	}
 
 	// Parse optional closed date
@@ -819,9 +1623,9 @@ func (s *Server) csvStockRecordToLongPosition(record CSVStockRecord) (*models.Lo
 	// Parse optional exit price
 	var exitPrice *float64
 	if record.ExitPrice != "" {
-		price, err := strconv.ParseFloat(record.ExitPrice, 64)
+		price, err := parseMoney(record.ExitPrice, locale)
 		if err != nil {
-			return nil, fmt.Errorf("invalid exit price format: %s", record.ExitPrice)
+			return nil, fmt.Errorf("invalid exit price format: %w", err)
 		}
 		exitPrice = &price
 	}
@@ -870,14 +1674,10 @@ func (s *Server) processDividendRecord(csvRecord CSVDividendRecord, rowNum int)
 		return nil, false, fmt.Errorf("invalid date format '%s' (expected MM/DD/YYYY, M/D/YYYY, MM/DD/YY, or M/D/YY)", csvRecord.DateReceived)
 	}
 
-	// Parse amount (handle dollar signs)
-	amountStr := strings.TrimSpace(csvRecord.Amount)
-	amountStr = strings.TrimPrefix(amountStr, "$")
-	amountStr = strings.ReplaceAll(amountStr, ",", "")
-
-	amount, err := strconv.ParseFloat(amountStr, 64)
+	// Parse amount (handles currency symbols and thousands separators)
+	amount, err := parseMoney(csvRecord.Amount, s.importNumberLocale())
 	if err != nil {
-		return nil, false, fmt.Errorf("invalid amount '%s'", csvRecord.Amount)
+		return nil, false, fmt.Errorf("invalid amount: %w", err)
 	}
 
 	if amount <= 0 {
@@ -916,6 +1716,18 @@ func (s *Server) processDividendRecord(csvRecord CSVDividendRecord, rowNum int)
 	return dividend, true, nil
 }
 
+// parseTreasuryRate parses an imported yield or coupon rate and normalizes it to the stored
+// percent convention under TREASURY_YIELD_INPUT_SCALE, rejecting implausible values
+func (s *Server) parseTreasuryRate(raw, locale string) (float64, error) {
+	raw = strings.TrimSpace(raw)
+	percentSign := strings.HasSuffix(raw, "%")
+	value, err := parseMoney(strings.TrimSpace(strings.TrimSuffix(raw, "%")), locale)
+	if err != nil {
+		return 0, err
+	}
+	return models.NormalizeTreasuryYield(value, percentSign, s.configValue("TREASURY_YIELD_INPUT_SCALE"))
+}
+
 // processTreasuryRecord processes a single treasury record from CSV
 func (s *Server) processTreasuryRecord(csvRecord CSVTreasuryRecord, rowNum int) (*models.Treasury, bool, error) {
 	// Validate CUSPID
@@ -961,26 +1773,26 @@ func (s *Server) processTreasuryRecord(csvRecord CSVTreasuryRecord, rowNum int)
 	}
 
 	// Parse amount
-	amount, err := strconv.ParseFloat(strings.TrimPrefix(strings.ReplaceAll(csvRecord.Amount, ",", ""), "$"), 64)
+	locale := s.importNumberLocale()
+	amount, err := parseMoney(csvRecord.Amount, locale)
 	if err != nil {
-		return nil, false, fmt.Errorf("invalid amount '%s'", csvRecord.Amount)
+		return nil, false, fmt.Errorf("invalid amount: %w", err)
 	}
 
 	if amount <= 0 {
 		return nil, false, fmt.Errorf("amount must be positive, got %.2f", amount)
 	}
 
-	// Parse yield
-	yieldStr := strings.TrimSuffix(csvRecord.Yield, "%")
-	yield, err := strconv.ParseFloat(yieldStr, 64)
+	// Parse yield, normalized to percent whether the file writes 4.5, 4.5% or 0.045
+	yield, err := s.parseTreasuryRate(csvRecord.Yield, locale)
 	if err != nil {
-		return nil, false, fmt.Errorf("invalid yield '%s'", csvRecord.Yield)
+		return nil, false, fmt.Errorf("invalid yield: %w", err)
 	}
 
 	// Parse buy price
-	buyPrice, err := strconv.ParseFloat(strings.TrimPrefix(strings.ReplaceAll(csvRecord.BuyPrice, ",", ""), "$"), 64)
+	buyPrice, err := parseMoney(csvRecord.BuyPrice, locale)
 	if err != nil {
-		return nil, false, fmt.Errorf("invalid buy price '%s'", csvRecord.BuyPrice)
+		return nil, false, fmt.Errorf("invalid buy price: %w", err)
 	}
 
 	if buyPrice <= 0 {
@@ -990,9 +1802,9 @@ func (s *Server) processTreasuryRecord(csvRecord CSVTreasuryRecord, rowNum int)
 	// Parse optional current value
 	var currentValue *float64
 	if csvRecord.CurrentValue != "" {
-		value, err := strconv.ParseFloat(strings.TrimPrefix(strings.ReplaceAll(csvRecord.CurrentValue, ",", ""), "$"), 64)
+		value, err := parseMoney(csvRecord.CurrentValue, locale)
 		if err != nil {
-			return nil, false, fmt.Errorf("invalid current value '%s'", csvRecord.CurrentValue)
+			return nil, false, fmt.Errorf("invalid current value: %w", err)
 		}
 		currentValue = &value
 	}
@@ -1000,13 +1812,32 @@ func (s *Server) processTreasuryRecord(csvRecord CSVTreasuryRecord, rowNum int)
 	// Parse optional exit price
 	var exitPrice *float64
 	if csvRecord.ExitPrice != "" {
-		price, err := strconv.ParseFloat(strings.TrimPrefix(strings.ReplaceAll(csvRecord.ExitPrice, ",", ""), "$"), 64)
+		price, err := parseMoney(csvRecord.ExitPrice, locale)
 		if err != nil {
-			return nil, false, fmt.Errorf("invalid exit price '%s'", csvRecord.ExitPrice)
+			return nil, false, fmt.Errorf("invalid exit price: %w", err)
 		}
 		exitPrice = &price
 	}
 
+	// Parse optional coupon terms; bills leave them empty
+	var couponRate float64
+	var couponFrequency int
+	if csvRecord.CouponRate != "" {
+		couponRate, err = s.parseTreasuryRate(csvRecord.CouponRate, locale)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid coupon rate: %w", err)
+		}
+	}
+	if csvRecord.CouponFrequency != "" {
+		couponFrequency, err = parseCount(csvRecord.CouponFrequency, locale)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid coupon frequency: %w", err)
+		}
+	}
+	if couponRate > 0 && couponFrequency == 0 {
+		couponFrequency = 2 // Treasury notes and bonds pay semiannually
+	}
+
 	// Check if treasury already exists (to avoid duplicates)
 	existingTreasury, err := s.treasuryService.GetByCUSPID(csvRecord.CUSPID)
 	if err == nil && existingTreasury != nil {
@@ -1032,6 +1863,14 @@ func (s *Server) processTreasuryRecord(csvRecord CSVTreasuryRecord, rowNum int)
 		}
 	}
 
+	if couponRate > 0 {
+		if err := s.treasuryService.SetCoupon(treasury.CUSPID, couponRate, couponFrequency); err != nil {
+			return nil, false, fmt.Errorf("failed to set coupon terms: %w", err)
+		}
+		treasury.CouponRate = couponRate
+		treasury.CouponFrequency = couponFrequency
+	}
+
 	return treasury, true, nil
 }
 
@@ -1153,10 +1992,35 @@ func (s *Server) handleCreateBackup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	backupFileName, err := s.createBackup(dbFileName)
+	if err != nil {
+		log.Printf("[BACKUP] Error creating backup: %v", err)
+		http.Error(w, `{"success": false, "error": "Failed to create backup"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Return success response
+	response := map[string]interface{}{
+		"success":  true,
+		"message":  fmt.Sprintf("Backup created: %s", backupFileName),
+		"filename": backupFileName,
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// createBackup checkpoints the WAL and copies a database in ./data to a timestamped file in
+// ./data/backups, returning the backup's filename
+func (s *Server) createBackup(dbFileName string) (string, error) {
+	sourceFilePath := filepath.Join("./data", dbFileName)
+
 	log.Printf("[BACKUP] Checkpointing WAL to ensure all data is committed")
+	s.checkpointMu.Lock()
 	if _, err := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
 		log.Printf("[BACKUP] Warning: WAL checkpoint failed: %v", err)
 	}
+	s.checkpointMu.Unlock()
 
 	// Create backup filename with timestamp
 	timestamp := time.Now().Format("2006-01-02-15-04-05")
@@ -1166,22 +2030,11 @@ func (s *Server) handleCreateBackup(w http.ResponseWriter, r *http.Request) {
 
 	// Create backup by copying the file
 	if err := s.copyFile(sourceFilePath, backupPath); err != nil {
-		log.Printf("[BACKUP] Error creating backup: %v", err)
-		http.Error(w, `{"success": false, "error": "Failed to create backup"}`, http.StatusInternalServerError)
-		return
+		return "", err
 	}
 
 	log.Printf("[BACKUP] Successfully created backup: %s -> %s", sourceFilePath, backupPath)
-
-	// Return success response
-	response := map[string]interface{}{
-		"success":  true,
-		"message":  fmt.Sprintf("Backup created: %s", backupFileName),
-		"filename": backupFileName,
-	}
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	return backupFileName, nil
 }
 
 // copyFile copies a file from src to dst
@@ -1323,6 +2176,32 @@ func (s *Server) handleSetCurrentDatabase(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// With DATABASE_SCOPE=session only this browser session switches; the global database is unchanged
+	if s.sessionScopedDatabases() {
+		if !validDatabaseName(dbName) {
+			log.Printf("[SET_DATABASE] Invalid database name: %s", dbName)
+			http.Error(w, `{"success": false, "error": "Invalid database name"}`, http.StatusBadRequest)
+			return
+		}
+		s.setSessionDatabase(w, dbName)
+		log.Printf("[SET_DATABASE] Switched session database to: %s", dbName)
+
+		response := map[string]interface{}{
+			"success":  true,
+			"message":  fmt.Sprintf("Session database set to: %s", dbName),
+			"database": dbName,
+			"scope":    DatabaseScopeSession,
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Wait for background tasks using the current database, and keep them off it until the
+	// new one is in place
+	s.dbMu.Lock()
+	defer s.dbMu.Unlock()
+
 	// Close existing database connection
 	log.Printf("[SET_DATABASE] Closing existing database connection")
 	if err := s.db.Close(); err != nil {
@@ -1351,11 +2230,23 @@ func (s *Server) handleSetCurrentDatabase(w http.ResponseWriter, r *http.Request
 	s.optionService = models.NewOptionService(dbWrapper.DB)
 	s.symbolService = models.NewSymbolService(dbWrapper.DB)
 	s.treasuryService = models.NewTreasuryService(dbWrapper.DB)
+	s.treasuryCouponService = models.NewTreasuryCouponService(dbWrapper.DB)
+	s.greeksSnapshotService = models.NewGreeksSnapshotService(dbWrapper.DB)
 	s.longPositionService = models.NewLongPositionService(dbWrapper.DB)
 	s.dividendService = models.NewDividendService(dbWrapper.DB)
-	s.settingService = models.NewSettingService(dbWrapper.DB)
+	s.settingService = models.NewSettingService(dbWrapper.DB) // fresh settings cache for the new database
 	s.metricService = models.NewMetricService(dbWrapper.DB)
 
+	// Rebind Polygon integration to the new database's services
+	s.polygonService.StopSymbolEnrichment()
+	s.polygonService = polygon.NewService(s.symbolService, s.settingService)
+	s.polygonService.StartSymbolEnrichment()
+	s.symbolService.SetCreateHook(s.polygonService.QueueSymbolEnrichment)
+
+	s.bindCostBasisSettings()
+	s.bindMetricSettings()
+	s.backfillRealizedProfit()
+
 	log.Printf("[SET_DATABASE] Successfully switched to database: %s", dbName)
 
 	// Return success response
@@ -1431,6 +2322,126 @@ func (s *Server) handleCreateDatabase(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleCloneDatabase handles POST /api/database/clone, copying a database to a new selectable name
+func (s *Server) handleCloneDatabase(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"success": false, "error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Source string `json:"source"`
+		Name   string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"success": false, "error": "Invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	source := strings.TrimSpace(req.Source)
+	if source == "" {
+		source = s.getCurrentDatabaseName()
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		log.Printf("[CLONE_DATABASE] Database name is required")
+		http.Error(w, `{"success": false, "error": "Database name is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Validate database names (same rules as create)
+	if strings.ContainsAny(source, "/\\:*?\"<>|") || strings.ContainsAny(name, "/\\:*?\"<>|") {
+		log.Printf("[CLONE_DATABASE] Invalid database name: source=%s name=%s", source, name)
+		http.Error(w, `{"success": false, "error": "Invalid database name"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := database.CloneDatabase(source, name); err != nil {
+		log.Printf("[CLONE_DATABASE] Error cloning %s to %s: %v", source, name, err)
+		switch {
+		case strings.Contains(err.Error(), "already exists"):
+			http.Error(w, `{"success": false, "error": "Database already exists"}`, http.StatusConflict)
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, `{"success": false, "error": "Source database not found"}`, http.StatusNotFound)
+		default:
+			http.Error(w, `{"success": false, "error": "Failed to clone database"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if !strings.HasSuffix(name, ".db") {
+		name = name + ".db"
+	}
+
+	log.Printf("[CLONE_DATABASE] Successfully cloned %s to %s", source, name)
+
+	response := map[string]interface{}{
+		"success":  true,
+		"message":  fmt.Sprintf("Database %s cloned to %s", source, name),
+		"source":   source,
+		"database": name,
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleResetDatabase handles POST /api/database/reset, clearing the current database's positions,
+// options, dividends, treasuries and metrics while keeping its settings. The request must set
+// confirm; a timestamped backup is taken first and the reset is refused if the backup fails.
+// include_symbols also clears the symbols table (prices and dividend data).
+func (s *Server) handleResetDatabase(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAPIToken(w, r) {
+		return
+	}
+
+	var req DatabaseResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if !req.Confirm {
+		http.Error(w, "Reset requires \"confirm\": true", http.StatusBadRequest)
+		return
+	}
+
+	dbName := s.getCurrentDatabaseName()
+	backupFileName, err := s.createBackup(dbName)
+	if err != nil {
+		log.Printf("[RESET_DATABASE] Backup of %s failed, not resetting: %v", dbName, err)
+		http.Error(w, "Failed to back up database; nothing was deleted", http.StatusInternalServerError)
+		return
+	}
+
+	deleted, err := database.ResetTradingData(s.db, req.IncludeSymbols)
+	if err != nil {
+		log.Printf("[RESET_DATABASE] Error resetting %s: %v", dbName, err)
+		http.Error(w, fmt.Sprintf("Failed to reset database: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var total int64
+	for _, rows := range deleted {
+		total += rows
+	}
+	log.Printf("[RESET_DATABASE] Reset %s: %d rows deleted, backup %s", dbName, total, backupFileName)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DatabaseResetResponse{
+		Success:      true,
+		Database:     dbName,
+		Backup:       backupFileName,
+		RowsDeleted:  deleted,
+		TotalDeleted: total,
+	})
+}
+
 // HandleGenerateTestData handles the POST request to generate wheel strategy test data
 func (s *Server) HandleGenerateTestData(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -1570,7 +2581,7 @@ func (s *Server) handleDeleteDatabase(w http.ResponseWriter, r *http.Request) {
 
 	// Check if this is the current database (prevent deletion of current database)
 	currentDB, err := database.GetCurrentDatabase()
-	if err == nil && currentDB == dbName {
+	if (err == nil && currentDB == dbName) || s.getCurrentDatabaseName() == dbName {
 		log.Printf("[DELETE_DATABASE] Cannot delete current database: %s", dbName)
 		http.Error(w, `{"success": false, "error": "Cannot delete the currently active database"}`, http.StatusConflict)
 		return
@@ -1586,6 +2597,9 @@ func (s *Server) handleDeleteDatabase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Release any session connection before deleting the file
+	s.rootServer().closeSessionDatabase(dbName)
+
 	// Delete the database file
 	if err := os.Remove(dbPath); err != nil {
 		log.Printf("[DELETE_DATABASE] Error deleting database file: %v", err)
@@ -1605,6 +2619,139 @@ func (s *Server) handleDeleteDatabase(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// maxUploadMB returns the MAX_UPLOAD_MB import upload limit, defaulting to 10
+func (s *Server) maxUploadMB() int {
+	limit, err := strconv.Atoi(strings.TrimSpace(s.settingService.GetValue("MAX_UPLOAD_MB")))
+	if err != nil || limit <= 0 {
+		return 10
+	}
+	return limit
+}
+
+// uploadTooLargeError reports an import upload rejected by the MAX_UPLOAD_MB limit
+type uploadTooLargeError struct {
+	limitMB int
+}
+
+func (e *uploadTooLargeError) Error() string {
+	return fmt.Sprintf("File exceeds the %d MB upload limit; raise MAX_UPLOAD_MB in Settings to import larger files", e.limitMB)
+}
+
+// parseUploadForm caps the request body at MAX_UPLOAD_MB and parses the multipart import form.
+// Every import upload handler goes through here so the limit is applied consistently.
+func (s *Server) parseUploadForm(w http.ResponseWriter, r *http.Request) error {
+	limitMB := s.maxUploadMB()
+	limit := int64(limitMB) << 20
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	if err := r.ParseMultipartForm(limit); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return &uploadTooLargeError{limitMB: limitMB}
+		}
+		return err
+	}
+	return nil
+}
+
+// uploadFormErrorMessage names the size limit for oversized uploads and falls back to a generic message otherwise
+func uploadFormErrorMessage(err error, fallback string) string {
+	var tooLarge *uploadTooLargeError
+	if errors.As(err, &tooLarge) {
+		return tooLarge.Error()
+	}
+	return fallback
+}
+
+// importNumberLocale returns IMPORT_NUMBER_LOCALE: "en" for 1,234.56 or "eu" for 1.234,56
+func (s *Server) importNumberLocale() string {
+	return strings.ToLower(strings.TrimSpace(s.settingService.GetValueWithDefault("IMPORT_NUMBER_LOCALE", "en")))
+}
+
+// usesCommaDecimal reports whether a number locale writes decimals with a comma
+func usesCommaDecimal(locale string) bool {
+	switch locale {
+	case "eu", "de", "fr", "es", "it", "nl", "pt":
+		return true
+	}
+	return false
+}
+
+// parseMoney parses a numeric CSV field shared by all importers. Currency symbols, spaces and
+// thousands separators are stripped, accounting-style parentheses mean negative, and the decimal
+// separator follows the locale. Thousands groups are validated so a comma decimal is never
+// silently read as a thousands separator.
+func parseMoney(raw, locale string) (float64, error) {
+	value := strings.TrimSpace(raw)
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = strings.TrimSuffix(strings.TrimPrefix(value, "("), ")")
+	}
+	value = strings.NewReplacer("$", "", "€", "", "£", "", " ", "", "\u00a0", "", "'", "").Replace(value)
+	if strings.HasPrefix(value, "-") {
+		negative = !negative
+		value = strings.TrimPrefix(value, "-")
+	}
+
+	decimalSep, groupSep := ".", ","
+	if usesCommaDecimal(locale) {
+		decimalSep, groupSep = ",", "."
+	}
+
+	intPart, fracPart, hasFraction := strings.Cut(value, decimalSep)
+	if value == "" || strings.Contains(fracPart, decimalSep) || strings.Contains(fracPart, groupSep) {
+		return 0, fmt.Errorf("%q is not a valid number%s", raw, numberLocaleHint(raw, locale))
+	}
+	if strings.Contains(intPart, groupSep) {
+		groups := strings.Split(intPart, groupSep)
+		for i, group := range groups {
+			if (i == 0 && (len(group) == 0 || len(group) > 3)) || (i > 0 && len(group) != 3) {
+				return 0, fmt.Errorf("%q is not a valid number%s", raw, numberLocaleHint(raw, locale))
+			}
+		}
+		intPart = strings.ReplaceAll(intPart, groupSep, "")
+	}
+
+	normalized := intPart
+	if hasFraction {
+		normalized += "." + fracPart
+	}
+	parsed, err := strconv.ParseFloat(normalized, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid number%s", raw, numberLocaleHint(raw, locale))
+	}
+	if negative {
+		parsed = -parsed
+	}
+	return parsed, nil
+}
+
+// parseCount parses a whole-number CSV field such as contracts, allowing thousands separators
+func parseCount(raw, locale string) (int, error) {
+	value, err := parseMoney(raw, locale)
+	if err != nil {
+		return 0, err
+	}
+	if value != math.Trunc(value) {
+		return 0, fmt.Errorf("%q is not a whole number", raw)
+	}
+	return int(value), nil
+}
+
+// numberLocaleHint suggests switching IMPORT_NUMBER_LOCALE when a value looks formatted for the other locale
+func numberLocaleHint(raw, locale string) string {
+	if usesCommaDecimal(locale) {
+		if strings.Contains(raw, ".") {
+			return " (if this file uses 1,234.56 formatting, set IMPORT_NUMBER_LOCALE to en)"
+		}
+		return ""
+	}
+	if strings.Contains(raw, ",") {
+		return " (if this file uses 1.234,56 formatting, set IMPORT_NUMBER_LOCALE to eu)"
+	}
+	return ""
+}
+
 // Helper function for min
 func min(a, b int) int {
 	if a < b {
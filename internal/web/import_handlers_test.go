@@ -0,0 +1,208 @@
+package web
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMapOptionColumns(t *testing.T) {
+	optional := []string{"commission", "fees", "account", "strategy", "contract", "status"}
+	tests := []struct {
+		name    string
+		headers []string
+		want    map[string]int
+		wantErr string
+	}{
+		{
+			name:    "reordered headers with aliases and unknown columns",
+			headers: []string{"Notes", "Exit Price", "Symbol", "Opened", "Closed", "Type", "Strike", "Expiration", "Premium", "Contracts", "Total Commission", "Reg Fees"},
+			want: map[string]int{"exit_price": 1, "symbol": 2, "opened": 3, "closed": 4, "type": 5, "strike": 6, "expiration": 7,
+				"premium": 8, "contracts": 9, "commission": 10, "fees": 11},
+		},
+		{
+			name:    "contract column replaces symbol, type, strike and expiration",
+			headers: []string{"OCC Symbol", "opened", "closed", "premium", "contracts", "exit_price"},
+			want:    map[string]int{"contract": 0, "opened": 1, "closed": 2, "premium": 3, "contracts": 4, "exit_price": 5},
+		},
+		{
+			name:    "missing contract fields suggest a contract column",
+			headers: []string{"opened", "closed", "premium", "contracts", "exit_price"},
+			wantErr: "missing required column(s): symbol, type, strike, expiration (symbol, type, strike and expiration may be replaced",
+		},
+		{
+			name:    "missing non-contract field",
+			headers: []string{"contract", "opened", "closed", "premium", "exit_price"},
+			wantErr: "missing required column(s): contracts",
+		},
+		{
+			name:    "repeated header after canonicalization",
+			headers: []string{"symbol", "opened", "closed", "type", "strike", "expiration", "premium", "contracts", "exit_price", "commission", "Total Commission"},
+			wantErr: "columns 10 and 11 are both 'commission'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mapOptionColumns(tt.headers, optionCSVRequiredColumns, optional...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStrictOptionColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		wantErr string
+	}{
+		{"exact headers", []string{"symbol", "opened", "closed", "type", "strike", "expiration", "premium", "contracts", "exit_price", "commission"}, ""},
+		{"case, spacing and aliases", []string{" Symbol", "Opened", "Closed", "Type", "Strike", "Expiration", "Premium", "Contracts", "Exit Price", "Total Commission"}, ""},
+		{"too few columns", []string{"symbol", "opened", "closed", "type", "strike", "expiration", "premium", "contracts", "exit_price"}, "exactly 10 columns, got 9"},
+		{"out of order", []string{"opened", "symbol", "closed", "type", "strike", "expiration", "premium", "contracts", "exit_price", "commission"}, "column 1 should be 'symbol', got 'opened'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := strictOptionColumns(tt.headers, optionCSVColumns)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i, name := range optionCSVColumns {
+				if got[name] != i {
+					t.Errorf("expected %s at column %d, got %d", name, i, got[name])
+				}
+			}
+		})
+	}
+}
+
+func TestOptionRecordFromColumns(t *testing.T) {
+	record := []string{" aapl ", "2025-01-02", "", "Put", "150", "2025-01-17", "1.25", "2", "", "1.30", "0.04"}
+	tests := []struct {
+		name    string
+		columns map[string]int
+		want    CSVOptionRecord
+	}{
+		{
+			name: "commission and fees columns",
+			columns: map[string]int{"symbol": 0, "opened": 1, "closed": 2, "type": 3, "strike": 4, "expiration": 5,
+				"premium": 6, "contracts": 7, "exit_price": 8, "commission": 9, "fees": 10},
+			want: CSVOptionRecord{Symbol: "AAPL", Opened: "2025-01-02", Type: "Put", Strike: "150", Expiration: "2025-01-17",
+				Premium: "1.25", Contracts: "2", Commission: "1.30", Fees: "0.04"},
+		},
+		{
+			name: "no commission or fees column",
+			columns: map[string]int{"symbol": 0, "opened": 1, "closed": 2, "type": 3, "strike": 4, "expiration": 5,
+				"premium": 6, "contracts": 7, "exit_price": 8},
+			want: CSVOptionRecord{Symbol: "AAPL", Opened: "2025-01-02", Type: "Put", Strike: "150", Expiration: "2025-01-17",
+				Premium: "1.25", Contracts: "2", Commission: "0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := optionRecordFromColumns(record, tt.columns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		locale  string
+		want    float64
+		wantErr string
+	}{
+		{"plain decimal", "0.65", "en", 0.65, ""},
+		{"en thousands", "1,234.56", "en", 1234.56, ""},
+		{"en currency symbol", "$1,234,567.89", "en", 1234567.89, ""},
+		{"parenthesised negative", "($12.50)", "en", -12.50, ""},
+		{"leading minus after currency strip", "-$3.25", "en", -3.25, ""},
+		{"spaces and non-breaking spaces", " 1 234\u00a0567.5 ", "en", 1234567.5, ""},
+		{"eu thousands", "1.234,56", "eu", 1234.56, ""},
+		{"eu currency symbol", "€1.234,56", "de", 1234.56, ""},
+		{"eu parenthesised negative", "(0,65)", "eu", -0.65, ""},
+		{"whole number", "12", "eu", 12, ""},
+		{"en comma decimal is malformed grouping", "1,5", "en", 0, "set IMPORT_NUMBER_LOCALE to eu"},
+		{"en short trailing group", "12,34.00", "en", 0, "not a valid number"},
+		{"en long leading group", "1234,567", "en", 0, "not a valid number"},
+		{"eu dot decimal is malformed grouping", "1.23", "eu", 0, "set IMPORT_NUMBER_LOCALE to en"},
+		{"en formatting in eu", "1,234.56", "eu", 0, "not a valid number"},
+		{"two decimal separators", "1.2.3", "en", 0, "not a valid number"},
+		{"empty", "", "en", 0, "not a valid number"},
+		{"only a currency symbol", "$", "en", 0, "not a valid number"},
+		{"text", "n/a", "en", 0, "not a valid number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMoney(tt.raw, tt.locale)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v (value %v)", tt.wantErr, err, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		locale  string
+		want    int
+		wantErr bool
+	}{
+		{"plain", "2", "en", 2, false},
+		{"en thousands", "1,000", "en", 1000, false},
+		{"eu thousands", "1.000", "eu", 1000, false},
+		{"trailing zero fraction", "3.00", "en", 3, false},
+		{"parenthesised negative", "(2)", "en", -2, false},
+		{"fractional", "1.5", "en", 0, true},
+		{"eu fractional", "1,5", "eu", 0, true},
+		{"empty", "", "en", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCount(tt.raw, tt.locale)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for %q, got %d", tt.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,410 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// incomeProjectionHandler handles GET /api/income/projection?months=12&trailing_months=6
+// Dividends use each symbol's quarterly dividend on shares still held, scheduled from the
+// last known ex-dividend date (or spread evenly when unknown). Coupons follow each held
+// note or bond's payment schedule. Premium is the trailing
+// average monthly net profit of closed options, held flat across the projection.
+func (s *Server) incomeProjectionHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INCOME API] %s %s - Projecting income", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	months := 12
+	if monthsStr := r.URL.Query().Get("months"); monthsStr != "" {
+		parsed, err := strconv.Atoi(monthsStr)
+		if err != nil || parsed <= 0 || parsed > 60 {
+			http.Error(w, "months must be between 1 and 60", http.StatusBadRequest)
+			return
+		}
+		months = parsed
+	}
+
+	trailingMonths := 6
+	if trailingStr := r.URL.Query().Get("trailing_months"); trailingStr != "" {
+		parsed, err := strconv.Atoi(trailingStr)
+		if err != nil || parsed <= 0 || parsed > 36 {
+			http.Error(w, "trailing_months must be between 1 and 36", http.StatusBadRequest)
+			return
+		}
+		trailingMonths = parsed
+	}
+
+	openPositions, err := s.longPositionService.GetOpenPositions()
+	if err != nil {
+		log.Printf("[INCOME API] Error getting open positions: %v", err)
+		http.Error(w, "Failed to get open positions", http.StatusInternalServerError)
+		return
+	}
+	if err := s.longPositionService.AttachExits(openPositions); err != nil {
+		log.Printf("[INCOME API] Error attaching position exits: %v", err)
+		http.Error(w, "Failed to get open positions", http.StatusInternalServerError)
+		return
+	}
+
+	sharesBySymbol := make(map[string]int)
+	for _, position := range openPositions {
+		sharesBySymbol[position.Symbol] += position.RemainingShares()
+	}
+
+	now := time.Now()
+	firstMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+
+	projection := make([]IncomeProjectionMonth, months)
+	for i := range projection {
+		projection[i].Month = firstMonth.AddDate(0, i, 0).Format("2006-01")
+	}
+
+	for symbol, shares := range sharesBySymbol {
+		if shares <= 0 {
+			continue
+		}
+		symbolData, err := s.symbolService.GetBySymbol(symbol)
+		if err != nil || symbolData.Dividend <= 0 {
+			continue
+		}
+
+		quarterly := symbolData.Dividend * float64(shares)
+		for i := range projection {
+			if symbolData.ExDividendDate == nil {
+				projection[i].Dividends += quarterly / 3
+				continue
+			}
+			month := firstMonth.AddDate(0, i, 0)
+			monthsSinceExDiv := (month.Year()-symbolData.ExDividendDate.Year())*12 + int(month.Month()-symbolData.ExDividendDate.Month())
+			if monthsSinceExDiv%3 == 0 {
+				projection[i].Dividends += quarterly
+			}
+		}
+	}
+
+	// Coupons on held notes and bonds follow their payment schedule up to maturity
+	projectionEnd := firstMonth.AddDate(0, months, 0).AddDate(0, 0, -1)
+	treasuries, err := s.treasuryService.GetAll()
+	if err != nil {
+		log.Printf("[INCOME API] Error getting treasuries: %v", err)
+		http.Error(w, "Failed to get treasuries", http.StatusInternalServerError)
+		return
+	}
+	for _, treasury := range treasuries {
+		if treasury.ExitPrice != nil || !treasury.HasCoupon() {
+			continue
+		}
+		payment := treasury.CalculateCouponPayment()
+		for _, date := range treasury.CouponDates(firstMonth.AddDate(0, 0, -1), projectionEnd) {
+			index := (date.Year()-firstMonth.Year())*12 + int(date.Month()-firstMonth.Month())
+			if index >= 0 && index < months {
+				projection[index].Coupons += payment
+			}
+		}
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[INCOME API] Error getting options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+
+	trailingStart := firstMonth.AddDate(0, -trailingMonths, 0)
+	var trailingPremium float64
+	for _, option := range options {
+		if option.Closed != nil && !option.Closed.Before(trailingStart) && option.Closed.Before(firstMonth) {
+			trailingPremium += option.RealizedProfitValue()
+		}
+	}
+	runRate := trailingPremium / float64(trailingMonths)
+
+	response := IncomeProjectionResponse{
+		Months:                 months,
+		TrailingMonths:         trailingMonths,
+		TrailingPremiumTotal:   trailingPremium,
+		PremiumRunRatePerMonth: runRate,
+		Projection:             projection,
+		Note:                   "Dividends are based on known quarterly dividends for shares currently held. Coupons follow the payment schedule of held treasury notes and bonds. Estimated premium is the trailing average monthly net profit from closed options and is not guaranteed.",
+	}
+	for i := range response.Projection {
+		month := &response.Projection[i]
+		month.EstimatedPremium = runRate
+		month.Total = month.Dividends + month.Coupons + month.EstimatedPremium
+		response.TotalDividends += month.Dividends
+		response.TotalCoupons += month.Coupons
+		response.TotalEstimatedPremium += month.EstimatedPremium
+	}
+	response.Total = response.TotalDividends + response.TotalCoupons + response.TotalEstimatedPremium
+
+	log.Printf("[INCOME API] Projected %d months: dividends=$%.2f premium=$%.2f", months, response.TotalDividends, response.TotalEstimatedPremium)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[INCOME API] Error encoding response: %v", err)
+	}
+}
+
+// optionsYTDPremiumHandler handles GET /api/options/ytd-premium?attribute_by=opened|closed&year=
+// Net premium is each option's premium less buybacks and commissions. attribute_by=opened (the default)
+// counts every option opened this year, including open ones; closed counts only realized options by close month.
+func (s *Server) optionsYTDPremiumHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[INCOME API] %s %s - Aggregating year-to-date premium", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	attributeBy := r.URL.Query().Get("attribute_by")
+	if attributeBy == "" {
+		attributeBy = "opened"
+	}
+	if attributeBy != "opened" && attributeBy != "closed" {
+		http.Error(w, "attribute_by must be opened or closed", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	year := now.Year()
+	if yearStr := r.URL.Query().Get("year"); yearStr != "" {
+		parsed, err := strconv.Atoi(yearStr)
+		if err != nil || parsed < 1900 || parsed > year {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+		year = parsed
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[INCOME API] Error getting options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+
+	lastMonth := 12
+	if year == now.Year() {
+		lastMonth = int(now.Month())
+	}
+	response := YTDPremiumResponse{
+		Year:        year,
+		AttributeBy: attributeBy,
+		Months:      make([]YTDPremiumMonth, lastMonth),
+	}
+	for i := range response.Months {
+		response.Months[i].Month = fmt.Sprintf("%d-%02d", year, i+1)
+	}
+
+	for _, option := range options {
+		date := option.Opened
+		if attributeBy == "closed" {
+			if option.Closed == nil {
+				continue
+			}
+			date = *option.Closed
+		}
+		if date.Year() != year || int(date.Month()) > lastMonth {
+			continue
+		}
+		month := &response.Months[date.Month()-1]
+		month.Options++
+		month.NetPremium += option.RealizedProfitValue()
+	}
+
+	for i := range response.Months {
+		response.TotalNetPremium += response.Months[i].NetPremium
+		response.Months[i].RunningTotal = response.TotalNetPremium
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[INCOME API] Error encoding YTD premium response: %v", err)
+	}
+}
+
+// Cash flow calendar entry types
+const (
+	CashflowDividend      = "dividend"
+	CashflowCoupon        = "treasury_coupon"
+	CashflowMaturity      = "treasury_maturity"
+	CashflowPremium       = "option_premium"
+	CashflowPutAssignment = "put_assignment"
+)
+
+// cashflowCalendarHandler handles GET /api/cashflow/calendar?months=6 returning dated cash flows from
+// the start of the current month through the end of the last requested month. Certain flows are
+// option premium already received this month, scheduled treasury coupons and maturities, and
+// dividends expected on each ex-date projected quarterly from the last known one. Contingent flows
+// are the cash needed if puts that are in the money today are assigned at expiration.
+func (s *Server) cashflowCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[CASHFLOW API] %s %s - Building cash flow calendar", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	months := 6
+	if monthsStr := r.URL.Query().Get("months"); monthsStr != "" {
+		parsed, err := strconv.Atoi(monthsStr)
+		if err != nil || parsed <= 0 || parsed > 24 {
+			http.Error(w, "months must be between 1 and 24", http.StatusBadRequest)
+			return
+		}
+		months = parsed
+	}
+
+	today := s.marketToday()
+	from := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, months, -1)
+
+	response := CashflowCalendarResponse{
+		Months:   months,
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		Calendar: make(map[string]*CashflowDay),
+		Note:     "Certain flows are scheduled or already received; expected dividends assume the last known dividend continues quarterly. Contingent outflows assume puts in the money at today's price are assigned at expiration.",
+	}
+	add := func(date time.Time, contingent bool, entry CashflowEntry) {
+		key := date.Format("2006-01-02")
+		day, ok := response.Calendar[key]
+		if !ok {
+			day = &CashflowDay{Certain: []CashflowEntry{}, Contingent: []CashflowEntry{}}
+			response.Calendar[key] = day
+		}
+		if contingent {
+			day.Contingent = append(day.Contingent, entry)
+			day.ContingentNet += entry.Amount
+			response.TotalContingentOutflows -= entry.Amount
+			return
+		}
+		day.Certain = append(day.Certain, entry)
+		day.CertainNet += entry.Amount
+		response.TotalCertainInflows += entry.Amount
+	}
+
+	// Expected dividends on shares still held
+	openPositions, err := s.longPositionService.GetOpenPositions()
+	if err != nil {
+		log.Printf("[CASHFLOW API] Error getting open positions: %v", err)
+		http.Error(w, "Failed to get open positions", http.StatusInternalServerError)
+		return
+	}
+	if err := s.longPositionService.AttachExits(openPositions); err != nil {
+		log.Printf("[CASHFLOW API] Error attaching position exits: %v", err)
+		http.Error(w, "Failed to get open positions", http.StatusInternalServerError)
+		return
+	}
+	sharesBySymbol := make(map[string]int)
+	for _, position := range openPositions {
+		sharesBySymbol[position.Symbol] += position.RemainingShares()
+	}
+	for symbol, shares := range sharesBySymbol {
+		if shares <= 0 {
+			continue
+		}
+		symbolData, err := s.symbolService.GetBySymbol(symbol)
+		if err != nil || symbolData.Dividend <= 0 || symbolData.ExDividendDate == nil {
+			continue
+		}
+		exDate := *symbolData.ExDividendDate
+		for exDate.Before(today) {
+			exDate = exDate.AddDate(0, 3, 0)
+		}
+		for ; !exDate.After(to); exDate = exDate.AddDate(0, 3, 0) {
+			add(exDate, false, CashflowEntry{
+				Type:        CashflowDividend,
+				Symbol:      symbol,
+				Description: fmt.Sprintf("Expected dividend on %d shares at $%.4f", shares, symbolData.Dividend),
+				Amount:      symbolData.Dividend * float64(shares),
+			})
+		}
+	}
+
+	// Treasury coupons and maturities of held notes and bills
+	treasuries, err := s.treasuryService.GetAll()
+	if err != nil {
+		log.Printf("[CASHFLOW API] Error getting treasuries: %v", err)
+		http.Error(w, "Failed to get treasuries", http.StatusInternalServerError)
+		return
+	}
+	for _, treasury := range treasuries {
+		if treasury.ExitPrice != nil {
+			continue
+		}
+		payment := treasury.CalculateCouponPayment()
+		for _, date := range treasury.CouponDates(today.AddDate(0, 0, -1), to) {
+			add(date, false, CashflowEntry{
+				Type:        CashflowCoupon,
+				CUSPID:      treasury.CUSPID,
+				Description: fmt.Sprintf("Coupon at %.3f%%", treasury.CouponRate),
+				Amount:      payment,
+			})
+		}
+		if !treasury.Maturity.Before(today) && !treasury.Maturity.After(to) {
+			add(treasury.Maturity, false, CashflowEntry{
+				Type:        CashflowMaturity,
+				CUSPID:      treasury.CUSPID,
+				Description: "Face value returned at maturity",
+				Amount:      treasury.Amount,
+			})
+		}
+	}
+
+	// Premium already received this month, and cash needed if ITM puts are assigned
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[CASHFLOW API] Error getting options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+	prices := make(map[string]float64)
+	for _, option := range options {
+		if !option.Opened.Before(from) && !option.Opened.After(today) {
+			add(option.Opened, false, CashflowEntry{
+				Type:        CashflowPremium,
+				Symbol:      option.Symbol,
+				OptionID:    option.ID,
+				Description: fmt.Sprintf("Premium received on %d %s $%.2f %s", option.Contracts, option.Symbol, option.Strike, option.Type),
+				Amount:      option.Premium*float64(option.Contracts)*100 - option.TransactionCosts(),
+			})
+		}
+
+		if option.Closed != nil || option.Type != "Put" || option.Expiration.Before(today) || option.Expiration.After(to) {
+			continue
+		}
+		price, ok := prices[option.Symbol]
+		if !ok {
+			if symbolData, err := s.symbolService.GetBySymbol(option.Symbol); err == nil {
+				price = symbolData.Price
+			}
+			prices[option.Symbol] = price
+		}
+		if ladderMoneyness(option.Type, option.Strike, price) != "ITM" {
+			continue
+		}
+		add(option.Expiration, true, CashflowEntry{
+			Type:        CashflowPutAssignment,
+			Symbol:      option.Symbol,
+			OptionID:    option.ID,
+			Description: fmt.Sprintf("Assignment of %d shares at $%.2f (last price $%.2f)", option.Contracts*100, option.Strike, price),
+			Amount:      -option.Strike * float64(option.Contracts) * 100,
+		})
+	}
+
+	log.Printf("[CASHFLOW API] %d days with flows: certain inflows=$%.2f contingent outflows=$%.2f", len(response.Calendar), response.TotalCertainInflows, response.TotalContingentOutflows)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[CASHFLOW API] Error encoding response: %v", err)
+	}
+}
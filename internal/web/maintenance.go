@@ -0,0 +1,214 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"stonks/internal/models"
+	"strconv"
+	"time"
+)
+
+// defaultStaleAfterDays is how long past expiration an open option may sit before it is reported
+const defaultStaleAfterDays = 7
+
+// walCheckpointRecheck is how often a disabled WAL checkpoint task looks for a new interval
+const walCheckpointRecheck = time.Minute
+
+// startWALCheckpoints runs PRAGMA wal_checkpoint(PASSIVE) every WAL_CHECKPOINT_INTERVAL_MINUTES
+// so the -wal file stays bounded between backups. An interval of 0 disables the task; the
+// setting is re-read each cycle so changes apply without a restart.
+func (s *Server) startWALCheckpoints() {
+	s.maintenanceDone = make(chan struct{})
+	go s.runWALCheckpoints(s.maintenanceDone)
+	s.registerScheduledTask("wal_checkpoint", s.walCheckpointTaskStatus)
+	log.Printf("[MAINTENANCE] WAL checkpoint task started")
+}
+
+// walCheckpointTaskStatus reports the WAL checkpoint task for /api/scheduler/status
+func (s *Server) walCheckpointTaskStatus() SchedulerTaskStatus {
+	minutes := s.settingService.GetFloatWithDefault("WAL_CHECKPOINT_INTERVAL_MINUTES", 30)
+	status := SchedulerTaskStatus{
+		Description: "Passive WAL checkpoint that keeps the -wal file bounded between backups",
+		Enabled:     minutes > 0,
+		Schedule:    "disabled",
+		Settings:    []string{"WAL_CHECKPOINT_INTERVAL_MINUTES"},
+	}
+	if minutes > 0 {
+		status.Schedule = fmt.Sprintf("every %g minutes", minutes)
+	}
+	s.walRun.fill(&status)
+	return status
+}
+
+// stopWALCheckpoints stops the background WAL checkpoint task
+func (s *Server) stopWALCheckpoints() {
+	if s.maintenanceDone != nil {
+		close(s.maintenanceDone)
+		s.maintenanceDone = nil
+	}
+}
+
+func (s *Server) runWALCheckpoints(done <-chan struct{}) {
+	for {
+		wait := walCheckpointRecheck
+		_, settings, release := s.activeDB()
+		minutes := settings.GetFloatWithDefault("WAL_CHECKPOINT_INTERVAL_MINUTES", 30)
+		release()
+		if minutes > 0 {
+			wait = time.Duration(minutes * float64(time.Minute))
+			next := time.Now().Add(wait)
+			s.walRun.scheduleNext(&next)
+		} else {
+			s.walRun.scheduleNext(nil)
+		}
+
+		select {
+		case <-done:
+			return
+		case <-time.After(wait):
+		}
+
+		if minutes > 0 {
+			started := time.Now()
+			result, err := s.checkpointWAL()
+			s.walRun.recordRun(started, result, err)
+		}
+	}
+}
+
+// checkpointWAL runs a passive checkpoint unless another checkpoint (e.g. a backup) is in progress,
+// returning TaskResultSkipped when it did not run
+func (s *Server) checkpointWAL() (string, error) {
+	if !s.checkpointMu.TryLock() {
+		log.Printf("[MAINTENANCE] Skipping WAL checkpoint: another checkpoint is in progress")
+		return TaskResultSkipped, nil
+	}
+	defer s.checkpointMu.Unlock()
+
+	db, _, release := s.activeDB()
+	defer release()
+
+	var busy, logFrames, checkpointed int
+	if err := db.QueryRow("PRAGMA wal_checkpoint(PASSIVE)").Scan(&busy, &logFrames, &checkpointed); err != nil {
+		log.Printf("[MAINTENANCE] WAL checkpoint failed: %v", err)
+		return TaskResultFailed, err
+	}
+	if busy != 0 {
+		log.Printf("[MAINTENANCE] WAL checkpoint skipped: database busy")
+		return TaskResultSkipped, nil
+	}
+	log.Printf("[MAINTENANCE] WAL checkpoint complete: %d of %d frames checkpointed", checkpointed, logFrames)
+	return TaskResultSuccess, nil
+}
+
+// dataQualityHandler reports options with missing or inconsistent data, grouped by the rule they fail.
+// It is read-only; an option failing several rules appears in each matching category.
+func (s *Server) dataQualityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	staleAfterDays := defaultStaleAfterDays
+	if param := r.URL.Query().Get("stale_days"); param != "" {
+		parsed, err := strconv.Atoi(param)
+		if err != nil || parsed < 0 {
+			log.Printf("[API] GET /api/maintenance/data-quality - Invalid stale_days parameter: %s", param)
+			http.Error(w, "Invalid stale_days parameter", http.StatusBadRequest)
+			return
+		}
+		staleAfterDays = parsed
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[API] GET /api/maintenance/data-quality - Failed to get options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+
+	response := DataQualityResponse{
+		OptionsChecked: len(options),
+		StaleAfterDays: staleAfterDays,
+	}
+	byCategory := make(map[string][]*models.Option)
+	now := time.Now()
+	for _, option := range options {
+		issues := option.DataQualityIssues(now, staleAfterDays)
+		if len(issues) > 0 {
+			response.OptionsWithIssues++
+		}
+		for _, issue := range issues {
+			byCategory[issue] = append(byCategory[issue], option)
+		}
+	}
+
+	for _, category := range models.OptionIssueCategories {
+		flagged := byCategory[category.Category]
+		if flagged == nil {
+			flagged = []*models.Option{}
+		}
+		response.Categories = append(response.Categories, DataQualityCategory{
+			Category:    category.Category,
+			Description: category.Description,
+			Count:       len(flagged),
+			Options:     flagged,
+		})
+	}
+
+	log.Printf("[API] GET /api/maintenance/data-quality - %d of %d options have issues", response.OptionsWithIssues, len(options))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[API] GET /api/maintenance/data-quality - Failed to encode response: %v", err)
+	}
+}
+
+// dedupeOptionsHandler handles /api/maintenance/dedupe-options. GET reports groups of exact-duplicate
+// options (same symbol, type, opened, strike, expiration, premium and contracts) left by interrupted
+// imports; POST collapses each group to one row, keeping a closed duplicate when there is one.
+func (s *Server) dedupeOptionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[API] %s /api/maintenance/dedupe-options - Failed to get options: %v", r.Method, err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+
+	groups := models.FindDuplicateOptions(options)
+	response := DedupeOptionsResponse{
+		OptionsChecked: len(options),
+		Applied:        r.Method == http.MethodPost,
+		Groups:         []models.DuplicateOptionGroup{},
+	}
+	symbols := make(map[string]bool)
+	for _, group := range groups {
+		if response.Applied {
+			if err := s.optionService.CollapseDuplicate(group); err != nil {
+				log.Printf("[API] POST /api/maintenance/dedupe-options - Failed to collapse option %d: %v", group.Keep.ID, err)
+				http.Error(w, "Failed to remove duplicate options", http.StatusInternalServerError)
+				return
+			}
+			symbols[group.Keep.Symbol] = true
+		}
+		response.Groups = append(response.Groups, group)
+		response.Removed += len(group.Removed)
+	}
+	for symbol := range symbols {
+		s.recalculateAdjustedCostBasis(symbol)
+	}
+
+	log.Printf("[API] %s /api/maintenance/dedupe-options - %d duplicate groups, %d rows to remove (applied: %t)", r.Method, len(groups), response.Removed, response.Applied)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[API] %s /api/maintenance/dedupe-options - Failed to encode response: %v", r.Method, err)
+	}
+}
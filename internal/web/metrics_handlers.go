@@ -8,6 +8,7 @@ import (
 	"stonks/internal/models"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // metricsHandler serves the metrics view
@@ -268,6 +269,46 @@ func (s *Server) createMetricsSnapshotHandler(w http.ResponseWriter, r *http.Req
 	log.Printf("[API] POST /api/metrics/snapshot - Successfully created comprehensive snapshot for %d days", days)
 }
 
+// recomputeMetricsHandler handles POST /api/metrics/recompute?date=YYYY-MM-DD
+// It recalculates every snapshot metric for exactly that date and replaces the recorded values,
+// for refreshing one day after correcting trades rather than rebuilding a range.
+func (s *Server) recomputeMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	dateParam := strings.TrimSpace(r.URL.Query().Get("date"))
+	if dateParam == "" {
+		http.Error(w, "date parameter is required (YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	date, err := time.ParseInLocation("2006-01-02", dateParam, s.marketLocation())
+	if err != nil {
+		log.Printf("[API] POST /api/metrics/recompute - Invalid date parameter: %s", dateParam)
+		http.Error(w, "Invalid date format, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if date.Format("2006-01-02") > s.marketToday().Format("2006-01-02") {
+		http.Error(w, "date cannot be in the future", http.StatusBadRequest)
+		return
+	}
+
+	values, err := s.metricService.RecomputeSnapshotForDate(date)
+	if err != nil {
+		log.Printf("[API] POST /api/metrics/recompute - Failed to recompute metrics for %s: %v", dateParam, err)
+		http.Error(w, fmt.Sprintf("Failed to recompute metrics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[API] POST /api/metrics/recompute - Recomputed %d metrics for %s", len(values), dateParam)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.MetricSnapshotPoint{Date: dateParam, Values: values}); err != nil {
+		log.Printf("[API] POST /api/metrics/recompute - Failed to encode response: %v", err)
+	}
+}
+
 // getMetricsChartDataHandler handles GET /api/metrics/chart-data
 func (s *Server) getMetricsChartDataHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[API] GET /api/metrics/chart-data - Start fetching chart data")
@@ -323,3 +364,237 @@ func (s *Server) getMetricsChartDataHandler(w http.ResponseWriter, r *http.Reque
 	log.Printf("[API] GET /api/metrics/chart-data - Successfully returned chart data")
 }
 
+// compareSnapshotModesHandler handles GET /api/metrics/compare-snapshot-modes?days= (1-366, default 30)
+// It computes the snapshot series with and without weekends/holidays so the two can be diffed.
+// Nothing is persisted.
+func (s *Server) compareSnapshotModesHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[API] GET /api/metrics/compare-snapshot-modes - Start comparing snapshot modes")
+
+	if r.Method != http.MethodGet {
+		log.Printf("[API] GET /api/metrics/compare-snapshot-modes - Method not allowed: %s", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := 30
+	if daysParam := r.URL.Query().Get("days"); daysParam != "" {
+		parsed, err := strconv.Atoi(daysParam)
+		if err != nil || parsed <= 0 || parsed > 366 {
+			log.Printf("[API] GET /api/metrics/compare-snapshot-modes - Invalid days parameter: %s", daysParam)
+			http.Error(w, "days must be between 1 and 366", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	allDays, err := s.metricService.PreviewSnapshotSeries(days, false)
+	if err != nil {
+		log.Printf("[API] GET /api/metrics/compare-snapshot-modes - Failed to compute calendar-day series: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to compute metrics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	tradingDays, err := s.metricService.PreviewSnapshotSeries(days, true)
+	if err != nil {
+		log.Printf("[API] GET /api/metrics/compare-snapshot-modes - Failed to compute trading-day series: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to compute metrics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	tradingDates := make(map[string]bool, len(tradingDays))
+	for _, point := range tradingDays {
+		tradingDates[point.Date] = true
+	}
+	excludedDates := []string{}
+	for _, point := range allDays {
+		if !tradingDates[point.Date] {
+			excludedDates = append(excludedDates, point.Date)
+		}
+	}
+
+	log.Printf("[API] GET /api/metrics/compare-snapshot-modes - %d calendar days, %d trading days, %d excluded",
+		len(allDays), len(tradingDays), len(excludedDates))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":           days,
+		"all_days":       allDays,
+		"trading_days":   tradingDays,
+		"excluded_dates": excludedDates,
+	}); err != nil {
+		log.Printf("[API] GET /api/metrics/compare-snapshot-modes - Failed to encode response: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// symbolContributionHandler handles GET /api/metrics/symbol-contribution?symbol=&date=
+// It reports how much one symbol contributes to each snapshot metric on a date (default today in
+// the market timezone) alongside the portfolio total. Nothing is persisted.
+func (s *Server) symbolContributionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("symbol")))
+	if symbol == "" {
+		http.Error(w, "symbol parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	date := time.Now().In(s.marketLocation())
+	if dateParam := r.URL.Query().Get("date"); dateParam != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", dateParam, s.marketLocation())
+		if err != nil {
+			log.Printf("[API] GET /api/metrics/symbol-contribution - Invalid date parameter: %s", dateParam)
+			http.Error(w, "Invalid date format, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		date = parsed
+	}
+
+	contribution, err := s.metricService.CalculateSymbolContribution(symbol, date)
+	if err != nil {
+		log.Printf("[API] GET /api/metrics/symbol-contribution - Failed to calculate contribution for %s: %v", symbol, err)
+		http.Error(w, fmt.Sprintf("Failed to calculate contribution: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	totals, err := s.metricService.CalculateMetricsForDate(date)
+	if err != nil {
+		log.Printf("[API] GET /api/metrics/symbol-contribution - Failed to calculate totals: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to calculate metrics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := SymbolContributionResponse{
+		Symbol: symbol,
+		Date:   date.Format("2006-01-02"),
+	}
+	for _, metricType := range models.SnapshotMetricTypes() {
+		entry := SymbolMetricContribution{
+			Type:  metricType,
+			Value: contribution[metricType],
+			Total: totals[metricType],
+		}
+		if entry.Total != 0 {
+			entry.Percent = entry.Value / entry.Total * 100
+		}
+		response.Contributions = append(response.Contributions, entry)
+	}
+
+	log.Printf("[API] GET /api/metrics/symbol-contribution - Computed contribution for %s on %s", symbol, response.Date)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[API] GET /api/metrics/symbol-contribution - Failed to encode response: %v", err)
+	}
+}
+
+// metricGapsHandler handles GET /api/metrics/gaps?type=&from=&to=
+// It lists the trading days between from (default the metric's first recorded date) and to (default
+// today in the market timezone) with no recorded value of the metric type, using the NYSE calendar.
+func (s *Server) metricGapsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	metricType := models.MetricType(strings.TrimSpace(query.Get("type")))
+	valid := false
+	for _, snapshotType := range models.SnapshotMetricTypes() {
+		if metricType == snapshotType {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		http.Error(w, "type must be a snapshot metric type (e.g. total_value)", http.StatusBadRequest)
+		return
+	}
+
+	today := s.marketToday()
+	to := today
+	if value := strings.TrimSpace(query.Get("to")); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			http.Error(w, "Invalid to date format, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+	var from time.Time
+	if value := strings.TrimSpace(query.Get("from")); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			http.Error(w, "Invalid from date format, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	} else {
+		first, ok, err := s.metricService.FirstRecordedDate(metricType)
+		if err != nil {
+			log.Printf("[API] GET /api/metrics/gaps - Failed to get first %s date: %v", metricType, err)
+			http.Error(w, "Failed to get metric history", http.StatusInternalServerError)
+			return
+		}
+		from = to
+		if ok {
+			from = first
+		}
+	}
+	if from.After(to) {
+		http.Error(w, "from must not be after to", http.StatusBadRequest)
+		return
+	}
+
+	recorded, err := s.metricService.RecordedDates(metricType, from, to)
+	if err != nil {
+		log.Printf("[API] GET /api/metrics/gaps - Failed to get recorded %s dates: %v", metricType, err)
+		http.Error(w, "Failed to get metric history", http.StatusInternalServerError)
+		return
+	}
+
+	response := MetricGapsResponse{
+		Type:   string(metricType),
+		From:   from.Format("2006-01-02"),
+		To:     to.Format("2006-01-02"),
+		Gaps:   []string{},
+		Ranges: []MetricGapRange{},
+	}
+	var current *MetricGapRange
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if !models.IsTradingDay(day) {
+			continue
+		}
+		response.TradingDays++
+		key := day.Format("2006-01-02")
+		if recorded[key] {
+			response.RecordedDays++
+			current = nil
+			continue
+		}
+		response.Gaps = append(response.Gaps, key)
+		if current == nil {
+			response.Ranges = append(response.Ranges, MetricGapRange{From: key})
+			current = &response.Ranges[len(response.Ranges)-1]
+		}
+		current.To = key
+		current.Days++
+	}
+	if len(response.Gaps) > 0 {
+		earliest, _ := time.Parse("2006-01-02", response.Gaps[0])
+		if !earliest.After(today) {
+			response.BackfillDays = optionDaysBetween(earliest, today) + 1
+		}
+	}
+
+	log.Printf("[API] GET /api/metrics/gaps - %s: %d of %d trading days missing between %s and %s", metricType, len(response.Gaps), response.TradingDays, response.From, response.To)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[API] GET /api/metrics/gaps - Failed to encode response: %v", err)
+	}
+}
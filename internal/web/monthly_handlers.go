@@ -38,6 +38,9 @@ func (s *Server) monthlyHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		longPositions = []*models.LongPosition{}
 	}
+	if err := s.longPositionService.AttachExits(longPositions); err != nil {
+		log.Printf("[MONTHLY] Failed to get long position exits: %v", err)
+	}
 
 	// Build monthly data
 	data := s.buildMonthlyData(symbols, options, dividends, longPositions, optionsIndex)
@@ -124,14 +127,13 @@ func (s *Server) buildMonthlyData(symbols []string, options []*models.Option, di
 		}
 	}
 
-	// Process capital gains only from closed long positions (realized gains only)
+	// Process capital gains from each exit event, so partial exits count in the month they were sold (realized gains only)
 	for _, position := range longPositions {
-		if position.Closed != nil {
-			// Calculate profit/loss for closed position
-			profit := (position.GetExitPriceValue() - position.BuyPrice) * float64(position.Shares)
+		for _, exit := range position.ExitEvents() {
+			profit := (exit.Price - position.BuyPrice) * float64(exit.Shares)
 
-			// Get the month from the closed date
-			month := int(position.Closed.Month()) - 1 // 0-11 for array indexing
+			// Get the month from the exit date
+			month := int(exit.Exited.Month()) - 1 // 0-11 for array indexing
 
 			// Aggregate by month and ticker
 			capGainsByMonth[month] += profit
@@ -1,12 +1,17 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
+	"math"
 	"net/http"
+	"sort"
 	"stonks/internal/models"
+	"stonks/internal/polygon"
 	"strconv"
 	"strings"
 	"time"
@@ -24,9 +29,30 @@ func (s *Server) optionsHandler(w http.ResponseWriter, r *http.Request) {
 		log.Printf("[OPTIONS PAGE] Retrieved %d symbols for navigation", len(symbols))
 	}
 
+	// Optional ?account= narrows the summary to a single broker account
+	account := strings.TrimSpace(r.URL.Query().Get("account"))
+
+	// Optional ?sort= overrides the OPTIONS_SUMMARY_SORT default (symbol, profit, exposure or count)
+	sortKey := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("sort")))
+	if sortKey == "" {
+		sortKey = strings.ToLower(s.settingService.GetValueWithDefault("OPTIONS_SUMMARY_SORT", models.OptionsSummarySortSymbol))
+		if !models.IsValidOptionsSummarySort(sortKey) {
+			log.Printf("[OPTIONS PAGE] WARNING: Invalid OPTIONS_SUMMARY_SORT %q, sorting by symbol", sortKey)
+			sortKey = models.OptionsSummarySortSymbol
+		}
+	} else if !models.IsValidOptionsSummarySort(sortKey) {
+		http.Error(w, "Invalid sort; use symbol, profit, exposure or count", http.StatusBadRequest)
+		return
+	}
+
 	// Get options summary by symbol
-	log.Printf("[OPTIONS PAGE] Fetching options summary data")
-	optionsSummary, err := s.optionService.GetOptionsSummaryBySymbol()
+	log.Printf("[OPTIONS PAGE] Fetching options summary data sorted by %s", sortKey)
+	gross, err := s.useGrossReturns(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	optionsSummary, err := s.optionService.GetOptionsSummaryBySymbolSorted(account, sortKey, gross)
 	if err != nil {
 		log.Printf("[OPTIONS PAGE] ERROR: Failed to get options summary: %v", err)
 		optionsSummary = []*models.OptionSummary{}
@@ -43,10 +69,19 @@ func (s *Server) optionsHandler(w http.ResponseWriter, r *http.Request) {
 	} else {
 		log.Printf("[OPTIONS PAGE] Retrieved %d open positions", len(openPositions))
 	}
+	if account != "" {
+		filtered := openPositions[:0]
+		for _, position := range openPositions {
+			if position.Account == account {
+				filtered = append(filtered, position)
+			}
+		}
+		openPositions = filtered
+	}
 
 	// Get summary totals
 	log.Printf("[OPTIONS PAGE] Calculating summary totals")
-	summaryTotals, err := s.optionService.GetOptionsSummaryTotals()
+	summaryTotals, err := s.optionService.GetOptionsSummaryTotalsForAccount(account)
 	if err != nil {
 		log.Printf("[OPTIONS PAGE] ERROR: Failed to get summary totals: %v", err)
 		summaryTotals = &models.OptionSummary{}
@@ -60,6 +95,8 @@ func (s *Server) optionsHandler(w http.ResponseWriter, r *http.Request) {
 		OptionsSummary: optionsSummary,
 		OpenPositions:  openPositions,
 		SummaryTotals:  summaryTotals,
+		SummarySort:    sortKey,
+		ReturnsBasis:   returnsBasisName(gross),
 		CurrentDB:      s.getCurrentDatabaseName(),
 		ActivePage:     "options",
 	}
@@ -143,6 +180,7 @@ func (s *Server) addOptionHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid expiration date", http.StatusBadRequest)
 		return
 	}
+	expiration = s.normalizeExpiration(expiration)
 
 	premium, err := strconv.ParseFloat(premiumStr, 64)
 	if err != nil {
@@ -156,11 +194,17 @@ func (s *Server) addOptionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = s.optionService.Create(symbol, optionType, time.Now(), strike, expiration, premium, contracts)
+	var option *models.Option
+	if profile, ok := s.brokerProfile(); ok {
+		option, err = s.optionService.CreateWithCommission(symbol, optionType, time.Now(), strike, expiration, premium, contracts, profile.OpeningCommission(contracts))
+	} else {
+		option, err = s.optionService.Create(symbol, optionType, time.Now(), strike, expiration, premium, contracts)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	s.captureUnderlyingPrices(option.ID)
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -200,7 +244,8 @@ func (s *Server) individualOptionAPIHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	optionID, err := strconv.Atoi(path)
+	idPart, action, _ := strings.Cut(path, "/")
+	optionID, err := strconv.Atoi(idPart)
 	if err != nil {
 		log.Printf("[INDIVIDUAL OPTION API] ERROR: Invalid option ID: %s", path)
 		http.Error(w, "Invalid option ID", http.StatusBadRequest)
@@ -217,9 +262,41 @@ func (s *Server) individualOptionAPIHandler(w http.ResponseWriter, r *http.Reque
 
 	log.Printf("[INDIVIDUAL OPTION API] Successfully retrieved option: %d", optionID)
 
+	if action == "roll-suggestions" {
+		s.optionRollSuggestionsHandler(w, r, option)
+		return
+	} else if action == "contract-details" {
+		s.optionContractDetailsHandler(w, r, option)
+		return
+	} else if action == "coverage" {
+		s.optionCoverageHandler(w, r, option)
+		return
+	} else if action == "timeline" {
+		s.optionTimelineHandler(w, r, option)
+		return
+	} else if action != "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	var underlyingPrice float64
+	if symbol, err := s.symbolService.GetBySymbol(option.Symbol); err == nil {
+		underlyingPrice = symbol.Price
+	}
+	gross, err := s.useGrossReturns(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response := newOptionWithPerformance(option, gross, s.annualizationBasis(), underlyingPrice, s.percentDecimals())
+	if includesField(r, "attribution") {
+		attribution := option.CalculateAttribution()
+		response.Attribution = &attribution
+	}
+
 	// Return option data as JSON
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(option); err != nil {
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		log.Printf("[INDIVIDUAL OPTION API] ERROR: Failed to encode option to JSON: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -248,6 +325,28 @@ func (s *Server) createOption(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Contracts <= 0 {
+		http.Error(w, "Contracts must be positive", http.StatusBadRequest)
+		return
+	}
+
+	if req.Fees != nil && *req.Fees < 0 {
+		http.Error(w, "Fees cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	if req.Status != nil {
+		if _, err := models.NormalizeOptionStatus(*req.Status); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := validateOptionCurrency(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Parse dates
 	opened, err := time.Parse("2006-01-02", req.Opened)
 	if err != nil {
@@ -260,14 +359,63 @@ func (s *Server) createOption(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid expiration date format", http.StatusBadRequest)
 		return
 	}
+	expiration = s.normalizeExpiration(expiration)
+	if s.rejectFutureOpened(w, opened) {
+		return
+	}
+
+	// An explicit commission always wins; otherwise the broker profile's opening schedule applies
+	var commission float64
+	profile, hasProfile := s.brokerProfile()
+	if req.Commission != nil {
+		commission = *req.Commission
+	} else if hasProfile {
+		commission = profile.OpeningCommission(req.Contracts)
+	}
+
+	var account string
+	if req.Account != nil {
+		account = *req.Account
+	}
 
 	// Create the option
-	option, err := s.optionService.CreateWithCommission(req.Symbol, req.Type, opened, req.Strike, expiration, req.Premium, req.Contracts, req.Commission)
+	option, err := s.optionService.CreateInAccount(req.Symbol, req.Type, opened, req.Strike, expiration, req.Premium, req.Contracts, commission, account)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to create option: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	if req.Strategy != nil {
+		if err := s.optionService.SetStrategy(option.ID, *req.Strategy); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to set option strategy: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if strategy := models.NormalizeStrategy(*req.Strategy); strategy != "" {
+			option.Strategy = &strategy
+		} else {
+			option.Strategy = nil
+		}
+	}
+	if req.Currency != nil || req.FXRate != nil {
+		if !s.applyOptionCurrency(w, option, req) {
+			return
+		}
+	}
+	if req.Status != nil {
+		if err := s.optionService.SetStatus(option.ID, *req.Status); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to set option status: %v", err), http.StatusInternalServerError)
+			return
+		}
+		option.Status, _ = models.NormalizeOptionStatus(*req.Status)
+	}
+	if req.Fees != nil {
+		if err := s.optionService.SetFees(option.ID, *req.Fees); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to set option fees: %v", err), http.StatusInternalServerError)
+			return
+		}
+		option.Fees = *req.Fees
+	}
+
 	// If closed date and exit price are provided, close the option immediately
 	if req.Closed != nil && *req.Closed != "" {
 		closed, err := time.Parse("2006-01-02", *req.Closed)
@@ -281,13 +429,20 @@ func (s *Server) createOption(w http.ResponseWriter, r *http.Request) {
 			exitPrice = *req.ExitPrice
 		}
 
-		err = s.optionService.Close(req.Symbol, req.Type, opened, req.Strike, expiration, req.Premium, req.Contracts, closed, exitPrice)
+		if hasProfile {
+			err = s.optionService.CloseByIDWithCommission(option.ID, closed, exitPrice, profile.ClosingCommission(req.Contracts))
+		} else {
+			err = s.optionService.CloseByID(option.ID, closed, exitPrice)
+		}
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Failed to close option: %v", err), http.StatusInternalServerError)
 			return
 		}
 	}
 	s.recalculateAdjustedCostBasis(req.Symbol)
+	if captured := s.captureUnderlyingPrices(option.ID); captured != nil {
+		option.UnderlyingAtOpen, option.UnderlyingAtClose = captured.UnderlyingAtOpen, captured.UnderlyingAtClose
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(option)
@@ -318,6 +473,28 @@ func (s *Server) updateOption(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Contracts <= 0 {
+		http.Error(w, "Contracts must be positive", http.StatusBadRequest)
+		return
+	}
+
+	if req.Fees != nil && *req.Fees < 0 {
+		http.Error(w, "Fees cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	if req.Status != nil {
+		if _, err := models.NormalizeOptionStatus(*req.Status); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := validateOptionCurrency(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Parse dates
 	opened, err := time.Parse("2006-01-02", req.Opened)
 	if err != nil {
@@ -330,6 +507,10 @@ func (s *Server) updateOption(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid expiration date format", http.StatusBadRequest)
 		return
 	}
+	expiration = s.normalizeExpiration(expiration)
+	if s.rejectFutureOpened(w, opened) {
+		return
+	}
 
 	// Parse closed date if provided
 	var closed *time.Time
@@ -342,13 +523,68 @@ func (s *Server) updateOption(w http.ResponseWriter, r *http.Request) {
 		closed = &closedDate
 	}
 
-	// Update the option
-	option, err := s.optionService.UpdateByID(*req.ID, req.Symbol, req.Type, opened, req.Strike, expiration, req.Premium, req.Contracts, req.Commission, closed, req.ExitPrice)
+	var commission float64
+	if req.Commission != nil {
+		commission = *req.Commission
+	}
+
+	// Update the option, moving it to the requested account when one is given
+	option, err := s.optionService.UpdateByIDInAccount(*req.ID, req.Symbol, req.Type, opened, req.Strike, expiration, req.Premium, req.Contracts, commission, closed, req.ExitPrice, req.Account)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to update option: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if req.Strategy != nil {
+		if err := s.optionService.SetStrategy(option.ID, *req.Strategy); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to set option strategy: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if strategy := models.NormalizeStrategy(*req.Strategy); strategy != "" {
+			option.Strategy = &strategy
+		} else {
+			option.Strategy = nil
+		}
+	}
+	if req.Currency != nil || req.FXRate != nil {
+		if !s.applyOptionCurrency(w, option, req) {
+			return
+		}
+	}
+	if req.Status != nil {
+		if err := s.optionService.SetStatus(option.ID, *req.Status); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to set option status: %v", err), http.StatusInternalServerError)
+			return
+		}
+		option.Status, _ = models.NormalizeOptionStatus(*req.Status)
+	}
+	if req.Fees != nil {
+		if err := s.optionService.SetFees(option.ID, *req.Fees); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to set option fees: %v", err), http.StatusInternalServerError)
+			return
+		}
+		option.Fees = *req.Fees
+	}
+	if req.Assigned != nil {
+		if *req.Assigned && option.Closed == nil {
+			http.Error(w, "Only closed options can be marked assigned", http.StatusBadRequest)
+			return
+		}
+		if err := s.optionService.SetAssignment(option.ID, *req.Assigned, req.AssignedPositionID); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to set option assignment: %v", err), http.StatusInternalServerError)
+			return
+		}
+		option.Assigned = *req.Assigned
+		option.AssignedPositionID = nil
+		if option.Assigned {
+			option.AssignedPositionID = req.AssignedPositionID
+		}
+	}
 	s.recalculateAdjustedCostBasis(req.Symbol)
+	if option.NeedsUnderlyingPrices() {
+		if captured := s.captureUnderlyingPrices(option.ID); captured != nil {
+			option.UnderlyingAtOpen, option.UnderlyingAtClose = captured.UnderlyingAtOpen, captured.UnderlyingAtClose
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(option)
@@ -402,17 +638,25 @@ func (s *Server) deleteOption(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		log.Printf("[DELETE OPTION] Attempting compound key deletion: Symbol=%s, Type=%s, Opened=%s, Strike=%f, Expiration=%s",
-			req.Symbol, req.Type, req.Opened, req.Strike, req.Expiration)
+		log.Printf("[DELETE OPTION] Attempting compound key deletion: Symbol=%s, Type=%s, Opened=%s, Strike=%f, Expiration=%s, Account=%v",
+			req.Symbol, req.Type, req.Opened, req.Strike, req.Expiration, req.Account)
 
-		// Delete the option using compound key
-		err = s.optionService.Delete(req.Symbol, req.Type, opened, req.Strike, expiration, req.Premium, req.Contracts)
+		// Delete the option using compound key within its account; several matches need match_all
+		account := models.DefaultAccount
+		if req.Account != nil {
+			account = models.NormalizeAccount(*req.Account)
+		}
+		deleted, err := s.optionService.Delete(req.Symbol, req.Type, opened, req.Strike, expiration, req.Premium, req.Contracts, account, req.MatchAll)
 		if err != nil {
 			log.Printf("[DELETE OPTION] ERROR: Compound key deletion failed: %v", err)
-			http.Error(w, fmt.Sprintf("Failed to delete option: %v", err), http.StatusInternalServerError)
+			status := http.StatusInternalServerError
+			if errors.Is(err, models.ErrAmbiguousOptionKey) {
+				status = http.StatusConflict
+			}
+			http.Error(w, fmt.Sprintf("Failed to delete option: %v", err), status)
 			return
 		}
-		log.Printf("[DELETE OPTION] Successfully deleted option using compound key")
+		log.Printf("[DELETE OPTION] Successfully deleted %d option(s) using compound key", deleted)
 	}
 	s.recalculateAdjustedCostBasis(req.Symbol)
 	log.Printf("[DELETE OPTION] Sending success response")
@@ -425,7 +669,8 @@ func (s *Server) deleteOption(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[DELETE OPTION] Request completed successfully")
 }
 
-// optionsFilterHandler handles filtered queries on the options index
+// optionsFilterHandler handles filtered queries on the options index. Each result keeps the raw
+// option fields and adds derived metrics (exposure, net premium, DTE, profit and returns).
 func (s *Server) optionsFilterHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[OPTIONS FILTER API] %s %s - Processing options filter request", r.Method, r.URL.Path)
 
@@ -456,9 +701,28 @@ func (s *Server) optionsFilterHandler(w http.ResponseWriter, r *http.Request) {
 	filteredOptions := models.GetByFilters(optionsIndex, filters)
 	log.Printf("[OPTIONS FILTER API] Filtered results: %d options", len(filteredOptions))
 
+	underlyingPrices := make(map[string]float64)
+	if symbols, err := s.symbolService.GetAll(); err == nil {
+		for _, symbol := range symbols {
+			underlyingPrices[symbol.Symbol] = symbol.Price
+		}
+	}
+
+	gross, err := s.useGrossReturns(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	annualization := s.annualizationBasis()
+	decimals := s.percentDecimals()
+	results := make([]OptionWithPerformance, 0, len(filteredOptions))
+	for _, option := range filteredOptions {
+		results = append(results, newOptionWithPerformance(option, gross, annualization, underlyingPrices[option.Symbol], decimals))
+	}
+
 	// Return filtered results
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(filteredOptions); err != nil {
+	if err := json.NewEncoder(w).Encode(results); err != nil {
 		log.Printf("[OPTIONS FILTER API] ERROR: Failed to encode options to JSON: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -466,3 +730,1646 @@ func (s *Server) optionsFilterHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("[OPTIONS FILTER API] Successfully returned %d filtered options", len(filteredOptions))
 }
+
+// optionAssignHandler handles POST /api/options/assign, closing an open put as assigned and
+// opening the resulting long position
+func (s *Server) optionAssignHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[OPTION ASSIGN API] %s %s - Processing assignment request", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req OptionAssignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[OPTION ASSIGN API] ERROR: Invalid JSON payload: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	option, err := s.optionService.GetByID(req.OptionID)
+	if err != nil {
+		log.Printf("[OPTION ASSIGN API] ERROR: Failed to get option %d: %v", req.OptionID, err)
+		http.Error(w, "Option not found", http.StatusNotFound)
+		return
+	}
+
+	assigned := option.Expiration
+	if req.Assigned != "" {
+		assigned, err = time.Parse("2006-01-02", req.Assigned)
+		if err != nil {
+			http.Error(w, "Invalid assigned date format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = s.settingService.GetValueWithDefault("ASSIGNMENT_BUY_PRICE_MODE", models.AssignmentBuyPriceStrike)
+	}
+
+	position, err := s.longPositionService.AssignPut(option.ID, assigned, mode)
+	if err != nil {
+		log.Printf("[OPTION ASSIGN API] ERROR: Failed to assign option %d: %v", option.ID, err)
+		http.Error(w, fmt.Sprintf("Failed to assign option: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("[OPTION ASSIGN API] Assigned option %d into long position %d (%s mode, buy price $%.2f)", option.ID, position.ID, mode, position.BuyPrice)
+	s.captureUnderlyingPrices(option.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(position)
+}
+
+// optionsBlendedBreakevenHandler handles GET /api/options/blended-breakeven?symbol= returning the
+// share-weighted acquisition cost, net of premium, if every open put on the symbol is assigned
+func (s *Server) optionsBlendedBreakevenHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[OPTIONS BREAKEVEN API] %s %s - Processing blended breakeven request", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("symbol")))
+	if symbol == "" {
+		http.Error(w, "Symbol is required", http.StatusBadRequest)
+		return
+	}
+
+	puts, err := s.optionService.GetOpenByType(symbol, "Put")
+	if err != nil {
+		log.Printf("[OPTIONS BREAKEVEN API] ERROR: Failed to get open puts for %s: %v", symbol, err)
+		http.Error(w, "Failed to get open options", http.StatusInternalServerError)
+		return
+	}
+
+	response := BlendedBreakevenResponse{
+		Symbol: symbol,
+		Legs:   []BlendedBreakevenLeg{},
+	}
+	if symbolData, err := s.symbolService.GetBySymbol(symbol); err == nil {
+		response.CurrentPrice = symbolData.Price
+	}
+
+	for _, put := range puts {
+		shares := put.Contracts * 100
+		response.Contracts += put.Contracts
+		response.Shares += shares
+		response.AssignmentCost += put.Strike * float64(shares)
+		response.TotalPremium += put.Premium*float64(shares) - put.TransactionCosts()
+		response.Legs = append(response.Legs, BlendedBreakevenLeg{
+			ID:         put.ID,
+			Strike:     put.Strike,
+			Expiration: put.Expiration.Format("2006-01-02"),
+			Contracts:  put.Contracts,
+			Premium:    put.Premium,
+			BreakEven:  put.CalculateBreakEven(),
+		})
+	}
+
+	if response.Shares > 0 {
+		response.WeightedAverageStrike = response.AssignmentCost / float64(response.Shares)
+		response.BlendedBreakeven = (response.AssignmentCost - response.TotalPremium) / float64(response.Shares)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[OPTIONS BREAKEVEN API] ERROR: Failed to encode response: %v", err)
+	}
+}
+
+// optionsPremiumYieldHandler handles GET /api/options/premium-yield?symbol= returning each open put's
+// premium collected as a percent of its collateral (strike x contracts x 100), annualized over the
+// put's DTE (opened to expiration) on the ANNUALIZATION_BASIS so short- and long-dated puts compare fairly
+func (s *Server) optionsPremiumYieldHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[OPTIONS YIELD API] %s %s - Processing premium yield request", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("symbol")))
+	puts, err := s.optionService.GetOpenByType(symbol, "Put")
+	if err != nil {
+		log.Printf("[OPTIONS YIELD API] ERROR: Failed to get open puts: %v", err)
+		http.Error(w, "Failed to get open options", http.StatusInternalServerError)
+		return
+	}
+
+	annualization := s.annualizationBasis()
+	response := PremiumYieldResponse{
+		Annualization: annualization,
+		DaysPerYear:   models.DaysPerYear(annualization),
+		Puts:          []PremiumYieldEntry{},
+	}
+	var weightedYield float64
+	for _, put := range puts {
+		collateral := put.CalculateExposure()
+		if collateral <= 0 {
+			continue
+		}
+		dte := put.CalculateDTE()
+		if dte < 1 {
+			dte = 1 // same-day expirations annualize over one day
+		}
+		premium := put.Premium * float64(put.Contracts) * 100
+		entry := PremiumYieldEntry{
+			ID:               put.ID,
+			Symbol:           put.Symbol,
+			Strike:           put.Strike,
+			Expiration:       put.Expiration.Format("2006-01-02"),
+			Contracts:        put.Contracts,
+			DTE:              dte,
+			DaysRemaining:    put.CalculateDaysRemaining(),
+			Collateral:       collateral,
+			PremiumCollected: premium,
+			NetPremium:       premium - put.TransactionCosts(),
+			PremiumYield:     premium / collateral * 100,
+			NetPremiumYield:  (premium - put.TransactionCosts()) / collateral * 100,
+		}
+		factor := models.AnnualizationFactor(annualization, put.Opened, put.Expiration, float64(dte))
+		entry.AnnualizedPremiumYield = entry.PremiumYield * factor
+		entry.AnnualizedNetYield = entry.NetPremiumYield * factor
+
+		response.TotalCollateral += collateral
+		response.TotalPremium += premium
+		weightedYield += entry.AnnualizedPremiumYield * collateral
+		response.Puts = append(response.Puts, entry)
+	}
+	if response.TotalCollateral > 0 {
+		response.WeightedAnnualizedYield = weightedYield / response.TotalCollateral
+	}
+
+	sort.SliceStable(response.Puts, func(i, j int) bool {
+		return response.Puts[i].AnnualizedPremiumYield > response.Puts[j].AnnualizedPremiumYield
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[OPTIONS YIELD API] ERROR: Failed to encode response: %v", err)
+	}
+}
+
+// optionsCoveredCallSimHandler handles GET /api/options/covered-call-sim?symbol=&strike=&premium=&expiration=&contracts=,
+// simulating a covered call written against the symbol's held shares. Covered shares are taken FIFO
+// from open lots after the shares already backing open calls, and both scenarios are measured
+// against those shares' adjusted cost basis: the premium alone if the call expires, and premium
+// plus the gain (or loss) to the strike if the shares are called away. Returns are annualized over
+// the days from today to expiration on the ANNUALIZATION_BASIS. contracts defaults to 1.
+func (s *Server) optionsCoveredCallSimHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[OPTIONS CALL SIM API] %s %s - Simulating covered call", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	symbol := strings.ToUpper(strings.TrimSpace(query.Get("symbol")))
+	if symbol == "" {
+		http.Error(w, "symbol is required", http.StatusBadRequest)
+		return
+	}
+	strike, err := strconv.ParseFloat(strings.TrimSpace(query.Get("strike")), 64)
+	if err != nil || strike <= 0 {
+		http.Error(w, "strike must be a positive number", http.StatusBadRequest)
+		return
+	}
+	premium, err := strconv.ParseFloat(strings.TrimSpace(query.Get("premium")), 64)
+	if err != nil || premium < 0 {
+		http.Error(w, "premium must be a non-negative per-share amount", http.StatusBadRequest)
+		return
+	}
+	expiration, err := time.Parse("2006-01-02", strings.TrimSpace(query.Get("expiration")))
+	if err != nil {
+		http.Error(w, "Invalid expiration date format (use YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	today := s.marketToday()
+	if expiration.Before(today) {
+		http.Error(w, "expiration is in the past", http.StatusBadRequest)
+		return
+	}
+	contracts := 1
+	if value := strings.TrimSpace(query.Get("contracts")); value != "" {
+		contracts, err = strconv.Atoi(value)
+		if err != nil || contracts < 1 {
+			http.Error(w, "contracts must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	positions, err := s.longPositionService.GetBySymbol(symbol)
+	if err != nil {
+		log.Printf("[OPTIONS CALL SIM API] ERROR: Failed to get long positions for %s: %v", symbol, err)
+		http.Error(w, "Failed to get long positions", http.StatusInternalServerError)
+		return
+	}
+	if err := s.longPositionService.AttachExits(positions); err != nil {
+		log.Printf("[OPTIONS CALL SIM API] ERROR: Failed to load exits for %s: %v", symbol, err)
+		http.Error(w, "Failed to get long positions", http.StatusInternalServerError)
+		return
+	}
+	calls, err := s.optionService.GetOpenByType(symbol, "Call")
+	if err != nil {
+		log.Printf("[OPTIONS CALL SIM API] ERROR: Failed to get open calls for %s: %v", symbol, err)
+		http.Error(w, "Failed to get open options", http.StatusInternalServerError)
+		return
+	}
+
+	// Oldest lots first; pending lots are not held yet and cannot cover a call
+	lots := make([]*models.LongPosition, 0, len(positions))
+	for _, position := range positions {
+		if position.RemainingShares() > 0 && !position.Opened.After(today) {
+			lots = append(lots, position)
+		}
+	}
+	sort.SliceStable(lots, func(i, j int) bool { return lots[i].Opened.Before(lots[j].Opened) })
+
+	response := CoveredCallSimResponse{
+		Symbol:        symbol,
+		Strike:        strike,
+		Premium:       premium,
+		Expiration:    expiration.Format("2006-01-02"),
+		Contracts:     contracts,
+		Shares:        contracts * 100,
+		Annualization: s.annualizationBasis(),
+		Lots:          []CoveredCallSimLot{},
+	}
+	response.DaysPerYear = models.DaysPerYear(response.Annualization)
+	for _, lot := range lots {
+		response.HeldShares += lot.RemainingShares()
+	}
+	for _, call := range calls {
+		response.CommittedShares += call.Contracts * 100
+	}
+	response.AvailableShares = max(response.HeldShares-response.CommittedShares, 0)
+	if response.AvailableShares < response.Shares {
+		http.Error(w, fmt.Sprintf("%d contract(s) need %d shares but only %d of %d held %s shares are not already covered by open calls",
+			contracts, response.Shares, response.AvailableShares, response.HeldShares, symbol), http.StatusBadRequest)
+		return
+	}
+
+	skip, need := response.CommittedShares, response.Shares
+	for _, lot := range lots {
+		if need == 0 {
+			break
+		}
+		free := lot.RemainingShares()
+		if skip >= free {
+			skip -= free
+			continue
+		}
+		free -= skip
+		skip = 0
+		covered := min(free, need)
+		need -= covered
+		basis := lot.CostBasisPerShare()
+		response.CostBasisTotal += basis * float64(covered)
+		response.Lots = append(response.Lots, CoveredCallSimLot{
+			PositionID:        lot.ID,
+			Opened:            lot.Opened.Format("2006-01-02"),
+			SharesCovered:     covered,
+			CostBasisPerShare: basis,
+		})
+	}
+	shares := float64(response.Shares)
+	response.CostBasisPerShare = response.CostBasisTotal / shares
+
+	response.DTE = int(expiration.Sub(today).Hours() / 24)
+	days := max(response.DTE, 1) // same-day expirations annualize over one day
+	factor := models.AnnualizationFactor(response.Annualization, today, expiration, float64(days))
+
+	response.IfExpires.Income = premium * shares
+	response.IfExpires.BreakEven = response.CostBasisPerShare - premium
+	response.IfCalled.Income = premium * shares
+	response.IfCalled.CapitalGain = (strike - response.CostBasisPerShare) * shares
+	response.IfCalled.TotalReturn = response.IfCalled.Income + response.IfCalled.CapitalGain
+	response.IfCalled.EffectiveSalePrice = strike + premium
+	if response.CostBasisTotal > 0 {
+		response.IfExpires.ReturnPercent = response.IfExpires.Income / response.CostBasisTotal * 100
+		response.IfExpires.AnnualizedReturn = response.IfExpires.ReturnPercent * factor
+		response.IfCalled.ReturnPercent = response.IfCalled.TotalReturn / response.CostBasisTotal * 100
+		response.IfCalled.AnnualizedReturn = response.IfCalled.ReturnPercent * factor
+	}
+	if symbolData, err := s.symbolService.GetBySymbol(symbol); err == nil && symbolData.Price > 0 {
+		response.CurrentPrice = symbolData.Price
+		response.IfCalled.UpsideCapped = max(symbolData.Price-strike, 0) * shares
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[OPTIONS CALL SIM API] ERROR: Failed to encode response: %v", err)
+	}
+}
+
+// optionsTheoreticalHandler handles GET /api/options/theoretical?symbol=&strike=&expiration=&type=&underlying=&iv=,
+// pricing a hypothetical contract with Black-Scholes at RISK_FREE_RATE_PERCENT. underlying defaults to
+// the symbol's stored price and iv (annual decimal, e.g. 0.35) to the contract's Polygon snapshot or
+// the nearest listed contract in the chain. Expiration is 16:00 market time; a contract past that
+// on its expiration day is priced at intrinsic value.
+func (s *Server) optionsTheoreticalHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[OPTIONS THEORETICAL API] %s %s - Pricing hypothetical contract", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	symbol := strings.ToUpper(strings.TrimSpace(query.Get("symbol")))
+	if symbol == "" {
+		http.Error(w, "symbol is required", http.StatusBadRequest)
+		return
+	}
+	var optionType string
+	switch strings.ToLower(strings.TrimSpace(query.Get("type"))) {
+	case "put":
+		optionType = "Put"
+	case "call":
+		optionType = "Call"
+	default:
+		http.Error(w, "type must be put or call", http.StatusBadRequest)
+		return
+	}
+	strike, err := strconv.ParseFloat(strings.TrimSpace(query.Get("strike")), 64)
+	if err != nil || strike <= 0 {
+		http.Error(w, "strike must be a positive number", http.StatusBadRequest)
+		return
+	}
+	expiration, err := time.Parse("2006-01-02", strings.TrimSpace(query.Get("expiration")))
+	if err != nil {
+		http.Error(w, "Invalid expiration date format (use YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+	if expiration.Before(s.marketToday()) {
+		http.Error(w, "expiration is in the past", http.StatusBadRequest)
+		return
+	}
+
+	var underlying float64
+	if value := strings.TrimSpace(query.Get("underlying")); value != "" {
+		underlying, err = strconv.ParseFloat(value, 64)
+		if err != nil || underlying <= 0 {
+			http.Error(w, "underlying must be a positive number", http.StatusBadRequest)
+			return
+		}
+	} else if symbolData, err := s.symbolService.GetBySymbol(symbol); err == nil && symbolData.Price > 0 {
+		underlying = symbolData.Price
+	}
+
+	var iv float64
+	ivSource := "request"
+	if value := strings.TrimSpace(query.Get("iv")); value != "" {
+		iv, err = strconv.ParseFloat(value, 64)
+		if err != nil || iv <= 0 || iv > 10 {
+			http.Error(w, "iv must be an annual decimal between 0 and 10 (e.g. 0.35)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	contract := &models.Option{Symbol: symbol, Type: optionType, Strike: strike, Expiration: expiration, Contracts: 1}
+	if iv == 0 || underlying == 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+		defer cancel()
+		snapshotIV, snapshotUnderlying, source := s.defaultImpliedVol(ctx, contract)
+		if iv == 0 {
+			iv, ivSource = snapshotIV, source
+		}
+		if underlying == 0 {
+			underlying = snapshotUnderlying
+		}
+	}
+	if underlying <= 0 {
+		http.Error(w, "underlying is required: no stored or quoted price for "+symbol, http.StatusBadRequest)
+		return
+	}
+	if iv <= 0 {
+		http.Error(w, "iv is required: no implied volatility available from Polygon for "+symbol, http.StatusBadRequest)
+		return
+	}
+
+	location := s.marketLocation()
+	expiresAt := time.Date(expiration.Year(), expiration.Month(), expiration.Day(), 16, 0, 0, 0, location)
+	years := expiresAt.Sub(time.Now().In(location)).Hours() / (24 * 365)
+	if years < 0 {
+		years = 0
+	}
+	riskFree := s.settingService.GetFloatWithDefault("RISK_FREE_RATE_PERCENT", 5)
+
+	value := models.BlackScholes(optionType, underlying, strike, years, iv, riskFree/100)
+	response := TheoreticalValueResponse{
+		TheoreticalValue: value,
+		Symbol:           symbol,
+		Type:             optionType,
+		Strike:           strike,
+		Expiration:       expiration.Format("2006-01-02"),
+		Underlying:       underlying,
+		ImpliedVol:       iv,
+		IVSource:         ivSource,
+		RiskFreeRate:     riskFree,
+		YearsToExpiry:    years,
+		Expired:          years == 0,
+		ContractPremium:  value.Price * 100,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// defaultImpliedVol looks up an implied volatility for a contract: its own Polygon snapshot, then the
+// same-type chain contract nearest in expiration and strike. It also returns the underlying price
+// Polygon reported alongside it, or 0 when none was found.
+func (s *Server) defaultImpliedVol(ctx context.Context, contract *models.Option) (float64, float64, string) {
+	if _, snapshot, err := s.polygonService.GetRawOptionSnapshot(ctx, contract); err == nil && snapshot != nil && snapshot.Results.ImpliedVolatility > 0 {
+		return snapshot.Results.ImpliedVolatility, snapshot.Results.UnderlyingAsset.Price, "snapshot"
+	} else if err != nil {
+		log.Printf("[OPTIONS THEORETICAL API] No snapshot for %s, trying chain: %v", polygon.OptionContractSymbol(contract), err)
+	}
+
+	chain, err := s.polygonService.GetOptionChain(ctx, contract.Symbol, polygon.ChainQuery{
+		ContractType:   contract.Type,
+		ExpirationFrom: contract.Expiration.AddDate(0, 0, -14),
+		ExpirationTo:   contract.Expiration.AddDate(0, 0, 14),
+	})
+	if err != nil {
+		log.Printf("[OPTIONS THEORETICAL API] Failed to fetch chain for %s: %v", contract.Symbol, err)
+		return 0, 0, ""
+	}
+
+	var best *polygon.ChainContract
+	var bestDays int
+	var bestStrike float64
+	for i := range chain {
+		candidate := &chain[i]
+		expiration, err := candidate.Expiration()
+		if err != nil || candidate.ImpliedVolatility <= 0 {
+			continue
+		}
+		days := optionDaysBetween(contract.Expiration, expiration)
+		if days < 0 {
+			days = -days
+		}
+		strikeGap := math.Abs(candidate.Details.StrikePrice - contract.Strike)
+		if best == nil || days < bestDays || (days == bestDays && strikeGap < bestStrike) {
+			best, bestDays, bestStrike = candidate, days, strikeGap
+		}
+	}
+	if best == nil {
+		return 0, 0, ""
+	}
+	return best.ImpliedVolatility, best.UnderlyingAsset.Price, "chain"
+}
+
+// optionsLadderHandler handles GET /api/options/ladder?symbol= returning open options grouped by strike
+func (s *Server) optionsLadderHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[OPTIONS LADDER API] %s %s - Processing options ladder request", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		log.Printf("[OPTIONS LADDER API] ERROR: Method not allowed: %s", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("symbol")))
+	if symbol == "" {
+		http.Error(w, "Symbol is required", http.StatusBadRequest)
+		return
+	}
+
+	var currentPrice float64
+	if symbolData, err := s.symbolService.GetBySymbol(symbol); err == nil {
+		currentPrice = symbolData.Price
+	} else {
+		log.Printf("[OPTIONS LADDER API] WARNING: No symbol data for %s: %v", symbol, err)
+	}
+
+	response := OptionLadderResponse{
+		Symbol:       symbol,
+		CurrentPrice: currentPrice,
+		Rungs:        []OptionLadderRung{},
+	}
+
+	for _, optionType := range []string{"Put", "Call"} {
+		options, err := s.optionService.GetOpenByType(symbol, optionType)
+		if err != nil {
+			log.Printf("[OPTIONS LADDER API] ERROR: Failed to get open %s options for %s: %v", optionType, symbol, err)
+			http.Error(w, "Failed to get open options", http.StatusInternalServerError)
+			return
+		}
+
+		// Options arrive sorted by strike, so consecutive rows share a rung
+		for _, option := range options {
+			last := len(response.Rungs) - 1
+			if last < 0 || response.Rungs[last].Type != optionType || response.Rungs[last].Strike != option.Strike {
+				response.Rungs = append(response.Rungs, OptionLadderRung{
+					Strike:    option.Strike,
+					Type:      optionType,
+					Moneyness: ladderMoneyness(optionType, option.Strike, currentPrice),
+				})
+				last++
+			}
+			rung := &response.Rungs[last]
+			rung.Contracts += option.Contracts
+			rung.TotalPremium += option.Premium * float64(option.Contracts) * 100
+			rung.Options = append(rung.Options, option)
+		}
+	}
+
+	log.Printf("[OPTIONS LADDER API] Built %d strike rungs for %s", len(response.Rungs), symbol)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[OPTIONS LADDER API] ERROR: Failed to encode ladder to JSON: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ladderMoneyness classifies a strike relative to the underlying price
+func ladderMoneyness(optionType string, strike, price float64) string {
+	if price <= 0 {
+		return "unknown"
+	}
+	if strike == price {
+		return "ATM"
+	}
+	itm := (optionType == "Put" && price < strike) || (optionType == "Call" && price > strike)
+	if itm {
+		return "ITM"
+	}
+	return "OTM"
+}
+
+// optionsExpiringWeekHandler handles GET /api/options/expiring-week?week=YYYY-Www&band=2
+// Each open option expiring that week gets a suggested action: let expire when OTM beyond the
+// moneyness band, roll when the price is within the band of the strike, and prepare for
+// assignment when ITM beyond it. The band defaults to the EXPIRING_MONEYNESS_BAND_PERCENT setting.
+// Roll suggestions carry the best net credit for rolling out when the chain offers one.
+func (s *Server) optionsExpiringWeekHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[OPTIONS EXPIRING API] %s %s - Processing expiring week request", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var weekStart time.Time
+	if week := strings.TrimSpace(r.URL.Query().Get("week")); week != "" {
+		parsed, err := parseISOWeek(week)
+		if err != nil {
+			log.Printf("[OPTIONS EXPIRING API] ERROR: Invalid week %q: %v", week, err)
+			http.Error(w, "Invalid week (expected YYYY-Www)", http.StatusBadRequest)
+			return
+		}
+		weekStart = parsed
+	} else {
+		year, week := time.Now().ISOWeek()
+		weekStart = isoWeekStart(year, week)
+	}
+	weekEnd := weekStart.AddDate(0, 0, 6)
+
+	band := s.settingService.GetFloatWithDefault("EXPIRING_MONEYNESS_BAND_PERCENT", 2)
+	if bandStr := r.URL.Query().Get("band"); bandStr != "" {
+		parsed, err := strconv.ParseFloat(bandStr, 64)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid band", http.StatusBadRequest)
+			return
+		}
+		band = parsed
+	}
+
+	openOptions, err := s.optionService.GetOpen()
+	if err != nil {
+		log.Printf("[OPTIONS EXPIRING API] ERROR: Failed to get open options: %v", err)
+		http.Error(w, "Failed to get open options", http.StatusInternalServerError)
+		return
+	}
+
+	year, week := weekStart.ISOWeek()
+	response := ExpiringWeekResponse{
+		Week:                 fmt.Sprintf("%d-W%02d", year, week),
+		Start:                weekStart.Format("2006-01-02"),
+		End:                  weekEnd.Format("2006-01-02"),
+		MoneynessBandPercent: band,
+		Options:              []ExpiringOptionAction{},
+	}
+
+	prices := make(map[string]float64)
+	for _, option := range openOptions {
+		expiration := option.Expiration.Format("2006-01-02")
+		if expiration < response.Start || expiration > response.End {
+			continue
+		}
+
+		price, ok := prices[option.Symbol]
+		if !ok {
+			if symbolData, err := s.symbolService.GetBySymbol(option.Symbol); err == nil {
+				price = symbolData.Price
+			}
+			prices[option.Symbol] = price
+		}
+
+		entry := ExpiringOptionAction{
+			Option:       option,
+			CurrentPrice: price,
+			Moneyness:    ladderMoneyness(option.Type, option.Strike, price),
+			Action:       "review",
+		}
+		if price > 0 {
+			entry.DistancePercent = math.Abs(price-option.Strike) / price * 100
+			switch {
+			case entry.DistancePercent <= band:
+				entry.Action = "roll"
+			case entry.Moneyness == "ITM":
+				entry.Action = "prepare_for_assignment"
+			default:
+				entry.Action = "let_expire"
+			}
+		}
+		if option.CurrentPrice != nil {
+			cost := *option.CurrentPrice * float64(option.Contracts) * 100
+			entry.BuybackCost = &cost
+		}
+		response.Options = append(response.Options, entry)
+	}
+
+	sort.Slice(response.Options, func(i, j int) bool {
+		a, b := response.Options[i].Option, response.Options[j].Option
+		if !a.Expiration.Equal(b.Expiration) {
+			return a.Expiration.Before(b.Expiration)
+		}
+		return a.Symbol < b.Symbol
+	})
+
+	s.attachRollCredits(r.Context(), response.Options)
+
+	log.Printf("[OPTIONS EXPIRING API] %d open options expiring in %s", len(response.Options), response.Week)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[OPTIONS EXPIRING API] ERROR: Failed to encode response: %v", err)
+	}
+}
+
+// attachRollCredits sets the roll credit on entries suggested to roll: the best net credit from
+// findCreditRolls over the chain up to defaultRollableMaxDTE out. Each underlying and type's chain
+// is fetched once; entries whose chain or buyback price is unavailable, or that cannot roll for a
+// credit, are left without one.
+func (s *Server) attachRollCredits(ctx context.Context, entries []ExpiringOptionAction) {
+	rolls := make(map[rollableChainKey][]int)
+	var keys []rollableChainKey
+	for i, entry := range entries {
+		if entry.Action != "roll" {
+			continue
+		}
+		key := rollableChainKey{Symbol: entry.Option.Symbol, Type: entry.Option.Type}
+		if _, ok := rolls[key]; !ok {
+			keys = append(keys, key)
+		}
+		rolls[key] = append(rolls[key], i)
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	today := time.Now().In(s.marketLocation())
+	lastExpiration := today.AddDate(0, 0, defaultRollableMaxDTE)
+	for _, key := range keys {
+		indexes := rolls[key]
+		from := entries[indexes[0]].Option.Expiration
+		for _, i := range indexes {
+			if entries[i].Option.Expiration.Before(from) {
+				from = entries[i].Option.Expiration
+			}
+		}
+		chain, err := s.polygonService.GetOptionChain(ctx, key.Symbol, polygon.ChainQuery{
+			ContractType:   key.Type,
+			ExpirationFrom: from.AddDate(0, 0, 1),
+			ExpirationTo:   lastExpiration,
+		})
+		if err != nil {
+			log.Printf("[OPTIONS EXPIRING API] No roll credit for %s %s: chain unavailable: %v", key.Symbol, key.Type, err)
+			continue
+		}
+
+		for _, i := range indexes {
+			option := entries[i].Option
+			closeCost, source := s.quoteCloseCost(ctx, option)
+			if source == "" {
+				continue
+			}
+			if opportunities := findCreditRolls(option, chain, closeCost, lastExpiration, today); len(opportunities) > 0 {
+				credit := opportunities[0].NetCredit
+				entries[i].RollCredit = &credit
+				entries[i].RollContract = opportunities[0].Contract
+			}
+		}
+	}
+}
+
+// parseISOWeek parses YYYY-Www (e.g. 2025-W07) into the Monday starting that week
+func parseISOWeek(value string) (time.Time, error) {
+	var year, week int
+	if _, err := fmt.Sscanf(strings.ToUpper(value), "%d-W%d", &year, &week); err != nil {
+		return time.Time{}, err
+	}
+	if week < 1 || week > 53 {
+		return time.Time{}, fmt.Errorf("week %d out of range", week)
+	}
+	start := isoWeekStart(year, week)
+	if y, wk := start.ISOWeek(); y != year || wk != week {
+		return time.Time{}, fmt.Errorf("%d has no week %d", year, week)
+	}
+	return start, nil
+}
+
+// isoWeekStart returns the Monday of an ISO week; week 1 is the week containing January 4th
+func isoWeekStart(year, week int) time.Time {
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	return monday.AddDate(0, 0, (week-1)*7)
+}
+
+// useGrossReturns reports whether option returns should be computed before commission.
+// A ?basis=gross|net query parameter overrides the OPTION_RETURNS_BASIS setting (default net);
+// any other basis in the query is an error.
+func (s *Server) useGrossReturns(r *http.Request) (bool, error) {
+	basis := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("basis")))
+	if basis == "" {
+		basis = strings.ToLower(s.settingService.GetValueWithDefault("OPTION_RETURNS_BASIS", "net"))
+		return basis == "gross", nil
+	}
+	if basis != "gross" && basis != "net" {
+		return false, fmt.Errorf("invalid basis %q; use gross or net", basis)
+	}
+	return basis == "gross", nil
+}
+
+// returnsBasisName is the basis reported alongside returns computed with useGrossReturns
+func returnsBasisName(gross bool) string {
+	if gross {
+		return "gross"
+	}
+	return "net"
+}
+
+// annualizationBasis returns the ANNUALIZATION_BASIS in effect: calendar (365.25 days, the default)
+// or trading (252 sessions)
+func (s *Server) annualizationBasis() string {
+	return models.NormalizeAnnualizationBasis(s.configValue("ANNUALIZATION_BASIS"))
+}
+
+// newOptionWithPerformance wraps an option with its derived metrics, rounding percentages to the
+// given number of decimals. The model calculations stay unrounded; rounding happens only here.
+func newOptionWithPerformance(option *models.Option, gross bool, annualization string, underlyingPrice float64, decimals int) OptionWithPerformance {
+	performance := option.CalculatePerformance(gross, annualization)
+	performance.ROI = roundPercent(performance.ROI, decimals)
+	performance.AROI = roundPercent(performance.AROI, decimals)
+
+	result := OptionWithPerformance{
+		Option:             option,
+		ExpirationClass:    option.ExpirationClass(),
+		Outcome:            option.Outcome(),
+		ContractMultiplier: 100,
+		Exposure:           option.CalculateExposure(),
+		NetPremium:         option.CalculateNetPremiumNoFees(),
+		DTE:                option.CalculateDTE(),
+		PercentOfProfit:    roundPercent(option.CalculatePercentOfProfit(), decimals),
+		PercentOfTime:      roundPercent(option.CalculatePercentOfTime(), decimals),
+		Performance:        &performance,
+	}
+	if option.Closed == nil {
+		result.DaysRemaining = option.CalculateDaysRemaining()
+	}
+	if underlyingPrice > 0 {
+		percentOTM := roundPercent(option.CalculatePercentOTM(underlyingPrice), decimals)
+		result.PercentOTM = &percentOTM
+	}
+	return result
+}
+
+// brokerProfile returns the commission schedule selected by BROKER_PROFILE. It is applied only to
+// trades entered without a commission; an empty or unknown profile keeps the built-in defaults.
+func (s *Server) brokerProfile() (models.BrokerProfile, bool) {
+	name := strings.TrimSpace(s.settingService.GetValue("BROKER_PROFILE"))
+	if name == "" {
+		return models.BrokerProfile{}, false
+	}
+	profile, ok := models.LookupBrokerProfile(name)
+	if !ok {
+		log.Printf("[OPTIONS] WARNING: Unknown BROKER_PROFILE %q, using default commission (profiles: %s)", name, strings.Join(models.BrokerProfileNames(), ", "))
+	}
+	return profile, ok
+}
+
+// Sources an option prefill value can come from
+const (
+	PrefillSourceSymbol        = "symbol"
+	PrefillSourceBrokerProfile = "broker_profile"
+	PrefillSourceGlobal        = "global"
+)
+
+// optionPrefill resolves the values for entering a new option on symbol: the symbol's stored
+// defaults first, then the broker profile for commission, then the built-in defaults. A positive
+// contracts argument overrides the default contract count the commission is computed for.
+func (s *Server) optionPrefill(symbol string, contracts int) (OptionPrefill, *models.SymbolDefaults, error) {
+	prefill := OptionPrefill{
+		Symbol:                symbol,
+		Contracts:             1,
+		ContractsSource:       PrefillSourceGlobal,
+		StrategySource:        PrefillSourceGlobal,
+		CommissionPerContract: models.OptionCommissionPerContract,
+		CommissionSource:      PrefillSourceGlobal,
+	}
+
+	defaults, err := s.symbolService.GetDefaults(symbol)
+	if err != nil {
+		return prefill, nil, err
+	}
+	if profile, ok := s.brokerProfile(); ok {
+		prefill.CommissionPerContract = profile.OpeningCommission(1)
+		prefill.CommissionSource = PrefillSourceBrokerProfile
+	}
+	if defaults != nil {
+		if defaults.Contracts != nil {
+			prefill.Contracts = *defaults.Contracts
+			prefill.ContractsSource = PrefillSourceSymbol
+		}
+		if defaults.Strategy != nil {
+			prefill.Strategy = *defaults.Strategy
+			prefill.StrategySource = PrefillSourceSymbol
+		}
+		if defaults.CommissionPerContract != nil {
+			prefill.CommissionPerContract = *defaults.CommissionPerContract
+			prefill.CommissionSource = PrefillSourceSymbol
+		}
+	}
+	if contracts > 0 {
+		prefill.Contracts = contracts
+	}
+	prefill.Commission = prefill.CommissionPerContract * float64(prefill.Contracts)
+	return prefill, defaults, nil
+}
+
+// optionPrefillHandler handles GET /api/options/prefill?symbol=&contracts=, returning the values
+// the new-option form starts with for a symbol
+func (s *Server) optionPrefillHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := strings.TrimSpace(r.URL.Query().Get("symbol"))
+	if symbol == "" {
+		http.Error(w, "symbol is required", http.StatusBadRequest)
+		return
+	}
+	contracts := 0
+	if value := r.URL.Query().Get("contracts"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "contracts must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		contracts = parsed
+	}
+
+	prefill, _, err := s.optionPrefill(symbol, contracts)
+	if err != nil {
+		log.Printf("[OPTIONS API] Error loading defaults for %s: %v", symbol, err)
+		http.Error(w, "Failed to load symbol defaults", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefill)
+}
+
+// normalizeExpiration moves an expiration that falls on a weekend or market holiday back to the
+// prior trading day when SNAP_NON_TRADING_EXPIRATIONS is on. Otherwise the date is kept as
+// entered and the data-quality report flags it.
+func (s *Server) normalizeExpiration(expiration time.Time) time.Time {
+	if models.IsTradingDay(expiration) {
+		return expiration
+	}
+	if !s.settingService.GetBoolWithDefault("SNAP_NON_TRADING_EXPIRATIONS", false) {
+		log.Printf("[OPTIONS] WARNING: Expiration %s is not a trading day", expiration.Format("2006-01-02"))
+		return expiration
+	}
+	snapped := models.PreviousTradingDay(expiration)
+	log.Printf("[OPTIONS] Expiration %s is not a trading day, using %s", expiration.Format("2006-01-02"), snapped.Format("2006-01-02"))
+	return snapped
+}
+
+// percentDecimals returns the PERCENT_DECIMALS setting used to round percentages in API responses
+func (s *Server) percentDecimals() int {
+	decimals, err := strconv.Atoi(strings.TrimSpace(s.settingService.GetValue("PERCENT_DECIMALS")))
+	if err != nil || decimals < 0 || decimals > 10 {
+		return 2
+	}
+	return decimals
+}
+
+// roundPercent rounds a percentage to the given number of decimal places
+func roundPercent(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
+
+// includesField reports whether a comma-separated ?include= query parameter lists the given field
+func includesField(r *http.Request, field string) bool {
+	for _, include := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if strings.EqualFold(strings.TrimSpace(include), field) {
+			return true
+		}
+	}
+	return false
+}
+
+// optionsPerformanceByStrategyHandler handles GET /api/options/performance-by-strategy?basis=net|gross&account=
+// Options without a strategy label are grouped under the strategy inferred from their type.
+func (s *Server) optionsPerformanceByStrategyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[OPTIONS STRATEGY API] ERROR: Failed to get options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+
+	account := strings.TrimSpace(r.URL.Query().Get("account"))
+	if account != "" {
+		filtered := options[:0]
+		for _, option := range options {
+			if option.Account == account {
+				filtered = append(filtered, option)
+			}
+		}
+		options = filtered
+	}
+
+	gross, err := s.useGrossReturns(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	decimals := s.percentDecimals()
+	annualization := s.annualizationBasis()
+	strategies := models.SummarizeStrategyPerformance(options, gross, annualization)
+	for i := range strategies {
+		strategies[i].WinRate = roundPercent(strategies[i].WinRate, decimals)
+		strategies[i].AROI = roundPercent(strategies[i].AROI, decimals)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StrategyPerformanceResponse{
+		Basis:         returnsBasisName(gross),
+		Annualization: annualization,
+		DaysPerYear:   models.DaysPerYear(annualization),
+		Account:       account,
+		Strategies:    strategies,
+	})
+}
+
+// defaultCloseTimingBucket is the width, in percent of time held, of each close-timing bucket
+const defaultCloseTimingBucket = 25
+
+// optionsCloseTimingHandler handles GET /api/options/close-timing?bucket=&account=, bucketing closed
+// puts and calls by percent of time held and averaging the percent of max profit captured in each
+func (s *Server) optionsCloseTimingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bucketSize := float64(defaultCloseTimingBucket)
+	if value := strings.TrimSpace(r.URL.Query().Get("bucket")); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 5 || parsed > 100 {
+			http.Error(w, "Invalid bucket; use a width between 5 and 100", http.StatusBadRequest)
+			return
+		}
+		bucketSize = parsed
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[OPTIONS CLOSE TIMING API] ERROR: Failed to get options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+
+	account := strings.TrimSpace(r.URL.Query().Get("account"))
+	if account != "" {
+		filtered := options[:0]
+		for _, option := range options {
+			if option.Account == account {
+				filtered = append(filtered, option)
+			}
+		}
+		options = filtered
+	}
+
+	gross, err := s.useGrossReturns(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	decimals := s.percentDecimals()
+	response := CloseTimingResponse{
+		Basis:      returnsBasisName(gross),
+		Account:    account,
+		BucketSize: bucketSize,
+		Puts:       models.SummarizeCloseTiming(options, "Put", bucketSize, gross),
+		Calls:      models.SummarizeCloseTiming(options, "Call", bucketSize, gross),
+	}
+	for _, buckets := range [][]models.CloseTimingBucket{response.Puts, response.Calls} {
+		for i := range buckets {
+			buckets[i].AvgPercentOfTime = roundPercent(buckets[i].AvgPercentOfTime, decimals)
+			buckets[i].AvgPercentOfProfit = roundPercent(buckets[i].AvgPercentOfProfit, decimals)
+			buckets[i].ProfitCapturePerTime = roundPercent(buckets[i].ProfitCapturePerTime, decimals)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// optionsStreaksHandler handles GET /api/options/streaks?account=&by_type=, reporting the current
+// and longest win and loss streaks over closed options, optionally broken down by put and call
+func (s *Server) optionsStreaksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[OPTIONS STREAKS API] ERROR: Failed to get options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+
+	account := strings.TrimSpace(r.URL.Query().Get("account"))
+	if account != "" {
+		filtered := options[:0]
+		for _, option := range options {
+			if option.Account == account {
+				filtered = append(filtered, option)
+			}
+		}
+		options = filtered
+	}
+
+	gross, err := s.useGrossReturns(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response := OptionStreaksResponse{
+		Basis:         returnsBasisName(gross),
+		Account:       account,
+		OptionStreaks: models.ComputeOptionStreaks(options, gross),
+	}
+	if byType, _ := strconv.ParseBool(r.URL.Query().Get("by_type")); byType {
+		response.ByType = make(map[string]models.OptionStreaks)
+		for _, optionType := range []string{"Put", "Call"} {
+			var ofType []*models.Option
+			for _, option := range options {
+				if option.Type == optionType {
+					ofType = append(ofType, option)
+				}
+			}
+			response.ByType[optionType] = models.ComputeOptionStreaks(ofType, gross)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// optionsDetectRollsHandler handles /api/options/detect-rolls. GET reports probable rolls (a close
+// and an open of the same symbol and type on the same day) grouped into chains; POST writes the
+// rolled_from_id links for the pairs in the request body, or for every detected pair when the body sets
+// all, in a single transaction.
+func (s *Server) optionsDetectRollsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[OPTIONS ROLLS API] ERROR: Failed to get options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+	pairs := models.DetectRolls(options)
+
+	linked := 0
+	if r.Method == http.MethodPost {
+		var req RollLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		links := req.Links
+		if len(links) == 0 {
+			if !req.All {
+				http.Error(w, "Specify the links to record, or set all to link every detected roll", http.StatusBadRequest)
+				return
+			}
+			for _, pair := range pairs {
+				if !pair.AlreadyLinked {
+					links = append(links, models.RollLink{FromID: pair.From.ID, ToID: pair.To.ID})
+				}
+			}
+		} else if req.All {
+			http.Error(w, "Specify either links or all, not both", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.optionService.SetRolledFromLinks(links); err != nil {
+			log.Printf("[OPTIONS ROLLS API] ERROR: Failed to link rolls: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to link rolls: %v", err), http.StatusBadRequest)
+			return
+		}
+		linked = len(links)
+		log.Printf("[OPTIONS ROLLS API] Linked %d rolls", linked)
+
+		if options, err = s.optionService.GetAll(); err != nil {
+			http.Error(w, "Failed to get options", http.StatusInternalServerError)
+			return
+		}
+		pairs = models.DetectRolls(options)
+	}
+
+	if pairs == nil {
+		pairs = []models.RollPair{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DetectRollsResponse{
+		Pairs:  pairs,
+		Chains: models.RollChains(pairs),
+		Linked: linked,
+	})
+}
+
+// optionCoverageHandler handles GET /api/options/{id}/coverage, listing the long lots a call is
+// written against. The mapping is stored during cost basis recalculation only while
+// CALL_COVERAGE_TRACKING is on; otherwise the lot list is empty and a note says so.
+func (s *Server) optionCoverageHandler(w http.ResponseWriter, r *http.Request, option *models.Option) {
+	if option.Type != "Call" {
+		http.Error(w, "Coverage applies to calls only", http.StatusBadRequest)
+		return
+	}
+
+	response := OptionCoverageResponse{
+		OptionID:         option.ID,
+		Symbol:           option.Symbol,
+		CoverageTracking: s.longPositionService.CallCoverageTracked(),
+		CallShares:       option.Contracts * 100,
+		Lots:             []OptionCoverageLot{},
+	}
+	if !response.CoverageTracking {
+		response.UncoveredShares = response.CallShares
+		response.Note = "Enable CALL_COVERAGE_TRACKING to record which lots each call covers"
+	} else {
+		coverage, err := s.longPositionService.GetCallCoverage(option.ID)
+		if err != nil {
+			log.Printf("[INDIVIDUAL OPTION API] ERROR: Failed to get coverage for option %d: %v", option.ID, err)
+			http.Error(w, "Failed to get call coverage", http.StatusInternalServerError)
+			return
+		}
+		for _, c := range coverage {
+			position, err := s.longPositionService.GetByID(c.PositionID)
+			if err != nil {
+				log.Printf("[INDIVIDUAL OPTION API] ERROR: Failed to get long position %d: %v", c.PositionID, err)
+				http.Error(w, "Failed to get long position", http.StatusInternalServerError)
+				return
+			}
+			lot := OptionCoverageLot{
+				PositionID: position.ID,
+				Opened:     position.Opened.Format("2006-01-02"),
+				LotShares:  position.Shares,
+				BuyPrice:   position.BuyPrice,
+				Shares:     c.Shares,
+			}
+			if position.Closed != nil {
+				closed := position.Closed.Format("2006-01-02")
+				lot.Closed = &closed
+			}
+			response.CoveredShares += c.Shares
+			response.Lots = append(response.Lots, lot)
+		}
+		response.UncoveredShares = max(response.CallShares-response.CoveredShares, 0)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[INDIVIDUAL OPTION API] ERROR: Failed to encode coverage response: %v", err)
+	}
+}
+
+// optionTimelineHandler handles GET /api/options/{id}/timeline, returning the chronological story of
+// the option's roll chain (rolled_from_id links): each leg opening, rolls out and in, the close by
+// assignment, expiry or buyback, and for a leg still open its current mark and days remaining.
+// ProfitLoss is the leg's realized P/L at a close and its unrealized P/L at the mark;
+// CumulativeProfitLoss adds the realized P/L of earlier legs.
+func (s *Server) optionTimelineHandler(w http.ResponseWriter, r *http.Request, option *models.Option) {
+	options, err := s.optionService.GetBySymbol(option.Symbol)
+	if err != nil {
+		log.Printf("[INDIVIDUAL OPTION API] ERROR: Failed to get options for %s: %v", option.Symbol, err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+	chain := models.LinkedRollChain(options, option.ID)
+	if chain == nil {
+		chain = []*models.Option{option}
+	}
+
+	response := OptionTimelineResponse{
+		OptionID: option.ID,
+		Symbol:   option.Symbol,
+		Type:     option.Type,
+		Outcome:  option.Outcome(),
+		Chain:    make([]int, 0, len(chain)),
+		Events:   []OptionTimelineEvent{},
+	}
+	var underlying *float64
+	if symbol, err := s.symbolService.GetBySymbol(option.Symbol); err == nil && symbol.Price > 0 {
+		underlying = &symbol.Price
+	}
+	today := s.marketToday().Format("2006-01-02")
+
+	for i, leg := range chain {
+		response.Chain = append(response.Chain, leg.ID)
+		credit := leg.Premium * float64(leg.Contracts) * 100
+		premium := leg.Premium
+		opened := OptionTimelineEvent{
+			Date:                 leg.Opened.Format("2006-01-02"),
+			Event:                "opened",
+			OptionID:             leg.ID,
+			Strike:               leg.Strike,
+			Expiration:           leg.Expiration.Format("2006-01-02"),
+			Contracts:            leg.Contracts,
+			Price:                &premium,
+			Underlying:           leg.UnderlyingAtOpen,
+			Credit:               credit,
+			CumulativeProfitLoss: response.RealizedProfitLoss,
+		}
+		if i > 0 {
+			opened.Event = "rolled_in"
+			opened.RolledFromID = &chain[i-1].ID
+		}
+		response.Events = append(response.Events, opened)
+
+		if leg.Closed == nil {
+			mark := OptionTimelineEvent{
+				Date:          today,
+				Event:         "mark",
+				OptionID:      leg.ID,
+				Strike:        leg.Strike,
+				Expiration:    leg.Expiration.Format("2006-01-02"),
+				Contracts:     leg.Contracts,
+				Price:         leg.CurrentPrice,
+				Underlying:    underlying,
+				DaysRemaining: max(leg.CalculateDaysRemaining(), 0),
+			}
+			if leg.CurrentPrice != nil {
+				unrealized := (leg.Premium-*leg.CurrentPrice)*float64(leg.Contracts)*100 - leg.TransactionCosts()
+				mark.ProfitLoss = unrealized
+				response.UnrealizedProfitLoss = &unrealized
+			}
+			mark.CumulativeProfitLoss = response.RealizedProfitLoss + mark.ProfitLoss
+			response.Events = append(response.Events, mark)
+			continue
+		}
+
+		realized := leg.RealizedProfitValue()
+		response.RealizedProfitLoss += realized
+		closed := OptionTimelineEvent{
+			Date:                 leg.Closed.Format("2006-01-02"),
+			Event:                leg.Outcome(),
+			OptionID:             leg.ID,
+			Strike:               leg.Strike,
+			Expiration:           leg.Expiration.Format("2006-01-02"),
+			Contracts:            leg.Contracts,
+			Price:                leg.ExitPrice,
+			Underlying:           leg.UnderlyingAtClose,
+			ProfitLoss:           realized,
+			CumulativeProfitLoss: response.RealizedProfitLoss,
+		}
+		if i < len(chain)-1 {
+			closed.Event = "rolled_out"
+			closed.RolledToID = &chain[i+1].ID
+		}
+		response.Events = append(response.Events, closed)
+	}
+
+	response.ChainOutcome = chain[len(chain)-1].Outcome()
+	response.TotalProfitLoss = response.RealizedProfitLoss
+	if response.UnrealizedProfitLoss != nil {
+		response.TotalProfitLoss += *response.UnrealizedProfitLoss
+	}
+	// Legs are in roll order already; a stable sort keeps a roll's close ahead of its reopening
+	sort.SliceStable(response.Events, func(i, j int) bool {
+		return response.Events[i].Date < response.Events[j].Date
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[INDIVIDUAL OPTION API] ERROR: Failed to encode timeline response: %v", err)
+	}
+}
+
+// optionContractDetailsHandler handles GET /api/options/{id}/contract-details, returning the stored
+// exercise style and shares per contract. Details are fetched from Polygon when none are stored or
+// ?refresh=true; standard 100-share American-style terms are reported when Polygon has none.
+func (s *Server) optionContractDetailsHandler(w http.ResponseWriter, r *http.Request, option *models.Option) {
+	details, err := s.optionService.GetContractDetails(option.ID)
+	if err != nil {
+		log.Printf("[INDIVIDUAL OPTION API] ERROR: Failed to get contract details for option %d: %v", option.ID, err)
+		http.Error(w, "Failed to get contract details", http.StatusInternalServerError)
+		return
+	}
+
+	var warning string
+	if details == nil || r.URL.Query().Get("refresh") == "true" {
+		ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+		defer cancel()
+
+		contract, snapshot, err := s.polygonService.GetRawOptionSnapshot(ctx, option)
+		switch {
+		case err != nil:
+			warning = fmt.Sprintf("Polygon contract details unavailable: %v", err)
+		case snapshot == nil || snapshot.Results.Details.SharesPerContract <= 0:
+			warning = "Polygon returned no contract details"
+		default:
+			fetched := s.saveContractDetails(option.ID, contract, snapshot.Results.Details.ExerciseStyle, snapshot.Results.Details.SharesPerContract)
+			if fetched != nil {
+				details = fetched
+			}
+		}
+	}
+	if details == nil {
+		details = models.DefaultContractDetails(option.ID)
+		details.Contract = polygon.OptionContractSymbol(option)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ContractDetailsResponse{
+		OptionContractDetails: *details,
+		Standard:              details.IsStandard(),
+		Warning:               warning,
+	})
+}
+
+// saveContractDetails stores Polygon contract terms for an option and returns them, or nil when
+// the snapshot carried no share count or the write failed
+func (s *Server) saveContractDetails(optionID int, contract, exerciseStyle string, sharesPerContract float64) *models.OptionContractDetails {
+	if optionID == 0 || sharesPerContract <= 0 {
+		return nil
+	}
+	if exerciseStyle == "" {
+		exerciseStyle = models.DefaultExerciseStyle
+	}
+	details := &models.OptionContractDetails{
+		OptionID:          optionID,
+		Contract:          contract,
+		ExerciseStyle:     strings.ToLower(exerciseStyle),
+		SharesPerContract: sharesPerContract,
+		Source:            models.ContractDetailsSourcePolygon,
+	}
+	if err := s.optionService.SaveContractDetails(details); err != nil {
+		log.Printf("[POLYGON] Warning: failed to store contract details for option %d: %v", optionID, err)
+		return nil
+	}
+	if !details.IsStandard() {
+		log.Printf("[POLYGON] Option %d (%s) is non-standard: %s style, %.4g shares per contract", optionID, contract, details.ExerciseStyle, sharesPerContract)
+	}
+	return details
+}
+
+// validateOptionCurrency checks a request's currency fields before anything is written: a currency
+// other than the base currency needs an explicit fx_rate, and a given fx_rate must be positive
+// unless the currency is the base currency, which always uses 1.0.
+func validateOptionCurrency(req OptionRequest) error {
+	baseCurrency := req.Currency != nil && models.NormalizeCurrency(*req.Currency) == models.BaseCurrency
+	if req.Currency != nil && !baseCurrency && req.FXRate == nil {
+		return fmt.Errorf("fx_rate is required when currency is not %s", models.BaseCurrency)
+	}
+	if req.FXRate != nil && *req.FXRate <= 0 && !baseCurrency {
+		return fmt.Errorf("fx_rate must be positive")
+	}
+	return nil
+}
+
+// applyOptionCurrency stores the request's currency and trade-time FX rate on an option, keeping
+// the option's current value for whichever field is omitted. It writes the error response and
+// returns false on failure.
+func (s *Server) applyOptionCurrency(w http.ResponseWriter, option *models.Option, req OptionRequest) bool {
+	currency, fxRate := option.Currency, option.FXRate
+	if req.Currency != nil {
+		currency = models.NormalizeCurrency(*req.Currency)
+	}
+	if req.FXRate != nil {
+		fxRate = *req.FXRate
+	}
+	if currency != models.BaseCurrency && fxRate <= 0 {
+		http.Error(w, "fx_rate must be positive", http.StatusBadRequest)
+		return false
+	}
+	if err := s.optionService.SetCurrency(option.ID, currency, fxRate); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to set option currency: %v", err), http.StatusInternalServerError)
+		return false
+	}
+	option.Currency = currency
+	option.FXRate = fxRate
+	if currency == models.BaseCurrency {
+		option.FXRate = 1.0
+	}
+	return true
+}
+
+// underlyingPriceTimeout bounds the Polygon lookup made when an option is saved
+const underlyingPriceTimeout = 10 * time.Second
+
+// underlyingPriceResolver looks up underlying prices by date: the stored symbol price for today and
+// the Polygon grouped-daily close for earlier sessions, caching one grouped response per session.
+// Lookups stop once maxRequests grouped requests have been made (0 means unlimited).
+type underlyingPriceResolver struct {
+	s           *Server
+	ctx         context.Context
+	today       time.Time
+	closes      map[string]map[string]float64 // session date -> symbol -> close; nil when the request failed
+	requests    int
+	maxRequests int
+	throttle    time.Duration // pause between grouped requests
+}
+
+func (s *Server) newUnderlyingPriceResolver(ctx context.Context, maxRequests int, throttle time.Duration) *underlyingPriceResolver {
+	return &underlyingPriceResolver{
+		s:           s,
+		ctx:         ctx,
+		today:       s.marketToday(),
+		closes:      make(map[string]map[string]float64),
+		maxRequests: maxRequests,
+		throttle:    throttle,
+	}
+}
+
+// price returns the underlying price of symbol on date, or false when it is unknown, in the future
+// or beyond the request budget
+func (p *underlyingPriceResolver) price(symbol string, date time.Time) (float64, bool) {
+	day := date.Format("2006-01-02")
+	today := p.today.Format("2006-01-02")
+	if day > today {
+		return 0, false
+	}
+	if day == today {
+		if symbolData, err := p.s.symbolService.GetBySymbol(symbol); err == nil && symbolData.Price > 0 {
+			return symbolData.Price, true
+		}
+		return 0, false
+	}
+
+	session := models.PreviousTradingDay(time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)).Format("2006-01-02")
+	closes, cached := p.closes[session]
+	if !cached {
+		if p.maxRequests > 0 && p.requests >= p.maxRequests {
+			return 0, false
+		}
+		if p.requests > 0 && p.throttle > 0 {
+			time.Sleep(p.throttle)
+		}
+		p.requests++
+		fetched, _, err := p.s.polygonService.GetClosesOn(p.ctx, date)
+		if err != nil {
+			log.Printf("[UNDERLYING PRICE] Could not get closes for %s: %v", session, err)
+		}
+		p.closes[session] = fetched
+		closes = fetched
+	}
+	price, ok := closes[strings.ToUpper(symbol)]
+	return price, ok && price > 0
+}
+
+// fill stores whichever underlying prices the option is missing and can be resolved, returning
+// whether anything was stored
+func (p *underlyingPriceResolver) fill(option *models.Option) (bool, error) {
+	var atOpen, atClose *float64
+	if option.UnderlyingAtOpen == nil {
+		if price, ok := p.price(option.Symbol, option.Opened); ok {
+			atOpen = &price
+		}
+	}
+	if option.Closed != nil && option.UnderlyingAtClose == nil {
+		if price, ok := p.price(option.Symbol, *option.Closed); ok {
+			atClose = &price
+		}
+	}
+	if atOpen == nil && atClose == nil {
+		return false, nil
+	}
+	if err := p.s.optionService.SetUnderlyingPrices(option.ID, atOpen, atClose); err != nil {
+		return false, err
+	}
+	if atOpen != nil {
+		option.UnderlyingAtOpen = atOpen
+	}
+	if atClose != nil {
+		option.UnderlyingAtClose = atClose
+	}
+	return true, nil
+}
+
+// captureUnderlyingPrices records the underlying price at open and close on a saved option, best
+// effort: failures are logged and the option is returned with whatever could be stored, or nil
+// when it could not be loaded
+func (s *Server) captureUnderlyingPrices(optionID int) *models.Option {
+	option, err := s.optionService.GetByID(optionID)
+	if err != nil {
+		log.Printf("[UNDERLYING PRICE] Could not load option %d: %v", optionID, err)
+		return nil
+	}
+	if !option.NeedsUnderlyingPrices() {
+		return option
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), underlyingPriceTimeout)
+	defer cancel()
+	if _, err := s.newUnderlyingPriceResolver(ctx, 2, 0).fill(option); err != nil {
+		log.Printf("[UNDERLYING PRICE] Could not store underlying prices for option %d: %v", optionID, err)
+	}
+	return option
+}
+
+// optionsBackfillUnderlyingHandler handles POST /api/options/backfill-underlying?requests=
+// It fills missing underlying prices at open and close on stored options, making at most requests
+// (default 5) Polygon grouped-daily calls spaced for the free-tier rate limit. Run it again to
+// continue; Remaining counts the options still missing a price.
+func (s *Server) optionsBackfillUnderlyingHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[UNDERLYING PRICE] %s %s - Backfilling underlying prices", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	maxRequests := 5
+	if value := strings.TrimSpace(r.URL.Query().Get("requests")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 50 {
+			http.Error(w, "requests must be between 1 and 50", http.StatusBadRequest)
+			return
+		}
+		maxRequests = parsed
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[UNDERLYING PRICE] ERROR: Failed to get options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+
+	resolver := s.newUnderlyingPriceResolver(r.Context(), maxRequests, 12*time.Second)
+	response := UnderlyingBackfillResponse{}
+	for _, option := range options {
+		if !option.NeedsUnderlyingPrices() {
+			continue
+		}
+		filled, err := resolver.fill(option)
+		if err != nil {
+			log.Printf("[UNDERLYING PRICE] ERROR: Failed to store underlying prices for option %d: %v", option.ID, err)
+			http.Error(w, "Failed to store underlying prices", http.StatusInternalServerError)
+			return
+		}
+		if filled {
+			response.Updated++
+		}
+		if option.NeedsUnderlyingPrices() {
+			response.Remaining++
+		}
+	}
+	response.Requests = resolver.requests
+
+	log.Printf("[UNDERLYING PRICE] Backfill updated %d options with %d Polygon requests, %d still missing", response.Updated, response.Requests, response.Remaining)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,152 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"stonks/internal/models"
+	"time"
+)
+
+// pendingActivationInterval is how often the activation task checks for a new market date
+const pendingActivationInterval = time.Hour
+
+// marketToday returns today's date in the configured market timezone as a UTC calendar date,
+// matching how opened dates are parsed from YYYY-MM-DD
+func (s *Server) marketToday() time.Time {
+	year, month, day := time.Now().In(s.marketLocation()).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// rejectFutureOpened writes a 400 and returns true when opened is after today and
+// ALLOW_PENDING_TRADES is off; with it on, future-opened trades are stored as pending
+func (s *Server) rejectFutureOpened(w http.ResponseWriter, opened time.Time) bool {
+	if !opened.After(s.marketToday()) || s.settingService.GetBoolWithDefault("ALLOW_PENDING_TRADES", false) {
+		return false
+	}
+	http.Error(w, fmt.Sprintf("Opened date %s is in the future; enable ALLOW_PENDING_TRADES in Settings to enter planned trades", opened.Format("2006-01-02")), http.StatusBadRequest)
+	return true
+}
+
+// pendingTradesHandler handles GET /api/pending-trades, listing planned options and long
+// positions whose opened date has not arrived yet
+func (s *Server) pendingTradesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	options, err := s.optionService.GetPending()
+	if err != nil {
+		log.Printf("[PENDING API] ERROR: Failed to get pending options: %v", err)
+		http.Error(w, "Failed to get pending options", http.StatusInternalServerError)
+		return
+	}
+	positions, err := s.longPositionService.GetPendingPositions()
+	if err != nil {
+		log.Printf("[PENDING API] ERROR: Failed to get pending long positions: %v", err)
+		http.Error(w, "Failed to get pending long positions", http.StatusInternalServerError)
+		return
+	}
+
+	response := PendingTradesResponse{
+		Options:       []*models.Option{},
+		LongPositions: []*models.LongPosition{},
+	}
+	if options != nil {
+		response.Options = options
+	}
+	if positions != nil {
+		response.LongPositions = positions
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// startPendingActivation runs a background task that activates pending trades once their opened
+// date arrives. Open-position queries already exclude trades opened in the future, so activation
+// recalculates adjusted cost basis for the affected symbols and logs what became active.
+func (s *Server) startPendingActivation() {
+	s.pendingDone = make(chan struct{})
+	go s.runPendingActivation(s.pendingDone)
+	s.registerScheduledTask("pending_activation", s.pendingActivationTaskStatus)
+	log.Printf("[PENDING] Pending trade activation task started")
+}
+
+// pendingActivationTaskStatus reports the pending trade activation task for /api/scheduler/status.
+// Its last run is the last time the market date changed and trades were checked for activation.
+func (s *Server) pendingActivationTaskStatus() SchedulerTaskStatus {
+	status := SchedulerTaskStatus{
+		Description: "Activates planned trades once their opened date arrives",
+		Enabled:     true,
+		Schedule:    "hourly check for a new market date",
+		Settings:    []string{"MARKET_TIMEZONE"},
+	}
+	s.pendingRun.fill(&status)
+	return status
+}
+
+// stopPendingActivation stops the background pending trade activation task
+func (s *Server) stopPendingActivation() {
+	if s.pendingDone != nil {
+		close(s.pendingDone)
+		s.pendingDone = nil
+	}
+}
+
+func (s *Server) runPendingActivation(done <-chan struct{}) {
+	lastDate := s.marketToday()
+	for {
+		next := time.Now().Add(pendingActivationInterval)
+		s.pendingRun.scheduleNext(&next)
+
+		select {
+		case <-done:
+			return
+		case <-time.After(pendingActivationInterval):
+		}
+
+		today := s.marketToday()
+		if today.After(lastDate) {
+			started := time.Now()
+			s.pendingRun.recordRun(started, TaskResultSuccess, s.activatePendingTrades(lastDate, today))
+			lastDate = today
+		}
+	}
+}
+
+// activatePendingTrades handles trades opened after the last checked date, up to and including today
+func (s *Server) activatePendingTrades(lastDate, today time.Time) error {
+	symbols := make(map[string]bool)
+
+	options, err := s.optionService.GetOpen()
+	if err != nil {
+		log.Printf("[PENDING] Failed to get open options: %v", err)
+		return fmt.Errorf("failed to get open options: %w", err)
+	}
+	for _, option := range options {
+		if option.Opened.After(lastDate) && !option.Opened.After(today) {
+			log.Printf("[PENDING] Activated option %d: %s %s $%.2f exp %s", option.ID, option.Symbol, option.Type, option.Strike, option.Expiration.Format("2006-01-02"))
+			symbols[option.Symbol] = true
+		}
+	}
+
+	positions, err := s.longPositionService.GetOpenPositions()
+	if err != nil {
+		log.Printf("[PENDING] Failed to get open long positions: %v", err)
+		return fmt.Errorf("failed to get open long positions: %w", err)
+	}
+	for _, position := range positions {
+		if position.Opened.After(lastDate) && !position.Opened.After(today) {
+			log.Printf("[PENDING] Activated long position %d: %s %d shares", position.ID, position.Symbol, position.Shares)
+			symbols[position.Symbol] = true
+		}
+	}
+
+	for symbol := range symbols {
+		s.recalculateAdjustedCostBasis(symbol)
+	}
+	return nil
+}
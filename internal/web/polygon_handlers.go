@@ -3,9 +3,12 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"stonks/internal/polygon"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -43,7 +46,8 @@ func (s *Server) polygonTestHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// polygonUpdatePricesHandler triggers price updates for symbols
+// polygonUpdatePricesHandler triggers price updates for symbols. Symbols priced within
+// MIN_PRICE_UPDATE_INTERVAL_MINUTES are skipped and listed under "recent" unless force is set.
 func (s *Server) polygonUpdatePricesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -56,6 +60,7 @@ func (s *Server) polygonUpdatePricesHandler(w http.ResponseWriter, r *http.Reque
 	var request struct {
 		Symbols []string `json:"symbols,omitempty"`
 		All     bool     `json:"all,omitempty"`
+		Force   bool     `json:"force,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -66,8 +71,8 @@ func (s *Server) polygonUpdatePricesHandler(w http.ResponseWriter, r *http.Reque
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	var updated, failed int
-	var errors []string
+	var updated, failed, skipped int
+	var updateErrors, recent []string
 
 	if request.All || len(request.Symbols) == 0 {
 		// Update all symbols (prioritized: active positions first)
@@ -80,26 +85,25 @@ func (s *Server) polygonUpdatePricesHandler(w http.ResponseWriter, r *http.Reque
 
 		log.Printf("[POLYGON API] Updating prices for %d symbols (prioritized order)", len(symbols))
 
-		for _, symbol := range symbols {
-			if err := s.polygonService.UpdateSymbolPrice(ctx, symbol); err != nil {
-				log.Printf("[POLYGON API] Failed to update %s: %v", symbol, err)
-				errors = append(errors, symbol+": "+err.Error())
-				failed++
-			} else {
-				updated++
-			}
-
-			// Rate limiting for free tier (5 requests per minute)
-			time.Sleep(12 * time.Second)
-		}
+		// One grouped-daily request covers every ticker; only missing symbols are fetched individually
+		result := s.polygonService.UpdateSymbolPricesGrouped(ctx, symbols, request.Force)
+		updated, failed, skipped, updateErrors, recent = result.Updated, result.Failed, result.Skipped, result.Errors, result.Recent
 	} else {
 		// Update specific symbols
 		log.Printf("[POLYGON API] Updating prices for specific symbols: %v", request.Symbols)
 
 		for _, symbol := range request.Symbols {
-			if err := s.polygonService.UpdateSymbolPrice(ctx, symbol); err != nil {
+			if current, err := s.symbolService.GetBySymbol(symbol); err == nil && current.ManualPrice {
+				log.Printf("[POLYGON API] Skipping %s: manual price", symbol)
+				skipped++
+				continue
+			}
+			if err := s.polygonService.UpdateSymbolPrice(ctx, symbol, request.Force); errors.Is(err, polygon.ErrRecentlyUpdated) {
+				recent = append(recent, symbol)
+				continue
+			} else if err != nil {
 				log.Printf("[POLYGON API] Failed to update %s: %v", symbol, err)
-				errors = append(errors, symbol+": "+err.Error())
+				updateErrors = append(updateErrors, symbol+": "+err.Error())
 				failed++
 			} else {
 				updated++
@@ -112,23 +116,30 @@ func (s *Server) polygonUpdatePricesHandler(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
+	// Symbols still fresh from a recent update are not a failure
 	response := map[string]interface{}{
-		"success": updated > 0,
+		"success": updated > 0 || (failed == 0 && len(recent) > 0),
 		"updated": updated,
 		"failed":  failed,
+		"skipped": skipped,
 	}
 
-	if len(errors) > 0 {
-		response["errors"] = errors
+	if len(updateErrors) > 0 {
+		response["errors"] = updateErrors
+	}
+	if len(recent) > 0 {
+		response["recent"] = recent
 	}
 
 	if updated > 0 {
 		response["message"] = "Price update completed"
+	} else if len(recent) > 0 && failed == 0 {
+		response["message"] = "Prices were updated recently; nothing needed refreshing"
 	} else {
 		response["message"] = "No prices were updated"
 	}
 
-	log.Printf("[POLYGON API] Price update completed: %d updated, %d failed", updated, failed)
+	log.Printf("[POLYGON API] Price update completed: %d updated, %d failed, %d skipped (manual price), %d recently updated", updated, failed, skipped, len(recent))
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -334,4 +345,55 @@ func (s *Server) polygonFetchDividendsHandler(w http.ResponseWriter, r *http.Req
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		log.Printf("[POLYGON API] Error encoding dividend fetch response: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+// polygonOptionSnapshotDebugHandler returns the raw Polygon snapshot for an existing option so
+// vendor Greeks can be inspected when they disagree with computed values
+func (s *Server) polygonOptionSnapshotDebugHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAPIToken(w, r) {
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid option ID", http.StatusBadRequest)
+		return
+	}
+
+	option, err := s.optionService.GetByID(id)
+	if err != nil {
+		http.Error(w, "Option not found", http.StatusNotFound)
+		return
+	}
+	if symbol := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("symbol"))); symbol != "" && symbol != option.Symbol {
+		http.Error(w, "Option not found", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("[POLYGON API] Debug snapshot request for option %d (%s)", option.ID, option.Symbol)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	contractSymbol, snapshot, err := s.polygonService.GetRawOptionSnapshot(ctx, option)
+
+	response := map[string]interface{}{
+		"option_id":       option.ID,
+		"symbol":          option.Symbol,
+		"contract_symbol": contractSymbol,
+		"snapshot":        snapshot,
+	}
+	if err != nil {
+		log.Printf("[POLYGON API] Debug snapshot failed for %s: %v", contractSymbol, err)
+		response["error"] = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[POLYGON API] Error encoding debug snapshot response: %v", err)
+	}
+}
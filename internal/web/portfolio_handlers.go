@@ -0,0 +1,1099 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"stonks/internal/models"
+	"stonks/internal/polygon"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// portfolioBetaHandler handles GET /api/portfolio/beta
+// Beta is weighted by adjusted long value; symbols without a beta are excluded from the weighting.
+func (s *Server) portfolioBetaHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[PORTFOLIO API] %s %s - Computing portfolio beta", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	openPositions, err := s.longPositionService.GetOpenPositions()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting open positions: %v", err)
+		http.Error(w, "Failed to get open positions", http.StatusInternalServerError)
+		return
+	}
+	if err := s.longPositionService.AttachExits(openPositions); err != nil {
+		log.Printf("[PORTFOLIO API] Error getting long position exits: %v", err)
+		http.Error(w, "Failed to get long position exits", http.StatusInternalServerError)
+		return
+	}
+
+	// Partially exited lots count only their remaining shares
+	valueBySymbol := make(map[string]float64)
+	var totalValue float64
+	for _, position := range openPositions {
+		remaining := position.RemainingShares()
+		if remaining <= 0 {
+			continue
+		}
+		value := position.CostBasisPerShare() * float64(remaining)
+		valueBySymbol[position.Symbol] += value
+		totalValue += value
+	}
+
+	response := PortfolioBetaResponse{
+		TotalLongValue: totalValue,
+		MissingBeta:    []string{},
+		Symbols:        []SymbolBetaWeight{},
+		Note:           "Weighted by adjusted long value of open positions. Options are not beta-weighted.",
+	}
+
+	var weightedBeta float64
+	for symbol, value := range valueBySymbol {
+		entry := SymbolBetaWeight{Symbol: symbol, LongValue: value}
+		if totalValue > 0 {
+			entry.Weight = value / totalValue
+		}
+
+		if symbolData, err := s.symbolService.GetBySymbol(symbol); err == nil && symbolData.Beta != nil {
+			entry.Beta = symbolData.Beta
+			weightedBeta += *symbolData.Beta * value
+			response.CoveredLongValue += value
+		} else {
+			response.MissingBeta = append(response.MissingBeta, symbol)
+		}
+		response.Symbols = append(response.Symbols, entry)
+	}
+
+	if response.CoveredLongValue > 0 {
+		response.PortfolioBeta = weightedBeta / response.CoveredLongValue
+	}
+
+	sort.Slice(response.Symbols, func(i, j int) bool {
+		return response.Symbols[i].LongValue > response.Symbols[j].LongValue
+	})
+	sort.Strings(response.MissingBeta)
+
+	log.Printf("[PORTFOLIO API] Portfolio beta %.2f across %d symbols (%d missing beta)", response.PortfolioBeta, len(response.Symbols), len(response.MissingBeta))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[PORTFOLIO API] Error encoding response: %v", err)
+	}
+}
+
+// defaultCapitalEfficiencyDays is the trailing period used when days is not given
+const defaultCapitalEfficiencyDays = 365
+
+// portfolioCapitalEfficiencyHandler handles GET /api/portfolio/capital-efficiency?days=
+//
+// Over the trailing period [today - days, today]:
+//
+//	realized   = net profit of options closed in the period
+//	           + gains on long shares sold in the period (against adjusted cost basis)
+//	           + dividends received in the period
+//	unrealized = marked profit of open options with a current price ((premium - current) x 100 x contracts)
+//	           + (symbol price - adjusted cost basis) x remaining shares of open long positions
+//	average capital = mean over snapshot dates in the period of put_exposure + long_value + treasury_value
+//	capital efficiency = (realized + unrealized) / average capital x days per year / days x 100
+//
+// Days per year and the period length follow ANNUALIZATION_BASIS: 365.25 over calendar days, or
+// 252 over the trading days in the period. Unrealized profit is the mark at the end of the period, not the change over it. When no metric
+// history exists in the period, today's live metrics stand in for the average.
+func (s *Server) portfolioCapitalEfficiencyHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[PORTFOLIO API] %s %s - Computing capital efficiency", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := defaultCapitalEfficiencyDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	end := s.marketToday()
+	start := end.AddDate(0, 0, -days)
+	periodEnd := end.AddDate(0, 0, 1)
+	inPeriod := func(date time.Time) bool {
+		return !date.Before(start) && date.Before(periodEnd)
+	}
+
+	annualization := s.annualizationBasis()
+	response := CapitalEfficiencyResponse{
+		Start:         start.Format("2006-01-02"),
+		End:           end.Format("2006-01-02"),
+		PeriodDays:    days,
+		Formula:       "(realized + unrealized) / average capital deployed x " + annualizationFormula(annualization) + " x 100",
+		Annualization: annualization,
+		DaysPerYear:   models.DaysPerYear(annualization),
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+	for _, option := range options {
+		if option.Closed != nil {
+			if inPeriod(*option.Closed) {
+				response.RealizedOptions += option.RealizedProfitValue()
+			}
+		} else if option.CurrentPrice != nil && !option.Opened.After(end) {
+			response.UnrealizedOptions += (option.Premium - *option.CurrentPrice) * 100 * float64(option.Contracts)
+		}
+	}
+
+	positions, err := s.longPositionService.GetAll()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting long positions: %v", err)
+		http.Error(w, "Failed to get long positions", http.StatusInternalServerError)
+		return
+	}
+	if err := s.longPositionService.AttachExits(positions); err != nil {
+		log.Printf("[PORTFOLIO API] Error getting long position exits: %v", err)
+		http.Error(w, "Failed to get long position exits", http.StatusInternalServerError)
+		return
+	}
+	prices := make(map[string]float64)
+	for _, position := range positions {
+		if len(position.Exits) > 0 {
+			for _, exit := range position.Exits {
+				if inPeriod(exit.Exited) {
+					response.RealizedLongs += (exit.Price - position.CostBasisPerShare()) * float64(exit.Shares)
+				}
+			}
+		} else if position.Closed != nil && inPeriod(*position.Closed) {
+			response.RealizedLongs += position.CalculateRealizedProfitLoss()
+		}
+
+		if remaining := position.RemainingShares(); remaining > 0 && !position.Opened.After(end) {
+			price, ok := prices[position.Symbol]
+			if !ok {
+				if symbolData, err := s.symbolService.GetBySymbol(position.Symbol); err == nil {
+					price = symbolData.Price
+				}
+				prices[position.Symbol] = price
+			}
+			if price > 0 {
+				response.UnrealizedLongs += (price - position.CostBasisPerShare()) * float64(remaining)
+			}
+		}
+	}
+
+	dividends, err := s.dividendService.GetAll()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting dividends: %v", err)
+		http.Error(w, "Failed to get dividends", http.StatusInternalServerError)
+		return
+	}
+	for _, dividend := range dividends {
+		if inPeriod(dividend.Received) {
+			response.Dividends += dividend.Amount
+		}
+	}
+
+	response.AverageCapital, response.SnapshotDays, response.CapitalBasis, err = s.averageCapitalDeployed(start, end)
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error averaging capital deployed: %v", err)
+		http.Error(w, "Failed to calculate capital deployed", http.StatusInternalServerError)
+		return
+	}
+
+	response.Realized = response.RealizedOptions + response.RealizedLongs + response.Dividends
+	response.Unrealized = response.UnrealizedOptions + response.UnrealizedLongs
+	response.TotalProfit = response.Realized + response.Unrealized
+	if response.AverageCapital > 0 {
+		response.PeriodReturnPercent = response.TotalProfit / response.AverageCapital * 100
+		response.CapitalEfficiencyPercent = response.PeriodReturnPercent * models.AnnualizationFactor(annualization, start, end, float64(days))
+	}
+
+	log.Printf("[PORTFOLIO API] Capital efficiency %.2f%% over %d days (profit $%.2f on average capital $%.2f)",
+		response.CapitalEfficiencyPercent, days, response.TotalProfit, response.AverageCapital)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[PORTFOLIO API] Error encoding response: %v", err)
+	}
+}
+
+// annualizationFormula describes how a trailing period is annualized on a basis, for formula strings
+func annualizationFormula(basis string) string {
+	if basis == models.AnnualizationTrading {
+		return fmt.Sprintf("%d / period trading days", models.TradingDaysPerYear)
+	}
+	return fmt.Sprintf("%g / period days", models.CalendarDaysPerYear)
+}
+
+// averageCapitalDeployed returns the average capital deployed between start and end from metric
+// history, with the number of snapshot days and the basis used ("history"). Without history in
+// the range, today's live put exposure, long value and treasury value stand in ("live").
+func (s *Server) averageCapitalDeployed(start, end time.Time) (float64, int, string, error) {
+	average, days, err := s.metricService.AverageCapitalDeployed(start, end)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	if days > 0 {
+		return average, days, "history", nil
+	}
+	live, err := s.metricService.CalculateMetricsForDate(end)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("failed to calculate live metrics: %w", err)
+	}
+	return live[models.PutExposure] + live[models.LongValue] + live[models.TreasuryValue], 0, "live", nil
+}
+
+// portfolioIncomeYieldHandler handles GET /api/portfolio/income-yield?days=
+//
+// Over the trailing period [today - days, today] (365 by default):
+//
+//	option premium    = net profit of options closed in the period, in the base currency
+//	dividends         = dividends received in the period
+//	treasury interest = coupons received in the period
+//	                  + proceeds - buy price of treasuries redeemed in the period (at the exit price,
+//	                    or face value once matured)
+//	income yield      = (option premium + dividends + treasury interest) / average capital x days per year / days x 100
+//
+// Average capital deployed and the ANNUALIZATION_BASIS day count are the same capital-efficiency uses. Each component is also
+// reported as its own annualized yield so the composite can be broken down.
+func (s *Server) portfolioIncomeYieldHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := defaultCapitalEfficiencyDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	end := s.marketToday()
+	start := end.AddDate(0, 0, -days)
+	periodEnd := end.AddDate(0, 0, 1)
+	inPeriod := func(date time.Time) bool {
+		return !date.Before(start) && date.Before(periodEnd)
+	}
+
+	annualization := s.annualizationBasis()
+	response := IncomeYieldResponse{
+		Start:         start.Format("2006-01-02"),
+		End:           end.Format("2006-01-02"),
+		PeriodDays:    days,
+		Formula:       "(option premium + dividends + treasury interest) / average capital deployed x " + annualizationFormula(annualization) + " x 100",
+		Annualization: annualization,
+		DaysPerYear:   models.DaysPerYear(annualization),
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+	for _, option := range options {
+		if option.Closed != nil && inPeriod(*option.Closed) {
+			response.OptionPremium += option.ToBase(option.RealizedProfitValue())
+		}
+	}
+
+	dividends, err := s.dividendService.GetAll()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting dividends: %v", err)
+		http.Error(w, "Failed to get dividends", http.StatusInternalServerError)
+		return
+	}
+	for _, dividend := range dividends {
+		if inPeriod(dividend.Received) {
+			response.Dividends += dividend.Amount
+		}
+	}
+
+	coupons, err := s.treasuryCouponService.GetAll()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting treasury coupons: %v", err)
+		http.Error(w, "Failed to get treasury coupons", http.StatusInternalServerError)
+		return
+	}
+	for _, coupon := range coupons {
+		if inPeriod(coupon.Received) {
+			response.TreasuryInterest += coupon.Amount
+		}
+	}
+	treasuries, err := s.treasuryService.GetAll()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting treasuries: %v", err)
+		http.Error(w, "Failed to get treasuries", http.StatusInternalServerError)
+		return
+	}
+	for _, treasury := range treasuries {
+		if !inPeriod(treasury.Maturity) || (treasury.ExitPrice == nil && treasury.Maturity.After(end)) {
+			continue
+		}
+		proceeds := treasury.Amount
+		if treasury.ExitPrice != nil {
+			proceeds = *treasury.ExitPrice
+		}
+		response.TreasuryInterest += proceeds - treasury.BuyPrice
+	}
+
+	response.AverageCapital, response.SnapshotDays, response.CapitalBasis, err = s.averageCapitalDeployed(start, end)
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error averaging capital deployed: %v", err)
+		http.Error(w, "Failed to calculate capital deployed", http.StatusInternalServerError)
+		return
+	}
+
+	response.TotalIncome = response.OptionPremium + response.Dividends + response.TreasuryInterest
+	if response.AverageCapital > 0 {
+		factor := models.AnnualizationFactor(annualization, start, end, float64(days))
+		annualize := func(amount float64) float64 {
+			return amount / response.AverageCapital * 100 * factor
+		}
+		response.PeriodYieldPercent = response.TotalIncome / response.AverageCapital * 100
+		response.OptionYieldPercent = annualize(response.OptionPremium)
+		response.DividendYieldPercent = annualize(response.Dividends)
+		response.TreasuryYieldPercent = annualize(response.TreasuryInterest)
+		response.IncomeYieldPercent = annualize(response.TotalIncome)
+	}
+
+	log.Printf("[PORTFOLIO API] Income yield %.2f%% over %d days (income $%.2f on average capital $%.2f)",
+		response.IncomeYieldPercent, days, response.TotalIncome, response.AverageCapital)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[PORTFOLIO API] Error encoding response: %v", err)
+	}
+}
+
+// portfolioDeltaExposureHandler handles GET /api/portfolio/delta-exposure-by-symbol
+//
+// Per symbol, directional exposure in dollars of the underlying:
+//
+//	share delta  = remaining shares of open long positions (delta 1)
+//	option delta = -delta x contracts x 100 for each open option (positions are short)
+//	exposure     = (share delta + option delta) x underlying price
+//
+// The underlying price is the symbol price, then the price reported with the Greeks, then the
+// strike of the symbol's first open option, with or without Greeks. Options without a Polygon delta are excluded; Coverage is the percent of open option
+// notional (strike x contracts x 100) that had one.
+func (s *Server) portfolioDeltaExposureHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[PORTFOLIO API] %s %s - Computing delta exposure by symbol", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	options, err := s.optionService.GetOpen()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting open options: %v", err)
+		http.Error(w, "Failed to get open options", http.StatusInternalServerError)
+		return
+	}
+	positions, err := s.longPositionService.GetOpenPositions()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting open positions: %v", err)
+		http.Error(w, "Failed to get open positions", http.StatusInternalServerError)
+		return
+	}
+	if err := s.longPositionService.AttachExits(positions); err != nil {
+		log.Printf("[PORTFOLIO API] Error getting long position exits: %v", err)
+		http.Error(w, "Failed to get long position exits", http.StatusInternalServerError)
+		return
+	}
+
+	bySymbol := make(map[string]*SymbolDeltaExposure)
+	entry := func(symbol string) *SymbolDeltaExposure {
+		e, ok := bySymbol[symbol]
+		if !ok {
+			e = &SymbolDeltaExposure{Symbol: symbol}
+			if symbolData, err := s.symbolService.GetBySymbol(symbol); err == nil && symbolData.Price > 0 {
+				e.UnderlyingPrice = symbolData.Price
+			}
+			bySymbol[symbol] = e
+		}
+		return e
+	}
+
+	for _, position := range positions {
+		if remaining := position.RemainingShares(); remaining > 0 {
+			entry(position.Symbol).Shares += remaining
+		}
+	}
+
+	response := DeltaExposureResponse{
+		Symbols:       []SymbolDeltaExposure{},
+		MissingGreeks: []string{},
+		Note:          "Options without a delta are excluded; coverage is the share of open option notional with Greeks.",
+	}
+
+	indices := make([]int, len(options))
+	for i := range options {
+		indices[i] = i
+	}
+	greeks := map[int]*polygon.OptionGreeks{}
+	if len(indices) > 0 {
+		greeks, response.Warnings = s.fetchPolygonGreeks(r.Context(), options, indices)
+	}
+
+	var totalNotional, coveredNotional float64
+	strikes := make(map[string]float64) // strike fallback per symbol, whether or not its options have Greeks
+	for i, option := range options {
+		e := entry(option.Symbol)
+		notional := option.CalculateExposure()
+		totalNotional += notional
+		if _, ok := strikes[option.Symbol]; !ok {
+			strikes[option.Symbol] = option.Strike
+		}
+
+		g := greeks[i]
+		if g == nil || g.Delta == nil {
+			e.OptionsMissing++
+			response.MissingGreeks = append(response.MissingGreeks, fmt.Sprintf("%s %s %.2f %s", option.Symbol, option.Type, option.Strike, option.Expiration.Format("2006-01-02")))
+			continue
+		}
+		if e.UnderlyingPrice == 0 && g.UnderlyingPrice != nil && *g.UnderlyingPrice > 0 {
+			e.UnderlyingPrice = *g.UnderlyingPrice
+		}
+		e.OptionDeltaShares += -*g.Delta * float64(option.Contracts) * 100
+		e.OptionsCovered++
+		coveredNotional += notional
+	}
+
+	for _, e := range bySymbol {
+		if e.UnderlyingPrice == 0 {
+			e.UnderlyingPrice = strikes[e.Symbol]
+		}
+		e.ShareExposure = float64(e.Shares) * e.UnderlyingPrice
+		e.OptionExposure = e.OptionDeltaShares * e.UnderlyingPrice
+		e.DeltaExposure = e.ShareExposure + e.OptionExposure
+		response.TotalDeltaExposure += e.DeltaExposure
+		response.Symbols = append(response.Symbols, *e)
+	}
+	if totalNotional > 0 {
+		response.Coverage = coveredNotional / totalNotional * 100
+	} else {
+		response.Coverage = 100
+	}
+
+	sort.Slice(response.Symbols, func(i, j int) bool {
+		return math.Abs(response.Symbols[i].DeltaExposure) > math.Abs(response.Symbols[j].DeltaExposure)
+	})
+
+	log.Printf("[PORTFOLIO API] Delta exposure $%.2f across %d symbols (%.1f%% option coverage)", response.TotalDeltaExposure, len(response.Symbols), response.Coverage)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[PORTFOLIO API] Error encoding response: %v", err)
+	}
+}
+
+// portfolioSharesEquivalentHandler handles GET /api/portfolio/shares-equivalent?account=
+//
+// Per symbol, in shares:
+//
+//	held    = remaining shares of open long positions
+//	at risk = contracts x 100 of open puts (shares bought if every put assigns)
+//	covered = contracts x 100 of open calls (shares called away if every call assigns)
+//	net     = held + at risk - covered
+//
+// Uncovered call shares are the covered shares beyond held and put-assigned shares. With
+// CALL_COVERAGE_TRACKING on, held is replaced by the stored call-to-lot links into open lots, each
+// lot counting at most its remaining shares, so a call is only covered by the lots it was written
+// against.
+func (s *Server) portfolioSharesEquivalentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	account := strings.TrimSpace(r.URL.Query().Get("account"))
+
+	options, err := s.optionService.GetOpen()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting open options: %v", err)
+		http.Error(w, "Failed to get open options", http.StatusInternalServerError)
+		return
+	}
+	positions, err := s.longPositionService.GetOpenPositions()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting open positions: %v", err)
+		http.Error(w, "Failed to get open positions", http.StatusInternalServerError)
+		return
+	}
+	if err := s.longPositionService.AttachExits(positions); err != nil {
+		log.Printf("[PORTFOLIO API] Error getting long position exits: %v", err)
+		http.Error(w, "Failed to get long position exits", http.StatusInternalServerError)
+		return
+	}
+
+	bySymbol := make(map[string]*SymbolSharesEquivalent)
+	entry := func(symbol string) *SymbolSharesEquivalent {
+		e, ok := bySymbol[symbol]
+		if !ok {
+			e = &SymbolSharesEquivalent{Symbol: symbol}
+			bySymbol[symbol] = e
+		}
+		return e
+	}
+	for _, position := range positions {
+		if account != "" && position.Account != account {
+			continue
+		}
+		if remaining := position.RemainingShares(); remaining > 0 {
+			entry(position.Symbol).HeldShares += remaining
+		}
+	}
+	for _, option := range options {
+		if account != "" && option.Account != account {
+			continue
+		}
+		switch option.Type {
+		case "Put":
+			entry(option.Symbol).PutSharesAtRisk += option.Contracts * 100
+		case "Call":
+			entry(option.Symbol).CallSharesCovered += option.Contracts * 100
+		}
+	}
+
+	tracked := s.longPositionService.CallCoverageTracked()
+	if tracked {
+		coverage, err := s.longPositionService.GetOpenCallCoverage()
+		if err != nil {
+			log.Printf("[PORTFOLIO API] Error getting call coverage: %v", err)
+			http.Error(w, "Failed to get call coverage", http.StatusInternalServerError)
+			return
+		}
+		openCalls := make(map[int]*models.Option)
+		for _, option := range options {
+			if option.Type == "Call" && (account == "" || option.Account == account) {
+				openCalls[option.ID] = option
+			}
+		}
+		openLots := make(map[int]*models.LongPosition)
+		for _, position := range positions {
+			openLots[position.ID] = position
+		}
+		linkedByLot := make(map[int]int)
+		for _, c := range coverage {
+			if openCalls[c.OptionID] == nil || openLots[c.PositionID] == nil {
+				continue
+			}
+			linkedByLot[c.PositionID] += c.Shares
+		}
+		for positionID, shares := range linkedByLot {
+			lot := openLots[positionID]
+			e := entry(lot.Symbol)
+			if e.LinkedCallShares == nil {
+				e.LinkedCallShares = new(int)
+			}
+			*e.LinkedCallShares += min(shares, max(lot.RemainingShares(), 0))
+		}
+	}
+
+	response := SharesEquivalentResponse{Account: account, CoverageTracking: tracked, Symbols: []SymbolSharesEquivalent{}}
+	for _, e := range bySymbol {
+		e.NetShares = e.HeldShares + e.PutSharesAtRisk - e.CallSharesCovered
+		backing := e.HeldShares
+		if tracked {
+			backing = 0
+			if e.LinkedCallShares != nil {
+				backing = *e.LinkedCallShares
+			}
+		}
+		e.UncoveredCallShares = e.CallSharesCovered - backing - e.PutSharesAtRisk
+		if e.UncoveredCallShares < 0 {
+			e.UncoveredCallShares = 0
+		}
+		response.Symbols = append(response.Symbols, *e)
+	}
+	sort.Slice(response.Symbols, func(i, j int) bool {
+		return response.Symbols[i].Symbol < response.Symbols[j].Symbol
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[PORTFOLIO API] Error encoding response: %v", err)
+	}
+}
+
+// portfolioPnLSplitHandler handles GET /api/portfolio/pnl-split, splitting P/L into realized
+// (closed options, sold shares and split cash in lieu) and unrealized (open shares at the symbol price, open options
+// marked to current_price). Components that cannot be marked are reported as unavailable.
+func (s *Server) portfolioPnLSplitHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+	positions, err := s.longPositionService.GetAll()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting long positions: %v", err)
+		http.Error(w, "Failed to get long positions", http.StatusInternalServerError)
+		return
+	}
+	if err := s.longPositionService.AttachExits(positions); err != nil {
+		log.Printf("[PORTFOLIO API] Error getting long position exits: %v", err)
+		http.Error(w, "Failed to get long position exits", http.StatusInternalServerError)
+		return
+	}
+	splits, err := s.longPositionService.GetSplits("")
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting stock splits: %v", err)
+		http.Error(w, "Failed to get stock splits", http.StatusInternalServerError)
+		return
+	}
+	symbols, err := s.symbolService.GetAll()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting symbols: %v", err)
+		http.Error(w, "Failed to get symbols", http.StatusInternalServerError)
+		return
+	}
+	prices := make(map[string]float64, len(symbols))
+	for _, symbol := range symbols {
+		prices[symbol.Symbol] = symbol.Price
+	}
+
+	type accumulator struct {
+		split             SymbolPnLSplit
+		unrealizedOptions float64
+		unrealizedStock   float64
+		hasOpenOptions    bool
+		hasOpenStock      bool
+	}
+	bySymbol := make(map[string]*accumulator)
+	entry := func(symbol string) *accumulator {
+		a, ok := bySymbol[symbol]
+		if !ok {
+			a = &accumulator{split: SymbolPnLSplit{Symbol: symbol}}
+			bySymbol[symbol] = a
+		}
+		return a
+	}
+
+	for _, option := range options {
+		a := entry(option.Symbol)
+		if option.Closed != nil {
+			a.split.RealizedOptions += option.RealizedProfitValue()
+			continue
+		}
+		a.hasOpenOptions = true
+		if option.CurrentPrice == nil {
+			a.split.OptionsMissingMark++
+			continue
+		}
+		a.unrealizedOptions += (option.Premium-*option.CurrentPrice)*float64(option.Contracts)*100 - option.TransactionCosts()
+	}
+
+	for _, position := range positions {
+		a := entry(position.Symbol)
+		a.split.RealizedStock += position.CalculateRealizedProfitLoss()
+		remaining := position.RemainingShares()
+		if remaining <= 0 {
+			continue
+		}
+		a.hasOpenStock = true
+		price := prices[position.Symbol]
+		if price <= 0 {
+			a.split.StockMissingPrice = true
+			continue
+		}
+		a.unrealizedStock += (price - position.CostBasisPerShare()) * float64(remaining)
+	}
+	// Cash in lieu of fractional split shares is realized outside the lot's exits
+	for _, split := range splits {
+		entry(split.Symbol).split.RealizedStock += split.Realized
+	}
+
+	response := PnLSplitResponse{Symbols: []SymbolPnLSplit{}, UnrealizedComplete: true, Unavailable: []string{}}
+	for _, a := range bySymbol {
+		split := a.split
+		split.Realized = split.RealizedOptions + split.RealizedStock
+		response.TotalRealized += split.Realized
+
+		complete := true
+		var unrealized float64
+		if a.hasOpenOptions {
+			if split.OptionsMissingMark == 0 {
+				value := a.unrealizedOptions
+				split.UnrealizedOptions = &value
+				unrealized += value
+			} else {
+				complete = false
+				response.Unavailable = append(response.Unavailable, fmt.Sprintf("%s: %d open option(s) without a current price", split.Symbol, split.OptionsMissingMark))
+			}
+		} else {
+			zero := 0.0
+			split.UnrealizedOptions = &zero
+		}
+		if a.hasOpenStock {
+			if !split.StockMissingPrice {
+				value := a.unrealizedStock
+				split.UnrealizedStock = &value
+				unrealized += value
+			} else {
+				complete = false
+				response.Unavailable = append(response.Unavailable, fmt.Sprintf("%s: open shares without a symbol price", split.Symbol))
+			}
+		} else {
+			zero := 0.0
+			split.UnrealizedStock = &zero
+		}
+		if complete {
+			split.Unrealized = &unrealized
+		} else {
+			response.UnrealizedComplete = false
+		}
+		response.TotalUnrealized += unrealized
+		response.Symbols = append(response.Symbols, split)
+	}
+	sort.Slice(response.Symbols, func(i, j int) bool {
+		return response.Symbols[i].Symbol < response.Symbols[j].Symbol
+	})
+	sort.Strings(response.Unavailable)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[PORTFOLIO API] Error encoding response: %v", err)
+	}
+}
+
+// portfolioRiskAdjustedHandler handles GET /api/portfolio/risk-adjusted?days=, computing daily
+// returns of the total_value metric series, their volatility and an annualized Sharpe-like ratio
+// against RISK_FREE_RATE_PERCENT. days limits the history to the trailing calendar days (default all).
+func (s *Server) portfolioRiskAdjustedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var since time.Time
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days <= 0 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		since = s.marketToday().AddDate(0, 0, -days)
+	}
+
+	metrics, err := s.metricService.GetByType(models.TotalValue)
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting total value history: %v", err)
+		http.Error(w, "Failed to get metric history", http.StatusInternalServerError)
+		return
+	}
+	if !since.IsZero() {
+		var filtered []*models.Metric
+		for _, metric := range metrics {
+			if !metric.Created.Before(since) {
+				filtered = append(filtered, metric)
+			}
+		}
+		metrics = filtered
+	}
+
+	riskFree := s.settingService.GetFloatWithDefault("RISK_FREE_RATE_PERCENT", 5)
+	response := RiskAdjustedResponse{
+		RiskAdjustedStats: models.ComputeRiskAdjustedReturn(metrics, riskFree),
+		Metric:            string(models.TotalValue),
+		Assumptions: []string{
+			"Returns are day-over-day changes in total_value; deposits and withdrawals are not separated out and count as returns",
+			"Snapshots on weekends or holidays count for the preceding trading day; the latest snapshot on a trading day is used",
+			"A return spanning trading days without a snapshot is converted to its equivalent daily rate",
+			fmt.Sprintf("Annualized with %d trading days; Sharpe = (mean daily return - daily risk-free rate) / daily std dev x sqrt(%d)", models.TradingDaysPerYear, models.TradingDaysPerYear),
+			"The risk-free rate is RISK_FREE_RATE_PERCENT compounded to a daily rate",
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[PORTFOLIO API] Error encoding response: %v", err)
+	}
+}
+
+// portfolioDrawdownHandler handles GET /api/portfolio/drawdown?days=&metric=, computing the maximum
+// and current drawdown from peak over a value metric series (default total_value) with the peak and
+// trough dates. days limits the history to the trailing calendar days (default all).
+func (s *Server) portfolioDrawdownHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	metricType := models.TotalValue
+	switch value := models.MetricType(strings.TrimSpace(r.URL.Query().Get("metric"))); value {
+	case "":
+	case models.TotalValue, models.LongValue, models.TreasuryValue:
+		metricType = value
+	default:
+		http.Error(w, "metric must be total_value, long_value or treasury_value", http.StatusBadRequest)
+		return
+	}
+
+	var since time.Time
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days <= 0 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		since = s.marketToday().AddDate(0, 0, -days)
+	}
+
+	metrics, err := s.metricService.GetByType(metricType)
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting %s history: %v", metricType, err)
+		http.Error(w, "Failed to get metric history", http.StatusInternalServerError)
+		return
+	}
+	if !since.IsZero() {
+		var filtered []*models.Metric
+		for _, metric := range metrics {
+			if !metric.Created.Before(since) {
+				filtered = append(filtered, metric)
+			}
+		}
+		metrics = filtered
+	}
+
+	response := DrawdownResponse{
+		DrawdownStats: models.ComputeDrawdown(metrics),
+		Metric:        string(metricType),
+	}
+	if response.Observations < 2 {
+		response.Note = "Fewer than two days of history; drawdown is zero until more snapshots are recorded."
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[PORTFOLIO API] Error encoding response: %v", err)
+	}
+}
+
+// Defaults for GET /api/portfolio/correlation
+const (
+	defaultCorrelationMinObservations = 20
+	defaultCorrelationPairs           = 5
+)
+
+// portfolioCorrelationHandler handles GET /api/portfolio/correlation?days=&min_observations=&limit=,
+// correlating the daily returns of every symbol with an open position from the recorded close
+// history. Symbols with fewer than min_observations returns (default 20) are excluded with a note;
+// a pair that shares fewer returns than that is left null in the matrix. days limits the history
+// to the trailing calendar days (default all) and limit caps the most-correlated pairs (default 5).
+func (s *Server) portfolioCorrelationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var since time.Time
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days <= 0 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		since = s.marketToday().AddDate(0, 0, -days)
+	}
+
+	minObservations := defaultCorrelationMinObservations
+	if minStr := r.URL.Query().Get("min_observations"); minStr != "" {
+		value, err := strconv.Atoi(minStr)
+		if err != nil || value < 2 {
+			http.Error(w, "min_observations must be an integer of at least 2", http.StatusBadRequest)
+			return
+		}
+		minObservations = value
+	}
+
+	limit := defaultCorrelationPairs
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		value, err := strconv.Atoi(limitStr)
+		if err != nil || value <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = value
+	}
+
+	symbols, err := s.symbolService.GetActivePositionSymbols()
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting open position symbols: %v", err)
+		http.Error(w, "Failed to get open positions", http.StatusInternalServerError)
+		return
+	}
+	history, err := s.symbolService.GetCloseHistory(symbols, since)
+	if err != nil {
+		log.Printf("[PORTFOLIO API] Error getting price history: %v", err)
+		http.Error(w, "Failed to get price history", http.StatusInternalServerError)
+		return
+	}
+
+	response := CorrelationResponse{
+		MinObservations: minObservations,
+		Symbols:         []string{},
+		Matrix:          [][]*float64{},
+		Pairs:           []models.ReturnCorrelation{},
+		Excluded:        []CorrelationExclusion{},
+	}
+	returns := make(map[string]map[string]float64)
+	for _, symbol := range symbols {
+		series := models.DailyReturns(history[symbol])
+		if len(series) < minObservations {
+			response.Excluded = append(response.Excluded, CorrelationExclusion{
+				Symbol:  symbol,
+				Returns: len(series),
+				Note:    fmt.Sprintf("Only %d daily returns recorded; at least %d are needed", len(series), minObservations),
+			})
+			continue
+		}
+		returns[symbol] = series
+		response.Symbols = append(response.Symbols, symbol)
+	}
+
+	for i, a := range response.Symbols {
+		row := make([]*float64, len(response.Symbols))
+		one := 1.0
+		row[i] = &one
+		response.Matrix = append(response.Matrix, row)
+		for j := 0; j < i; j++ {
+			b := response.Symbols[j]
+			pair, ok := models.CorrelateReturns(returns[b], returns[a], minObservations)
+			if !ok {
+				continue
+			}
+			pair.SymbolA, pair.SymbolB = b, a
+			value := pair.Correlation
+			row[j] = &value
+			response.Matrix[j][i] = &value
+			response.Pairs = append(response.Pairs, pair)
+		}
+	}
+	sort.SliceStable(response.Pairs, func(i, j int) bool {
+		return response.Pairs[i].Correlation > response.Pairs[j].Correlation
+	})
+	if len(response.Pairs) > limit {
+		response.Pairs = response.Pairs[:limit]
+	}
+
+	if len(response.Symbols) < 2 {
+		response.Note = "Fewer than two symbols have enough recorded closes to correlate; history builds up as prices are updated."
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[PORTFOLIO API] Error encoding response: %v", err)
+	}
+}
+
+// Portfolio alert rules and severities
+const (
+	AlertRulePutConcentration = "put_concentration"
+	AlertSeverityWarning      = "warning"
+)
+
+// alertsHandler handles GET /api/alerts, running the portfolio-risk rules over open positions.
+// The put concentration rule flags symbols whose open put exposure (strike x contracts x 100 in
+// base currency) exceeds PUT_CONCENTRATION_ALERT_PERCENT of total open put exposure.
+func (s *Server) alertsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[ALERTS API] %s %s - Checking portfolio alerts", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	options, err := s.optionService.GetOpen()
+	if err != nil {
+		log.Printf("[ALERTS API] Error getting open options: %v", err)
+		http.Error(w, "Failed to get open options", http.StatusInternalServerError)
+		return
+	}
+
+	response := s.portfolioAlerts(options)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[ALERTS API] Error encoding response: %v", err)
+	}
+}
+
+// portfolioAlerts runs the portfolio-risk rules over the given open options
+func (s *Server) portfolioAlerts(options []*models.Option) AlertsResponse {
+	check := putConcentrationCheck(options, s.settingService.GetFloatWithDefault("PUT_CONCENTRATION_ALERT_PERCENT", 25))
+	response := AlertsResponse{
+		Alerts:           []PortfolioAlert{},
+		PutConcentration: check,
+	}
+	for _, offending := range check.Offending {
+		response.Alerts = append(response.Alerts, PortfolioAlert{
+			Rule:      AlertRulePutConcentration,
+			Severity:  AlertSeverityWarning,
+			Symbol:    offending.Symbol,
+			Value:     offending.ConcentrationPercent,
+			Threshold: check.ThresholdPercent,
+			Message:   fmt.Sprintf("%s is %.1f%% of open put exposure (threshold %.1f%%)", offending.Symbol, offending.ConcentrationPercent, check.ThresholdPercent),
+		})
+	}
+	return response
+}
+
+// putConcentrationCheck aggregates open put exposure by symbol and returns the symbols above
+// thresholdPercent of the total, most concentrated first
+func putConcentrationCheck(options []*models.Option, thresholdPercent float64) PutConcentrationCheck {
+	check := PutConcentrationCheck{ThresholdPercent: thresholdPercent, Offending: []SymbolPutConcentration{}}
+
+	exposureBySymbol := make(map[string]float64)
+	for _, option := range options {
+		if option.Type != "Put" {
+			continue
+		}
+		exposure := option.ToBase(option.CalculateExposure())
+		exposureBySymbol[option.Symbol] += exposure
+		check.TotalPutExposure += exposure
+	}
+	if check.TotalPutExposure <= 0 {
+		return check
+	}
+
+	for symbol, exposure := range exposureBySymbol {
+		percent := exposure / check.TotalPutExposure * 100
+		if percent > thresholdPercent {
+			check.Offending = append(check.Offending, SymbolPutConcentration{Symbol: symbol, PutExposure: exposure, ConcentrationPercent: percent})
+		}
+	}
+	sort.Slice(check.Offending, func(i, j int) bool {
+		if check.Offending[i].ConcentrationPercent != check.Offending[j].ConcentrationPercent {
+			return check.Offending[i].ConcentrationPercent > check.Offending[j].ConcentrationPercent
+		}
+		return check.Offending[i].Symbol < check.Offending[j].Symbol
+	})
+	return check
+}
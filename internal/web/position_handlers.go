@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"sort"
 	"stonks/internal/models"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -43,12 +45,40 @@ func (s *Server) createDividendHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create the dividend
-	dividend, err := s.dividendService.Create(req.Symbol, receivedDate, req.Amount)
+	// Without a reinvested or cash split the whole dividend is paid as cash
+	if req.ReinvestedAmount == nil && req.CashAmount == nil {
+		dividend, err := s.dividendService.Create(req.Symbol, receivedDate, req.Amount)
+		if err != nil {
+			http.Error(w, "Failed to create dividend", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(dividend)
+		return
+	}
+
+	var reinvested, cash float64
+	switch {
+	case req.ReinvestedAmount != nil && req.CashAmount != nil:
+		reinvested, cash = *req.ReinvestedAmount, *req.CashAmount
+	case req.ReinvestedAmount != nil:
+		reinvested = *req.ReinvestedAmount
+		cash = req.Amount - reinvested
+	default:
+		cash = *req.CashAmount
+		reinvested = req.Amount - cash
+	}
+
+	dividend, err := s.dividendService.CreateWithReinvestment(req.Symbol, receivedDate, req.Amount, reinvested, cash, req.ReinvestPrice)
 	if err != nil {
-		http.Error(w, "Failed to create dividend", http.StatusInternalServerError)
+		log.Printf("Error creating reinvested dividend: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to create dividend: %v", err), http.StatusBadRequest)
 		return
 	}
+	if dividend.ReinvestedPositionID != nil {
+		log.Printf("Reinvested $%.2f of %s dividend into long position %d", dividend.ReinvestedAmount, req.Symbol, *dividend.ReinvestedPositionID)
+		s.recalculateAdjustedCostBasis(req.Symbol)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(dividend)
@@ -86,6 +116,10 @@ func (s *Server) deleteDividendHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	// A reinvested dividend takes the lot it bought with it
+	if req.Symbol != "" {
+		s.recalculateAdjustedCostBasis(req.Symbol)
+	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"success": true}`))
@@ -102,6 +136,11 @@ func (s *Server) dividendsHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to load open positions", http.StatusInternalServerError)
 		return
 	}
+	if err := s.longPositionService.AttachExits(openPositions); err != nil {
+		log.Printf("[DIVIDENDS] Error getting position exits: %v", err)
+		http.Error(w, "Failed to load open positions", http.StatusInternalServerError)
+		return
+	}
 
 	// Build dividend data for symbols with open positions
 	// Use a map to aggregate positions by symbol
@@ -137,14 +176,15 @@ func (s *Server) dividendsHandler(w http.ResponseWriter, r *http.Request) {
 				yieldPercent = (annualDividend / symbol.Price) * 100
 			}
 
-			// Calculate total dividend income: shares x quarterly dividend x 4
-			positionAnnualIncome := float64(position.Shares) * annualDividend
+			// Calculate total dividend income: remaining shares x quarterly dividend x 4
+			shares := position.RemainingShares()
+			positionAnnualIncome := float64(shares) * annualDividend
 			totalAnnualIncome += positionAnnualIncome
 
 			// Check if we already have this symbol in the map
 			if existing, exists := dividendSymbolsMap[position.Symbol]; exists {
 				// Aggregate shares and income
-				existing.Shares += position.Shares
+				existing.Shares += shares
 				existing.TotalAnnualIncome += positionAnnualIncome
 				existing.Positions = append(existing.Positions, position)
 				incomeBySymbolMap[position.Symbol] += positionAnnualIncome
@@ -167,7 +207,7 @@ func (s *Server) dividendsHandler(w http.ResponseWriter, r *http.Request) {
 					YieldPercent:      yieldPercent,
 					ExDividendDate:    symbol.ExDividendDate,
 					DividendCount:     len(dividends),
-					Shares:            position.Shares,
+					Shares:            shares,
 					TotalAnnualIncome: positionAnnualIncome,
 					Positions:         []*models.LongPosition{position},
 					DividendPayments:  dividends,
@@ -298,7 +338,7 @@ func (s *Server) dividendsHandler(w http.ResponseWriter, r *http.Request) {
 			Title:      "Dividends",
 			ActivePage: "dividends",
 			CurrentDB:  s.getCurrentDatabaseName(),
-			AllSymbols: s.getAllSymbolsList(),
+			AllSymbols: s.getAllSymbolsList(r),
 		},
 		DividendSymbols:   dividendSymbols,
 		IncomeBySymbol:    incomeBySymbol,
@@ -320,6 +360,8 @@ func (s *Server) dividendsHandler(w http.ResponseWriter, r *http.Request) {
 // longPositionsAPIHandler handles CRUD operations for long positions
 func (s *Server) longPositionsAPIHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
+	case http.MethodGet:
+		s.listLongPositionsHandler(w, r)
 	case http.MethodPost:
 		s.createLongPositionHandler(w, r)
 	case http.MethodPut:
@@ -331,6 +373,38 @@ func (s *Server) longPositionsAPIHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// listLongPositionsHandler returns long positions, optionally filtered by ?symbol= and ?account=
+func (s *Server) listLongPositionsHandler(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("symbol")))
+	account := strings.TrimSpace(r.URL.Query().Get("account"))
+
+	var positions []*models.LongPosition
+	var err error
+	if symbol != "" {
+		positions, err = s.longPositionService.GetBySymbol(symbol)
+	} else {
+		positions, err = s.longPositionService.GetAll()
+	}
+	if err != nil {
+		log.Printf("Error getting long positions: %v", err)
+		http.Error(w, "Failed to get long positions", http.StatusInternalServerError)
+		return
+	}
+
+	result := []*models.LongPosition{}
+	for _, position := range positions {
+		if account == "" || position.Account == account {
+			result = append(result, position)
+		}
+	}
+	if err := s.attachProvisionalBasis(result); err != nil {
+		log.Printf("Error computing provisional basis: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
 // createLongPositionHandler creates a new long position
 func (s *Server) createLongPositionHandler(w http.ResponseWriter, r *http.Request) {
 	var req LongPositionRequest
@@ -351,6 +425,9 @@ func (s *Server) createLongPositionHandler(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Invalid opened date format", http.StatusBadRequest)
 		return
 	}
+	if s.rejectFutureOpened(w, openedDate) {
+		return
+	}
 
 	// Create the long position
 	position, err := s.longPositionService.Create(req.Symbol, openedDate, req.Shares, req.BuyPrice)
@@ -360,6 +437,15 @@ func (s *Server) createLongPositionHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if req.Account != nil {
+		if err := s.longPositionService.SetAccount(position.ID, *req.Account); err != nil {
+			log.Printf("Error setting long position account: %v", err)
+			http.Error(w, "Failed to set long position account", http.StatusInternalServerError)
+			return
+		}
+		position.Account = models.NormalizeAccount(*req.Account)
+	}
+
 	// If closed date and/or exit price are provided, update them
 	if req.Closed != nil && *req.Closed != "" {
 		closedDate, err := time.Parse("2006-01-02", *req.Closed)
@@ -408,6 +494,9 @@ func (s *Server) updateLongPositionHandler(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Invalid opened date format", http.StatusBadRequest)
 		return
 	}
+	if s.rejectFutureOpened(w, openedDate) {
+		return
+	}
 
 	var closedDate *time.Time
 	if req.Closed != nil && *req.Closed != "" {
@@ -427,10 +516,140 @@ func (s *Server) updateLongPositionHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if req.Account != nil {
+		if err := s.longPositionService.SetAccount(position.ID, *req.Account); err != nil {
+			log.Printf("Error setting long position account: %v", err)
+			http.Error(w, "Failed to set long position account", http.StatusInternalServerError)
+			return
+		}
+		position.Account = models.NormalizeAccount(*req.Account)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(position)
 }
 
+// longPositionExitsAPIHandler records or removes partial exit events for a long position
+func (s *Server) longPositionExitsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		positionID, err := strconv.Atoi(r.URL.Query().Get("position_id"))
+		if err != nil {
+			http.Error(w, "Invalid position_id", http.StatusBadRequest)
+			return
+		}
+		exits, err := s.longPositionService.GetExits(positionID)
+		if err != nil {
+			log.Printf("Error getting long position exits: %v", err)
+			http.Error(w, "Failed to get long position exits", http.StatusInternalServerError)
+			return
+		}
+		if exits == nil {
+			exits = []*models.LongPositionExit{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(exits)
+	case http.MethodPost:
+		var req LongPositionExitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if req.PositionID == 0 || req.Exited == "" || req.Shares <= 0 || req.Price <= 0 {
+			http.Error(w, "Position ID, exit date, shares, and price are required", http.StatusBadRequest)
+			return
+		}
+		exitedDate, err := time.Parse("2006-01-02", req.Exited)
+		if err != nil {
+			http.Error(w, "Invalid exit date format", http.StatusBadRequest)
+			return
+		}
+
+		exit, err := s.longPositionService.AddExit(req.PositionID, exitedDate, req.Shares, req.Price)
+		if err != nil {
+			log.Printf("Error recording long position exit: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to record exit: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(exit)
+	case http.MethodDelete:
+		var req LongPositionExitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if req.ID == nil {
+			http.Error(w, "ID is required", http.StatusBadRequest)
+			return
+		}
+		if err := s.longPositionService.DeleteExit(*req.ID); err != nil {
+			log.Printf("Error deleting long position exit: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to delete exit: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"success": true}`))
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// stockSplitsAPIHandler handles /api/long-positions/splits: GET lists recorded splits (optionally
+// ?symbol=) and POST applies a split to the symbol's open lots
+func (s *Server) stockSplitsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		symbol := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("symbol")))
+		splits, err := s.longPositionService.GetSplits(symbol)
+		if err != nil {
+			log.Printf("Error getting stock splits: %v", err)
+			http.Error(w, "Failed to get stock splits", http.StatusInternalServerError)
+			return
+		}
+		if splits == nil {
+			splits = []*models.StockSplit{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(splits)
+	case http.MethodPost:
+		var req StockSplitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+		if symbol == "" || req.Effective == "" || req.SplitTo <= 0 || req.SplitFrom <= 0 {
+			http.Error(w, "Symbol, effective date, split_to and split_from are required", http.StatusBadRequest)
+			return
+		}
+		effective, err := time.Parse("2006-01-02", req.Effective)
+		if err != nil {
+			http.Error(w, "Invalid effective date format", http.StatusBadRequest)
+			return
+		}
+		rounding := req.Rounding
+		if rounding == "" {
+			rounding = s.settingService.GetValueWithDefault("SPLIT_ROUNDING", models.SplitRoundingCashInLieu)
+		}
+
+		splits, err := s.longPositionService.ApplySplit(symbol, effective, req.SplitTo, req.SplitFrom, rounding, req.CashInLieuPrice)
+		if err != nil {
+			log.Printf("Error applying %d-for-%d split to %s: %v", req.SplitTo, req.SplitFrom, symbol, err)
+			http.Error(w, fmt.Sprintf("Failed to apply split: %v", err), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Applied %d-for-%d split to %d %s lot(s) (%s)", req.SplitTo, req.SplitFrom, len(splits), symbol, rounding)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(splits)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
 // deleteLongPositionHandler deletes a long position
 func (s *Server) deleteLongPositionHandler(w http.ResponseWriter, r *http.Request) {
 	var req LongPositionRequest
@@ -468,3 +687,50 @@ func (s *Server) deleteLongPositionHandler(w http.ResponseWriter, r *http.Reques
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"success": true}`))
 }
+
+// attachProvisionalBasis adds the display-only basis that nets open call premium when
+// BASIS_DEFER_OPEN_CALL_PREMIUM is on
+func (s *Server) attachProvisionalBasis(positions []*models.LongPosition) error {
+	if !s.settingService.GetBoolWithDefault("BASIS_DEFER_OPEN_CALL_PREMIUM", false) {
+		return nil
+	}
+	return s.longPositionService.AttachProvisionalBasis(positions)
+}
+
+// positionBasisDetailHandler handles GET /api/positions/{id}/basis-detail, returning the lot's
+// original cost, each put/call premium allocated to it and the resulting adjusted basis
+func (s *Server) positionBasisDetailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/positions/")
+	idPart, ok := strings.CutSuffix(path, "/basis-detail")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	positionID, err := strconv.Atoi(idPart)
+	if err != nil {
+		http.Error(w, "Invalid position ID", http.StatusBadRequest)
+		return
+	}
+
+	detail, err := s.longPositionService.GetBasisDetail(positionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Long position not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("[BASIS DETAIL] Failed to compute basis detail for position %d: %v", positionID, err)
+		http.Error(w, "Failed to compute basis detail", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(detail); err != nil {
+		log.Printf("[BASIS DETAIL] Error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,505 @@
+package web
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"stonks/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// washSaleWindowDays is the IRS window on either side of a loss sale in which a replacement purchase disallows the loss
+const washSaleWindowDays = 30
+
+// harvestCandidatesHandler handles GET /api/reports/harvest-candidates?account=&window_days=
+// It lists open long lots priced below their adjusted cost basis, largest loss first. A lot is
+// flagged wash_sale when another lot of the symbol was bought within window_days (default 30)
+// before today, since selling now would disallow the loss; open short puts on the symbol get a
+// caution because an assignment inside the window would buy replacement shares. Adjusted basis
+// nets option premium, so it can differ from the broker's tax basis.
+func (s *Server) harvestCandidatesHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[REPORTS API] %s %s - Finding tax-loss harvest candidates", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	windowDays := washSaleWindowDays
+	if value := strings.TrimSpace(query.Get("window_days")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 || parsed > 365 {
+			http.Error(w, "window_days must be between 0 and 365", http.StatusBadRequest)
+			return
+		}
+		windowDays = parsed
+	}
+	account := strings.TrimSpace(query.Get("account"))
+
+	// Every lot counts toward wash sales, including closed ones and other accounts
+	positions, err := s.longPositionService.GetAll()
+	if err != nil {
+		log.Printf("[REPORTS API] Error getting long positions: %v", err)
+		http.Error(w, "Failed to get long positions", http.StatusInternalServerError)
+		return
+	}
+	if err := s.longPositionService.AttachExits(positions); err != nil {
+		log.Printf("[REPORTS API] Error getting long position exits: %v", err)
+		http.Error(w, "Failed to get long position exits", http.StatusInternalServerError)
+		return
+	}
+	openPuts, err := s.optionService.GetOpenByType("", "Put")
+	if err != nil {
+		log.Printf("[REPORTS API] Error getting open puts: %v", err)
+		http.Error(w, "Failed to get open options", http.StatusInternalServerError)
+		return
+	}
+	putsBySymbol := make(map[string]int)
+	for _, put := range openPuts {
+		putsBySymbol[put.Symbol] += put.Contracts
+	}
+
+	today := s.marketToday()
+	windowStart := today.AddDate(0, 0, -windowDays)
+	response := HarvestCandidatesResponse{
+		AsOf:         today.Format("2006-01-02"),
+		WindowDays:   windowDays,
+		Candidates:   []HarvestCandidate{},
+		MissingPrice: []string{},
+		Note:         "Loss is measured against adjusted cost basis, which nets option premium and may differ from your broker's tax basis. Buying the symbol within the window after selling also triggers a wash sale.",
+	}
+	missing := make(map[string]bool)
+	for _, position := range positions {
+		remaining := position.RemainingShares()
+		if remaining <= 0 || position.Opened.After(today) {
+			continue
+		}
+		if account != "" && position.Account != models.NormalizeAccount(account) {
+			continue
+		}
+
+		symbolData, err := s.symbolService.GetBySymbol(position.Symbol)
+		if err != nil || symbolData.Price <= 0 {
+			if !missing[position.Symbol] {
+				missing[position.Symbol] = true
+				response.MissingPrice = append(response.MissingPrice, position.Symbol)
+			}
+			continue
+		}
+		basis := position.CostBasisPerShare()
+		if symbolData.Price >= basis {
+			continue
+		}
+
+		candidate := HarvestCandidate{
+			PositionID:        position.ID,
+			Symbol:            position.Symbol,
+			Account:           position.Account,
+			Opened:            position.Opened.Format("2006-01-02"),
+			Shares:            remaining,
+			CostBasisPerShare: basis,
+			CurrentPrice:      symbolData.Price,
+			Loss:              (basis - symbolData.Price) * float64(remaining),
+			LossPercent:       (basis - symbolData.Price) / basis * 100,
+			LongTerm:          !position.Opened.AddDate(1, 0, 0).After(today),
+			ReplacementLots:   []int{},
+			Cautions:          []string{},
+		}
+		for _, other := range positions {
+			if other.ID == position.ID || other.Symbol != position.Symbol || other.Opened.Before(windowStart) || other.Opened.After(today) {
+				continue
+			}
+			candidate.ReplacementLots = append(candidate.ReplacementLots, other.ID)
+		}
+		if len(candidate.ReplacementLots) > 0 {
+			candidate.WashSale = true
+			candidate.Cautions = append(candidate.Cautions, fmt.Sprintf("%d other %s lot(s) bought in the last %d days; selling now would disallow the loss", len(candidate.ReplacementLots), position.Symbol, windowDays))
+		}
+		if contracts := putsBySymbol[position.Symbol]; contracts > 0 {
+			candidate.Cautions = append(candidate.Cautions, fmt.Sprintf("%d open short put contract(s) on %s; an assignment within %d days of the sale would be a replacement purchase", contracts, position.Symbol, windowDays))
+		}
+
+		response.TotalLoss += candidate.Loss
+		if !candidate.WashSale {
+			response.HarvestableLoss += candidate.Loss
+		}
+		response.Candidates = append(response.Candidates, candidate)
+	}
+
+	sort.Slice(response.Candidates, func(i, j int) bool {
+		if response.Candidates[i].Loss != response.Candidates[j].Loss {
+			return response.Candidates[i].Loss > response.Candidates[j].Loss
+		}
+		return response.Candidates[i].PositionID < response.Candidates[j].PositionID
+	})
+	sort.Strings(response.MissingPrice)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[REPORTS API] Error encoding response: %v", err)
+	}
+}
+
+// reportPeriod is an inclusive date range parsed from a compare query parameter
+type reportPeriod struct {
+	start time.Time
+	end   time.Time
+}
+
+// contains reports whether a date falls on or between the period's start and end days
+func (p reportPeriod) contains(date time.Time) bool {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	return !day.Before(p.start) && !day.After(p.end)
+}
+
+// parseReportPeriod parses a START..END period of YYYY-MM-DD dates, both inclusive
+func parseReportPeriod(name, value string) (reportPeriod, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return reportPeriod{}, fmt.Errorf("%s is required (use YYYY-MM-DD..YYYY-MM-DD)", name)
+	}
+	startStr, endStr, ok := strings.Cut(value, "..")
+	startStr, endStr = strings.TrimSpace(startStr), strings.TrimSpace(endStr)
+	if !ok || startStr == "" || endStr == "" {
+		return reportPeriod{}, fmt.Errorf("%s must have a start and an end (use YYYY-MM-DD..YYYY-MM-DD)", name)
+	}
+	start, err := time.Parse("2006-01-02", startStr)
+	if err != nil {
+		return reportPeriod{}, fmt.Errorf("invalid %s start date (use YYYY-MM-DD)", name)
+	}
+	end, err := time.Parse("2006-01-02", endStr)
+	if err != nil {
+		return reportPeriod{}, fmt.Errorf("invalid %s end date (use YYYY-MM-DD)", name)
+	}
+	if end.Before(start) {
+		return reportPeriod{}, fmt.Errorf("%s end must not be before its start", name)
+	}
+	return reportPeriod{start: start, end: end}, nil
+}
+
+// periodCompareHandler handles GET /api/reports/compare?period1=START..END&period2=START..END
+// Each period reports realized profit and win rate over options closed in it, premium collected on
+// options opened in it and cash dividends received in it, all in the base currency. Dates are
+// inclusive and the periods must not overlap, so no trade is counted in both. Delta is period2
+// minus period1; the win rate delta is omitted when either period has no closed options.
+func (s *Server) periodCompareHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[REPORTS API] %s %s - Comparing periods", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	period1, err := parseReportPeriod("period1", query.Get("period1"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	period2, err := parseReportPeriod("period2", query.Get("period2"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !period1.end.Before(period2.start) && !period2.end.Before(period1.start) {
+		http.Error(w, "period1 and period2 must not overlap", http.StatusBadRequest)
+		return
+	}
+
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		log.Printf("[REPORTS API] Error getting options: %v", err)
+		http.Error(w, "Failed to get options", http.StatusInternalServerError)
+		return
+	}
+	dividends, err := s.dividendService.GetAll()
+	if err != nil {
+		log.Printf("[REPORTS API] Error getting dividends: %v", err)
+		http.Error(w, "Failed to get dividends", http.StatusInternalServerError)
+		return
+	}
+
+	decimals := s.percentDecimals()
+	first := summarizeReportPeriod(period1, options, dividends, decimals)
+	second := summarizeReportPeriod(period2, options, dividends, decimals)
+	response := PeriodCompareResponse{
+		Period1: first,
+		Period2: second,
+		Delta: PeriodCompareDelta{
+			RealizedProfit:   second.RealizedProfit - first.RealizedProfit,
+			PremiumCollected: second.PremiumCollected - first.PremiumCollected,
+			ClosedOptions:    second.ClosedOptions - first.ClosedOptions,
+			DividendIncome:   second.DividendIncome - first.DividendIncome,
+		},
+	}
+	if first.WinRate != nil && second.WinRate != nil {
+		delta := roundPercent(*second.WinRate-*first.WinRate, decimals)
+		response.Delta.WinRate = &delta
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[REPORTS API] Error encoding compare response: %v", err)
+	}
+}
+
+// summarizeReportPeriod totals one period of a period comparison
+func summarizeReportPeriod(period reportPeriod, options []*models.Option, dividends []*models.Dividend, decimals int) PeriodSummary {
+	summary := PeriodSummary{
+		Start: period.start.Format("2006-01-02"),
+		End:   period.end.Format("2006-01-02"),
+	}
+	for _, option := range options {
+		if period.contains(option.Opened) {
+			summary.PremiumCollected += option.ToBase(option.Premium * float64(option.Contracts) * 100)
+		}
+		if option.Closed == nil || !period.contains(*option.Closed) {
+			continue
+		}
+		profit := option.ToBase(option.RealizedProfitValue())
+		summary.RealizedProfit += profit
+		summary.ClosedOptions++
+		if profit > 0 {
+			summary.Wins++
+		}
+	}
+	if summary.ClosedOptions > 0 {
+		rate := roundPercent(float64(summary.Wins)/float64(summary.ClosedOptions)*100, decimals)
+		summary.WinRate = &rate
+	}
+	for _, dividend := range dividends {
+		if period.contains(dividend.Received) {
+			summary.DividendIncome += dividend.Amount
+		}
+	}
+	return summary
+}
+
+// form8949Row is one disposal in IRS Form 8949 layout; the gain or loss is Proceeds less CostBasis
+type form8949Row struct {
+	Description string
+	Acquired    time.Time
+	Sold        time.Time
+	Proceeds    float64
+	CostBasis   float64
+	LongTerm    bool
+	Symbol      string
+	Account     string
+	Notes       []string
+}
+
+// heldLongTerm reports whether property acquired and sold on these dates was held more than one year
+func heldLongTerm(acquired, sold time.Time) bool {
+	return sold.After(acquired.AddDate(1, 0, 0))
+}
+
+// buildForm8949Rows lists every share sale and closed option as a Form 8949 disposal.
+//
+// Share sales use the lot's adjusted cost basis with the premium of options that get their own
+// row added back, so no premium is counted twice: only assigned options stay in the basis, the
+// way the IRS folds put premium into the shares bought and call premium into the shares sold.
+// Assigned options are therefore linked to the lots they adjust instead of reported alone, unless
+// no lot carries their premium. Written options are short-term whatever the holding period.
+func (s *Server) buildForm8949Rows() ([]form8949Row, error) {
+	options, err := s.optionService.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get options: %w", err)
+	}
+	optionsByID := make(map[int]*models.Option, len(options))
+	for _, option := range options {
+		optionsByID[option.ID] = option
+	}
+
+	positions, err := s.longPositionService.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get long positions: %w", err)
+	}
+	if err := s.longPositionService.AttachExits(positions); err != nil {
+		return nil, fmt.Errorf("failed to get long position exits: %w", err)
+	}
+
+	details := make(map[int]*models.LotBasisDetail)
+	detailed := make(map[string]bool)
+	for _, position := range positions {
+		if detailed[position.Symbol] {
+			continue
+		}
+		detailed[position.Symbol] = true
+		lots, err := s.longPositionService.GetSymbolBasisDetails(position.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get basis details for %s: %w", position.Symbol, err)
+		}
+		for _, lot := range lots {
+			details[lot.PositionID] = lot
+		}
+	}
+
+	var rows []form8949Row
+	inBasis := make(map[int]bool)
+	for _, position := range positions {
+		if position.Shares <= 0 {
+			continue
+		}
+		basisTotal := position.CostBasisPerShare() * float64(position.Shares)
+		var lotNotes []string
+		if detail := details[position.ID]; detail != nil {
+			basisTotal = detail.AdjustedCostBasisTotal
+			for _, attribution := range detail.Attributions {
+				option := optionsByID[attribution.OptionID]
+				if attribution.Unrealized || option == nil {
+					continue
+				}
+				if !option.Assigned {
+					basisTotal += attribution.Amount
+					continue
+				}
+				inBasis[option.ID] = true
+				lotNotes = append(lotNotes, fmt.Sprintf("Basis includes premium of assigned %s #%d", strings.ToLower(option.Type), option.ID))
+			}
+		}
+		perShare := basisTotal / float64(position.Shares)
+
+		type disposal struct {
+			sold   time.Time
+			shares int
+			price  float64
+		}
+		var disposals []disposal
+		for _, exit := range position.Exits {
+			disposals = append(disposals, disposal{sold: exit.Exited, shares: exit.Shares, price: exit.Price})
+		}
+		if len(position.Exits) == 0 && position.Closed != nil && position.ExitPrice != nil {
+			disposals = append(disposals, disposal{sold: *position.Closed, shares: position.Shares, price: *position.ExitPrice})
+		}
+
+		for _, d := range disposals {
+			notes := append([]string{}, lotNotes...)
+			for _, option := range options {
+				if option.Assigned && option.Type == "Call" && option.Symbol == position.Symbol && option.Closed != nil &&
+					sameDay(option.Closed, &d.sold) && option.Strike == d.price {
+					notes = append(notes, fmt.Sprintf("Called away by call #%d", option.ID))
+				}
+			}
+			rows = append(rows, form8949Row{
+				Description: fmt.Sprintf("%d sh %s", d.shares, position.Symbol),
+				Acquired:    position.Opened,
+				Sold:        d.sold,
+				Proceeds:    d.price * float64(d.shares),
+				CostBasis:   perShare * float64(d.shares),
+				LongTerm:    heldLongTerm(position.Opened, d.sold),
+				Symbol:      position.Symbol,
+				Account:     position.Account,
+				Notes:       notes,
+			})
+		}
+	}
+
+	for _, option := range options {
+		if option.Closed == nil || inBasis[option.ID] {
+			continue
+		}
+		shares := float64(option.Contracts) * 100
+		notes := []string{"Written option (short-term)", option.Outcome()}
+		if option.Assigned {
+			notes = append(notes, "no stock lot carries this premium")
+		}
+		rows = append(rows, form8949Row{
+			Description: fmt.Sprintf("%d %s %s %.2f exp %s", option.Contracts, option.Symbol, option.Type, option.Strike, option.Expiration.Format("2006-01-02")),
+			Acquired:    option.Opened,
+			Sold:        *option.Closed,
+			Proceeds:    option.ToBase(option.Premium * shares),
+			CostBasis:   option.ToBase(option.GetExitPriceValue()*shares + option.TransactionCosts()),
+			Symbol:      option.Symbol,
+			Account:     option.Account,
+			Notes:       notes,
+		})
+	}
+
+	// Short-term (Part I) before long-term (Part II), then by sale date
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].LongTerm != rows[j].LongTerm {
+			return !rows[i].LongTerm
+		}
+		if !rows[i].Sold.Equal(rows[j].Sold) {
+			return rows[i].Sold.Before(rows[j].Sold)
+		}
+		return rows[i].Description < rows[j].Description
+	})
+	return rows, nil
+}
+
+// HandleForm8949Export downloads /export/form8949.csv?year=, every closed stock sale and option in
+// IRS Form 8949 layout for tax software import. year filters by the date sold. Dates are MM/DD/YYYY.
+func (s *Server) HandleForm8949Export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	year := 0
+	if value := strings.TrimSpace(r.URL.Query().Get("year")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1900 || parsed > 9999 {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+		year = parsed
+	}
+
+	rows, err := s.buildForm8949Rows()
+	if err != nil {
+		log.Printf("[EXPORT] Error building Form 8949 rows: %v", err)
+		http.Error(w, "Failed to build tax lots", http.StatusInternalServerError)
+		return
+	}
+
+	filename := "form8949.csv"
+	if year != 0 {
+		filename = fmt.Sprintf("form8949-%d.csv", year)
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+
+	formatAmount := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', 2, 64)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Description", "Date Acquired", "Date Sold", "Proceeds", "Cost Basis", "Gain or Loss", "Term", "Symbol", "Account", "Notes"})
+	exported := 0
+	for _, row := range rows {
+		if year != 0 && row.Sold.Year() != year {
+			continue
+		}
+		term := "Short-term"
+		if row.LongTerm {
+			term = "Long-term"
+		}
+		writer.Write([]string{
+			row.Description,
+			row.Acquired.Format("01/02/2006"),
+			row.Sold.Format("01/02/2006"),
+			formatAmount(row.Proceeds),
+			formatAmount(row.CostBasis),
+			formatAmount(row.Proceeds - row.CostBasis),
+			term,
+			row.Symbol,
+			row.Account,
+			strings.Join(row.Notes, "; "),
+		})
+		exported++
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("[EXPORT] Error writing Form 8949 CSV: %v", err)
+		return
+	}
+
+	log.Printf("[EXPORT] Exported %d Form 8949 rows", exported)
+}
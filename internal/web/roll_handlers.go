@@ -0,0 +1,456 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"stonks/internal/models"
+	"stonks/internal/polygon"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRollDTEWindow      = 10
+	defaultRollTargetDelta    = 0.30
+	defaultRollDeltaTolerance = 0.10
+	defaultRollPerExpiration  = 3
+)
+
+// rollSuggestionParams are the search criteria for roll candidates
+type rollSuggestionParams struct {
+	TargetDTE      int
+	DTEWindow      int
+	TargetDelta    float64
+	DeltaTolerance float64
+	PerExpiration  int
+}
+
+// parseRollSuggestionParams reads target_dte, dte_window, target_delta, delta_tolerance and limit.
+// Without target_dte the search targets the monthly expiration following the option's own.
+func parseRollSuggestionParams(r *http.Request, option *models.Option, today time.Time) (rollSuggestionParams, error) {
+	params := rollSuggestionParams{
+		DTEWindow:      defaultRollDTEWindow,
+		TargetDelta:    defaultRollTargetDelta,
+		DeltaTolerance: defaultRollDeltaTolerance,
+		PerExpiration:  defaultRollPerExpiration,
+	}
+
+	query := r.URL.Query()
+	if value := strings.TrimSpace(query.Get("target_dte")); value != "" {
+		dte, err := strconv.Atoi(value)
+		if err != nil || dte <= 0 {
+			return params, fmt.Errorf("invalid target_dte")
+		}
+		params.TargetDTE = dte
+	} else {
+		year, month, _ := option.Expiration.Date()
+		nextMonthly := models.StandardMonthlyExpiration(year, month+1)
+		params.TargetDTE = optionDaysBetween(today, nextMonthly)
+	}
+	if value := strings.TrimSpace(query.Get("dte_window")); value != "" {
+		window, err := strconv.Atoi(value)
+		if err != nil || window < 0 {
+			return params, fmt.Errorf("invalid dte_window")
+		}
+		params.DTEWindow = window
+	}
+	if value := strings.TrimSpace(query.Get("target_delta")); value != "" {
+		delta, err := strconv.ParseFloat(value, 64)
+		if err != nil || delta <= 0 || delta >= 1 {
+			return params, fmt.Errorf("invalid target_delta")
+		}
+		params.TargetDelta = delta
+	}
+	if value := strings.TrimSpace(query.Get("delta_tolerance")); value != "" {
+		tolerance, err := strconv.ParseFloat(value, 64)
+		if err != nil || tolerance < 0 {
+			return params, fmt.Errorf("invalid delta_tolerance")
+		}
+		params.DeltaTolerance = tolerance
+	}
+	if value := strings.TrimSpace(query.Get("limit")); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return params, fmt.Errorf("invalid limit")
+		}
+		params.PerExpiration = limit
+	}
+	return params, nil
+}
+
+// optionDaysBetween returns whole calendar days from one date to another
+func optionDaysBetween(from, to time.Time) int {
+	fromDate := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDate.Sub(fromDate).Hours() / 24)
+}
+
+// chainQuotePrice prices a contract from its last quote: the bid when selling, the ask when
+// buying, falling back to the midpoint when that side is missing
+func chainQuotePrice(quote polygon.Quote, selling bool) float64 {
+	if selling && quote.Bid > 0 {
+		return quote.Bid
+	}
+	if !selling && quote.Ask > 0 {
+		return quote.Ask
+	}
+	return quote.Midpoint
+}
+
+// buildRollSuggestions filters chain contracts to those near the target DTE and delta, then
+// ranks each expiration's contracts by net credit relative to the strike at risk
+func buildRollSuggestions(option *models.Option, chain []polygon.ChainContract, closeCost float64, params rollSuggestionParams, today time.Time) []RollExpirationGroup {
+	groups := make(map[string]*RollExpirationGroup)
+	for _, contract := range chain {
+		if !strings.EqualFold(contract.Details.ContractType, option.Type) {
+			continue
+		}
+		expiration, err := contract.Expiration()
+		if err != nil || !expiration.After(option.Expiration) {
+			continue
+		}
+		dte := optionDaysBetween(today, expiration)
+		if math.Abs(float64(dte-params.TargetDTE)) > float64(params.DTEWindow) {
+			continue
+		}
+		delta := math.Abs(contract.Greeks.Delta)
+		if delta == 0 || math.Abs(delta-params.TargetDelta) > params.DeltaTolerance {
+			continue
+		}
+		premium := chainQuotePrice(contract.LastQuote, true)
+		if premium <= 0 || contract.Details.StrikePrice <= 0 {
+			continue
+		}
+
+		netCredit := (premium - closeCost) * 100 * float64(option.Contracts)
+		candidate := RollCandidate{
+			Contract:          contract.Details.Ticker,
+			Strike:            contract.Details.StrikePrice,
+			Delta:             contract.Greeks.Delta,
+			Bid:               contract.LastQuote.Bid,
+			Ask:               contract.LastQuote.Ask,
+			ImpliedVolatility: contract.ImpliedVolatility,
+			OpenInterest:      contract.OpenInterest,
+			NetCredit:         netCredit,
+			CreditForRisk:     (premium - closeCost) / contract.Details.StrikePrice * 100,
+		}
+
+		key := contract.Details.ExpirationDate
+		group, ok := groups[key]
+		if !ok {
+			group = &RollExpirationGroup{Expiration: key, DTE: dte, Candidates: []RollCandidate{}}
+			groups[key] = group
+		}
+		group.Candidates = append(group.Candidates, candidate)
+	}
+
+	result := make([]RollExpirationGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Slice(group.Candidates, func(i, j int) bool {
+			return group.Candidates[i].CreditForRisk > group.Candidates[j].CreditForRisk
+		})
+		if len(group.Candidates) > params.PerExpiration {
+			group.Candidates = group.Candidates[:params.PerExpiration]
+		}
+		group.BestNetCredit = group.Candidates[0].NetCredit
+		result = append(result, *group)
+	}
+
+	// Expirations closest to the target DTE first
+	sort.Slice(result, func(i, j int) bool {
+		di := math.Abs(float64(result[i].DTE - params.TargetDTE))
+		dj := math.Abs(float64(result[j].DTE - params.TargetDTE))
+		if di != dj {
+			return di < dj
+		}
+		return result[i].Expiration < result[j].Expiration
+	})
+	return result
+}
+
+// optionRollSuggestionsHandler searches the chain for roll candidates near a target DTE and delta
+func (s *Server) optionRollSuggestionsHandler(w http.ResponseWriter, r *http.Request, option *models.Option) {
+	if option.Closed != nil {
+		http.Error(w, "Option is already closed", http.StatusBadRequest)
+		return
+	}
+
+	today := time.Now().In(s.marketLocation())
+	params, err := parseRollSuggestionParams(r, option, today)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	// Buying back the current contract at the ask; the stored mark is used when it cannot be quoted
+	var closeCost float64
+	if option.CurrentPrice != nil {
+		closeCost = *option.CurrentPrice
+	}
+	if _, snapshot, err := s.polygonService.GetRawOptionSnapshot(ctx, option); err == nil && snapshot != nil {
+		if price := chainQuotePrice(snapshot.Results.LastQuote, false); price > 0 {
+			closeCost = price
+		}
+	} else if err != nil {
+		log.Printf("[ROLL SUGGESTIONS] Could not quote option %d, using stored price: %v", option.ID, err)
+	}
+
+	from := today.AddDate(0, 0, params.TargetDTE-params.DTEWindow)
+	if !from.After(option.Expiration) {
+		from = option.Expiration.AddDate(0, 0, 1)
+	}
+	chain, err := s.polygonService.GetOptionChain(ctx, option.Symbol, polygon.ChainQuery{
+		ContractType:   option.Type,
+		ExpirationFrom: from,
+		ExpirationTo:   today.AddDate(0, 0, params.TargetDTE+params.DTEWindow),
+	})
+	if err != nil {
+		log.Printf("[ROLL SUGGESTIONS] ERROR: Failed to fetch chain for %s: %v", option.Symbol, err)
+		http.Error(w, "Failed to fetch option chain: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	response := RollSuggestionsResponse{
+		OptionID:       option.ID,
+		Symbol:         option.Symbol,
+		Type:           option.Type,
+		CloseCost:      closeCost,
+		TargetDTE:      params.TargetDTE,
+		DTEWindow:      params.DTEWindow,
+		TargetDelta:    params.TargetDelta,
+		DeltaTolerance: params.DeltaTolerance,
+		Expirations:    buildRollSuggestions(option, chain, closeCost, params, today),
+	}
+	log.Printf("[ROLL SUGGESTIONS] Option %d: %d chain contracts, %d expirations with candidates", option.ID, len(chain), len(response.Expirations))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+const (
+	defaultRollableDays   = 7
+	defaultRollableMaxDTE = 45
+	defaultRollableLimit  = 3
+)
+
+// rollableChainKey groups open options sharing an underlying and type so each chain is fetched once
+type rollableChainKey struct {
+	Symbol string
+	Type   string
+}
+
+// findCreditRolls lists chain contracts later than the option's expiration, up to the last
+// expiration allowed, at the same or a better strike (higher for calls, lower for puts) whose bid
+// exceeds the cost of buying the option back. The largest net credit comes first.
+func findCreditRolls(option *models.Option, chain []polygon.ChainContract, closeCost float64, lastExpiration, today time.Time) []RollableOpportunity {
+	opportunities := []RollableOpportunity{}
+	for _, contract := range chain {
+		if !strings.EqualFold(contract.Details.ContractType, option.Type) {
+			continue
+		}
+		expiration, err := contract.Expiration()
+		if err != nil || !expiration.After(option.Expiration) || expiration.After(lastExpiration) {
+			continue
+		}
+		strike := contract.Details.StrikePrice
+		if strike <= 0 {
+			continue
+		}
+		if option.Type == "Call" && strike < option.Strike || option.Type == "Put" && strike > option.Strike {
+			continue
+		}
+		premium := chainQuotePrice(contract.LastQuote, true)
+		if premium <= closeCost {
+			continue
+		}
+		opportunities = append(opportunities, RollableOpportunity{
+			Contract:   contract.Details.Ticker,
+			Expiration: contract.Details.ExpirationDate,
+			DTE:        optionDaysBetween(today, expiration),
+			Strike:     strike,
+			Bid:        contract.LastQuote.Bid,
+			Ask:        contract.LastQuote.Ask,
+			Delta:      contract.Greeks.Delta,
+			NetCredit:  (premium - closeCost) * 100 * float64(option.Contracts),
+		})
+	}
+	sort.Slice(opportunities, func(i, j int) bool {
+		if opportunities[i].NetCredit != opportunities[j].NetCredit {
+			return opportunities[i].NetCredit > opportunities[j].NetCredit
+		}
+		return opportunities[i].Contract < opportunities[j].Contract
+	})
+	return opportunities
+}
+
+// quoteCloseCost prices buying an option back per share: the ask from a live snapshot, falling back
+// to the stored mark. The source is "quote" or "stored", or empty when neither is available.
+func (s *Server) quoteCloseCost(ctx context.Context, option *models.Option) (float64, string) {
+	if _, snapshot, err := s.polygonService.GetRawOptionSnapshot(ctx, option); err == nil && snapshot != nil {
+		if price := chainQuotePrice(snapshot.Results.LastQuote, false); price > 0 {
+			return price, "quote"
+		}
+	}
+	if option.CurrentPrice != nil {
+		return *option.CurrentPrice, "stored"
+	}
+	return 0, ""
+}
+
+// optionsRollableHandler handles GET /api/options/rollable?days=7&max_dte=45&limit=3
+// Every open option expiring within days is checked against the live chain for rolls to a later
+// expiration (up to max_dte from today) at the same or a better strike that collect a net credit:
+// the new contract's bid less the ask to buy the current one back, falling back to the stored
+// mark when the current contract cannot be quoted. Options whose chain or buyback price is
+// unavailable are listed under skipped.
+func (s *Server) optionsRollableHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[ROLLABLE API] %s %s - Scanning expiring options for credit rolls", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	days := defaultRollableDays
+	if value := strings.TrimSpace(query.Get("days")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+	maxDTE := defaultRollableMaxDTE
+	if value := strings.TrimSpace(query.Get("max_dte")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid max_dte", http.StatusBadRequest)
+			return
+		}
+		maxDTE = parsed
+	}
+	limit := defaultRollableLimit
+	if value := strings.TrimSpace(query.Get("limit")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	openOptions, err := s.optionService.GetOpen()
+	if err != nil {
+		log.Printf("[ROLLABLE API] ERROR: Failed to get open options: %v", err)
+		http.Error(w, "Failed to get open options", http.StatusInternalServerError)
+		return
+	}
+
+	today := time.Now().In(s.marketLocation())
+	lastExpiration := today.AddDate(0, 0, maxDTE)
+	response := RollableResponse{
+		Days:    days,
+		MaxDTE:  maxDTE,
+		Options: []RollableOption{},
+		Skipped: []RollableSkip{},
+	}
+
+	expiring := make(map[rollableChainKey][]*models.Option)
+	var keys []rollableChainKey
+	for _, option := range openOptions {
+		remaining := option.CalculateDaysRemaining()
+		if remaining < 0 || remaining > days {
+			continue
+		}
+		key := rollableChainKey{Symbol: option.Symbol, Type: option.Type}
+		if _, ok := expiring[key]; !ok {
+			keys = append(keys, key)
+		}
+		expiring[key] = append(expiring[key], option)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Symbol != keys[j].Symbol {
+			return keys[i].Symbol < keys[j].Symbol
+		}
+		return keys[i].Type < keys[j].Type
+	})
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	for _, key := range keys {
+		options := expiring[key]
+		skip := func(option *models.Option, reason string) {
+			response.Skipped = append(response.Skipped, RollableSkip{OptionID: option.ID, Symbol: option.Symbol, Reason: reason})
+		}
+
+		from := options[0].Expiration
+		for _, option := range options {
+			if option.Expiration.Before(from) {
+				from = option.Expiration
+			}
+		}
+		chain, err := s.polygonService.GetOptionChain(ctx, key.Symbol, polygon.ChainQuery{
+			ContractType:   key.Type,
+			ExpirationFrom: from.AddDate(0, 0, 1),
+			ExpirationTo:   lastExpiration,
+		})
+		if err == nil && len(chain) == 0 {
+			err = fmt.Errorf("empty chain")
+		}
+		if err != nil {
+			log.Printf("[ROLLABLE API] Skipping %s %s: chain unavailable: %v", key.Symbol, key.Type, err)
+			for _, option := range options {
+				skip(option, "Option chain unavailable: "+err.Error())
+			}
+			continue
+		}
+
+		for _, option := range options {
+			entry := RollableOption{
+				OptionID:      option.ID,
+				Symbol:        option.Symbol,
+				Type:          option.Type,
+				Strike:        option.Strike,
+				Expiration:    option.Expiration.Format("2006-01-02"),
+				DaysRemaining: option.CalculateDaysRemaining(),
+				Contracts:     option.Contracts,
+			}
+			entry.CloseCost, entry.CloseCostSource = s.quoteCloseCost(ctx, option)
+			if entry.CloseCostSource == "" {
+				skip(option, "No quote or stored price for the current contract")
+				continue
+			}
+
+			entry.Opportunities = findCreditRolls(option, chain, entry.CloseCost, lastExpiration, today)
+			if len(entry.Opportunities) == 0 {
+				continue
+			}
+			if len(entry.Opportunities) > limit {
+				entry.Opportunities = entry.Opportunities[:limit]
+			}
+			entry.BestNetCredit = entry.Opportunities[0].NetCredit
+			response.Options = append(response.Options, entry)
+		}
+	}
+
+	sort.SliceStable(response.Options, func(i, j int) bool {
+		return response.Options[i].BestNetCredit > response.Options[j].BestNetCredit
+	})
+	log.Printf("[ROLLABLE API] %d option(s) can roll for a credit, %d skipped", len(response.Options), len(response.Skipped))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[ROLLABLE API] ERROR: Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Background task run results, reported by /api/scheduler/status and the snapshot status endpoint
+const (
+	TaskResultNeverRun = "never_run"
+	TaskResultRunning  = "running"
+	TaskResultSuccess  = "success"
+	TaskResultSkipped  = "skipped"
+	TaskResultFailed   = "failed"
+)
+
+// scheduledTask is a background task registered by its start function. status reads the task's
+// current settings and run history, so the scheduler status lists exactly what is running.
+type scheduledTask struct {
+	name   string
+	status func() SchedulerTaskStatus
+}
+
+// registerScheduledTask adds a background task to the scheduler status, replacing an earlier
+// registration under the same name when a task is restarted
+func (s *Server) registerScheduledTask(name string, status func() SchedulerTaskStatus) {
+	s.schedulerMu.Lock()
+	defer s.schedulerMu.Unlock()
+	for i, task := range s.scheduledTasks {
+		if task.name == name {
+			s.scheduledTasks[i].status = status
+			return
+		}
+	}
+	s.scheduledTasks = append(s.scheduledTasks, scheduledTask{name: name, status: status})
+}
+
+// taskRunState is the outcome of a background task's most recent run
+type taskRunState struct {
+	result        string
+	lastRunAt     *time.Time
+	lastSuccessAt *time.Time
+	lastFailureAt *time.Time
+	lastError     string
+	attempts      int
+	runDate       string // the market date a daily task last ran for
+	detail        string // task-specific summary of the last successful run
+	nextRunAt     *time.Time
+}
+
+// taskRunRecord guards the run state of one background task
+type taskRunRecord struct {
+	mu    sync.Mutex
+	state taskRunState
+}
+
+// begin marks a run that started at started as running; runDate is blank for tasks not run daily
+func (r *taskRunRecord) begin(started time.Time, runDate string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state.result = TaskResultRunning
+	r.state.lastRunAt = &started
+	r.state.runDate = runDate
+	r.state.attempts = 0
+}
+
+// setAttempts records how many attempts the current run has made
+func (r *taskRunRecord) setAttempts(attempts int) {
+	r.mu.Lock()
+	r.state.attempts = attempts
+	r.mu.Unlock()
+}
+
+// finish stores the outcome of the current run; a non-nil err marks it failed whatever result is
+func (r *taskRunRecord) finish(result string, err error, detail string) {
+	finished := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.state.result = TaskResultFailed
+		r.state.lastFailureAt = &finished
+		r.state.lastError = err.Error()
+		return
+	}
+	r.state.result = result
+	r.state.lastSuccessAt = &finished
+	r.state.lastError = ""
+	r.state.detail = detail
+}
+
+// recordRun stores the outcome of a single-attempt run that started at started
+func (r *taskRunRecord) recordRun(started time.Time, result string, err error) {
+	r.begin(started, "")
+	r.finish(result, err, "")
+}
+
+// reset clears the recorded run history so the task reports never_run
+func (r *taskRunRecord) reset() {
+	r.mu.Lock()
+	r.state = taskRunState{}
+	r.mu.Unlock()
+}
+
+// scheduleNext stores when the task will next run; nil means it is not scheduled
+func (r *taskRunRecord) scheduleNext(next *time.Time) {
+	r.mu.Lock()
+	r.state.nextRunAt = next
+	r.mu.Unlock()
+}
+
+// current returns a copy of the recorded run state
+func (r *taskRunRecord) current() taskRunState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// fill copies the recorded run history into a task status
+func (r *taskRunRecord) fill(status *SchedulerTaskStatus) {
+	state := r.current()
+	status.LastRunAt = state.lastRunAt
+	status.LastResult = state.resultOrNeverRun()
+	status.LastError = state.lastError
+	status.NextRunAt = state.nextRunAt
+}
+
+// resultOrNeverRun is the last result, or TaskResultNeverRun before the first run
+func (state taskRunState) resultOrNeverRun() string {
+	if state.result == "" {
+		return TaskResultNeverRun
+	}
+	return state.result
+}
+
+// enrichmentTaskStatus reports the Polygon symbol enrichment worker, which runs as symbols are
+// created rather than on a clock
+func (s *Server) enrichmentTaskStatus() SchedulerTaskStatus {
+	enrichment := s.polygonService.EnrichmentStatus()
+	status := SchedulerTaskStatus{
+		Description: "Fetches price and dividend data from Polygon for newly created symbols",
+		Enabled:     enrichment.Running && s.settingService.GetBoolWithDefault("AUTO_ENRICH_SYMBOLS", false),
+		Schedule:    "when symbols are created",
+		Settings:    []string{"AUTO_ENRICH_SYMBOLS"},
+		LastRunAt:   enrichment.LastRunAt,
+		LastResult:  TaskResultNeverRun,
+		LastError:   enrichment.LastError,
+		Detail:      fmt.Sprintf("%d symbol(s) queued", enrichment.Queued),
+	}
+	if enrichment.LastRunAt != nil {
+		status.LastResult = TaskResultSuccess
+		if enrichment.LastError != "" {
+			status.LastResult = TaskResultFailed
+		}
+		status.Detail += fmt.Sprintf("; last enriched %s", enrichment.LastSymbol)
+	}
+	return status
+}
+
+// schedulerStatusHandler handles GET /api/scheduler/status, listing every registered background
+// task with whether it is enabled, its schedule, the result of its last run and its next run
+func (s *Server) schedulerStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Background tasks belong to the global server
+	root := s.rootServer()
+	root.schedulerMu.Lock()
+	tasks := append([]scheduledTask(nil), root.scheduledTasks...)
+	root.schedulerMu.Unlock()
+
+	response := SchedulerStatusResponse{
+		GeneratedAt: time.Now(),
+		Tasks:       make([]SchedulerTaskStatus, 0, len(tasks)),
+	}
+	for _, task := range tasks {
+		status := task.status()
+		status.Name = task.name
+		if status.Settings == nil {
+			status.Settings = []string{}
+		}
+		response.Tasks = append(response.Tasks, status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
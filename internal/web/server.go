@@ -2,6 +2,7 @@ package web
 
 import (
 	"bytes"
+	"crypto/subtle"
 	"database/sql"
 	"fmt"
 	"html/template"
@@ -11,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"stonks/internal/database"
@@ -21,16 +23,48 @@ import (
 )
 
 type Server struct {
-	db                  *sql.DB
-	optionService       *models.OptionService
-	symbolService       *models.SymbolService
-	treasuryService     *models.TreasuryService
-	longPositionService *models.LongPositionService
-	dividendService     *models.DividendService
-	settingService      *models.SettingService
-	metricService       *models.MetricService
-	polygonService      *polygon.Service
-	templates           *template.Template
+	db                    *sql.DB
+	optionService         *models.OptionService
+	symbolService         *models.SymbolService
+	treasuryService       *models.TreasuryService
+	treasuryCouponService *models.TreasuryCouponService
+	greeksSnapshotService *models.GreeksSnapshotService
+	longPositionService   *models.LongPositionService
+	dividendService       *models.DividendService
+	settingService        *models.SettingService
+	metricService         *models.MetricService
+	polygonService        *polygon.Service
+	templates             *template.Template
+
+	// Background tasks registered for /api/scheduler/status
+	schedulerMu    sync.Mutex
+	scheduledTasks []scheduledTask
+
+	// dbMu guards db and settingService against handleSetCurrentDatabase: background tasks hold a
+	// read lock while they use them and the switch holds the write lock while it swaps them
+	dbMu sync.RWMutex
+
+	// checkpointMu serializes WAL checkpoints between backups and the background task
+	checkpointMu    sync.Mutex
+	maintenanceDone chan struct{}
+	walRun          taskRunRecord
+	pendingDone     chan struct{}
+	pendingRun      taskRunRecord
+
+	// Scheduled metrics snapshot task and the outcome of its last run
+	snapshotRun  taskRunRecord
+	snapshotDone chan struct{}
+
+	// Automatic backup task and the outcome of its last run
+	backupRun  taskRunRecord
+	backupDone chan struct{}
+
+	// Per-session database servers, keyed by database name, when DATABASE_SCOPE is "session".
+	// root and sessionDBName are set only on those session servers.
+	sessionMu      sync.Mutex
+	sessionServers map[string]*Server
+	root           *Server
+	sessionDBName  string
 }
 
 func NewServer() (*Server, error) {
@@ -55,7 +89,9 @@ func NewServer() (*Server, error) {
 
 	// Create template with custom functions
 	funcMap := template.FuncMap{
-		"groupByExpiration": groupPositionsByExpiration,
+		"groupByExpiration":         groupPositionsByExpiration,
+		"formatOptionProfit":        formatOptionTotalProfit,
+		"formatOptionProfitPercent": formatOptionPercentOfProfit,
 		"replace": func(old, new, src string) string {
 			return strings.Replace(src, old, new, -1)
 		},
@@ -252,26 +288,68 @@ func NewServer() (*Server, error) {
 	settingService := models.NewSettingService(dbWrapper.DB)
 
 	server := &Server{
-		db:                  dbWrapper.DB,
-		optionService:       models.NewOptionService(dbWrapper.DB),
-		symbolService:       symbolService,
-		treasuryService:     models.NewTreasuryService(dbWrapper.DB),
-		longPositionService: models.NewLongPositionService(dbWrapper.DB),
-		dividendService:     models.NewDividendService(dbWrapper.DB),
-		settingService:      settingService,
-		metricService:       models.NewMetricService(dbWrapper.DB),
-		polygonService:      polygon.NewService(symbolService, settingService),
-		templates:           templates,
+		db:                    dbWrapper.DB,
+		optionService:         models.NewOptionService(dbWrapper.DB),
+		symbolService:         symbolService,
+		treasuryService:       models.NewTreasuryService(dbWrapper.DB),
+		treasuryCouponService: models.NewTreasuryCouponService(dbWrapper.DB),
+		greeksSnapshotService: models.NewGreeksSnapshotService(dbWrapper.DB),
+		longPositionService:   models.NewLongPositionService(dbWrapper.DB),
+		dividendService:       models.NewDividendService(dbWrapper.DB),
+		settingService:        settingService,
+		metricService:         models.NewMetricService(dbWrapper.DB),
+		polygonService:        polygon.NewService(symbolService, settingService),
+		templates:             templates,
 	}
 
+	// Enrich newly created symbols in the background when AUTO_ENRICH_SYMBOLS is enabled
+	server.polygonService.StartSymbolEnrichment()
+	symbolService.SetCreateHook(server.polygonService.QueueSymbolEnrichment)
+	server.registerScheduledTask("symbol_enrichment", server.enrichmentTaskStatus)
+
+	// Keep the WAL file bounded between backups
+	server.startWALCheckpoints()
+
+	// Activate planned trades as their opened dates arrive
+	server.startPendingActivation()
+
+	// Snapshot daily metrics at SNAPSHOT_SCHEDULE_TIME
+	server.startSnapshotSchedule()
+
+	// Back up the active database every BACKUP_INTERVAL_HOURS
+	server.startBackupSchedule()
+
+	server.bindCostBasisSettings()
+	server.bindMetricSettings()
+	server.backfillRealizedProfit()
+
 	log.Printf("[SERVER] All services initialized successfully")
 	log.Printf("[SERVER] Server creation completed")
 
 	return server, nil
 }
 
+// backfillRealizedProfit stores the realized profit snapshot for closed options recorded before
+// the snapshot existed; it only touches options without one, so it is a no-op after the first run
+func (s *Server) backfillRealizedProfit() {
+	count, err := s.optionService.BackfillRealizedProfit()
+	if err != nil {
+		log.Printf("[SERVER] Warning: Failed to backfill realized profit: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("[SERVER] Backfilled realized profit for %d closed options", count)
+	}
+}
+
 // Close closes the database connection
 func (s *Server) Close() error {
+	s.polygonService.StopSymbolEnrichment()
+	s.stopWALCheckpoints()
+	s.stopPendingActivation()
+	s.stopSnapshotSchedule()
+	s.stopBackupSchedule()
+	s.closeSessionDatabases()
 	if s.db != nil {
 		log.Printf("[SERVER] Closing database connection")
 		return s.db.Close()
@@ -286,58 +364,115 @@ func (s *Server) setupRoutes() {
 	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("internal/web/static"))))
 	log.Printf("[SERVER] Route registered: /static/ -> file server")
 
-	http.HandleFunc("/", s.dashboardHandler)
+	http.HandleFunc("/", s.route((*Server).dashboardHandler))
 	log.Printf("[SERVER] Route registered: / -> dashboardHandler")
 
-	http.HandleFunc("/monthly", s.monthlyHandler)
+	http.HandleFunc("/monthly", s.route((*Server).monthlyHandler))
 	log.Printf("[SERVER] Route registered: /monthly -> monthlyHandler")
 
-	http.HandleFunc("/options", s.optionsHandler)
+	http.HandleFunc("/options", s.route((*Server).optionsHandler))
 	log.Printf("[SERVER] Route registered: /options -> optionsHandler")
 
-	http.HandleFunc("/all-options", s.allOptionsHandler)
+	http.HandleFunc("/all-options", s.route((*Server).allOptionsHandler))
 	log.Printf("[SERVER] Route registered: /all-options -> allOptionsHandler")
 
-	http.HandleFunc("/treasuries", s.treasuriesHandler)
+	http.HandleFunc("/treasuries", s.route((*Server).treasuriesHandler))
 	log.Printf("[SERVER] Route registered: /treasuries -> treasuriesHandler")
 
-	http.HandleFunc("/dividends", s.dividendsHandler)
+	http.HandleFunc("/dividends", s.route((*Server).dividendsHandler))
 	log.Printf("[SERVER] Route registered: /dividends -> dividendsHandler")
 
-	http.HandleFunc("/metrics", s.metricsHandler)
+	http.HandleFunc("/metrics", s.route((*Server).metricsHandler))
 	log.Printf("[SERVER] Route registered: /metrics -> metricsHandler")
 
-	http.HandleFunc("/zen", s.zenHandler)
+	http.HandleFunc("/zen", s.route((*Server).zenHandler))
 	log.Printf("[SERVER] Route registered: /zen -> zenHandler")
 
-	http.HandleFunc("/symbol/", s.symbolHandler)
+	http.HandleFunc("/symbol/", s.route((*Server).symbolHandler))
 	log.Printf("[SERVER] Route registered: /symbol/ -> symbolHandler")
 
-	http.HandleFunc("/api/premium-data", s.premiumDataHandler)
+	http.HandleFunc("/api/premium-data", s.route((*Server).premiumDataHandler))
 	log.Printf("[SERVER] Route registered: /api/premium-data -> premiumDataHandler")
 
-	http.HandleFunc("/api/options", s.optionAPIHandler)
+	http.HandleFunc("/api/options", s.route((*Server).optionAPIHandler))
 	log.Printf("[SERVER] Route registered: /api/options -> optionAPIHandler")
 
-	http.HandleFunc("/api/options/", s.individualOptionAPIHandler)
+	http.HandleFunc("/api/options/", s.route((*Server).individualOptionAPIHandler))
 	log.Printf("[SERVER] Route registered: /api/options/ -> individualOptionAPIHandler")
 
-	http.HandleFunc("/api/options/filter", s.optionsFilterHandler)
+	http.HandleFunc("/api/options/filter", s.route((*Server).optionsFilterHandler))
 	log.Printf("[SERVER] Route registered: /api/options/filter -> optionsFilterHandler")
 
-	http.HandleFunc("/api/symbols/", s.symbolAPIHandler)
+	http.HandleFunc("/api/options/ladder", s.route((*Server).optionsLadderHandler))
+	log.Printf("[SERVER] Route registered: /api/options/ladder -> optionsLadderHandler")
+
+	http.HandleFunc("/api/options/assign", s.route((*Server).optionAssignHandler))
+	log.Printf("[SERVER] Route registered: /api/options/assign -> optionAssignHandler")
+
+	http.HandleFunc("/api/options/expiring-week", s.route((*Server).optionsExpiringWeekHandler))
+	log.Printf("[SERVER] Route registered: /api/options/expiring-week -> optionsExpiringWeekHandler")
+
+	http.HandleFunc("/api/options/blended-breakeven", s.route((*Server).optionsBlendedBreakevenHandler))
+	log.Printf("[SERVER] Route registered: /api/options/blended-breakeven -> optionsBlendedBreakevenHandler")
+
+	http.HandleFunc("/api/options/premium-yield", s.route((*Server).optionsPremiumYieldHandler))
+	log.Printf("[SERVER] Route registered: /api/options/premium-yield -> optionsPremiumYieldHandler")
+
+	http.HandleFunc("/api/options/theoretical", s.route((*Server).optionsTheoreticalHandler))
+	log.Printf("[SERVER] Route registered: /api/options/theoretical -> optionsTheoreticalHandler")
+	http.HandleFunc("/api/options/prefill", s.route((*Server).optionPrefillHandler))
+	log.Printf("[SERVER] Route registered: /api/options/prefill -> optionPrefillHandler")
+
+	http.HandleFunc("/api/options/backfill-underlying", s.route((*Server).optionsBackfillUnderlyingHandler))
+	log.Printf("[SERVER] Route registered: /api/options/backfill-underlying -> optionsBackfillUnderlyingHandler")
+
+	http.HandleFunc("/api/options/ytd-premium", s.route((*Server).optionsYTDPremiumHandler))
+	log.Printf("[SERVER] Route registered: /api/options/ytd-premium -> optionsYTDPremiumHandler")
+
+	http.HandleFunc("/api/options/performance-by-strategy", s.route((*Server).optionsPerformanceByStrategyHandler))
+	log.Printf("[SERVER] Route registered: /api/options/performance-by-strategy -> optionsPerformanceByStrategyHandler")
+
+	http.HandleFunc("/api/options/detect-rolls", s.route((*Server).optionsDetectRollsHandler))
+	log.Printf("[SERVER] Route registered: /api/options/detect-rolls -> optionsDetectRollsHandler")
+
+	http.HandleFunc("/api/options/close-timing", s.route((*Server).optionsCloseTimingHandler))
+	log.Printf("[SERVER] Route registered: /api/options/close-timing -> optionsCloseTimingHandler")
+
+	http.HandleFunc("/api/options/streaks", s.route((*Server).optionsStreaksHandler))
+	log.Printf("[SERVER] Route registered: /api/options/streaks -> optionsStreaksHandler")
+	http.HandleFunc("/api/options/rollable", s.route((*Server).optionsRollableHandler))
+	log.Printf("[SERVER] Route registered: /api/options/rollable -> optionsRollableHandler")
+	http.HandleFunc("/api/options/covered-call-sim", s.route((*Server).optionsCoveredCallSimHandler))
+	log.Printf("[SERVER] Route registered: /api/options/covered-call-sim -> optionsCoveredCallSimHandler")
+
+	http.HandleFunc("/api/pending-trades", s.route((*Server).pendingTradesHandler))
+	log.Printf("[SERVER] Route registered: /api/pending-trades -> pendingTradesHandler")
+
+	http.HandleFunc("/api/symbols/", s.route((*Server).symbolAPIHandler))
 	log.Printf("[SERVER] Route registered: /api/symbols/ -> symbolAPIHandler")
 
-	http.HandleFunc("/api/dividends", s.dividendsAPIHandler)
+	http.HandleFunc("/api/symbols/rename", s.route((*Server).renameSymbolHandler))
+	log.Printf("[SERVER] Route registered: /api/symbols/rename -> renameSymbolHandler")
+
+	http.HandleFunc("/api/dividends", s.route((*Server).dividendsAPIHandler))
 	log.Printf("[SERVER] Route registered: /api/dividends -> dividendsAPIHandler")
 
-	http.HandleFunc("/api/long-positions", s.longPositionsAPIHandler)
+	http.HandleFunc("/api/long-positions", s.route((*Server).longPositionsAPIHandler))
 	log.Printf("[SERVER] Route registered: /api/long-positions -> longPositionsAPIHandler")
 
-	http.HandleFunc("/api/treasuries/", s.treasuryAPIHandler)
+	http.HandleFunc("/api/long-positions/exits", s.route((*Server).longPositionExitsAPIHandler))
+	log.Printf("[SERVER] Route registered: /api/long-positions/exits -> longPositionExitsAPIHandler")
+
+	http.HandleFunc("/api/long-positions/splits", s.route((*Server).stockSplitsAPIHandler))
+	log.Printf("[SERVER] Route registered: /api/long-positions/splits -> stockSplitsAPIHandler")
+
+	http.HandleFunc("/api/positions/", s.route((*Server).positionBasisDetailHandler))
+	log.Printf("[SERVER] Route registered: /api/positions/{id}/basis-detail -> positionBasisDetailHandler")
+
+	http.HandleFunc("/api/treasuries/", s.route((*Server).treasuryAPIHandler))
 	log.Printf("[SERVER] Route registered: /api/treasuries/ -> treasuryAPIHandler")
 
-	http.HandleFunc("/api/metrics", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/api/metrics", s.route(func(s *Server, w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			s.getMetricsHandler(w, r)
@@ -346,10 +481,10 @@ func (s *Server) setupRoutes() {
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-	})
+	}))
 	log.Printf("[SERVER] Route registered: /api/metrics -> metrics API handler")
 
-	http.HandleFunc("/api/metrics/", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/api/metrics/", s.route(func(s *Server, w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPut:
 			s.updateMetricHandler(w, r)
@@ -358,106 +493,204 @@ func (s *Server) setupRoutes() {
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-	})
+	}))
 	log.Printf("[SERVER] Route registered: /api/metrics/ -> individual metric API handler")
 
-	http.HandleFunc("/api/metrics/snapshot", s.createMetricsSnapshotHandler)
+	http.HandleFunc("/api/metrics/snapshot", s.route((*Server).createMetricsSnapshotHandler))
 	log.Printf("[SERVER] Route registered: /api/metrics/snapshot -> createMetricsSnapshotHandler")
 
-	http.HandleFunc("/api/metrics/chart-data", s.getMetricsChartDataHandler)
+	http.HandleFunc("/api/metrics/snapshot/status", s.route((*Server).snapshotStatusHandler))
+	log.Printf("[SERVER] Route registered: /api/metrics/snapshot/status -> snapshotStatusHandler")
+
+	http.HandleFunc("/api/metrics/recompute", s.route((*Server).recomputeMetricsHandler))
+	log.Printf("[SERVER] Route registered: /api/metrics/recompute -> recomputeMetricsHandler")
+
+	http.HandleFunc("/api/metrics/chart-data", s.route((*Server).getMetricsChartDataHandler))
 	log.Printf("[SERVER] Route registered: /api/metrics/chart-data -> getMetricsChartDataHandler")
 
-	http.HandleFunc("/add-option", s.addOptionHandler)
+	http.HandleFunc("/api/metrics/compare-snapshot-modes", s.route((*Server).compareSnapshotModesHandler))
+	log.Printf("[SERVER] Route registered: /api/metrics/compare-snapshot-modes -> compareSnapshotModesHandler")
+
+	http.HandleFunc("/api/metrics/symbol-contribution", s.route((*Server).symbolContributionHandler))
+	log.Printf("[SERVER] Route registered: /api/metrics/symbol-contribution -> symbolContributionHandler")
+
+	http.HandleFunc("/api/metrics/gaps", s.route((*Server).metricGapsHandler))
+	log.Printf("[SERVER] Route registered: /api/metrics/gaps -> metricGapsHandler")
+
+	http.HandleFunc("/add-option", s.route((*Server).addOptionHandler))
 	log.Printf("[SERVER] Route registered: /add-option -> addOptionHandler")
 
-	http.HandleFunc("/add-treasury", s.addTreasuryHandler)
+	http.HandleFunc("/add-treasury", s.route((*Server).addTreasuryHandler))
 	log.Printf("[SERVER] Route registered: /add-treasury -> addTreasuryHandler")
 
-	http.HandleFunc("/api/allocation-data", s.allocationDataHandler)
+	http.HandleFunc("/api/allocation-data", s.route((*Server).allocationDataHandler))
 	log.Printf("[SERVER] Route registered: /api/allocation-data -> allocationDataHandler")
 
-	http.HandleFunc("/api/optionable-positions", s.optionablePositionsHandler)
+	http.HandleFunc("/api/optionable-positions", s.route((*Server).optionablePositionsHandler))
 	log.Printf("[SERVER] Route registered: /api/optionable-positions -> optionablePositionsHandler")
 
-	http.HandleFunc("/import", s.HandleImport)
+	http.HandleFunc("/api/portfolio/beta", s.route((*Server).portfolioBetaHandler))
+	log.Printf("[SERVER] Route registered: /api/portfolio/beta -> portfolioBetaHandler")
+
+	http.HandleFunc("/api/portfolio/capital-efficiency", s.route((*Server).portfolioCapitalEfficiencyHandler))
+	log.Printf("[SERVER] Route registered: /api/portfolio/capital-efficiency -> portfolioCapitalEfficiencyHandler")
+
+	http.HandleFunc("/api/portfolio/income-yield", s.route((*Server).portfolioIncomeYieldHandler))
+	log.Printf("[SERVER] Route registered: /api/portfolio/income-yield -> portfolioIncomeYieldHandler")
+
+	http.HandleFunc("/api/portfolio/delta-exposure-by-symbol", s.route((*Server).portfolioDeltaExposureHandler))
+	log.Printf("[SERVER] Route registered: /api/portfolio/delta-exposure-by-symbol -> portfolioDeltaExposureHandler")
+
+	http.HandleFunc("/api/portfolio/shares-equivalent", s.route((*Server).portfolioSharesEquivalentHandler))
+	log.Printf("[SERVER] Route registered: /api/portfolio/shares-equivalent -> portfolioSharesEquivalentHandler")
+
+	http.HandleFunc("/api/portfolio/pnl-split", s.route((*Server).portfolioPnLSplitHandler))
+	log.Printf("[SERVER] Route registered: /api/portfolio/pnl-split -> portfolioPnLSplitHandler")
+
+	http.HandleFunc("/api/portfolio/risk-adjusted", s.route((*Server).portfolioRiskAdjustedHandler))
+	log.Printf("[SERVER] Route registered: /api/portfolio/risk-adjusted -> portfolioRiskAdjustedHandler")
+
+	http.HandleFunc("/api/portfolio/drawdown", s.route((*Server).portfolioDrawdownHandler))
+	log.Printf("[SERVER] Route registered: /api/portfolio/drawdown -> portfolioDrawdownHandler")
+
+	http.HandleFunc("/api/portfolio/correlation", s.route((*Server).portfolioCorrelationHandler))
+	log.Printf("[SERVER] Route registered: /api/portfolio/correlation -> portfolioCorrelationHandler")
+
+	http.HandleFunc("/api/alerts", s.route((*Server).alertsHandler))
+	log.Printf("[SERVER] Route registered: /api/alerts -> alertsHandler")
+
+	http.HandleFunc("/api/dashboard", s.route((*Server).dashboardAPIHandler))
+	log.Printf("[SERVER] Route registered: /api/dashboard -> dashboardAPIHandler")
+
+	http.HandleFunc("/api/reports/harvest-candidates", s.route((*Server).harvestCandidatesHandler))
+	log.Printf("[SERVER] Route registered: /api/reports/harvest-candidates -> harvestCandidatesHandler")
+	http.HandleFunc("/api/reports/compare", s.route((*Server).periodCompareHandler))
+	log.Printf("[SERVER] Route registered: /api/reports/compare -> periodCompareHandler")
+
+	http.HandleFunc("/api/income/projection", s.route((*Server).incomeProjectionHandler))
+	log.Printf("[SERVER] Route registered: /api/income/projection -> incomeProjectionHandler")
+
+	http.HandleFunc("/api/cashflow/calendar", s.route((*Server).cashflowCalendarHandler))
+	log.Printf("[SERVER] Route registered: /api/cashflow/calendar -> cashflowCalendarHandler")
+
+	http.HandleFunc("/import", s.route((*Server).HandleImport))
 	log.Printf("[SERVER] Route registered: /import -> HandleImport")
 
-	http.HandleFunc("/backup", s.HandleBackup)
+	http.HandleFunc("/backup", s.route((*Server).HandleBackup))
 	log.Printf("[SERVER] Route registered: /backup -> HandleBackup")
 
-	http.HandleFunc("/backup/", s.HandleBackupFile)
+	http.HandleFunc("/backup/", s.route((*Server).HandleBackupFile))
 	log.Printf("[SERVER] Route registered: /backup/ -> HandleBackupFile")
 
-	http.HandleFunc("/database/set-current", s.handleSetCurrentDatabase)
+	http.HandleFunc("/database/set-current", s.route((*Server).handleSetCurrentDatabase))
 	log.Printf("[SERVER] Route registered: /database/set-current -> handleSetCurrentDatabase")
 
-	http.HandleFunc("/database/create", s.handleCreateDatabase)
+	http.HandleFunc("/database/create", s.route((*Server).handleCreateDatabase))
 	log.Printf("[SERVER] Route registered: /database/create -> handleCreateDatabase")
 
-	http.HandleFunc("/database/delete/", s.handleDeleteDatabase)
+	http.HandleFunc("/database/delete/", s.route((*Server).handleDeleteDatabase))
 	log.Printf("[SERVER] Route registered: /database/delete/ -> handleDeleteDatabase")
 
+	http.HandleFunc("/api/database/clone", s.route((*Server).handleCloneDatabase))
+	log.Printf("[SERVER] Route registered: /api/database/clone -> handleCloneDatabase")
+
+	http.HandleFunc("/api/database/reset", s.route((*Server).handleResetDatabase))
+	log.Printf("[SERVER] Route registered: /api/database/reset -> handleResetDatabase")
+
 	http.Handle("/backups/", http.StripPrefix("/backups/", http.FileServer(http.Dir("./data/backups"))))
 	log.Printf("[SERVER] Route registered: /backups/ -> file server for backup directory")
 
-	http.HandleFunc("/import/upload", s.HandleImportUpload)
+	http.HandleFunc("/import/upload", s.route((*Server).HandleImportUpload))
 	log.Printf("[SERVER] Route registered: /import/upload -> HandleImportUpload")
 
-	http.HandleFunc("/import/upload/stocks", s.HandleStocksImportUpload)
+	http.HandleFunc("/import/upload/stocks", s.route((*Server).HandleStocksImportUpload))
 	log.Printf("[SERVER] Route registered: /import/upload/stocks -> HandleStocksImportUpload")
 
-	http.HandleFunc("/import/upload/dividends", s.HandleDividendsImportUpload)
+	http.HandleFunc("/import/upload/dividends", s.route((*Server).HandleDividendsImportUpload))
 	log.Printf("[SERVER] Route registered: /import/upload/dividends -> HandleDividendsImportUpload")
 
-	http.HandleFunc("/import/upload/treasuries", s.HandleTreasuriesImportUpload)
+	http.HandleFunc("/import/upload/treasuries", s.route((*Server).HandleTreasuriesImportUpload))
 	log.Printf("[SERVER] Route registered: /import/upload/treasuries -> HandleTreasuriesImportUpload")
 
-	http.HandleFunc("/api/generate-test-data", s.HandleGenerateTestData)
+	http.HandleFunc("/import/upload/greeks", s.route((*Server).HandleGreeksImportUpload))
+	log.Printf("[SERVER] Route registered: /import/upload/greeks -> HandleGreeksImportUpload")
+
+	http.HandleFunc("/import/upload/options-activity", s.route((*Server).HandleOptionActivityImportUpload))
+	log.Printf("[SERVER] Route registered: /import/upload/options-activity -> HandleOptionActivityImportUpload")
+
+	http.HandleFunc("/export/options", s.route((*Server).HandleOptionsExport))
+	log.Printf("[SERVER] Route registered: /export/options -> HandleOptionsExport")
+
+	http.HandleFunc("/export/stocks", s.route((*Server).HandleStocksExport))
+	log.Printf("[SERVER] Route registered: /export/stocks -> HandleStocksExport")
+
+	http.HandleFunc("/export/activity.csv", s.route((*Server).HandleActivityExport))
+	log.Printf("[SERVER] Route registered: /export/activity.csv -> HandleActivityExport")
+
+	http.HandleFunc("/export/form8949.csv", s.route((*Server).HandleForm8949Export))
+	log.Printf("[SERVER] Route registered: /export/form8949.csv -> HandleForm8949Export")
+
+	http.HandleFunc("/api/generate-test-data", s.route((*Server).HandleGenerateTestData))
 	log.Printf("[SERVER] Route registered: /api/generate-test-data -> HandleGenerateTestData")
 
-	http.HandleFunc("/help", s.helpHandler)
+	http.HandleFunc("/help", s.route((*Server).helpHandler))
 	log.Printf("[SERVER] Route registered: /help -> helpHandler")
 
-	http.HandleFunc("/settings", s.settingsHandler)
+	http.HandleFunc("/settings", s.route((*Server).settingsHandler))
 	log.Printf("[SERVER] Route registered: /settings -> settingsHandler")
 
-	http.HandleFunc("/api/settings", s.settingsAPIHandler)
+	http.HandleFunc("/api/settings", s.route((*Server).settingsAPIHandler))
 	log.Printf("[SERVER] Route registered: /api/settings -> settingsAPIHandler")
 
-	http.HandleFunc("/api/settings/", s.individualSettingAPIHandler)
+	http.HandleFunc("/api/settings/", s.route((*Server).individualSettingAPIHandler))
 	log.Printf("[SERVER] Route registered: /api/settings/ -> individualSettingAPIHandler")
 
-	http.HandleFunc("/api/polygon/test", s.polygonTestHandler)
+	http.HandleFunc("/api/config/effective", s.route((*Server).effectiveConfigHandler))
+	log.Printf("[SERVER] Route registered: /api/config/effective -> effectiveConfigHandler")
+
+	http.HandleFunc("/api/polygon/test", s.route((*Server).polygonTestHandler))
 	log.Printf("[SERVER] Route registered: /api/polygon/test -> polygonTestHandler")
 
-	http.HandleFunc("/api/polygon/update-prices", s.polygonUpdatePricesHandler)
+	http.HandleFunc("/api/polygon/update-prices", s.route((*Server).polygonUpdatePricesHandler))
 	log.Printf("[SERVER] Route registered: /api/polygon/update-prices -> polygonUpdatePricesHandler")
 
-	http.HandleFunc("/api/polygon/symbol-info/", s.polygonSymbolInfoHandler)
+	http.HandleFunc("/api/polygon/symbol-info/", s.route((*Server).polygonSymbolInfoHandler))
 	log.Printf("[SERVER] Route registered: /api/polygon/symbol-info/ -> polygonSymbolInfoHandler")
 
-	http.HandleFunc("/api/polygon/status", s.polygonStatusHandler)
+	http.HandleFunc("/api/polygon/status", s.route((*Server).polygonStatusHandler))
 	log.Printf("[SERVER] Route registered: /api/polygon/status -> polygonStatusHandler")
 
-	http.HandleFunc("/api/polygon/fetch-dividends", s.polygonFetchDividendsHandler)
+	http.HandleFunc("/api/polygon/fetch-dividends", s.route((*Server).polygonFetchDividendsHandler))
 	log.Printf("[SERVER] Route registered: /api/polygon/fetch-dividends -> polygonFetchDividendsHandler")
 
-	http.HandleFunc("/settings/ibkr", s.ibkrSettingsHandler)
+	http.HandleFunc("/api/debug/option-snapshot", s.route((*Server).polygonOptionSnapshotDebugHandler))
+	log.Printf("[SERVER] Route registered: /api/debug/option-snapshot -> polygonOptionSnapshotDebugHandler")
+
+	http.HandleFunc("/api/maintenance/data-quality", s.route((*Server).dataQualityHandler))
+	log.Printf("[SERVER] Route registered: /api/maintenance/data-quality -> dataQualityHandler")
+
+	http.HandleFunc("/api/maintenance/dedupe-options", s.route((*Server).dedupeOptionsHandler))
+	log.Printf("[SERVER] Route registered: /api/maintenance/dedupe-options -> dedupeOptionsHandler")
+
+	http.HandleFunc("/api/scheduler/status", s.route((*Server).schedulerStatusHandler))
+	log.Printf("[SERVER] Route registered: /api/scheduler/status -> schedulerStatusHandler")
+
+	http.HandleFunc("/settings/ibkr", s.route((*Server).ibkrSettingsHandler))
 	log.Printf("[SERVER] Route registered: /settings/ibkr -> ibkrSettingsHandler")
 
-	http.HandleFunc("/api/ibkr/test", s.ibkrTestHandler)
+	http.HandleFunc("/api/ibkr/test", s.route((*Server).ibkrTestHandler))
 	log.Printf("[SERVER] Route registered: /api/ibkr/test -> ibkrTestHandler")
 
-	http.HandleFunc("/api/ibkr/sync", s.ibkrSyncHandler)
+	http.HandleFunc("/api/ibkr/sync", s.route((*Server).ibkrSyncHandler))
 	log.Printf("[SERVER] Route registered: /api/ibkr/sync -> ibkrSyncHandler")
 
-	http.HandleFunc("/api/ibkr/status", s.ibkrStatusHandler)
+	http.HandleFunc("/api/ibkr/status", s.route((*Server).ibkrStatusHandler))
 	log.Printf("[SERVER] Route registered: /api/ibkr/status -> ibkrStatusHandler")
 
-	http.HandleFunc("/api/ibkr/owned-options", s.ibkrOwnedOptionsHandler)
+	http.HandleFunc("/api/ibkr/owned-options", s.route((*Server).ibkrOwnedOptionsHandler))
 	log.Printf("[SERVER] Route registered: /api/ibkr/owned-options -> ibkrOwnedOptionsHandler")
 
-	http.HandleFunc("/api/ibkr/disconnect", s.ibkrDisconnectHandler)
+	http.HandleFunc("/api/ibkr/disconnect", s.route((*Server).ibkrDisconnectHandler))
 	log.Printf("[SERVER] Route registered: /api/ibkr/disconnect -> ibkrDisconnectHandler")
 
 	log.Printf("[SERVER] All routes registered successfully")
@@ -481,6 +714,24 @@ func (s *Server) SetupTestRoutes() {
 	s.setupRoutes()
 }
 
+// formatOptionTotalProfit renders an option's net profit, wrapping losses in a "negative" span
+func formatOptionTotalProfit(option *models.Option) template.HTML {
+	profit := option.CalculateTotalProfit()
+	if profit < 0 {
+		return template.HTML(fmt.Sprintf("<span class=\"negative\">$%.2f</span>", profit))
+	}
+	return template.HTML(fmt.Sprintf("$%.2f", profit))
+}
+
+// formatOptionPercentOfProfit renders an option's percent of max profit, wrapping losses in a "negative" span
+func formatOptionPercentOfProfit(option *models.Option) template.HTML {
+	percent := option.CalculatePercentOfProfit()
+	if percent < 0 {
+		return template.HTML(fmt.Sprintf("<span class=\"negative\">%.2f%%</span>", percent))
+	}
+	return template.HTML(fmt.Sprintf("%.2f%%", percent))
+}
+
 // ExpirationGroup represents a group of positions with the same expiration date
 type ExpirationGroup struct {
 	Expiration time.Time
@@ -547,6 +798,81 @@ func (s *Server) renderTemplate(w http.ResponseWriter, templateName string, data
 	}
 }
 
+// marketLocation returns the configured MARKET_TIMEZONE, falling back to server-local time if invalid
+func (s *Server) marketLocation() *time.Location {
+	name := s.settingService.GetValueWithDefault("MARKET_TIMEZONE", models.DefaultMarketTimezone)
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("[SERVER] Invalid MARKET_TIMEZONE %q, using server local time: %v", name, err)
+		return time.Local
+	}
+	return location
+}
+
+// includeTreasuriesInTotal reports whether treasuries count toward portfolio totals (INCLUDE_TREASURIES_IN_TOTAL)
+func (s *Server) includeTreasuriesInTotal() bool {
+	return s.settingService.GetBoolWithDefault("INCLUDE_TREASURIES_IN_TOTAL", true)
+}
+
+// requireAPIToken enforces the optional API_TOKEN setting, writing a 401 and returning false
+// when a token is configured and the request does not present it
+func (s *Server) requireAPIToken(w http.ResponseWriter, r *http.Request) bool {
+	expected := strings.TrimSpace(s.settingService.GetValue("API_TOKEN"))
+	if expected == "" {
+		return true
+	}
+
+	provided := r.Header.Get("X-API-Token")
+	if bearer := r.Header.Get("Authorization"); strings.HasPrefix(bearer, "Bearer ") {
+		provided = strings.TrimPrefix(bearer, "Bearer ")
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(provided)), []byte(expected)) != 1 {
+		log.Printf("[SERVER] Rejected %s %s: missing or invalid API token", r.Method, r.URL.Path)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// bindMetricSettings makes the metric service read MARKET_TIMEZONE and INCLUDE_TREASURIES_IN_TOTAL,
+// and the option and long position services MARKET_TIMEZONE, from this server's current settings;
+// call it whenever those services are replaced
+func (s *Server) bindMetricSettings() {
+	s.metricService.SetMarketLocation(s.marketLocation)
+	s.optionService.SetMarketLocation(s.marketLocation)
+	s.longPositionService.SetMarketLocation(s.marketLocation)
+	s.metricService.SetIncludeTreasuries(s.includeTreasuriesInTotal)
+}
+
+// bindCostBasisSettings makes the long position service read BASIS_COMMISSION_MODE,
+// BASIS_DEFER_OPEN_CALL_PREMIUM and CALL_COVERAGE_TRACKING from this server's current settings;
+// call it whenever longPositionService is replaced
+func (s *Server) bindCostBasisSettings() {
+	s.longPositionService.SetBasisCommissionMode(func() string {
+		return s.configValue("BASIS_COMMISSION_MODE")
+	})
+	s.longPositionService.SetOpenCallPremiumSplit(func() bool {
+		return s.settingService.GetBoolWithDefault("BASIS_DEFER_OPEN_CALL_PREMIUM", false)
+	})
+	s.longPositionService.SetCallCoverageTracking(func() bool {
+		return s.settingService.GetBoolWithDefault("CALL_COVERAGE_TRACKING", false)
+	})
+}
+
+// recalculateAllCostBasis recomputes adjusted cost basis for every symbol, e.g. after
+// BASIS_COMMISSION_MODE changes
+func (s *Server) recalculateAllCostBasis() {
+	symbols, err := s.symbolService.GetDistinctSymbols()
+	if err != nil {
+		log.Printf("[COST BASIS] Failed to get symbols for recalculation: %v", err)
+		return
+	}
+	for _, symbol := range symbols {
+		s.recalculateAdjustedCostBasis(symbol)
+	}
+	log.Printf("[COST BASIS] Recalculated adjusted cost basis for %d symbols", len(symbols))
+}
+
 // recalculateAdjustedCostBasis recomputes adjusted basis for a symbol, logging any errors.
 func (s *Server) recalculateAdjustedCostBasis(symbol string) {
 	if symbol == "" {
@@ -559,6 +885,9 @@ func (s *Server) recalculateAdjustedCostBasis(symbol string) {
 
 // getCurrentDatabaseName returns the current database name for template rendering
 func (s *Server) getCurrentDatabaseName() string {
+	if s.sessionDBName != "" {
+		return s.sessionDBName
+	}
 	dbName, err := database.GetCurrentDatabase()
 	if err != nil {
 		log.Printf("[SERVER] Error getting current database name: %v", err)
@@ -567,9 +896,29 @@ func (s *Server) getCurrentDatabaseName() string {
 	return dbName
 }
 
+// activeDB returns the database and settings service for a background task, read-locked so the
+// database cannot be switched or closed under the task until release is called
+func (s *Server) activeDB() (db *sql.DB, settings *models.SettingService, release func()) {
+	s.dbMu.RLock()
+	return s.db, s.settingService, s.dbMu.RUnlock
+}
+
 // getAllSymbolsList returns a list of all distinct symbols for navigation
-func (s *Server) getAllSymbolsList() []string {
-	symbols, err := s.symbolService.GetDistinctSymbols()
+// When NAV_ACTIVE_SYMBOLS_ONLY is enabled, only symbols with open positions or activity within
+// NAV_SYMBOL_LOOKBACK_DAYS are listed; otherwise every symbol ever recorded is shown.
+// A request with ?all=1 lists every symbol regardless of the setting.
+func (s *Server) getAllSymbolsList(r *http.Request) []string {
+	var (
+		symbols []string
+		err     error
+	)
+	showAll := r.URL.Query().Get("all") == "1"
+	if !showAll && s.settingService.GetBoolWithDefault("NAV_ACTIVE_SYMBOLS_ONLY", false) {
+		lookbackDays := int(s.settingService.GetFloatWithDefault("NAV_SYMBOL_LOOKBACK_DAYS", 90))
+		symbols, err = s.symbolService.GetRecentlyActiveSymbols(time.Now().AddDate(0, 0, -lookbackDays))
+	} else {
+		symbols, err = s.symbolService.GetDistinctSymbols()
+	}
 	if err != nil {
 		log.Printf("[SERVER] Error getting symbols list: %v", err)
 		return []string{}
@@ -0,0 +1,169 @@
+package web
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"stonks/internal/database"
+	"stonks/internal/models"
+	"stonks/internal/polygon"
+	"strings"
+)
+
+// sessionDatabaseCookie holds the database a browser session has selected when DATABASE_SCOPE is "session"
+const sessionDatabaseCookie = "wheeler_db"
+
+// Database selection scopes for DATABASE_SCOPE
+const (
+	DatabaseScopeGlobal  = "global"
+	DatabaseScopeSession = "session"
+)
+
+// route adapts a Server method to an http.HandlerFunc that runs against the server for the
+// request's database: the global server, or a per-session server when DATABASE_SCOPE is "session"
+func (s *Server) route(handler func(*Server, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(s.forRequest(r), w, r)
+	}
+}
+
+// rootServer returns the global server that owns background tasks and session connections
+func (s *Server) rootServer() *Server {
+	if s.root != nil {
+		return s.root
+	}
+	return s
+}
+
+// sessionScopedDatabases reports whether database selection is per browser session. The scope
+// is always read from the globally selected database (or the DATABASE_SCOPE environment variable).
+func (s *Server) sessionScopedDatabases() bool {
+	return strings.EqualFold(strings.TrimSpace(s.rootServer().configValue("DATABASE_SCOPE")), DatabaseScopeSession)
+}
+
+// forRequest returns the server whose services use the database selected by the request's session
+// cookie, or the global server when the scope is global, no cookie is set, or it names the global database
+func (s *Server) forRequest(r *http.Request) *Server {
+	if !s.sessionScopedDatabases() {
+		return s
+	}
+	cookie, err := r.Cookie(sessionDatabaseCookie)
+	if err != nil || cookie.Value == "" || cookie.Value == s.getCurrentDatabaseName() {
+		return s
+	}
+
+	session, err := s.sessionServer(cookie.Value)
+	if err != nil {
+		log.Printf("[SESSION DB] Using global database, session database %q unavailable: %v", cookie.Value, err)
+		return s
+	}
+	return session
+}
+
+// sessionServer returns the cached server for a session-selected database, connecting on first use.
+// Session servers share templates with the global server but run no background tasks; symbol
+// enrichment, WAL checkpoints and pending activation only run against the global database.
+func (s *Server) sessionServer(dbName string) (*Server, error) {
+	if !validDatabaseName(dbName) {
+		return nil, fmt.Errorf("invalid database name")
+	}
+
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+
+	if session, ok := s.sessionServers[dbName]; ok {
+		return session, nil
+	}
+
+	dbPath := filepath.Join("./data", dbName)
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("database does not exist: %w", err)
+	}
+	dbWrapper, err := database.NewDB(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	symbolService := models.NewSymbolService(dbWrapper.DB)
+	settingService := models.NewSettingService(dbWrapper.DB)
+	session := &Server{
+		db:                    dbWrapper.DB,
+		optionService:         models.NewOptionService(dbWrapper.DB),
+		symbolService:         symbolService,
+		treasuryService:       models.NewTreasuryService(dbWrapper.DB),
+		treasuryCouponService: models.NewTreasuryCouponService(dbWrapper.DB),
+		greeksSnapshotService: models.NewGreeksSnapshotService(dbWrapper.DB),
+		longPositionService:   models.NewLongPositionService(dbWrapper.DB),
+		dividendService:       models.NewDividendService(dbWrapper.DB),
+		settingService:        settingService,
+		metricService:         models.NewMetricService(dbWrapper.DB),
+		polygonService:        polygon.NewService(symbolService, settingService),
+		templates:             s.templates,
+		root:                  s,
+		sessionDBName:         dbName,
+	}
+	session.bindCostBasisSettings()
+	session.bindMetricSettings()
+	session.backfillRealizedProfit()
+
+	if s.sessionServers == nil {
+		s.sessionServers = make(map[string]*Server)
+	}
+	s.sessionServers[dbName] = session
+	log.Printf("[SESSION DB] Opened session database: %s", dbName)
+	return session, nil
+}
+
+// closeSessionDatabase closes the cached session connection to a database, if any
+func (s *Server) closeSessionDatabase(dbName string) {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+
+	if session, ok := s.sessionServers[dbName]; ok {
+		if err := session.db.Close(); err != nil {
+			log.Printf("[SESSION DB] Warning: Error closing session database %s: %v", dbName, err)
+		}
+		delete(s.sessionServers, dbName)
+	}
+}
+
+// closeSessionDatabases closes every cached session connection
+func (s *Server) closeSessionDatabases() {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+
+	for name, session := range s.sessionServers {
+		if err := session.db.Close(); err != nil {
+			log.Printf("[SESSION DB] Warning: Error closing session database %s: %v", name, err)
+		}
+	}
+	s.sessionServers = nil
+}
+
+// setSessionDatabase selects a database for this browser session only. Selecting the global
+// database clears the cookie so the session follows later global switches again.
+func (s *Server) setSessionDatabase(w http.ResponseWriter, dbName string) {
+	cookie := &http.Cookie{
+		Name:     sessionDatabaseCookie,
+		Value:    dbName,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	globalName, err := database.GetCurrentDatabase()
+	if err == nil && globalName == dbName {
+		cookie.Value = ""
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+}
+
+// validDatabaseName reports whether name is a plain .db filename inside the data directory
+func validDatabaseName(name string) bool {
+	if strings.Contains(name, "..") || strings.Contains(name, "/") || strings.Contains(name, "\\") {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(name), ".db")
+}
@@ -23,11 +23,7 @@ func (s *Server) settingsHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[SETTINGS] Handling settings page request")
 
 	// Get all symbols for navigation
-	symbols, err := s.symbolService.GetDistinctSymbols()
-	if err != nil {
-		log.Printf("[SETTINGS] Error getting symbols: %v", err)
-		symbols = []string{}
-	}
+	symbols := s.getAllSymbolsList(r)
 
 	// Get all settings
 	settings, err := s.settingService.GetAll()
@@ -159,6 +155,7 @@ func (s *Server) createSettingAPI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("[SETTINGS API] Successfully created setting: %s", setting.Name)
+	s.applySettingChange(setting.Name)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -189,6 +186,7 @@ func (s *Server) updateSettingAPI(w http.ResponseWriter, r *http.Request, name s
 	}
 
 	log.Printf("[SETTINGS API] Successfully updated setting: %s", setting.Name)
+	s.applySettingChange(setting.Name)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(setting); err != nil {
@@ -210,6 +208,15 @@ func (s *Server) deleteSettingAPI(w http.ResponseWriter, r *http.Request, name s
 	}
 
 	log.Printf("[SETTINGS API] Successfully deleted setting: %s", name)
+	s.applySettingChange(name)
 
 	w.WriteHeader(http.StatusNoContent)
-}
\ No newline at end of file
+}
+
+// applySettingChange refreshes stored values derived from a setting that was just written
+func (s *Server) applySettingChange(name string) {
+	// Turning call coverage tracking on needs a full recalculation to populate the mapping
+	if name == "BASIS_COMMISSION_MODE" || name == "BASIS_DEFER_OPEN_CALL_PREMIUM" || name == "CALL_COVERAGE_TRACKING" {
+		s.recalculateAllCostBasis()
+	}
+}
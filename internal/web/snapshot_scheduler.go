@@ -0,0 +1,185 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// snapshotScheduleRecheck is how often the scheduled snapshot task checks whether a run is due
+const snapshotScheduleRecheck = time.Minute
+
+// startSnapshotSchedule runs a daily ComprehensiveSnapshot at SNAPSHOT_SCHEDULE_TIME (HH:MM in the
+// market timezone); a blank time disables it. The setting is re-read every minute so changes apply
+// without a restart.
+func (s *Server) startSnapshotSchedule() {
+	s.snapshotRun.reset()
+
+	s.snapshotDone = make(chan struct{})
+	go s.runSnapshotSchedule(s.snapshotDone)
+	s.registerScheduledTask("metrics_snapshot", s.snapshotTaskStatus)
+	log.Printf("[SNAPSHOT] Scheduled snapshot task started")
+}
+
+// snapshotTaskStatus reports the scheduled snapshot task for /api/scheduler/status
+func (s *Server) snapshotTaskStatus() SchedulerTaskStatus {
+	scheduled, enabled := s.snapshotScheduleTime()
+	state := s.snapshotRun.current()
+
+	status := SchedulerTaskStatus{
+		Description: "Daily snapshot of portfolio metrics",
+		Enabled:     enabled,
+		Schedule:    "disabled",
+		Settings:    []string{"SNAPSHOT_SCHEDULE_TIME", "SNAPSHOT_RETRY_ATTEMPTS", "SNAPSHOT_RETRY_BACKOFF_SECONDS"},
+		LastRunAt:   state.lastRunAt,
+		LastResult:  state.resultOrNeverRun(),
+		LastError:   state.lastError,
+	}
+	if state.attempts > 1 {
+		status.Detail = fmt.Sprintf("%d attempts", state.attempts)
+	}
+	if enabled {
+		status.Schedule = "daily at " + scheduled.Format("15:04")
+		next := nextSnapshotRun(scheduled, state)
+		status.NextRunAt = &next
+	}
+	return status
+}
+
+// nextSnapshotRun returns today's scheduled time, or tomorrow's once today's run has happened
+func nextSnapshotRun(scheduled time.Time, state taskRunState) time.Time {
+	if state.runDate == scheduled.Format("2006-01-02") {
+		return scheduled.AddDate(0, 0, 1)
+	}
+	return scheduled
+}
+
+// stopSnapshotSchedule stops the scheduled snapshot task
+func (s *Server) stopSnapshotSchedule() {
+	if s.snapshotDone != nil {
+		close(s.snapshotDone)
+		s.snapshotDone = nil
+	}
+}
+
+func (s *Server) runSnapshotSchedule(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(snapshotScheduleRecheck):
+		}
+
+		scheduled, ok := s.snapshotScheduleTime()
+		if !ok {
+			continue
+		}
+		now := time.Now().In(s.marketLocation())
+		today := now.Format("2006-01-02")
+		alreadyRan := s.snapshotRun.current().runDate == today
+		if alreadyRan || now.Before(scheduled) {
+			continue
+		}
+
+		s.runScheduledSnapshot(done, today)
+	}
+}
+
+// snapshotScheduleTime returns today's scheduled run time, or false when no valid time is configured
+func (s *Server) snapshotScheduleTime() (time.Time, bool) {
+	value := strings.TrimSpace(s.settingService.GetValue("SNAPSHOT_SCHEDULE_TIME"))
+	if value == "" {
+		return time.Time{}, false
+	}
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		log.Printf("[SNAPSHOT] Ignoring invalid SNAPSHOT_SCHEDULE_TIME %q (use HH:MM)", value)
+		return time.Time{}, false
+	}
+	now := time.Now().In(s.marketLocation())
+	return time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location()), true
+}
+
+// runScheduledSnapshot snapshots today's metrics, retrying with doubling backoff while SQLite
+// reports the database as locked (e.g. during a backup). Only the final failure is logged as an error.
+func (s *Server) runScheduledSnapshot(done <-chan struct{}, runDate string) {
+	attempts := int(s.settingService.GetFloatWithDefault("SNAPSHOT_RETRY_ATTEMPTS", 3))
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := time.Duration(s.settingService.GetFloatWithDefault("SNAPSHOT_RETRY_BACKOFF_SECONDS", 5) * float64(time.Second))
+	if backoff <= 0 {
+		backoff = 5 * time.Second
+	}
+
+	s.snapshotRun.begin(time.Now(), runDate)
+
+	var err error
+retry:
+	for attempt := 1; attempt <= attempts; attempt++ {
+		s.snapshotRun.setAttempts(attempt)
+
+		err = s.metricService.ComprehensiveSnapshot(1)
+		if err == nil || !isDatabaseLocked(err) || attempt == attempts {
+			break
+		}
+
+		log.Printf("[SNAPSHOT] Attempt %d of %d hit a locked database, retrying in %s: %v", attempt, attempts, backoff, err)
+		select {
+		case <-done:
+			err = fmt.Errorf("snapshot canceled during retry: %w", err)
+			break retry
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+
+	s.snapshotRun.finish(TaskResultSuccess, err, "")
+	if err != nil {
+		log.Printf("[SNAPSHOT] ERROR: Scheduled snapshot failed after %d attempt(s): %v", s.snapshotRun.current().attempts, err)
+		return
+	}
+	log.Printf("[SNAPSHOT] Scheduled snapshot complete in %d attempt(s)", s.snapshotRun.current().attempts)
+}
+
+// isDatabaseLocked reports whether err is SQLite's transient busy/locked error
+func isDatabaseLocked(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "database is locked") || strings.Contains(message, "database table is locked") || strings.Contains(message, "sqlite_busy")
+}
+
+// snapshotStatusHandler handles GET /api/metrics/snapshot/status, reporting the last scheduled
+// snapshot run with success and failure timestamps
+func (s *Server) snapshotStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Background tasks belong to the global server
+	root := s.rootServer()
+	scheduled, enabled := root.snapshotScheduleTime()
+
+	state := root.snapshotRun.current()
+
+	response := SnapshotStatusResponse{
+		Enabled:       enabled,
+		Schedule:      strings.TrimSpace(root.settingService.GetValue("SNAPSHOT_SCHEDULE_TIME")),
+		Status:        state.resultOrNeverRun(),
+		LastRunAt:     state.lastRunAt,
+		LastSuccessAt: state.lastSuccessAt,
+		LastFailureAt: state.lastFailureAt,
+		Attempts:      state.attempts,
+		LastError:     state.lastError,
+	}
+	if enabled {
+		next := nextSnapshotRun(scheduled, state)
+		response.NextRunAt = &next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
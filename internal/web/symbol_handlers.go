@@ -3,10 +3,12 @@ package web
 import (
 	"encoding/json"
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"sort"
 	"stonks/internal/models"
+	"stonks/internal/polygon"
 	"strconv"
 	"strings"
 	"time"
@@ -26,13 +28,8 @@ func (s *Server) symbolHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[SYMBOL] ===== Starting symbol handler for: %s =====", symbol)
 
 	log.Printf("[SYMBOL] Step 1: Getting all symbols from database")
-	symbols, err := s.symbolService.GetDistinctSymbols()
-	if err != nil {
-		log.Printf("[SYMBOL] ERROR: Failed to get symbols: %v", err)
-		symbols = []string{}
-	} else {
-		log.Printf("[SYMBOL] Successfully retrieved %d symbols: %v", len(symbols), symbols)
-	}
+	symbols := s.getAllSymbolsList(r)
+	log.Printf("[SYMBOL] Retrieved %d symbols: %v", len(symbols), symbols)
 
 	// Get symbol data from database
 	log.Printf("[SYMBOL] Step 2: Getting symbol data for %s", symbol)
@@ -123,6 +120,18 @@ func (s *Server) symbolHandler(w http.ResponseWriter, r *http.Request) {
 		log.Printf("[SYMBOL] Sorted %d options for %s (open positions first)", len(optionsList), symbol)
 	}
 
+	// Canceled orders are listed separately and left out of every calculation below
+	var canceledOptions []*models.Option
+	if allOptions, err := s.optionService.GetBySymbolWithCanceled(symbol); err != nil {
+		log.Printf("[SYMBOL] ERROR: Failed to get canceled options for %s: %v", symbol, err)
+	} else {
+		for _, option := range allOptions {
+			if option.IsCanceled() {
+				canceledOptions = append(canceledOptions, option)
+			}
+		}
+	}
+
 	// Get long positions for this symbol
 	log.Printf("[SYMBOL] Step 5: Getting long positions for %s", symbol)
 	longPositionsList, err := s.longPositionService.GetBySymbol(symbol)
@@ -131,6 +140,12 @@ func (s *Server) symbolHandler(w http.ResponseWriter, r *http.Request) {
 		longPositionsList = []*models.LongPosition{}
 	} else {
 		log.Printf("[SYMBOL] Retrieved %d long positions for %s", len(longPositionsList), symbol)
+		if err := s.longPositionService.AttachExits(longPositionsList); err != nil {
+			log.Printf("[SYMBOL] ERROR: Failed to get exits for %s: %v", symbol, err)
+		}
+		if err := s.attachProvisionalBasis(longPositionsList); err != nil {
+			log.Printf("[SYMBOL] ERROR: Failed to compute provisional basis for %s: %v", symbol, err)
+		}
 	}
 
 	// Attach cost-basis-adjusting options to each position for display
@@ -138,12 +153,21 @@ func (s *Server) symbolHandler(w http.ResponseWriter, r *http.Request) {
 		position.CostBasisOptions = costBasisAdjustingOptions(position, optionsList)
 	}
 
-	// Calculate Options Gains - sum of total profit from all closed options for this symbol
+	// Calculate Options Gains - sum of total profit from all closed options for this symbol,
+	// net of commission and fees unless the gross returns basis is in effect
 	log.Printf("[SYMBOL] Step 6: Calculating options gains for %s", symbol)
+	gross, err := s.useGrossReturns(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	var optionsGains float64
 	allOptionsCount := 0
 	for _, option := range optionsList {
 		profit := option.CalculateTotalProfit()
+		if gross {
+			profit = option.CalculateGrossProfit()
+		}
 		optionsGains += profit
 		allOptionsCount++
 		status := "closed"
@@ -159,20 +183,21 @@ func (s *Server) symbolHandler(w http.ResponseWriter, r *http.Request) {
 	var capGains float64
 	closedPositionsCount := 0
 	for _, position := range longPositionsList {
-		if position.Closed != nil && position.ExitPrice != nil {
-			profit := position.CalculateProfitLoss(*position.ExitPrice)
+		if (position.Closed != nil && position.ExitPrice != nil) || len(position.Exits) > 0 {
+			profit := position.CalculateRealizedProfitLoss()
 			capGains += profit
 			closedPositionsCount++
-			log.Printf("[SYMBOL] Position %d for %s: Profit=%.2f", position.ID, symbol, profit)
+			log.Printf("[SYMBOL] Position %d for %s: Profit=%.2f (%d exits)", position.ID, symbol, profit, len(position.Exits))
 		}
 	}
 	log.Printf("[SYMBOL] Total cap gains for %s: $%.2f (%d closed positions)", symbol, capGains, closedPositionsCount)
 
-	// Calculate total invested in all long positions (for Cash on Cash calculation)
+	// Calculate total invested in all long positions (for Cash on Cash calculation). This is the
+	// capital put into each lot, so exited shares still count.
 	log.Printf("[SYMBOL] Step 8: Calculating total invested for %s", symbol)
 	var totalInvested float64
 	for _, position := range longPositionsList {
-		invested := position.CalculateTotalInvested()
+		invested := position.CostBasisPerShare() * float64(position.Shares)
 		totalInvested += invested
 		status := "open"
 		if position.Closed != nil {
@@ -195,7 +220,7 @@ func (s *Server) symbolHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Build monthly results for this symbol
 	log.Printf("[SYMBOL] Step 9: Building monthly results for %s", symbol)
-	monthlyResults := s.buildSymbolMonthlyResults(optionsList)
+	monthlyResults := s.buildSymbolMonthlyResults(optionsList, gross)
 	log.Printf("[SYMBOL] Built %d monthly results for %s", len(monthlyResults), symbol)
 
 	log.Printf("[SYMBOL] Step 10: Creating template data for %s", symbol)
@@ -220,8 +245,10 @@ func (s *Server) symbolHandler(w http.ResponseWriter, r *http.Request) {
 		DividendsList:     dividendsList,
 		DividendsTotal:    dividendsTotal,
 		OptionsList:       optionsList,
+		CanceledOptions:   canceledOptions,
 		LongPositionsList: longPositionsList,
 		MonthlyResults:    monthlyResults,
+		ReturnsBasis:      returnsBasisName(gross),
 		CurrentDB:         s.getCurrentDatabaseName(),
 		ActivePage:        "symbol",
 	}
@@ -237,8 +264,9 @@ func (s *Server) symbolHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[SYMBOL] ===== Completed symbol handler for: %s =====", symbol)
 }
 
-// buildSymbolMonthlyResults creates monthly aggregation for a specific symbol's options based on opened date
-func (s *Server) buildSymbolMonthlyResults(options []*models.Option) []SymbolMonthlyResult {
+// buildSymbolMonthlyResults creates monthly aggregation for a specific symbol's options based on opened date,
+// with profit before commission and fees when gross
+func (s *Server) buildSymbolMonthlyResults(options []*models.Option, gross bool) []SymbolMonthlyResult {
 	log.Printf("[MONTHLY] Building monthly results for %d options", len(options))
 	monthMap := make(map[string]*SymbolMonthlyResult)
 	months := []string{"January", "February", "March", "April", "May", "June",
@@ -265,6 +293,9 @@ func (s *Server) buildSymbolMonthlyResults(options []*models.Option) []SymbolMon
 		monthData := monthMap[monthName]
 
 		profit := option.CalculateTotalProfit()
+		if gross {
+			profit = option.CalculateGrossProfit()
+		}
 		status := "open"
 		if option.Closed != nil {
 			status = "closed"
@@ -426,6 +457,21 @@ func (s *Server) updateSymbolHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if updateReq.Beta.Set {
+		updatedSymbol, err = s.symbolService.UpdateBeta(symbol, updateReq.Beta.Value)
+		if err != nil {
+			http.Error(w, "Failed to update symbol beta", http.StatusInternalServerError)
+			return
+		}
+	}
+	if updateReq.ManualPrice != nil {
+		updatedSymbol, err = s.symbolService.SetManualPrice(symbol, *updateReq.ManualPrice)
+		if err != nil {
+			http.Error(w, "Failed to update symbol manual price flag", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updatedSymbol)
 }
@@ -497,6 +543,12 @@ func (s *Server) symbolAPIHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check if this is an option-entry defaults request
+	if len(pathSegments) > 1 && pathSegments[1] == "defaults" {
+		s.symbolDefaultsHandler(w, r, symbol)
+		return
+	}
+
 	// Handle different HTTP methods for symbol operations
 	switch r.Method {
 	case http.MethodPut:
@@ -508,6 +560,65 @@ func (s *Server) symbolAPIHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// symbolDefaultsHandler handles /api/symbols/{symbol}/defaults: GET returns the stored option-entry
+// defaults with the prefill they produce, PUT replaces them and DELETE reverts to the global defaults
+func (s *Server) symbolDefaultsHandler(w http.ResponseWriter, r *http.Request, symbol string) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req SymbolDefaultsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if req.Contracts != nil && *req.Contracts <= 0 {
+			http.Error(w, "contracts must be positive", http.StatusBadRequest)
+			return
+		}
+		if req.CommissionPerContract != nil && *req.CommissionPerContract < 0 {
+			http.Error(w, "commission_per_contract cannot be negative", http.StatusBadRequest)
+			return
+		}
+		if _, err := s.symbolService.GetBySymbol(symbol); err != nil {
+			http.Error(w, "Symbol not found", http.StatusNotFound)
+			return
+		}
+
+		defaults := &models.SymbolDefaults{
+			Symbol:                symbol,
+			Contracts:             req.Contracts,
+			Strategy:              req.Strategy,
+			CommissionPerContract: req.CommissionPerContract,
+		}
+		if err := s.symbolService.SaveDefaults(defaults); err != nil {
+			log.Printf("[SYMBOL API] Error saving defaults for %s: %v", symbol, err)
+			http.Error(w, "Failed to save symbol defaults", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[SYMBOL API] Saved option-entry defaults for %s", symbol)
+	case http.MethodDelete:
+		if err := s.symbolService.DeleteDefaults(symbol); err != nil {
+			log.Printf("[SYMBOL API] Error deleting defaults for %s: %v", symbol, err)
+			http.Error(w, "Failed to delete symbol defaults", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[SYMBOL API] Cleared option-entry defaults for %s", symbol)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefill, defaults, err := s.optionPrefill(symbol, 0)
+	if err != nil {
+		log.Printf("[SYMBOL API] Error loading defaults for %s: %v", symbol, err)
+		http.Error(w, "Failed to load symbol defaults", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SymbolDefaultsResponse{Symbol: symbol, Defaults: defaults, Prefill: prefill})
+}
+
 // symbolDividendsHandler provides API data for symbol-specific dividends
 func (s *Server) symbolDividendsHandler(w http.ResponseWriter, r *http.Request, symbol string) {
 	dividends, err := s.dividendService.GetBySymbol(symbol)
@@ -520,7 +631,8 @@ func (s *Server) symbolDividendsHandler(w http.ResponseWriter, r *http.Request,
 	json.NewEncoder(w).Encode(dividends)
 }
 
-// symbolUpdatePriceHandler updates a symbol's price using Polygon.io API
+// symbolUpdatePriceHandler updates a symbol's price using Polygon.io API. A symbol priced within
+// MIN_PRICE_UPDATE_INTERVAL_MINUTES is reported as recent and left as is unless ?force=true.
 func (s *Server) symbolUpdatePriceHandler(w http.ResponseWriter, r *http.Request, symbol string) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -533,14 +645,20 @@ func (s *Server) symbolUpdatePriceHandler(w http.ResponseWriter, r *http.Request
 	defer cancel()
 
 	// Update symbol price using Polygon service
-	err := s.polygonService.UpdateSymbolPrice(ctx, symbol)
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	err := s.polygonService.UpdateSymbolPrice(ctx, symbol, force)
+	recent := errors.Is(err, polygon.ErrRecentlyUpdated)
 	
 	response := map[string]interface{}{
-		"success": err == nil,
+		"success": err == nil || recent,
 		"symbol":  symbol,
 	}
 
-	if err != nil {
+	if recent {
+		response["recent"] = true
+		response["message"] = err.Error()
+		log.Printf("[SYMBOL API] Skipped price update for %s: %v", symbol, err)
+	} else if err != nil {
 		response["error"] = err.Error()
 		log.Printf("[SYMBOL API] Failed to update price for %s: %v", symbol, err)
 		w.WriteHeader(http.StatusBadRequest)
@@ -591,3 +709,40 @@ func (s *Server) symbolFetchDividendsHandler(w http.ResponseWriter, r *http.Requ
 		log.Printf("[SYMBOL API] Error encoding response: %v", err)
 	}
 }
+
+// renameSymbolHandler moves all records of a ticker to a new ticker after a corporate action
+func (s *Server) renameSymbolHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SymbolRenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[RENAME_SYMBOL] ERROR: Failed to decode request: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("[RENAME_SYMBOL] Renaming %s -> %s", req.Old, req.New)
+	result, err := s.symbolService.Rename(req.Old, req.New)
+	if err != nil {
+		log.Printf("[RENAME_SYMBOL] ERROR: Failed to rename %s -> %s: %v", req.Old, req.New, err)
+		switch {
+		case err.Error() == "symbol not found":
+			http.Error(w, "Symbol not found", http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid") || err.Error() == "old and new symbol are the same":
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to rename symbol", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.recalculateAdjustedCostBasis(result.NewSymbol)
+
+	log.Printf("[RENAME_SYMBOL] Renamed %s -> %s (merged=%t, options=%d, positions=%d, dividends=%d, duplicates=%d)",
+		result.OldSymbol, result.NewSymbol, result.Merged, result.Options, result.LongPositions, result.Dividends, result.DuplicatesRemoved)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
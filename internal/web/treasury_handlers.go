@@ -160,6 +160,12 @@ func (s *Server) addTreasuryHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid yield", http.StatusBadRequest)
 		return
 	}
+	// The form takes percent, so only the plausibility check applies
+	if _, err := models.NormalizeTreasuryYield(yield, true, models.TreasuryYieldScalePercent); err != nil {
+		log.Printf("[ADD TREASURY] ERROR: Invalid yield '%s': %v", yieldStr, err)
+		http.Error(w, "Invalid yield: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	buyPrice, err := strconv.ParseFloat(buyPriceStr, 64)
 	if err != nil {
@@ -213,6 +219,12 @@ func (s *Server) treasuryAPIHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// /api/treasuries/{cuspid}/coupons[/{id}] manages coupon payments
+	if base, rest, found := strings.Cut(cuspid, "/coupons"); found {
+		s.treasuryCouponsHandler(w, r, base, strings.TrimPrefix(rest, "/"))
+		return
+	}
+
 	log.Printf("[TREASURY API] Extracted CUSPID: '%s' from path: %s", cuspid, r.URL.Path)
 
 	switch r.Method {
@@ -287,6 +299,20 @@ func (s *Server) updateTreasuryHandler(w http.ResponseWriter, r *http.Request, c
 	}
 
 	log.Printf("[UPDATE TREASURY] Parsed dates for CUSPID %s: Purchased=%v, Maturity=%v", cuspid, purchased, maturity)
+
+	// Yields and coupon rates arrive in percent, so only the plausibility check applies
+	if _, err := models.NormalizeTreasuryYield(updateReq.Yield, true, models.TreasuryYieldScalePercent); err != nil {
+		log.Printf("[UPDATE TREASURY] ERROR: Invalid yield for CUSPID %s: %v", cuspid, err)
+		http.Error(w, "Invalid yield: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if updateReq.CouponRate != nil {
+		if _, err := models.NormalizeTreasuryYield(*updateReq.CouponRate, true, models.TreasuryYieldScalePercent); err != nil {
+			log.Printf("[UPDATE TREASURY] ERROR: Invalid coupon rate for CUSPID %s: %v", cuspid, err)
+			http.Error(w, "Invalid coupon rate: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 	log.Printf("[UPDATE TREASURY] Calling UpdateFull service for CUSPID: %s", cuspid)
 
 	// Update treasury using UpdateFull method
@@ -311,6 +337,23 @@ func (s *Server) updateTreasuryHandler(w http.ResponseWriter, r *http.Request, c
 		return
 	}
 
+	if updateReq.CouponRate != nil || updateReq.CouponFrequency != nil {
+		couponRate, couponFrequency := updatedTreasury.CouponRate, updatedTreasury.CouponFrequency
+		if updateReq.CouponRate != nil {
+			couponRate = *updateReq.CouponRate
+		}
+		if updateReq.CouponFrequency != nil {
+			couponFrequency = *updateReq.CouponFrequency
+		}
+		if err := s.treasuryService.SetCoupon(cuspid, couponRate, couponFrequency); err != nil {
+			log.Printf("[UPDATE TREASURY] ERROR: Failed to set coupon terms for CUSPID %s: %v", cuspid, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updatedTreasury.CouponRate = couponRate
+		updatedTreasury.CouponFrequency = couponFrequency
+	}
+
 	log.Printf("[UPDATE TREASURY] Successfully updated treasury for CUSPID: %s", cuspid)
 	log.Printf("[UPDATE TREASURY] Updated treasury data: Amount=%.2f, Yield=%.3f, BuyPrice=%.2f",
 		updatedTreasury.Amount, updatedTreasury.Yield, updatedTreasury.BuyPrice)
@@ -346,4 +389,65 @@ func (s *Server) deleteTreasuryHandler(w http.ResponseWriter, r *http.Request, c
 	w.Write([]byte(`{"success": true}`))
 
 	log.Printf("[DELETE TREASURY] Successfully sent response for CUSPID: %s", cuspid)
-}
\ No newline at end of file
+}
+
+// treasuryCouponsHandler lists (GET) or records (POST) coupon payments for a treasury, and
+// deletes one (DELETE /api/treasuries/{cuspid}/coupons/{id})
+func (s *Server) treasuryCouponsHandler(w http.ResponseWriter, r *http.Request, cuspid, couponID string) {
+	log.Printf("[TREASURY COUPONS] %s request for CUSPID: %s", r.Method, cuspid)
+
+	if _, err := s.treasuryService.GetByCUSPID(cuspid); err != nil {
+		http.Error(w, "Treasury not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		coupons, err := s.treasuryCouponService.GetByCUSPID(cuspid)
+		if err != nil {
+			log.Printf("[TREASURY COUPONS] ERROR: Failed to get coupons for CUSPID %s: %v", cuspid, err)
+			http.Error(w, "Failed to get coupons", http.StatusInternalServerError)
+			return
+		}
+		if coupons == nil {
+			coupons = []*models.TreasuryCoupon{}
+		}
+		json.NewEncoder(w).Encode(coupons)
+	case http.MethodPost:
+		var req TreasuryCouponRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		received, err := time.Parse("2006-01-02", req.Received)
+		if err != nil {
+			http.Error(w, "Invalid received date format", http.StatusBadRequest)
+			return
+		}
+		coupon, err := s.treasuryCouponService.Create(cuspid, received, req.Amount)
+		if err != nil {
+			log.Printf("[TREASURY COUPONS] ERROR: Failed to record coupon for CUSPID %s: %v", cuspid, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(coupon)
+	case http.MethodDelete:
+		id, err := strconv.Atoi(couponID)
+		if err != nil {
+			http.Error(w, "Invalid coupon ID", http.StatusBadRequest)
+			return
+		}
+		if err := s.treasuryCouponService.DeleteByID(id); err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				http.Error(w, "Coupon not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to delete coupon", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"success": true}`))
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
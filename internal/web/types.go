@@ -1,6 +1,7 @@
 package web
 
 import (
+	"encoding/json"
 	"html/template"
 	"stonks/internal/models"
 	"time"
@@ -13,11 +14,69 @@ type PremiumData struct {
 	CallPremium float64 `json:"callPremium"`
 }
 
+// NullableFloat is a request field that tells an omitted value apart from an explicit null,
+// so an update can clear a stored value
+type NullableFloat struct {
+	Set   bool     // the field was present in the request, possibly as null
+	Value *float64 // nil when the field was null
+}
+
+// UnmarshalJSON records that the field was present and decodes its value
+func (n *NullableFloat) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = nil
+		return nil
+	}
+	var value float64
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	n.Value = &value
+	return nil
+}
+
 type SymbolUpdateRequest struct {
-	Price          *float64 `json:"price,omitempty"`
-	Dividend       *float64 `json:"dividend,omitempty"`
-	ExDividendDate *string  `json:"ex_dividend_date,omitempty"`
-	PERatio        *float64 `json:"pe_ratio,omitempty"`
+	Price          *float64      `json:"price,omitempty"`
+	Dividend       *float64      `json:"dividend,omitempty"`
+	ExDividendDate *string       `json:"ex_dividend_date,omitempty"`
+	PERatio        *float64      `json:"pe_ratio,omitempty"`
+	Beta           NullableFloat `json:"beta"`                   // null clears the stored beta
+	ManualPrice    *bool         `json:"manual_price,omitempty"` // true stops automatic price updates for the symbol
+}
+
+// SymbolDefaultsRequest replaces a symbol's option-entry defaults; an omitted field falls back
+// to the global default
+type SymbolDefaultsRequest struct {
+	Contracts             *int     `json:"contracts,omitempty"`
+	Strategy              *string  `json:"strategy,omitempty"`
+	CommissionPerContract *float64 `json:"commission_per_contract,omitempty"`
+}
+
+// SymbolDefaultsResponse is a symbol's stored option-entry defaults and the prefill they produce
+type SymbolDefaultsResponse struct {
+	Symbol   string                 `json:"symbol"`
+	Defaults *models.SymbolDefaults `json:"defaults"` // null when none are stored
+	Prefill  OptionPrefill          `json:"prefill"`
+}
+
+// OptionPrefill is the effective set of values for entering a new option on a symbol. Each
+// *Source field is "symbol", "broker_profile" or "global".
+type OptionPrefill struct {
+	Symbol                string  `json:"symbol"`
+	Contracts             int     `json:"contracts"`
+	ContractsSource       string  `json:"contracts_source"`
+	Strategy              string  `json:"strategy,omitempty"` // blank lets the strategy be inferred on save
+	StrategySource        string  `json:"strategy_source"`
+	CommissionPerContract float64 `json:"commission_per_contract"`
+	Commission            float64 `json:"commission"` // opening commission for Contracts
+	CommissionSource      string  `json:"commission_source"`
+}
+
+// SymbolRenameRequest moves all records from one ticker to another (e.g. FB -> META)
+type SymbolRenameRequest struct {
+	Old string `json:"old"`
+	New string `json:"new"`
 }
 
 type TreasuryUpdateRequest struct {
@@ -28,12 +87,23 @@ type TreasuryUpdateRequest struct {
 	BuyPrice     float64  `json:"buyPrice"`
 	CurrentValue *float64 `json:"currentValue,omitempty"`
 	ExitPrice    *float64 `json:"exitPrice,omitempty"`
+	// Coupon terms are left unchanged when omitted
+	CouponRate      *float64 `json:"couponRate,omitempty"`
+	CouponFrequency *int     `json:"couponFrequency,omitempty"`
+}
+
+// TreasuryCouponRequest records a coupon payment received on a treasury
+type TreasuryCouponRequest struct {
+	Received string  `json:"received"`
+	Amount   float64 `json:"amount"`
 }
 
 type ImportResponse struct {
 	Success       bool   `json:"success"`
 	ImportedCount int    `json:"imported_count"`
+	UpdatedCount  int    `json:"updated_count,omitempty"`
 	SkippedCount  int    `json:"skipped_count"`
+	AssignedCount int    `json:"assigned_count,omitempty"`
 	Error         string `json:"error,omitempty"`
 	Details       string `json:"details,omitempty"`
 }
@@ -49,6 +119,8 @@ type CSVOptionRecord struct {
 	Contracts  string
 	ExitPrice  string
 	Commission string
+	Fees       string // exchange and regulatory fees; blank or absent means 0
+	Contract   string // OCC contract symbol; fills symbol, type, strike and expiration when they are blank
 }
 
 type CSVStockRecord struct {
@@ -75,6 +147,9 @@ type CSVTreasuryRecord struct {
 	BuyPrice     string
 	CurrentValue string
 	ExitPrice    string
+	// Optional coupon terms for notes and bonds
+	CouponRate      string
+	CouponFrequency string
 }
 
 // DashboardData holds data for the dashboard template
@@ -211,6 +286,8 @@ type OptionsData struct {
 	OptionsSummary []*models.OptionSummary    `json:"options_summary"`
 	OpenPositions  []*models.OpenPositionData `json:"open_positions"`
 	SummaryTotals  *models.OptionSummary      `json:"summary_totals"`
+	SummarySort    string                     `json:"summary_sort"`
+	ReturnsBasis   string                     `json:"returns_basis"` // net or gross, for OptionsSummary profit
 	CurrentDB      string                     `json:"currentDB"`
 	ActivePage     string                     `json:"activePage"`
 }
@@ -266,32 +343,252 @@ type SymbolData struct {
 	DividendsList     []*models.Dividend     `json:"dividendsList"`
 	DividendsTotal    float64                `json:"dividendsTotal"`
 	OptionsList       []*models.Option       `json:"optionsList"`
+	CanceledOptions   []*models.Option       `json:"canceledOptions"` // kept for the record; not in any totals
 	LongPositionsList []*models.LongPosition `json:"longPositionsList"`
 	MonthlyResults    []SymbolMonthlyResult  `json:"monthlyResults"`
+	ReturnsBasis      string                 `json:"returnsBasis"` // net or gross, for OptionsGains and MonthlyResults
 	CurrentDB         string                 `json:"currentDB"`
 	ActivePage        string                 `json:"activePage"`
 }
 
+// OptionWithPerformance is an option with its expiration class and profit/ROI/AROI on the requested
+// commission basis, plus an optional P/L attribution breakdown
+type OptionWithPerformance struct {
+	*models.Option
+	ExpirationClass    string                    `json:"expiration_class"`
+	Outcome            string                    `json:"outcome"`
+	ContractMultiplier int                       `json:"contract_multiplier"`
+	Exposure           float64                   `json:"exposure"`    // strike x contracts x multiplier
+	NetPremium         float64                   `json:"net_premium"` // premium less buyback, before commission
+	DTE                int                       `json:"dte"`
+	DaysRemaining      int                       `json:"days_remaining"`
+	PercentOfProfit    float64                   `json:"percent_of_profit"`
+	PercentOfTime      float64                   `json:"percent_of_time"`
+	PercentOTM         *float64                  `json:"percent_otm,omitempty"` // only when the underlying price is known
+	Performance        *models.OptionPerformance `json:"performance,omitempty"`
+	Attribution        *models.OptionAttribution `json:"attribution,omitempty"`
+}
+
+// OptionLadderRung aggregates open options of one type at a single strike
+type OptionLadderRung struct {
+	Strike       float64          `json:"strike"`
+	Type         string           `json:"type"`
+	Contracts    int              `json:"contracts"`
+	TotalPremium float64          `json:"total_premium"`
+	Moneyness    string           `json:"moneyness"` // ITM, OTM, ATM, or unknown when no price is set
+	Options      []*models.Option `json:"options"`
+}
+
+// OptionLadderResponse is the strike ladder for a symbol's open options
+type OptionLadderResponse struct {
+	Symbol       string             `json:"symbol"`
+	CurrentPrice float64            `json:"current_price"`
+	Rungs        []OptionLadderRung `json:"rungs"`
+}
+
+// BlendedBreakevenLeg is one open put contributing to a symbol's blended breakeven
+type BlendedBreakevenLeg struct {
+	ID         int     `json:"id"`
+	Strike     float64 `json:"strike"`
+	Expiration string  `json:"expiration"`
+	Contracts  int     `json:"contracts"`
+	Premium    float64 `json:"premium"`
+	BreakEven  float64 `json:"break_even"`
+}
+
+// BlendedBreakevenResponse is the aggregate acquisition cost if every open put on a symbol is assigned
+type BlendedBreakevenResponse struct {
+	Symbol                string                `json:"symbol"`
+	CurrentPrice          float64               `json:"current_price"`
+	Contracts             int                   `json:"contracts"`
+	Shares                int                   `json:"shares"`
+	AssignmentCost        float64               `json:"assignment_cost"`         // sum of strike × shares
+	TotalPremium          float64               `json:"total_premium"`           // premium collected less commissions
+	WeightedAverageStrike float64               `json:"weighted_average_strike"` // share-weighted
+	BlendedBreakeven      float64               `json:"blended_breakeven"`       // (assignment cost - premium) / shares
+	Legs                  []BlendedBreakevenLeg `json:"legs"`
+}
+
+// PremiumYieldEntry is one open put's premium as a percent of its cash-secured collateral
+type PremiumYieldEntry struct {
+	ID                     int     `json:"id"`
+	Symbol                 string  `json:"symbol"`
+	Strike                 float64 `json:"strike"`
+	Expiration             string  `json:"expiration"`
+	Contracts              int     `json:"contracts"`
+	DTE                    int     `json:"dte"`            // opened to expiration
+	DaysRemaining          int     `json:"days_remaining"` // today to expiration
+	Collateral             float64 `json:"collateral"`     // strike x contracts x 100
+	PremiumCollected       float64 `json:"premium_collected"`
+	NetPremium             float64 `json:"net_premium"`              // premium collected less commission
+	PremiumYield           float64 `json:"premium_yield"`            // percent, premium / collateral
+	NetPremiumYield        float64 `json:"net_premium_yield"`        // percent
+	AnnualizedPremiumYield float64 `json:"annualized_premium_yield"` // percent, premium yield x days per year / DTE
+	AnnualizedNetYield     float64 `json:"annualized_net_yield"`     // percent
+}
+
+// PremiumYieldResponse ranks open puts by annualized premium yield, highest first
+type PremiumYieldResponse struct {
+	Annualization           string              `json:"annualization"` // calendar or trading
+	DaysPerYear             float64             `json:"days_per_year"`
+	TotalCollateral         float64             `json:"total_collateral"`
+	TotalPremium            float64             `json:"total_premium"`
+	WeightedAnnualizedYield float64             `json:"weighted_annualized_yield"` // collateral-weighted
+	Puts                    []PremiumYieldEntry `json:"puts"`
+}
+
+// CoveredCallSimLot is the part of a lot a simulated covered call would be written against
+type CoveredCallSimLot struct {
+	PositionID        int     `json:"position_id"`
+	Opened            string  `json:"opened"`
+	SharesCovered     int     `json:"shares_covered"`
+	CostBasisPerShare float64 `json:"cost_basis_per_share"` // adjusted, or buy price when unadjusted
+}
+
+// CoveredCallExpiresScenario is a simulated covered call that expires worthless: the shares are
+// kept and only the premium is earned
+type CoveredCallExpiresScenario struct {
+	Income           float64 `json:"income"`
+	ReturnPercent    float64 `json:"return_percent"` // on the covered shares' cost basis
+	AnnualizedReturn float64 `json:"annualized_return"`
+	BreakEven        float64 `json:"break_even"` // cost basis per share less the premium
+}
+
+// CoveredCallCalledScenario is a simulated covered call assigned at the strike. UpsideCapped is the
+// gain above the strike given up at the current price.
+type CoveredCallCalledScenario struct {
+	Income             float64 `json:"income"`
+	CapitalGain        float64 `json:"capital_gain"` // strike less cost basis, times shares
+	TotalReturn        float64 `json:"total_return"`
+	ReturnPercent      float64 `json:"return_percent"`
+	AnnualizedReturn   float64 `json:"annualized_return"`
+	EffectiveSalePrice float64 `json:"effective_sale_price"` // strike plus premium
+	UpsideCapped       float64 `json:"upside_capped"`
+}
+
+// CoveredCallSimResponse is the outcome of writing a hypothetical covered call against held shares
+// not already covered by open calls
+type CoveredCallSimResponse struct {
+	Symbol            string                     `json:"symbol"`
+	Strike            float64                    `json:"strike"`
+	Premium           float64                    `json:"premium"` // per share
+	Expiration        string                     `json:"expiration"`
+	Contracts         int                        `json:"contracts"`
+	Shares            int                        `json:"shares"`
+	DTE               int                        `json:"dte"` // from today
+	Annualization     string                     `json:"annualization"`
+	DaysPerYear       float64                    `json:"days_per_year"`
+	CurrentPrice      float64                    `json:"current_price"`
+	HeldShares        int                        `json:"held_shares"`
+	CommittedShares   int                        `json:"committed_shares"` // backing open calls
+	AvailableShares   int                        `json:"available_shares"`
+	CostBasisPerShare float64                    `json:"cost_basis_per_share"`
+	CostBasisTotal    float64                    `json:"cost_basis_total"`
+	Lots              []CoveredCallSimLot        `json:"lots"`
+	IfExpires         CoveredCallExpiresScenario `json:"if_expires"`
+	IfCalled          CoveredCallCalledScenario  `json:"if_called"`
+}
+
+// TheoreticalValueResponse is the Black-Scholes value of a hypothetical contract with the inputs
+// used. IVSource is "request", "snapshot" (the contract's own Polygon snapshot) or "chain" (the
+// nearest listed contract of the same type).
+type TheoreticalValueResponse struct {
+	models.TheoreticalValue
+	Symbol          string  `json:"symbol"`
+	Type            string  `json:"type"`
+	Strike          float64 `json:"strike"`
+	Expiration      string  `json:"expiration"`
+	Underlying      float64 `json:"underlying"`
+	ImpliedVol      float64 `json:"implied_volatility"` // annual decimal
+	IVSource        string  `json:"iv_source"`
+	RiskFreeRate    float64 `json:"risk_free_rate"` // annual percent
+	YearsToExpiry   float64 `json:"years_to_expiry"`
+	Expired         bool    `json:"expired"`          // no time left; priced at intrinsic value
+	ContractPremium float64 `json:"contract_premium"` // price x 100
+}
+
+// UnderlyingBackfillResponse reports a pass of the underlying price backfill
+type UnderlyingBackfillResponse struct {
+	Updated   int `json:"updated"`
+	Remaining int `json:"remaining"` // options still missing a price at open or close
+	Requests  int `json:"requests"`  // Polygon grouped-daily requests made
+}
+
+// ExpiringOptionAction is an open option expiring in the requested week with a suggested action
+type ExpiringOptionAction struct {
+	Option          *models.Option `json:"option"`
+	CurrentPrice    float64        `json:"current_price"`
+	Moneyness       string         `json:"moneyness"`
+	DistancePercent float64        `json:"distance_percent"`        // |price - strike| / price
+	Action          string         `json:"action"`                  // let_expire, roll, prepare_for_assignment, or review
+	BuybackCost     *float64       `json:"buyback_cost,omitempty"`  // cost to buy the option back at its current price, when known
+	RollCredit      *float64       `json:"roll_credit,omitempty"`   // best net credit to roll out for a roll action, when the chain offers one
+	RollContract    string         `json:"roll_contract,omitempty"` // contract behind RollCredit
+}
+
+// ExpiringWeekResponse lists open options expiring in one ISO week
+type ExpiringWeekResponse struct {
+	Week                 string                 `json:"week"` // e.g. 2025-W07
+	Start                string                 `json:"start"`
+	End                  string                 `json:"end"`
+	MoneynessBandPercent float64                `json:"moneyness_band_percent"`
+	Options              []ExpiringOptionAction `json:"options"`
+}
+
 type OptionRequest struct {
-	ID         *int     `json:"id,omitempty"`
-	Symbol     string   `json:"symbol"`
-	Type       string   `json:"type"`
-	Strike     float64  `json:"strike"`
-	Expiration string   `json:"expiration"`
-	Premium    float64  `json:"premium"`
-	Contracts  int      `json:"contracts"`
-	Opened     string   `json:"opened"`
-	Closed     *string  `json:"closed,omitempty"`
-	ExitPrice  *float64 `json:"exit_price,omitempty"`
-	Commission float64  `json:"commission,omitempty"`
+	ID                 *int     `json:"id,omitempty"`
+	Symbol             string   `json:"symbol"`
+	Type               string   `json:"type"`
+	Strike             float64  `json:"strike"`
+	Expiration         string   `json:"expiration"`
+	Premium            float64  `json:"premium"`
+	Contracts          int      `json:"contracts"`
+	Opened             string   `json:"opened"`
+	Closed             *string  `json:"closed,omitempty"`
+	ExitPrice          *float64 `json:"exit_price,omitempty"`
+	Commission         *float64 `json:"commission,omitempty"` // omitted on create uses the BROKER_PROFILE schedule
+	Fees               *float64 `json:"fees,omitempty"`       // exchange and regulatory fees; omitted on update keeps them
+	Account            *string  `json:"account,omitempty"`
+	Assigned           *bool    `json:"assigned,omitempty"` // flags the close as an assignment rather than a buyback or expiry
+	AssignedPositionID *int     `json:"assigned_position_id,omitempty"`
+	Strategy           *string  `json:"strategy,omitempty"`  // e.g. CSP, CC, wheel, spread; blank clears it
+	Currency           *string  `json:"currency,omitempty"`  // trading currency; omitted keeps USD
+	FXRate             *float64 `json:"fx_rate,omitempty"`   // base currency per unit of Currency at trade time; required when Currency is not USD
+	Status             *string  `json:"status,omitempty"`    // "active" or "canceled"; canceled keeps the row out of metrics and profit
+	MatchAll           bool     `json:"match_all,omitempty"` // compound-key delete applies to every matching option instead of failing
 }
 
+// OptionAssignRequest records a put assignment. Assigned defaults to the option's expiration and
+// Mode ("strike" or "net_premium") defaults to the ASSIGNMENT_BUY_PRICE_MODE setting.
+type OptionAssignRequest struct {
+	OptionID int    `json:"option_id"`
+	Assigned string `json:"assigned,omitempty"`
+	Mode     string `json:"mode,omitempty"`
+}
+
+// StockSplitRequest applies a SplitTo-for-SplitFrom split to a symbol's open lots. Rounding defaults
+// to the SPLIT_ROUNDING setting; CashInLieuPrice is the post-split price paid per whole share and is
+// required when a lot is left with a fractional share.
+type StockSplitRequest struct {
+	Symbol          string  `json:"symbol"`
+	Effective       string  `json:"effective"`
+	SplitTo         int     `json:"split_to"`
+	SplitFrom       int     `json:"split_from"`
+	Rounding        string  `json:"rounding,omitempty"`
+	CashInLieuPrice float64 `json:"cash_in_lieu_price,omitempty"`
+}
+
+// DividendRequest creates or deletes a dividend. Without ReinvestedAmount or CashAmount the whole
+// amount is cash; when only one is given the other is the rest of the amount.
 type DividendRequest struct {
-	ID           *int    `json:"id,omitempty"`
-	Symbol       string  `json:"symbol"`
-	Amount       float64 `json:"amount"`
-	DateReceived string  `json:"date_received"`
-	Received     string  `json:"received"`
+	ID               *int     `json:"id,omitempty"`
+	Symbol           string   `json:"symbol"`
+	Amount           float64  `json:"amount"`
+	DateReceived     string   `json:"date_received"`
+	Received         string   `json:"received"`
+	ReinvestedAmount *float64 `json:"reinvested_amount,omitempty"`
+	CashAmount       *float64 `json:"cash_amount,omitempty"`
+	ReinvestPrice    float64  `json:"reinvest_price,omitempty"` // price per reinvested share
 }
 
 type LongPositionRequest struct {
@@ -303,19 +600,561 @@ type LongPositionRequest struct {
 	Opened    string   `json:"opened"`
 	Closed    *string  `json:"closed,omitempty"`
 	ExitPrice *float64 `json:"exit_price,omitempty"`
+	Account   *string  `json:"account,omitempty"`
+}
+
+type LongPositionExitRequest struct {
+	ID         *int    `json:"id,omitempty"` // For deletes
+	PositionID int     `json:"position_id"`
+	Exited     string  `json:"exited"`
+	Shares     int     `json:"shares"`
+	Price      float64 `json:"price"`
+}
+
+// DataQualityCategory groups options that fail one data-quality rule
+type DataQualityCategory struct {
+	Category    string           `json:"category"`
+	Description string           `json:"description"`
+	Count       int              `json:"count"`
+	Options     []*models.Option `json:"options"`
+}
+
+// DataQualityResponse is the read-only report returned by /api/maintenance/data-quality
+type DataQualityResponse struct {
+	OptionsChecked    int                   `json:"options_checked"`
+	OptionsWithIssues int                   `json:"options_with_issues"`
+	StaleAfterDays    int                   `json:"stale_after_days"`
+	Categories        []DataQualityCategory `json:"categories"`
+}
+
+// SymbolMetricContribution is one symbol's share of a single snapshot metric
+type SymbolMetricContribution struct {
+	Type    models.MetricType `json:"type"`
+	Value   float64           `json:"value"`
+	Total   float64           `json:"total"`
+	Percent float64           `json:"percent"` // share of the portfolio total, 0 when the total is 0
+}
+
+// SymbolContributionResponse breaks down one symbol's contribution to the metrics on a date
+type SymbolContributionResponse struct {
+	Symbol        string                     `json:"symbol"`
+	Date          string                     `json:"date"`
+	Contributions []SymbolMetricContribution `json:"contributions"`
+}
+
+// EffectiveConfigEntry is one configuration value as resolved at request time
+type EffectiveConfigEntry struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Source  string `json:"source"`
+	Default string `json:"default"`
+	Env     string `json:"env,omitempty"`
+	Secret  bool   `json:"secret"`
+}
+
+// EffectiveConfigResponse lists the effective configuration with value sources
+type EffectiveConfigResponse struct {
+	Settings []EffectiveConfigEntry `json:"settings"`
+}
+
+// RollCandidate is one chain contract considered as a roll target
+type RollCandidate struct {
+	Contract          string  `json:"contract"`
+	Strike            float64 `json:"strike"`
+	Delta             float64 `json:"delta"`
+	Bid               float64 `json:"bid"`
+	Ask               float64 `json:"ask"`
+	ImpliedVolatility float64 `json:"implied_volatility"`
+	OpenInterest      float64 `json:"open_interest"`
+	NetCredit         float64 `json:"net_credit"`
+	CreditForRisk     float64 `json:"credit_for_risk_percent"`
+}
+
+// RollExpirationGroup holds the ranked roll candidates for one expiration
+type RollExpirationGroup struct {
+	Expiration    string          `json:"expiration"`
+	DTE           int             `json:"dte"`
+	BestNetCredit float64         `json:"best_net_credit"`
+	Candidates    []RollCandidate `json:"candidates"`
+}
+
+// RollSuggestionsResponse lists roll candidates for an open option grouped by expiration
+type RollSuggestionsResponse struct {
+	OptionID       int                   `json:"option_id"`
+	Symbol         string                `json:"symbol"`
+	Type           string                `json:"type"`
+	CloseCost      float64               `json:"close_cost"`
+	TargetDTE      int                   `json:"target_dte"`
+	DTEWindow      int                   `json:"dte_window"`
+	TargetDelta    float64               `json:"target_delta"`
+	DeltaTolerance float64               `json:"delta_tolerance"`
+	Expirations    []RollExpirationGroup `json:"expirations"`
+}
+
+// RollableOpportunity is a later contract the option can be rolled to for a net credit
+type RollableOpportunity struct {
+	Contract   string  `json:"contract"`
+	Expiration string  `json:"expiration"`
+	DTE        int     `json:"dte"`
+	Strike     float64 `json:"strike"`
+	Bid        float64 `json:"bid"`
+	Ask        float64 `json:"ask"`
+	Delta      float64 `json:"delta"`
+	NetCredit  float64 `json:"net_credit"` // (new bid - close cost) x 100 x contracts
+}
+
+// RollableOption is an expiring open option with its credit roll opportunities, best first
+type RollableOption struct {
+	OptionID        int                   `json:"option_id"`
+	Symbol          string                `json:"symbol"`
+	Type            string                `json:"type"`
+	Strike          float64               `json:"strike"`
+	Expiration      string                `json:"expiration"`
+	DaysRemaining   int                   `json:"days_remaining"`
+	Contracts       int                   `json:"contracts"`
+	CloseCost       float64               `json:"close_cost"`        // per share to buy the option back
+	CloseCostSource string                `json:"close_cost_source"` // quote or stored
+	BestNetCredit   float64               `json:"best_net_credit"`
+	Opportunities   []RollableOpportunity `json:"opportunities"`
+}
+
+// RollableSkip is an expiring option that could not be checked for a credit roll
+type RollableSkip struct {
+	OptionID int    `json:"option_id"`
+	Symbol   string `json:"symbol"`
+	Reason   string `json:"reason"`
+}
+
+// RollableResponse lists expiring options that can be rolled for a net credit, largest credit first
+type RollableResponse struct {
+	Days    int              `json:"days"`
+	MaxDTE  int              `json:"max_dte"`
+	Options []RollableOption `json:"options"`
+	Skipped []RollableSkip   `json:"skipped"`
+}
+
+// StrategyPerformanceResponse reports option performance aggregated per strategy label
+type StrategyPerformanceResponse struct {
+	Basis         string                       `json:"basis"`
+	Annualization string                       `json:"annualization"` // calendar or trading
+	DaysPerYear   float64                      `json:"days_per_year"`
+	Account       string                       `json:"account,omitempty"`
+	Strategies    []models.StrategyPerformance `json:"strategies"`
+}
+
+// CloseTimingResponse buckets closed puts and calls by percent of time held
+type CloseTimingResponse struct {
+	Basis      string                     `json:"basis"` // net or gross TotalProfit
+	Account    string                     `json:"account,omitempty"`
+	BucketSize float64                    `json:"bucket_size"`
+	Puts       []models.CloseTimingBucket `json:"puts"`
+	Calls      []models.CloseTimingBucket `json:"calls"`
+}
+
+// OptionStreaksResponse reports win/loss streaks over closed options; ByType is keyed by "Put" and
+// "Call" when by_type is requested
+type OptionStreaksResponse struct {
+	Basis   string `json:"basis"` // wins and losses judged net or gross of commission and fees
+	Account string `json:"account,omitempty"`
+	models.OptionStreaks
+	ByType map[string]models.OptionStreaks `json:"by_type,omitempty"`
+}
+
+// RollLinkRequest lists the rolls to record. All records every detected, unlinked roll instead and
+// must be set explicitly; a request with neither is rejected.
+type RollLinkRequest struct {
+	Links []models.RollLink `json:"links"`
+	All   bool              `json:"all"`
+}
+
+// DetectRollsResponse reports probable rolls and the chains they form
+type DetectRollsResponse struct {
+	Pairs  []models.RollPair `json:"pairs"`
+	Chains [][]int           `json:"chains"`
+	Linked int               `json:"linked"`
+}
+
+// PendingTradesResponse lists trades entered ahead of their opened date
+type PendingTradesResponse struct {
+	Options       []*models.Option       `json:"options"`
+	LongPositions []*models.LongPosition `json:"long_positions"`
+}
+
+// SymbolDeltaExposure is one symbol's delta-weighted directional exposure from shares and options
+type SymbolDeltaExposure struct {
+	Symbol            string  `json:"symbol"`
+	UnderlyingPrice   float64 `json:"underlying_price"`
+	Shares            int     `json:"shares"`
+	OptionDeltaShares float64 `json:"option_delta_shares"` // share-equivalent delta of the open options
+	ShareExposure     float64 `json:"share_exposure"`
+	OptionExposure    float64 `json:"option_exposure"`
+	DeltaExposure     float64 `json:"delta_exposure"`
+	OptionsCovered    int     `json:"options_covered"`
+	OptionsMissing    int     `json:"options_missing"`
+}
+
+// DeltaExposureResponse is the per-symbol delta-weighted exposure across long shares and open options
+type DeltaExposureResponse struct {
+	Symbols            []SymbolDeltaExposure `json:"symbols"`
+	TotalDeltaExposure float64               `json:"total_delta_exposure"`
+	Coverage           float64               `json:"coverage"` // percent of open option notional with a delta
+	MissingGreeks      []string              `json:"missing_greeks"`
+	Warnings           []string              `json:"warnings,omitempty"`
+	Note               string                `json:"note"`
+}
+
+// SymbolSharesEquivalent is a symbol's exposure in shares if every open option assigns. With call
+// coverage tracking on, UncoveredCallShares is measured against the lots each call is linked to
+// (LinkedCallShares) instead of all held shares.
+type SymbolSharesEquivalent struct {
+	Symbol              string `json:"symbol"`
+	HeldShares          int    `json:"held_shares"`
+	PutSharesAtRisk     int    `json:"put_shares_at_risk"`
+	CallSharesCovered   int    `json:"call_shares_covered"`
+	LinkedCallShares    *int   `json:"linked_call_shares,omitempty"` // open lot shares open calls are written against
+	NetShares           int    `json:"net_shares"`                   // held + at risk - covered
+	UncoveredCallShares int    `json:"uncovered_call_shares"`        // call shares beyond held (or linked) and put-assigned shares
+}
+
+// SharesEquivalentResponse lists share-equivalent exposure per symbol
+type SharesEquivalentResponse struct {
+	Account          string                   `json:"account,omitempty"`
+	CoverageTracking bool                     `json:"coverage_tracking"`
+	Symbols          []SymbolSharesEquivalent `json:"symbols"`
+}
+
+// OptionCoverageLot is one long lot a covered call is written against
+type OptionCoverageLot struct {
+	PositionID int     `json:"position_id"`
+	Opened     string  `json:"opened"`
+	Closed     *string `json:"closed,omitempty"`
+	LotShares  int     `json:"lot_shares"`
+	BuyPrice   float64 `json:"buy_price"`
+	Shares     int     `json:"shares"` // shares of the lot this call covers
+}
+
+// OptionCoverageResponse lists the lots a call covers, as allocated FIFO for adjusted cost basis
+type OptionCoverageResponse struct {
+	OptionID         int                 `json:"option_id"`
+	Symbol           string              `json:"symbol"`
+	CoverageTracking bool                `json:"coverage_tracking"`
+	CallShares       int                 `json:"call_shares"`
+	CoveredShares    int                 `json:"covered_shares"`
+	UncoveredShares  int                 `json:"uncovered_shares"`
+	Lots             []OptionCoverageLot `json:"lots"`
+	Note             string              `json:"note,omitempty"`
+}
+
+// OptionTimelineEvent is one stage of an option roll chain. Event is opened, rolled_in, mark (a leg
+// still open, valued at its current price), rolled_out, or the closing outcome: assigned, expired
+// or buyback. Price is the premium at an opening, the exit price at a close and the option's
+// current price at the mark.
+type OptionTimelineEvent struct {
+	Date                 string   `json:"date"`
+	Event                string   `json:"event"`
+	OptionID             int      `json:"option_id"`
+	Strike               float64  `json:"strike"`
+	Expiration           string   `json:"expiration"`
+	Contracts            int      `json:"contracts"`
+	Price                *float64 `json:"price,omitempty"`
+	Underlying           *float64 `json:"underlying,omitempty"`
+	Credit               float64  `json:"credit,omitempty"` // premium collected at an opening
+	DaysRemaining        int      `json:"days_remaining,omitempty"`
+	RolledFromID         *int     `json:"rolled_from_id,omitempty"`
+	RolledToID           *int     `json:"rolled_to_id,omitempty"`
+	ProfitLoss           float64  `json:"profit_loss"`            // this leg: realized at a close, unrealized at the mark
+	CumulativeProfitLoss float64  `json:"cumulative_profit_loss"` // the whole chain up to this stage
+}
+
+// OptionTimelineResponse is the chronological event list of an option and the roll chain it belongs
+// to. Outcome is the requested option's; ChainOutcome is that of the chain's last leg.
+type OptionTimelineResponse struct {
+	OptionID             int                   `json:"option_id"`
+	Symbol               string                `json:"symbol"`
+	Type                 string                `json:"type"`
+	Outcome              string                `json:"outcome"`
+	ChainOutcome         string                `json:"chain_outcome"`
+	Chain                []int                 `json:"chain"` // option IDs, first leg first
+	RealizedProfitLoss   float64               `json:"realized_profit_loss"`
+	UnrealizedProfitLoss *float64              `json:"unrealized_profit_loss,omitempty"` // open leg with a known mark
+	TotalProfitLoss      float64               `json:"total_profit_loss"`
+	Events               []OptionTimelineEvent `json:"events"`
+}
+
+// DedupeOptionsResponse reports exact-duplicate option groups; Applied is true when they were collapsed
+type DedupeOptionsResponse struct {
+	OptionsChecked int                           `json:"options_checked"`
+	Applied        bool                          `json:"applied"`
+	Removed        int                           `json:"removed"`
+	Groups         []models.DuplicateOptionGroup `json:"groups"`
+}
+
+// SymbolPnLSplit is a symbol's realized and unrealized P/L. Unrealized components are nil when
+// a current price needed to mark them is missing.
+type SymbolPnLSplit struct {
+	Symbol             string   `json:"symbol"`
+	RealizedOptions    float64  `json:"realized_options"`
+	RealizedStock      float64  `json:"realized_stock"`
+	Realized           float64  `json:"realized"`
+	UnrealizedOptions  *float64 `json:"unrealized_options"`
+	UnrealizedStock    *float64 `json:"unrealized_stock"`
+	Unrealized         *float64 `json:"unrealized"`
+	OptionsMissingMark int      `json:"options_missing_mark"` // open options without a current_price
+	StockMissingPrice  bool     `json:"stock_missing_price"`  // open shares but no symbol price
+}
+
+// PnLSplitResponse is the portfolio-wide realized vs unrealized P/L. TotalUnrealized sums only the
+// components that could be marked; UnrealizedComplete is false when any were unavailable.
+type PnLSplitResponse struct {
+	Symbols            []SymbolPnLSplit `json:"symbols"`
+	TotalRealized      float64          `json:"total_realized"`
+	TotalUnrealized    float64          `json:"total_unrealized"`
+	UnrealizedComplete bool             `json:"unrealized_complete"`
+	Unavailable        []string         `json:"unavailable"`
+}
+
+// ContractDetailsResponse reports an option's contract terms; Standard is true for 100-share
+// American-style contracts, which are also assumed when Polygon details are unavailable
+type ContractDetailsResponse struct {
+	models.OptionContractDetails
+	Standard bool   `json:"standard"`
+	Warning  string `json:"warning,omitempty"`
+}
+
+// SnapshotStatusResponse reports the most recent scheduled metrics snapshot run
+type SnapshotStatusResponse struct {
+	Enabled       bool       `json:"enabled"`
+	Schedule      string     `json:"schedule"` // HH:MM in the market timezone
+	Status        string     `json:"status"`   // never_run, running, success or failed
+	LastRunAt     *time.Time `json:"last_run_at"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+	LastFailureAt *time.Time `json:"last_failure_at"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextRunAt     *time.Time `json:"next_run_at,omitempty"`
+}
+
+// SchedulerTaskStatus is one background task in /api/scheduler/status
+type SchedulerTaskStatus struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Enabled     bool       `json:"enabled"`
+	Schedule    string     `json:"schedule"` // e.g. "every 30 minutes" or "daily at 16:30"
+	Settings    []string   `json:"settings"` // settings that configure the task
+	LastRunAt   *time.Time `json:"last_run_at"`
+	LastResult  string     `json:"last_result"` // never_run, running, success, skipped or failed
+	LastError   string     `json:"last_error,omitempty"`
+	Detail      string     `json:"detail,omitempty"`
+	NextRunAt   *time.Time `json:"next_run_at,omitempty"`
+}
+
+// SchedulerStatusResponse lists the background tasks registered on the server
+type SchedulerStatusResponse struct {
+	GeneratedAt time.Time             `json:"generated_at"`
+	Tasks       []SchedulerTaskStatus `json:"tasks"`
+}
+
+// RiskAdjustedResponse is the Sharpe-like ratio of the total_value metric history with its component
+// statistics and the assumptions behind them
+type RiskAdjustedResponse struct {
+	models.RiskAdjustedStats
+	Metric      string   `json:"metric"`
+	Assumptions []string `json:"assumptions"`
+}
+
+// DrawdownResponse is the peak-to-trough drawdown of a value metric series
+type DrawdownResponse struct {
+	models.DrawdownStats
+	Metric string `json:"metric"`
+	Note   string `json:"note,omitempty"`
+}
+
+// CorrelationExclusion is an open-position symbol left out of the correlation matrix
+type CorrelationExclusion struct {
+	Symbol  string `json:"symbol"`
+	Returns int    `json:"returns"`
+	Note    string `json:"note"`
+}
+
+// CorrelationResponse is the correlation matrix of daily returns across open-position symbols.
+// Matrix rows and columns follow Symbols; a nil cell means the pair shares too few returns.
+type CorrelationResponse struct {
+	Symbols         []string                   `json:"symbols"`
+	Matrix          [][]*float64               `json:"matrix"`
+	Pairs           []models.ReturnCorrelation `json:"pairs"`
+	Excluded        []CorrelationExclusion     `json:"excluded"`
+	MinObservations int                        `json:"min_observations"`
+	Note            string                     `json:"note,omitempty"`
+}
+
+// PortfolioAlert is one triggered portfolio-risk rule. Value and Threshold are in the rule's unit
+// (percent for concentration rules).
+type PortfolioAlert struct {
+	Rule      string  `json:"rule"`
+	Severity  string  `json:"severity"`
+	Symbol    string  `json:"symbol,omitempty"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Message   string  `json:"message"`
+}
+
+// SymbolPutConcentration is one symbol's share of total open put exposure, in base currency
+type SymbolPutConcentration struct {
+	Symbol               string  `json:"symbol"`
+	PutExposure          float64 `json:"put_exposure"`
+	ConcentrationPercent float64 `json:"concentration_percent"`
+}
+
+// PutConcentrationCheck reports the symbols whose open put exposure exceeds the configured share
+// of total open put exposure
+type PutConcentrationCheck struct {
+	ThresholdPercent float64                  `json:"threshold_percent"`
+	TotalPutExposure float64                  `json:"total_put_exposure"`
+	Offending        []SymbolPutConcentration `json:"offending"`
+}
+
+// AlertsResponse lists every triggered portfolio-risk alert with the checks behind them
+type AlertsResponse struct {
+	Alerts           []PortfolioAlert      `json:"alerts"`
+	PutConcentration PutConcentrationCheck `json:"put_concentration"`
+}
+
+// MetricGapRange is a run of consecutive trading days missing from a metric's history
+type MetricGapRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Days int    `json:"days"` // trading days in the run
+}
+
+// MetricGapsResponse lists the trading days in a range with no recorded value for a metric type.
+// BackfillDays is the days value for POST /api/metrics/snapshot that reaches back to the earliest gap.
+type MetricGapsResponse struct {
+	Type         string           `json:"type"`
+	From         string           `json:"from"`
+	To           string           `json:"to"`
+	TradingDays  int              `json:"trading_days"`
+	RecordedDays int              `json:"recorded_days"`
+	Gaps         []string         `json:"gaps"`
+	Ranges       []MetricGapRange `json:"ranges"`
+	BackfillDays int              `json:"backfill_days"`
+}
+
+// HarvestCandidate is an open long lot trading below its adjusted cost basis. WashSale marks lots
+// whose sale today would be a wash sale because of the ReplacementLots bought inside the window.
+type HarvestCandidate struct {
+	PositionID        int      `json:"position_id"`
+	Symbol            string   `json:"symbol"`
+	Account           string   `json:"account"`
+	Opened            string   `json:"opened"`
+	Shares            int      `json:"shares"`
+	CostBasisPerShare float64  `json:"cost_basis_per_share"`
+	CurrentPrice      float64  `json:"current_price"`
+	Loss              float64  `json:"loss"` // positive dollars
+	LossPercent       float64  `json:"loss_percent"`
+	LongTerm          bool     `json:"long_term"` // held at least one year
+	WashSale          bool     `json:"wash_sale"`
+	ReplacementLots   []int    `json:"replacement_lots"`
+	Cautions          []string `json:"cautions"`
+}
+
+// HarvestCandidatesResponse lists tax-loss harvest candidates, largest loss first. HarvestableLoss
+// excludes lots flagged as wash sales.
+type HarvestCandidatesResponse struct {
+	AsOf            string             `json:"as_of"`
+	WindowDays      int                `json:"window_days"`
+	TotalLoss       float64            `json:"total_loss"`
+	HarvestableLoss float64            `json:"harvestable_loss"`
+	Candidates      []HarvestCandidate `json:"candidates"`
+	MissingPrice    []string           `json:"missing_price"`
+	Note            string             `json:"note"`
+}
+
+// PeriodSummary is one side of a period comparison. WinRate is the percent of options closed in
+// the period with a realized profit, nil when none closed.
+type PeriodSummary struct {
+	Start            string   `json:"start"`
+	End              string   `json:"end"`
+	RealizedProfit   float64  `json:"realized_profit"`   // options closed in the period
+	PremiumCollected float64  `json:"premium_collected"` // gross premium on options opened in the period
+	ClosedOptions    int      `json:"closed_options"`
+	Wins             int      `json:"wins"`
+	WinRate          *float64 `json:"win_rate"`
+	DividendIncome   float64  `json:"dividend_income"` // cash dividends received in the period
+}
+
+// PeriodCompareDelta is period2 minus period1; WinRate is in percentage points
+type PeriodCompareDelta struct {
+	RealizedProfit   float64  `json:"realized_profit"`
+	PremiumCollected float64  `json:"premium_collected"`
+	ClosedOptions    int      `json:"closed_options"`
+	WinRate          *float64 `json:"win_rate"`
+	DividendIncome   float64  `json:"dividend_income"`
+}
+
+// PeriodCompareResponse compares two non-overlapping date ranges side by side
+type PeriodCompareResponse struct {
+	Period1 PeriodSummary      `json:"period1"`
+	Period2 PeriodSummary      `json:"period2"`
+	Delta   PeriodCompareDelta `json:"delta"`
+}
+
+// CashflowEntry is one dated cash flow; Amount is positive for inflows and negative for outflows
+type CashflowEntry struct {
+	Type        string  `json:"type"`
+	Symbol      string  `json:"symbol,omitempty"`
+	CUSPID      string  `json:"cuspid,omitempty"`
+	OptionID    int     `json:"option_id,omitempty"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+}
+
+// CashflowDay holds a date's certain and contingent flows with the net of each
+type CashflowDay struct {
+	Certain       []CashflowEntry `json:"certain"`
+	Contingent    []CashflowEntry `json:"contingent"`
+	CertainNet    float64         `json:"certain_net"`
+	ContingentNet float64         `json:"contingent_net"`
+}
+
+// CashflowCalendarResponse is the date-keyed (YYYY-MM-DD) cash flow calendar
+type CashflowCalendarResponse struct {
+	Months                  int                     `json:"months"`
+	From                    string                  `json:"from"`
+	To                      string                  `json:"to"`
+	TotalCertainInflows     float64                 `json:"total_certain_inflows"`
+	TotalContingentOutflows float64                 `json:"total_contingent_outflows"`
+	Calendar                map[string]*CashflowDay `json:"calendar"`
+	Note                    string                  `json:"note"`
+}
+
+// DatabaseResetRequest confirms a reset of the current database's trading data
+type DatabaseResetRequest struct {
+	Confirm        bool `json:"confirm"`
+	IncludeSymbols bool `json:"include_symbols,omitempty"`
+}
+
+// DatabaseResetResponse reports the backup taken and the rows deleted per table by a reset
+type DatabaseResetResponse struct {
+	Success      bool             `json:"success"`
+	Database     string           `json:"database"`
+	Backup       string           `json:"backup"`
+	RowsDeleted  map[string]int64 `json:"rows_deleted"`
+	TotalDeleted int64            `json:"total_deleted"`
 }
 
 type AllocationData struct {
-	LongByTicker        []ChartData `json:"longByTicker"`
-	PutsByTicker        []ChartData `json:"putsByTicker"`
-	CallsToLongs        []ChartData `json:"callsToLongs"`
-	TotalAllocation     []ChartData `json:"totalAllocation"`
-	PutROI              float64     `json:"putROI"`
-	LongROI             float64     `json:"longROI"`
-	TotalPutPremiums    float64     `json:"totalPutPremiums"`
-	TotalCallPremiums   float64     `json:"totalCallPremiums"`
-	TotalCallCovered    float64     `json:"totalCallCovered"`
-	TotalOptionable     float64     `json:"totalOptionable"`
+	LongByTicker      []ChartData `json:"longByTicker"`
+	PutsByTicker      []ChartData `json:"putsByTicker"`
+	CallsToLongs      []ChartData `json:"callsToLongs"`
+	TotalAllocation   []ChartData `json:"totalAllocation"`
+	PutROI            float64     `json:"putROI"`
+	LongROI           float64     `json:"longROI"`
+	TotalPutPremiums  float64     `json:"totalPutPremiums"`
+	TotalCallPremiums float64     `json:"totalCallPremiums"`
+	TotalCallCovered  float64     `json:"totalCallCovered"`
+	TotalOptionable   float64     `json:"totalOptionable"`
+	TotalValue        float64     `json:"totalValue"`
+	IncludeTreasuries bool        `json:"includeTreasuries"`
 }
 
 type ChartPoint struct {
@@ -461,10 +1300,163 @@ type UpcomingDividendDate struct {
 	ExpectedAmount float64   `json:"expectedAmount"`
 }
 
+// DashboardSnapshot is the latest recorded value of each metric type. Date is the newest metric's
+// date; a type missing from the latest snapshot keeps its most recent older value.
+type DashboardSnapshot struct {
+	Date    string             `json:"date,omitempty"`
+	Metrics map[string]float64 `json:"metrics"`
+}
+
+// DashboardCounts are open-position counts; lots and shares exclude fully exited lots
+type DashboardCounts struct {
+	ActiveSymbols int `json:"active_symbols"`
+	OpenLots      int `json:"open_lots"`
+	SharesHeld    int `json:"shares_held"`
+	OpenPuts      int `json:"open_puts"`
+	OpenCalls     int `json:"open_calls"`
+}
+
+// DashboardExpiration is an open option in the nearest-expirations list
+type DashboardExpiration struct {
+	ID            int     `json:"id"`
+	Symbol        string  `json:"symbol"`
+	Type          string  `json:"type"`
+	Strike        float64 `json:"strike"`
+	Expiration    string  `json:"expiration"`
+	Contracts     int     `json:"contracts"`
+	DaysRemaining int     `json:"days_remaining"`
+}
+
+// DashboardActivity is one recent event; Amount is the signed cash flow before commission
+type DashboardActivity struct {
+	Date        string  `json:"date"`
+	Event       string  `json:"event"`
+	Symbol      string  `json:"symbol"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+}
+
+// DashboardAPIResponse is everything the home dashboard shows, served by /api/dashboard
+type DashboardAPIResponse struct {
+	Snapshot            DashboardSnapshot      `json:"snapshot"`
+	Counts              DashboardCounts        `json:"counts"`
+	NearestExpirations  []DashboardExpiration  `json:"nearest_expirations"`
+	UpcomingExDividends []UpcomingDividendDate `json:"upcoming_ex_dividends"`
+	RecentActivity      []DashboardActivity    `json:"recent_activity"`
+	ActivityDays        int                    `json:"activity_days"`
+	Alerts              []PortfolioAlert       `json:"alerts"`
+}
+
 // PageData holds common data for all page templates
 type PageData struct {
 	Title      string   `json:"title"`
 	ActivePage string   `json:"activePage"`
 	CurrentDB  string   `json:"currentDB"`
 	AllSymbols []string `json:"allSymbols"`
-}
\ No newline at end of file
+}
+
+// SymbolBetaWeight is one symbol's contribution to the portfolio beta
+type SymbolBetaWeight struct {
+	Symbol    string   `json:"symbol"`
+	LongValue float64  `json:"long_value"`
+	Weight    float64  `json:"weight"`
+	Beta      *float64 `json:"beta"`
+}
+
+// PortfolioBetaResponse is a position-weighted beta over open long positions.
+// Options are not beta-weighted in this version.
+type PortfolioBetaResponse struct {
+	PortfolioBeta    float64            `json:"portfolio_beta"`
+	TotalLongValue   float64            `json:"total_long_value"`
+	CoveredLongValue float64            `json:"covered_long_value"`
+	MissingBeta      []string           `json:"missing_beta"`
+	Symbols          []SymbolBetaWeight `json:"symbols"`
+	Note             string             `json:"note"`
+}
+
+// CapitalEfficiencyResponse is trailing-period profit over average capital deployed, annualized.
+// CapitalBasis is "history" when averaged from metric snapshots or "live" when none existed.
+type CapitalEfficiencyResponse struct {
+	Start                    string  `json:"start"`
+	End                      string  `json:"end"`
+	PeriodDays               int     `json:"period_days"`
+	RealizedOptions          float64 `json:"realized_options"`
+	RealizedLongs            float64 `json:"realized_longs"`
+	Dividends                float64 `json:"dividends"`
+	Realized                 float64 `json:"realized"`
+	UnrealizedOptions        float64 `json:"unrealized_options"`
+	UnrealizedLongs          float64 `json:"unrealized_longs"`
+	Unrealized               float64 `json:"unrealized"`
+	TotalProfit              float64 `json:"total_profit"`
+	AverageCapital           float64 `json:"average_capital"`
+	SnapshotDays             int     `json:"snapshot_days"`
+	CapitalBasis             string  `json:"capital_basis"`
+	PeriodReturnPercent      float64 `json:"period_return_percent"`
+	CapitalEfficiencyPercent float64 `json:"capital_efficiency_percent"`
+	Formula                  string  `json:"formula"`
+	Annualization            string  `json:"annualization"` // calendar or trading
+	DaysPerYear              float64 `json:"days_per_year"`
+}
+
+// IncomeYieldResponse is trailing-period income over average capital deployed, annualized, with
+// each income component's share of the yield
+type IncomeYieldResponse struct {
+	Start                string  `json:"start"`
+	End                  string  `json:"end"`
+	PeriodDays           int     `json:"period_days"`
+	OptionPremium        float64 `json:"option_premium"`
+	Dividends            float64 `json:"dividends"`
+	TreasuryInterest     float64 `json:"treasury_interest"`
+	TotalIncome          float64 `json:"total_income"`
+	AverageCapital       float64 `json:"average_capital"`
+	SnapshotDays         int     `json:"snapshot_days"`
+	CapitalBasis         string  `json:"capital_basis"`
+	PeriodYieldPercent   float64 `json:"period_yield_percent"`
+	OptionYieldPercent   float64 `json:"option_yield_percent"`
+	DividendYieldPercent float64 `json:"dividend_yield_percent"`
+	TreasuryYieldPercent float64 `json:"treasury_yield_percent"`
+	IncomeYieldPercent   float64 `json:"income_yield_percent"` // annualized composite
+	Formula              string  `json:"formula"`
+	Annualization        string  `json:"annualization"` // calendar or trading
+	DaysPerYear          float64 `json:"days_per_year"`
+}
+
+// IncomeProjectionMonth is one month of projected income. Dividends come from each
+// symbol's known quarterly dividend, coupons from held notes and bonds; premium is an estimate from trailing closed options.
+type IncomeProjectionMonth struct {
+	Month            string  `json:"month"` // YYYY-MM
+	Dividends        float64 `json:"dividends"`
+	Coupons          float64 `json:"coupons"`
+	EstimatedPremium float64 `json:"estimated_premium"`
+	Total            float64 `json:"total"`
+}
+
+// IncomeProjectionResponse is the forward month-by-month income projection
+type IncomeProjectionResponse struct {
+	Months                 int                     `json:"months"`
+	TrailingMonths         int                     `json:"trailing_months"`
+	TrailingPremiumTotal   float64                 `json:"trailing_premium_total"`
+	PremiumRunRatePerMonth float64                 `json:"premium_run_rate_per_month"`
+	TotalDividends         float64                 `json:"total_dividends"`
+	TotalCoupons           float64                 `json:"total_coupons"`
+	TotalEstimatedPremium  float64                 `json:"total_estimated_premium"`
+	Total                  float64                 `json:"total"`
+	Projection             []IncomeProjectionMonth `json:"projection"`
+	Note                   string                  `json:"note"`
+}
+
+// YTDPremiumMonth is one month of net option premium with the running total through that month
+type YTDPremiumMonth struct {
+	Month        string  `json:"month"` // YYYY-MM
+	Options      int     `json:"options"`
+	NetPremium   float64 `json:"net_premium"`
+	RunningTotal float64 `json:"running_total"`
+}
+
+// YTDPremiumResponse is the year-to-date net premium, attributed by open or close date
+type YTDPremiumResponse struct {
+	Year            int               `json:"year"`
+	AttributeBy     string            `json:"attribute_by"` // opened or closed
+	TotalNetPremium float64           `json:"total_net_premium"`
+	Months          []YTDPremiumMonth `json:"months"`
+}
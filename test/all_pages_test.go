@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+
+	"stonks/internal/models"
 )
 
 // TestAllPageRendering tests every HTML page in the Wheeler application
@@ -84,6 +86,57 @@ func TestAllPageRendering(t *testing.T) {
 	}
 }
 
+// TestNavigationShowAllOverride checks that ?all=1 lists inactive symbols in the navigation
+// while NAV_ACTIVE_SYMBOLS_ONLY hides them by default
+func TestNavigationShowAllOverride(t *testing.T) {
+	testDB := openTestServerDB(t)
+	defer testDB.Close()
+	if _, err := models.NewSymbolService(testDB.DB).Create("NAVIDLE"); err != nil {
+		t.Fatalf("Failed to create symbol: %v", err)
+	}
+
+	setActiveOnly := func(value string) {
+		req, err := http.NewRequest(http.MethodPut, "http://localhost:8081/api/settings/NAV_ACTIVE_SYMBOLS_ONLY",
+			strings.NewReader(`{"value": "`+value+`"}`))
+		if err != nil {
+			t.Fatalf("Failed to build settings request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to update NAV_ACTIVE_SYMBOLS_ONLY: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Updating NAV_ACTIVE_SYMBOLS_ONLY returned status %d", resp.StatusCode)
+		}
+	}
+	setActiveOnly("true")
+	defer setActiveOnly("false")
+
+	listsIdleSymbol := func(url string) bool {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("Failed to request %s: %v", url, err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", url, err)
+		}
+		return strings.Contains(string(body), `href="/symbol/NAVIDLE"`)
+	}
+
+	for _, page := range []string{"/settings", "/backup", "/dividends"} {
+		if listsIdleSymbol("http://localhost:8081" + page) {
+			t.Errorf("%s navigation lists an inactive symbol with NAV_ACTIVE_SYMBOLS_ONLY on", page)
+		}
+		if !listsIdleSymbol("http://localhost:8081" + page + "?all=1") {
+			t.Errorf("%s?all=1 navigation does not list every symbol", page)
+		}
+	}
+}
+
 // TestSymbolPagesRendering tests symbol-specific pages
 func TestSymbolPagesRendering(t *testing.T) {
 	client := &http.Client{}
@@ -0,0 +1,178 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"time"
+
+	"stonks/internal/models"
+	"stonks/internal/web"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// uploadImportCSV posts a CSV with form fields to one of the test server's import endpoints
+func uploadImportCSV(t *testing.T, path, csv string, fields map[string]string) web.ImportResponse {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("csvFile", "import.csv")
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	part.Write([]byte(csv))
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	form.Close()
+
+	resp, err := http.Post("http://localhost:8081"+path, form.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("Failed to upload CSV to %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	var result web.ImportResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode import response: %v", err)
+	}
+	return result
+}
+
+// uploadOptionActivity posts a CSV to the options activity importer on the test server
+func uploadOptionActivity(t *testing.T, csv string) web.ImportResponse {
+	t.Helper()
+	return uploadImportCSV(t, "/import/upload/options-activity", csv, nil)
+}
+
+// TestOptionActivityImportAssignments imports an assigned put and a called-away call and checks
+// that the put opens a lot, the call closes it, and both options store their realized profit
+func TestOptionActivityImportAssignments(t *testing.T) {
+	csv := "symbol,opened,closed,type,strike,expiration,premium,contracts,exit_price,commission,event\n" +
+		"ACTV,2025-01-02,2025-01-17,Put,50,2025-01-17,1.00,1,,0.65,Assigned\n" +
+		"ACTV,2025-02-03,2025-02-21,Call,55,2025-02-21,1.20,1,,0.65,Assigned\n"
+
+	result := uploadOptionActivity(t, csv)
+	if !result.Success {
+		t.Fatalf("Activity import failed: %s (%s)", result.Error, result.Details)
+	}
+	if result.ImportedCount != 2 || result.AssignedCount != 2 {
+		t.Fatalf("Expected 2 imported and 2 assigned, got %+v", result)
+	}
+
+	testDB := openTestServerDB(t)
+	defer testDB.Close()
+
+	rows, err := testDB.Query(`SELECT type, closed IS NOT NULL, assigned, assigned_position_id, realized_profit
+		FROM options WHERE symbol = 'ACTV' ORDER BY opened`)
+	if err != nil {
+		t.Fatalf("Failed to query options: %v", err)
+	}
+	defer rows.Close()
+
+	var positionIDs []int
+	expectedProfit := map[string]float64{"Put": 100.0 - 0.65, "Call": 120.0 - 0.65}
+	for rows.Next() {
+		var optionType string
+		var closed, assigned bool
+		var positionID *int
+		var realized *float64
+		if err := rows.Scan(&optionType, &closed, &assigned, &positionID, &realized); err != nil {
+			t.Fatalf("Failed to scan option: %v", err)
+		}
+		if !closed || !assigned || positionID == nil {
+			t.Errorf("Expected the %s closed by assignment with a linked lot", optionType)
+			continue
+		}
+		if realized == nil || math.Abs(*realized-expectedProfit[optionType]) > 0.001 {
+			t.Errorf("Expected %s realized profit %.2f, got %v", optionType, expectedProfit[optionType], realized)
+		}
+		positionIDs = append(positionIDs, *positionID)
+	}
+	if len(positionIDs) != 2 || positionIDs[0] != positionIDs[1] {
+		t.Fatalf("Expected the call to close the lot the put opened, got positions %v", positionIDs)
+	}
+
+	var shares int
+	var buyPrice float64
+	var positionClosed bool
+	if err := testDB.QueryRow(`SELECT shares, buy_price, closed IS NOT NULL FROM long_positions WHERE id = ?`, positionIDs[0]).
+		Scan(&shares, &buyPrice, &positionClosed); err != nil {
+		t.Fatalf("Failed to load assigned lot: %v", err)
+	}
+	if shares != 100 || buyPrice != 50.0 || !positionClosed {
+		t.Errorf("Expected a closed 100-share lot bought at $50, got %d shares at $%.2f (closed=%v)", shares, buyPrice, positionClosed)
+	}
+
+	var exitShares int
+	var exitPrice float64
+	if err := testDB.QueryRow(`SELECT SUM(shares), MAX(price) FROM long_position_exits WHERE position_id = ?`, positionIDs[0]).
+		Scan(&exitShares, &exitPrice); err != nil {
+		t.Fatalf("Failed to load lot exits: %v", err)
+	}
+	if exitShares != 100 || exitPrice != 55.0 {
+		t.Errorf("Expected 100 shares called away at $55, got %d at $%.2f", exitShares, exitPrice)
+	}
+
+	// Re-importing the same activity leaves the closed options alone
+	again := uploadOptionActivity(t, csv)
+	if !again.Success || again.ImportedCount != 0 || again.AssignedCount != 0 || again.SkippedCount != 2 {
+		t.Fatalf("Expected both rows skipped on re-import, got %+v", again)
+	}
+}
+
+// TestOptionsImportUpdatesFees closes stored options from a CSV with updateExisting and checks that
+// a fees column overwrites stored fees even with 0, while a file without one leaves them alone
+func TestOptionsImportUpdatesFees(t *testing.T) {
+	testDB := openTestServerDB(t)
+	defer testDB.Close()
+
+	symbolService := models.NewSymbolService(testDB.DB)
+	optionService := models.NewOptionService(testDB.DB)
+	if _, err := symbolService.Create("FEEZ"); err != nil {
+		t.Fatalf("Failed to create symbol: %v", err)
+	}
+	opened := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	var ids []int
+	for _, strike := range []float64{30, 31} {
+		option, err := optionService.CreateWithCommission("FEEZ", "Put", opened, strike, time.Date(2025, 3, 21, 0, 0, 0, 0, time.UTC), 0.80, 1, 0.65)
+		if err != nil {
+			t.Fatalf("Failed to create option: %v", err)
+		}
+		if err := optionService.SetFees(option.ID, 0.12); err != nil {
+			t.Fatalf("Failed to set fees: %v", err)
+		}
+		ids = append(ids, option.ID)
+	}
+
+	updateExisting := map[string]string{"updateExisting": "true"}
+	withFees := uploadImportCSV(t, "/import/upload",
+		"symbol,opened,closed,type,strike,expiration,premium,contracts,exit_price,commission,fees\n"+
+			"FEEZ,2025-03-03,2025-03-14,Put,30,2025-03-21,0.80,1,0.10,1.30,0\n", updateExisting)
+	withoutFees := uploadImportCSV(t, "/import/upload",
+		"symbol,opened,closed,type,strike,expiration,premium,contracts,exit_price,commission\n"+
+			"FEEZ,2025-03-03,2025-03-14,Put,31,2025-03-21,0.80,1,0.10,1.30\n", updateExisting)
+	for _, result := range []web.ImportResponse{withFees, withoutFees} {
+		if !result.Success || result.UpdatedCount != 1 {
+			t.Fatalf("Expected one option updated, got %+v", result)
+		}
+	}
+
+	for i, expected := range []float64{0, 0.12} {
+		option, err := optionService.GetByID(ids[i])
+		if err != nil {
+			t.Fatalf("Failed to get option: %v", err)
+		}
+		if option.Closed == nil {
+			t.Errorf("Expected option %d closed by the import", option.ID)
+		}
+		if math.Abs(option.Fees-expected) > 1e-9 {
+			t.Errorf("Expected option %d fees %.2f, got %.2f", option.ID, expected, option.Fees)
+		}
+	}
+}
@@ -112,4 +112,18 @@ func stopTestServer() {
 		defer cancel()
 		testServer.Shutdown(ctx)
 	}
-}
\ No newline at end of file
+}
+// openTestServerDB opens a second connection to the database the test server is using, for
+// seeding data and checking what handlers wrote
+func openTestServerDB(t *testing.T) *database.DB {
+	t.Helper()
+	dbPath, err := database.GetCurrentDatabasePath()
+	if err != nil {
+		t.Fatalf("Failed to get test database path: %v", err)
+	}
+	testDB, err := database.NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	return testDB
+}
@@ -0,0 +1,37 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestOptionsReturnsBasisValidation checks that endpoints computing returns accept gross and net
+// for ?basis= and reject anything else
+func TestOptionsReturnsBasisValidation(t *testing.T) {
+	endpoints := []string{
+		"/api/options/performance-by-strategy",
+		"/api/options/close-timing",
+		"/api/options/streaks",
+	}
+	tests := []struct {
+		basis    string
+		expected int
+	}{
+		{"gross", http.StatusOK},
+		{"NET", http.StatusOK},
+		{"grosss", http.StatusBadRequest},
+	}
+
+	for _, endpoint := range endpoints {
+		for _, tt := range tests {
+			resp, err := http.Get("http://localhost:8081" + endpoint + "?basis=" + tt.basis)
+			if err != nil {
+				t.Fatalf("Failed to request %s: %v", endpoint, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.expected {
+				t.Errorf("%s?basis=%s: expected status %d, got %d", endpoint, tt.basis, tt.expected, resp.StatusCode)
+			}
+		}
+	}
+}
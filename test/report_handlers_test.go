@@ -0,0 +1,148 @@
+package test
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"stonks/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// fetchForm8949Rows downloads the Form 8949 export from the test server and returns the rows for
+// one symbol, keyed by description
+func fetchForm8949Rows(t *testing.T, query, symbol string) []map[string]string {
+	t.Helper()
+
+	resp, err := http.Get("http://localhost:8081/export/form8949.csv" + query)
+	if err != nil {
+		t.Fatalf("Failed to download Form 8949 export: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse Form 8949 CSV: %v", err)
+	}
+	if len(records) == 0 {
+		t.Fatalf("Expected a header row")
+	}
+
+	header := records[0]
+	var rows []map[string]string
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			row[column] = record[i]
+		}
+		if row["Symbol"] == symbol {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// TestForm8949Export seeds a lot with a partial exit on each side of the one-year mark, an expired
+// covered call and an assigned put, and checks terms, basis adjustments and row order
+func TestForm8949Export(t *testing.T) {
+	testDB := openTestServerDB(t)
+	defer testDB.Close()
+	testDB.SetMaxOpenConns(1)
+
+	date := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			t.Fatalf("Invalid test date %s: %v", value, err)
+		}
+		return parsed
+	}
+
+	symbolService := models.NewSymbolService(testDB.DB)
+	optionService := models.NewOptionService(testDB.DB)
+	lpService := models.NewLongPositionService(testDB.DB)
+	if _, err := symbolService.Create("FORM"); err != nil {
+		t.Fatalf("Failed to create symbol: %v", err)
+	}
+
+	// 100 shares at $50, sold 40 within the year and 60 after it
+	lot, err := lpService.Create("FORM", date("2018-01-02"), 100, 50.0)
+	if err != nil {
+		t.Fatalf("Failed to create lot: %v", err)
+	}
+	if _, err := lpService.AddExit(lot.ID, date("2018-06-01"), 40, 55.0); err != nil {
+		t.Fatalf("Failed to add short-term exit: %v", err)
+	}
+	if _, err := lpService.AddExit(lot.ID, date("2019-03-01"), 60, 60.0); err != nil {
+		t.Fatalf("Failed to add long-term exit: %v", err)
+	}
+
+	// A covered call that expired: its $100 lowers the lot's adjusted basis but is reported on its
+	// own row, so the export adds it back to the lot
+	call, err := optionService.CreateWithCommission("FORM", "Call", date("2018-02-01"), 60.0, date("2018-03-16"), 1.00, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to create call: %v", err)
+	}
+	if err := optionService.CloseByIDWithCommission(call.ID, date("2018-03-16"), 0, 0); err != nil {
+		t.Fatalf("Failed to expire call: %v", err)
+	}
+
+	// An assigned put: its $100 stays in the basis of the lot it opened and gets no row of its own
+	put, err := optionService.CreateWithCommission("FORM", "Put", date("2019-01-02"), 40.0, date("2019-01-18"), 1.00, 1, 0)
+	if err != nil {
+		t.Fatalf("Failed to create put: %v", err)
+	}
+	assigned, err := lpService.AssignPut(put.ID, date("2019-01-18"), models.AssignmentBuyPriceStrike)
+	if err != nil {
+		t.Fatalf("Failed to assign put: %v", err)
+	}
+	if _, err := lpService.AddExit(assigned.ID, date("2019-05-01"), 100, 45.0); err != nil {
+		t.Fatalf("Failed to sell assigned lot: %v", err)
+	}
+
+	rows := fetchForm8949Rows(t, "", "FORM")
+	expected := []struct {
+		description, acquired, sold, proceeds, basis, gain, term string
+		note                                                     string
+	}{
+		// Short-term (Part I) by sale date, then long-term (Part II)
+		{"1 FORM Call 60.00 exp 2018-03-16", "02/01/2018", "03/16/2018", "100.00", "0.00", "100.00", "Short-term", "Written option (short-term)"},
+		{"40 sh FORM", "01/02/2018", "06/01/2018", "2200.00", "2000.00", "200.00", "Short-term", ""},
+		{"100 sh FORM", "01/18/2019", "05/01/2019", "4500.00", "3900.00", "600.00", "Short-term", "Basis includes premium of assigned put"},
+		{"60 sh FORM", "01/02/2018", "03/01/2019", "3600.00", "3000.00", "600.00", "Long-term", ""},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("Expected %d FORM rows, got %d: %v", len(expected), len(rows), rows)
+	}
+	for i, want := range expected {
+		row := rows[i]
+		got := []string{row["Description"], row["Date Acquired"], row["Date Sold"], row["Proceeds"], row["Cost Basis"], row["Gain or Loss"], row["Term"]}
+		wanted := []string{want.description, want.acquired, want.sold, want.proceeds, want.basis, want.gain, want.term}
+		if strings.Join(got, "|") != strings.Join(wanted, "|") {
+			t.Errorf("Row %d: expected %v, got %v", i, wanted, got)
+		}
+		if want.note != "" && !strings.Contains(row["Notes"], want.note) {
+			t.Errorf("Row %d: expected notes to mention %q, got %q", i, want.note, row["Notes"])
+		}
+	}
+
+	// year filters by the date sold
+	rows = fetchForm8949Rows(t, "?year=2019", "FORM")
+	if len(rows) != 2 || rows[0]["Description"] != "100 sh FORM" || rows[1]["Description"] != "60 sh FORM" {
+		t.Fatalf("Expected the two 2019 sales, got %v", rows)
+	}
+
+	resp, err := http.Get("http://localhost:8081/export/form8949.csv?year=abc")
+	if err != nil {
+		t.Fatalf("Failed to request export: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid year, got %d", resp.StatusCode)
+	}
+}
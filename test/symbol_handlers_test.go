@@ -117,7 +117,7 @@ func TestSymbolDataStructure(t *testing.T) {
 			}
 			
 			// Test AROI calculation
-			aroi := option.CalculateAROI()
+			aroi := option.CalculateAROI(models.AnnualizationCalendar)
 			if math.IsNaN(aroi) || math.IsInf(aroi, 0) {
 				t.Errorf("Option %d AROI should be a valid number, got %f", i, aroi)
 			}
@@ -611,7 +611,7 @@ func TestOptionAROICalculation(t *testing.T) {
 			Commission: 1.30, // $0.65 * 2
 		}
 		
-		aroi := option.CalculateAROI()
+		aroi := option.CalculateAROI(models.AnnualizationCalendar)
 		
 		// Expected calculation:
 		// Profit = (2.0 - 1.0) * 1 * 100 = $100
@@ -650,7 +650,7 @@ func TestOptionAROICalculation(t *testing.T) {
 			Commission: 1.30,
 		}
 		
-		aroi := option.CalculateAROI()
+		aroi := option.CalculateAROI(models.AnnualizationCalendar)
 		
 		// Expected calculation:
 		// Profit = (3.0 - 3.5) * 1 * 100 = -$50
@@ -688,7 +688,7 @@ func TestOptionAROICalculation(t *testing.T) {
 			Commission: 0.65,
 		}
 		
-		aroi := option.CalculateAROI()
+		aroi := option.CalculateAROI(models.AnnualizationCalendar)
 		
 		// For open positions, profit = premium * contracts * 100 (assuming exit at $0)
 		// Profit = 1.25 * 1 * 100 = $125
@@ -723,7 +723,7 @@ func TestOptionAROICalculation(t *testing.T) {
 			Commission: 0.65,
 		}
 		
-		aroi := option.CalculateAROI()
+		aroi := option.CalculateAROI(models.AnnualizationCalendar)
 		
 		// Should return 0 for zero capital to avoid division by zero
 		if aroi != 0 {